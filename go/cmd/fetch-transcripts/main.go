@@ -1,34 +1,164 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"sort"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/errs"
+	"github.com/aramova/twit-transcript-archiver/go/internal/httplog"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+	"github.com/aramova/twit-transcript-archiver/go/internal/lock"
 	"github.com/aramova/twit-transcript-archiver/go/internal/scraper"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
 	"github.com/aramova/twit-transcript-archiver/go/internal/utils"
+	"github.com/aramova/twit-transcript-archiver/go/internal/warc"
 )
 
 func main() {
+	os.Exit(run())
+}
+
+// run does the work of main and returns the exit code, so deferred cleanup
+// (the failure report, the WARC file) happens before the process exits
+func run() int {
+	addUnknownPtr := flag.Bool("add-unknown", false, "Add shows found in the listing that match no known show to the catalog, with a generated prefix")
+	splitByEraPtr := flag.Bool("split-by-era", false, "Keep the eras of renamed shows (e.g. TWIG before it became IM) under separate prefixes")
 	allPtr := flag.Bool("all", false, "Download transcripts for ALL known shows")
 	pagesPtr := flag.Int("pages", 200, "Number of pages to scan")
+	listWorkersPtr := flag.Int("list-workers", 4, "Number of listing pages to fetch at once; 1 fetches them one after another")
+	stopAfterEmptyPtr := flag.Int("stop-after-empty", 0, "Stop paging the listing after this many consecutive pages without the target shows, once one has been seen; 0 scans all --pages")
+	stopAfterKnownPtr := flag.Int("stop-after-known", 0, "Stop paging the listing after this many consecutive pages with no transcript of the target shows that is not archived yet; 0 scans all --pages")
 	refreshPtr := flag.Bool("refresh-list", false, "Force re-download of list pages")
+	cacheTTLPtr := flag.String("cache-ttl", "", "How long cached list pages stay fresh: a duration for all pages (24h, 7d, never) or per page range (1-5=6h,6-=never); default re-downloads pages 1-5 only")
 	throttlePtr := flag.Duration("throttle", 1*time.Second, "Duration to wait between requests (e.g. 1s, 500ms)")
 	noThrottlePtr := flag.Bool("no-throttle", false, "Disable throttling")
+	fixedThrottlePtr := flag.Bool("fixed-throttle", false, "Always wait --throttle between requests, instead of slowing down and pausing when the site pushes back or responds slowly")
+	episodesPtr := flag.String("episodes", "", "Only fetch this episode range (e.g. 900-950, 900-, -950)")
+	sincePtr := flag.String("since", "", "Only keep episodes published on or after this date (YYYY-MM-DD)")
+	urlPtr := flag.String("url", "", "Download a single transcript page by URL and add it to the archive")
+	forcePtr := flag.Bool("force", false, "With --url, replace an already archived copy of the episode")
+	discoveryPtr := flag.String("discovery", "auto", "How to find transcripts: list (page through the full listing), search (site search per show) or auto")
+	withMediaPtr := flag.Bool("with-media", false, "Also download each episode's audio into the media directory")
+	mediaBudgetPtr := flag.String("media-budget", "", "Stop downloading media after this much data (e.g. 20G); empty for no limit")
+	fillGapsPtr := flag.Bool("fill-gaps", false, "Only look for episodes missing between archived ones, without paging the listing")
+	maxBandwidthPtr := flag.String("max-bandwidth", "", "Limit download speed (e.g. 500KB/s); empty for no limit")
+	maxBytesPtr := flag.String("max-bytes-per-run", "", "Stop after downloading this much data in total (e.g. 2G); empty for no limit")
+	maxArchivePtr := flag.String("max-archive-size", "", "Stop before the data directory grows past this size (e.g. 50G); empty for no limit")
+	errorsPtr := flag.String("errors", "", "Where to write the JSON report of URLs that failed (default: errors.json in the data directory)")
+	warcPtr := flag.String("warc", "", "Also record every HTTP request and response into this WARC file (.warc or .warc.gz)")
+	mirrorPtr := flag.Bool("mirror", false, "Also keep an exact copy of every downloaded page under its URL path in raw/ (e.g. raw/twit.tv/posts/transcripts/index.html), with a URL-to-file table in raw/mirror.json")
+	debugHTTPPtr := flag.String("debug-http", "", "Record every request and response with headers and timings into this file: a HAR file if it ends in .har, else a text log")
+	debugBodiesPtr := flag.Bool("debug-http-bodies", false, "With --debug-http, also record response bodies (up to 1 MB each)")
+	verbosePtr := flag.Bool("verbose", false, "Print connection statistics (new and reused connections, HTTP/2) in the summary")
+	waitPtr := flag.Duration("wait", 0, "If another run is using the data directory, wait this long for it to finish instead of failing")
+	untilPtr := flag.String("until", "", "Only keep episodes published on or before this date (YYYY-MM-DD)")
+	priorityPtr := flag.String("priority", "", "Comma-separated shows to download first (e.g. SN,TWIT); the listing is scanned before anything is downloaded")
+	maxDurationPtr := flag.Duration("max-duration", 0, "Stop cleanly once the run has taken this long (e.g. 30m), leaving the rest for the next run; 0 for no limit")
+	quotaPtr := flag.String("quota", "", "Most new transcripts to download per show in this run (e.g. SN=20,*=5, or 5 for every show)")
+	strict := errs.Classes{}
+	flag.Var(strict, "strict", "Stop at the first failure of these classes (comma-separated; all if given without a value)")
 	// "shows" flag is harder in Go flag package as it doesn't support nargs easily without a custom Value
 	// We'll treat remaining args as shows if --all is not set
 
-	flag.Parse()
+	args, _ := utils.ParseFlags(flag.CommandLine, os.Args[1:])
+	if _, err := config.LoadSettings(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return errs.ExitUsage
+	}
+	if err := config.Loaded.Apply(flag.CommandLine, "fetch-transcripts"); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return errs.ExitUsage
+	}
+	if len(args) == 0 {
+		args = config.Loaded.Shows()
+	}
+	config.SplitByEra = *splitByEraPtr
+	deadline = utils.NewDeadline(*maxDurationPtr)
+
+	filter, err := converter.NewFilter(*episodesPtr, *sincePtr, *untilPtr)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return errs.ExitUsage
+	}
+	mediaMax, err := scraper.ParseSize(*mediaBudgetPtr)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return errs.ExitUsage
+	}
+	mediaBudget := &scraper.MediaBudget{Max: mediaMax}
+	rate, err := scraper.ParseRate(*maxBandwidthPtr)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return errs.ExitUsage
+	}
+	maxBytes, err := scraper.ParseSize(*maxBytesPtr)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return errs.ExitUsage
+	}
+	scraper.Bandwidth = scraper.NewLimiter(rate, maxBytes)
+	maxArchive, err := scraper.ParseSize(*maxArchivePtr)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return errs.ExitUsage
+	}
+	schedule, err := scraper.ParseSchedule(*priorityPtr, *quotaPtr)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return errs.ExitUsage
+	}
+	if *cacheTTLPtr != "" {
+		if scraper.ListCache, err = scraper.ParseCachePolicy(*cacheTTLPtr); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return errs.ExitUsage
+		}
+	}
+	switch *discoveryPtr {
+	case "auto", "list", "search":
+	default:
+		fmt.Printf("Error: unknown discovery mode '%s' (want auto, list or search)\n", *discoveryPtr)
+		return errs.ExitUsage
+	}
+	if *discoveryPtr == "search" && config.ActiveSource != nil {
+		fmt.Printf("Error: --discovery search only works on %s, not source %s\n", config.BaseSiteURL, config.ActiveSource.Name)
+		return errs.ExitUsage
+	}
 
 	dataDir := config.GetDataDir()
-	if err := utils.EnsureDir(dataDir); err != nil {
-		fmt.Printf("Error creating data dir: %v\n", err)
-		os.Exit(1)
+	if !storage.IsRemote(dataDir) {
+		if err := utils.EnsureDir(dataDir); err != nil {
+			fmt.Printf("Error creating data dir: %v\n", err)
+			return errs.ExitError
+		}
 	}
 	fmt.Printf("Using data directory: %s\n", dataDir)
+	if !config.ReadOnly {
+		l, err := lock.Acquire(dataDir, "fetch-transcripts", *waitPtr)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return errs.ExitLocked
+		}
+		defer l.Release()
+		if removed, err := scraper.CleanPartial(dataDir); err != nil {
+			fmt.Printf("Warning: could not clean up partial downloads: %v\n", err)
+		} else if len(removed) > 0 {
+			fmt.Printf("Removed %d partial download(s) left by an interrupted run\n", len(removed))
+		}
+	}
+	catchInterrupt()
+	if err := config.LoadCustomShows(dataDir); err != nil {
+		fmt.Printf("Warning: could not load custom shows: %v\n", err)
+	}
 
 	throttle := *throttlePtr
 	if *noThrottlePtr {
@@ -39,45 +169,80 @@ func main() {
 	} else {
 		fmt.Println("Throttling disabled.")
 	}
+	if !*fixedThrottlePtr {
+		scraper.Politeness = scraper.NewPacer()
+	}
+
+	if maxArchive > 0 {
+		if scraper.Quota, err = scraper.NewSizeQuota(dataDir, maxArchive); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return errs.ExitUsage
+		}
+	}
+
+	closeWARC := func() {}
+	if *warcPtr != "" {
+		if closeWARC, err = openWARC(*warcPtr); err != nil {
+			fmt.Printf("Error opening WARC file: %v\n", err)
+			return errs.ExitError
+		}
+	}
+	defer closeWARC()
+
+	closeHTTPLog := func() {}
+	if *debugHTTPPtr != "" {
+		if closeHTTPLog, err = openHTTPLog(*debugHTTPPtr, *debugBodiesPtr); err != nil {
+			fmt.Printf("Error opening HTTP debug log: %v\n", err)
+			return errs.ExitError
+		}
+	}
+	defer closeHTTPLog()
+
+	closeMirror := func() {}
+	if *mirrorPtr {
+		if closeMirror, err = openMirror(storage.Join(dataDir, scraper.MirrorDir)); err != nil {
+			fmt.Printf("Error opening mirror: %v\n", err)
+			return errs.ExitError
+		}
+	}
+	defer closeMirror()
+
+	if *urlPtr != "" {
+		path, err := scraper.FetchURL(*urlPtr, dataDir, throttle, scraper.IngestOptions{Force: *forcePtr})
+		if errors.Is(err, scraper.ErrOutranked) {
+			fmt.Printf("Note: %v\n", err)
+			return errs.ExitOK
+		}
+		if err == nil {
+			_, err = index.AddFile(dataDir, path)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return errs.ExitError
+		}
+		fmt.Printf("Saved %s\n", path)
+		return errs.ExitOK
+	}
 
 	targetPrefixes := make(map[string]bool)
 
 	if *allPtr {
 		for _, prefix := range config.ShowMap {
-			targetPrefixes[prefix] = true
+			targetPrefixes[config.CanonicalPrefix(prefix)] = true
 		}
 	} else {
-		args := flag.Args()
 		if len(args) == 0 {
 			fmt.Println("No shows specified. Defaulting to IM and TWIG.")
 			targetPrefixes["IM"] = true
-			targetPrefixes["TWIG"] = true
+			targetPrefixes[config.CanonicalPrefix("TWIG")] = true
 		} else {
 			for _, arg := range args {
-				argClean := strings.ToLower(strings.TrimSpace(arg))
-				found := false
-
-				// Check values (prefixes)
-				for _, p := range config.ShowMap {
-					if p == strings.ToUpper(argClean) {
-						targetPrefixes[p] = true
-						found = true
-						break
-					}
-				}
-				if found {
-					continue
-				}
-
-				// Check keys (names)
-				if prefix, ok := config.ShowMap[argClean]; ok {
-					targetPrefixes[prefix] = true
-					found = true
-				}
-
-				if !found {
-					fmt.Printf("Warning: Unknown show '%s'\n", arg)
+				prefix, ok := config.ResolveShow(arg)
+				if !ok {
+					fmt.Printf("Error: %v\n", config.UnknownShowError(arg))
+					return errs.ExitUsage
 				}
+				targetPrefixes[prefix] = true
 			}
 		}
 	}
@@ -86,83 +251,316 @@ func main() {
 	for p := range targetPrefixes {
 		shows = append(shows, p)
 	}
+	schedule.SortShows(shows)
 	fmt.Printf("Targeting Shows: %v\n", shows)
+	if !storage.IsRemote(dataDir) && !*fillGapsPtr && !preflight(targetPrefixes, dataDir) {
+		return errs.ExitError
+	}
 
-	stats := struct {
-		PagesScanned          int
-		PagesDownloaded       int
-		PagesCached           int
-		TranscriptsFound      int
-		TranscriptsDownloaded int
-		TranscriptsSkipped    int
-		TranscriptsIgnored    int
-	}{}
-
-	// Main Loop
-	for pageNum := 1; pageNum <= *pagesPtr; pageNum++ {
-		stats.PagesScanned++
-		fmt.Printf("--- Processing Page %d ---\n", pageNum)
-
-		html, cached, err := scraper.GetListPageWithCacheStatus(pageNum, dataDir, *refreshPtr, throttle)
-		if err != nil {
-			fmt.Printf("Failed to get content for page %d: %v. Stopping.\n", pageNum, err)
-			break
+	report := errs.NewReport("fetch-transcripts")
+	report.StopOn = strict
+	defer writeReport(report, *errorsPtr, dataDir)
+
+	if *fillGapsPtr {
+		fillGaps(shows, dataDir, filter, throttle, report)
+		return report.ExitCode()
+	}
+
+	// The site search is only worth it when a few shows are wanted
+	discovery := *discoveryPtr
+	if discovery == "auto" && *allPtr {
+		discovery = "list"
+	}
+	crawler := &scraper.Crawler{
+		Shows:          shows,
+		DataDir:        dataDir,
+		Discovery:      discovery,
+		Pages:          *pagesPtr,
+		Workers:        *listWorkersPtr,
+		Refresh:        *refreshPtr,
+		StopAfterEmpty: *stopAfterEmptyPtr,
+		StopAfterKnown: *stopAfterKnownPtr,
+		Throttle:       throttle,
+		Filter:         filter,
+		Schedule:       schedule,
+		WithMedia:      *withMediaPtr,
+		MediaBudget:    mediaBudget,
+		Report:         report,
+		Stop:           func() bool { return interrupted.Load() || deadline.Passed() },
+	}
+	crawl := crawler.Run()
+	reportUnknown(crawl.Unknown, dataDir, *addUnknownPtr)
+
+	fmt.Println("\n========================================")
+	fmt.Println("           CRAWL SUMMARY")
+	fmt.Println("========================================")
+	fmt.Printf("Pages Scanned:       %d\n", crawl.PagesScanned)
+	fmt.Printf("  - Downloaded:      %d\n", crawl.PagesDownloaded)
+	fmt.Printf("  - Cached:          %d\n", crawl.PagesCached)
+	if scraper.Bandwidth != nil {
+		fmt.Printf("Data Downloaded:     %.1f MB\n", float64(scraper.Bandwidth.Used())/(1<<20))
+	}
+	if *verbosePtr {
+		c := scraper.Connections()
+		fmt.Printf("HTTP Requests:       %d\n", c.Requests)
+		fmt.Printf("  - New Connections: %d\n", c.Opened)
+		fmt.Printf("  - Reused:          %d\n", c.Reused)
+		fmt.Printf("  - Over HTTP/2:     %d\n", c.HTTP2)
+	}
+	if p := scraper.Politeness.Stats(); p.Pushbacks+p.Slow > 0 {
+		fmt.Printf("Site Pushback:       %d rate limited, %d slow responses\n", p.Pushbacks, p.Slow)
+		fmt.Printf("  - Slowed Down:     up to %.0fx the throttle\n", p.MaxFactor)
+		fmt.Printf("  - Paused:          %d time(s), %v in all\n", p.Pauses, p.Paused)
+	}
+	t := crawl.Totals()
+	fmt.Printf("Transcripts Found:   %d\n", crawl.Found)
+	fmt.Printf("  - Downloaded:      %d\n", t.Downloaded)
+	fmt.Printf("  - Skipped (Exist): %d\n", t.Skipped)
+	fmt.Printf("  - Ignored (Type):  %d\n", crawl.Ignored)
+	fmt.Printf("  - Out of Range:    %d\n", t.Filtered)
+	fmt.Printf("  - Pending:         %d\n", t.Pending)
+	if t.Deferred > 0 {
+		fmt.Printf("  - Over Quota:      %d (%s)\n", t.Deferred, deferredShows(crawl))
+	}
+	if *withMediaPtr {
+		fmt.Printf("Media Files:         %d (%d failed, %.1f MB downloaded)\n", t.Media, t.MediaFailed, float64(mediaBudget.Used)/(1<<20))
+	}
+	fmt.Println("========================================")
+	if scraper.Quota.Full() {
+		fmt.Printf("Stopped early: the data directory reached --max-archive-size (%.1f MB). Raise the limit or free space to continue.\n", float64(scraper.Quota.Max)/(1<<20))
+	} else if scraper.Bandwidth.Exhausted() {
+		fmt.Printf("Stopped early: the --max-bytes-per-run budget (%.1f MB) was used up. Rerun to continue.\n", float64(scraper.Bandwidth.Max)/(1<<20))
+	} else if report.ShouldStop() {
+		fmt.Println("Stopped early: a --strict error class was hit.")
+	} else if interrupted.Load() {
+		fmt.Println("Stopped early: interrupted. Rerun to continue.")
+		return errs.ExitError
+	} else if deadline.Passed() {
+		fmt.Printf("Stopped early: the --max-duration of %v was reached.\n", deadline.Budget)
+		if t.Left > 0 {
+			fmt.Printf("  - %d listed transcript(s) of the target shows were not fetched\n", t.Left)
 		}
-		if cached {
-			stats.PagesCached++
-		} else {
-			stats.PagesDownloaded++
+		if len(crawl.Unsearched) > 0 {
+			fmt.Printf("  - Shows not searched: %s\n", strings.Join(crawl.Unsearched, ", "))
 		}
+		if crawl.NextPage > 0 {
+			fmt.Printf("  - Listing pages %d-%d were not scanned\n", crawl.NextPage, *pagesPtr)
+		}
+		fmt.Println("Rerun to continue; archived transcripts are skipped.")
+	}
+	return report.ExitCode()
+}
 
-		items := scraper.ExtractItems(html)
-		if len(items) == 0 {
-			fmt.Printf("No items found on page %d. Stopping.\n", pageNum)
-			break
+// deferredShows lists the shows whose quota held transcripts back, with
+// their counts
+func deferredShows(crawl *scraper.CrawlReport) string {
+	var parts []string
+	for _, s := range crawl.Shows {
+		if len(s.Deferred) > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", s.Prefix, len(s.Deferred)))
 		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// reportUnknown lists the shows in the listing that match no known show and,
+// with add, adds them to the data directory's custom shows
+func reportUnknown(unknown scraper.UnknownShows, dataDir string, add bool) {
+	if len(unknown) == 0 {
+		return
+	}
+	fmt.Println("\nUnknown shows in the listing:")
+	for _, s := range unknown.Sorted() {
+		if !add {
+			fmt.Printf("  %-40s %4d transcript(s), e.g. %q\n", s.Name, s.Count, s.Example)
+			continue
+		}
+		show := config.CustomShow{Name: s.Name, Prefix: config.GeneratePrefix(s.Name)}
+		if err := config.AddCustomShow(dataDir, show); err != nil {
+			fmt.Printf("  Error adding %s: %v\n", s.Name, err)
+			continue
+		}
+		fmt.Printf("  %-40s %4d transcript(s), added as %s\n", s.Name, s.Count, show.Prefix)
+	}
+	if add {
+		fmt.Printf("New shows are saved in %s. Fetch them by prefix on the next run.\n", config.CustomShowsFile)
+	} else {
+		fmt.Println("Run with --add-unknown to add them to the catalog.")
+	}
+}
 
-		fmt.Printf("Found %d items on page %d.\n", len(items), pageNum)
+// writeReport saves the run's failures as JSON
+func writeReport(r *errs.Report, path, dataDir string) {
+	if path == "" {
+		path = storage.Join(dataDir, errs.ReportFile)
+	}
+	if err := r.Write(path); err != nil {
+		fmt.Printf("Error writing %s: %v\n", path, err)
+		return
+	}
+	if n := r.Len(); n > 0 {
+		fmt.Printf("%d failure(s) written to %s\n", n, path)
+	}
+}
 
-		for _, item := range items {
-			stats.TranscriptsFound++
-			titleLower := strings.ToLower(item.Title)
-			var matchedPrefix string
+// stopping reports whether the run should end early, because of --strict,
+// an interrupt or because the transfer budget or archive size limit is
+// reached
+func stopping(r *errs.Report) bool {
+	return r.ShouldStop() || interrupted.Load() || scraper.Bandwidth.Exhausted() || scraper.Quota.Full() || deadline.Passed()
+}
 
-			for name, prefix := range config.ShowMap {
-				if strings.Contains(titleLower, name) {
-					matchedPrefix = prefix
-					break
-				}
-			}
+// interrupted is set once the run has been asked to stop
+var interrupted atomic.Bool
 
-			if matchedPrefix != "" {
-				if targetPrefixes[matchedPrefix] {
-					skipped, err := scraper.DownloadTranscriptWithStatus(item.URL, item.Title, matchedPrefix, dataDir, throttle)
-					if err != nil {
-						fmt.Printf("Error downloading %s: %v\n", item.Title, err)
-					} else if skipped {
-						stats.TranscriptsSkipped++
-					} else {
-						stats.TranscriptsDownloaded++
-					}
-				} else {
-					// fmt.Printf("  [IGNORE] %s\n", item.Title)
-					stats.TranscriptsIgnored++
-				}
-			} else {
-				stats.TranscriptsIgnored++
+// deadline ends the run once --max-duration has passed
+var deadline utils.Deadline
+
+// catchInterrupt makes the first SIGINT or SIGTERM end the run after the
+// download in progress, so the failure report, WARC file and lock are
+// still written and released. A second one quits at once; pages being
+// written then are left as .part files for the next run to clean up.
+func catchInterrupt() {
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		fmt.Println("\nInterrupted: stopping after the current download. Interrupt again to quit now.")
+		interrupted.Store(true)
+		<-sigs
+		os.Exit(errs.ExitError)
+	}()
+}
+
+// fail records a failure in the report, except for downloads cut off by the
+// transfer budget or archive size limit, which a later run picks up anyway
+func fail(r *errs.Report, target string, err error) {
+	if !errors.Is(err, scraper.ErrTransferBudget) && !errors.Is(err, scraper.ErrQuota) {
+		r.Add(target, err)
+	}
+}
+
+// preflight estimates the space the run needs from the cached listing pages
+// and checks it against the free disk space and --max-archive-size. It
+// returns false if there is clearly not enough disk for the run.
+func preflight(prefixes map[string]bool, dataDir string) bool {
+	est, err := scraper.EstimateFetch(prefixes, dataDir)
+	if err != nil {
+		fmt.Printf("Warning: could not estimate the space needed: %v\n", err)
+		return true
+	}
+	if est.Pages == 0 {
+		fmt.Println("Preflight: no cached listing pages yet, so the space needed cannot be estimated.")
+		return true
+	}
+	fmt.Printf("Preflight: %d transcript(s) listed in %d cached page(s) are not archived yet, about %.1f MB\n", est.Missing, est.Pages, float64(est.Bytes)/(1<<20))
+	if free, err := utils.FreeSpace(dataDir); err == nil && est.Bytes > free {
+		fmt.Printf("Error: not enough disk space for this run: about %.1f MB needed, %.1f MB free\n", float64(est.Bytes)/(1<<20), float64(free)/(1<<20))
+		return false
+	}
+	if q := scraper.Quota; q != nil && q.Used()+est.Bytes > q.Max {
+		fmt.Printf("Warning: the data directory (%.1f MB) will reach --max-archive-size (%.1f MB) before this run completes. The run will stop there.\n", float64(q.Used())/(1<<20), float64(q.Max)/(1<<20))
+	}
+	return true
+}
+
+// fillGaps searches for each show's missing episodes individually
+func fillGaps(shows []string, dataDir string, filter converter.Filter, throttle time.Duration, report *errs.Report) {
+	sort.Strings(shows)
+	var found, missing, left int
+	for _, prefix := range shows {
+		nums, err := scraper.LocalEpisodes(prefix, dataDir)
+		if err != nil {
+			fmt.Printf("Error listing %s: %v\n", prefix, err)
+			continue
+		}
+		gaps := scraper.Gaps(nums)
+		for i, ep := range gaps {
+			if stopping(report) {
+				left += len(gaps) - i
+				break
+			}
+			if !filter.MatchEpisode(ep) {
+				continue
 			}
+			source, err := scraper.FillGap(prefix, ep, dataDir, throttle)
+			if err != nil {
+				fmt.Printf("  [MISSING] %s %d: %v\n", prefix, ep, err)
+				fail(report, scraper.TranscriptURL(prefix, ep), err)
+				missing++
+				continue
+			}
+			fmt.Printf("  [FOUND]   %s %d (%s)\n", prefix, ep, source)
+			found++
 		}
 	}
+	fmt.Printf("Gap fill: %d found, %d still missing\n", found, missing)
+	if left > 0 {
+		fmt.Printf("Stopped early: %d gap(s) not tried. Rerun to continue.\n", left)
+	}
+}
 
-	fmt.Println("\n========================================")
-	fmt.Println("           CRAWL SUMMARY")
-	fmt.Println("========================================")
-	fmt.Printf("Pages Scanned:       %d\n", stats.PagesScanned)
-	fmt.Printf("  - Downloaded:      %d\n", stats.PagesDownloaded)
-	fmt.Printf("  - Cached:          %d\n", stats.PagesCached)
-	fmt.Printf("Transcripts Found:   %d\n", stats.TranscriptsFound)
-	fmt.Printf("  - Downloaded:      %d\n", stats.TranscriptsDownloaded)
-	fmt.Printf("  - Skipped (Exist): %d\n", stats.TranscriptsSkipped)
-	fmt.Printf("  - Ignored (Type):  %d\n", stats.TranscriptsIgnored)
-	fmt.Println("========================================")
+// openWARC starts recording the crawl into a WARC file. The returned
+// function finishes the file and reports how many records it holds.
+func openWARC(path string) (func(), error) {
+	f, err := storage.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := warc.NewWriter(f, warc.IsCompressed(path), "twit-transcript-archiver")
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	scraper.Archive = w
+	return func() {
+		scraper.Archive = nil
+		if err := f.Close(); err != nil {
+			fmt.Printf("Error writing WARC file: %v\n", err)
+			return
+		}
+		fmt.Printf("Recorded %d WARC records in %s\n", w.Records, path)
+	}, nil
+}
+
+// openHTTPLog starts recording the run's HTTP exchanges into a debug log.
+// The returned function finishes the file and reports how many it holds.
+func openHTTPLog(path string, bodies bool) (func(), error) {
+	f, err := storage.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := httplog.NewWriter(f, httplog.IsHAR(path), "twit-transcript-archiver/"+config.Version)
+	w.Bodies = bodies
+	scraper.HTTPLog = w
+	return func() {
+		scraper.HTTPLog = nil
+		err := w.Close()
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			fmt.Printf("Error writing HTTP debug log: %v\n", err)
+			return
+		}
+		fmt.Printf("Recorded %d HTTP exchanges in %s\n", w.Records, path)
+	}, nil
+}
+
+// openMirror starts copying downloaded pages into the site mirror at dir.
+// The returned function writes the URL table and reports the pages saved.
+func openMirror(dir string) (func(), error) {
+	m, err := scraper.OpenMirror(dir)
+	if err != nil {
+		return nil, err
+	}
+	scraper.SiteMirror = m
+	return func() {
+		scraper.SiteMirror = nil
+		if err := m.Close(); err != nil {
+			fmt.Printf("Error writing mirror table: %v\n", err)
+			return
+		}
+		fmt.Printf("Mirrored %d pages into %s (%d URLs in total)\n", m.Saved, dir, len(m.Files))
+	}, nil
 }
@@ -3,50 +3,294 @@ package main
 import (
 	"flag"
 	"fmt"
-	"path/filepath"
+	"os"
+	"sort"
 	"strings"
 
 	"github.com/aramova/twit-transcript-archiver/go/internal/config"
 	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/errs"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+	"github.com/aramova/twit-transcript-archiver/go/internal/lock"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+	"github.com/aramova/twit-transcript-archiver/go/internal/utils"
 )
 
 func main() {
+	splitByEraPtr := flag.Bool("split-by-era", false, "Keep the eras of renamed shows (e.g. TWIG before it became IM) under separate prefixes")
 	allPtr := flag.Bool("all", false, "Process ALL prefixes found in data directory")
 	byYearPtr := flag.Bool("by-year", false, "Break files up by year as well as size limits")
+	chunkByPtr := flag.String("chunk-by", "size", "Chunk grouping: size (fill chunks up to --max-words), month, year or decade (one chunk per period, however large)")
+	combinePtr := flag.String("combine", "", "Interleave the shows by date into one set of chunks under this name (e.g. NETWORK) instead of chunking each show")
+	chunkNamesPtr := flag.String("chunk-names", "compat", "Chunk file names: compat (SN_Transcripts_1-99.md), padded (SN_0001-0099_2005-2006.md) or a template of {prefix}, {start}, {end}, {year}, {years}, {from}, {to} and {lang}")
+	splitPtr := flag.String("split", "episode", "Where chunks may split: episode, turn (speaker turns) or topic (segment markers)")
+	overlapPtr := flag.Int("overlap", 0, "Speaker turns repeated when an episode continues in the next chunk")
+	stripAdsPtr := flag.Bool("strip-ads", false, "Remove detected sponsor reads from the output")
+	markAdsPtr := flag.Bool("mark-ads", false, "Wrap detected sponsor reads in [Ad Segment Start]/[Ad Segment End] markers")
+	episodeTmplPtr := flag.String("episode-template", "", "Go text/template file for rendering each episode (default: built-in)")
+	chunkTmplPtr := flag.String("chunk-template", "", "Go text/template file wrapping each chunk file (default: built-in)")
+	perEpisodePtr := flag.Bool("per-episode", false, "Also write each episode to its own Markdown file")
+	frontMatterPtr := flag.Bool("front-matter", false, "Start each per-episode file with YAML front matter (implies --per-episode)")
+	nonEnglishPtr := flag.String("non-english", "keep", "Episodes detected as another language: keep, exclude (from chunks) or separate (into per-language chunks)")
+	redactPtr := flag.String("redact", "", "Mask text matching the rules in this file (words, /regexes/, @email, @phone) in the output")
+	redactPIIPtr := flag.Bool("redact-pii", false, "Mask email addresses and phone numbers in the output")
+	redactWithPtr := flag.String("redact-with", converter.DefaultRedaction, "Text that replaces redacted matches")
+	asciiQuotesPtr := flag.Bool("ascii-quotes", false, "Replace curly quotes, dashes, ellipses and non-breaking spaces with plain ASCII")
+	noNormalizePtr := flag.Bool("no-normalize", false, "Keep text as published: do not compose accents (NFC) or strip control characters")
+	noTOCPtr := flag.Bool("no-toc", false, "Do not start chunk files with a table of contents and episode anchors (e.g. for LLM input)")
+	showNotesPtr := flag.Bool("show-notes", false, "Append each episode's show notes and related links after its transcript")
+	timestampsPtr := flag.String("timestamps", "keep", "Timecodes in the output: keep, normalize (HH:MM:SS) or strip")
+	maxWordsPtr := flag.Int("max-words", converter.MaxWords, "Maximum words per chunk file")
+	episodesPtr := flag.String("episodes", "", "Only process this episode range (e.g. 900-950, 900-, -950)")
+	sincePtr := flag.String("since", "", "Only process episodes published on or after this date (YYYY-MM-DD)")
+	untilPtr := flag.String("until", "", "Only process episodes published on or before this date (YYYY-MM-DD)")
+	ifOutdatedPtr := flag.Bool("if-outdated", false, "Only process shows whose chunks are missing, incomplete or written by an older converter")
+	waitPtr := flag.Duration("wait", 0, "If another run is using the data directory, wait this long for it to finish instead of failing")
+	keepUnparseablePtr := flag.Bool("keep-unparseable", false, "Leave files that are not transcripts (no title or body) in place instead of moving them to quarantine/")
+	maxDurationPtr := flag.Duration("max-duration", 0, "Stop cleanly once the run has taken this long (e.g. 30m): the show in progress is finished and the rest are left for the next run")
+	errorsPtr := flag.String("errors", "", "Where to write the JSON report of files that failed (default: errors.json in the data directory)")
+	strict := errs.Classes{}
+	flag.Var(strict, "strict", "Stop at the first failure of these classes (comma-separated; all if given without a value)")
 	// prefixes via args
 
-	flag.Parse()
+	args, _ := utils.ParseFlags(flag.CommandLine, os.Args[1:])
+	if _, err := config.LoadSettings(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(errs.ExitUsage)
+	}
+	if err := config.Loaded.Apply(flag.CommandLine, "process-transcripts"); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(errs.ExitUsage)
+	}
+	if len(args) == 0 {
+		args = config.Loaded.Shows()
+	}
+	config.SplitByEra = *splitByEraPtr
+	deadline := utils.NewDeadline(*maxDurationPtr)
+
+	mode, err := converter.ParseChunkMode(*splitPtr)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(errs.ExitUsage)
+	}
+	filter, err := converter.NewFilter(*episodesPtr, *sincePtr, *untilPtr)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(errs.ExitUsage)
+	}
+	chunkBy, err := converter.ParseChunkGroup(*chunkByPtr)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(errs.ExitUsage)
+	}
+	chunkName, err := converter.ParseChunkName(*chunkNamesPtr)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(errs.ExitUsage)
+	}
+	timestamps, err := converter.ParseTimestampMode(*timestampsPtr)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(errs.ExitUsage)
+	}
+	converter.Normalize = converter.Normalization{
+		Compose:      !*noNormalizePtr,
+		StripControl: !*noNormalizePtr,
+		ASCIIQuotes:  *asciiQuotesPtr,
+	}
+	if err := converter.SetupRedaction(*redactPtr, *redactPIIPtr, *redactWithPtr); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(errs.ExitUsage)
+	}
+	nonEnglish, err := converter.ParseLanguageMode(*nonEnglishPtr)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(errs.ExitUsage)
+	}
+	templates, err := converter.LoadTemplates(*episodeTmplPtr, *chunkTmplPtr)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(errs.ExitUsage)
+	}
+	ads := converter.AdsKeep
+	if *stripAdsPtr {
+		ads = converter.AdsStrip
+	} else if *markAdsPtr {
+		ads = converter.AdsMark
+	}
+	opts := converter.Options{
+		Ads:         ads,
+		ByYear:      *byYearPtr,
+		ChunkBy:     chunkBy,
+		ChunkName:   chunkName,
+		Filter:      filter,
+		Timestamps:  timestamps,
+		ShowNotes:   *showNotesPtr,
+		TOC:         !*noTOCPtr,
+		NonEnglish:  nonEnglish,
+		Templates:   templates,
+		PerEpisode:  *perEpisodePtr || *frontMatterPtr,
+		FrontMatter: *frontMatterPtr,
+		Mode:        mode,
+		Overlap:     *overlapPtr,
+		MaxWords:    *maxWordsPtr,
+		Report:      errs.NewReport("process-transcripts"),
+		Quarantine:  !*keepUnparseablePtr && !config.ReadOnly,
+		Stop:        deadline.Passed,
+	}
+	opts.Report.StopOn = strict
 
 	dataDir := config.GetDataDir()
+	if err := config.LoadCustomShows(dataDir); err != nil {
+		fmt.Printf("Warning: could not load custom shows: %v\n", err)
+	}
+
+	if opts.FrontMatter {
+		// Tags and summaries come from the index, as built by
+		// 'twit-archiver tag' and 'twit-archiver summarize'
+		ix, err := index.Load(dataDir)
+		if err != nil {
+			fmt.Printf("Warning: could not load index, front matter will have no tags: %v\n", err)
+		} else {
+			opts.Tags = make(map[string][]string)
+			opts.Summaries = make(map[string]*converter.Summary)
+			for key, e := range ix.Entries {
+				opts.Tags[key] = e.Tags
+				if e.Summary != nil {
+					opts.Summaries[key] = e.Summary
+				}
+			}
+		}
+	}
 
 	prefixesToProcess := make(map[string]bool)
 
 	if *allPtr {
-		files, _ := filepath.Glob(filepath.Join(dataDir, "*_*.html"))
-		re := config.PrefixRegex
-		for _, f := range files {
-			base := filepath.Base(f)
-			matches := re.FindStringSubmatch(base)
-			if len(matches) > 1 {
-				prefixesToProcess[matches[1]] = true
-			}
+		prefixes, _ := converter.ListPrefixes(dataDir)
+		for _, p := range prefixes {
+			prefixesToProcess[p] = true
 		}
 	} else {
-		args := flag.Args()
 		if len(args) == 0 {
 			fmt.Println("No prefixes specified. Defaulting to IM and TWIG.")
 			prefixesToProcess["IM"] = true
-			prefixesToProcess["TWIG"] = true
+			prefixesToProcess[config.CanonicalPrefix("TWIG")] = true
 		} else {
 			for _, arg := range args {
-				prefixesToProcess[strings.ToUpper(arg)] = true
+				prefix, err := converter.ResolvePrefix(arg, dataDir)
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(errs.ExitUsage)
+				}
+				prefixesToProcess[prefix] = true
 			}
 		}
 	}
 
-	for prefix := range prefixesToProcess {
-		if err := converter.ProcessPrefix(prefix, dataDir, dataDir, *byYearPtr); err != nil {
+	var l *lock.Lock
+	if !config.ReadOnly {
+		if l, err = lock.Acquire(dataDir, "process-transcripts", *waitPtr); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(errs.ExitLocked)
+		}
+	}
+
+	if *ifOutdatedPtr {
+		if *combinePtr != "" {
+			if upToDate(strings.ToUpper(*combinePtr), dataDir) {
+				prefixesToProcess = nil
+			}
+		} else {
+			for prefix := range prefixesToProcess {
+				if upToDate(prefix, dataDir) {
+					delete(prefixesToProcess, prefix)
+				}
+			}
+		}
+	}
+	if *combinePtr != "" && len(prefixesToProcess) > 0 {
+		prefixes := make([]string, 0, len(prefixesToProcess))
+		for p := range prefixesToProcess {
+			prefixes = append(prefixes, p)
+		}
+		sort.Strings(prefixes)
+		res, err := converter.ProcessCombinedResult(*combinePtr, prefixes, dataDir, dataDir, opts)
+		if err != nil {
+			fmt.Printf("Error combining %s: %v\n", *combinePtr, err)
+			opts.Report.Add(*combinePtr, err)
+		}
+		printResult(res)
+		if res != nil && res.Stopped {
+			fmt.Printf("Stopped early: the --max-duration of %v was reached part way through %s. Rerun to continue.\n", deadline.Budget, *combinePtr)
+		}
+		prefixesToProcess = nil
+	}
+	prefixes := make([]string, 0, len(prefixesToProcess))
+	for p := range prefixesToProcess {
+		prefixes = append(prefixes, p)
+	}
+	sort.Strings(prefixes)
+	for i, prefix := range prefixes {
+		if opts.Report.ShouldStop() {
+			fmt.Println("Stopped early: a --strict error class was hit.")
+			break
+		}
+		if deadline.Passed() {
+			fmt.Printf("Stopped early: the --max-duration of %v was reached. Not processed: %s. Rerun to continue.\n", deadline.Budget, strings.Join(prefixes[i:], ", "))
+			break
+		}
+		res, err := converter.ProcessPrefixResult(prefix, dataDir, dataDir, opts)
+		if err != nil {
 			fmt.Printf("Error processing prefix %s: %v\n", prefix, err)
+			opts.Report.Add(prefix, err)
+		}
+		printResult(res)
+		if res != nil && res.Stopped {
+			fmt.Printf("Stopped early: the --max-duration of %v was reached part way through %s. Not processed: %s. Rerun to continue.\n", deadline.Budget, prefix, strings.Join(prefixes[i:], ", "))
+			break
 		}
 	}
+	writeReport(opts.Report, *errorsPtr, dataDir)
+	l.Release()
+	os.Exit(opts.Report.ExitCode())
+}
+
+// printResult sums up what processing a show did
+func printResult(r *converter.Result) {
+	if r == nil || r.Files == 0 {
+		return
+	}
+	fmt.Printf("%s: %d processed, %d skipped, %d failed; %d chunks (%d changed", r.Prefix, len(r.Processed), len(r.Skipped), len(r.Failed), len(r.Chunks), r.Changed())
+	if len(r.Removed) > 0 {
+		fmt.Printf(", %d stale removed", len(r.Removed))
+	}
+	fmt.Println(")")
+}
+
+// upToDate reports whether a show's (or combined set's) chunks were written
+// by a complete run of the current converter, for --if-outdated
+func upToDate(set, dataDir string) bool {
+	outdated, version, err := converter.Outdated(set, dataDir)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(errs.ExitError)
+	}
+	if !outdated {
+		fmt.Printf("%s is up to date (converter version %d).\n", set, version)
+	}
+	return !outdated
+}
+
+// writeReport saves the run's failures as JSON
+func writeReport(r *errs.Report, path, dataDir string) {
+	if path == "" {
+		path = storage.Join(dataDir, errs.ReportFile)
+	}
+	if err := r.Write(path); err != nil {
+		fmt.Printf("Error writing %s: %v\n", path, err)
+		return
+	}
+	if n := r.Len(); n > 0 {
+		fmt.Printf("%d failure(s) written to %s\n", n, path)
+	}
 }
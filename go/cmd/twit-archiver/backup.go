@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/backup"
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+func runBackup(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	outPtr := fs.String("out", "", "Backup file to write (default: backups/backup-TIMESTAMP.tar.gz in the data directory)")
+	listPtr := fs.Bool("list", false, "List the backups in the data directory instead")
+	parseFlags(fs, args)
+
+	dataDir := config.GetDataDir()
+	if *listPtr {
+		files, err := backup.List(dataDir)
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			fmt.Println(f)
+		}
+		if len(files) == 0 {
+			fmt.Printf("No backups in %s\n", storage.Join(dataDir, backup.Dir))
+		}
+		return nil
+	}
+
+	unlock, err := lockDataDir(fs.Name())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	path, m, err := backup.Create(dataDir, *outPtr)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Backed up %d files to %s\n", len(m.Files), path)
+	return nil
+}
+
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	dryRunPtr := fs.Bool("dry-run", false, "Only report which files the backup would replace")
+	noBackupPtr := fs.Bool("no-backup", false, "Do not back up the current state files before replacing them")
+	fs.Usage = func() {
+		fmt.Println("Usage: twit-archiver restore [flags] [backup file]")
+		fmt.Println("\nRestores the index, chunk manifests and configuration from a backup,")
+		fmt.Println("by default the newest in the data directory's backups directory.")
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+	if fs.NArg() > 1 {
+		return fmt.Errorf("usage: twit-archiver restore [flags] [backup file]")
+	}
+
+	dataDir := config.GetDataDir()
+	path := fs.Arg(0)
+	if path == "" {
+		latest, err := backup.Latest(dataDir)
+		if err != nil {
+			return err
+		}
+		path = latest
+	}
+	m, files, err := backup.Read(path)
+	if err != nil {
+		return err
+	}
+	changes, err := backup.Compare(dataDir, files)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Backup %s: %d files, taken %s (layout %s, converter version %d)\n",
+		path, len(m.Files), m.Created.Format("2006-01-02 15:04:05 MST"), m.Layout, m.Converter)
+	if m.Layout != config.ActiveLayout.Name {
+		fmt.Printf("Warning: the archive now uses the %s layout; chunk manifests are restored to the backup's paths\n", config.ActiveLayout.Name)
+	}
+	replaced := 0
+	for _, c := range changes {
+		if c.Kind != backup.Unchanged {
+			replaced++
+			fmt.Printf("  %-9s %s\n", c.Kind, c.Path)
+		}
+	}
+	if replaced == 0 {
+		fmt.Println("The state files match the backup; nothing to restore.")
+		return nil
+	}
+	if *dryRunPtr {
+		return nil
+	}
+
+	unlock, err := lockDataDir(fs.Name())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if !*noBackupPtr {
+		saved, _, err := backup.Create(dataDir, "")
+		if err != nil {
+			return fmt.Errorf("backing up the current state: %w", err)
+		}
+		fmt.Printf("Current state backed up to %s\n", saved)
+	}
+	written, err := backup.Restore(dataDir, files)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Restored %d files\n", len(written))
+	return nil
+}
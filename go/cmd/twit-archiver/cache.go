@@ -0,0 +1,181 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/scraper"
+)
+
+func runCache(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: twit-archiver cache status|clear|prune [flags]")
+	}
+	switch args[0] {
+	case "status":
+		return cacheStatus(args[1:])
+	case "clear":
+		return cacheClear(args[1:])
+	case "prune":
+		return cachePrune(args[1:])
+	}
+	return fmt.Errorf("unknown cache operation '%s' (want status, clear or prune)", args[0])
+}
+
+func cacheStatus(args []string) error {
+	fs := flag.NewFlagSet("cache status", flag.ExitOnError)
+	parseFlags(fs, args)
+
+	dataDir := config.GetDataDir()
+	pages, err := scraper.CachedListPages(dataDir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%-10s %8s %10s  %-10s  %s\n", "CACHE", "FILES", "SIZE", "OLDEST", "NEWEST")
+	printCacheLine("lists", pages)
+	prefixes, err := converter.ListPrefixes(dataDir)
+	if err != nil {
+		return err
+	}
+	for _, prefix := range prefixes {
+		files, err := scraper.CachedTranscripts(dataDir, prefix)
+		if err != nil {
+			return err
+		}
+		printCacheLine(prefix, files)
+	}
+	fmt.Printf("\nData directory:  %s\nList pages in:   %s\n", dataDir, config.ListPageDir(dataDir))
+	fmt.Println("List pages are refreshed according to fetch-transcripts --cache-ttl; transcripts are kept until cleared.")
+	return nil
+}
+
+func printCacheLine(name string, files []scraper.CachedFile) {
+	var oldest, newest time.Time
+	for _, f := range files {
+		if f.Fetched.IsZero() {
+			continue
+		}
+		if oldest.IsZero() || f.Fetched.Before(oldest) {
+			oldest = f.Fetched
+		}
+		if f.Fetched.After(newest) {
+			newest = f.Fetched
+		}
+	}
+	day := func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Format("2006-01-02")
+	}
+	fmt.Printf("%-10s %8d %8.1f MB  %-10s  %s\n", name, len(files), float64(scraper.CacheSize(files))/(1<<20), day(oldest), day(newest))
+}
+
+func cacheClear(args []string) error {
+	fs := flag.NewFlagSet("cache clear", flag.ExitOnError)
+	listPagesPtr := fs.Bool("list-pages", false, "Remove the cached transcript listing pages")
+	showPtr := fs.String("show", "", "Remove the archived transcript HTML of this show (e.g. SN)")
+	dryRunPtr := fs.Bool("dry-run", false, "Only report what would be removed")
+	parseFlags(fs, args)
+	unlock, err := lockDataDir(fs.Name())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if !*listPagesPtr && *showPtr == "" {
+		return fmt.Errorf("nothing to clear: give --list-pages and/or --show")
+	}
+	dataDir := config.GetDataDir()
+	if *listPagesPtr {
+		pages, err := scraper.CachedListPages(dataDir)
+		if err != nil {
+			return err
+		}
+		if err := removeCached(dataDir, "list pages", pages, *dryRunPtr); err != nil {
+			return err
+		}
+	}
+	if *showPtr != "" {
+		prefix, err := converter.ResolvePrefix(*showPtr, dataDir)
+		if err != nil {
+			return err
+		}
+		files, err := scraper.CachedTranscripts(dataDir, prefix)
+		if err != nil {
+			return err
+		}
+		if err := removeCached(dataDir, prefix+" transcripts", files, *dryRunPtr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func cachePrune(args []string) error {
+	fs := flag.NewFlagSet("cache prune", flag.ExitOnError)
+	olderThanPtr := fs.String("older-than", "", "Remove files fetched longer ago than this (e.g. 90d, 12h)")
+	transcriptsPtr := fs.Bool("transcripts", false, "Also prune archived transcript HTML, not just list pages")
+	dryRunPtr := fs.Bool("dry-run", false, "Only report what would be removed")
+	parseFlags(fs, args)
+	unlock, err := lockDataDir(fs.Name())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if *olderThanPtr == "" {
+		return fmt.Errorf("--older-than is required")
+	}
+	age, err := scraper.ParseAge(*olderThanPtr)
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-age)
+
+	dataDir := config.GetDataDir()
+	pages, err := scraper.CachedListPages(dataDir)
+	if err != nil {
+		return err
+	}
+	if err := removeCached(dataDir, "list pages", scraper.FetchedBefore(pages, cutoff), *dryRunPtr); err != nil {
+		return err
+	}
+	if !*transcriptsPtr {
+		return nil
+	}
+	prefixes, err := converter.ListPrefixes(dataDir)
+	if err != nil {
+		return err
+	}
+	for _, prefix := range prefixes {
+		files, err := scraper.CachedTranscripts(dataDir, prefix)
+		if err != nil {
+			return err
+		}
+		if err := removeCached(dataDir, prefix+" transcripts", scraper.FetchedBefore(files, cutoff), *dryRunPtr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func removeCached(dataDir, what string, files []scraper.CachedFile, dryRun bool) error {
+	size := float64(scraper.CacheSize(files)) / (1 << 20)
+	if dryRun {
+		for _, f := range files {
+			fmt.Printf("Would remove %s\n", f.Path)
+		}
+		fmt.Printf("%s: would remove %d file(s), %.1f MB\n", what, len(files), size)
+		return nil
+	}
+	n, dropped, err := scraper.RemoveCached(dataDir, files)
+	fmt.Printf("%s: removed %d file(s), %.1f MB\n", what, n, size)
+	if dropped > 0 {
+		fmt.Printf("%s: dropped %d index entries (tags and summaries included); the next fetch downloads them again\n", what, dropped)
+	}
+	return err
+}
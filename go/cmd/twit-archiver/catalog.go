@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/scraper"
+)
+
+func runCatalog(args []string) error {
+	fs := flag.NewFlagSet("catalog", flag.ExitOnError)
+	showPtr := fs.String("show", "", "Show prefix to list (e.g. SN)")
+	pagesPtr := fs.Int("pages", 200, "Number of listing pages to scan")
+	refreshPtr := fs.Bool("refresh-list", false, "Re-download cached listing pages")
+	cacheTTLPtr := fs.String("cache-ttl", "", "How long cached listing pages stay fresh (as for fetch-transcripts)")
+	missingPtr := fs.Bool("missing", false, "Only print episodes missing locally or upstream")
+	throttlePtr := fs.Duration("throttle", 1*time.Second, "Duration to wait between requests")
+	parseFlags(fs, args)
+	unlock, err := lockDataDir(fs.Name())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if *showPtr == "" {
+		return fmt.Errorf("--show is required")
+	}
+	prefix, ok := config.ResolveShow(*showPtr)
+	if !ok {
+		return config.UnknownShowError(*showPtr)
+	}
+	if *cacheTTLPtr != "" {
+		policy, err := scraper.ParseCachePolicy(*cacheTTLPtr)
+		if err != nil {
+			return err
+		}
+		scraper.ListCache = policy
+	}
+
+	dataDir := config.GetDataDir()
+	remote, err := scraper.ListRemote(prefix, dataDir, *pagesPtr, *refreshPtr, *throttlePtr)
+	if err != nil {
+		return err
+	}
+	local, err := scraper.LocalEpisodes(prefix, dataDir)
+	if err != nil {
+		return err
+	}
+
+	var both, missingLocal, missingUpstream int
+	for _, e := range scraper.Catalog(remote, local) {
+		status := "ok"
+		switch {
+		case !e.Local:
+			status = "MISSING LOCALLY"
+			missingLocal++
+		case !e.Upstream:
+			status = "MISSING UPSTREAM"
+			missingUpstream++
+		default:
+			both++
+		}
+		if *missingPtr && status == "ok" {
+			continue
+		}
+		fmt.Printf("%-6s %5d  %-16s  %s\n", prefix, e.Number, status, e.Title)
+	}
+	fmt.Printf("%s: %d listed upstream, %d archived locally; %d in both, %d missing locally, %d missing upstream\n",
+		prefix, len(remote), len(local), both, missingLocal, missingUpstream)
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/chapters"
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/scraper"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+func runChapters(args []string) error {
+	fs := flag.NewFlagSet("chapters", flag.ExitOnError)
+	feedPtr := fs.String("feed", "", "RSS feed URL or file (default: the show's TWiT feed; one show only)")
+	formatPtr := fs.String("format", "md,json", "Comma-separated outputs: md, json")
+	outPtr := fs.String("out", "", "Output directory (default: chapters in the data directory)")
+	throttlePtr := fs.Duration("throttle", 1*time.Second, "Duration to wait between requests")
+	parseFlags(fs, args)
+
+	formats := map[string]bool{}
+	for _, f := range strings.Split(*formatPtr, ",") {
+		if f != "md" && f != "json" {
+			return fmt.Errorf("unknown --format '%s' (want md or json)", f)
+		}
+		formats[f] = true
+	}
+
+	dataDir := config.GetDataDir()
+	prefixes, err := resolvePrefixes(dataDir, fs.Args())
+	if err != nil {
+		return err
+	}
+	if *feedPtr != "" && len(prefixes) != 1 {
+		return fmt.Errorf("--feed needs exactly one show")
+	}
+	out := outPath(*outPtr, dataDir, "chapters")
+
+	for _, prefix := range prefixes {
+		feed := *feedPtr
+		if feed == "" {
+			feed = chapters.FeedURL(prefix)
+		}
+		data, err := readSource(feed, *throttlePtr)
+		if err != nil {
+			return fmt.Errorf("%s feed: %v", prefix, err)
+		}
+		items, err := chapters.ParseFeed([]byte(data))
+		if err != nil {
+			return fmt.Errorf("%s feed: %v", prefix, err)
+		}
+		byEpisode := make(map[int]chapters.Item)
+		for _, it := range items {
+			if it.Episode > 0 && (len(it.Chapters) > 0 || it.ChaptersURL != "") {
+				byEpisode[it.Episode] = it
+			}
+		}
+
+		eps, err := loadEpisodes(dataDir, []string{prefix})
+		if err != nil {
+			return err
+		}
+		aligned, untimed := 0, 0
+		for _, ep := range eps {
+			it, ok := byEpisode[ep.Number]
+			if !ok {
+				continue
+			}
+			chs := it.Chapters
+			if len(chs) == 0 {
+				data, err := readSource(it.ChaptersURL, *throttlePtr)
+				if err == nil {
+					chs, err = chapters.ParseJSON([]byte(data))
+				}
+				if err != nil {
+					fmt.Printf("Warning: %s %d chapters: %v\n", prefix, ep.Number, err)
+					continue
+				}
+			}
+			c, timed := chapters.Align(ep, chs)
+			if timed == 0 {
+				untimed++
+				continue
+			}
+			base := storage.Join(out, prefix, fmt.Sprintf("%s_%d", prefix, ep.Number))
+			if formats["md"] {
+				if err := storage.WriteFile(base+".md", []byte(c.Markdown())); err != nil {
+					return err
+				}
+			}
+			if formats["json"] {
+				js, err := json.MarshalIndent(c, "", "  ")
+				if err != nil {
+					return err
+				}
+				if err := storage.WriteFile(base+".json", append(js, '\n')); err != nil {
+					return err
+				}
+			}
+			aligned++
+		}
+		fmt.Printf("%s: aligned %d episodes with %d chaptered feed items (%d without timecodes skipped)\n", prefix, aligned, len(byEpisode), untimed)
+	}
+	return nil
+}
+
+// readSource reads a URL, or else a local file
+func readSource(src string, throttle time.Duration) (string, error) {
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		return scraper.DownloadPage(src, throttle)
+	}
+	data, err := os.ReadFile(src)
+	return string(data), err
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+	"github.com/aramova/twit-transcript-archiver/go/internal/transcribe"
+	"github.com/aramova/twit-transcript-archiver/go/internal/utils"
+)
+
+func runCompare(args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	hotspotsPtr := fs.Int("hotspots", 5, "Stretches where the transcripts differ most to show per episode")
+	outPtr := fs.String("out", "", "Also write the comparisons to this JSON file")
+	prefixArgs, err := utils.ParseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	applySettings(fs)
+
+	dataDir := config.GetDataDir()
+	prefixes, err := resolvePrefixes(dataDir, prefixArgs)
+	if err != nil {
+		return err
+	}
+	var all []transcribe.Comparison
+	for _, prefix := range prefixes {
+		outputs, err := transcribe.Outputs(dataDir, prefix)
+		if err != nil {
+			return err
+		}
+		var eps []int
+		for ep := range outputs {
+			eps = append(eps, ep)
+		}
+		sort.Ints(eps)
+
+		refWords, wrong := 0, 0
+		for _, ep := range eps {
+			c, err := transcribe.CompareEpisode(dataDir, prefix, ep, *hotspotsPtr)
+			if errors.Is(err, transcribe.ErrSameSource) {
+				continue
+			}
+			if err != nil {
+				fmt.Printf("Warning: %s %d: %v\n", prefix, ep, err)
+				continue
+			}
+			all = append(all, c)
+			refWords += c.RefWords
+			wrong += c.Substitutions + c.Deletions + c.Insertions
+			printComparison(c)
+		}
+		if refWords > 0 {
+			fmt.Printf("%s: word error rate %.1f%% over %d published words\n\n", prefix, 100*float64(wrong)/float64(refWords), refWords)
+		}
+	}
+	if len(all) == 0 {
+		fmt.Println("No episodes with both a published transcript and speech recognition output. Run 'twit-archiver transcribe --compare' first.")
+		return nil
+	}
+	if *outPtr != "" {
+		data, err := json.MarshalIndent(all, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := storage.WriteFile(*outPtr, data); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %d comparisons to %s\n", len(all), *outPtr)
+	}
+	return nil
+}
+
+// printComparison prints an episode's error rate and its hotspots
+func printComparison(c transcribe.Comparison) {
+	fmt.Printf("%s %d: %d published / %d asr words, word error rate %.1f%% (%d substituted, %d deleted, %d inserted)\n",
+		c.Prefix, c.Number, c.RefWords, c.HypWords, 100*c.WER, c.Substitutions, c.Deletions, c.Insertions)
+	for _, h := range c.Hotspots {
+		fmt.Printf("  %d errors at %s / %s\n", h.Errors, orDash(h.RefTime), orDash(h.HypTime))
+		fmt.Printf("    published: %s\n", orDash(h.Ref))
+		fmt.Printf("    asr:       %s\n", orDash(h.Hyp))
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
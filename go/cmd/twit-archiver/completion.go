@@ -0,0 +1,119 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+)
+
+// completedTools are the binaries whose show arguments get completion
+var completedTools = []string{"twit-archiver", "fetch-transcripts", "process-transcripts"}
+
+func runCompletion(args []string) error {
+	fs := flag.NewFlagSet("completion", flag.ExitOnError)
+	parseFlags(fs, args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: twit-archiver completion <bash|zsh|fish>")
+	}
+
+	shows := config.Shows()
+	switch fs.Arg(0) {
+	case "bash":
+		fmt.Print(bashCompletion(shows))
+	case "zsh":
+		fmt.Print(zshCompletion(shows))
+	case "fish":
+		fmt.Print(fishCompletion(shows))
+	default:
+		return fmt.Errorf("unknown shell '%s' (want bash, zsh or fish)", fs.Arg(0))
+	}
+	return nil
+}
+
+func showPrefixes(shows []config.Show) string {
+	var prefixes []string
+	for _, s := range shows {
+		prefixes = append(prefixes, s.Prefix)
+	}
+	return strings.Join(prefixes, " ")
+}
+
+// bashCompletion completes subcommands, then show prefixes
+func bashCompletion(shows []config.Show) string {
+	var names []string
+	for _, c := range commands {
+		names = append(names, c.Name)
+	}
+	var b strings.Builder
+	b.WriteString("# twit-archiver bash completion. Load with: source <(twit-archiver completion bash)\n")
+	fmt.Fprintf(&b, "_twit_shows=\"%s\"\n\n", showPrefixes(shows))
+	b.WriteString("_twit_archiver() {\n")
+	b.WriteString("    local cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	b.WriteString("    if [ \"$COMP_CWORD\" -eq 1 ]; then\n")
+	fmt.Fprintf(&b, "        COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n", strings.Join(names, " "))
+	b.WriteString("    else\n")
+	b.WriteString("        COMPREPLY=($(compgen -W \"$_twit_shows\" -- \"${cur^^}\"))\n")
+	b.WriteString("    fi\n")
+	b.WriteString("}\n\n")
+	b.WriteString("_twit_shows_only() {\n")
+	b.WriteString("    local cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	b.WriteString("    COMPREPLY=($(compgen -W \"$_twit_shows\" -- \"${cur^^}\"))\n")
+	b.WriteString("}\n\n")
+	b.WriteString("complete -o default -F _twit_archiver twit-archiver\n")
+	for _, tool := range completedTools[1:] {
+		fmt.Fprintf(&b, "complete -o default -F _twit_shows_only %s\n", tool)
+	}
+	return b.String()
+}
+
+// zshCompletion completes subcommands, then show prefixes with their names
+func zshCompletion(shows []config.Show) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "#compdef %s\n", strings.Join(completedTools, " "))
+	b.WriteString("# twit-archiver zsh completion. Load with: source <(twit-archiver completion zsh)\n\n")
+	b.WriteString("_twit_shows() {\n    local -a shows\n    shows=(\n")
+	for _, s := range shows {
+		fmt.Fprintf(&b, "        %s\n", zshQuote(s.Prefix+":"+s.Name))
+	}
+	b.WriteString("    )\n    _describe 'show' shows\n}\n\n")
+	b.WriteString("_twit_archiver() {\n    local -a cmds\n    cmds=(\n")
+	for _, c := range commands {
+		fmt.Fprintf(&b, "        %s\n", zshQuote(c.Name+":"+c.Description))
+	}
+	b.WriteString("    )\n")
+	b.WriteString("    if (( CURRENT == 2 )); then\n        _describe 'command' cmds\n    else\n        _twit_shows\n    fi\n}\n\n")
+	b.WriteString("compdef _twit_archiver twit-archiver\n")
+	for _, tool := range completedTools[1:] {
+		fmt.Fprintf(&b, "compdef _twit_shows %s\n", tool)
+	}
+	return b.String()
+}
+
+// fishCompletion completes subcommands, then show prefixes with descriptions
+func fishCompletion(shows []config.Show) string {
+	var b strings.Builder
+	b.WriteString("# twit-archiver fish completion. Load with: twit-archiver completion fish | source\n")
+	for _, c := range commands {
+		fmt.Fprintf(&b, "complete -c twit-archiver -f -n __fish_use_subcommand -a %s -d %s\n", c.Name, fishQuote(c.Description))
+	}
+	for _, tool := range completedTools {
+		cond := ""
+		if tool == "twit-archiver" {
+			cond = " -n 'not __fish_use_subcommand'"
+		}
+		for _, s := range shows {
+			fmt.Fprintf(&b, "complete -c %s%s -a %s -d %s\n", tool, cond, s.Prefix, fishQuote(s.Name))
+		}
+	}
+	return b.String()
+}
+
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
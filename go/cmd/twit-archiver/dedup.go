@@ -0,0 +1,175 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+	"github.com/aramova/twit-transcript-archiver/go/internal/transcribe"
+)
+
+func runDedup(args []string) error {
+	fs := flag.NewFlagSet("dedup", flag.ExitOnError)
+	preferPtr := fs.String("prefer", strings.Join(config.Precedence, ","), "Kinds of copy in order of preference: site, wayback, feed, captions, asr")
+	listPtr := fs.Bool("list", false, "List every copy of the episodes that have alternatives")
+	dryRunPtr := fs.Bool("dry-run", false, "Only print which copies would become the episodes' transcripts")
+	parseFlags(fs, args)
+	if err := config.SetPrecedence(*preferPtr); err != nil {
+		return err
+	}
+	if !*listPtr && !*dryRunPtr {
+		unlock, err := lockDataDir(fs.Name())
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	dataDir := config.GetDataDir()
+	prefixes, err := resolvePrefixes(dataDir, fs.Args())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Preferring %s\n", strings.Join(config.Precedence, " > "))
+	swapped := 0
+	for _, prefix := range prefixes {
+		alts, err := converter.Alternatives(dataDir, prefix)
+		if err != nil {
+			return err
+		}
+		// Kept recognizer output counts as an alternative too
+		outputs, err := transcribe.Outputs(dataDir, prefix)
+		if err != nil {
+			return err
+		}
+		for ep, out := range outputs {
+			alts[ep] = append(alts[ep], converter.Copy{Path: out, Kind: converter.OriginASR})
+		}
+		var eps []int
+		for ep := range alts {
+			eps = append(eps, ep)
+		}
+		sort.Ints(eps)
+
+		for _, ep := range eps {
+			page, cur := currentCopy(dataDir, prefix, ep)
+			best := cur
+			for _, c := range alts[ep] {
+				if best == nil || c.Outranks(*best) {
+					c := c
+					best = &c
+				}
+			}
+			if *listPtr {
+				printCopies(prefix, ep, cur, alts[ep])
+				continue
+			}
+			if best == cur || (cur == nil && best.Kind == converter.OriginASR && !strings.HasSuffix(best.Path, ".html")) {
+				continue // Recognizer output needs a page to take the title from
+			}
+			from := "none"
+			if cur != nil {
+				from = cur.Kind
+			}
+			fmt.Printf("%s %d: %s copy replaces %s\n", prefix, ep, best.Kind, from)
+			swapped++
+			if *dryRunPtr {
+				continue
+			}
+			if err := promote(dataDir, prefix, ep, page, cur != nil, *best); err != nil {
+				return fmt.Errorf("%s %d: %w", prefix, ep, err)
+			}
+		}
+	}
+	if !*listPtr {
+		fmt.Printf("%d episode(s) with a better copy\n", swapped)
+	}
+	return nil
+}
+
+// currentCopy finds an episode's transcript page, under any of the show's
+// prefixes, and describes it; nil for a missing page or a placeholder
+func currentCopy(dataDir, prefix string, ep int) (string, *converter.Copy) {
+	page := ""
+	for _, p := range config.EraPrefixes(prefix) {
+		path := storage.Join(config.ActiveLayout.RawDir(dataDir, p), fmt.Sprintf("%s_%d.html", p, ep))
+		if storage.Exists(path) {
+			page = path
+			break
+		}
+	}
+	if page == "" {
+		return storage.Join(config.ActiveLayout.RawDir(dataDir, prefix), fmt.Sprintf("%s_%d.html", prefix, ep)), nil
+	}
+	html, err := storage.ReadFile(page)
+	if err != nil {
+		return page, nil
+	}
+	c := converter.PageCopy(page, string(html))
+	if c.Placeholder {
+		return page, nil
+	}
+	return page, &c
+}
+
+// promote makes a copy the episode's transcript page, setting the current
+// page aside. Recognizer output is rendered as a page with the current
+// page's title and date, and stays where it is.
+func promote(dataDir, prefix string, ep int, page string, hasPage bool, c converter.Copy) error {
+	var html []byte
+	if strings.HasSuffix(c.Path, ".html") {
+		data, err := storage.ReadFile(c.Path)
+		if err != nil {
+			return err
+		}
+		html = data
+	} else {
+		cues, err := transcribe.LoadOutput(c.Path)
+		if err != nil {
+			return err
+		}
+		e, err := converter.LoadEpisode(page)
+		if err != nil {
+			return err
+		}
+		html = []byte(converter.CaptionsPage(e.Title, e.Date, converter.OriginASR, cues))
+	}
+	if hasPage {
+		if _, err := converter.SetAside(dataDir, prefix, ep, page); err != nil {
+			return err
+		}
+	}
+	if err := storage.WriteFile(page, html); err != nil {
+		return err
+	}
+	if strings.HasSuffix(c.Path, ".html") {
+		if err := storage.Remove(c.Path); err != nil {
+			return err
+		}
+	}
+	_, err := index.AddFile(dataDir, page)
+	return err
+}
+
+// printCopies lists an episode's current copy and its alternatives
+func printCopies(prefix string, ep int, cur *converter.Copy, alts []converter.Copy) {
+	fmt.Printf("%s %d:\n", prefix, ep)
+	if cur != nil {
+		fmt.Printf("  * %-9s %s  %s\n", cur.Kind, fetchedDate(*cur), cur.Path)
+	}
+	for _, c := range alts {
+		fmt.Printf("    %-9s %s  %s\n", c.Kind, fetchedDate(c), c.Path)
+	}
+}
+
+func fetchedDate(c converter.Copy) string {
+	if c.Fetched.IsZero() {
+		return "          "
+	}
+	return c.Fetched.Format("2006-01-02")
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/digest"
+	"github.com/aramova/twit-transcript-archiver/go/internal/errs"
+	"github.com/aramova/twit-transcript-archiver/go/internal/scraper"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+func runDigest(args []string) error {
+	fs := flag.NewFlagSet("digest", flag.ExitOnError)
+	smtpPtr := fs.String("smtp", envOr("TWIT_SMTP", ""), "SMTP server as host:port (e.g. smtp.example.com:587)")
+	userPtr := fs.String("smtp-user", envOr("TWIT_SMTP_USER", ""), "SMTP user name; the password is read from TWIT_SMTP_PASSWORD")
+	senderPtr := fs.String("sender", "", "Address the digest is sent from")
+	toPtr := fs.String("to", "", "Comma-separated addresses to send the digest to")
+	everyPtr := fs.String("every", "", "Only send if the last digest is at least this old (e.g. 7d), so a nightly job can send weekly")
+	errorsPtr := fs.String("errors", "", "Failure report to include (default: errors.json in the data directory)")
+	skipEmptyPtr := fs.Bool("skip-empty", false, "Send nothing when nothing changed")
+	dryRunPtr := fs.Bool("dry-run", false, "Print the digest instead of sending it, and do not record it as sent")
+	parseFlags(fs, args)
+	if !*dryRunPtr {
+		unlock, err := lockDataDir(fs.Name())
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	dataDir := config.GetDataDir()
+	last, err := digest.LoadState(dataDir)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	if *everyPtr != "" && last != nil {
+		every, err := scraper.ParseAge(*everyPtr)
+		if err != nil {
+			return err
+		}
+		if now.Sub(last.Sent) < every {
+			fmt.Printf("Last digest sent %s; the next is due after %s.\n", last.Sent.Format("2006-01-02 15:04"), last.Sent.Add(every).Format("2006-01-02 15:04"))
+			return nil
+		}
+	}
+	reportPath := *errorsPtr
+	if reportPath == "" {
+		reportPath = storage.Join(dataDir, errs.ReportFile)
+	}
+	d, next, err := digest.Build(dataDir, reportPath, last, now)
+	if err != nil {
+		return err
+	}
+
+	if *dryRunPtr {
+		fmt.Printf("Subject: %s\n\n%s", d.Subject(), d.Text())
+		return nil
+	}
+	if d.Empty() && *skipEmptyPtr {
+		fmt.Println("Nothing changed since the last digest; not sending one.")
+		return next.Save(dataDir)
+	}
+	var to []string
+	for _, addr := range strings.Split(*toPtr, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			to = append(to, addr)
+		}
+	}
+	m := &digest.Mailer{Addr: *smtpPtr, Username: *userPtr, Password: os.Getenv("TWIT_SMTP_PASSWORD"), From: *senderPtr, To: to}
+	if err := m.Send(d.Subject(), d.Text()); err != nil {
+		return err
+	}
+	fmt.Printf("Sent \"%s\" to %s\n", d.Subject(), strings.Join(to, ", "))
+	return next.Save(dataDir)
+}
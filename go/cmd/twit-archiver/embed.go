@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/embed"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+func runEmbed(args []string) error {
+	fs := flag.NewFlagSet("embed", flag.ExitOnError)
+	endpointPtr := fs.String("endpoint", envOr("TWIT_EMBED_ENDPOINT", "http://localhost:11434/v1"), "OpenAI-compatible API base URL")
+	modelPtr := fs.String("model", envOr("TWIT_EMBED_MODEL", "nomic-embed-text"), "Embedding model name")
+	sizePtr := fs.Int("size", 200, "Passage size in words")
+	overlapPtr := fs.Int("overlap", 40, "Words of overlap between consecutive passages")
+	batchPtr := fs.Int("batch", 32, "Passages per embedding request")
+	outPtr := fs.String("out", "", "Output JSONL file (default: in the data directory)")
+	parseFlags(fs, args)
+
+	dataDir := config.GetDataDir()
+	episodes, err := loadEpisodes(dataDir, fs.Args())
+	if err != nil {
+		return err
+	}
+
+	out := *outPtr
+	if out == "" {
+		out = storage.Join(dataDir, "embeddings.jsonl")
+	}
+	f, err := storage.Create(out)
+	if err != nil {
+		return err
+	}
+
+	client := embed.NewClient(*endpointPtr, *modelPtr, os.Getenv("OPENAI_API_KEY"))
+	w := embed.NewWriter(f, client, *batchPtr)
+	fmt.Printf("Embedding %d episodes with %s via %s\n", len(episodes), client.Model, client.Endpoint)
+
+	for _, ep := range episodes {
+		passages := embed.Passages(ep, *sizePtr, *overlapPtr)
+		if err := w.Write(passages); err != nil {
+			f.Close()
+			return fmt.Errorf("%s %d: %v", ep.Prefix, ep.Number, err)
+		}
+		fmt.Printf("Embedded %s %d (%d passages)\n", ep.Prefix, ep.Number, len(passages))
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d vectors to %s\n", w.Written, out)
+	return nil
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+)
+
+func runEpisodes(args []string) error {
+	fs := flag.NewFlagSet("episodes", flag.ExitOnError)
+	showPtr := fs.String("show", "", "Only list episodes of this show prefix")
+	tagPtr := fs.String("tag", "", "Only list episodes tagged with this entity or topic")
+	guestPtr := fs.String("guest", "", "Only list episodes with this guest")
+	hostPtr := fs.String("host", "", "Only list episodes with this host")
+	pendingPtr := fs.Bool("pending", false, "Only list episodes whose page had no transcript yet")
+	chunksPtr := fs.Bool("chunks", false, "Also list the chunk file and anchor of each episode (file#anchor), from links.json")
+	parseFlags(fs, args)
+
+	ix, err := index.Load(config.GetDataDir())
+	if err != nil {
+		return err
+	}
+	var links *converter.LinkIndex
+	if *chunksPtr {
+		if links, err = converter.LoadLinks(config.GetDataDir()); err != nil {
+			return err
+		}
+	}
+
+	count := 0
+	for _, e := range ix.Sorted() {
+		if *showPtr != "" && !strings.EqualFold(e.Prefix, *showPtr) {
+			continue
+		}
+		if *tagPtr != "" && !e.HasTag(*tagPtr) {
+			continue
+		}
+		if *guestPtr != "" && !e.HasGuest(*guestPtr) {
+			continue
+		}
+		if *hostPtr != "" && !e.HasHost(*hostPtr) {
+			continue
+		}
+		if *pendingPtr && !e.Pending {
+			continue
+		}
+		date := e.Date
+		if date == "" {
+			date = "----------"
+		}
+		fmt.Printf("%-6s %5d  %s  %s\n", e.Prefix, e.Number, date, e.Title)
+		if links != nil {
+			for _, l := range links.Find(e.Prefix, e.Number) {
+				target := l.File
+				if l.Anchor != "" {
+					target += "#" + l.Anchor
+				}
+				fmt.Printf("               %s\n", target)
+			}
+		}
+		count++
+	}
+	fmt.Printf("%d episodes\n", count)
+	return nil
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/bundle"
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/export"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+	"github.com/aramova/twit-transcript-archiver/go/internal/scraper"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+func runExport(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("usage: twit-archiver export <sqlite|csv|tsv|jsonl|segments|bundle|epub|srt|vtt|opensearch|meilisearch|typesense> [flags] [prefixes...]")
+	}
+	format := args[0]
+
+	fs := flag.NewFlagSet("export "+format, flag.ExitOnError)
+	outPtr := fs.String("out", "", "Output file (default: in the data directory)")
+	perPtr := fs.String("per", "episode", "jsonl: one record per 'episode' or per speaker 'turn'")
+	shardPtr := fs.String("shard-size", "", "jsonl: split output into shards of at most this size (e.g. 100M)")
+	showPtr := fs.String("show", "", "Comma-separated show prefixes to export (same as positional prefixes)")
+	contentPtr := fs.String("content", bundle.ContentRaw, "bundle: package 'raw' HTML or per-episode 'markdown'")
+	asciiQuotesPtr := fs.Bool("ascii-quotes", false, "Replace curly quotes, dashes and ellipses in the text with plain ASCII")
+	redactPtr := fs.String("redact", "", "Mask text matching the rules in this file (words, /regexes/, @email, @phone)")
+	redactPIIPtr := fs.Bool("redact-pii", false, "Mask email addresses and phone numbers")
+	redactWithPtr := fs.String("redact-with", converter.DefaultRedaction, "Text that replaces redacted matches")
+	endpointPtr := fs.String("endpoint", "", "opensearch, meilisearch, typesense: server URL (default: $TWIT_<FORMAT>_URL or localhost)")
+	indexPtr := fs.String("index", "twit", "opensearch: index name prefix (<index>-episodes, <index>-segments); meilisearch, typesense: index or collection")
+	mappingPtr := fs.String("mapping", "", "opensearch: JSON file with the episode index settings and mappings")
+	segMappingPtr := fs.String("segment-mapping", "", "opensearch: JSON file with the segment index settings and mappings")
+	batchPtr := fs.Int("batch", 0, "opensearch, meilisearch, typesense: documents per request (default 500, 100, 100)")
+	fullPtr := fs.Bool("full", false, "meilisearch, typesense: push every episode, not only new and changed ones")
+	parseFlags(fs, args[1:])
+	converter.Normalize.ASCIIQuotes = *asciiQuotesPtr
+	if err := converter.SetupRedaction(*redactPtr, *redactPIIPtr, *redactWithPtr); err != nil {
+		return err
+	}
+
+	if *perPtr != "episode" && *perPtr != "turn" {
+		return fmt.Errorf("unknown --per value '%s' (want episode or turn)", *perPtr)
+	}
+	var shardSize int64
+	if *shardPtr != "" {
+		var err error
+		if shardSize, err = scraper.ParseSize(*shardPtr); err != nil {
+			return err
+		}
+	}
+
+	prefixArgs := fs.Args()
+	if *showPtr != "" {
+		prefixArgs = append(prefixArgs, strings.Split(*showPtr, ",")...)
+	}
+	dataDir := config.GetDataDir()
+	if format == "bundle" {
+		return exportBundle(dataDir, prefixArgs, *contentPtr, *outPtr)
+	}
+	episodes, err := loadEpisodes(dataDir, prefixArgs)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "opensearch":
+		return exportOpenSearch(episodes, *endpointPtr, *indexPtr, *mappingPtr, *segMappingPtr, *batchPtr)
+	case "meilisearch", "typesense":
+		return syncSearch(format, episodes, dataDir, *endpointPtr, *indexPtr, *batchPtr, *fullPtr)
+	}
+	return exportEpisodes(format, episodes, dataDir, *outPtr, *perPtr == "turn", shardSize)
+}
+
+// exportEpisodes writes episodes in one of the file formats (all but
+// bundle) to out, or to the format's default file in the data directory
+func exportEpisodes(format string, episodes []converter.Episode, dataDir, out string, perTurn bool, shardSize int64) error {
+	switch format {
+	case "sqlite":
+		out = outPath(out, dataDir, "twit_archive.db")
+		if err := export.SQLite(episodes, out); err != nil {
+			return err
+		}
+		fmt.Printf("Exported %d episodes to %s\n", len(episodes), out)
+	case "segments":
+		out = outPath(out, dataDir, "segments.jsonl")
+		var n int
+		err := writeOutput(out, func(w io.Writer) (err error) {
+			n, err = export.Segments(episodes, w)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Exported %d timed segments from %d episodes to %s\n", n, len(episodes), out)
+	case "jsonl":
+		per := "episode"
+		if perTurn {
+			per = "turn"
+		}
+		out = outPath(out, dataDir, "corpus.jsonl")
+		w := export.NewShardWriter(out, shardSize)
+		n, err := export.JSONL(episodes, w, perTurn)
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Exported %d %s records from %d episodes to %d file(s)\n", n, per, len(episodes), len(w.Files))
+		for _, f := range w.Files {
+			fmt.Printf("  %s\n", f)
+		}
+	case "epub":
+		out = outPath(out, dataDir, "epub")
+		for _, ep := range episodes {
+			path := storage.Join(out, ep.Prefix, export.EPUBName(ep))
+			if err := writeOutput(path, func(w io.Writer) error { return export.EPUB(ep, w) }); err != nil {
+				return fmt.Errorf("%s: %v", path, err)
+			}
+		}
+		fmt.Printf("Exported %d episodes as EPUB books to %s\n", len(episodes), out)
+	case "srt", "vtt":
+		out = outPath(out, dataDir, "subtitles")
+		written := 0
+		for _, ep := range episodes {
+			cues := export.Cues(ep)
+			if cues == nil {
+				continue
+			}
+			path := storage.Join(out, ep.Prefix, fmt.Sprintf("%s_%d.%s", ep.Prefix, ep.Number, format))
+			if err := writeOutput(path, func(w io.Writer) error {
+				if format == "srt" {
+					return export.SRT(cues, w)
+				}
+				return export.VTT(cues, w)
+			}); err != nil {
+				return err
+			}
+			written++
+		}
+		fmt.Printf("Exported %s subtitles for %d episodes to %s (%d without timecodes skipped)\n", format, written, out, len(episodes)-written)
+	case "csv", "tsv":
+		comma := ','
+		if format == "tsv" {
+			comma = '\t'
+		}
+		out = outPath(out, dataDir, "twit_catalog."+format)
+		if err := writeOutput(out, func(w io.Writer) error {
+			return export.CSV(episodes, w, dataDir, comma)
+		}); err != nil {
+			return err
+		}
+		fmt.Printf("Exported %d episodes to %s\n", len(episodes), out)
+	default:
+		return fmt.Errorf("unknown export format '%s'", format)
+	}
+	return nil
+}
+
+// searchEndpoints are the default server URLs of the search exports
+var searchEndpoints = map[string]string{
+	"opensearch":  "http://localhost:9200",
+	"meilisearch": "http://localhost:7700",
+	"typesense":   "http://localhost:8108",
+}
+
+// searchEndpoint returns the --endpoint value, or the format's
+// TWIT_<FORMAT>_URL environment variable, or its default
+func searchEndpoint(format, endpoint string) string {
+	if endpoint != "" {
+		return endpoint
+	}
+	return envOr("TWIT_"+strings.ToUpper(format)+"_URL", searchEndpoints[format])
+}
+
+// exportOpenSearch indexes episodes and their turns in an OpenSearch or
+// Elasticsearch cluster
+func exportOpenSearch(episodes []converter.Episode, endpoint, index, mapping, segMapping string, batch int) error {
+	o := export.NewOpenSearch(searchEndpoint("opensearch", endpoint), index)
+	o.APIKey = os.Getenv("TWIT_OPENSEARCH_API_KEY")
+	if batch > 0 {
+		o.Batch = batch
+	}
+	for _, m := range []struct {
+		file   string
+		target *string
+	}{{mapping, &o.EpisodeMapping}, {segMapping, &o.SegmentMapping}} {
+		if m.file == "" {
+			continue
+		}
+		data, err := os.ReadFile(m.file)
+		if err != nil {
+			return err
+		}
+		*m.target = string(data)
+	}
+	res, err := o.Push(episodes)
+	fmt.Printf("Indexed %d episodes and %d segments into %s (%s-episodes, %s-segments)\n", res.Episodes, res.Segments, o.Endpoint, o.Index, o.Index)
+	return err
+}
+
+// syncSearch pushes new and changed episodes to Meilisearch or Typesense,
+// recording what was pushed in the data directory
+func syncSearch(format string, episodes []converter.Episode, dataDir, endpoint, index string, batch int, full bool) error {
+	endpoint = searchEndpoint(format, endpoint)
+	apiKey := os.Getenv("TWIT_" + strings.ToUpper(format) + "_API_KEY")
+	var engine export.SearchEngine = export.NewMeilisearch(endpoint, index, apiKey)
+	if format == "typesense" {
+		engine = export.NewTypesense(endpoint, index, apiKey)
+	}
+	state, err := export.LoadSyncState(dataDir)
+	if err != nil {
+		return err
+	}
+	res, err := export.Sync(engine, episodes, state, batch, full)
+	// Record the batches that made it, even if a later one failed
+	if serr := state.Save(dataDir); err == nil {
+		err = serr
+	}
+	fmt.Printf("Pushed %d episodes to %s (%d unchanged)\n", res.Pushed, engine.Target(), res.Unchanged)
+	return err
+}
+
+// exportBundle packages a show's files with a checksum manifest
+func exportBundle(dataDir string, args []string, content, out string) error {
+	prefixes, err := resolvePrefixes(dataDir, args)
+	if err != nil {
+		return err
+	}
+	files, err := bundle.Files(dataDir, prefixes, content)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no %s files to bundle", content)
+	}
+	out = outPath(out, dataDir, fmt.Sprintf("twit_%s_%s.tar.gz", strings.Join(prefixes, "_"), content))
+	var m *bundle.Manifest
+	if err := writeOutput(out, func(w io.Writer) (err error) {
+		m, err = bundle.Create(w, bundle.IsZip(out), prefixes, content, files)
+		return err
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("Bundled %d %s files to %s\n", len(m.Files), content, out)
+	return nil
+}
+
+// outPath returns the --out value, or name in the data directory
+func outPath(out, dataDir, name string) string {
+	if out == "" {
+		return storage.Join(dataDir, name)
+	}
+	return out
+}
+
+// writeOutput creates out (locally or in remote storage) and passes it to write
+func writeOutput(out string, write func(w io.Writer) error) error {
+	f, err := storage.Create(out)
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// resolvePrefixes maps the given show arguments to prefixes, or lists every
+// prefix in the data directory when none are given
+func resolvePrefixes(dataDir string, args []string) ([]string, error) {
+	if len(args) == 0 {
+		return converter.ListPrefixes(dataDir)
+	}
+	var prefixes []string
+	for _, arg := range args {
+		prefix, err := converter.ResolvePrefix(arg, dataDir)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
+
+// loadEpisodes parses the transcripts for the given prefixes, or for every
+// prefix in the data directory when none are given, with their summaries
+func loadEpisodes(dataDir string, args []string) ([]converter.Episode, error) {
+	prefixes, err := resolvePrefixes(dataDir, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var episodes []converter.Episode
+	for _, prefix := range prefixes {
+		eps, err := converter.LoadEpisodes(prefix, dataDir)
+		if err != nil {
+			return nil, err
+		}
+		episodes = append(episodes, eps...)
+	}
+	// Attach the abstracts written by summarize
+	if ix, err := index.Load(dataDir); err == nil {
+		for i := range episodes {
+			if e := ix.Entries[index.Key(episodes[i].Path)]; e != nil {
+				episodes[i].Summary = e.Summary
+			}
+		}
+	}
+	return episodes, nil
+}
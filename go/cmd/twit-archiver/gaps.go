@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/scraper"
+)
+
+func runGaps(args []string) error {
+	fs := flag.NewFlagSet("gaps", flag.ExitOnError)
+	parseFlags(fs, args)
+
+	dataDir := config.GetDataDir()
+	prefixes, err := resolvePrefixes(dataDir, fs.Args())
+	if err != nil {
+		return err
+	}
+
+	total := 0
+	for _, prefix := range prefixes {
+		nums, err := scraper.LocalEpisodes(prefix, dataDir)
+		if err != nil {
+			return err
+		}
+		gaps := scraper.Gaps(nums)
+		total += len(gaps)
+		if len(gaps) == 0 {
+			fmt.Printf("%s: no gaps (%d episodes)\n", prefix, len(nums))
+			continue
+		}
+		fmt.Printf("%s: missing %d of %d-%d: %s\n", prefix, len(gaps), nums[0], nums[len(nums)-1], scraper.FormatGaps(gaps))
+	}
+	fmt.Printf("%d missing episodes. Run 'fetch-transcripts --fill-gaps' to look for them.\n", total)
+	return nil
+}
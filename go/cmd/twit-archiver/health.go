@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/health"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+func runHealth(args []string) error {
+	fs := flag.NewFlagSet("health", flag.ExitOnError)
+	filePtr := fs.String("health-file", "", "Health file to check (default: health.json in the data directory)")
+	maxAgePtr := fs.Duration("max-age", 15*time.Minute, "Fail if the last heartbeat is older than this")
+	parseFlags(fs, args)
+
+	path := *filePtr
+	if path == "" {
+		path = storage.Join(config.GetDataDir(), health.FileName)
+	}
+	s, err := health.Load(path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s (pid %d on %s), running since %s\n", s.Command, s.PID, s.Host, s.Started.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("Phase:     %s", s.Phase)
+	if s.Detail != "" {
+		fmt.Printf(" (%s)", s.Detail)
+	}
+	fmt.Printf("\nHeartbeat: %s (%s ago)\n", s.Heartbeat.Local().Format("2006-01-02 15:04:05"), time.Since(s.Heartbeat).Round(time.Second))
+	if r := s.LastRun; r != nil {
+		result := "ok"
+		if r.Error != "" {
+			result = r.Error
+		}
+		fmt.Printf("Last run:  %s, %d downloaded, %d failed: %s\n", r.Finished.Local().Format("2006-01-02 15:04:05"), r.Downloaded, r.Failed, result)
+	}
+	if s.NextRun != nil {
+		fmt.Printf("Next run:  %s\n", s.NextRun.Local().Format("2006-01-02 15:04:05"))
+	}
+	return health.Check(s, *maxAgePtr, time.Now())
+}
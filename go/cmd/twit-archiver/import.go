@@ -0,0 +1,148 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/bundle"
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+	"github.com/aramova/twit-transcript-archiver/go/internal/utils"
+)
+
+const importUsage = "usage: twit-archiver import [--keep-local] <data-dir> | import bundle <file.tar.gz|file.zip>..."
+
+func runImport(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf(importUsage)
+	}
+	if args[0] == "bundle" {
+		return importBundles(args[1:])
+	}
+
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	keepLocalPtr := fs.Bool("keep-local", false, "Never replace a differing local copy, even if the other one is newer")
+	rest, _ := utils.ParseFlags(fs, args)
+	applySettings(fs)
+	unlock, err := lockDataDir(fs.Name())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if len(rest) != 1 {
+		return fmt.Errorf(importUsage)
+	}
+	srcDir := rest[0]
+
+	dataDir := config.GetDataDir()
+	if srcDir == dataDir {
+		return fmt.Errorf("%s is the current data directory", srcDir)
+	}
+	res, err := bundle.Merge(srcDir, dataDir, *keepLocalPtr)
+	if err != nil {
+		return err
+	}
+	changed := append(append([]string{}, res.Added...), res.Replaced...)
+	if err := indexAdded(dataDir, changed); err != nil {
+		return err
+	}
+	if err := copyTags(srcDir, dataDir, changed); err != nil {
+		return err
+	}
+	fmt.Printf("%s: %d added, %d replaced by newer copies, %d already present, %d conflicting (kept local copy)\n",
+		srcDir, len(res.Added), len(res.Replaced), len(res.Skipped), len(res.Conflicts))
+	for _, f := range res.Added {
+		fmt.Printf("  added: %s\n", f)
+	}
+	for _, f := range res.Replaced {
+		fmt.Printf("  replaced: %s\n", f)
+	}
+	for _, c := range res.Conflicts {
+		fmt.Printf("  conflict: %s\n", c)
+	}
+	return nil
+}
+
+func importBundles(args []string) error {
+	fs := flag.NewFlagSet("import bundle", flag.ExitOnError)
+	parseFlags(fs, args)
+	unlock, err := lockDataDir(fs.Name())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: twit-archiver import bundle <file.tar.gz|file.zip>...")
+	}
+
+	dataDir := config.GetDataDir()
+	for _, file := range fs.Args() {
+		res, err := bundle.Import(file, dataDir)
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		if err := indexAdded(dataDir, res.Added); err != nil {
+			return err
+		}
+		fmt.Printf("%s: %d added, %d already present, %d conflicting (kept local copy)\n",
+			file, len(res.Added), len(res.Skipped), len(res.Conflicts))
+		for _, c := range res.Conflicts {
+			fmt.Printf("  conflict: %s\n", c)
+		}
+	}
+	return nil
+}
+
+// indexAdded records newly imported transcript HTML in the index
+func indexAdded(dataDir string, files []string) error {
+	ix, err := index.Load(dataDir)
+	if err != nil {
+		return err
+	}
+	n := 0
+	for _, f := range files {
+		if !strings.HasSuffix(f, ".html") {
+			continue
+		}
+		ep, err := converter.LoadEpisode(f)
+		if err != nil {
+			fmt.Printf("Warning: could not index %s: %v\n", f, err)
+			continue
+		}
+		ix.Upsert(ep)
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+	return ix.Save(dataDir)
+}
+
+// copyTags carries the other archive's tags over for the imported
+// transcripts that have none yet
+func copyTags(srcDir, dataDir string, files []string) error {
+	other, err := index.Load(srcDir)
+	if err != nil || len(other.Entries) == 0 {
+		return err
+	}
+	ix, err := index.Load(dataDir)
+	if err != nil {
+		return err
+	}
+	n := 0
+	for _, f := range files {
+		key := index.Key(f)
+		e, theirs := ix.Entries[key], other.Entries[key]
+		if e == nil || theirs == nil || len(e.Tags) > 0 || len(theirs.Tags) == 0 {
+			continue
+		}
+		e.SetTags(theirs.Entities, theirs.Topics)
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+	return ix.Save(dataDir)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+)
+
+func runIndex(args []string) error {
+	if len(args) == 0 || args[0] != "rebuild" {
+		return fmt.Errorf("usage: twit-archiver index rebuild [flags]")
+	}
+	return indexRebuild(args[1:])
+}
+
+func indexRebuild(args []string) error {
+	fs := flag.NewFlagSet("index rebuild", flag.ExitOnError)
+	workersPtr := fs.Int("workers", 0, "Transcripts parsed at once (default: one per CPU)")
+	dryRunPtr := fs.Bool("dry-run", false, "Only report how the rebuilt index would differ from the current one")
+	maxPtr := fs.Int("max", 20, "Changed episodes listed per kind (0 for all)")
+	parseFlags(fs, args)
+	unlock, err := lockDataDir(fs.Name())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	dataDir := config.GetDataDir()
+	old, err := index.Load(dataDir)
+	if err != nil {
+		fmt.Printf("Warning: could not read the current index, rebuilding without its tags and summaries: %v\n", err)
+		old = index.New()
+	}
+
+	step := 0
+	res, err := index.Rebuild(dataDir, old, index.RebuildOptions{
+		Workers: *workersPtr,
+		Progress: func(done, total int) {
+			// About every 10%, and at the end
+			if pct := done * 10 / total; pct > step || done == total {
+				step = pct
+				fmt.Printf("Parsed %d/%d transcripts\n", done, total)
+			}
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for path := range res.Failed {
+		failed = append(failed, path)
+	}
+	sort.Strings(failed)
+	for _, path := range failed {
+		fmt.Printf("Could not parse %s: %v\n", path, res.Failed[path])
+	}
+
+	changes := index.Diff(old, res.Index)
+	byKind := make(map[string][]index.Change)
+	for _, c := range changes {
+		byKind[c.Kind] = append(byKind[c.Kind], c)
+	}
+	fmt.Printf("\n%d episodes indexed (was %d): %d added, %d removed, %d changed\n",
+		len(res.Index.Entries), len(old.Entries), len(byKind[index.Added]), len(byKind[index.Removed]), len(byKind[index.Changed]))
+	for _, kind := range []string{index.Added, index.Removed, index.Changed} {
+		for i, c := range byKind[kind] {
+			if *maxPtr > 0 && i == *maxPtr {
+				fmt.Printf("  ... and %d more %s\n", len(byKind[kind])-i, kind)
+				break
+			}
+			if kind == index.Changed {
+				fmt.Printf("  %-8s %s (%s)\n", kind, c.Key, strings.Join(c.Fields, ", "))
+			} else {
+				fmt.Printf("  %-8s %s\n", kind, c.Key)
+			}
+		}
+	}
+
+	if *dryRunPtr {
+		return nil
+	}
+	if len(res.Index.Entries) == 0 && len(old.Entries) > 0 {
+		return fmt.Errorf("no transcripts found in %s; not replacing the index of %d episodes", dataDir, len(old.Entries))
+	}
+	if err := res.Index.Save(dataDir); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s\n", index.FileName)
+	if len(failed) > 0 {
+		return fmt.Errorf("%d transcript(s) could not be parsed and are not in the index", len(failed))
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/chapters"
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+	"github.com/aramova/twit-transcript-archiver/go/internal/scraper"
+)
+
+func runIngest(args []string) error {
+	fs := flag.NewFlagSet("ingest", flag.ExitOnError)
+	showPtr := fs.String("show", "", "Show prefix, if the page title does not name the show")
+	episodePtr := fs.Int("episode", 0, "Episode number, if the page title does not include it")
+	forcePtr := fs.Bool("force", false, "Replace an already archived copy of the episode")
+	feedPtr := fs.String("feed", "", "Podcast RSS feed URL or file whose podcast:transcript links to ingest, instead of pages")
+	throttlePtr := fs.Duration("throttle", 1*time.Second, "Duration to wait between requests (with --feed)")
+	parseFlags(fs, args)
+	unlock, err := lockDataDir(fs.Name())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	dataDir := config.GetDataDir()
+	opts := scraper.IngestOptions{Prefix: strings.ToUpper(*showPtr), Episode: *episodePtr, Force: *forcePtr}
+	if *feedPtr != "" {
+		if fs.NArg() > 0 || *episodePtr != 0 {
+			return fmt.Errorf("--feed cannot be combined with files or --episode")
+		}
+		return ingestFeed(*feedPtr, dataDir, *throttlePtr, opts)
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: twit-archiver ingest [flags] <file.html>... | --feed <url|file>")
+	}
+	if *episodePtr != 0 && fs.NArg() > 1 {
+		return fmt.Errorf("--episode can only be used with a single file")
+	}
+
+	for _, file := range fs.Args() {
+		html, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		path, err := scraper.IngestPage(string(html), dataDir, opts)
+		if errors.Is(err, scraper.ErrOutranked) {
+			fmt.Printf("%s: %v\n", file, err)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		e, err := index.AddFile(dataDir, path)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s -> %s (%s %d: %s)\n", file, path, e.Prefix, e.Number, e.Title)
+	}
+	return nil
+}
+
+// ingestFeed ingests the transcripts linked from a podcast feed, skipping
+// archived episodes. Items that fail are reported and the rest carry on.
+func ingestFeed(feed, dataDir string, throttle time.Duration, opts scraper.IngestOptions) error {
+	data, err := readSource(feed, throttle)
+	if err != nil {
+		return err
+	}
+	items, err := chapters.ParseFeed([]byte(data))
+	if err != nil {
+		return err
+	}
+	added, skipped, kept, failed := 0, 0, 0, 0
+	for _, it := range items {
+		path, err := scraper.IngestFeedItem(it, dataDir, throttle, opts)
+		switch {
+		case errors.Is(err, scraper.ErrNoTranscript), errors.Is(err, scraper.ErrArchived):
+			skipped++
+			continue
+		case errors.Is(err, scraper.ErrOutranked):
+			kept++
+			fmt.Printf("%s: %v\n", it.Title, err)
+			continue
+		case err != nil:
+			failed++
+			fmt.Printf("Warning: %s: %v\n", it.Title, err)
+			continue
+		}
+		e, err := index.AddFile(dataDir, path)
+		if err != nil {
+			return err
+		}
+		added++
+		fmt.Printf("%s -> %s (%s %d: %s)\n", it.Title, path, e.Prefix, e.Number, e.Title)
+	}
+	fmt.Printf("%d transcripts added, %d items skipped (no transcript or already archived), %d kept as alternatives, %d failed\n", added, skipped, kept, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d feed transcript(s) could not be ingested", failed)
+	}
+	return nil
+}
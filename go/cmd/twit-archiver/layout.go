@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+	"github.com/aramova/twit-transcript-archiver/go/internal/migrate"
+)
+
+func runLayout(args []string) error {
+	fs := flag.NewFlagSet("layout", flag.ExitOnError)
+	fromPtr := fs.String("from", "flat", "Current layout of the data directory")
+	toPtr := fs.String("to", "structured", "Layout to move files into")
+	dryRunPtr := fs.Bool("dry-run", false, "Only print the planned moves")
+	parseFlags(fs, args)
+	unlock, err := lockDataDir(fs.Name())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	from, err := config.ParseLayout(*fromPtr)
+	if err != nil {
+		return err
+	}
+	to, err := config.ParseLayout(*toPtr)
+	if err != nil {
+		return err
+	}
+
+	dataDir := config.GetDataDir()
+	moves, err := migrate.Plan(dataDir, from, to)
+	if err != nil {
+		return err
+	}
+	if len(moves) == 0 {
+		fmt.Printf("Nothing to move: data directory already uses the %s layout\n", to.Name)
+		return nil
+	}
+
+	for _, m := range moves {
+		fmt.Printf("%s -> %s\n", m.From, m.To)
+	}
+	if *dryRunPtr {
+		fmt.Printf("%d files would be moved (dry run)\n", len(moves))
+		return nil
+	}
+
+	if err := migrate.Apply(moves); err != nil {
+		return err
+	}
+	ix, err := index.Load(dataDir)
+	if err != nil {
+		return err
+	}
+	updated := migrate.UpdateIndex(ix, dataDir, moves)
+	if err := ix.Save(dataDir); err != nil {
+		return err
+	}
+	fmt.Printf("Moved %d files, updated %d index entries. Set TWIT_LAYOUT=%s for future runs.\n", len(moves), updated, to.Name)
+	return nil
+}
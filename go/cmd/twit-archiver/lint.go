@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/analysis"
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+func runLint(args []string) error {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	maxPtr := fs.Int("max", 20, "Violations printed per file (0 for all)")
+	fs.Usage = func() {
+		fmt.Println("Usage: twit-archiver lint [--max N] [files or directories...]")
+		fmt.Println("\nChecks generated Markdown (.md) and JSON records (.jsonl, .json). Without")
+		fmt.Println("arguments, the chunk and per-episode Markdown of the data directory.")
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+
+	files, err := lintTargets(fs.Args())
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("nothing to lint: no Markdown or JSON files found")
+	}
+
+	bad, total := 0, 0
+	for _, f := range files {
+		violations, err := analysis.LintFile(f)
+		if err != nil {
+			return err
+		}
+		if len(violations) == 0 {
+			continue
+		}
+		bad++
+		total += len(violations)
+		fmt.Printf("%s: %d violation(s)\n", f, len(violations))
+		for i, v := range violations {
+			if *maxPtr > 0 && i == *maxPtr {
+				fmt.Printf("  ... and %d more\n", len(violations)-i)
+				break
+			}
+			fmt.Printf("  %s\n", strings.TrimPrefix(v.String(), f+":"))
+		}
+	}
+	if bad > 0 {
+		return fmt.Errorf("%d violation(s) in %d of %d files", total, bad, len(files))
+	}
+	fmt.Printf("All %d files passed.\n", len(files))
+	return nil
+}
+
+// lintTargets expands the lint arguments into files. Directories are
+// searched for .md and .jsonl files; without arguments, the data
+// directory's chunks and per-episode Markdown are linted.
+func lintTargets(args []string) ([]string, error) {
+	var files []string
+	if len(args) == 0 {
+		dataDir := config.GetDataDir()
+		seen := make(map[string]bool)
+		for _, dir := range []string{config.ActiveLayout.ChunkDir(dataDir, "*"), config.ActiveLayout.MarkdownDir(dataDir, "*")} {
+			matches, err := storage.Glob(storage.Join(dir, "*.md"))
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range matches {
+				if !seen[m] {
+					seen[m] = true
+					files = append(files, m)
+				}
+			}
+		}
+		sort.Strings(files)
+		return files, nil
+	}
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil || !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		err = filepath.Walk(arg, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			// .json files are only linted when named: directories also
+			// hold manifests and indexes
+			switch filepath.Ext(path) {
+			case ".md", ".jsonl":
+				if !info.IsDir() {
+					files = append(files, path)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
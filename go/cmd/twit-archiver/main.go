@@ -0,0 +1,161 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/errs"
+	"github.com/aramova/twit-transcript-archiver/go/internal/lock"
+	"github.com/aramova/twit-transcript-archiver/go/internal/scraper"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// command is a twit-archiver subcommand
+type command struct {
+	Name        string
+	Description string
+	Run         func(args []string) error
+}
+
+var commands = []command{
+	{"export", "Export the archive to another format (sqlite, csv, jsonl, segments, srt, vtt, epub, bundle, search servers)", runExport},
+	{"import", "Merge another data directory or a bundle into the archive", runImport},
+	{"chapters", "Align transcripts with the chapter markers of the show's RSS feed", runChapters},
+	{"embed", "Split transcripts into passages and write embedding vectors", runEmbed},
+	{"summarize", "Write a short abstract and topic list per episode with an LLM", runSummarize},
+	{"tag", "Extract companies, products, people, CVEs and topics into the index", runTag},
+	{"ingest", "Add locally saved transcript pages to the archive", runIngest},
+	{"transcribe", "Transcribe the audio of episodes without a transcript with a speech recognizer", runTranscribe},
+	{"compare", "Compare published transcripts with speech recognition output: word error rate and where they differ", runCompare},
+	{"youtube", "Add episodes without transcripts from the captions of a YouTube channel or playlist", runYouTube},
+	{"dedup", "Keep the best copy of each episode by source precedence, with the others as alternatives", runDedup},
+	{"gaps", "List missing episode numbers per show", runGaps},
+	{"cache", "Show, clear or prune cached list pages and transcript HTML", runCache},
+	{"digest", "Mail a digest of new episodes, revisions, gaps and failures since the last one", runDigest},
+	{"prune", "Remove old listing pages, recognizer output, audio, revisions and backups by retention rules", runPrune},
+	{"catalog", "List the transcripts available upstream and compare them with the archive", runCatalog},
+	{"episodes", "List indexed episodes, filtered by show or tag", runEpisodes},
+	{"backup", "Snapshot the index, chunk manifests and configuration into a timestamped archive", runBackup},
+	{"restore", "Put back the index, chunk manifests and configuration from a backup", runRestore},
+	{"index", "Rebuild the episode index from the archived transcript pages", runIndex},
+	{"layout", "Move the data directory's files into another layout", runLayout},
+	{"migrate", "Convert a flat-layout archive to the structured layout and backfill the index", runMigrate},
+	{"run", "Fetch new transcripts, convert the shows that changed and write exports in one go", runPipeline},
+	{"health", "Check the health file of a run --every daemon; fails if it stopped or shows no progress", runHealth},
+	{"retry", "Re-attempt the downloads and conversions listed in errors.json", runRetry},
+	{"serve", "Serve the shows, index and episodes (JSON or Markdown) over an HTTP API", runServe},
+	{"translate", "Write translated copies of the per-episode Markdown via a translation service", runTranslate},
+	{"stats", "Report words per speaker for each show and episode", runStats},
+	{"lint", "Check generated Markdown and JSON for episodes without title, date or turns and leftover HTML", runLint},
+	{"verify", "Flag transcripts that are too short or still pending, e.g. after truncated downloads", runVerify},
+	{"shows", "List the known shows with their prefixes and archived episode counts", runShows},
+}
+
+func init() {
+	// completion lists the commands, so it cannot be in their initializer
+	commands = append(commands, command{"completion", "Print a bash, zsh or fish completion script", runCompletion})
+}
+
+// Global options, given before the command
+var (
+	global      = flag.NewFlagSet("twit-archiver", flag.ExitOnError)
+	readOnlyPtr = global.Bool("read-only", false, "Refuse any change to the data directory, e.g. for exports from a preservation copy")
+	lockWaitPtr = global.Duration("wait", 0, "If another run is using the data directory, wait this long for it to finish instead of failing")
+	fixedPtr    = global.Bool("fixed-throttle", false, "Always wait --throttle between requests, instead of slowing down and pausing when the site pushes back")
+)
+
+func usage() {
+	fmt.Println("Usage: twit-archiver [--read-only] [--wait DURATION] [--fixed-throttle] <command> [flags] [args]")
+	fmt.Println("\nCommands:")
+	for _, c := range commands {
+		fmt.Printf("  %-12s %s\n", c.Name, c.Description)
+	}
+	fmt.Println("\nGlobal options:")
+	global.PrintDefaults()
+	fmt.Println("\nRun 'twit-archiver <command> -h' for command flags.")
+}
+
+func main() {
+	global.Usage = usage
+	global.Parse(os.Args[1:])
+	args := global.Args()
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := args[0]
+	if name == "help" {
+		usage()
+		return
+	}
+
+	if _, err := config.LoadSettings(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(2)
+	}
+	applySettings(global)
+	if *readOnlyPtr && !config.ReadOnly {
+		config.ReadOnly = true
+		storage.ReadOnly = append(storage.ReadOnly, config.GetDataDir())
+	}
+	if !*fixedPtr {
+		scraper.Politeness = scraper.NewPacer()
+	}
+	if err := config.LoadCustomShows(config.GetDataDir()); err != nil {
+		fmt.Printf("Warning: could not load custom shows: %v\n", err)
+	}
+	for _, c := range commands {
+		if c.Name == name {
+			if err := c.Run(args[1:]); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				if errors.Is(err, lock.ErrLocked) {
+					os.Exit(errs.ExitLocked)
+				}
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	fmt.Printf("Unknown command '%s'\n\n", name)
+	usage()
+	os.Exit(2)
+}
+
+// lockDataDir takes the data directory lock for a command that changes the
+// archive, waiting up to --wait for another run to finish. The returned
+// function releases it.
+func lockDataDir(command string) (func(), error) {
+	if config.ReadOnly {
+		return func() {}, nil
+	}
+	l, err := lock.Acquire(config.GetDataDir(), "twit-archiver "+command, *lockWaitPtr)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		if err := l.Release(); err != nil {
+			fmt.Printf("Warning: could not release the lock: %v\n", err)
+		}
+	}, nil
+}
+
+// parseFlags parses a subcommand's flags and fills in those not given from
+// the configuration file
+func parseFlags(fs *flag.FlagSet, args []string) {
+	fs.Parse(args)
+	applySettings(fs)
+}
+
+// applySettings fills in the flags of a parsed subcommand that were not
+// given from the configuration file
+func applySettings(fs *flag.FlagSet) {
+	if err := config.Loaded.Apply(fs, fs.Name()); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+	"github.com/aramova/twit-transcript-archiver/go/internal/migrate"
+)
+
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRunPtr := fs.Bool("dry-run", false, "Only report what would be moved")
+	parseFlags(fs, args)
+	unlock, err := lockDataDir(fs.Name())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	dataDir := config.GetDataDir()
+	from, to := config.FlatLayout, config.StructuredLayout
+
+	if migrate.IsLegacyFlat(dataDir) {
+		before, err := migrate.Count(dataDir, from)
+		if err != nil {
+			return err
+		}
+		moves, err := migrate.Plan(dataDir, from, to)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Detected flat layout: %d transcripts, %d chunks, %d episode files, %d list pages (%d files to move)\n",
+			before.Raw, before.Chunks, before.Markdown, before.ListPages, len(moves))
+		if *dryRunPtr {
+			return nil
+		}
+
+		if err := migrate.Apply(moves); err != nil {
+			return err
+		}
+		after, err := migrate.Count(dataDir, to)
+		if err != nil {
+			return err
+		}
+		if after != before {
+			return fmt.Errorf("file counts differ after migration: before %+v, after %+v", before, after)
+		}
+		fmt.Printf("Moved %d files; counts verified\n", len(moves))
+	} else {
+		fmt.Println("No flat-layout transcripts found; backfilling the index only")
+		if *dryRunPtr {
+			return nil
+		}
+	}
+
+	config.ActiveLayout = to
+	ix, err := index.Load(dataDir)
+	if err != nil {
+		return err
+	}
+	n, err := migrate.Backfill(ix, dataDir)
+	if err != nil {
+		return err
+	}
+	if len(ix.Entries) < n {
+		return fmt.Errorf("index has %d entries after backfilling %d transcripts", len(ix.Entries), n)
+	}
+	if err := ix.Save(dataDir); err != nil {
+		return err
+	}
+	fmt.Printf("Indexed %d transcripts. Set TWIT_LAYOUT=%s for future runs.\n", n, to.Name)
+	return nil
+}
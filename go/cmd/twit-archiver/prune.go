@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/retention"
+)
+
+func runPrune(args []string) error {
+	fs := flag.NewFlagSet("prune", flag.ExitOnError)
+	keepPtr := fs.String("keep", "", "Retention rules, CLASS=RULE separated by commas (e.g. raw=forever,asr=90d,alt=2); classes: raw, lists, alt, asr, media, quarantine, backups")
+	dryRunPtr := fs.Bool("dry-run", false, "Only list what would be removed")
+	parseFlags(fs, args)
+
+	policy, err := retention.ParsePolicy(*keepPtr)
+	if err != nil {
+		return err
+	}
+	if len(policy) == 0 {
+		return fmt.Errorf("no retention rules: give --keep or set keep in the prune section of %s", config.SettingsFile)
+	}
+	if !*dryRunPtr {
+		unlock, err := lockDataDir(fs.Name())
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	for _, class := range retention.Classes {
+		if rule, ok := policy[class]; ok {
+			fmt.Printf("Keeping %-10s %s\n", class, rule)
+		}
+	}
+	dataDir := config.GetDataDir()
+	files, err := retention.Plan(dataDir, policy, time.Now())
+	if err != nil {
+		return err
+	}
+	byClass := make(map[string][]retention.File)
+	for _, f := range files {
+		byClass[f.Class] = append(byClass[f.Class], f)
+		if *dryRunPtr {
+			fmt.Printf("Would remove %s (%s)\n", f.Path, f.Reason)
+		}
+	}
+	for _, class := range retention.Classes {
+		if _, ok := policy[class]; !ok {
+			continue
+		}
+		size := float64(retention.Size(byClass[class])) / (1 << 20)
+		verb := "would remove"
+		if !*dryRunPtr {
+			verb = "removing"
+		}
+		fmt.Printf("%s: %s %d file(s), %.1f MB\n", class, verb, len(byClass[class]), size)
+	}
+	if *dryRunPtr {
+		return nil
+	}
+	n, err := retention.Remove(dataDir, files)
+	fmt.Printf("Removed %d file(s)\n", n)
+	return err
+}
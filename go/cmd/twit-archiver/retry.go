@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/errs"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+	"github.com/aramova/twit-transcript-archiver/go/internal/scraper"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+func runRetry(args []string) error {
+	fs := flag.NewFlagSet("retry", flag.ExitOnError)
+	reportPtr := fs.String("errors", "", "Failure report to retry (default: errors.json in the data directory)")
+	notFoundPtr := fs.Bool("include-not-found", false, "Also retry targets that were not found last time")
+	throttlePtr := fs.Duration("throttle", 1*time.Second, "Duration to wait between requests")
+	parseFlags(fs, args)
+	unlock, err := lockDataDir(fs.Name())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	dataDir := config.GetDataDir()
+	path := *reportPtr
+	if path == "" {
+		path = storage.Join(dataDir, errs.ReportFile)
+	}
+	prev, err := errs.LoadReport(path)
+	if errors.Is(err, storage.ErrNotExist) {
+		fmt.Printf("No failure report at %s. Nothing to retry.\n", path)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(prev.Failures) == 0 {
+		fmt.Printf("%s lists no failures. Nothing to retry.\n", path)
+		return nil
+	}
+
+	remaining := errs.NewReport("twit-archiver retry")
+	reprocess := make(map[string]bool)
+	fixed, kept := 0, 0
+	for _, f := range prev.Failures {
+		if f.Class == "not_found" && !*notFoundPtr {
+			remaining.Failures = append(remaining.Failures, f)
+			kept++
+			continue
+		}
+		prefix, err := retryTarget(f.Target, dataDir, *throttlePtr)
+		if err != nil {
+			fmt.Printf("  [FAILED] %s: %v\n", f.Target, err)
+			remaining.Add(f.Target, err)
+			continue
+		}
+		fmt.Printf("  [OK]     %s\n", f.Target)
+		fixed++
+		if prefix != "" {
+			reprocess[prefix] = true
+		}
+	}
+
+	// Rebuild the chunks of every show that gained or repaired a transcript
+	var prefixes []string
+	for p := range reprocess {
+		prefixes = append(prefixes, p)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		if err := converter.ProcessPrefixWithOptions(prefix, dataDir, dataDir, converter.Options{Report: remaining, Quarantine: true}); err != nil {
+			remaining.Add(prefix, err)
+		}
+	}
+
+	if err := remaining.Write(path); err != nil {
+		return err
+	}
+	fmt.Printf("Retried %d of %d failures: %d fixed, %d still failing", len(prev.Failures)-kept, len(prev.Failures), fixed, remaining.Len()-kept)
+	if kept > 0 {
+		fmt.Printf(", %d not found skipped (use --include-not-found)", kept)
+	}
+	fmt.Printf(". Report updated: %s\n", path)
+	return nil
+}
+
+// retryTarget re-attempts one failed download or conversion. It returns the
+// prefix of a show whose chunks need rebuilding, if any. Targets are URLs
+// (transcript or listing pages), "PREFIX_N media" entries, transcript files
+// or show prefixes, as written by fetch-transcripts and process-transcripts.
+func retryTarget(target, dataDir string, throttle time.Duration) (string, error) {
+	switch {
+	case strings.HasSuffix(target, " media"):
+		key := strings.TrimSuffix(target, " media")
+		i := strings.LastIndex(key, "_")
+		if i < 0 {
+			return "", fmt.Errorf("unrecognized media target")
+		}
+		_, err := scraper.DownloadEpisodeMedia(key[:i], key[i+1:], dataDir, nil, throttle)
+		return "", err
+
+	case isListPage(target):
+		page, _ := scraper.ListPageNumber(target)
+		_, err := scraper.GetListPage(page, dataDir, true, throttle)
+		return "", err
+
+	case strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://"):
+		path, err := scraper.FetchURL(target, dataDir, throttle, scraper.IngestOptions{Force: true})
+		if err != nil {
+			return "", err
+		}
+		e, err := index.AddFile(dataDir, path)
+		if err != nil {
+			return "", err
+		}
+		return config.CanonicalPrefix(e.Prefix), nil
+
+	case strings.HasSuffix(target, ".html"):
+		m := config.PrefixRegex.FindStringSubmatch(storage.Base(target))
+		ep := converter.GetEpNum(target)
+		if m == nil || ep == 0 {
+			return "", fmt.Errorf("unrecognized transcript file")
+		}
+		if _, err := converter.LoadEpisode(target); err != nil {
+			// The saved page is damaged, so download it again
+			if _, err := scraper.FillGap(m[1], ep, dataDir, throttle); err != nil {
+				return "", err
+			}
+		}
+		e, err := index.AddFile(dataDir, target)
+		if err != nil {
+			return "", err
+		}
+		return config.CanonicalPrefix(e.Prefix), nil
+	}
+
+	// A show that could not be processed at all
+	return strings.TrimSpace(target), nil
+}
+
+// isListPage reports whether a failure target is a listing page URL
+func isListPage(target string) bool {
+	_, ok := scraper.ListPageNumber(target)
+	return ok
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/errs"
+	"github.com/aramova/twit-transcript-archiver/go/internal/health"
+	"github.com/aramova/twit-transcript-archiver/go/internal/scraper"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+	"github.com/aramova/twit-transcript-archiver/go/internal/utils"
+)
+
+// exportFormats are the formats run can export to
+var exportFormats = []string{"jsonl", "csv", "tsv", "segments", "sqlite", "epub", "srt", "vtt"}
+
+// showRun is what a run did for one show
+type showRun struct {
+	Prefix     string
+	Downloaded int
+	Skipped    int
+	Pending    int // Placeholders without a transcript yet
+	Deferred   int // Left for a later run by the show's quota
+	Left       int // Listed but not reached before --max-duration
+	Failed     int
+	Converted  bool
+	Chunks     int // Chunk files of the conversion
+	Changed    int // Of those, the ones whose content changed
+}
+
+// pipelineOptions are the flags of run that shape each pass
+type pipelineOptions struct {
+	shows     []string
+	formats   []string
+	pages     int
+	known     int // --stop-after-known
+	discovery string
+	throttle  time.Duration
+	perTurn   bool
+	force     bool
+	errors    string
+	schedule  *scraper.Schedule
+	budget    time.Duration // --max-duration of each pass
+}
+
+func runPipeline(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	showsPtr := fs.String("shows", "", "Comma-separated shows to update (default: IM and TWIG)")
+	exportPtr := fs.String("export", "", "Comma-separated exports to write when anything changed: "+strings.Join(exportFormats, ", "))
+	pagesPtr := fs.Int("pages", 10, "Search result or listing pages to scan for new transcripts")
+	discoveryPtr := fs.String("discovery", "auto", "How to find transcripts: list, search or auto")
+	throttlePtr := fs.Duration("throttle", 1*time.Second, "Duration to wait between requests")
+	perPtr := fs.String("per", "episode", "jsonl: one record per 'episode' or per speaker 'turn'")
+	forcePtr := fs.Bool("force", false, "Convert and export every show, even if nothing changed")
+	errorsPtr := fs.String("errors", "", "Where to write the JSON report of failures (default: errors.json in the data directory)")
+	everyPtr := fs.Duration("every", 0, "Keep running as a daemon, starting a run this often (e.g. 6h)")
+	healthPtr := fs.String("health-file", "", "Where to write the health file (default with --every: health.json in the data directory)")
+	priorityPtr := fs.String("priority", "", "Comma-separated shows to fetch first (e.g. SN,TWIT)")
+	maxDurationPtr := fs.Duration("max-duration", 0, "Stop fetching and converting once a run has taken this long (e.g. 30m); 0 for no limit")
+	stopAfterKnownPtr := fs.Int("stop-after-known", 0, "Stop paging the listing after this many consecutive pages with nothing new for the shows; 0 scans all --pages")
+	quotaPtr := fs.String("quota", "", "Most new transcripts to download per show in each run (e.g. SN=20,*=5, or 5 for every show)")
+	parseFlags(fs, args)
+
+	switch *discoveryPtr {
+	case "auto", "list", "search":
+	default:
+		return fmt.Errorf("unknown discovery mode '%s' (want auto, list or search)", *discoveryPtr)
+	}
+	if *perPtr != "episode" && *perPtr != "turn" {
+		return fmt.Errorf("unknown --per value '%s' (want episode or turn)", *perPtr)
+	}
+	schedule, err := scraper.ParseSchedule(*priorityPtr, *quotaPtr)
+	if err != nil {
+		return err
+	}
+	opts := pipelineOptions{pages: *pagesPtr, known: *stopAfterKnownPtr, discovery: *discoveryPtr, throttle: *throttlePtr, perTurn: *perPtr == "turn", force: *forcePtr, errors: *errorsPtr, schedule: schedule, budget: *maxDurationPtr}
+	for _, f := range strings.Split(*exportPtr, ",") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if f == "" {
+			continue
+		}
+		known := false
+		for _, ef := range exportFormats {
+			known = known || ef == f
+		}
+		if !known {
+			return fmt.Errorf("unknown export format '%s' (want %s)", f, strings.Join(exportFormats, ", "))
+		}
+		opts.formats = append(opts.formats, f)
+	}
+
+	names := fs.Args()
+	if *showsPtr != "" {
+		names = append(names, strings.Split(*showsPtr, ",")...)
+	}
+	if len(names) == 0 {
+		fmt.Println("No shows specified. Defaulting to IM and TWIG.")
+		names = []string{"IM", "TWIG"}
+	}
+	seen := make(map[string]bool)
+	for _, name := range names {
+		prefix, ok := config.ResolveShow(strings.TrimSpace(name))
+		if !ok {
+			return config.UnknownShowError(name)
+		}
+		if !seen[prefix] {
+			seen[prefix] = true
+			opts.shows = append(opts.shows, prefix)
+		}
+	}
+	sort.Strings(opts.shows)
+
+	healthPath := *healthPtr
+	if healthPath == "" && *everyPtr > 0 {
+		healthPath = storage.Join(config.GetDataDir(), health.FileName)
+	}
+	if *everyPtr > 0 {
+		return runDaemon(opts, *everyPtr, health.NewMonitor(healthPath, "twit-archiver run"))
+	}
+	var monitor *health.Monitor
+	if healthPath != "" {
+		monitor = health.NewMonitor(healthPath, "twit-archiver run")
+	}
+	unlock, err := lockDataDir(fs.Name())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	catchInterrupt(monitor)
+	monitor.StartRun()
+	runs, err := pipeline(opts, monitor)
+	if err == nil && interrupted.Load() {
+		err = errInterrupted
+	}
+	downloaded, failed := totals(runs)
+	monitor.FinishRun(downloaded, failed, err)
+	monitor.Stop()
+	return err
+}
+
+// errInterrupted is returned by a run stopped by SIGINT or SIGTERM
+var errInterrupted = errors.New("interrupted")
+
+// interrupted is set once run has been asked to stop. The pass in progress
+// checks it before each download and each file it converts.
+var interrupted atomic.Bool
+
+// catchInterrupt makes the first SIGINT or SIGTERM end the pass in progress
+// after the current download or file, still writing the failure report and
+// releasing the lock; a second one quits at once. The returned channel is
+// closed on the first.
+func catchInterrupt(monitor *health.Monitor) <-chan struct{} {
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	quit := make(chan struct{})
+	go func() {
+		<-sigs
+		fmt.Println("\nInterrupted: stopping after the current download or file. Interrupt again to quit now.")
+		interrupted.Store(true)
+		close(quit)
+		<-sigs
+		monitor.Stop()
+		os.Exit(errs.ExitError)
+	}()
+	return quit
+}
+
+// runDaemon starts a pass every interval until SIGINT or SIGTERM, taking
+// the data directory lock for each pass only. A pass that fails is
+// reported and the next one runs as usual. An interrupt ends the pass in
+// progress as it does for a single run. Between passes the monitor is kept
+// alive for systemd's watchdog.
+func runDaemon(opts pipelineOptions, every time.Duration, monitor *health.Monitor) error {
+	quit := catchInterrupt(monitor)
+
+	fmt.Printf("Running every %s; the health file is %s\n", every, monitor.Path)
+	monitor.Ready()
+	for !interrupted.Load() {
+		started := time.Now()
+		monitor.StartRun()
+		unlock, err := lockDataDir("run")
+		var runs map[string]*showRun
+		if err == nil {
+			runs, err = pipeline(opts, monitor)
+			unlock()
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+		}
+		downloaded, failed := totals(runs)
+		monitor.FinishRun(downloaded, failed, err)
+
+		if interrupted.Load() {
+			break
+		}
+		next := started.Add(every)
+		fmt.Printf("Next run at %s\n", next.Format("2006-01-02 15:04:05"))
+		monitor.Idle(next)
+		keepalive := keepaliveInterval(monitor)
+		for !interrupted.Load() && time.Now().Before(next) {
+			wait := time.Until(next)
+			if wait > keepalive {
+				wait = keepalive
+			}
+			select {
+			case <-time.After(wait):
+				monitor.Beat(health.Idle, "")
+			case <-quit:
+			}
+		}
+	}
+	monitor.Stop()
+	return nil
+}
+
+// keepaliveInterval is how often the monitor is beaten while nothing else
+// shows progress: as often as systemd's watchdog wants, else once a minute
+func keepaliveInterval(monitor *health.Monitor) time.Duration {
+	if d := monitor.Watchdog(); d > 0 {
+		return d
+	}
+	return time.Minute
+}
+
+// totals adds up the downloads and failures of a pass
+func totals(runs map[string]*showRun) (int, int) {
+	downloaded, failed := 0, 0
+	for _, r := range runs {
+		downloaded += r.Downloaded
+		failed += r.Failed
+	}
+	return downloaded, failed
+}
+
+// pipeline is one pass of run: fetch, convert, export and summary
+func pipeline(opts pipelineOptions, monitor *health.Monitor) (map[string]*showRun, error) {
+	shows := opts.shows
+	runs := make(map[string]*showRun)
+	for _, prefix := range shows {
+		runs[prefix] = &showRun{Prefix: prefix}
+	}
+
+	dataDir := config.GetDataDir()
+	report := errs.NewReport("twit-archiver run")
+	deadline := utils.NewDeadline(opts.budget)
+	stop := func() bool { return interrupted.Load() || deadline.Passed() }
+
+	// A cooldown asked for by the site can outlast the watchdog
+	if p := scraper.Politeness; p != nil && monitor != nil {
+		p.Beat = func() { monitor.Beat(health.Fetching, "paused by the site") }
+		p.BeatEvery = keepaliveInterval(monitor)
+	}
+
+	order := append([]string(nil), shows...)
+	opts.schedule.SortShows(order)
+	fmt.Printf("== Fetching %s ==\n", strings.Join(order, ", "))
+	crawler := &scraper.Crawler{
+		Shows:          order,
+		DataDir:        dataDir,
+		Discovery:      opts.discovery,
+		Pages:          opts.pages,
+		StopAfterKnown: opts.known,
+		Throttle:       opts.throttle,
+		Schedule:       opts.schedule,
+		Report:         report,
+		Stop:           stop,
+		Progress:       func(detail string) { monitor.Beat(health.Fetching, detail) },
+	}
+	crawl := crawler.Run()
+	for _, s := range crawl.Shows {
+		r := runs[s.Prefix]
+		r.Downloaded, r.Skipped, r.Pending = len(s.Downloaded), len(s.Skipped), len(s.Pending)
+		r.Deferred, r.Left, r.Failed = len(s.Deferred), len(s.Left), len(s.Failed)
+	}
+
+	fmt.Println("== Converting ==")
+	converted := 0
+	var unconverted []string // Left by --max-duration
+	for _, prefix := range shows {
+		if stop() {
+			unconverted = append(unconverted, prefix)
+			continue
+		}
+		monitor.Beat(health.Converting, prefix)
+		r := runs[prefix]
+		need := opts.force || r.Downloaded > 0
+		if !need {
+			var err error
+			if need, err = converter.NeedsProcessing(prefix, dataDir, dataDir); err != nil {
+				report.Add(prefix, err)
+				continue
+			}
+		}
+		if !need {
+			fmt.Printf("%s is up to date.\n", prefix)
+			continue
+		}
+		beat := func(path string) { monitor.Beat(health.Converting, prefix+": "+storage.Base(path)) }
+		res, err := converter.ProcessPrefixResult(prefix, dataDir, dataDir, converter.Options{TOC: true, Report: report, Quarantine: true, Stop: stop, Progress: beat})
+		if err != nil {
+			fmt.Printf("Error processing prefix %s: %v\n", prefix, err)
+			report.Add(prefix, err)
+			continue
+		}
+		if res.Stopped {
+			unconverted = append(unconverted, prefix)
+			continue
+		}
+		r.Converted = true
+		r.Chunks, r.Changed = len(res.Chunks), res.Changed()
+		converted++
+	}
+
+	var exported []string
+	if len(opts.formats) > 0 && (converted > 0 || opts.force) && !interrupted.Load() {
+		fmt.Println("== Exporting ==")
+		episodes, err := loadEpisodes(dataDir, shows)
+		if err != nil {
+			return runs, err
+		}
+		for _, format := range opts.formats {
+			monitor.Beat(health.Exporting, format)
+			if err := exportEpisodes(format, episodes, dataDir, "", opts.perTurn, 0); err != nil {
+				fmt.Printf("Error exporting %s: %v\n", format, err)
+				report.Add(format+" export", err)
+				continue
+			}
+			exported = append(exported, format)
+		}
+	}
+
+	path := opts.errors
+	if path == "" {
+		path = storage.Join(dataDir, errs.ReportFile)
+	}
+	if err := report.Write(path); err != nil {
+		fmt.Printf("Error writing %s: %v\n", path, err)
+	}
+
+	fmt.Println("\n========================================")
+	fmt.Println("           RUN SUMMARY")
+	fmt.Println("========================================")
+	fmt.Printf("%-8s %8s %8s %8s %8s  %s\n", "Show", "New", "Existing", "Pending", "Failed", "Converted")
+	deferred, left := 0, 0
+	for _, prefix := range shows {
+		r := runs[prefix]
+		conv := "no"
+		if r.Converted {
+			conv = fmt.Sprintf("yes (%d of %d chunks changed)", r.Changed, r.Chunks)
+		}
+		fmt.Printf("%-8s %8d %8d %8d %8d  %s\n", prefix, r.Downloaded, r.Skipped, r.Pending, r.Failed, conv)
+		deferred += r.Deferred
+		left += r.Left
+	}
+	if deferred > 0 {
+		fmt.Printf("Over quota: %d transcript(s) left for the next run\n", deferred)
+	}
+	if stop() {
+		if interrupted.Load() {
+			fmt.Println("Stopped early: interrupted. Rerun to continue.")
+		} else {
+			fmt.Printf("Stopped early: the --max-duration of %v was reached\n", deadline.Budget)
+		}
+		if left > 0 {
+			fmt.Printf("  - %d listed transcript(s) not fetched\n", left)
+		}
+		if len(unconverted) > 0 {
+			fmt.Printf("  - Not checked for conversion: %s\n", strings.Join(unconverted, ", "))
+		}
+	}
+	if p := scraper.Politeness.Stats(); p.Pushbacks+p.Slow > 0 {
+		fmt.Printf("Site pushback: %d rate limited, %d slow responses; slowed down up to %.0fx, paused %d time(s)\n", p.Pushbacks, p.Slow, p.MaxFactor, p.Pauses)
+	}
+	switch {
+	case len(opts.formats) == 0:
+	case len(exported) > 0:
+		fmt.Printf("Exports: %s\n", strings.Join(exported, ", "))
+	case interrupted.Load():
+		fmt.Println("Exports: skipped, interrupted")
+	case converted == 0:
+		fmt.Println("Exports: skipped, nothing changed (use --force to export anyway)")
+	default:
+		fmt.Println("Exports: none written")
+	}
+	fmt.Println("========================================")
+
+	if n := report.Len(); n > 0 {
+		return runs, fmt.Errorf("%d failure(s), written to %s", n, path)
+	}
+	return runs, nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/server"
+)
+
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addrPtr := fs.String("addr", "localhost:8080", "Address to listen on")
+	cachePtr := fs.Int("cache", 256, "Rendered responses and parsed episodes kept in memory (0 to disable)")
+	mcpPtr := fs.Bool("mcp", false, "Serve the Model Context Protocol on stdin/stdout instead of HTTP")
+	parseFlags(fs, args)
+
+	dataDir := config.GetDataDir()
+	srv := server.New(dataDir, *cachePtr)
+	if *mcpPtr {
+		// stdout carries the protocol, so nothing else may be printed there
+		return srv.ServeMCP(os.Stdin, os.Stdout)
+	}
+	fmt.Printf("Serving %s on http://%s/api/ (Ctrl-C to stop)\n", dataDir, *addrPtr)
+	return http.ListenAndServe(*addrPtr, srv.Handler())
+}
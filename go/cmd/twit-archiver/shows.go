@@ -0,0 +1,26 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/scraper"
+)
+
+func runShows(args []string) error {
+	fs := flag.NewFlagSet("shows", flag.ExitOnError)
+	parseFlags(fs, args)
+
+	dataDir := config.GetDataDir()
+	fmt.Printf("%-6s %-22s %8s  %s\n", "PREFIX", "NAME", "ARCHIVED", "DESCRIPTION")
+	for _, s := range config.Shows() {
+		nums, err := scraper.LocalEpisodes(s.Prefix, dataDir)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%-6s %-22s %8d  %s\n", s.Prefix, s.Name, len(nums), s.Description)
+	}
+	fmt.Println("\nShows can be given by prefix (any case) or by quoted name, e.g. SN or \"security now\".")
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/analysis"
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/export"
+	"github.com/aramova/twit-transcript-archiver/go/internal/utils"
+)
+
+func runStats(args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	speakersPtr := fs.Bool("speakers", false, "Report words per speaker, a proxy for talk time")
+	perEpisodePtr := fs.Bool("per-episode", false, "Also print each episode's speakers, not just show totals")
+	topPtr := fs.Int("top", 10, "Speakers printed per show or episode (0 for all)")
+	csvPtr := fs.String("csv", "", "Write per-episode and per-show rows to this CSV file instead of printing")
+	minWordsPtr := fs.String("min-words", "500", minWordsUsage)
+	prefixArgs, err := utils.ParseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	applySettings(fs)
+	if err := analysis.ParseMinWords(*minWordsPtr); err != nil {
+		return err
+	}
+
+	if !*speakersPtr {
+		return fmt.Errorf("usage: twit-archiver stats --speakers [--per-episode] [--csv FILE] [shows...]")
+	}
+	dataDir := config.GetDataDir()
+	prefixes, err := resolvePrefixes(dataDir, prefixArgs)
+	if err != nil {
+		return err
+	}
+	var episodes []converter.Episode
+	for _, prefix := range prefixes {
+		eps, err := converter.LoadEpisodes(prefix, dataDir)
+		if err != nil {
+			return err
+		}
+		// Keep each show's episodes together under one prefix, as SpeakerCSV
+		// expects, even when earlier eras are archived under another one
+		for i := range eps {
+			eps[i].Prefix = prefix
+		}
+		episodes = append(episodes, eps...)
+	}
+
+	if *csvPtr != "" {
+		err := writeOutput(*csvPtr, func(w io.Writer) error {
+			return export.SpeakerCSV(episodes, w, ',')
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Wrote speaker statistics for %d episodes to %s\n", len(episodes), *csvPtr)
+		return nil
+	}
+
+	for _, prefix := range prefixes {
+		var show []converter.Episode
+		for _, ep := range episodes {
+			if ep.Prefix == prefix {
+				show = append(show, ep)
+			}
+		}
+		fmt.Printf("%s (%s): %d episodes\n", config.ShowName(prefix), prefix, len(show))
+		if issues := analysis.CheckQuality(show); len(issues) > 0 {
+			fmt.Printf("  Warning: %d transcripts fail the quality checks and skew these numbers; run 'twit-archiver verify %s' for the list\n", len(issues), prefix)
+		}
+		printSpeakers(analysis.ShowSpeakers(show), *topPtr, true)
+		if *perEpisodePtr {
+			for _, ep := range show {
+				fmt.Printf("\n  %s %d: %s\n", prefix, ep.Number, ep.Title)
+				printSpeakers(analysis.EpisodeSpeakers(ep), *topPtr, false)
+			}
+		}
+		fmt.Println()
+	}
+	fmt.Println("Words are a proxy for talk time: transcripts carry no durations per turn.")
+	if items, err := converter.Quarantined(dataDir); err == nil && len(items) > 0 {
+		fmt.Printf("%d quarantined file(s) are not counted; run 'twit-archiver verify' for the list.\n", len(items))
+	}
+	return nil
+}
+
+func printSpeakers(stats []analysis.SpeakerStats, top int, episodes bool) {
+	if len(stats) == 0 {
+		fmt.Println("    (no speaker turns)")
+		return
+	}
+	for i, s := range stats {
+		if top > 0 && i == top {
+			fmt.Printf("    ... %d more\n", len(stats)-top)
+			break
+		}
+		if episodes {
+			fmt.Printf("    %-28s %6.1f%% %9d words %6d turns %5d episodes\n", s.Speaker, 100*s.Share, s.Words, s.Turns, s.Episodes)
+		} else {
+			fmt.Printf("    %-28s %6.1f%% %9d words %6d turns\n", s.Speaker, 100*s.Share, s.Words, s.Turns)
+		}
+	}
+}
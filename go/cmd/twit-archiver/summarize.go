@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+	"github.com/aramova/twit-transcript-archiver/go/internal/summarize"
+)
+
+func runSummarize(args []string) error {
+	fs := flag.NewFlagSet("summarize", flag.ExitOnError)
+	endpointPtr := fs.String("endpoint", envOr("TWIT_LLM_ENDPOINT", "http://localhost:11434/v1"), "OpenAI-compatible API base URL")
+	modelPtr := fs.String("model", envOr("TWIT_LLM_MODEL", "llama3.1"), "Chat model name")
+	maxCharsPtr := fs.Int("max-chars", summarize.MaxChars, "Transcript characters sent per episode (longer ones are cut in the middle)")
+	forcePtr := fs.Bool("force", false, "Summarize episodes again even if their transcript has not changed")
+	parseFlags(fs, args)
+	summarize.MaxChars = *maxCharsPtr
+	unlock, err := lockDataDir(fs.Name())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	dataDir := config.GetDataDir()
+	ix, err := index.Load(dataDir)
+	if err != nil {
+		return err
+	}
+	episodes, err := loadEpisodes(dataDir, fs.Args())
+	if err != nil {
+		return err
+	}
+
+	client := summarize.NewClient(*endpointPtr, *modelPtr, os.Getenv("OPENAI_API_KEY"))
+	fmt.Printf("Summarizing %d episodes with %s via %s\n", len(episodes), client.Model, client.Endpoint)
+	done, current := 0, 0
+	for _, ep := range episodes {
+		e := ix.Upsert(ep)
+		if !*forcePtr && e.Summary != nil && e.Summary.Source == summarize.Source(ep) {
+			current++
+			continue
+		}
+		s, err := client.Summarize(ep)
+		if err != nil {
+			fmt.Printf("Warning: %s %d: %v\n", ep.Prefix, ep.Number, err)
+			continue
+		}
+		e.Summary = s
+		// Save as we go, so an interrupted run keeps its summaries
+		if err := ix.Save(dataDir); err != nil {
+			return err
+		}
+		done++
+		fmt.Printf("Summarized %s %d: %s\n", ep.Prefix, ep.Number, s.Abstract)
+	}
+	fmt.Printf("Summarized %d episodes (%d already up to date, %d failed)\n", done, current, len(episodes)-done-current)
+	if done+current < len(episodes) {
+		return fmt.Errorf("%d episodes could not be summarized", len(episodes)-done-current)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/analysis"
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+)
+
+func runTag(args []string) error {
+	fs := flag.NewFlagSet("tag", flag.ExitOnError)
+	gazetteerPtr := fs.String("gazetteer", "", "Extra 'Name,category' entity list to match")
+	parseFlags(fs, args)
+	unlock, err := lockDataDir(fs.Name())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if *gazetteerPtr != "" {
+		if err := analysis.LoadGazetteer(*gazetteerPtr); err != nil {
+			return err
+		}
+	}
+
+	dataDir := config.GetDataDir()
+	ix, err := index.Load(dataDir)
+	if err != nil {
+		return err
+	}
+	episodes, err := loadEpisodes(dataDir, fs.Args())
+	if err != nil {
+		return err
+	}
+
+	for _, ep := range episodes {
+		tags := analysis.Extract(ep.Content)
+		ix.Upsert(ep).SetTags(tags.Entities, tags.Topics)
+	}
+	if err := ix.Save(dataDir); err != nil {
+		return err
+	}
+	fmt.Printf("Tagged %d episodes\n", len(episodes))
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/chapters"
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+	"github.com/aramova/twit-transcript-archiver/go/internal/scraper"
+	"github.com/aramova/twit-transcript-archiver/go/internal/transcribe"
+)
+
+func runTranscribe(args []string) error {
+	fs := flag.NewFlagSet("transcribe", flag.ExitOnError)
+	commandPtr := fs.String("command", envOr("TWIT_ASR_COMMAND", ""), "Speech recognition command, with {audio} for the audio file and optionally {out} for the output file without extension")
+	formatPtr := fs.String("format", "vtt", "Format of the command's result: vtt, srt, json or text")
+	endpointPtr := fs.String("endpoint", envOr("TWIT_ASR_ENDPOINT", ""), "OpenAI-compatible API base URL to send the audio to instead of running a command")
+	modelPtr := fs.String("model", envOr("TWIT_ASR_MODEL", "whisper-1"), "Transcription model name (with --endpoint)")
+	feedPtr := fs.String("feed", "", "RSS feed URL or file (default: the show's TWiT feed; one show only)")
+	limitPtr := fs.Int("limit", 0, "Transcribe at most this many episodes per show (0 for all)")
+	dryRunPtr := fs.Bool("dry-run", false, "Only list the episodes that would be transcribed")
+	forcePtr := fs.Bool("force", false, "Transcribe episodes that already have a transcript")
+	comparePtr := fs.Bool("compare", false, "Transcribe episodes with a published transcript, only keeping the result for 'twit-archiver compare'")
+	mediaBudgetPtr := fs.String("media-budget", "", "Stop downloading audio after this much data (e.g. 20G); empty for no limit")
+	throttlePtr := fs.Duration("throttle", 1*time.Second, "Duration to wait between requests")
+	parseFlags(fs, args)
+
+	var t transcribe.Transcriber
+	switch {
+	case *commandPtr != "" && *endpointPtr != "":
+		return fmt.Errorf("give --command or --endpoint, not both")
+	case *commandPtr != "":
+		c, err := transcribe.NewCommand(*commandPtr, *formatPtr)
+		if err != nil {
+			return err
+		}
+		t = c
+	case *endpointPtr != "":
+		t = transcribe.NewAPI(*endpointPtr, *modelPtr, os.Getenv("OPENAI_API_KEY"))
+	case !*dryRunPtr:
+		return fmt.Errorf("no speech recognizer configured (use --command or --endpoint)")
+	}
+	budget, err := scraper.ParseSize(*mediaBudgetPtr)
+	if err != nil {
+		return fmt.Errorf("invalid --media-budget: %v", err)
+	}
+
+	dataDir := config.GetDataDir()
+	prefixes, err := resolvePrefixes(dataDir, fs.Args())
+	if err != nil {
+		return err
+	}
+	if *feedPtr != "" && len(prefixes) != 1 {
+		return fmt.Errorf("--feed needs exactly one show")
+	}
+	if !*dryRunPtr {
+		unlock, err := lockDataDir(fs.Name())
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	opts := transcribe.Options{Force: *forcePtr, Budget: &scraper.MediaBudget{Max: budget}, Throttle: *throttlePtr}
+	done, failed := 0, 0
+	for _, prefix := range prefixes {
+		feed := *feedPtr
+		if feed == "" {
+			feed = chapters.FeedURL(prefix)
+		}
+		data, err := readSource(feed, *throttlePtr)
+		if err != nil {
+			return fmt.Errorf("%s feed: %v", prefix, err)
+		}
+		items, err := chapters.ParseFeed([]byte(data))
+		if err != nil {
+			return fmt.Errorf("%s feed: %v", prefix, err)
+		}
+		opts.Prefix = prefix
+		todo := transcribe.Candidates(items, dataDir, opts)
+		if *comparePtr {
+			todo = transcribe.ComparisonCandidates(items, dataDir, opts)
+		}
+		if *limitPtr > 0 && len(todo) > *limitPtr {
+			todo = todo[:*limitPtr]
+		}
+		fmt.Printf("%s: %d episodes to transcribe\n", prefix, len(todo))
+		for _, it := range todo {
+			if *dryRunPtr {
+				fmt.Printf("  %s %d: %s (%s)\n", prefix, it.Episode, it.Title, it.Audio)
+				continue
+			}
+			start := time.Now()
+			var path string
+			if *comparePtr {
+				path, err = transcribe.Run(t, it, dataDir, opts)
+			} else {
+				path, err = transcribe.Episode(t, it, dataDir, opts)
+			}
+			if errors.Is(err, scraper.ErrOutranked) {
+				fmt.Printf("%s %d: %v\n", prefix, it.Episode, err)
+				continue
+			}
+			if err != nil {
+				failed++
+				fmt.Printf("Warning: %s %d: %v\n", prefix, it.Episode, err)
+				continue
+			}
+			if !*comparePtr {
+				if _, err := index.AddFile(dataDir, path); err != nil {
+					return err
+				}
+			}
+			done++
+			fmt.Printf("Transcribed %s %d in %s -> %s\n", prefix, it.Episode, time.Since(start).Round(time.Second), path)
+		}
+	}
+	if !*dryRunPtr {
+		fmt.Printf("Transcribed %d episodes, %d failed\n", done, failed)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d episode(s) could not be transcribed", failed)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/translate"
+)
+
+func runTranslate(args []string) error {
+	fs := flag.NewFlagSet("translate", flag.ExitOnError)
+	toPtr := fs.String("to", "", "Comma-separated target languages (e.g. es,de)")
+	fromPtr := fs.String("from", "en", "Source language of episodes whose language was not detected")
+	endpointPtr := fs.String("endpoint", envOr("TWIT_TRANSLATE_ENDPOINT", "http://localhost:5000"), "LibreTranslate-compatible API base URL")
+	batchPtr := fs.Int("batch", 50, "Texts per translation request")
+	forcePtr := fs.Bool("force", false, "Translate episodes again even if their translation is cached")
+	parseFlags(fs, args)
+
+	if *toPtr == "" {
+		return fmt.Errorf("usage: twit-archiver translate --to <lang>[,<lang>...] [flags] [prefixes...]")
+	}
+	dataDir := config.GetDataDir()
+	episodes, err := loadEpisodes(dataDir, fs.Args())
+	if err != nil {
+		return err
+	}
+
+	t := translate.NewHTTPTranslator(*endpointPtr, os.Getenv("TWIT_TRANSLATE_API_KEY"))
+	for _, lang := range strings.Split(*toPtr, ",") {
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		opts := translate.Options{Source: *fromPtr, Target: lang, Batch: *batchPtr, Force: *forcePtr}
+		res, err := translate.Episodes(t, episodes, dataDir, opts)
+		fmt.Printf("%s: translated %d episodes, %d already translated\n", lang, res.Translated, res.Cached)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
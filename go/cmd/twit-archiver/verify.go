@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/analysis"
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+	"github.com/aramova/twit-transcript-archiver/go/internal/utils"
+)
+
+// minWordsUsage documents the --min-words flag of verify and stats
+const minWordsUsage = `Minimum words per transcript, optionally per show ("500,SN=2000,TWIET=300")`
+
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	minWordsPtr := fs.String("min-words", "500", minWordsUsage)
+	prefixArgs, err := utils.ParseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	applySettings(fs)
+	if err := analysis.ParseMinWords(*minWordsPtr); err != nil {
+		return err
+	}
+
+	dataDir := config.GetDataDir()
+	prefixes, err := resolvePrefixes(dataDir, prefixArgs)
+	if err != nil {
+		return err
+	}
+	total, failed := 0, 0
+	for _, prefix := range prefixes {
+		episodes, err := converter.LoadEpisodes(prefix, dataDir)
+		if err != nil {
+			return err
+		}
+		issues := analysis.CheckQuality(episodes)
+		total += len(episodes)
+		failed += len(issues)
+		if len(issues) == 0 {
+			fmt.Printf("%s: %d transcripts OK\n", prefix, len(episodes))
+			continue
+		}
+		fmt.Printf("%s: %d of %d transcripts fail the quality checks\n", prefix, len(issues), len(episodes))
+		for _, is := range issues {
+			fmt.Printf("  %5d  %s: %s\n", is.Episode.Number, is.Episode.Path, is.Problem)
+		}
+	}
+	if err := printQuarantine(dataDir); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d transcripts failed the quality checks. Download them again with 'fetch-transcripts --url URL --force'", failed, total)
+	}
+	fmt.Printf("All %d transcripts passed the quality checks.\n", total)
+	return nil
+}
+
+// printQuarantine lists the downloads that were set aside because they are
+// not transcripts
+func printQuarantine(dataDir string) error {
+	items, err := converter.Quarantined(dataDir)
+	if err != nil || len(items) == 0 {
+		return err
+	}
+	fmt.Printf("%d file(s) in %s, set aside as not transcripts:\n", len(items), storage.Join(dataDir, converter.QuarantineDir))
+	for _, q := range items {
+		fmt.Printf("  %s  %s (from %s): %s\n", q.At.Format("2006-01-02"), storage.Base(q.Path), q.From, q.Reason)
+	}
+	fmt.Println("Download them again with 'fetch-transcripts --url URL --force', then delete them from the quarantine.")
+	return nil
+}
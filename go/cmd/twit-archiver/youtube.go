@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+	"github.com/aramova/twit-transcript-archiver/go/internal/scraper"
+)
+
+func runYouTube(args []string) error {
+	fs := flag.NewFlagSet("youtube", flag.ExitOnError)
+	channelPtr := fs.String("channel", "", "YouTube channel id (UC...) whose latest videos to check")
+	playlistPtr := fs.String("playlist", "", "YouTube playlist id (PL...) to check instead of a channel")
+	showPtr := fs.String("show", "", "Show prefix, if the video titles do not name the show")
+	langPtr := fs.String("lang", "en", "Caption language")
+	autoPtr := fs.Bool("auto", true, "Fall back to automatically generated captions")
+	forcePtr := fs.Bool("force", false, "Replace already archived episodes with their captions")
+	throttlePtr := fs.Duration("throttle", 1*time.Second, "Duration to wait between requests")
+	parseFlags(fs, args)
+
+	feed, err := scraper.VideoFeedURL(*channelPtr, *playlistPtr)
+	if err != nil {
+		return fmt.Errorf("%v (use --channel or --playlist, or set them in the youtube section of %s)", err, config.SettingsFile)
+	}
+	unlock, err := lockDataDir(fs.Name())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := scraper.DownloadPage(feed, *throttlePtr)
+	if err != nil {
+		return err
+	}
+	videos, err := scraper.ParseVideoFeed([]byte(data))
+	if err != nil {
+		return err
+	}
+
+	dataDir := config.GetDataDir()
+	opts := scraper.IngestOptions{Prefix: strings.ToUpper(*showPtr), Force: *forcePtr}
+	added, archived, kept, uncaptioned, failed := 0, 0, 0, 0, 0
+	for _, v := range videos {
+		path, err := scraper.IngestVideo(v, dataDir, *throttlePtr, *langPtr, *autoPtr, opts)
+		switch {
+		case errors.Is(err, scraper.ErrArchived):
+			archived++
+			continue
+		case errors.Is(err, scraper.ErrOutranked):
+			kept++
+			fmt.Printf("%s (%s): %v\n", v.Title, v.ID, err)
+			continue
+		case errors.Is(err, scraper.ErrNoCaptions):
+			uncaptioned++
+			continue
+		case err != nil:
+			failed++
+			fmt.Printf("Warning: %s (%s): %v\n", v.Title, v.ID, err)
+			continue
+		}
+		e, err := index.AddFile(dataDir, path)
+		if err != nil {
+			return err
+		}
+		added++
+		fmt.Printf("%s -> %s (%s %d: %s)\n", v.WatchURL(), path, e.Prefix, e.Number, e.Title)
+	}
+	fmt.Printf("%d videos: %d added from captions, %d already archived, %d kept as alternatives, %d without captions, %d failed\n",
+		len(videos), added, archived, kept, uncaptioned, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d video(s) could not be ingested", failed)
+	}
+	return nil
+}
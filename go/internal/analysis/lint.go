@@ -0,0 +1,245 @@
+package analysis
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// Lint rules, as reported in Violation.Rule
+const (
+	RuleTitle  = "title"  // Episode without a title
+	RuleDate   = "date"   // Episode without a date
+	RuleTurns  = "turns"  // Episode without a speaker turn
+	RuleHTML   = "html"   // HTML tag left in the text
+	RuleEntity = "entity" // HTML entity left undecoded
+	RuleFormat = "format" // File that cannot be read as its format
+)
+
+// Violation is a structural problem in a generated file
+type Violation struct {
+	File    string
+	Line    int    // 1-based; 0 for the whole file
+	Episode string // Title or id of the episode, if known
+	Rule    string
+	Message string
+}
+
+func (v Violation) String() string {
+	loc := v.File
+	if v.Line > 0 {
+		loc = fmt.Sprintf("%s:%d", loc, v.Line)
+	}
+	if v.Episode != "" {
+		return fmt.Sprintf("%s: %s: %s (%s)", loc, v.Rule, v.Message, v.Episode)
+	}
+	return fmt.Sprintf("%s: %s: %s", loc, v.Rule, v.Message)
+}
+
+var (
+	// episodeHeadingRegex matches the heading of the built-in episode
+	// template
+	episodeHeadingRegex = regexp.MustCompile(`^# Episode: ?(.*?)(?: \(continued\))?$`)
+	dateLineRegex       = regexp.MustCompile(`^\*\*Date:\*\* ?(.*)$`)
+	// turnLineRegex matches a speaker turn ("EP:950 Date:23-11-11 TS:0:00:00 - Leo Laporte ...")
+	turnLineRegex = regexp.MustCompile(`^EP:\d+ Date:\S* (?:TS:\S+ )?-`)
+	// anchorTagRegex matches the episode anchors chunk files have on purpose
+	anchorTagRegex  = regexp.MustCompile(`<a id="[^"<>]*"></a>`)
+	htmlTagRegex    = regexp.MustCompile(`</?[A-Za-z][A-Za-z0-9-]*(?:\s[^<>]*)?/?>`)
+	htmlEntityRegex = regexp.MustCompile(`&(?:[A-Za-z][A-Za-z0-9]*|#[0-9]+|#[xX][0-9A-Fa-f]+);`)
+)
+
+// LintFile checks a generated file against the structural rules: Markdown
+// (.md) from process-transcripts, and JSON Lines (.jsonl) or JSON (.json)
+// episode or turn records from the JSONL export
+func LintFile(path string) ([]Violation, error) {
+	data, err := storage.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case strings.HasSuffix(path, ".md"):
+		return LintMarkdown(path, string(data)), nil
+	case strings.HasSuffix(path, ".jsonl"):
+		return LintJSONL(path, strings.NewReader(string(data))), nil
+	case strings.HasSuffix(path, ".json"):
+		return lintJSON(path, data), nil
+	}
+	return nil, fmt.Errorf("cannot lint %s: not a .md, .jsonl or .json file", path)
+}
+
+// lintEpisode collects what an episode of a Markdown file has
+type lintEpisode struct {
+	line          int
+	title, date   string
+	hasDate       bool
+	turns         int
+	frontHasTitle bool
+}
+
+// LintMarkdown checks chunk and per-episode files, as written with the
+// built-in episode template: every episode has a title, a date and at
+// least one speaker turn, and no text has HTML tags (other than the
+// episode anchors) or entities left in it. Per-episode files may take the
+// title and date from their front matter.
+func LintMarkdown(name, text string) []Violation {
+	var out []Violation
+	var episodes []*lintEpisode
+	var cur *lintEpisode
+	front := &lintEpisode{}
+
+	lines := strings.Split(text, "\n")
+	start := 0
+	if len(lines) > 0 && lines[0] == "---" {
+		for i := 1; i < len(lines); i++ {
+			if lines[i] == "---" {
+				start = i + 1
+				break
+			}
+			key, value, _ := strings.Cut(lines[i], ":")
+			value = strings.Trim(strings.TrimSpace(value), `"`)
+			switch key {
+			case "title":
+				front.frontHasTitle = value != ""
+			case "date":
+				front.hasDate, front.date = value != "", value
+			}
+		}
+	}
+
+	for i := start; i < len(lines); i++ {
+		line := lines[i]
+		if m := episodeHeadingRegex.FindStringSubmatch(line); m != nil {
+			cur = &lintEpisode{line: i + 1, title: strings.TrimSpace(m[1])}
+			episodes = append(episodes, cur)
+		} else if m := dateLineRegex.FindStringSubmatch(line); m != nil && cur != nil && !cur.hasDate {
+			cur.date = strings.TrimSpace(m[1])
+			cur.hasDate = cur.date != ""
+		} else if cur != nil && turnLineRegex.MatchString(line) {
+			cur.turns++
+		}
+		out = append(out, lintText(name, i+1, episodeName(cur), line)...)
+	}
+
+	if len(episodes) == 0 {
+		return append([]Violation{{File: name, Rule: RuleFormat, Message: `no "# Episode:" headings (written with a custom episode template?)`}}, out...)
+	}
+	for _, ep := range episodes {
+		if ep.title == "" && !front.frontHasTitle {
+			out = append(out, Violation{File: name, Line: ep.line, Rule: RuleTitle, Message: "episode has no title"})
+		}
+		if !ep.hasDate && !front.hasDate {
+			out = append(out, Violation{File: name, Line: ep.line, Episode: ep.title, Rule: RuleDate, Message: "episode has no date"})
+		}
+		if ep.turns == 0 {
+			out = append(out, Violation{File: name, Line: ep.line, Episode: ep.title, Rule: RuleTurns, Message: "episode has no speaker turns"})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Line < out[j].Line })
+	return out
+}
+
+func episodeName(ep *lintEpisode) string {
+	if ep == nil {
+		return ""
+	}
+	return ep.title
+}
+
+// lintText reports HTML tags and entities left in a line of text
+func lintText(name string, line int, episode, text string) []Violation {
+	var out []Violation
+	text = anchorTagRegex.ReplaceAllString(text, "")
+	if tag := htmlTagRegex.FindString(text); tag != "" {
+		out = append(out, Violation{File: name, Line: line, Episode: episode, Rule: RuleHTML, Message: "leftover HTML tag " + tag})
+	}
+	if entity := htmlEntityRegex.FindString(text); entity != "" {
+		out = append(out, Violation{File: name, Line: line, Episode: episode, Rule: RuleEntity, Message: "unresolved entity " + entity})
+	}
+	return out
+}
+
+// lintRecord is the part of an export record the rules look at
+type lintRecord struct {
+	ID    string  `json:"id"`
+	Title *string `json:"title"`
+	Date  string  `json:"date"`
+	Text  *string `json:"text"`
+	Turn  *int    `json:"turn"`
+}
+
+// LintJSONL checks JSON Lines episode or turn records: every line is a
+// JSON object with a title, a date and non-empty text, free of HTML tags
+// and entities
+func LintJSONL(name string, r io.Reader) []Violation {
+	var out []Violation
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	n := 0
+	for sc.Scan() {
+		n++
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+		var rec lintRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			out = append(out, Violation{File: name, Line: n, Rule: RuleFormat, Message: "invalid JSON: " + err.Error()})
+			continue
+		}
+		out = append(out, lintRecordRules(name, n, rec)...)
+	}
+	if err := sc.Err(); err != nil {
+		out = append(out, Violation{File: name, Line: n + 1, Rule: RuleFormat, Message: err.Error()})
+	}
+	return out
+}
+
+// lintJSON checks a JSON file holding one record or an array of them
+func lintJSON(name string, data []byte) []Violation {
+	var recs []lintRecord
+	if err := json.Unmarshal(data, &recs); err != nil {
+		var rec lintRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return []Violation{{File: name, Rule: RuleFormat, Message: "not a JSON record or array of records: " + err.Error()}}
+		}
+		recs = []lintRecord{rec}
+	}
+	var out []Violation
+	for _, rec := range recs {
+		out = append(out, lintRecordRules(name, 0, rec)...)
+	}
+	return out
+}
+
+func lintRecordRules(name string, line int, rec lintRecord) []Violation {
+	var out []Violation
+	v := func(rule, msg string) {
+		out = append(out, Violation{File: name, Line: line, Episode: rec.ID, Rule: rule, Message: msg})
+	}
+	if rec.Title == nil || strings.TrimSpace(*rec.Title) == "" {
+		v(RuleTitle, "record has no title")
+	}
+	if rec.Date == "" {
+		v(RuleDate, "record has no date")
+	}
+	if rec.Text == nil || strings.TrimSpace(*rec.Text) == "" {
+		if rec.Turn != nil {
+			v(RuleTurns, "turn has no text")
+		} else {
+			v(RuleTurns, "episode has no speaker turns")
+		}
+		return out
+	}
+	for _, field := range []*string{rec.Title, rec.Text} {
+		if field != nil {
+			out = append(out, lintText(name, line, rec.ID, *field)...)
+		}
+	}
+	return out
+}
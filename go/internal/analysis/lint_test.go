@@ -0,0 +1,68 @@
+package analysis
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func rules(vs []Violation) string {
+	var r []string
+	for _, v := range vs {
+		r = append(r, v.Rule)
+	}
+	return strings.Join(r, ",")
+}
+
+func TestLintMarkdown(t *testing.T) {
+	good := "## Contents\n\n* [Episode 1](#sn-1): Ep 1\n\n---\n\n" +
+		"<a id=\"sn-1\"></a>\n# Episode: Ep 1\n**Date:** Feb 11th 2025\n\nEP:1 Date:25-02-11 TS:0:00:00 - Leo Laporte Hello & welcome.\n\n---\n\n"
+	if vs := LintMarkdown("good.md", good); len(vs) != 0 {
+		t.Errorf("Unexpected violations: %v", vs)
+	}
+
+	bad := "# Episode: \n**Date:** \n\nNo turns here.\n\n---\n\n" +
+		"# Episode: Ep 2\n**Date:** Feb 12th 2025\n\nEP:2 Date:25-02-12 - Leo <b>Hello</b> &amp; bye\n"
+	vs := LintMarkdown("bad.md", bad)
+	if got := rules(vs); got != "title,date,turns,html,entity" {
+		t.Errorf("Unexpected violations %s: %v", got, vs)
+	}
+	if vs[3].Line != 11 || vs[3].Episode != "Ep 2" {
+		t.Errorf("Unexpected location of %v", vs[3])
+	}
+
+	// Per-episode files may have their title and date in the front matter
+	front := "---\ntitle: \"Ep 3\"\ndate: 2025-02-13\n---\n\n# Episode: \n**Date:** \n\nEP:3 Date:25-02-13 - Leo Hi\n"
+	if vs := LintMarkdown("ep.md", front); len(vs) != 0 {
+		t.Errorf("Unexpected violations with front matter: %v", vs)
+	}
+
+	if vs := LintMarkdown("custom.md", "Some text\n"); rules(vs) != RuleFormat {
+		t.Errorf("Expected a format violation without episode headings: %v", vs)
+	}
+}
+
+func TestLintJSONL(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "corpus.jsonl")
+	os.WriteFile(path, []byte(`{"id":"SN_1","title":"Ep 1","date":"2025-02-11","text":"Hello"}
+{"id":"SN_2","title":"","text":"Hi &lt;there&gt;"}
+not json
+{"id":"SN_3_1","title":"Ep 3","date":"2025-02-13","turn":1,"text":""}
+`), 0644)
+	vs, err := LintFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rules(vs); got != "title,date,entity,format,turns" {
+		t.Errorf("Unexpected violations %s: %v", got, vs)
+	}
+	if vs[0].Line != 2 || vs[0].Episode != "SN_2" {
+		t.Errorf("Unexpected location of %v", vs[0])
+	}
+
+	if _, err := LintFile(filepath.Join(dir, "notes.txt")); err == nil {
+		t.Error("Expected an error for an unknown file type")
+	}
+}
@@ -0,0 +1,87 @@
+package analysis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+// MinWords is the least words a transcript needs to pass the quality gate.
+// Shorter ones are usually truncated downloads or partial pages.
+var MinWords = 500
+
+// ShowMinWords overrides MinWords per show prefix, for shows whose episodes
+// are short by design
+var ShowMinWords = map[string]int{}
+
+// Issue is a transcript that fails a quality gate
+type Issue struct {
+	Episode converter.Episode
+	Words   int
+	Problem string
+}
+
+// MinWordsFor returns the minimum word count of a show
+func MinWordsFor(prefix string) int {
+	if n, ok := ShowMinWords[strings.ToUpper(prefix)]; ok {
+		return n
+	}
+	return MinWords
+}
+
+// ParseMinWords sets MinWords and ShowMinWords from a spec such as
+// "500,SN=2000,TWIET=300": a bare number is the default, PREFIX=N a show's
+// own minimum
+func ParseMinWords(spec string) error {
+	shows := make(map[string]int)
+	minimum := MinWords
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		prefix, value, isShow := strings.Cut(part, "=")
+		if !isShow {
+			value = prefix
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid minimum word count %q", part)
+		}
+		if isShow {
+			shows[strings.ToUpper(strings.TrimSpace(prefix))] = n
+		} else {
+			minimum = n
+		}
+	}
+	MinWords, ShowMinWords = minimum, shows
+	return nil
+}
+
+// TranscriptWords counts the words of an episode's transcript text, without
+// the EP:/Date: prefixes of its turn lines
+func TranscriptWords(ep converter.Episode) int {
+	return len(strings.Fields(stripTurnPrefixes(ep.Content)))
+}
+
+// CheckQuality returns the episodes that fail a quality gate: placeholders
+// without a transcript yet, transcripts shorter than their show's minimum
+// word count, and transcripts with HTML left over even after the strict
+// markup pass
+func CheckQuality(episodes []converter.Episode) []Issue {
+	var issues []Issue
+	for _, ep := range episodes {
+		words := TranscriptWords(ep)
+		switch minimum := MinWordsFor(ep.Prefix); {
+		case ep.Pending:
+			issues = append(issues, Issue{ep, words, "no transcript yet (pending)"})
+		case words < minimum:
+			issues = append(issues, Issue{ep, words, fmt.Sprintf("%d words, under the minimum of %d", words, minimum)})
+		case len(ep.Residual) > 0:
+			issues = append(issues, Issue{ep, words, "leftover HTML: " + strings.Join(ep.Residual, " ")})
+		}
+	}
+	return issues
+}
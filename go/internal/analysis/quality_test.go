@@ -0,0 +1,58 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+func TestParseMinWords(t *testing.T) {
+	savedMin, savedShows := MinWords, ShowMinWords
+	defer func() { MinWords, ShowMinWords = savedMin, savedShows }()
+
+	if err := ParseMinWords("300, sn=2000,TWIET=100"); err != nil {
+		t.Fatal(err)
+	}
+	if MinWordsFor("SN") != 2000 || MinWordsFor("TWIET") != 100 || MinWordsFor("IM") != 300 {
+		t.Errorf("MinWords = %d, ShowMinWords = %v", MinWords, ShowMinWords)
+	}
+	for _, bad := range []string{"lots", "SN=", "-5"} {
+		if err := ParseMinWords(bad); err == nil {
+			t.Errorf("ParseMinWords(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestCheckQuality(t *testing.T) {
+	savedMin, savedShows := MinWords, ShowMinWords
+	defer func() { MinWords, ShowMinWords = savedMin, savedShows }()
+	MinWords, ShowMinWords = 10, map[string]int{"SN": 20}
+
+	words := func(n int) string {
+		return "EP:1 Date:22-01-01 - " + strings.TrimSpace(strings.Repeat("word ", n))
+	}
+	episodes := []converter.Episode{
+		{Prefix: "IM", Number: 1, Content: words(12)},
+		{Prefix: "IM", Number: 2, Content: words(9)},
+		{Prefix: "SN", Number: 3, Content: words(12)},
+		{Prefix: "SN", Number: 4, Content: words(30), Pending: true},
+		{Prefix: "SN", Number: 5, Content: words(30), Residual: []string{"</div"}},
+	}
+	issues := CheckQuality(episodes)
+	if len(issues) != 4 {
+		t.Fatalf("got %d issues: %+v", len(issues), issues)
+	}
+	if issues[0].Episode.Number != 2 || issues[0].Words != 9 {
+		t.Errorf("issues[0] = %+v", issues[0])
+	}
+	if issues[1].Episode.Number != 3 || !strings.Contains(issues[1].Problem, "minimum of 20") {
+		t.Errorf("issues[1] = %+v", issues[1])
+	}
+	if issues[2].Episode.Number != 4 || !strings.Contains(issues[2].Problem, "pending") {
+		t.Errorf("issues[2] = %+v", issues[2])
+	}
+	if issues[3].Episode.Number != 5 || issues[3].Problem != "leftover HTML: </div" {
+		t.Errorf("issues[3] = %+v", issues[3])
+	}
+}
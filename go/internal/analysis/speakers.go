@@ -0,0 +1,71 @@
+package analysis
+
+import (
+	"sort"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+// UnknownSpeaker labels turns whose speaker the page does not give
+const UnknownSpeaker = "(unknown)"
+
+// SpeakerStats is how much one speaker talked, as a proxy for talk time
+type SpeakerStats struct {
+	Speaker  string
+	Episodes int // Episodes the speaker has turns in
+	Turns    int
+	Words    int
+	Share    float64 // Fraction of all words in the episode or show
+}
+
+// EpisodeSpeakers totals an episode's turns per speaker, most words first
+func EpisodeSpeakers(ep converter.Episode) []SpeakerStats {
+	return speakerTotals([]converter.Episode{ep})
+}
+
+// ShowSpeakers totals the turns of a show's episodes per speaker, most
+// words first
+func ShowSpeakers(episodes []converter.Episode) []SpeakerStats {
+	return speakerTotals(episodes)
+}
+
+func speakerTotals(episodes []converter.Episode) []SpeakerStats {
+	bySpeaker := make(map[string]*SpeakerStats)
+	total := 0
+	for _, ep := range episodes {
+		seen := make(map[string]bool)
+		for _, t := range ep.Turns {
+			name := t.Speaker
+			if name == "" {
+				name = UnknownSpeaker
+			}
+			s, ok := bySpeaker[name]
+			if !ok {
+				s = &SpeakerStats{Speaker: name}
+				bySpeaker[name] = s
+			}
+			if !seen[name] {
+				seen[name] = true
+				s.Episodes++
+			}
+			s.Turns++
+			s.Words += t.Words
+			total += t.Words
+		}
+	}
+
+	stats := make([]SpeakerStats, 0, len(bySpeaker))
+	for _, s := range bySpeaker {
+		if total > 0 {
+			s.Share = float64(s.Words) / float64(total)
+		}
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Words != stats[j].Words {
+			return stats[i].Words > stats[j].Words
+		}
+		return stats[i].Speaker < stats[j].Speaker
+	})
+	return stats
+}
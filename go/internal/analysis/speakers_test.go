@@ -0,0 +1,47 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+func TestSpeakerStats(t *testing.T) {
+	episodes := []converter.Episode{
+		{Number: 1, Turns: []converter.Turn{
+			{Speaker: "Leo Laporte", Words: 60},
+			{Speaker: "Jeff Jarvis", Words: 20},
+			{Speaker: "Leo Laporte", Words: 20},
+		}},
+		{Number: 2, Turns: []converter.Turn{
+			{Speaker: "Leo Laporte", Words: 30},
+			{Words: 70},
+		}},
+	}
+
+	ep := EpisodeSpeakers(episodes[0])
+	if len(ep) != 2 || ep[0].Speaker != "Leo Laporte" || ep[0].Turns != 2 || ep[0].Words != 80 || ep[0].Share != 0.8 {
+		t.Errorf("EpisodeSpeakers = %+v", ep)
+	}
+
+	show := ShowSpeakers(episodes)
+	want := []SpeakerStats{
+		{Speaker: "Leo Laporte", Episodes: 2, Turns: 3, Words: 110, Share: 0.55},
+		{Speaker: UnknownSpeaker, Episodes: 1, Turns: 1, Words: 70, Share: 0.35},
+		{Speaker: "Jeff Jarvis", Episodes: 1, Turns: 1, Words: 20, Share: 0.1},
+	}
+	if len(show) != len(want) {
+		t.Fatalf("ShowSpeakers = %+v", show)
+	}
+	for i, w := range want {
+		got := show[i]
+		if got.Speaker != w.Speaker || got.Episodes != w.Episodes || got.Turns != w.Turns || got.Words != w.Words || math.Abs(got.Share-w.Share) > 1e-9 {
+			t.Errorf("ShowSpeakers[%d] = %+v, want %+v", i, got, w)
+		}
+	}
+
+	if got := ShowSpeakers(nil); len(got) != 0 {
+		t.Errorf("No episodes gave %+v", got)
+	}
+}
@@ -0,0 +1,195 @@
+package analysis
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Entity categories
+const (
+	Company = "company"
+	Product = "product"
+	Person  = "person"
+	CVE     = "cve"
+)
+
+// Gazetteer maps a canonical name to its category. Matching is
+// case-sensitive on word boundaries so "Apple" the company does not match
+// "apple pie".
+var Gazetteer = map[string]string{
+	"Amazon": Company, "AMD": Company, "Anthropic": Company, "Apple": Company,
+	"Broadcom": Company, "Cisco": Company, "Cloudflare": Company, "CrowdStrike": Company,
+	"Dell": Company, "Facebook": Company, "Google": Company, "IBM": Company,
+	"Intel": Company, "LastPass": Company, "Meta": Company, "Microsoft": Company,
+	"Mozilla": Company, "Netflix": Company, "Nvidia": Company, "NVIDIA": Company,
+	"OpenAI": Company, "Oracle": Company, "Qualcomm": Company, "Samsung": Company,
+	"SpaceX": Company, "Tesla": Company, "TikTok": Company, "Twitter": Company,
+	"Verizon": Company, "AT&T": Company,
+
+	"Android": Product, "Bitwarden": Product, "ChatGPT": Product, "Chrome": Product,
+	"Claude": Product, "Copilot": Product, "Edge": Product, "Firefox": Product,
+	"Gemini": Product, "GitHub": Product, "iOS": Product, "iPad": Product,
+	"iPhone": Product, "Kubernetes": Product, "Linux": Product, "macOS": Product,
+	"Mastodon": Product, "OpenSSH": Product, "OpenSSL": Product, "Outlook": Product,
+	"Pixel": Product, "Safari": Product, "Signal": Product, "SpinRite": Product,
+	"Threads": Product, "Vision Pro": Product, "WhatsApp": Product, "Windows": Product,
+	"WordPress": Product, "YouTube": Product,
+}
+
+// Topics maps a topic tag to the (lowercase) keywords that indicate it
+var Topics = map[string][]string{
+	"ai":           {"artificial intelligence", "machine learning", "large language model", "llm", "chatbot", "neural network"},
+	"security":     {"vulnerability", "exploit", "malware", "ransomware", "zero-day", "patch tuesday", "phishing", "breach"},
+	"privacy":      {"privacy", "tracking", "surveillance", "gdpr", "data broker"},
+	"crypto":       {"bitcoin", "cryptocurrency", "blockchain", "ethereum"},
+	"space":        {"rocket", "nasa", "orbit", "satellite", "launch pad"},
+	"regulation":   {"antitrust", "ftc", "regulation", "lawsuit", "supreme court", "congress"},
+	"hardware":     {"processor", "chipset", "gpu", "semiconductor"},
+	"social-media": {"social media", "social network"},
+}
+
+// MinTopicMentions is how many keyword hits an episode needs to get a topic
+var MinTopicMentions = 3
+
+var (
+	cveRegex = regexp.MustCompile(`\bCVE-\d{4}-\d{4,7}\b`)
+	// personRegex matches capitalized two-word names
+	personRegex = regexp.MustCompile(`\b([A-Z][a-z]+(?:-[A-Z][a-z]+)?) ([A-Z][a-z]+(?:-[A-Z][a-z]+)?)\b`)
+	// Words that often start capitalized bigrams that are not names
+	nonNameWords = map[string]bool{
+		"The": true, "This": true, "That": true, "And": true, "But": true, "So": true,
+		"Well": true, "Yeah": true, "Now": true, "Oh": true, "Okay": true, "Thank": true,
+		"Week": true, "Security": true, "Windows": true, "Tech": true, "News": true,
+		"United": true, "New": true, "North": true, "South": true, "San": true, "Los": true,
+	}
+	gazetteerRegexes = compileGazetteer()
+)
+
+func compileGazetteer() map[string]*regexp.Regexp {
+	res := make(map[string]*regexp.Regexp, len(Gazetteer))
+	for name := range Gazetteer {
+		res[name] = regexp.MustCompile(`(?:^|[^\w])` + regexp.QuoteMeta(name) + `(?:$|[^\w])`)
+	}
+	return res
+}
+
+// LoadGazetteer adds "Name,category" lines from a file to the gazetteer.
+// Blank lines and lines starting with # are ignored.
+func LoadGazetteer(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, category, ok := strings.Cut(line, ",")
+		if !ok {
+			return fmt.Errorf("%s:%d: expected 'Name,category'", path, n)
+		}
+		name = strings.TrimSpace(name)
+		Gazetteer[name] = strings.ToLower(strings.TrimSpace(category))
+		gazetteerRegexes[name] = regexp.MustCompile(`(?:^|[^\w])` + regexp.QuoteMeta(name) + `(?:$|[^\w])`)
+	}
+	return scanner.Err()
+}
+
+// Tags holds the entities and topics found in one transcript
+type Tags struct {
+	Entities map[string][]string
+	Topics   []string
+}
+
+// Extract finds companies, products, people, CVEs and topics in a
+// standardized Markdown transcript. People are capitalized name pairs that
+// appear at least twice, which includes the speakers named in turn prefixes.
+func Extract(content string) Tags {
+	text := stripTurnPrefixes(content)
+	entities := make(map[string][]string)
+
+	for name, category := range Gazetteer {
+		if gazetteerRegexes[name].MatchString(text) {
+			entities[category] = append(entities[category], name)
+		}
+	}
+
+	seenCVE := make(map[string]bool)
+	for _, id := range cveRegex.FindAllString(text, -1) {
+		if !seenCVE[id] {
+			seenCVE[id] = true
+			entities[CVE] = append(entities[CVE], id)
+		}
+	}
+
+	people := make(map[string]bool)
+	counts := make(map[string]int)
+	for _, m := range personRegex.FindAllStringSubmatch(text, -1) {
+		if nonNameWords[m[1]] || Gazetteer[m[1]] != "" || Gazetteer[m[2]] != "" {
+			continue
+		}
+		counts[m[0]]++
+	}
+	for name, n := range counts {
+		if n >= 2 {
+			people[name] = true
+		}
+	}
+	for name := range people {
+		entities[Person] = append(entities[Person], name)
+	}
+
+	lower := strings.ToLower(text)
+	var topics []string
+	for topic, keywords := range Topics {
+		hits := 0
+		for _, k := range keywords {
+			hits += countWord(lower, k)
+		}
+		if hits >= MinTopicMentions {
+			topics = append(topics, topic)
+		}
+	}
+
+	for _, names := range entities {
+		sort.Strings(names)
+	}
+	sort.Strings(topics)
+	return Tags{Entities: entities, Topics: topics}
+}
+
+// turnPrefixRegex matches the "EP:... Date:... TS:... - " part of a turn,
+// leaving the speaker name and text
+var turnPrefixRegex = regexp.MustCompile(`(?m)^EP:\d+ Date:\S+(?: TS:\S+)? - ?`)
+
+func stripTurnPrefixes(content string) string {
+	return turnPrefixRegex.ReplaceAllString(content, "")
+}
+
+// countWord counts occurrences of a lowercase phrase on word boundaries
+func countWord(text, phrase string) int {
+	n := 0
+	for i := 0; ; {
+		j := strings.Index(text[i:], phrase)
+		if j < 0 {
+			return n
+		}
+		start := i + j
+		end := start + len(phrase)
+		if (start == 0 || !isWordByte(text[start-1])) && (end == len(text) || !isWordByte(text[end])) {
+			n++
+		}
+		i = end
+	}
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
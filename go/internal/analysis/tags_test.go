@@ -0,0 +1,52 @@
+package analysis
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	content := strings.Join([]string{
+		"EP:1000 Date:24-11-19 TS:00:01:00 - Steve Gibson OpenSSL shipped a fix for CVE-2024-12345 today.",
+		"EP:1000 Date:24-11-19 TS:00:02:00 - Leo Laporte Is that the vulnerability Microsoft flagged?",
+		"EP:1000 Date:24-11-19 TS:00:03:00 - Steve Gibson Yes, and the exploit is public. Another vulnerability too.",
+		"EP:1000 Date:24-11-19 TS:00:04:00 - Leo Laporte Steve Gibson, everybody. I love my apple pie.",
+	}, "\n")
+
+	tags := Extract(content)
+	if !reflect.DeepEqual(tags.Entities[Product], []string{"OpenSSL"}) {
+		t.Errorf("Products = %v", tags.Entities[Product])
+	}
+	if !reflect.DeepEqual(tags.Entities[Company], []string{"Microsoft"}) {
+		t.Errorf("Companies = %v (lowercase 'apple' must not match)", tags.Entities[Company])
+	}
+	if !reflect.DeepEqual(tags.Entities[CVE], []string{"CVE-2024-12345"}) {
+		t.Errorf("CVEs = %v", tags.Entities[CVE])
+	}
+	if !reflect.DeepEqual(tags.Entities[Person], []string{"Leo Laporte", "Steve Gibson"}) {
+		t.Errorf("People = %v", tags.Entities[Person])
+	}
+	if !reflect.DeepEqual(tags.Topics, []string{"security"}) {
+		t.Errorf("Topics = %v", tags.Topics)
+	}
+}
+
+func TestLoadGazetteer(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "gazetteertest")
+	defer os.RemoveAll(tmpDir)
+	path := filepath.Join(tmpDir, "extra.csv")
+	os.WriteFile(path, []byte("# extra\nSQRL,product\n"), 0644)
+
+	if err := LoadGazetteer(path); err != nil {
+		t.Fatalf("LoadGazetteer failed: %v", err)
+	}
+	defer delete(Gazetteer, "SQRL")
+
+	tags := Extract("EP:1 Date:24-01-01 - Steve Gibson SQRL is done")
+	if !reflect.DeepEqual(tags.Entities[Product], []string{"SQRL"}) {
+		t.Errorf("Products = %v", tags.Entities[Product])
+	}
+}
@@ -0,0 +1,292 @@
+// Package backup snapshots the archive's state files (index, chunk
+// manifests, link index, configuration) into timestamped tar.gz files and
+// restores them, so a botched migration or a damaged index can be undone
+// without re-deriving everything from the transcripts.
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/digest"
+	"github.com/aramova/twit-transcript-archiver/go/internal/export"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+	"github.com/aramova/twit-transcript-archiver/go/internal/scraper"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// Dir is where backups are kept, relative to the data directory
+const Dir = "backups"
+
+// ManifestName is the manifest's path inside a backup
+const ManifestName = "backup.json"
+
+// stateFiles are the files at the root of the data directory that a
+// backup holds, when they exist
+var stateFiles = []string{
+	index.FileName,
+	converter.LinkIndexFile,
+	config.SettingsFile,
+	config.CustomShowsFile,
+	scraper.MirrorMapFile,
+	export.SyncStateFile,
+	digest.StateFile,
+}
+
+// File is one manifest entry; Path is relative to the data directory
+type File struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest describes a backup's contents
+type Manifest struct {
+	Created   time.Time `json:"created"`
+	Layout    string    `json:"layout"`
+	Converter int       `json:"converter"`
+	Files     []File    `json:"files"`
+}
+
+// Files lists the state files of the data directory: the index, link
+// index, settings, custom shows, mirror table, search sync and digest
+// state, and every show's chunk manifest
+func Files(dataDir string) ([]string, error) {
+	var files []string
+	for _, name := range stateFiles {
+		if p := storage.Join(dataDir, name); storage.Exists(p) {
+			files = append(files, p)
+		}
+	}
+	manifests, err := storage.Glob(storage.Join(config.ActiveLayout.ChunkDir(dataDir, "*"), "*_chunks.json"))
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, manifests...)
+	sort.Strings(files)
+	return files, nil
+}
+
+// Name returns the file name of a backup taken at t
+func Name(t time.Time) string {
+	return "backup-" + t.UTC().Format("20060102T150405.000Z") + ".tar.gz"
+}
+
+// Time returns when the backup named by path was taken, from its name
+func Time(path string) (time.Time, bool) {
+	name := strings.TrimSuffix(strings.TrimPrefix(storage.Base(path), "backup-"), ".tar.gz")
+	t, err := time.Parse("20060102T150405.000Z", name)
+	return t, err == nil
+}
+
+// Create writes a backup of the data directory's state files to out
+// (default: a new file in the data directory's backups directory) and
+// returns its path and manifest
+func Create(dataDir, out string) (string, *Manifest, error) {
+	files, err := Files(dataDir)
+	if err != nil {
+		return "", nil, err
+	}
+	m := &Manifest{Created: time.Now().UTC(), Layout: config.ActiveLayout.Name, Converter: converter.ConverterVersion}
+	if out == "" {
+		out = storage.Join(dataDir, Dir, Name(m.Created))
+	}
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	add := func(name string, data []byte) error {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: m.Created}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+	for _, f := range files {
+		data, err := storage.ReadFile(f)
+		if err != nil {
+			return "", nil, err
+		}
+		rel := storage.Rel(dataDir, f)
+		if err := add(rel, data); err != nil {
+			return "", nil, err
+		}
+		sum := sha256.Sum256(data)
+		m.Files = append(m.Files, File{Path: rel, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:])})
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", nil, err
+	}
+	if err := add(ManifestName, data); err != nil {
+		return "", nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return "", nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return "", nil, err
+	}
+	return out, m, storage.WriteFile(out, buf.Bytes())
+}
+
+// List returns the backups in the data directory's backups directory,
+// oldest first
+func List(dataDir string) ([]string, error) {
+	files, err := storage.Glob(storage.Join(dataDir, Dir, "backup-*.tar.gz"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files) // The timestamps sort by name
+	return files, nil
+}
+
+// Latest returns the newest backup in the backups directory
+func Latest(dataDir string) (string, error) {
+	files, err := List(dataDir)
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("no backups in %s", storage.Join(dataDir, Dir))
+	}
+	return files[len(files)-1], nil
+}
+
+// Read loads a backup and checks every file against its manifest
+func Read(path string) (*Manifest, map[string][]byte, error) {
+	raw, err := storage.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	gr, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s is not a backup: %w", path, err)
+	}
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, nil, err
+		}
+		files[hdr.Name] = b
+	}
+
+	mdata, ok := files[ManifestName]
+	if !ok {
+		return nil, nil, fmt.Errorf("%s has no %s", path, ManifestName)
+	}
+	var m Manifest
+	if err := json.Unmarshal(mdata, &m); err != nil {
+		return nil, nil, fmt.Errorf("%s: invalid manifest: %w", path, err)
+	}
+	delete(files, ManifestName)
+	for _, f := range m.Files {
+		if !safePath(f.Path) {
+			return nil, nil, fmt.Errorf("%s: unsafe path %s", path, f.Path)
+		}
+		data, ok := files[f.Path]
+		if !ok {
+			return nil, nil, fmt.Errorf("%s: %s is in the manifest but missing", path, f.Path)
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != f.SHA256 {
+			return nil, nil, fmt.Errorf("%s: checksum mismatch for %s", path, f.Path)
+		}
+	}
+	if len(files) != len(m.Files) {
+		return nil, nil, fmt.Errorf("%s holds files that are not in its manifest", path)
+	}
+	return &m, files, nil
+}
+
+// safePath reports whether a backup path stays inside the data directory
+func safePath(p string) bool {
+	if p == "" || strings.HasPrefix(p, "/") || strings.Contains(p, `\`) {
+		return false
+	}
+	for _, part := range strings.Split(p, "/") {
+		if part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// Change kinds, as reported in Change.Kind
+const (
+	Unchanged = "unchanged"
+	Modified  = "modified"
+	Missing   = "missing" // Not in the data directory now
+)
+
+// Change is how a file of a backup compares with the data directory
+type Change struct {
+	Path string
+	Kind string
+}
+
+// Compare reports how each file of a backup differs from the data directory
+func Compare(dataDir string, files map[string][]byte) ([]Change, error) {
+	var changes []Change
+	for p, data := range files {
+		kind := Unchanged
+		cur, err := storage.ReadFile(storage.Join(dataDir, p))
+		switch {
+		case errors.Is(err, storage.ErrNotExist):
+			kind = Missing
+		case err != nil:
+			return nil, err
+		case !bytes.Equal(cur, data):
+			kind = Modified
+		}
+		changes = append(changes, Change{Path: p, Kind: kind})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// Restore writes a backup's files back into the data directory, replacing
+// the current ones. State files created since the backup are left alone.
+// It returns the files that were written.
+func Restore(dataDir string, files map[string][]byte) ([]string, error) {
+	changes, err := Compare(dataDir, files)
+	if err != nil {
+		return nil, err
+	}
+	var written []string
+	for _, c := range changes {
+		if c.Kind == Unchanged {
+			continue
+		}
+		if err := storage.WriteFile(storage.Join(dataDir, c.Path), files[c.Path]); err != nil {
+			return written, err
+		}
+		written = append(written, c.Path)
+	}
+	return written, nil
+}
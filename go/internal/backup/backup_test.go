@@ -0,0 +1,95 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+)
+
+func TestBackupRestore(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		p := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("index.json", `{"entries":{"SN_1":{}}}`)
+	write("links.json", `{}`)
+	write("SN_chunks.json", `{"prefix":"SN"}`)
+	write("SN_1.html", "<html></html>")
+
+	path, m, err := Create(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(path, filepath.Join(dir, Dir, "backup-")) || len(m.Files) != 3 || m.Layout != config.ActiveLayout.Name {
+		t.Fatalf("Create = %s, %+v", path, m)
+	}
+	if latest, err := Latest(dir); err != nil || latest != path {
+		t.Errorf("Latest = %s, %v; want %s", latest, err, path)
+	}
+
+	// A botched change: the index is damaged and a manifest removed
+	write("index.json", "{garbage")
+	os.Remove(filepath.Join(dir, "SN_chunks.json"))
+	write("IM_chunks.json", `{"prefix":"IM"}`)
+
+	_, files, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := Compare(dir, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := make(map[string]string)
+	for _, c := range changes {
+		kinds[c.Path] = c.Kind
+	}
+	if kinds["index.json"] != Modified || kinds["SN_chunks.json"] != Missing || kinds["links.json"] != Unchanged {
+		t.Errorf("Compare = %v", changes)
+	}
+	written, err := Restore(dir, files)
+	if err != nil || len(written) != 2 {
+		t.Fatalf("Restore = %v, %v", written, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "index.json")); string(data) != `{"entries":{"SN_1":{}}}` {
+		t.Errorf("index.json not restored: %s", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "IM_chunks.json")); err != nil {
+		t.Error("Files created since the backup should be kept")
+	}
+}
+
+func TestReadRejectsTampering(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "index.json"), []byte(`{}`), 0644)
+	path, _, err := Create(dir, filepath.Join(dir, "b.tar.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Read(path); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	os.WriteFile(path, []byte("not a backup"), 0644)
+	if _, _, err := Read(path); err == nil {
+		t.Error("Expected an error for a file that is not a backup")
+	}
+	if safePath("../etc/passwd") || safePath("/etc/passwd") || !safePath("SN/chunks/SN_chunks.json") {
+		t.Error("safePath misjudged a path")
+	}
+}
+
+func TestNameSortsByTime(t *testing.T) {
+	a := Name(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	b := Name(time.Date(2026, 1, 2, 3, 4, 5, 1e6, time.UTC))
+	if a != "backup-20260102T030405.000Z.tar.gz" || !(a < b) {
+		t.Errorf("Name = %s, %s", a, b)
+	}
+}
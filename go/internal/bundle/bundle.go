@@ -0,0 +1,376 @@
+// Package bundle packages archived transcripts into checksummed tar.gz or zip
+// files for distribution, and merges such bundles, or whole data directories,
+// into a local archive.
+package bundle
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// ManifestName is the manifest's path inside a bundle
+const ManifestName = "manifest.json"
+
+// Content kinds a bundle can carry
+const (
+	ContentRaw      = "raw"      // Transcript HTML
+	ContentMarkdown = "markdown" // Per-episode Markdown
+)
+
+var (
+	rawFileRegex      = regexp.MustCompile(`^([A-Z0-9]+)_\d+\.html$`)
+	markdownFileRegex = regexp.MustCompile(`^([A-Z0-9]+)_\d+\.md$`)
+)
+
+// File is one manifest entry. Path is "raw/SN_1000.html" or "md/SN_1000.md"
+// regardless of the layout of the archive the bundle came from.
+type File struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest describes a bundle's contents
+type Manifest struct {
+	Created time.Time `json:"created"`
+	Shows   []string  `json:"shows"`
+	Content string    `json:"content"`
+	Files   []File    `json:"files"`
+}
+
+// Result reports what Import or Merge did with each file
+type Result struct {
+	Added     []string // Files written to the archive
+	Skipped   []string // Files already archived with the same checksum
+	Conflicts []string // Files archived with different content; kept local copy
+	Replaced  []string // Files archived with different content; replaced by a newer copy
+}
+
+// IsZip reports whether a bundle path names a zip (rather than tar.gz) file
+func IsZip(path string) bool {
+	return strings.HasSuffix(strings.ToLower(path), ".zip")
+}
+
+// Files lists the archived files of a kind for the given shows, including
+// those archived under the prefixes of a show's earlier titles
+func Files(dataDir string, prefixes []string, content string) ([]string, error) {
+	var files []string
+	for _, show := range prefixes {
+		for _, prefix := range config.EraPrefixes(show) {
+			var pattern string
+			var re *regexp.Regexp
+			switch content {
+			case ContentRaw:
+				pattern, re = config.ActiveLayout.RawGlob(dataDir, prefix), rawFileRegex
+			case ContentMarkdown:
+				pattern = storage.Join(config.ActiveLayout.MarkdownDir(dataDir, prefix), prefix+"_*.md")
+				re = markdownFileRegex
+			default:
+				return nil, fmt.Errorf("unknown bundle content '%s' (want raw or markdown)", content)
+			}
+			matches, err := storage.Glob(pattern)
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range matches {
+				if sub := re.FindStringSubmatch(storage.Base(m)); sub != nil && sub[1] == prefix {
+					files = append(files, m)
+				}
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Create writes a bundle of the given files (from Files) to w, as zip or
+// tar.gz, followed by its manifest
+func Create(w io.Writer, asZip bool, prefixes []string, content string, files []string) (*Manifest, error) {
+	m := &Manifest{Created: time.Now().UTC(), Shows: prefixes, Content: content}
+	aw := newArchiveWriter(w, asZip)
+	for _, f := range files {
+		data, err := storage.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		name := bundlePath(storage.Base(f))
+		if err := aw.add(name, data); err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(data)
+		m.Files = append(m.Files, File{Path: name, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:])})
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := aw.add(ManifestName, data); err != nil {
+		return nil, err
+	}
+	return m, aw.close()
+}
+
+// Import merges a bundle into the data directory. Every file is checked
+// against the manifest; files already present with the same checksum are
+// skipped, and differing local copies are never overwritten.
+func Import(path, dataDir string) (*Result, error) {
+	raw, err := storage.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	contents, err := readArchive(raw, IsZip(path))
+	if err != nil {
+		return nil, err
+	}
+	mdata, ok := contents[ManifestName]
+	if !ok {
+		return nil, fmt.Errorf("%s has no %s", path, ManifestName)
+	}
+	var m Manifest
+	if err := json.Unmarshal(mdata, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	res := &Result{}
+	for _, f := range m.Files {
+		data, ok := contents[f.Path]
+		if !ok {
+			return res, fmt.Errorf("%s is listed in the manifest but missing from the bundle", f.Path)
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != f.SHA256 {
+			return res, fmt.Errorf("checksum mismatch for %s", f.Path)
+		}
+		dest, err := destination(dataDir, f.Path)
+		if err != nil {
+			return res, err
+		}
+		if existing, err := storage.ReadFile(dest); err == nil {
+			if bytes.Equal(existing, data) {
+				res.Skipped = append(res.Skipped, dest)
+			} else {
+				res.Conflicts = append(res.Conflicts, dest)
+			}
+			continue
+		}
+		if err := storage.WriteFile(dest, data); err != nil {
+			return res, err
+		}
+		res.Added = append(res.Added, dest)
+	}
+	return res, nil
+}
+
+// Merge copies the transcript HTML and per-episode Markdown of another data
+// directory, in either layout, into dataDir. Identical files are skipped.
+// When both archives hold different copies, the more recently modified one
+// wins unless keepLocal is set (or either side is in object storage, where
+// modification times are not available), in which case the local copy is
+// kept and reported as a conflict.
+func Merge(srcDir, dataDir string, keepLocal bool) (*Result, error) {
+	files, err := sourceFiles(srcDir)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{}
+	for _, src := range files {
+		dest, err := destination(dataDir, bundlePath(storage.Base(src)))
+		if err != nil {
+			return res, err
+		}
+		data, err := storage.ReadFile(src)
+		if err != nil {
+			return res, err
+		}
+		existing, err := storage.ReadFile(dest)
+		switch {
+		case err != nil:
+			res.Added = append(res.Added, dest)
+		case bytes.Equal(existing, data):
+			res.Skipped = append(res.Skipped, dest)
+			continue
+		case keepLocal || !newer(src, dest):
+			res.Conflicts = append(res.Conflicts, dest)
+			continue
+		default:
+			res.Replaced = append(res.Replaced, dest)
+		}
+		if err := storage.WriteFile(dest, data); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// sourceFiles finds the archived files of a data directory in any layout
+func sourceFiles(dataDir string) ([]string, error) {
+	seen := make(map[string]bool)
+	var files []string
+	for _, l := range []config.Layout{config.FlatLayout, config.StructuredLayout} {
+		for _, step := range []struct {
+			pattern string
+			re      *regexp.Regexp
+		}{
+			{l.RawGlob(dataDir, "*"), rawFileRegex},
+			{storage.Join(l.MarkdownDir(dataDir, "*"), "*_*.md"), markdownFileRegex},
+		} {
+			matches, err := storage.Glob(step.pattern)
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range matches {
+				name := storage.Base(m)
+				if step.re.MatchString(name) && !seen[name] {
+					seen[name] = true
+					files = append(files, m)
+				}
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// newer reports whether local file a was modified after local file b
+func newer(a, b string) bool {
+	if storage.IsRemote(a) || storage.IsRemote(b) {
+		return false
+	}
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return ai.ModTime().After(bi.ModTime())
+}
+
+// bundlePath maps an archived file name to its layout-independent path
+func bundlePath(name string) string {
+	if strings.HasSuffix(name, ".md") {
+		return "md/" + name
+	}
+	return "raw/" + name
+}
+
+// destination maps a bundle path to its location in the active layout
+func destination(dataDir, p string) (string, error) {
+	dir, name := "", p
+	if i := strings.Index(p, "/"); i >= 0 {
+		dir, name = p[:i], p[i+1:]
+	}
+	switch {
+	case dir == "raw" && rawFileRegex.MatchString(name):
+		prefix := rawFileRegex.FindStringSubmatch(name)[1]
+		return storage.Join(config.ActiveLayout.RawDir(dataDir, prefix), name), nil
+	case dir == "md" && markdownFileRegex.MatchString(name):
+		prefix := markdownFileRegex.FindStringSubmatch(name)[1]
+		return storage.Join(config.ActiveLayout.MarkdownDir(dataDir, prefix), name), nil
+	}
+	return "", fmt.Errorf("unexpected bundle entry %s", p)
+}
+
+// archiveWriter hides the difference between tar.gz and zip output
+type archiveWriter struct {
+	zw *zip.Writer
+	gw *gzip.Writer
+	tw *tar.Writer
+}
+
+func newArchiveWriter(w io.Writer, asZip bool) *archiveWriter {
+	if asZip {
+		return &archiveWriter{zw: zip.NewWriter(w)}
+	}
+	gw := gzip.NewWriter(w)
+	return &archiveWriter{gw: gw, tw: tar.NewWriter(gw)}
+}
+
+func (a *archiveWriter) add(name string, data []byte) error {
+	if a.zw != nil {
+		f, err := a.zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(data)
+		return err
+	}
+	hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: time.Now()}
+	if err := a.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := a.tw.Write(data)
+	return err
+}
+
+func (a *archiveWriter) close() error {
+	if a.zw != nil {
+		return a.zw.Close()
+	}
+	if err := a.tw.Close(); err != nil {
+		return err
+	}
+	return a.gw.Close()
+}
+
+// readArchive returns the regular files of a tar.gz or zip by name
+func readArchive(data []byte, asZip bool) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	if asZip {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			b, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return nil, err
+			}
+			files[f.Name] = b
+		}
+		return files, nil
+	}
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[hdr.Name] = b
+	}
+}
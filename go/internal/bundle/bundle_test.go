@@ -0,0 +1,127 @@
+package bundle
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/utils"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	src, _ := os.MkdirTemp("", "bundlesrc")
+	defer os.RemoveAll(src)
+	dst, _ := os.MkdirTemp("", "bundledst")
+	defer os.RemoveAll(dst)
+
+	for _, name := range []string{"SN_1.html", "SN_2.html", "SN_3.html", "SN_Transcripts_1-3.md", "IM_5.html"} {
+		os.WriteFile(filepath.Join(src, name), []byte(name), 0644)
+	}
+	files, err := Files(src, []string{"SN"}, ContentRaw)
+	if err != nil || len(files) != 3 {
+		t.Fatalf("Files = %v, %v", files, err)
+	}
+
+	for _, asZip := range []bool{false, true} {
+		var buf bytes.Buffer
+		m, err := Create(&buf, asZip, []string{"SN"}, ContentRaw, files)
+		if err != nil || len(m.Files) != 3 || m.Files[0].Path != "raw/SN_1.html" {
+			t.Fatalf("Create(zip=%v) = %+v, %v", asZip, m, err)
+		}
+		name := "sn.tar.gz"
+		if asZip {
+			name = "sn.zip"
+		}
+		bundlePath := filepath.Join(src, name)
+		os.WriteFile(bundlePath, buf.Bytes(), 0644)
+
+		// Import into a structured archive that already has two of the episodes
+		saved := config.ActiveLayout
+		config.ActiveLayout = config.StructuredLayout
+		target := filepath.Join(dst, name)
+		os.MkdirAll(filepath.Join(target, "raw", "SN"), 0755)
+		os.WriteFile(filepath.Join(target, "raw", "SN", "SN_1.html"), []byte("SN_1.html"), 0644)
+		os.WriteFile(filepath.Join(target, "raw", "SN", "SN_2.html"), []byte("local edit"), 0644)
+
+		res, err := Import(bundlePath, target)
+		config.ActiveLayout = saved
+		if err != nil {
+			t.Fatalf("Import(zip=%v) failed: %v", asZip, err)
+		}
+		if len(res.Added) != 1 || len(res.Skipped) != 1 || len(res.Conflicts) != 1 {
+			t.Errorf("Unexpected result: %+v", res)
+		}
+		if !utils.FileExists(filepath.Join(target, "raw", "SN", "SN_3.html")) {
+			t.Error("Expected SN_3.html in the structured layout")
+		}
+		if data, _ := os.ReadFile(filepath.Join(target, "raw", "SN", "SN_2.html")); string(data) != "local edit" {
+			t.Error("Conflicting local file was overwritten")
+		}
+	}
+}
+
+func TestImportRejectsBadChecksum(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "bundlebad")
+	defer os.RemoveAll(dir)
+
+	var buf bytes.Buffer
+	aw := newArchiveWriter(&buf, false)
+	aw.add("raw/SN_1.html", []byte("tampered"))
+	aw.add(ManifestName, []byte(`{"files":[{"path":"raw/SN_1.html","size":8,"sha256":"00"}]}`))
+	aw.close()
+	path := filepath.Join(dir, "bad.tar.gz")
+	os.WriteFile(path, buf.Bytes(), 0644)
+
+	if _, err := Import(path, dir); err == nil {
+		t.Error("Expected a checksum error")
+	}
+	if utils.FileExists(filepath.Join(dir, "SN_1.html")) {
+		t.Error("Tampered file should not be imported")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	src, _ := os.MkdirTemp("", "mergesrc")
+	defer os.RemoveAll(src)
+	dst, _ := os.MkdirTemp("", "mergedst")
+	defer os.RemoveAll(dst)
+
+	// The other archive uses the structured layout, ours is flat
+	os.MkdirAll(filepath.Join(src, "raw", "SN"), 0755)
+	os.MkdirAll(filepath.Join(src, "md", "SN"), 0755)
+	for _, name := range []string{"SN_1.html", "SN_2.html", "SN_3.html", "SN_4.html"} {
+		os.WriteFile(filepath.Join(src, "raw", "SN", name), []byte(name), 0644)
+	}
+	os.WriteFile(filepath.Join(src, "md", "SN", "SN_1.md"), []byte("# SN 1"), 0644)
+
+	old := time.Now().Add(-time.Hour)
+	os.WriteFile(filepath.Join(dst, "SN_1.html"), []byte("SN_1.html"), 0644)
+	os.WriteFile(filepath.Join(dst, "SN_2.html"), []byte("older local copy"), 0644)
+	os.Chtimes(filepath.Join(dst, "SN_2.html"), old, old)
+	os.WriteFile(filepath.Join(dst, "SN_3.html"), []byte("newer local copy"), 0644)
+	os.Chtimes(filepath.Join(src, "raw", "SN", "SN_3.html"), old, old)
+
+	saved := config.ActiveLayout
+	config.ActiveLayout = config.FlatLayout
+	defer func() { config.ActiveLayout = saved }()
+
+	res, err := Merge(src, dst, false)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if len(res.Added) != 2 || len(res.Skipped) != 1 || len(res.Replaced) != 1 || len(res.Conflicts) != 1 {
+		t.Errorf("Unexpected result: %+v", res)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst, "SN_2.html")); string(data) != "SN_2.html" {
+		t.Error("Older local copy should be replaced")
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst, "SN_3.html")); string(data) != "newer local copy" {
+		t.Error("Newer local copy should be kept")
+	}
+	if !utils.FileExists(filepath.Join(dst, "SN_1.md")) || !utils.FileExists(filepath.Join(dst, "SN_4.html")) {
+		t.Error("Expected new files in the flat layout")
+	}
+}
@@ -0,0 +1,98 @@
+package chapters
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+// Section is a chapter with the transcript turns spoken during it
+type Section struct {
+	Title    string `json:"title"`
+	Start    int    `json:"start_seconds"`
+	Timecode string `json:"timecode"`
+	URL      string `json:"url,omitempty"`
+	Turns    []Turn `json:"turns"`
+}
+
+// Turn is one speaker turn of a section
+type Turn struct {
+	Timecode string `json:"timecode,omitempty"`
+	Speaker  string `json:"speaker,omitempty"`
+	Text     string `json:"text"`
+}
+
+// Chaptered is an episode transcript organized by chapter
+type Chaptered struct {
+	Prefix   string    `json:"prefix"`
+	Episode  int       `json:"episode"`
+	Title    string    `json:"title"`
+	Sections []Section `json:"chapters"`
+}
+
+// Align assigns each turn of an episode to the chapter it starts in. Turns
+// before the first chapter go to the first one, and untimed turns stay with
+// the turn before them. Returns the number of timed turns, as an episode
+// without any cannot be aligned meaningfully.
+func Align(ep converter.Episode, chs []Chapter) (Chaptered, int) {
+	c := Chaptered{Prefix: ep.Prefix, Episode: ep.Number, Title: ep.Title}
+	for _, ch := range chs {
+		c.Sections = append(c.Sections, Section{
+			Title:    ch.Title,
+			Start:    int(ch.Start.Seconds()),
+			Timecode: converter.FormatTimecode(ch.Start),
+			URL:      ch.URL,
+			Turns:    []Turn{},
+		})
+	}
+	if len(chs) == 0 {
+		return c, 0
+	}
+
+	cur, timed := 0, 0
+	for i, seg := range converter.Turns(ep.Content) {
+		if seg.Timecode != "" {
+			timed++
+			for cur+1 < len(chs) && chs[cur+1].Start <= seg.Start {
+				cur++
+			}
+		}
+		t := Turn{Timecode: seg.Timecode, Text: seg.Text}
+		if i < len(ep.Turns) && ep.Turns[i].Speaker != "" && strings.HasPrefix(seg.Text, ep.Turns[i].Speaker) {
+			t.Speaker = ep.Turns[i].Speaker
+			t.Text = strings.TrimSpace(seg.Text[len(t.Speaker):])
+		}
+		c.Sections[cur].Turns = append(c.Sections[cur].Turns, t)
+	}
+	return c, timed
+}
+
+// Markdown renders the chaptered transcript with a contents list linking
+// to a heading per chapter
+func (c Chaptered) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", c.Title)
+	for i, s := range c.Sections {
+		fmt.Fprintf(&b, "%d. [%s](#chapter-%d) (%s)\n", i+1, s.Title, i+1, s.Timecode)
+	}
+	for i, s := range c.Sections {
+		fmt.Fprintf(&b, "\n<a id=\"chapter-%d\"></a>\n## %s\n\n", i+1, s.Title)
+		fmt.Fprintf(&b, "Starts at %s", s.Timecode)
+		if s.URL != "" {
+			fmt.Fprintf(&b, " - %s", s.URL)
+		}
+		b.WriteString("\n\n")
+		for _, t := range s.Turns {
+			if t.Timecode != "" {
+				fmt.Fprintf(&b, "[%s] ", t.Timecode)
+			}
+			if t.Speaker != "" {
+				fmt.Fprintf(&b, "**%s:** ", t.Speaker)
+			}
+			b.WriteString(t.Text)
+			b.WriteString("\n\n")
+		}
+	}
+	return b.String()
+}
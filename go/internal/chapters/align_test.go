@@ -0,0 +1,47 @@
+package chapters
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+func TestAlign(t *testing.T) {
+	ep := converter.Episode{
+		Prefix: "SN", Number: 1000, Title: "Security Now 1000",
+		Content: strings.Join([]string{
+			"EP:1000 Date:24-11-19 - Leo Laporte Untimed opening",
+			"EP:1000 Date:24-11-19 TS:0:30 - Steve Gibson Welcome",
+			"EP:1000 Date:24-11-19 TS:10:00 - Steve Gibson First letter",
+			"EP:1000 Date:24-11-19 - Leo Laporte Good one",
+		}, "\n"),
+		Turns: []converter.Turn{{Speaker: "Leo Laporte"}, {Speaker: "Steve Gibson"}, {Speaker: "Steve Gibson"}, {Speaker: "Leo Laporte"}},
+	}
+	chs := []Chapter{{Start: 10 * time.Second, Title: "Intro"}, {Start: 5 * time.Minute, Title: "Empty"}, {Start: 10 * time.Minute, Title: "Feedback"}}
+	c, timed := Align(ep, chs)
+	if timed != 2 || len(c.Sections) != 3 {
+		t.Fatalf("Align = %+v, %d timed", c, timed)
+	}
+	got := make([]int, len(c.Sections))
+	for i, s := range c.Sections {
+		got[i] = len(s.Turns)
+	}
+	if got[0] != 2 || got[1] != 0 || got[2] != 2 {
+		t.Errorf("Turns per chapter = %v", got)
+	}
+	if turn := c.Sections[2].Turns[1]; turn.Speaker != "Leo Laporte" || turn.Text != "Good one" || turn.Timecode != "" {
+		t.Errorf("Untimed turn = %+v", turn)
+	}
+
+	md := c.Markdown()
+	for _, want := range []string{
+		"3. [Feedback](#chapter-3) (00:10:00)",
+		"<a id=\"chapter-3\"></a>\n## Feedback\n\nStarts at 00:10:00\n\n[00:10:00] **Steve Gibson:** First letter\n\n**Leo Laporte:** Good one\n",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown lacks %q:\n%s", want, md)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Package chapters reads podcast feeds: the chapter markers they publish,
+// which it aligns transcripts with so downstream tools can jump from a
+// chapter to its part of the transcript, and the transcripts and audio they
+// link to.
+package chapters
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+// FeedURLTemplate is the RSS feed of a show; {show} is its lowercase prefix
+var FeedURLTemplate = "https://feeds.twit.tv/{show}.xml"
+
+// FeedURL returns the RSS feed URL of a show
+func FeedURL(prefix string) string {
+	return strings.ReplaceAll(FeedURLTemplate, "{show}", strings.ToLower(prefix))
+}
+
+// Chapter is one chapter marker of an episode
+type Chapter struct {
+	Start time.Duration
+	Title string
+	URL   string
+}
+
+// Item is an episode of a feed with its chapter markers: either inline
+// (Podlove Simple Chapters) or as a link to a JSON chapters file (the
+// Podcasting 2.0 podcast:chapters tag)
+type Item struct {
+	Title       string
+	Episode     int // 0 if neither itunes:episode nor the title give one
+	Published   time.Time
+	Audio       string // The enclosure, if it is an audio file
+	Chapters    []Chapter
+	ChaptersURL string
+	// Transcripts are the item's podcast:transcript links, in feed order
+	Transcripts []Transcript
+}
+
+// Transcript is a transcript file linked with the Podcasting 2.0
+// podcast:transcript tag
+type Transcript struct {
+	URL      string
+	Type     string // MIME type, e.g. "text/vtt" or "application/json"
+	Language string
+	Rel      string // "captions" for closed captions
+}
+
+type rss struct {
+	Items []struct {
+		Title     string `xml:"title"`
+		PubDate   string `xml:"pubDate"`
+		Enclosure struct {
+			URL  string `xml:"url,attr"`
+			Type string `xml:"type,attr"`
+		} `xml:"enclosure"`
+		Episode string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episode"`
+		PSC     []struct {
+			Start string `xml:"start,attr"`
+			Title string `xml:"title,attr"`
+			Href  string `xml:"href,attr"`
+		} `xml:"http://podlove.org/simple-chapters chapters>chapter"`
+		Chapters []struct {
+			URL  string `xml:"url,attr"`
+			Type string `xml:"type,attr"`
+		} `xml:"https://podcastindex.org/namespace/1.0 chapters"`
+		Transcripts []struct {
+			URL      string `xml:"url,attr"`
+			Type     string `xml:"type,attr"`
+			Language string `xml:"language,attr"`
+			Rel      string `xml:"rel,attr"`
+		} `xml:"https://podcastindex.org/namespace/1.0 transcript"`
+	} `xml:"channel>item"`
+}
+
+// titleNumberRegex finds the episode number in an item title
+var titleNumberRegex = regexp.MustCompile(`\b(\d+)\b`)
+
+// ParseFeed returns the items of an RSS feed
+func ParseFeed(data []byte) ([]Item, error) {
+	var feed rss
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		return nil, fmt.Errorf("invalid feed: %v", err)
+	}
+	var items []Item
+	for _, it := range feed.Items {
+		item := Item{Title: strings.TrimSpace(it.Title)}
+		if n, err := strconv.Atoi(strings.TrimSpace(it.Episode)); err == nil {
+			item.Episode = n
+		} else if m := titleNumberRegex.FindStringSubmatch(item.Title); m != nil {
+			item.Episode, _ = strconv.Atoi(m[1])
+		}
+		if t, ok := converter.ParseDate(it.PubDate); ok {
+			item.Published = t
+		}
+		if u := strings.TrimSpace(it.Enclosure.URL); strings.HasPrefix(it.Enclosure.Type, "audio/") || converter.IsAudioURL(u) {
+			item.Audio = u
+		}
+		for _, t := range it.Transcripts {
+			if t.URL != "" {
+				item.Transcripts = append(item.Transcripts, Transcript{URL: strings.TrimSpace(t.URL), Type: t.Type, Language: t.Language, Rel: t.Rel})
+			}
+		}
+		for _, c := range it.PSC {
+			start, ok := parseStart(c.Start)
+			if !ok {
+				continue
+			}
+			item.Chapters = append(item.Chapters, Chapter{Start: start, Title: strings.TrimSpace(c.Title), URL: c.Href})
+		}
+		for _, c := range it.Chapters {
+			if c.URL != "" && (c.Type == "" || strings.Contains(c.Type, "json")) {
+				item.ChaptersURL = c.URL
+			}
+		}
+		sortChapters(item.Chapters)
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// parseStart parses a Podlove start time ("HH:MM:SS.mmm", "MM:SS" or
+// "SS"), dropping fractions of a second
+func parseStart(s string) (time.Duration, bool) {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		s = s[:i]
+	}
+	if !strings.Contains(s, ":") {
+		n, err := strconv.Atoi(s)
+		return time.Duration(n) * time.Second, err == nil && n >= 0
+	}
+	return converter.ParseTimecode(s)
+}
+
+// ParseJSON parses a Podcasting 2.0 JSON chapters file. Chapters marked
+// as not belonging in the table of contents are left out.
+func ParseJSON(data []byte) ([]Chapter, error) {
+	var file struct {
+		Chapters []struct {
+			StartTime float64 `json:"startTime"`
+			Title     string  `json:"title"`
+			URL       string  `json:"url"`
+			TOC       *bool   `json:"toc"`
+		} `json:"chapters"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid chapters file: %v", err)
+	}
+	var chs []Chapter
+	for _, c := range file.Chapters {
+		if c.TOC != nil && !*c.TOC {
+			continue
+		}
+		start := time.Duration(c.StartTime) * time.Second
+		chs = append(chs, Chapter{Start: start, Title: strings.TrimSpace(c.Title), URL: c.URL})
+	}
+	sortChapters(chs)
+	return chs, nil
+}
+
+func sortChapters(chs []Chapter) {
+	sort.SliceStable(chs, func(i, j int) bool { return chs[i].Start < chs[j].Start })
+}
@@ -0,0 +1,85 @@
+package chapters
+
+import (
+	"testing"
+	"time"
+)
+
+const testFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd" xmlns:psc="http://podlove.org/simple-chapters" xmlns:podcast="https://podcastindex.org/namespace/1.0">
+<channel>
+  <title>Security Now</title>
+  <item>
+    <title>SN 1000: The Big One</title>
+    <enclosure url="https://example.com/sn1000.mp3" length="1" type="audio/mpeg"/>
+    <psc:chapters version="1.2">
+      <psc:chapter start="00:10:00.500" title="Listener Feedback"/>
+      <psc:chapter start="0" title="Intro" href="https://example.com/intro"/>
+      <psc:chapter start="bogus" title="Broken"/>
+    </psc:chapters>
+  </item>
+  <item>
+    <title>Security Now 2024: not the episode number</title>
+    <itunes:episode>999</itunes:episode>
+    <pubDate>Tue, 5 Nov 2024 20:30:00 GMT</pubDate>
+    <podcast:chapters url="https://example.com/999.json" type="application/json+chapters"/>
+    <podcast:transcript url=" https://example.com/999.vtt " type="text/vtt" language="en" rel="captions"/>
+    <podcast:transcript url="https://example.com/999.json" type="application/json"/>
+  </item>
+</channel>
+</rss>`
+
+func TestParseFeed(t *testing.T) {
+	items, err := ParseFeed([]byte(testFeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("ParseFeed = %+v", items)
+	}
+	if items[0].Episode != 1000 || len(items[0].Chapters) != 2 {
+		t.Fatalf("Item 0 = %+v", items[0])
+	}
+	if c := items[0].Chapters[0]; c.Title != "Intro" || c.Start != 0 || c.URL != "https://example.com/intro" {
+		t.Errorf("Chapter 0 = %+v", c)
+	}
+	if c := items[0].Chapters[1]; c.Title != "Listener Feedback" || c.Start != 10*time.Minute {
+		t.Errorf("Chapter 1 = %+v", c)
+	}
+	if items[1].Episode != 999 || items[1].ChaptersURL != "https://example.com/999.json" {
+		t.Errorf("Item 1 = %+v", items[1])
+	}
+	if items[0].Audio != "https://example.com/sn1000.mp3" || items[1].Audio != "" {
+		t.Errorf("Audio = %q, %q", items[0].Audio, items[1].Audio)
+	}
+	if items[0].Transcripts != nil || !items[0].Published.IsZero() {
+		t.Errorf("Item 0 has transcripts or a date: %+v", items[0])
+	}
+	if d := items[1].Published; d.Format("2006-01-02") != "2024-11-05" {
+		t.Errorf("Item 1 published %v", d)
+	}
+	want := []Transcript{
+		{URL: "https://example.com/999.vtt", Type: "text/vtt", Language: "en", Rel: "captions"},
+		{URL: "https://example.com/999.json", Type: "application/json"},
+	}
+	if len(items[1].Transcripts) != 2 || items[1].Transcripts[0] != want[0] || items[1].Transcripts[1] != want[1] {
+		t.Errorf("Item 1 transcripts = %+v", items[1].Transcripts)
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	chs, err := ParseJSON([]byte(`{"version": "1.2.0", "chapters": [
+		{"startTime": 95.5, "title": "News"},
+		{"startTime": 0, "title": "Intro"},
+		{"startTime": 50, "title": "Ad", "toc": false}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chs) != 2 || chs[0].Title != "Intro" || chs[1].Title != "News" || chs[1].Start != 95*time.Second {
+		t.Errorf("ParseJSON = %+v", chs)
+	}
+	if _, err := ParseJSON([]byte("<html>")); err == nil {
+		t.Error("ParseJSON accepted HTML")
+	}
+}
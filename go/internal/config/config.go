@@ -1,9 +1,14 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
+	"time"
+	// The zone database is built in, so --timezone works on hosts without one
+	_ "time/tzdata"
 )
 
 var (
@@ -11,6 +16,11 @@ var (
 	BaseListURL = "https://twit.tv/posts/transcripts"
 	// Base URL for the site
 	BaseSiteURL = "https://twit.tv"
+	// SearchURL is the site search, with %s replaced by the escaped query.
+	// Results use the same markup as the transcript listing.
+	SearchURL = "https://twit.tv/search?q=%s"
+	// WaybackAPI is the Internet Archive's snapshot lookup endpoint
+	WaybackAPI = "https://archive.org/wayback/available"
 
 	// Data directory (relative to where the binary is run, usually project root)
 	// We assume the user runs the binary from the project root or the 'go' folder.
@@ -18,13 +28,133 @@ var (
 	// We'll try to resolve it relative to the 'git' root if possible.
 	DataDir = "data"
 
+	// StorageLocation overrides the data directory with an explicit location.
+	// Accepts a local path, "s3://bucket/prefix" or "gs://bucket/prefix".
+	// Set via the TWIT_STORAGE environment variable.
+	StorageLocation = os.Getenv("TWIT_STORAGE")
+
+	// CacheLocation keeps disposable files (cached list pages) in a directory
+	// of their own instead of the data directory. Set via the TWIT_CACHE
+	// environment variable.
+	CacheLocation = os.Getenv("TWIT_CACHE")
+
+	// ReadOnly protects the data directory from any change, for archives on
+	// shared mounts or preservation copies. Set via the TWIT_READ_ONLY
+	// environment variable, "read-only" in the configuration file or
+	// twit-archiver --read-only; LoadSettings applies it to the storage layer.
+	ReadOnly = os.Getenv("TWIT_READ_ONLY") != ""
+
 	// PrefixRegex matches transcript filenames like IM_123.html or TWIG_05.html
 	PrefixRegex = regexp.MustCompile(`([A-Z0-9]+)_\d+\.html`)
 
-	// UserAgent is the Chrome user agent to use for requests
-	UserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	// Version is the archiver's release, set at build time with
+	// -ldflags "-X github.com/aramova/twit-transcript-archiver/go/internal/config.Version=1.2.0"
+	Version = "dev"
+
+	// Contact is a URL or email address where site operators can reach
+	// whoever runs the archiver; it is added to the User-Agent. Set via the
+	// TWIT_CONTACT environment variable or "contact" in the configuration file.
+	Contact = os.Getenv("TWIT_CONTACT")
+
+	// From is an email address sent in the From header of requests to the
+	// site. Set via TWIT_FROM or "from" in the configuration file.
+	From = os.Getenv("TWIT_FROM")
+
+	// UserAgent identifies the archiver in requests to the site. Set via
+	// TWIT_USER_AGENT or "user-agent" in the configuration file to replace
+	// the default built by ArchiverUserAgent.
+	UserAgent = envOr("TWIT_USER_AGENT", ArchiverUserAgent())
+
+	// Timezone is where episodes' dates fall: bylines without a zone are
+	// read in it and timestamps with one are converted to it. Set via
+	// TWIT_TIMEZONE or "timezone" in the configuration file; UTC by default.
+	Timezone = time.UTC
+
+	// Precedence ranks the kinds of copy of an episode's transcript, best
+	// first (see SourceKinds). When the archive has several copies of an
+	// episode, the best one is its transcript and the others are kept as
+	// alternatives. Set via TWIT_PREFER or "prefer" in the configuration
+	// file.
+	Precedence = SourceKinds
 )
 
+// SourceKinds are the kinds of copy an episode's transcript can be, in the
+// default order of Precedence: the site's transcript page, a Wayback
+// Machine copy of it, and transcripts made from a podcast feed's
+// transcript file, video captions or speech recognition
+var SourceKinds = []string{"site", "wayback", "feed", "captions", "asr"}
+
+// SetPrecedence sets Precedence from a comma-separated list of kinds, best
+// first. Kinds left out follow the listed ones in their default order.
+func SetPrecedence(list string) error {
+	var order []string
+	seen := make(map[string]bool)
+	for _, k := range strings.Split(list, ",") {
+		k = strings.ToLower(strings.TrimSpace(k))
+		if k == "" || seen[k] {
+			continue
+		}
+		known := false
+		for _, s := range SourceKinds {
+			known = known || s == k
+		}
+		if !known {
+			return fmt.Errorf("unknown transcript source '%s' (want %s)", k, strings.Join(SourceKinds, ", "))
+		}
+		seen[k] = true
+		order = append(order, k)
+	}
+	for _, k := range SourceKinds {
+		if !seen[k] {
+			order = append(order, k)
+		}
+	}
+	Precedence = order
+	return nil
+}
+
+// Rank is a kind's place in Precedence, 0 for the best
+func Rank(kind string) int {
+	for i, k := range Precedence {
+		if k == kind {
+			return i
+		}
+	}
+	return len(Precedence)
+}
+
+// SetTimezone sets Timezone from an IANA zone name such as
+// "America/Los_Angeles", or "UTC" or "Local"
+func SetTimezone(name string) error {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("unknown timezone '%s'", name)
+	}
+	Timezone = loc
+	return nil
+}
+
+// ProjectURL is where site operators can learn what the archiver does
+const ProjectURL = "https://github.com/aramova/twit-transcript-archiver"
+
+// ArchiverUserAgent returns the archiver's User-Agent: its name, Version,
+// ProjectURL and Contact, e.g. "twit-transcript-archiver/1.2.0
+// (+https://github.com/aramova/twit-transcript-archiver; archive@example.com)"
+func ArchiverUserAgent() string {
+	info := "+" + ProjectURL
+	if Contact != "" {
+		info += "; " + Contact
+	}
+	return fmt.Sprintf("twit-transcript-archiver/%s (%s)", Version, info)
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
 // ShowMap maps lowercase show title segments to file prefixes
 var ShowMap = map[string]string{
 	"intelligent machines": "IM",
@@ -42,14 +172,67 @@ var ShowMap = map[string]string{
 	"know how":             "KH",
 	"before you buy":       "BYB",
 	"ios today":            "IOS",
+	"ipad today":           "IPAD",
 	"all about android":    "AAA",
 	"floss weekly":         "FLOSS",
 	"ham nation":           "HAM",
 }
 
+// PrefixForTitle returns the prefix of the show whose name appears in a
+// listing title, or "" if the title is not a known show. Names only match
+// as whole words, and the longest matching name wins, so the result does not
+// depend on map order. Titles of a renamed show's earlier eras map to its
+// current prefix unless SplitByEra is set.
+func PrefixForTitle(title string) string {
+	titleLower := strings.ToLower(title)
+	best := ""
+	for name := range ShowMap {
+		if len(name) < len(best) || (len(name) == len(best) && name > best) {
+			continue
+		}
+		if containsWord(titleLower, name) {
+			best = name
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return CanonicalPrefix(ShowMap[best])
+}
+
+// containsWord reports whether phrase occurs in s delimited by non-word
+// characters or the ends of s
+func containsWord(s, phrase string) bool {
+	for from := 0; from <= len(s)-len(phrase); {
+		i := strings.Index(s[from:], phrase)
+		if i < 0 {
+			return false
+		}
+		start, end := from+i, from+i+len(phrase)
+		if !isWordByte(s, start-1) && !isWordByte(s, end) {
+			return true
+		}
+		from = start + 1
+	}
+	return false
+}
+
+func isWordByte(s string, i int) bool {
+	if i < 0 || i >= len(s) {
+		return false
+	}
+	c := s[i]
+	return c == '_' || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') || c >= 0x80
+}
+
 // GetDataDir returns the absolute path to the data directory.
-// It checks if "data" exists in current dir, otherwise checks "../data"
+// It checks if "data" exists in current dir, otherwise checks "../data".
+// If StorageLocation is set it is returned unchanged.
 func GetDataDir() string {
+	if StorageLocation != "" {
+		return StorageLocation
+	}
+
 	cwd, _ := os.Getwd()
 
 	// Check current dir
@@ -70,6 +253,54 @@ func GetDataDir() string {
 		return ppData
 	}
 
-	// Default to local data dir even if it doesn't exist (caller will create)
-	return localData
+	// Otherwise use the XDG data directory (caller will create)
+	return DefaultDataDir()
+}
+
+// DefaultDataDir is the data directory when none is configured and no
+// "data" directory is found: $XDG_DATA_HOME/twit-archiver, which defaults
+// to ~/.local/share/twit-archiver
+func DefaultDataDir() string {
+	base := os.Getenv("XDG_DATA_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "data"
+		}
+		base = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(base, "twit-archiver")
+}
+
+// CacheDir returns the directory for disposable files, or "" to keep them in
+// the data directory as the layout says. An archive in the default data
+// directory keeps its cache in $XDG_CACHE_HOME/twit-archiver
+// (~/.cache/twit-archiver); one elsewhere keeps it inside, unless
+// CacheLocation is set.
+func CacheDir(dataDir string) string {
+	if CacheLocation != "" {
+		return CacheLocation
+	}
+	if dataDir == DefaultDataDir() {
+		if dir, err := os.UserCacheDir(); err == nil {
+			return filepath.Join(dir, "twit-archiver")
+		}
+	}
+	return ""
+}
+
+// ShowName returns the show title segment for a prefix, or the prefix itself
+// if it is not in ShowMap. If several names share the prefix, the
+// alphabetically first is returned.
+func ShowName(prefix string) string {
+	best := ""
+	for name, p := range ShowMap {
+		if p == prefix && (best == "" || name < best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return prefix
+	}
+	return best
 }
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrefixForTitle(t *testing.T) {
+	ShowMap["this week"] = "TW"
+	ShowMap["now"] = "NOW"
+	defer delete(ShowMap, "this week")
+	defer delete(ShowMap, "now")
+
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Security Now 1000 Transcript", "SN"},
+		{"This Week in Tech 1000 Transcript", "TWIT"},
+		{"This Week in Space 150 Transcript", "TWIS"},
+		// The longest name wins over a shorter one it contains
+		{"This Week 12 Transcript", "TW"},
+		{"Right Now 5 Transcript", "NOW"},
+		// Names only match whole words
+		{"This Week in Technology Policy 3", "TW"},
+		{"Knowhow 12 Transcript", ""},
+		{"Hands-On Windows 100 Transcript", "HOW"},
+		{"Hands-On Tech 200 Transcript", "HOT"},
+		{"Security Nowhere 1 Transcript", ""},
+		{"Floss Weekly 800", "FLOSS"},
+		{"Tech News Weekly 300 Transcript", "TNW"},
+	}
+	for _, tt := range tests {
+		// Repeat to catch any dependence on map iteration order
+		for i := 0; i < 20; i++ {
+			if got := PrefixForTitle(tt.title); got != tt.want {
+				t.Errorf("PrefixForTitle(%q) = %q, want %q", tt.title, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestShowName(t *testing.T) {
+	if got := ShowName("SN"); got != "security now" {
+		t.Errorf("ShowName(SN) = %q", got)
+	}
+	if got := ShowName("XYZ"); got != "XYZ" {
+		t.Errorf("ShowName(XYZ) = %q", got)
+	}
+}
+
+func TestDefaultDirs(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "share"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+	oldCache := CacheLocation
+	defer func() { CacheLocation = oldCache }()
+	CacheLocation = ""
+
+	dataDir := DefaultDataDir()
+	if want := filepath.Join(dir, "share", "twit-archiver"); dataDir != want {
+		t.Errorf("DefaultDataDir() = %q, want %q", dataDir, want)
+	}
+	// The default archive keeps its list pages in the XDG cache
+	if got, want := ListPageDir(dataDir), filepath.Join(dir, "cache", "twit-archiver"); got != want {
+		t.Errorf("ListPageDir(default) = %q, want %q", got, want)
+	}
+	// Any other data directory keeps them inside, per the layout
+	other := filepath.Join(dir, "data")
+	if got := ListPageDir(other); got != ActiveLayout.ListPageDir(other) {
+		t.Errorf("ListPageDir(%q) = %q", other, got)
+	}
+	CacheLocation = filepath.Join(dir, "mycache")
+	if got := ListPageDir(other); got != CacheLocation {
+		t.Errorf("TWIT_CACHE not honored: %q", got)
+	}
+}
+
+func TestSetPrecedence(t *testing.T) {
+	defer func() { Precedence = SourceKinds }()
+	if err := SetPrecedence("ASR, wayback"); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(Precedence, ","); got != "asr,wayback,site,feed,captions" {
+		t.Errorf("Precedence = %s", got)
+	}
+	if Rank("asr") != 0 || Rank("site") != 2 || Rank("mirror") != len(SourceKinds) {
+		t.Errorf("Unexpected ranks %d, %d, %d", Rank("asr"), Rank("site"), Rank("mirror"))
+	}
+	if err := SetPrecedence("site,mirror"); err == nil {
+		t.Error("Expected an error for an unknown kind")
+	}
+}
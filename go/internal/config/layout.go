@@ -0,0 +1,120 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// Layout describes where each kind of file lives inside the data directory.
+// Each field is a directory template relative to the data directory, where
+// "{show}" is replaced by the show prefix. An empty template means the data
+// directory itself.
+type Layout struct {
+	Name      string
+	Raw       string // Downloaded transcript HTML
+	Markdown  string // Per-episode Markdown
+	Chunks    string // Combined chunk files
+	ListPages string // Cached transcript list pages
+	Media     string // Downloaded episode audio
+}
+
+var (
+	// FlatLayout keeps everything in the data directory (the original scheme)
+	FlatLayout = Layout{Name: "flat"}
+	// StructuredLayout separates raw, converted and chunked files per show
+	StructuredLayout = Layout{
+		Name:      "structured",
+		Raw:       "raw/{show}",
+		Markdown:  "md/{show}",
+		Chunks:    "chunks/{show}",
+		ListPages: "lists",
+		Media:     "media/{show}",
+	}
+
+	// ActiveLayout is the layout used by the fetcher and processor.
+	// Set via the TWIT_LAYOUT environment variable ("flat" or "structured").
+	ActiveLayout = layoutFromEnv()
+)
+
+func layoutFromEnv() Layout {
+	l, err := ParseLayout(os.Getenv("TWIT_LAYOUT"))
+	if err != nil {
+		fmt.Printf("Warning: %v. Using flat layout.\n", err)
+		return FlatLayout
+	}
+	return l
+}
+
+// ParseLayout returns a named layout
+func ParseLayout(name string) (Layout, error) {
+	switch strings.ToLower(name) {
+	case "", "flat":
+		return FlatLayout, nil
+	case "structured":
+		return StructuredLayout, nil
+	}
+	return Layout{}, fmt.Errorf("unknown layout '%s' (want flat or structured)", name)
+}
+
+func (l Layout) dir(dataDir, template, prefix string) string {
+	if template == "" {
+		return dataDir
+	}
+	return storage.Join(dataDir, strings.ReplaceAll(template, "{show}", prefix))
+}
+
+// RawDir is where a show's transcript HTML is stored
+func (l Layout) RawDir(dataDir, prefix string) string {
+	return l.dir(dataDir, l.Raw, prefix)
+}
+
+// MarkdownDir is where a show's per-episode Markdown is written
+func (l Layout) MarkdownDir(dataDir, prefix string) string {
+	return l.dir(dataDir, l.Markdown, prefix)
+}
+
+// ChunkDir is where a show's chunk files are written
+func (l Layout) ChunkDir(dataDir, prefix string) string {
+	return l.dir(dataDir, l.Chunks, prefix)
+}
+
+// MediaDir is where a show's episode audio is downloaded
+func (l Layout) MediaDir(dataDir, prefix string) string {
+	return l.dir(dataDir, l.Media, prefix)
+}
+
+// ListPageDir is where list pages are cached
+func (l Layout) ListPageDir(dataDir string) string {
+	return l.dir(dataDir, l.ListPages, "")
+}
+
+// ListPageDir is where list pages are cached for a data directory: the
+// cache directory if there is one, else the active layout's directory
+func ListPageDir(dataDir string) string {
+	if dir := CacheDir(dataDir); dir != "" {
+		return sourceListDir(dir)
+	}
+	return sourceListDir(ActiveLayout.ListPageDir(dataDir))
+}
+
+// RawGlob returns a pattern matching transcript HTML for a prefix ("*" for all)
+func (l Layout) RawGlob(dataDir, prefix string) string {
+	return storage.Join(l.RawDir(dataDir, prefix), prefix+"_*.html")
+}
+
+// RawFiles lists the transcript HTML archived for a show, including files
+// saved under the prefixes of its earlier titles (see EraPrefixes)
+func (l Layout) RawFiles(dataDir, prefix string) ([]string, error) {
+	var files []string
+	for _, p := range EraPrefixes(prefix) {
+		matches, err := storage.Glob(l.RawGlob(dataDir, p))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
@@ -0,0 +1,383 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// SettingsFile is the configuration file, looked for in the user's config
+// directory ($XDG_CONFIG_HOME/twit-archiver) and in the data directory
+const SettingsFile = "twit-archiver.yaml"
+
+// Settings holds the option values of the configuration files. Keys are
+// flag names. Top-level keys apply to every command with such a flag; keys
+// in a section named after a command ("fetch-transcripts", "run",
+// "cache prune") apply to that command only. Lists are joined with commas,
+// as the flags expect.
+type Settings struct {
+	// Files lists the files loaded, in order of increasing precedence
+	Files []string
+
+	sections map[string]map[string]string // "" for the top level
+	// lists keeps the items of "- item" lists, which sections joins with
+	// commas, for values that may contain commas
+	lists map[string]map[string][]string
+}
+
+// Loaded is the configuration read by LoadSettings
+var Loaded = &Settings{}
+
+// SettingsPaths returns the configuration files to read, in order of
+// increasing precedence. TWIT_CONFIG names a single file instead.
+func SettingsPaths() []string {
+	if p := os.Getenv("TWIT_CONFIG"); p != "" {
+		return []string{p}
+	}
+	var paths []string
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, "twit-archiver", SettingsFile))
+	}
+	return paths
+}
+
+// LoadSettings reads the user's configuration file and then the data
+// directory's, whose values take precedence, into Loaded. A "data-dir" key
+// in the user's file selects the data directory unless TWIT_STORAGE is set,
+// and "cache-dir" the cache directory unless TWIT_CACHE is. "contact",
+// "from" and "user-agent" set how requests identify the archiver, unless
+// their environment variables are set, "timezone" Timezone unless
+// TWIT_TIMEZONE is, and "prefer" Precedence unless TWIT_PREFER is. "source" (or TWIT_SOURCE) selects ActiveSource. With ReadOnly (or "read-only: true")
+// the data directory is made read-only in storage. Missing files are not an
+// error.
+func LoadSettings() (*Settings, error) {
+	s := &Settings{sections: make(map[string]map[string]string)}
+	for _, path := range SettingsPaths() {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err == nil {
+			err = s.merge(data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		s.Files = append(s.Files, path)
+	}
+	if dir := s.sections[""]["data-dir"]; dir != "" && StorageLocation == "" {
+		StorageLocation = expandHome(dir)
+	}
+	if dir := s.sections[""]["cache-dir"]; dir != "" && CacheLocation == "" {
+		CacheLocation = expandHome(dir)
+	}
+	if os.Getenv("TWIT_CONFIG") == "" {
+		path := storage.Join(GetDataDir(), SettingsFile)
+		data, err := storage.ReadFile(path)
+		if err == nil {
+			if err := s.merge(data); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			s.Files = append(s.Files, path)
+		} else if !errors.Is(err, storage.ErrNotExist) {
+			return nil, err
+		}
+	}
+	if v := s.sections[""]["contact"]; v != "" && os.Getenv("TWIT_CONTACT") == "" {
+		Contact = v
+	}
+	if v := s.sections[""]["from"]; v != "" && os.Getenv("TWIT_FROM") == "" {
+		From = v
+	}
+	if os.Getenv("TWIT_USER_AGENT") == "" {
+		UserAgent = s.sections[""]["user-agent"]
+		if UserAgent == "" {
+			UserAgent = ArchiverUserAgent()
+		}
+	}
+	if tz := envOr("TWIT_TIMEZONE", s.sections[""]["timezone"]); tz != "" {
+		if err := SetTimezone(tz); err != nil {
+			return nil, err
+		}
+	}
+	if p := envOr("TWIT_PREFER", s.sections[""]["prefer"]); p != "" {
+		if err := SetPrecedence(p); err != nil {
+			return nil, err
+		}
+	}
+	ActiveSource = nil
+	if name := envOr("TWIT_SOURCE", s.sections[""]["source"]); name != "" && name != DefaultSource {
+		src, err := ParseSource(name, s)
+		if err != nil {
+			return nil, err
+		}
+		ActiveSource = src
+	}
+	if v, ok := s.Get("", "read-only"); ok && v != "false" {
+		ReadOnly = true
+	}
+	if ReadOnly {
+		storage.ReadOnly = append(storage.ReadOnly, GetDataDir())
+	}
+	Loaded = s
+	return s, nil
+}
+
+// expandHome replaces a leading "~/" with the user's home directory
+func expandHome(dir string) string {
+	if strings.HasPrefix(dir, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, dir[2:])
+		}
+	}
+	return dir
+}
+
+// merge adds the values of a configuration file, replacing earlier ones
+func (s *Settings) merge(data []byte) error {
+	sections, lists, err := parseSettings(data)
+	if err != nil {
+		return err
+	}
+	if s.sections == nil {
+		s.sections = make(map[string]map[string]string)
+	}
+	if s.lists == nil {
+		s.lists = make(map[string]map[string][]string)
+	}
+	for name, values := range sections {
+		if s.sections[name] == nil {
+			s.sections[name] = make(map[string]string)
+		}
+		for k, v := range values {
+			s.sections[name][k] = v
+			if items, ok := lists[name][k]; ok {
+				if s.lists[name] == nil {
+					s.lists[name] = make(map[string][]string)
+				}
+				s.lists[name][k] = items
+			} else {
+				delete(s.lists[name], k)
+			}
+		}
+	}
+	return nil
+}
+
+// Get returns a value from a section ("" for the top level)
+func (s *Settings) Get(section, key string) (string, bool) {
+	v, ok := s.sections[section][key]
+	return v, ok
+}
+
+// List returns a value's items: those of a "- item" list as written, or
+// else the value split at commas
+func (s *Settings) List(section, key string) []string {
+	if items, ok := s.lists[section][key]; ok {
+		return items
+	}
+	v, ok := s.sections[section][key]
+	if !ok {
+		return nil
+	}
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Shows returns the top-level "shows" list, the default for commands that
+// take shows as arguments
+func (s *Settings) Shows() []string {
+	v, _ := s.Get("", "shows")
+	var shows []string
+	for _, show := range strings.Split(v, ",") {
+		if show = strings.TrimSpace(show); show != "" {
+			shows = append(shows, show)
+		}
+	}
+	return shows
+}
+
+// Apply sets the flags of fs that were not given on the command line from
+// the top level, then from the section of the command's first word (e.g.
+// "cache"), then from the section of its full name ("cache prune"). It must
+// be called after fs is parsed. Keys in the full-name section that match no
+// flag are an error, to catch typos.
+func (s *Settings) Apply(fs *flag.FlagSet, name string) error {
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	sections := []string{""}
+	if i := strings.Index(name, " "); i > 0 {
+		sections = append(sections, name[:i])
+	}
+	sections = append(sections, name)
+
+	values := make(map[string]string)
+	for _, section := range sections {
+		for k, v := range s.sections[section] {
+			values[k] = v
+		}
+	}
+	var keys []string
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if fs.Lookup(k) == nil {
+			if _, ok := s.sections[name][k]; ok {
+				return fmt.Errorf("%s: unknown option '%s' for %s", SettingsFile, k, name)
+			}
+			continue
+		}
+		if given[k] {
+			continue
+		}
+		if err := fs.Set(k, values[k]); err != nil {
+			return fmt.Errorf("%s: %s: %w", SettingsFile, k, err)
+		}
+	}
+	return nil
+}
+
+// ParseSettings parses the YAML subset the configuration file uses: scalar
+// or list values ("- item" lines or "[a, b]") at the top level or one level
+// down, under a section name, with "#" comments. It returns the values by
+// section, "" for the top level.
+func ParseSettings(data []byte) (map[string]map[string]string, error) {
+	sections, _, err := parseSettings(data)
+	return sections, err
+}
+
+// parseSettings is ParseSettings, also returning the items of each "- item"
+// list by section and key
+func parseSettings(data []byte) (map[string]map[string]string, map[string]map[string][]string, error) {
+	sections := map[string]map[string]string{"": {}}
+	lists := make(map[string]map[string][]string)
+	section := ""       // current section, while indented
+	sectionIndent := -1 // indentation of the current section's keys
+	var list *[]string  // open "- item" list
+	listSection, listKey := "", ""
+	flush := func() {
+		if list != nil && len(*list) > 0 {
+			sections[listSection][listKey] = strings.Join(*list, ",")
+			if lists[listSection] == nil {
+				lists[listSection] = make(map[string][]string)
+			}
+			lists[listSection][listKey] = *list
+		}
+		list = nil
+	}
+
+	for n, line := range strings.Split(string(data), "\n") {
+		line = stripComment(strings.TrimRight(line, " \t\r"))
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if strings.HasPrefix(line[indent:], "\t") {
+			return nil, nil, fmt.Errorf("line %d: indent with spaces, not tabs", n+1)
+		}
+
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			if list == nil {
+				return nil, nil, fmt.Errorf("line %d: list item outside a list", n+1)
+			}
+			*list = append(*list, unquote(strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))))
+			continue
+		}
+		flush()
+
+		key, value, ok := strings.Cut(trimmed, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, nil, fmt.Errorf("line %d: expected 'key: value'", n+1)
+		}
+		value = strings.TrimSpace(value)
+
+		switch {
+		case indent == 0:
+			section, sectionIndent = "", -1
+		case section == "":
+			return nil, nil, fmt.Errorf("line %d: unexpected indentation", n+1)
+		case sectionIndent < 0:
+			sectionIndent = indent
+		case indent != sectionIndent:
+			return nil, nil, fmt.Errorf("line %d: inconsistent indentation", n+1)
+		}
+
+		current := section
+		if value == "" {
+			if indent == 0 {
+				// A section or a list; the next lines tell which
+				section = key
+				if sections[section] == nil {
+					sections[section] = make(map[string]string)
+				}
+			}
+			list, listSection, listKey = &[]string{}, current, key
+			continue
+		}
+		sections[current][key] = parseScalar(value)
+	}
+	flush()
+
+	// A top-level key followed by a list is a value, not a section
+	for name, values := range sections {
+		if name != "" && len(values) == 0 {
+			delete(sections, name)
+		}
+	}
+	return sections, lists, nil
+}
+
+// parseScalar returns a value, with "[a, b]" lists joined by commas
+func parseScalar(v string) string {
+	if strings.HasPrefix(v, "[") && strings.HasSuffix(v, "]") {
+		var items []string
+		for _, item := range strings.Split(v[1:len(v)-1], ",") {
+			if item = unquote(strings.TrimSpace(item)); item != "" {
+				items = append(items, item)
+			}
+		}
+		return strings.Join(items, ",")
+	}
+	return unquote(v)
+}
+
+func unquote(v string) string {
+	if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+		return v[1 : len(v)-1]
+	}
+	return v
+}
+
+// stripComment removes a "#" comment that is not inside quotes
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return strings.TrimRight(line[:i], " \t")
+		}
+	}
+	return line
+}
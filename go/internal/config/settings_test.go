@@ -0,0 +1,229 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+const testSettings = `# Nightly run
+data-dir: /srv/twit   # archive location
+shows: [SN, "TWIT"]
+throttle: 2s
+
+fetch-transcripts:
+  pages: 5
+  strict:
+    - network
+    - not_found
+
+run:
+  export: jsonl
+`
+
+func TestParseSettings(t *testing.T) {
+	got, err := ParseSettings([]byte(testSettings))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]map[string]string{
+		"":                  {"data-dir": "/srv/twit", "shows": "SN,TWIT", "throttle": "2s"},
+		"fetch-transcripts": {"pages": "5", "strict": "network,not_found"},
+		"run":               {"export": "jsonl"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseSettings = %v, want %v", got, want)
+	}
+
+	// A top-level list is a value, not a section
+	got, err = ParseSettings([]byte("shows:\n  - SN\n  - 'IM'\npages: 3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[""]["shows"] != "SN,IM" || got[""]["pages"] != "3" || len(got) != 1 {
+		t.Errorf("Unexpected list parse: %v", got)
+	}
+
+	for _, bad := range []string{"pages 5\n", "  pages: 5\n", "run:\n  a: 1\n    b: 2\n", "- SN\n"} {
+		if _, err := ParseSettings([]byte(bad)); err == nil {
+			t.Errorf("ParseSettings(%q) should fail", bad)
+		}
+	}
+}
+
+func TestSettingsApply(t *testing.T) {
+	s := &Settings{}
+	if err := s.merge([]byte(testSettings)); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := flag.NewFlagSet("fetch-transcripts", flag.ContinueOnError)
+	pages := fs.Int("pages", 200, "")
+	throttle := fs.Duration("throttle", time.Second, "")
+	strict := fs.String("strict", "", "")
+	fs.Parse([]string{"--throttle", "5s"})
+	if err := s.Apply(fs, fs.Name()); err != nil {
+		t.Fatal(err)
+	}
+	if *pages != 5 || *strict != "network,not_found" {
+		t.Errorf("Settings not applied: pages=%d strict=%q", *pages, *strict)
+	}
+	if *throttle != 5*time.Second {
+		t.Errorf("A flag on the command line should win, got throttle %v", *throttle)
+	}
+
+	// Sections of other commands do not apply
+	fs = flag.NewFlagSet("cache prune", flag.ContinueOnError)
+	fs.String("export", "", "")
+	fs.Parse(nil)
+	if err := s.Apply(fs, fs.Name()); err != nil {
+		t.Fatal(err)
+	}
+	if v := fs.Lookup("export").Value.String(); v != "" {
+		t.Errorf("The run section leaked into cache prune: %q", v)
+	}
+
+	// Unknown keys in a command's own section are reported
+	fs = flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.Parse(nil)
+	if err := s.Apply(fs, fs.Name()); err == nil {
+		t.Error("Expected an error for an unknown option")
+	}
+
+	if got := s.Shows(); !reflect.DeepEqual(got, []string{"SN", "TWIT"}) {
+		t.Errorf("Shows() = %v", got)
+	}
+}
+
+func TestLoadSettings(t *testing.T) {
+	dir := t.TempDir()
+	dataDir := filepath.Join(dir, "data")
+	os.Mkdir(dataDir, 0755)
+	user := filepath.Join(dir, "user.yaml")
+	os.WriteFile(user, []byte("data-dir: "+dataDir+"\npages: 7\n"), 0644)
+	os.WriteFile(filepath.Join(dataDir, SettingsFile), []byte("pages: 9\n"), 0644)
+
+	oldLocation, oldLoaded := StorageLocation, Loaded
+	defer func() { StorageLocation, Loaded = oldLocation, oldLoaded }()
+	StorageLocation = ""
+
+	// TWIT_CONFIG replaces the search, so the data directory's file is skipped
+	t.Setenv("TWIT_CONFIG", user)
+	s, err := LoadSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if StorageLocation != dataDir {
+		t.Errorf("data-dir not applied: %q", StorageLocation)
+	}
+	if v, _ := s.Get("", "pages"); v != "7" || len(s.Files) != 1 {
+		t.Errorf("Unexpected settings: pages=%s files=%v", v, s.Files)
+	}
+
+	// The data directory's file overrides the user's
+	t.Setenv("TWIT_CONFIG", "")
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	os.MkdirAll(filepath.Join(dir, "twit-archiver"), 0755)
+	os.Rename(user, filepath.Join(dir, "twit-archiver", SettingsFile))
+	StorageLocation = ""
+	if s, err = LoadSettings(); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := s.Get("", "pages"); v != "9" || len(s.Files) != 2 || Loaded != s {
+		t.Errorf("Unexpected settings: pages=%s files=%v", v, s.Files)
+	}
+}
+
+func TestLoadSettingsReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	cfg := filepath.Join(dir, "user.yaml")
+	os.WriteFile(cfg, []byte("data-dir: "+dir+"\nread-only: true\n"), 0644)
+	t.Setenv("TWIT_CONFIG", cfg)
+
+	oldLocation, oldLoaded, oldReadOnly := StorageLocation, Loaded, ReadOnly
+	defer func() {
+		StorageLocation, Loaded, ReadOnly = oldLocation, oldLoaded, oldReadOnly
+		storage.ReadOnly = nil
+	}()
+	StorageLocation, ReadOnly = "", false
+
+	if _, err := LoadSettings(); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.WriteFile(filepath.Join(dir, "index.json"), []byte("{}")); !errors.Is(err, storage.ErrReadOnly) {
+		t.Errorf("Expected the data directory to be read-only, got %v", err)
+	}
+}
+
+func TestLoadSettingsIdentity(t *testing.T) {
+	dir := t.TempDir()
+	cfg := filepath.Join(dir, "user.yaml")
+	os.WriteFile(cfg, []byte("data-dir: "+dir+"\ncontact: https://example.com/archive\nfrom: archive@example.com\n"), 0644)
+	t.Setenv("TWIT_CONFIG", cfg)
+	t.Setenv("TWIT_CONTACT", "")
+	t.Setenv("TWIT_FROM", "")
+	t.Setenv("TWIT_USER_AGENT", "")
+
+	oldLocation, oldLoaded := StorageLocation, Loaded
+	oldContact, oldFrom, oldAgent, oldVersion := Contact, From, UserAgent, Version
+	defer func() {
+		StorageLocation, Loaded = oldLocation, oldLoaded
+		Contact, From, UserAgent, Version = oldContact, oldFrom, oldAgent, oldVersion
+	}()
+	StorageLocation, Version = "", "1.2.0"
+
+	if _, err := LoadSettings(); err != nil {
+		t.Fatal(err)
+	}
+	if From != "archive@example.com" {
+		t.Errorf("From = %q", From)
+	}
+	want := "twit-transcript-archiver/1.2.0 (+" + ProjectURL + "; https://example.com/archive)"
+	if UserAgent != want {
+		t.Errorf("UserAgent = %q, want %q", UserAgent, want)
+	}
+
+	// An explicit user-agent replaces the generated one
+	os.WriteFile(cfg, []byte("data-dir: "+dir+"\nuser-agent: MyMirror/1.0\n"), 0644)
+	if _, err := LoadSettings(); err != nil {
+		t.Fatal(err)
+	}
+	if UserAgent != "MyMirror/1.0" {
+		t.Errorf("UserAgent = %q, want MyMirror/1.0", UserAgent)
+	}
+}
+
+func TestLoadSettingsTimezone(t *testing.T) {
+	dir := t.TempDir()
+	cfg := filepath.Join(dir, "user.yaml")
+	os.WriteFile(cfg, []byte("data-dir: "+dir+"\ntimezone: America/New_York\n"), 0644)
+	t.Setenv("TWIT_CONFIG", cfg)
+	t.Setenv("TWIT_TIMEZONE", "")
+
+	oldLocation, oldLoaded := StorageLocation, Loaded
+	defer func() { StorageLocation, Loaded, Timezone = oldLocation, oldLoaded, time.UTC }()
+	StorageLocation = ""
+
+	if _, err := LoadSettings(); err != nil {
+		t.Fatal(err)
+	}
+	if Timezone.String() != "America/New_York" {
+		t.Errorf("Timezone = %v, want America/New_York", Timezone)
+	}
+
+	// The environment takes precedence, and unknown zones are an error
+	t.Setenv("TWIT_TIMEZONE", "Europe/London")
+	if _, err := LoadSettings(); err != nil || Timezone.String() != "Europe/London" {
+		t.Errorf("Timezone = %v (%v), want Europe/London", Timezone, err)
+	}
+	t.Setenv("TWIT_TIMEZONE", "Mars/Olympus_Mons")
+	if _, err := LoadSettings(); err == nil {
+		t.Error("Expected an error for an unknown timezone")
+	}
+}
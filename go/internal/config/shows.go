@@ -0,0 +1,271 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// ShowDescriptions gives a one-line description of each show, by prefix
+var ShowDescriptions = map[string]string{
+	"IM":    "AI and the people building it (formerly This Week in Google)",
+	"TWIG":  "Google, the cloud and the web (renamed Intelligent Machines)",
+	"WW":    "Microsoft and Windows news",
+	"MBW":   "Apple and Mac news",
+	"TWIT":  "The weekly tech news roundtable",
+	"SN":    "Computer security and privacy with Steve Gibson",
+	"TWIS":  "Space exploration and astronomy",
+	"TNW":   "Tech news with the journalists who broke it",
+	"ULS":   "Linux and open source",
+	"HOT":   "Tech product reviews and how-tos",
+	"HOW":   "Windows tips and tricks",
+	"HOA":   "Apple tips and tricks",
+	"KH":    "Hands-on tech projects",
+	"BYB":   "Gadget buying advice",
+	"IOS":   "iPhone and iPad tips (formerly iPad Today)",
+	"IPAD":  "iPad apps and news (renamed iOS Today)",
+	"AAA":   "Android news and apps",
+	"FLOSS": "Free, libre and open source software projects",
+	"HAM":   "Amateur radio",
+}
+
+// ShowAliases maps the prefix of a renamed show's earlier title to the prefix
+// of the title it became. Episode numbering carried over, so by default both
+// eras are archived as one show under the current prefix.
+var ShowAliases = map[string]string{
+	"TWIG": "IM",
+	"IPAD": "IOS",
+}
+
+// SplitByEra keeps the eras of renamed shows apart, each under its own prefix
+var SplitByEra = false
+
+// CanonicalPrefix returns the current prefix of a show, following
+// ShowAliases from earlier titles, or the prefix itself if SplitByEra is set
+func CanonicalPrefix(prefix string) string {
+	if SplitByEra {
+		return prefix
+	}
+	for i := 0; i < len(ShowAliases); i++ {
+		next, ok := ShowAliases[prefix]
+		if !ok {
+			break
+		}
+		prefix = next
+	}
+	return prefix
+}
+
+// EraPrefixes returns the prefixes a show's files may be archived under:
+// the prefix itself followed by those of its earlier titles, or only the
+// prefix if SplitByEra is set
+func EraPrefixes(prefix string) []string {
+	prefixes := []string{prefix}
+	if SplitByEra {
+		return prefixes
+	}
+	var earlier []string
+	for old := range ShowAliases {
+		if old != prefix && CanonicalPrefix(old) == prefix {
+			earlier = append(earlier, old)
+		}
+	}
+	sort.Strings(earlier)
+	return append(prefixes, earlier...)
+}
+
+// Show describes one entry of the show catalog
+type Show struct {
+	Prefix      string
+	Name        string // Lowercase title segment, as in ShowMap
+	Description string
+}
+
+// Shows returns the show catalog ordered by prefix
+func Shows() []Show {
+	shows := make([]Show, 0, len(ShowMap))
+	for name, prefix := range ShowMap {
+		shows = append(shows, Show{Prefix: prefix, Name: name, Description: ShowDescriptions[prefix]})
+	}
+	sort.Slice(shows, func(i, j int) bool { return shows[i].Prefix < shows[j].Prefix })
+	return shows
+}
+
+// ResolveShow maps a show argument, given as a prefix in any case or as a
+// show name, to its canonical prefix
+func ResolveShow(arg string) (string, bool) {
+	clean := strings.ToLower(strings.TrimSpace(arg))
+	for name, prefix := range ShowMap {
+		if clean == strings.ToLower(prefix) || clean == name {
+			return CanonicalPrefix(prefix), true
+		}
+	}
+	return "", false
+}
+
+// SuggestShows returns up to three shows whose name or prefix is close to
+// arg, closest first
+func SuggestShows(arg string) []Show {
+	clean := strings.ToLower(strings.TrimSpace(arg))
+	type scored struct {
+		show Show
+		dist int
+	}
+	var matches []scored
+	for _, s := range Shows() {
+		d := editDistance(clean, s.Name)
+		if pd := editDistance(clean, strings.ToLower(s.Prefix)); pd < d {
+			d = pd
+		}
+		// A fragment of the name ("google") is as good as a one-letter typo
+		if len(clean) >= 3 && strings.Contains(s.Name, clean) && d > 1 {
+			d = 1
+		}
+		limit := len(clean) / 3
+		if limit < 2 {
+			limit = 2
+		}
+		if d <= limit {
+			matches = append(matches, scored{s, d})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].dist < matches[j].dist })
+	var shows []Show
+	for i := 0; i < len(matches) && i < 3; i++ {
+		shows = append(shows, matches[i].show)
+	}
+	return shows
+}
+
+// UnknownShowError describes an unrecognized show argument, suggesting the
+// closest known shows
+func UnknownShowError(arg string) error {
+	suggestions := SuggestShows(arg)
+	if len(suggestions) == 0 {
+		return fmt.Errorf("unknown show '%s' (run 'twit-archiver shows' for the list)", arg)
+	}
+	var alts []string
+	for _, s := range suggestions {
+		alts = append(alts, fmt.Sprintf("'%s' (%s)", s.Name, s.Prefix))
+	}
+	return fmt.Errorf("unknown show '%s'; did you mean %s?", arg, strings.Join(alts, " or "))
+}
+
+// editDistance is the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if d := prev[j] + 1; d < cur[j] {
+				cur[j] = d
+			}
+			if d := cur[j-1] + 1; d < cur[j] {
+				cur[j] = d
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// CustomShowsFile lists shows added to the catalog at run time (for example
+// by fetch-transcripts --add-unknown), kept at the root of the data directory
+const CustomShowsFile = "shows.json"
+
+// CustomShow is a show added outside the built-in ShowMap
+type CustomShow struct {
+	Name   string `json:"name"` // Lowercase title segment
+	Prefix string `json:"prefix"`
+}
+
+// LoadCustomShows adds the data directory's custom shows to ShowMap. A
+// missing file is not an error.
+func LoadCustomShows(dataDir string) error {
+	shows, err := readCustomShows(dataDir)
+	for _, s := range shows {
+		if _, ok := ShowMap[s.Name]; !ok {
+			ShowMap[s.Name] = s.Prefix
+		}
+	}
+	return err
+}
+
+// AddCustomShow records a new show in the data directory and in ShowMap
+func AddCustomShow(dataDir string, show CustomShow) error {
+	shows, err := readCustomShows(dataDir)
+	if err != nil {
+		return err
+	}
+	shows = append(shows, show)
+	data, err := json.MarshalIndent(shows, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := storage.WriteFile(storage.Join(dataDir, CustomShowsFile), data); err != nil {
+		return err
+	}
+	ShowMap[show.Name] = show.Prefix
+	return nil
+}
+
+func readCustomShows(dataDir string) ([]CustomShow, error) {
+	data, err := storage.ReadFile(storage.Join(dataDir, CustomShowsFile))
+	if errors.Is(err, storage.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var shows []CustomShow
+	if err := json.Unmarshal(data, &shows); err != nil {
+		return nil, fmt.Errorf("%s: %w", CustomShowsFile, err)
+	}
+	return shows, nil
+}
+
+// GeneratePrefix builds an unused prefix from a show name's initials
+// ("this week in enterprise tech" -> "TWIET"), adding a number if needed
+func GeneratePrefix(name string) string {
+	var initials strings.Builder
+	for _, w := range strings.Fields(name) {
+		for _, r := range w {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				initials.WriteRune(unicode.ToUpper(r))
+				break
+			}
+		}
+	}
+	base := initials.String()
+	if base == "" {
+		base = "SHOW"
+	}
+	taken := func(p string) bool {
+		for _, existing := range ShowMap {
+			if existing == p {
+				return true
+			}
+		}
+		return false
+	}
+	prefix := base
+	for n := 2; taken(prefix); n++ {
+		prefix = fmt.Sprintf("%s%d", base, n)
+	}
+	return prefix
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestResolveShow(t *testing.T) {
+	for arg, want := range map[string]string{"sn": "SN", "TWIG": "IM", "Security Now": "SN", " windows weekly ": "WW", "ipad today": "IOS"} {
+		if got, ok := ResolveShow(arg); !ok || got != want {
+			t.Errorf("ResolveShow(%q) = %q, %v; want %q", arg, got, ok, want)
+		}
+	}
+	if _, ok := ResolveShow("securty now"); ok {
+		t.Error("Expected a misspelled name to be unknown")
+	}
+}
+
+func TestSuggestShows(t *testing.T) {
+	for arg, want := range map[string]string{
+		"securty now":   "SN",
+		"secuirty now":  "SN",
+		"twgi":          "TWIG",
+		"google":        "TWIG",
+		"windows wekly": "WW",
+	} {
+		got := SuggestShows(arg)
+		if len(got) == 0 || got[0].Prefix != want {
+			t.Errorf("SuggestShows(%q) = %+v; want %s first", arg, got, want)
+		}
+	}
+	if got := SuggestShows("cooking with gas"); len(got) != 0 {
+		t.Errorf("Expected no suggestions, got %+v", got)
+	}
+
+	err := UnknownShowError("securty now")
+	if !strings.Contains(err.Error(), "did you mean 'security now' (SN)?") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestEras(t *testing.T) {
+	if got := PrefixForTitle("This Week in Google 700 Transcript"); got != "IM" {
+		t.Errorf("PrefixForTitle = %q, want IM", got)
+	}
+	if got := EraPrefixes("IM"); len(got) != 2 || got[0] != "IM" || got[1] != "TWIG" {
+		t.Errorf("EraPrefixes(IM) = %v", got)
+	}
+	if got := EraPrefixes("SN"); len(got) != 1 {
+		t.Errorf("EraPrefixes(SN) = %v", got)
+	}
+
+	SplitByEra = true
+	defer func() { SplitByEra = false }()
+	if got := PrefixForTitle("This Week in Google 700 Transcript"); got != "TWIG" {
+		t.Errorf("PrefixForTitle with SplitByEra = %q, want TWIG", got)
+	}
+	if got, _ := ResolveShow("twig"); got != "TWIG" {
+		t.Errorf("ResolveShow with SplitByEra = %q, want TWIG", got)
+	}
+	if got := EraPrefixes("IM"); len(got) != 1 {
+		t.Errorf("EraPrefixes with SplitByEra = %v", got)
+	}
+}
+
+func TestCustomShows(t *testing.T) {
+	dir, err := os.MkdirTemp("", "customshows")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer delete(ShowMap, "this week in enterprise tech")
+
+	if p := GeneratePrefix("this week in enterprise tech"); p != "TWIET" {
+		t.Errorf("GeneratePrefix = %q, want TWIET", p)
+	}
+	// "TWIG" is taken, so a number is added
+	if p := GeneratePrefix("the world is great"); p != "TWIG2" {
+		t.Errorf("GeneratePrefix = %q, want TWIG2", p)
+	}
+
+	if err := AddCustomShow(dir, CustomShow{Name: "this week in enterprise tech", Prefix: "TWIET"}); err != nil {
+		t.Fatal(err)
+	}
+	delete(ShowMap, "this week in enterprise tech")
+	if err := LoadCustomShows(dir); err != nil {
+		t.Fatal(err)
+	}
+	if got := PrefixForTitle("This Week in Enterprise Tech 500 Transcript"); got != "TWIET" {
+		t.Errorf("PrefixForTitle after loading = %q, want TWIET", got)
+	}
+	if err := LoadCustomShows(dir + "/missing"); err != nil {
+		t.Errorf("A missing file should not be an error: %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// DefaultSource is twit.tv, whose URLs are BaseSiteURL and BaseListURL and
+// whose patterns are built into the scraper and converter
+const DefaultSource = "twit"
+
+// Source is another site publishing HTML transcripts: its listing pages,
+// the patterns finding transcript links on them and the patterns finding a
+// transcript page's parts. Patterns are regular expressions tried in order.
+// In Items the first group captures the link and the second the title; in
+// Title, Byline and Body the first group captures the part.
+type Source struct {
+	Name string
+	// SiteURL is prefixed to the relative links of the listing
+	SiteURL string
+	// ListURL is a listing page, with "{page}" replaced by its number.
+	// FirstListURL, if set, is page 1.
+	ListURL, FirstListURL string
+	Items                 []*regexp.Regexp
+	Title, Byline, Body   []*regexp.Regexp
+}
+
+// ActiveSource is the site being archived, nil for twit.tv. Set via
+// TWIT_SOURCE or "source" in the configuration file, naming a
+// "source NAME" section that LoadSettings reads with ParseSource.
+var ActiveSource *Source
+
+var sourceNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// ParseSource builds a source from its section of the configuration file:
+// site-url, list-url and optionally first-list-url, then item, title,
+// byline and body, each a pattern or a list of them ("- pattern" lines,
+// since patterns may hold commas). list-url and item are required; pages
+// whose parts no pattern finds fall back to the built-in extraction.
+func ParseSource(name string, s *Settings) (*Source, error) {
+	if !sourceNameRegex.MatchString(name) {
+		return nil, fmt.Errorf("invalid source name '%s' (want lowercase letters, digits and dashes)", name)
+	}
+	section := "source " + name
+	if _, ok := s.sections[section]; !ok {
+		return nil, fmt.Errorf("unknown source '%s': no '%s' section in %s", name, section, SettingsFile)
+	}
+	src := &Source{Name: name}
+	src.SiteURL, _ = s.Get(section, "site-url")
+	src.ListURL, _ = s.Get(section, "list-url")
+	src.FirstListURL, _ = s.Get(section, "first-list-url")
+	src.SiteURL = strings.TrimRight(src.SiteURL, "/")
+	if !strings.HasPrefix(src.SiteURL, "http://") && !strings.HasPrefix(src.SiteURL, "https://") {
+		return nil, fmt.Errorf("source %s: site-url must be an http(s) URL", name)
+	}
+	if !strings.Contains(src.ListURL, "{page}") {
+		return nil, fmt.Errorf("source %s: list-url must contain {page}", name)
+	}
+
+	for key, dst := range map[string]*[]*regexp.Regexp{
+		"item": &src.Items, "title": &src.Title, "byline": &src.Byline, "body": &src.Body,
+	} {
+		for _, p := range s.List(section, key) {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return nil, fmt.Errorf("source %s: invalid %s pattern: %w", name, key, err)
+			}
+			groups := 1
+			if key == "item" {
+				groups = 2
+			}
+			if re.NumSubexp() < groups {
+				return nil, fmt.Errorf("source %s: %s pattern %s needs %d capture group(s)", name, key, p, groups)
+			}
+			*dst = append(*dst, re)
+		}
+	}
+	if len(src.Items) == 0 {
+		return nil, fmt.Errorf("source %s: no item pattern", name)
+	}
+	return src, nil
+}
+
+// SiteURL returns the URL that the links of the active source's listing
+// are relative to
+func SiteURL() string {
+	if ActiveSource != nil {
+		return ActiveSource.SiteURL
+	}
+	return BaseSiteURL
+}
+
+// ListURL returns the URL of a page of the active source's listing
+func ListURL(page int) string {
+	s := ActiveSource
+	switch {
+	case s == nil && page > 1:
+		return fmt.Sprintf("%s?page=%d", BaseListURL, page)
+	case s == nil:
+		return BaseListURL
+	case page <= 1 && s.FirstListURL != "":
+		return s.FirstListURL
+	}
+	return strings.ReplaceAll(s.ListURL, "{page}", strconv.Itoa(page))
+}
+
+// sourceListDir keeps the listing pages of other sources apart from
+// twit.tv's, since they are cached by page number
+func sourceListDir(dir string) string {
+	if ActiveSource == nil {
+		return dir
+	}
+	return storage.Join(dir, "source-"+ActiveSource.Name)
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSource = `source: maxfun
+source maxfun:
+  site-url: https://example.org/
+  first-list-url: https://example.org/transcripts
+  list-url: https://example.org/transcripts/page/{page}
+  item:
+    - <a class="ep" href="([^"]+)">(.*?)</a>
+    - <li><a href="([^"]+)" title="([^"]+)">
+  title: <h1 class="entry">(.*?)</h1>
+  body: (?s)<section class="transcript">(.*?)</section>
+`
+
+func TestLoadSettingsSource(t *testing.T) {
+	dir := t.TempDir()
+	cfg := filepath.Join(dir, "user.yaml")
+	os.WriteFile(cfg, []byte("data-dir: "+dir+"\n"+testSource), 0644)
+	t.Setenv("TWIT_CONFIG", cfg)
+	t.Setenv("TWIT_SOURCE", "")
+
+	oldLocation, oldLoaded := StorageLocation, Loaded
+	defer func() { StorageLocation, Loaded, ActiveSource = oldLocation, oldLoaded, nil }()
+	StorageLocation = ""
+
+	if _, err := LoadSettings(); err != nil {
+		t.Fatal(err)
+	}
+	s := ActiveSource
+	if s == nil || s.Name != "maxfun" || s.SiteURL != "https://example.org" || len(s.Items) != 2 || len(s.Title) != 1 || len(s.Byline) != 0 {
+		t.Fatalf("ActiveSource = %+v", s)
+	}
+	// Commas in list items are kept
+	if !strings.Contains(s.Items[1].String(), `title="([^"]+)"`) {
+		t.Errorf("Item pattern = %s", s.Items[1])
+	}
+	if SiteURL() != "https://example.org" || ListURL(1) != "https://example.org/transcripts" || ListURL(3) != "https://example.org/transcripts/page/3" {
+		t.Errorf("URLs: %s %s %s", SiteURL(), ListURL(1), ListURL(3))
+	}
+	if got := ListPageDir(dir); !strings.HasSuffix(got, "source-maxfun") {
+		t.Errorf("ListPageDir = %s, want a directory of the source's own", got)
+	}
+
+	// TWIT_SOURCE overrides the file; "twit" is the built-in site
+	t.Setenv("TWIT_SOURCE", DefaultSource)
+	if _, err := LoadSettings(); err != nil || ActiveSource != nil {
+		t.Errorf("ActiveSource = %+v (%v), want twit.tv", ActiveSource, err)
+	}
+	if SiteURL() != BaseSiteURL || ListURL(2) != BaseListURL+"?page=2" {
+		t.Errorf("twit.tv URLs: %s %s", SiteURL(), ListURL(2))
+	}
+	t.Setenv("TWIT_SOURCE", "nosuch")
+	if _, err := LoadSettings(); err == nil {
+		t.Error("Expected an error for an undefined source")
+	}
+}
+
+func TestParseSourceErrors(t *testing.T) {
+	for _, bad := range []string{
+		"site-url: example.org\nlist-url: https://example.org/{page}\nitem: <a href=\"(.*)\">(.*)</a>",
+		"site-url: https://example.org\nlist-url: https://example.org/\nitem: <a href=\"(.*)\">(.*)</a>",
+		"site-url: https://example.org\nlist-url: https://example.org/{page}",
+		"site-url: https://example.org\nlist-url: https://example.org/{page}\nitem: <a href=\"(.*)\">",
+		"site-url: https://example.org\nlist-url: https://example.org/{page}\nitem: <a href=\"(.*)\">(.*)</a>\nbody: (unclosed",
+	} {
+		s := &Settings{}
+		indented := "  " + strings.ReplaceAll(bad, "\n", "\n  ")
+		if err := s.merge([]byte("source x:\n" + indented + "\n")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ParseSource("x", s); err == nil {
+			t.Errorf("ParseSource should fail for:\n%s", bad)
+		}
+	}
+	if _, err := ParseSource("Bad Name", &Settings{}); err == nil {
+		t.Error("Expected an error for an invalid source name")
+	}
+}
@@ -0,0 +1,115 @@
+package converter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// AdMode controls how detected sponsor reads are handled
+type AdMode string
+
+const (
+	AdsKeep  AdMode = "keep"
+	AdsStrip AdMode = "strip"
+	AdsMark  AdMode = "mark"
+)
+
+// Markers written around sponsor reads in AdsMark mode
+const (
+	AdStartMarker = "[Ad Segment Start]"
+	AdEndMarker   = "[Ad Segment End]"
+)
+
+// maxAdTurns caps how many consecutive turns one sponsor read may span
+const maxAdTurns = 6
+
+var (
+	// adStartRegex matches the turn that opens a sponsor read
+	adStartRegex = regexp.MustCompile(`(?i)\b(?:(?:show|episode|segment|portion|hour) (?:today )?(?:is )?brought to you by|brought to you (?:today )?by|a word from our sponsors?|our (?:first |next |last |final )?sponsor (?:for (?:this|the) (?:show|hour|episode) )?is|thanks? (?:to|you to) our sponsors?)\b`)
+	// adBodyRegex matches phrases typical inside a sponsor read
+	adBodyRegex = regexp.MustCompile(`(?i)(?:\b(?:promo|offer|discount) code\b|\bfree trial\b|\bsign up\b|\b[a-z0-9-]+\.(?:com|net|org|io|co|tv)/twit\b|\bslash twit\b|\bour thanks to\b)`)
+	// adEndRegex matches phrases that hand back to the show
+	adEndRegex = regexp.MustCompile(`(?i)\b(?:(?:now )?back to (?:the show|our show|the news|you)|thank you,? [a-z]+,? for (?:supporting|sponsoring)|we thank [a-z0-9 ]+ for (?:their|its) support)\b`)
+)
+
+// ParseAdMode validates an ad handling mode name
+func ParseAdMode(s string) (AdMode, error) {
+	switch m := AdMode(strings.ToLower(s)); m {
+	case "":
+		return AdsKeep, nil
+	case AdsKeep, AdsStrip, AdsMark:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown ad mode '%s' (want keep, strip or mark)", s)
+}
+
+// AdSegment is a detected sponsor read, as a half-open range of line indexes
+type AdSegment struct {
+	Start, End int
+}
+
+// DetectAds finds sponsor reads in standardized Markdown. A read starts at a
+// turn containing a sponsor phrase and extends over following turns by the
+// same speaker while they look like ad copy, ending at a hand-back phrase.
+// turns are the episode's speaker turns, one per turn line of content; a
+// read by an unknown speaker covers its opening turn only.
+func DetectAds(content string, turns []Turn) []AdSegment {
+	lines := strings.Split(content, "\n")
+	speakers := make([]string, len(lines))
+	for i, seg := range Turns(content) {
+		if i < len(turns) {
+			speakers[seg.Line] = turns[i].Speaker
+		}
+	}
+	var segs []AdSegment
+	for i := 0; i < len(lines); i++ {
+		if !adStartRegex.MatchString(turnText(lines[i])) {
+			continue
+		}
+		speaker := speakers[i]
+		end := i + 1
+		if !adEndRegex.MatchString(turnText(lines[i])) {
+			for speaker != "" && end < len(lines) && end-i < maxAdTurns && speakers[end] == speaker {
+				text := turnText(lines[end])
+				if adEndRegex.MatchString(text) {
+					end++
+					break
+				}
+				if !adBodyRegex.MatchString(text) {
+					break
+				}
+				end++
+			}
+		}
+		segs = append(segs, AdSegment{Start: i, End: end})
+		i = end - 1
+	}
+	return segs
+}
+
+// ApplyAdMode strips or marks detected sponsor reads (see DetectAds)
+func ApplyAdMode(content string, turns []Turn, mode AdMode) string {
+	if mode == "" || mode == AdsKeep {
+		return content
+	}
+	segs := DetectAds(content, turns)
+	if len(segs) == 0 {
+		return content
+	}
+
+	lines := strings.Split(content, "\n")
+	var out []string
+	prev := 0
+	for _, seg := range segs {
+		out = append(out, lines[prev:seg.Start]...)
+		if mode == AdsMark {
+			out = append(out, AdStartMarker)
+			out = append(out, lines[seg.Start:seg.End]...)
+			out = append(out, AdEndMarker)
+		}
+		prev = seg.End
+	}
+	out = append(out, lines[prev:]...)
+	return strings.Join(out, "\n")
+}
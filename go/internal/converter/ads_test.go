@@ -0,0 +1,68 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+const adTranscript = `EP:1 Date:25-01-01 - Leo Laporte Welcome to the show
+EP:1 Date:25-01-01 - Leo Laporte Our show today is brought to you by Acme Widgets
+EP:1 Date:25-01-01 - Leo Laporte Go to acme.com/twit and use the offer code TWIT for a free trial
+EP:1 Date:25-01-01 - Leo Laporte Thank you, Acme, for supporting the show
+EP:1 Date:25-01-01 - Jeff Jarvis So about that Google news`
+
+// speakerTurns returns turns with the given speakers, one per turn line
+func speakerTurns(speakers ...string) []Turn {
+	turns := make([]Turn, len(speakers))
+	for i, s := range speakers {
+		turns[i].Speaker = s
+	}
+	return turns
+}
+
+var adTurns = speakerTurns("Leo Laporte", "Leo Laporte", "Leo Laporte", "Leo Laporte", "Jeff Jarvis")
+
+func TestDetectAds(t *testing.T) {
+	segs := DetectAds(adTranscript, adTurns)
+	if len(segs) != 1 || segs[0].Start != 1 || segs[0].End != 4 {
+		t.Fatalf("Expected one ad covering lines 1-3, got %+v", segs)
+	}
+
+	// Mentioning a sponsor phrase inside other speech still stops at the next speaker
+	segs = DetectAds("EP:1 Date:25-01-01 - Leo Laporte This hour is brought to you by Acme\nEP:1 Date:25-01-01 - Jeff Jarvis Right", speakerTurns("Leo Laporte", "Jeff Jarvis"))
+	if len(segs) != 1 || segs[0].End != 1 {
+		t.Errorf("Ad should not extend into another speaker's turn: %+v", segs)
+	}
+
+	// Speakers come from the parsed turns, not the first words of the line
+	segs = DetectAds("EP:1 Date:25-01-01 - Leo Our sponsor is Acme\nEP:1 Date:25-01-01 - Leo Sign up for the free trial", speakerTurns("Leo", "Leo"))
+	if len(segs) != 1 || segs[0].End != 2 {
+		t.Errorf("A one-word speaker's read should span their ad turns: %+v", segs)
+	}
+	segs = DetectAds("EP:1 Date:25-01-01 - Father Robert Ballecer Our sponsor is Acme\nEP:1 Date:25-01-01 - Father Robert Smith Sign up for the free trial", speakerTurns("Father Robert Ballecer", "Father Robert Smith"))
+	if len(segs) != 1 || segs[0].End != 1 {
+		t.Errorf("Ad should not extend into another three-word speaker's turn: %+v", segs)
+	}
+}
+
+func TestApplyAdMode(t *testing.T) {
+	stripped := ApplyAdMode(adTranscript, adTurns, AdsStrip)
+	if strings.Contains(stripped, "Acme") {
+		t.Errorf("Strip mode left ad text:\n%s", stripped)
+	}
+	if !strings.Contains(stripped, "Welcome to the show") || !strings.Contains(stripped, "Google news") {
+		t.Errorf("Strip mode removed show content:\n%s", stripped)
+	}
+
+	marked := ApplyAdMode(adTranscript, adTurns, AdsMark)
+	if !strings.Contains(marked, AdStartMarker+"\nEP:1 Date:25-01-01 - Leo Laporte Our show today") {
+		t.Errorf("Mark mode should open the ad block before the sponsor turn:\n%s", marked)
+	}
+	if !strings.Contains(marked, "supporting the show\n"+AdEndMarker) {
+		t.Errorf("Mark mode should close the ad block after the hand-back:\n%s", marked)
+	}
+
+	if ApplyAdMode(adTranscript, adTurns, AdsKeep) != adTranscript {
+		t.Error("Keep mode should not change content")
+	}
+}
@@ -0,0 +1,123 @@
+package converter
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// AltDir keeps the copies of episodes that lost to a better one (see
+// config.Precedence), relative to the data directory, one directory per
+// show. Being outside the transcript directories, they are left out of
+// conversion, the index and exports.
+const AltDir = "alt"
+
+// Kinds of copy of a site transcript page, besides the origins of
+// generated ones (see PageKind)
+const (
+	KindSite    = "site"
+	KindWayback = "wayback"
+)
+
+// altTimeFormat stamps alternatives' file names with when they were fetched
+const altTimeFormat = "20060102T150405Z"
+
+// altNameRegex matches an alternative's file name, SN_1000.site-20250211T090500Z.html
+var altNameRegex = regexp.MustCompile(`^([A-Z0-9]+)_(\d+)\.([a-z]+)-(\d{8}T\d{6}Z)(?:-\d+)?\.html$`)
+
+// Copy is one copy of an episode's transcript
+type Copy struct {
+	Path    string
+	Kind    string    // KindSite, KindWayback or an origin
+	Fetched time.Time // Zero if unknown
+	// Placeholder marks a site page published before its transcript,
+	// which is no copy at all (see IsPlaceholder)
+	Placeholder bool
+}
+
+// PageKind returns what kind of copy a transcript page is: the origin of a
+// generated page, KindWayback for a page downloaded from the Wayback
+// Machine, else KindSite
+func PageKind(html string) string {
+	if origin := PageOrigin(html); origin != "" {
+		return origin
+	}
+	if u, _, ok := PageSource(html); ok && strings.Contains(u, "://web.archive.org/") {
+		return KindWayback
+	}
+	return KindSite
+}
+
+// PageCopy describes a transcript page as a Copy
+func PageCopy(path, html string) Copy {
+	c := Copy{Path: path, Kind: PageKind(html), Fetched: fetchedAt(path, html)}
+	c.Placeholder = PageOrigin(html) == "" && IsPlaceholder(html)
+	return c
+}
+
+// Outranks reports whether copy a is better than b: its kind comes first
+// in config.Precedence or, for the same kind, it is a newer revision
+func (a Copy) Outranks(b Copy) bool {
+	if ra, rb := config.Rank(a.Kind), config.Rank(b.Kind); ra != rb {
+		return ra < rb
+	}
+	return a.Fetched.After(b.Fetched)
+}
+
+// AltPath is where a copy of an episode is kept as an alternative. A
+// number is added if another alternative was fetched at the same second.
+func AltPath(dataDir, prefix string, ep int, c Copy) string {
+	name := fmt.Sprintf("%s_%d.%s-%s", prefix, ep, c.Kind, c.Fetched.UTC().Format(altTimeFormat))
+	path := storage.Join(dataDir, AltDir, prefix, name+".html")
+	for n := 2; storage.Exists(path); n++ {
+		path = storage.Join(dataDir, AltDir, prefix, fmt.Sprintf("%s-%d.html", name, n))
+	}
+	return path
+}
+
+// Alternatives lists the alternatives kept for a show by episode number,
+// each episode's best first
+func Alternatives(dataDir, prefix string) (map[int][]Copy, error) {
+	matches, err := storage.Glob(storage.Join(dataDir, AltDir, prefix, prefix+"_*.html"))
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[int][]Copy)
+	for _, m := range matches {
+		parts := altNameRegex.FindStringSubmatch(storage.Base(m))
+		if parts == nil || parts[1] != prefix {
+			continue
+		}
+		ep, _ := strconv.Atoi(parts[2])
+		at, _ := time.Parse(altTimeFormat, parts[4])
+		out[ep] = append(out[ep], Copy{Path: m, Kind: parts[3], Fetched: at})
+	}
+	for _, copies := range out {
+		sort.SliceStable(copies, func(i, j int) bool { return copies[i].Outranks(copies[j]) })
+	}
+	return out, nil
+}
+
+// SetAside moves an episode's transcript page to its alternatives, unless
+// it is a placeholder without a transcript, which is just removed. Returns
+// the alternative's path, "" for a placeholder.
+func SetAside(dataDir, prefix string, ep int, path string) (string, error) {
+	html, err := storage.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	alt := ""
+	if c := PageCopy(path, string(html)); !c.Placeholder {
+		alt = AltPath(dataDir, prefix, ep, c)
+		if err := storage.WriteFile(alt, html); err != nil {
+			return "", err
+		}
+	}
+	return alt, storage.Remove(path)
+}
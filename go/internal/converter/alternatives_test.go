@@ -0,0 +1,78 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+)
+
+func TestPageKind(t *testing.T) {
+	page := `<h1 class="post-title">Security Now 1000</h1>`
+	at := time.Date(2025, 2, 11, 9, 5, 0, 0, time.UTC)
+	for html, want := range map[string]string{
+		page: KindSite,
+		StampSource(page, "https://twit.tv/posts/transcripts/sn-1000", at):                                  KindSite,
+		StampSource(page, "https://web.archive.org/web/2025/https://twit.tv/posts/transcripts/sn-1000", at): KindWayback,
+		CaptionsPage("Security Now 1000", at, OriginCaptions, []Cue{{Text: "Hello"}}):                       OriginCaptions,
+	} {
+		if got := PageKind(html); got != want {
+			t.Errorf("PageKind = %q, want %q for\n%s", got, want, html)
+		}
+	}
+}
+
+func TestOutranks(t *testing.T) {
+	defer func() { config.Precedence = config.SourceKinds }()
+	old, now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	site := Copy{Kind: KindSite, Fetched: old}
+	asr := Copy{Kind: OriginASR, Fetched: now}
+	if !site.Outranks(asr) || asr.Outranks(site) {
+		t.Error("The site's page should outrank speech recognition by default")
+	}
+	if !(Copy{Kind: KindSite, Fetched: now}).Outranks(site) {
+		t.Error("A newer revision should outrank an older one")
+	}
+	config.SetPrecedence("asr")
+	if !asr.Outranks(site) {
+		t.Error("Precedence should be configurable")
+	}
+}
+
+func TestAlternatives(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "twittest")
+	defer os.RemoveAll(tmpDir)
+	body := `<div class="body textual"><p>` + strings.Repeat("word ", 60) + `</p></div>`
+	page := filepath.Join(tmpDir, "SN_1000.html")
+	at := time.Date(2025, 2, 11, 9, 5, 0, 0, time.UTC)
+	os.WriteFile(page, []byte(StampSource(`<h1 class="post-title">Security Now 1000</h1>`+body, "https://twit.tv/sn-1000", at)), 0644)
+
+	alt, err := SetAside(tmpDir, "SN", 1000, page)
+	if err != nil || filepath.Base(alt) != "SN_1000.site-20250211T090500Z.html" {
+		t.Fatalf("SetAside = %s, %v", alt, err)
+	}
+	if _, err := os.Stat(page); !os.IsNotExist(err) {
+		t.Error("SetAside should remove the page")
+	}
+	os.WriteFile(page, []byte(StampSource(`<h1 class="post-title">Security Now 1000</h1>`+body, "https://twit.tv/sn-1000", at)), 0644)
+	alt2, _ := SetAside(tmpDir, "SN", 1000, page)
+	if filepath.Base(alt2) != "SN_1000.site-20250211T090500Z-2.html" {
+		t.Errorf("Second alternative from the same second = %s", alt2)
+	}
+	wayback := AltPath(tmpDir, "SN", 1000, Copy{Kind: KindWayback, Fetched: at.Add(time.Hour)})
+	os.WriteFile(wayback, []byte("x"), 0644)
+
+	alts, err := Alternatives(tmpDir, "SN")
+	if err != nil || len(alts[1000]) != 3 || alts[1000][2].Kind != KindWayback || !alts[1000][0].Fetched.Equal(at) {
+		t.Errorf("Alternatives = %+v, %v", alts, err)
+	}
+
+	// Placeholders are not kept
+	os.WriteFile(page, []byte(`<h1 class="post-title">Security Now 1001</h1><div class="body textual">Transcript coming soon</div>`), 0644)
+	if alt, err := SetAside(tmpDir, "SN", 1001, page); err != nil || alt != "" {
+		t.Errorf("SetAside of a placeholder = %q, %v", alt, err)
+	}
+}
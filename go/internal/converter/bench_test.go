@@ -0,0 +1,123 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// benchSpeakers and benchLines give generated transcripts the mix of
+// speaker formats, markup and sentence lengths seen in real pages
+var (
+	benchSpeakers = []string{"Leo Laporte", "Steve Gibson", "Paris Martineau", "Jeff Jarvis"}
+	benchLines    = []string{
+		"Hey, welcome back. It's time for the show, and we have a lot to talk about this week.",
+		"That's right. The patch came out on Tuesday &amp; it fixes <em>three</em> zero-days, one of which was already being exploited.",
+		"I'd point people to <a href=\"https://twit.tv/shows/security-now\">the show notes</a> for the full list of CVEs.",
+		"<strong>Let's take a quick break.</strong> This episode is brought to you by our sponsors.",
+		"So the question is whether the browser vendors will follow along, and I think they will, eventually, once the standard settles.",
+	}
+)
+
+// benchTranscript generates a transcript page with the given number of
+// speaker turns. 1,500 turns is about a three-hour episode (roughly 30,000
+// words, 250 KB), the size of the largest pages in the archive.
+func benchTranscript(ep, turns int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<html><head><title>Security Now %d Transcript</title><script>var analytics = {};</script></head><body>\n", ep)
+	fmt.Fprintf(&b, "<h1 class=\"post-title\">Security Now %d Transcript</h1>\n<p class=\"byline\">Feb 1st 2025</p>\n", ep)
+	b.WriteString("<div class=\"body textual\">\n<p><em>Please be advised this transcript is AI-generated and may not be word for word.</em></p>\n")
+	for i := 0; i < turns; i++ {
+		ts := fmt.Sprintf("%02d:%02d:%02d", i/360, i/6%60, i*10%60)
+		speaker := benchSpeakers[i%len(benchSpeakers)]
+		line := benchLines[i%len(benchLines)]
+		switch i % 3 {
+		case 0:
+			fmt.Fprintf(&b, "<p>%s - %s: %s</p>\n", ts, speaker, line)
+		case 1:
+			fmt.Fprintf(&b, "<p><strong>%s</strong> [%s]: %s<br>\n%s</p>\n", speaker, ts, line, benchLines[(i+1)%len(benchLines)])
+		default:
+			fmt.Fprintf(&b, "<p>%s (%s): %s</p>\n", speaker, ts, line)
+		}
+	}
+	b.WriteString("</div>\n</body></html>\n")
+	return b.String()
+}
+
+func BenchmarkHTMLToMarkdown(b *testing.B) {
+	html := benchTranscript(1000, 1500)
+	b.SetBytes(int64(len(html)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		HTMLToMarkdown(html, 1000, "25-02-01")
+	}
+}
+
+func BenchmarkParseTranscriptFile(b *testing.B) {
+	tmpDir, _ := os.MkdirTemp("", "twitbench")
+	defer os.RemoveAll(tmpDir)
+	path := filepath.Join(tmpDir, "SN_1000.html")
+	html := benchTranscript(1000, 1500)
+	os.WriteFile(path, []byte(html), 0644)
+
+	b.SetBytes(int64(len(html)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, _, _, err := ParseTranscriptFile(path); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkProcessPrefix(b *testing.B) {
+	tmpDir, _ := os.MkdirTemp("", "twitbench")
+	defer os.RemoveAll(tmpDir)
+	var total int64
+	for ep := 1; ep <= 50; ep++ {
+		html := benchTranscript(ep, 1500)
+		total += int64(len(html))
+		os.WriteFile(filepath.Join(tmpDir, fmt.Sprintf("SN_%d.html", ep)), []byte(html), 0644)
+	}
+	stdout := os.Stdout
+	os.Stdout, _ = os.Open(os.DevNull)
+	defer func() { os.Stdout = stdout }()
+
+	b.SetBytes(total)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := ProcessPrefixWithOptions("SN", tmpDir, tmpDir, Options{MaxWords: 200000}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// TestHTMLToMarkdownScaling guards against conversion becoming worse than
+// linear in the page size (e.g. a backtracking pattern or repeated
+// concatenation): an 8x larger page may take at most 20x as long.
+func TestHTMLToMarkdownScaling(t *testing.T) {
+	if testing.Short() {
+		t.Skip("timing test")
+	}
+	fastest := func(html string) time.Duration {
+		best := time.Duration(1<<63 - 1)
+		for i := 0; i < 5; i++ {
+			start := time.Now()
+			HTMLToMarkdown(html, 1, "25-02-01")
+			if d := time.Since(start); d < best {
+				best = d
+			}
+		}
+		return best
+	}
+	small := fastest(benchTranscript(1, 200))
+	large := fastest(benchTranscript(1, 1600))
+	if large > 20*small {
+		t.Errorf("Converting 8x the turns took %v vs %v (%.1fx)", large, small, float64(large)/float64(small))
+	}
+}
@@ -0,0 +1,234 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Caption formats, as returned by CaptionFormat
+const (
+	CaptionsSRT  = "srt"
+	CaptionsVTT  = "vtt"
+	CaptionsJSON = "json"
+	CaptionsHTML = "html"
+	CaptionsText = "text"
+)
+
+// Cue is one timed line of a caption file. Speaker is empty when the file
+// does not name one.
+type Cue struct {
+	Start   time.Duration
+	Timed   bool
+	Speaker string
+	Text    string
+}
+
+// captionParagraph is how long a run of cues without a speaker grows
+// before CaptionsPage starts a new paragraph
+const captionParagraph = time.Minute
+
+var (
+	// cueTimingRegex matches an SRT or WebVTT timing line and captures the
+	// start: "00:01:02,500 --> 00:01:05,000" or "01:02.500 --> 01:05.000"
+	cueTimingRegex = regexp.MustCompile(`^\s*((?:\d+:)?\d+:\d+)(?:[.,]\d+)?\s*-->`)
+	// voiceRegex matches a WebVTT voice span, "<v Leo Laporte>"
+	voiceRegex = regexp.MustCompile(`<v(?:\.[^\s>]*)?\s+([^>]+)>`)
+)
+
+// CaptionFormat works out a transcript file's format from the MIME type it
+// was linked with, falling back to the file extension of its URL
+func CaptionFormat(mimeType, url string) string {
+	switch strings.ToLower(strings.TrimSpace(strings.Split(mimeType, ";")[0])) {
+	case "application/srt", "application/x-subrip", "text/srt":
+		return CaptionsSRT
+	case "text/vtt":
+		return CaptionsVTT
+	case "application/json":
+		return CaptionsJSON
+	case "text/html":
+		return CaptionsHTML
+	case "text/plain":
+		return CaptionsText
+	}
+	u := strings.ToLower(url)
+	if i := strings.IndexAny(u, "?#"); i >= 0 {
+		u = u[:i]
+	}
+	switch {
+	case strings.HasSuffix(u, ".srt"):
+		return CaptionsSRT
+	case strings.HasSuffix(u, ".vtt"):
+		return CaptionsVTT
+	case strings.HasSuffix(u, ".json"):
+		return CaptionsJSON
+	case strings.HasSuffix(u, ".html"), strings.HasSuffix(u, ".htm"):
+		return CaptionsHTML
+	}
+	return CaptionsText
+}
+
+// ParseCaptions reads a transcript file in one of the caption formats:
+// SubRip, WebVTT (speakers from voice spans), Podcasting 2.0 JSON, or HTML
+// and plain text, which are untimed. Lines of the form "Speaker: text" or
+// "[SPEAKER_01]: text" name their speaker when the format does not.
+func ParseCaptions(data []byte, format string) ([]Cue, error) {
+	var cues []Cue
+	var err error
+	switch format {
+	case CaptionsSRT, CaptionsVTT:
+		cues = parseTimedCues(string(data))
+	case CaptionsJSON:
+		cues, err = parseJSONCaptions(data)
+	case CaptionsHTML:
+		for _, p := range paragraphRegex.FindAllString(string(data), -1) {
+			cues = append(cues, Cue{Text: p})
+		}
+	case CaptionsText:
+		for _, line := range strings.Split(string(data), "\n") {
+			cues = append(cues, Cue{Text: line})
+		}
+	default:
+		return nil, fmt.Errorf("unknown caption format '%s'", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var out []Cue
+	for _, c := range cues {
+		if c.Speaker == "" {
+			if m := voiceRegex.FindStringSubmatch(c.Text); m != nil {
+				c.Speaker = m[1]
+			}
+		}
+		c.Text = strings.Join(strings.Fields(html.UnescapeString(anyTagRegex.ReplaceAllString(c.Text, " "))), " ")
+		if c.Speaker == "" {
+			if m := speakerNameRegex.FindStringSubmatch(c.Text); m != nil {
+				c.Speaker, c.Text = m[1], m[2]
+			} else if m := diarizedLineRegex.FindStringSubmatch(c.Text); m != nil {
+				c.Speaker, c.Text = m[1], m[2]
+			}
+		}
+		c.Speaker = strings.TrimSpace(html.UnescapeString(c.Speaker))
+		if c.Text != "" {
+			out = append(out, c)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no captions found")
+	}
+	return out, nil
+}
+
+// parseTimedCues reads the blocks of an SRT or WebVTT file. Blocks without
+// a timing line (the WEBVTT header, NOTE and STYLE blocks) are skipped.
+// Rolling captions, as generated for YouTube videos, repeat the last line
+// of the previous cue at the start of the next; the repeat is dropped.
+func parseTimedCues(s string) []Cue {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	var cues []Cue
+	prev := ""
+	for _, block := range strings.Split(s, "\n\n") {
+		lines := strings.Split(strings.TrimSpace(block), "\n")
+		for i, line := range lines {
+			m := cueTimingRegex.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			start, ok := ParseTimecode(m[1])
+			if !ok {
+				break
+			}
+			text := lines[i+1:]
+			if len(text) > 0 && prev != "" && captionLine(text[0]) == prev {
+				text = text[1:]
+			}
+			if len(text) > 0 {
+				prev = captionLine(text[len(text)-1])
+			}
+			cues = append(cues, Cue{Start: start, Timed: true, Text: strings.Join(text, " ")})
+			break
+		}
+	}
+	return cues
+}
+
+// captionLine is the text of a caption line without its markup, for
+// comparing lines
+func captionLine(s string) string {
+	return strings.Join(strings.Fields(anyTagRegex.ReplaceAllString(s, " ")), " ")
+}
+
+// jsonCaptions is the Podcasting 2.0 JSON transcript format
+type jsonCaptions struct {
+	Segments []struct {
+		Speaker   string  `json:"speaker"`
+		StartTime float64 `json:"startTime"`
+		Body      string  `json:"body"`
+	} `json:"segments"`
+}
+
+func parseJSONCaptions(data []byte) ([]Cue, error) {
+	var doc jsonCaptions
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON transcript: %w", err)
+	}
+	var cues []Cue
+	for _, s := range doc.Segments {
+		cues = append(cues, Cue{
+			Start:   time.Duration(s.StartTime * float64(time.Second)),
+			Timed:   true,
+			Speaker: s.Speaker,
+			Text:    s.Body,
+		})
+	}
+	return cues, nil
+}
+
+// CaptionsPage renders cues as a transcript page in the site's markup, so
+// caption files go through the same conversion as scraped pages. Runs of
+// cues by one speaker become a paragraph headed like the site's
+// ("0:00:00 - Speaker"); timed runs without a speaker are split every
+// minute and untimed ones keep their lines. origin is recorded on the page
+// for PageOrigin.
+func CaptionsPage(title string, published time.Time, origin string, cues []Cue) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<meta name=\"twit-archiver-origin\" content=\"%s\">\n", origin)
+	fmt.Fprintf(&b, "<h1 class=\"post-title\">%s</h1>\n", html.EscapeString(title))
+	if !published.IsZero() {
+		fmt.Fprintf(&b, "<p class=\"byline\">%s</p>\n", published.Format("Jan 02 2006"))
+	}
+	b.WriteString(`<div class="body textual">`)
+
+	for i := 0; i < len(cues); {
+		first := cues[i]
+		var text []string
+		for ; i < len(cues); i++ {
+			c := cues[i]
+			if c.Speaker != first.Speaker {
+				break
+			}
+			if c.Speaker == "" && len(text) > 0 && (!first.Timed || c.Start-first.Start >= captionParagraph) {
+				break
+			}
+			text = append(text, html.EscapeString(c.Text))
+		}
+		body := strings.Join(text, " ")
+		switch {
+		case first.Timed && first.Speaker != "":
+			fmt.Fprintf(&b, "<p>%s - %s<br>\n%s</p>\n", FormatTimecode(first.Start), html.EscapeString(first.Speaker), body)
+		case first.Timed:
+			fmt.Fprintf(&b, "<p>(%s): %s</p>\n", FormatTimecode(first.Start), body)
+		case first.Speaker != "":
+			fmt.Fprintf(&b, "<p>%s: %s</p>\n", html.EscapeString(first.Speaker), body)
+		default:
+			fmt.Fprintf(&b, "<p>%s</p>\n", body)
+		}
+	}
+	b.WriteString("</div>\n")
+	return b.String()
+}
@@ -0,0 +1,91 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCaptionFormat(t *testing.T) {
+	for _, tc := range []struct{ mime, url, want string }{
+		{"text/vtt", "https://example.com/t", CaptionsVTT},
+		{"application/x-subrip", "", CaptionsSRT},
+		{"application/json; charset=utf-8", "", CaptionsJSON},
+		{"", "https://example.com/ep1.srt?x=1", CaptionsSRT},
+		{"", "https://example.com/ep1.html", CaptionsHTML},
+		{"", "https://example.com/ep1", CaptionsText},
+	} {
+		if got := CaptionFormat(tc.mime, tc.url); got != tc.want {
+			t.Errorf("CaptionFormat(%q, %q) = %q, want %q", tc.mime, tc.url, got, tc.want)
+		}
+	}
+}
+
+func TestParseCaptions(t *testing.T) {
+	for _, tc := range []struct {
+		name, format, data string
+		want               []Cue
+	}{
+		{"srt", CaptionsSRT, "1\r\n00:00:01,000 --> 00:00:04,000\r\nLeo Laporte: Hello\r\nthere\r\n\r\n2\r\n00:01:05,500 --> 00:01:07,000\r\nwelcome &amp; all\r\n",
+			[]Cue{{Start: time.Second, Timed: true, Speaker: "Leo Laporte", Text: "Hello there"}, {Start: 65 * time.Second, Timed: true, Text: "welcome & all"}}},
+		{"vtt", CaptionsVTT, "WEBVTT\n\nNOTE made by hand\n\nintro\n00:02.000 --> 00:04.000\n<v Steve Gibson>Yes, <i>indeed</i>.</v>\n",
+			[]Cue{{Start: 2 * time.Second, Timed: true, Speaker: "Steve Gibson", Text: "Yes, indeed ."}}},
+		{"rolling", CaptionsVTT, "WEBVTT\nKind: captions\n\n00:00:01.000 --> 00:00:03.000\nwelcome<00:00:02.000><c> back</c>\n\n00:00:03.000 --> 00:00:05.000\nwelcome back\nto the show\n",
+			[]Cue{{Start: time.Second, Timed: true, Text: "welcome back"}, {Start: 3 * time.Second, Timed: true, Text: "to the show"}}},
+		{"json", CaptionsJSON, `{"version":"1.0.0","segments":[{"speaker":"Leo","startTime":12.5,"endTime":14,"body":"Hi"}]}`,
+			[]Cue{{Start: 12500 * time.Millisecond, Timed: true, Speaker: "Leo", Text: "Hi"}}},
+		{"diarized", CaptionsSRT, "1\n00:00:01,000 --> 00:00:02,000\n[SPEAKER_01]: Hello\n",
+			[]Cue{{Start: time.Second, Timed: true, Speaker: "SPEAKER_01", Text: "Hello"}}},
+		{"text", CaptionsText, "Leo: Hi\n\nthanks\n",
+			[]Cue{{Speaker: "Leo", Text: "Hi"}, {Text: "thanks"}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseCaptions([]byte(tc.data), tc.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("ParseCaptions = %+v, want %+v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("Cue %d = %+v, want %+v", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+
+	if _, err := ParseCaptions([]byte("WEBVTT\n"), CaptionsVTT); err == nil {
+		t.Error("Expected an error for a file without cues")
+	}
+	if _, err := ParseCaptions([]byte("{"), CaptionsJSON); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+}
+
+func TestCaptionsPage(t *testing.T) {
+	cues := []Cue{
+		{Start: 0, Timed: true, Speaker: "Leo Laporte", Text: "It's time for"},
+		{Start: 2 * time.Second, Timed: true, Speaker: "Leo Laporte", Text: "Security Now."},
+		{Start: 31 * time.Second, Timed: true, Speaker: "Steve Gibson", Text: "Great to be here & more."},
+	}
+	page := CaptionsPage("Security Now 1000", time.Date(2024, 11, 19, 0, 0, 0, 0, time.UTC), OriginFeed, cues)
+	if PageTitle(page) != "Security Now 1000" {
+		t.Errorf("Title = %q", PageTitle(page))
+	}
+	if PageOrigin(page) != OriginFeed {
+		t.Errorf("Origin = %q", PageOrigin(page))
+	}
+	if d, ok := PublishedDate(page); !ok || d.Format("2006-01-02") != "2024-11-19" {
+		t.Errorf("Published = %v, %v", d, ok)
+	}
+	md := HTMLToMarkdown(page, 1000, "2024-11-19")
+	for _, want := range []string{
+		"TS:00:00:00 - Leo Laporte It's time for Security Now.",
+		"TS:00:00:31 - Steve Gibson Great to be here & more.",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown missing %q:\n%s", want, md)
+		}
+	}
+}
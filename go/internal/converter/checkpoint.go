@@ -0,0 +1,183 @@
+package converter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// chunkManifest records the chunk files a run writes for a show, so that a
+// run that dies part way can be cleaned up by the next one. Chunks are first
+// written to a ".tmp" file and renamed once complete, then recorded here.
+type chunkManifest struct {
+	Prefix    string    `json:"prefix"`
+	Started   time.Time `json:"started"`
+	Completed bool      `json:"completed"`
+	// Converter is the ConverterVersion that wrote the chunks; zero for
+	// runs from before it was recorded
+	Converter int `json:"converter,omitempty"`
+	// Chunks lists the files (by name) written by this run so far
+	Chunks []string `json:"chunks"`
+	// Previous lists the files of the last completed run, while a run is in
+	// progress
+	Previous []string `json:"previous,omitempty"`
+
+	base string
+}
+
+// chunkManifestPath returns where a show's chunk manifest is kept
+func chunkManifestPath(base, prefix string) string {
+	return storage.Join(base, prefix+"_chunks.json")
+}
+
+// beginChunks starts a run in a chunk directory. If the last run did not
+// complete, its temp files and the chunks it added are removed, so the new
+// run cannot leave overlapping chunks behind.
+func beginChunks(base, prefix string) (*chunkManifest, error) {
+	m := &chunkManifest{Prefix: prefix, Converter: ConverterVersion, base: base}
+	if data, err := storage.ReadFile(chunkManifestPath(base, prefix)); err == nil {
+		var last chunkManifest
+		if err := json.Unmarshal(data, &last); err != nil {
+			return nil, fmt.Errorf("invalid chunk manifest: %w", err)
+		}
+		if last.Completed {
+			m.Previous = last.Chunks
+		} else {
+			fmt.Printf("Cleaning up an interrupted run for %s (started %s)\n", prefix, last.Started.Format(time.RFC3339))
+			kept := make(map[string]bool)
+			for _, name := range last.Previous {
+				kept[name] = true
+			}
+			for _, name := range last.Chunks {
+				if !kept[name] {
+					removeChunk(storage.Join(base, name))
+				}
+			}
+			m.Previous = last.Previous
+		}
+	}
+	leftovers, err := storage.Glob(storage.Join(base, prefix+"_*.md.tmp"))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range leftovers {
+		removeChunk(f)
+	}
+	m.Started = time.Now().UTC()
+	return m, m.save()
+}
+
+// record adds a completed chunk file to the manifest
+func (m *chunkManifest) record(filename string) {
+	m.Chunks = append(m.Chunks, storage.Base(filename))
+	if err := m.save(); err != nil {
+		fmt.Printf("Warning: could not update chunk manifest: %v\n", err)
+	}
+}
+
+// finish marks the run complete. With removeStale, chunks of the previous
+// run that this run did not rewrite (their episode ranges changed) are
+// removed and returned; otherwise, as after a filtered run, they are kept.
+func (m *chunkManifest) finish(removeStale bool) ([]string, error) {
+	var removed []string
+	written := make(map[string]bool)
+	for _, name := range m.Chunks {
+		written[name] = true
+	}
+	for _, name := range m.Previous {
+		if written[name] {
+			continue
+		}
+		if removeStale {
+			removeChunk(storage.Join(m.base, name))
+			removed = append(removed, name)
+		} else {
+			m.Chunks = append(m.Chunks, name)
+		}
+	}
+	m.Previous = nil
+	m.Completed = true
+	return removed, m.save()
+}
+
+func (m *chunkManifest) save() error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return storage.WriteFile(chunkManifestPath(m.base, m.Prefix), data)
+}
+
+// ConverterVersion identifies the conversion logic. Raise it with every
+// change that alters the Markdown written for the same transcripts, so
+// that Outdated picks up the shows converted before the change.
+const ConverterVersion = 3
+
+// lastRun reads the chunk manifest of a show's (or combined set's) last
+// run, nil if it has none
+func lastRun(prefix, outputBase string) (*chunkManifest, error) {
+	data, err := storage.ReadFile(chunkManifestPath(config.ActiveLayout.ChunkDir(outputBase, prefix), prefix))
+	if errors.Is(err, storage.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var last chunkManifest
+	if err := json.Unmarshal(data, &last); err != nil {
+		return &chunkManifest{}, nil
+	}
+	return &last, nil
+}
+
+// Outdated reports whether a show's chunks were not written by a complete
+// run of the current ConverterVersion: they are missing, the last run did
+// not complete, or an older converter wrote them. The version of the last
+// run is returned, 0 if unknown.
+func Outdated(prefix, outputBase string) (bool, int, error) {
+	last, err := lastRun(prefix, outputBase)
+	if err != nil || last == nil {
+		return err == nil, 0, err
+	}
+	return !last.current(), last.Converter, nil
+}
+
+// current reports whether a run completed with the current converter
+func (m *chunkManifest) current() bool {
+	return m.Completed && m.Converter >= ConverterVersion
+}
+
+// NeedsProcessing reports whether a show's chunks are missing, outdated
+// (see Outdated) or older than its transcripts: a transcript file was
+// modified after the last run started. Modification times are not
+// available for remote storage, so there only the other cases count.
+func NeedsProcessing(prefix, dataDir, outputBase string) (bool, error) {
+	last, err := lastRun(prefix, outputBase)
+	if err != nil || last == nil || !last.current() {
+		return err == nil, err
+	}
+	if storage.IsRemote(dataDir) {
+		return false, nil
+	}
+	files, err := config.ActiveLayout.RawFiles(dataDir, prefix)
+	if err != nil {
+		return false, err
+	}
+	for _, f := range files {
+		if info, err := os.Stat(f); err == nil && info.ModTime().After(last.Started) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func removeChunk(p string) {
+	if err := storage.Remove(p); err != nil && !errors.Is(err, storage.ErrNotExist) {
+		fmt.Printf("Warning: could not remove %s: %v\n", p, err)
+	}
+}
@@ -0,0 +1,146 @@
+package converter
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestEpisodes(t *testing.T, dir string) {
+	t.Helper()
+	for _, ep := range []string{"1", "2"} {
+		page := `<h1 class="post-title">Ep ` + ep + `</h1><p class="byline">Feb 1st 2025</p><div class="body textual">Content ` + ep + `</div>`
+		if err := os.WriteFile(filepath.Join(dir, "IM_"+ep+".html"), []byte(page), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readChunkManifest(t *testing.T, dir string) chunkManifest {
+	t.Helper()
+	data, err := os.ReadFile(chunkManifestPath(dir, "IM"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m chunkManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func exists(dir, name string) bool {
+	_, err := os.Stat(filepath.Join(dir, name))
+	return err == nil
+}
+
+func TestChunkCrashRecovery(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "checkpointtest")
+	defer os.RemoveAll(tmpDir)
+	writeTestEpisodes(t, tmpDir)
+
+	// An interrupted run that had added IM_Transcripts_2-9.md and was
+	// writing another chunk, after a completed run that wrote 1-1
+	for _, name := range []string{"IM_Transcripts_1-1.md", "IM_Transcripts_2-9.md", "IM_Transcripts_10-12.md.tmp"} {
+		os.WriteFile(filepath.Join(tmpDir, name), []byte("old"), 0644)
+	}
+	crashed, _ := json.Marshal(chunkManifest{Prefix: "IM", Chunks: []string{"IM_Transcripts_2-9.md"}, Previous: []string{"IM_Transcripts_1-1.md"}})
+	os.WriteFile(chunkManifestPath(tmpDir, "IM"), crashed, 0644)
+
+	if err := ProcessPrefixWithOptions("IM", tmpDir, tmpDir, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"IM_Transcripts_2-9.md", "IM_Transcripts_10-12.md.tmp", "IM_Transcripts_1-2.md.tmp"} {
+		if exists(tmpDir, name) {
+			t.Errorf("%s should have been removed", name)
+		}
+	}
+	if !exists(tmpDir, "IM_Transcripts_1-2.md") {
+		t.Error("Expected the new chunk")
+	}
+	// 1-1 belonged to the last completed run and is stale now
+	if exists(tmpDir, "IM_Transcripts_1-1.md") {
+		t.Error("Stale chunk IM_Transcripts_1-1.md should have been removed")
+	}
+	m := readChunkManifest(t, tmpDir)
+	if !m.Completed || len(m.Chunks) != 1 || m.Chunks[0] != "IM_Transcripts_1-2.md" || len(m.Previous) != 0 {
+		t.Errorf("Unexpected manifest: %+v", m)
+	}
+}
+
+func TestChunkManifestFilteredRun(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "checkpointtest")
+	defer os.RemoveAll(tmpDir)
+	writeTestEpisodes(t, tmpDir)
+
+	os.WriteFile(filepath.Join(tmpDir, "IM_Transcripts_3-4.md"), []byte("old"), 0644)
+	done, _ := json.Marshal(chunkManifest{Prefix: "IM", Completed: true, Chunks: []string{"IM_Transcripts_3-4.md"}})
+	os.WriteFile(chunkManifestPath(tmpDir, "IM"), done, 0644)
+
+	if err := ProcessPrefixWithOptions("IM", tmpDir, tmpDir, Options{Filter: Filter{FromEp: 1, ToEp: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if !exists(tmpDir, "IM_Transcripts_3-4.md") {
+		t.Error("A filtered run should keep chunks outside its range")
+	}
+	m := readChunkManifest(t, tmpDir)
+	if !m.Completed || len(m.Chunks) != 2 {
+		t.Errorf("Expected both chunks in the manifest: %+v", m)
+	}
+}
+
+func TestNeedsProcessing(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTestEpisodes(t, tmpDir)
+
+	if need, err := NeedsProcessing("IM", tmpDir, tmpDir); err != nil || !need {
+		t.Fatalf("A show never processed needs processing: %v %v", need, err)
+	}
+	if err := ProcessPrefixWithOptions("IM", tmpDir, tmpDir, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if need, err := NeedsProcessing("IM", tmpDir, tmpDir); err != nil || need {
+		t.Fatalf("Chunks are up to date: %v %v", need, err)
+	}
+
+	// A transcript downloaded after the last run
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(tmpDir, "IM_2.html"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if need, _ := NeedsProcessing("IM", tmpDir, tmpDir); !need {
+		t.Error("A changed transcript should need processing")
+	}
+}
+
+func TestOutdated(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTestEpisodes(t, tmpDir)
+
+	if outdated, _, err := Outdated("IM", tmpDir); err != nil || !outdated {
+		t.Fatalf("A show never processed is outdated: %v %v", outdated, err)
+	}
+	if err := ProcessPrefixWithOptions("IM", tmpDir, tmpDir, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if outdated, version, err := Outdated("IM", tmpDir); err != nil || outdated || version != ConverterVersion {
+		t.Fatalf("Fresh chunks reported outdated: %v %d %v", outdated, version, err)
+	}
+
+	// Chunks written by an older converter
+	path := chunkManifestPath(tmpDir, "IM")
+	data, _ := os.ReadFile(path)
+	var m chunkManifest
+	json.Unmarshal(data, &m)
+	m.Converter = ConverterVersion - 1
+	data, _ = json.Marshal(m)
+	os.WriteFile(path, data, 0644)
+	if outdated, version, _ := Outdated("IM", tmpDir); !outdated || version != ConverterVersion-1 {
+		t.Errorf("Chunks of an older converter not outdated: %v %d", outdated, version)
+	}
+	if need, _ := NeedsProcessing("IM", tmpDir, tmpDir); !need {
+		t.Error("Outdated chunks should need processing")
+	}
+}
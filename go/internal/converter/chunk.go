@@ -0,0 +1,678 @@
+package converter
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/errs"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// ChunkMode selects where chunks may be split
+type ChunkMode string
+
+const (
+	// ChunkByEpisode only splits between episodes (an oversized episode is
+	// written whole)
+	ChunkByEpisode ChunkMode = "episode"
+	// ChunkByTurn may split inside an episode, but only between speaker turns
+	ChunkByTurn ChunkMode = "turn"
+	// ChunkByTopic may split inside an episode at headings and ad-break
+	// markers, falling back to speaker turns for oversized segments
+	ChunkByTopic ChunkMode = "topic"
+)
+
+// ChunkGroup selects calendar periods that each get one chunk file
+type ChunkGroup string
+
+const (
+	// GroupBySize fills chunks up to the size limits (the default)
+	GroupBySize ChunkGroup = ""
+	// GroupByYear writes each year's episodes to one chunk
+	GroupByYear ChunkGroup = "year"
+	// GroupByDecade writes each decade's episodes to one chunk
+	GroupByDecade ChunkGroup = "decade"
+	// GroupByMonth writes each month's episodes to one chunk
+	GroupByMonth ChunkGroup = "month"
+)
+
+// ParseChunkGroup validates a --chunk-by value
+func ParseChunkGroup(s string) (ChunkGroup, error) {
+	switch g := ChunkGroup(strings.ToLower(s)); g {
+	case "size":
+		return GroupBySize, nil
+	case GroupBySize, GroupByYear, GroupByDecade, GroupByMonth:
+		return g, nil
+	}
+	return "", fmt.Errorf("unknown chunk grouping '%s' (want size, month, year or decade)", s)
+}
+
+// label names the period an episode belongs to ("2008-03", "2008",
+// "2000s"), or "undated" if its date (for months) or year is unknown
+func (g ChunkGroup) label(ep Episode) string {
+	if g == GroupByMonth {
+		if ep.Date.IsZero() {
+			return "undated"
+		}
+		return ep.Date.Format("2006-01")
+	}
+	year := ep.Year
+	if !ep.Date.IsZero() {
+		year = ep.Date.Year()
+	}
+	switch {
+	case year <= 0:
+		return "undated"
+	case g == GroupByDecade:
+		return fmt.Sprintf("%ds", year/10*10)
+	}
+	return fmt.Sprint(year)
+}
+
+// Options controls how ProcessPrefixWithOptions builds chunk files
+type Options struct {
+	ByYear bool
+	Mode   ChunkMode
+	// ChunkBy writes one chunk per year or decade, however large, instead
+	// of filling chunks up to the size limits
+	ChunkBy ChunkGroup
+	// ChunkName is a ParseChunkName template for chunk file names; empty
+	// keeps the original names
+	ChunkName string
+	// Overlap is the number of speaker turns repeated at the start of a chunk
+	// that continues an episode split by the previous chunk
+	Overlap int
+	// MaxWords and MaxBytes override the package limits when non-zero
+	MaxWords int
+	MaxBytes int
+	// Ads selects whether sponsor reads are kept, stripped or marked
+	Ads AdMode
+	// Filter limits processing to an episode or date range
+	Filter Filter
+	// Timestamps selects whether timecodes are kept, normalized or stripped
+	Timestamps TimestampMode
+	// ShowNotes appends each episode's show notes and links after its transcript
+	ShowNotes bool
+	// Templates renders episodes and chunk files (nil for the defaults)
+	Templates *Templates
+	// PerEpisode also writes each episode to its own Markdown file in the
+	// layout's Markdown directory, with YAML front matter if FrontMatter is set
+	PerEpisode  bool
+	FrontMatter bool
+	// TOC starts each chunk file with a table of contents linking to an
+	// anchor before each episode
+	TOC bool
+	// NonEnglish selects whether episodes detected as another language are
+	// kept, excluded or chunked separately per language
+	NonEnglish LanguageMode
+	// Tags lists index tags by index key ("SN_950") for the front matter
+	Tags map[string][]string
+	// Summaries lists generated abstracts by index key for the front matter
+	Summaries map[string]*Summary
+	// Report collects the files that could not be processed (nil to ignore)
+	Report *errs.Report
+	// Quarantine moves files that are not transcripts at all (errs.ErrParse)
+	// out of the raw files, into QuarantineDir
+	Quarantine bool
+	// Stop, if set, is checked before each file and ends the run once it
+	// returns true: at --max-duration or on an interrupt. The chunks written
+	// so far are kept, but the run is not marked complete, so Outdated
+	// reports the show and the next run converts it again.
+	Stop func() bool
+	// Progress, if set, is told about each file before it is processed
+	Progress func(path string)
+}
+
+// ParseChunkMode validates a chunk mode name
+func ParseChunkMode(s string) (ChunkMode, error) {
+	switch m := ChunkMode(strings.ToLower(s)); m {
+	case "", ChunkByEpisode:
+		return ChunkByEpisode, nil
+	case ChunkByTurn, ChunkByTopic:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown chunk mode '%s' (want episode, turn or topic)", s)
+}
+
+// topicMarkerRegex matches turns that typically open a new segment of a show
+var topicMarkerRegex = regexp.MustCompile(`(?i)^#|^\[Ad Segment Start\]|\b(?:let'?s take a (?:quick )?break|we'?ll be right back|brought to you by|a word from our sponsors?|(?:our|this) (?:show|episode) (?:today )?is brought)\b`)
+
+// ProcessPrefixWithOptions combines all transcripts for a prefix into
+// Markdown chunk files according to opts
+func ProcessPrefixWithOptions(prefix, dataDir, outputBase string, opts Options) error {
+	_, err := ProcessPrefixResult(prefix, dataDir, outputBase, opts)
+	return err
+}
+
+// ProcessPrefixResult is ProcessPrefixWithOptions, returning what the run
+// did. Files that fail are listed in the result (and opts.Report) rather
+// than returned as errors; the error is for failures of the whole run.
+func ProcessPrefixResult(prefix, dataDir, outputBase string, opts Options) (*Result, error) {
+	files, err := config.ActiveLayout.RawFiles(dataDir, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		fmt.Printf("No files found for prefix: %s\n", prefix)
+		return &Result{Prefix: prefix}, nil
+	}
+
+	// Sort by episode number
+	sort.Slice(files, func(i, j int) bool {
+		return GetEpNum(files[i]) < GetEpNum(files[j])
+	})
+	return processFiles(prefix, files, dataDir, outputBase, opts)
+}
+
+// processFiles chunks transcript files, in the given order, under prefix
+func processFiles(prefix string, files []string, dataDir, outputBase string, opts Options) (*Result, error) {
+	if opts.Mode == "" {
+		opts.Mode = ChunkByEpisode
+	}
+	if opts.MaxWords <= 0 {
+		opts.MaxWords = MaxWords
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = MaxBytes
+	}
+	if opts.Templates == nil {
+		opts.Templates = DefaultTemplates()
+	}
+
+	fmt.Printf("Processing %d files for %s (By Year: %v, Split: %s)...\n", len(files), prefix, opts.ByYear, opts.Mode)
+
+	res := &Result{Prefix: prefix, Files: len(files)}
+	base := config.ActiveLayout.ChunkDir(outputBase, prefix)
+	manifest, err := beginChunks(base, prefix)
+	if err != nil {
+		return nil, err
+	}
+	links := newChunkLinks(prefix, outputBase)
+	primary := &chunker{prefix: prefix, base: base, opts: opts, year: -1, written: make(map[string]bool), manifest: manifest, links: links, result: res}
+	// Chunkers for other languages, with LanguageSeparate
+	byLanguage := make(map[string]*chunker)
+	var languages []string
+	for _, fpath := range files {
+		if opts.Stop != nil && opts.Stop() {
+			fmt.Printf("Stopping %s before %s\n", prefix, fpath)
+			res.Stopped = true
+			break
+		}
+		if opts.Progress != nil {
+			opts.Progress(fpath)
+		}
+		epNum := GetEpNum(fpath)
+		if epNum > 0 && !opts.Filter.MatchEpisode(epNum) {
+			res.skip(fpath, "outside the episode range")
+			continue
+		}
+		ep, err := LoadEpisode(fpath)
+		if err != nil {
+			fmt.Printf("Error processing %s: %v. Skipping.\n", fpath, err)
+			if opts.Quarantine && errors.Is(err, errs.ErrParse) {
+				if dest, qerr := Quarantine(dataDir, fpath, err); qerr != nil {
+					fmt.Printf("Warning: could not quarantine %s: %v\n", fpath, qerr)
+				} else {
+					fmt.Printf("Moved %s to %s\n", fpath, dest)
+					res.Quarantined = append(res.Quarantined, dest)
+				}
+			}
+			res.fail(fpath, err)
+			opts.Report.Add(fpath, err)
+			if opts.Report.ShouldStop() {
+				break
+			}
+			continue
+		}
+		if !opts.Filter.Match(ep) {
+			res.skip(fpath, "outside the episode or date range")
+			continue
+		}
+		epYear := ep.Year
+
+		c := primary
+		if !isEnglish(ep.Language) {
+			switch opts.NonEnglish {
+			case LanguageExclude:
+				c = nil
+			case LanguageSeparate:
+				if c = byLanguage[ep.Language]; c == nil {
+					c = &chunker{prefix: prefix, lang: ep.Language, base: base, opts: opts, year: -1, written: make(map[string]bool), manifest: manifest, links: links, result: res}
+					byLanguage[ep.Language] = c
+					languages = append(languages, ep.Language)
+				}
+			}
+		}
+
+		content := ApplyAdMode(ep.Content, ep.Turns, opts.Ads)
+		content = ApplyTimestampMode(content, opts.Timestamps)
+		if opts.ShowNotes && !ep.Notes.Empty() {
+			content += "\n\n" + ep.Notes.Markdown()
+		}
+
+		if c != nil && opts.ByYear && opts.ChunkBy == GroupBySize && c.year != -1 && epYear != c.year {
+			c.flush()
+		}
+
+		epText, err := opts.Templates.RenderEpisode(ep, content, false)
+		if err != nil {
+			fmt.Printf("Error rendering %s: %v. Skipping.\n", fpath, err)
+			res.fail(fpath, err)
+			opts.Report.Add(fpath, err)
+			if opts.Report.ShouldStop() {
+				break
+			}
+			continue
+		}
+		epWords := len(strings.Fields(content))
+		if opts.PerEpisode {
+			writeEpisodeFile(ep, epText, epWords, outputBase, opts)
+		}
+		if c == nil {
+			// Per-episode files are still written, with the language in
+			// their front matter
+			fmt.Printf("Leaving %s out of the chunks: detected language %s\n", fpath, ep.Language)
+			res.skip(fpath, "left out of the chunks: detected language "+ep.Language)
+			continue
+		}
+		res.Processed = append(res.Processed, fpath)
+		if opts.ChunkBy != GroupBySize {
+			group := opts.ChunkBy.label(ep)
+			if group != c.group {
+				c.flush()
+				c.group = group
+			}
+			c.add(epText, epWords, ep, false)
+			continue
+		}
+		if c.fits(epWords, len(epText)) || opts.Mode == ChunkByEpisode {
+			if !c.fits(epWords, len(epText)) {
+				c.flush()
+			}
+			c.add(epText, epWords, ep, false)
+			continue
+		}
+
+		c.addSplit(ep, content)
+	}
+	primary.flush()
+	for _, lang := range languages {
+		byLanguage[lang].flush()
+	}
+	if res.Stopped {
+		// The manifest stays incomplete, for the next run to clean up
+		if err := links.save(false); err != nil {
+			fmt.Printf("Warning: could not update %s: %v\n", LinkIndexFile, err)
+		}
+		return res, nil
+	}
+
+	// Chunks left over from a previous run are only stale if this run
+	// covered the whole show
+	complete := opts.Filter == Filter{} && !opts.Report.ShouldStop()
+	res.Complete = complete
+	if res.Removed, err = manifest.finish(complete); err != nil {
+		return res, err
+	}
+	if err := links.save(complete); err != nil {
+		fmt.Printf("Warning: could not update %s: %v\n", LinkIndexFile, err)
+	}
+	return res, nil
+}
+
+// chunker accumulates episode text and writes chunk files
+type chunker struct {
+	prefix, base string
+	lang         string // Language of the chunk files, "" for the main ones
+	opts         Options
+
+	body           *os.File      // Episode text of the open chunk (a temp file)
+	buf            *bufio.Writer // Buffers writes to body; nil when no chunk is open
+	err            error         // First error writing body
+	words, bytes   int
+	startEp, endEp int
+	year           int
+	group          string    // Period of the open chunk, with ChunkBy
+	first, last    time.Time // Dates of the first and last dated episodes
+	toc            []TOCEntry
+	linked         []linkedEpisode // Episodes of the open chunk, for the link index
+	written        map[string]bool
+	manifest       *chunkManifest
+	links          *chunkLinks
+	result         *Result
+}
+
+func (c *chunker) empty() bool {
+	return c.buf == nil
+}
+
+func (c *chunker) fits(words, bytes int) bool {
+	return c.words+words <= c.opts.MaxWords && c.bytes+bytes <= c.opts.MaxBytes
+}
+
+func (c *chunker) add(text string, words int, ep Episode, continued bool) {
+	if c.empty() {
+		c.startEp = ep.Number
+		c.year = ep.Year
+		c.body, c.err = os.CreateTemp("", "twit-chunk-*")
+		if c.err == nil {
+			c.buf = bufio.NewWriter(c.body)
+		} else {
+			c.buf = bufio.NewWriter(io.Discard)
+		}
+	}
+	linked := linkedEpisode{key: fmt.Sprintf("%s_%d", ep.Prefix, ep.Number), continued: continued}
+	if c.opts.TOC {
+		anchor := EpisodeAnchor(ep.Prefix, ep.Number)
+		c.toc = append(c.toc, TOCEntry{Number: ep.Number, Title: ep.Title, DateStr: ep.DateStr, Anchor: anchor, Continued: continued})
+		text = anchorTag(anchor) + text
+		linked.anchor = anchor
+	}
+	c.linked = append(c.linked, linked)
+	if _, err := c.buf.WriteString(text); err != nil && c.err == nil {
+		c.err = err
+	}
+	c.words += words
+	c.bytes += len(text)
+	c.endEp = ep.Number
+	if !ep.Date.IsZero() {
+		if c.first.IsZero() || ep.Date.Before(c.first) {
+			c.first = ep.Date
+		}
+		if ep.Date.After(c.last) {
+			c.last = ep.Date
+		}
+	}
+}
+
+// addSplit adds an episode that does not fit in the current chunk, splitting
+// it at the boundaries allowed by the chunk mode
+func (c *chunker) addSplit(ep Episode, content string) {
+	render := func(lines []string, continued bool) string {
+		// Templates were already executed once for this episode, so errors
+		// are not expected here
+		text, _ := c.opts.Templates.RenderEpisode(ep, strings.Join(lines, "\n"), continued)
+		return text
+	}
+
+	// Size of the rendered header and footer around a part
+	frame := map[bool]int{false: len(render(nil, false)), true: len(render(nil, true))}
+
+	var part []string // Lines of the episode in the current chunk
+	partWords := 0
+	continued := false
+
+	closePart := func() {
+		if len(part) == 0 {
+			return
+		}
+		c.add(render(part, continued), partWords, ep, continued)
+	}
+
+	for _, seg := range c.segments(content) {
+		segText := strings.Join(seg, "\n")
+		segWords := len(strings.Fields(segText))
+		overhead := frame[continued] + len(strings.Join(part, "\n")) + 1
+
+		if !c.fits(partWords+segWords, overhead+len(segText)) && (len(part) > 0 || !c.empty()) {
+			closePart()
+			c.flush()
+
+			// Carry the last turns over for context
+			var overlap []string
+			if c.opts.Overlap > 0 && len(part) > 0 {
+				n := c.opts.Overlap
+				if n > len(part) {
+					n = len(part)
+				}
+				overlap = append(overlap, part[len(part)-n:]...)
+			}
+			if len(part) > 0 {
+				continued = true
+			}
+			part = overlap
+			partWords = len(strings.Fields(strings.Join(part, " ")))
+		}
+
+		part = append(part, seg...)
+		partWords += segWords
+	}
+	closePart()
+}
+
+// segments groups the episode's lines into the units a chunk may not split
+func (c *chunker) segments(content string) [][]string {
+	lines := strings.Split(content, "\n")
+	var segs [][]string
+	if c.opts.Mode != ChunkByTopic {
+		for _, l := range lines {
+			segs = append(segs, []string{l})
+		}
+		return segs
+	}
+
+	var current []string
+	for _, l := range lines {
+		if len(current) > 0 && topicMarkerRegex.MatchString(turnText(l)) {
+			segs = append(segs, current)
+			current = nil
+		}
+		current = append(current, l)
+	}
+	if len(current) > 0 {
+		segs = append(segs, current)
+	}
+
+	// A topic segment larger than a whole chunk falls back to turn boundaries
+	var result [][]string
+	for _, seg := range segs {
+		text := strings.Join(seg, "\n")
+		if len(strings.Fields(text)) > c.opts.MaxWords || len(text) > c.opts.MaxBytes {
+			for _, l := range seg {
+				result = append(result, []string{l})
+			}
+			continue
+		}
+		result = append(result, seg)
+	}
+	return result
+}
+
+// turnText strips the "EP:... Date:... - Speaker" metadata prefix from a line
+func turnText(line string) string {
+	if !strings.HasPrefix(line, "EP:") {
+		return line
+	}
+	if i := strings.Index(line, " - "); i >= 0 {
+		return line[i+3:]
+	}
+	return line
+}
+
+func (c *chunker) flush() {
+	if c.empty() {
+		return
+	}
+	span := chunkSpan{prefix: c.prefix, lang: c.lang, start: c.startEp, end: c.endEp, year: c.year, group: c.group, first: c.first, last: c.last}
+	filename := chunkPath(c.base, span, c.opts.ChunkName, c.opts.ByYear)
+	// Episodes split across several chunks can produce the same range twice
+	if c.written[filename] {
+		stem := strings.TrimSuffix(filename, ".md")
+		for n := 2; ; n++ {
+			candidate := fmt.Sprintf("%s_part%d.md", stem, n)
+			if !c.written[candidate] {
+				filename = candidate
+				break
+			}
+		}
+	}
+	c.written[filename] = true
+	if err := c.buf.Flush(); err != nil && c.err == nil {
+		c.err = err
+	}
+	header, footer, err := c.opts.Templates.ChunkFrame(ChunkData{
+		Prefix:   c.prefix,
+		Show:     config.ShowName(c.prefix),
+		Start:    c.startEp,
+		End:      c.endEp,
+		Year:     c.year,
+		Episodes: c.toc,
+		TOC:      RenderTOC(c.toc),
+	})
+	switch {
+	case c.err != nil:
+		fmt.Printf("Error buffering %s: %v\n", filename, c.err)
+	case err != nil:
+		fmt.Printf("Error rendering %s: %v\n", filename, err)
+	default:
+		chunk, err := writeChunk(filename, header, c.body, footer, c.words)
+		if err != nil {
+			c.result.fail(filename, err)
+			break
+		}
+		chunk.Lang, chunk.Start, chunk.End = c.lang, c.startEp, c.endEp
+		chunk.First, chunk.Last, chunk.Episodes = c.first, c.last, len(c.linked)
+		c.result.Chunks = append(c.result.Chunks, chunk)
+		c.manifest.record(filename)
+		c.links.record(filename, c.linked)
+	}
+
+	if c.body != nil {
+		c.body.Close()
+		os.Remove(c.body.Name())
+	}
+	c.body, c.buf, c.err = nil, nil, nil
+	c.toc, c.linked = nil, nil
+	c.words = 0
+	c.bytes = 0
+	c.first, c.last = time.Time{}, time.Time{}
+}
+
+// writeEpisodeFile writes one episode's rendered Markdown to its own file
+func writeEpisodeFile(ep Episode, text string, words int, outputBase string, opts Options) {
+	if opts.FrontMatter {
+		key := fmt.Sprintf("%s_%d", ep.Prefix, ep.Number)
+		if ep.Summary == nil {
+			ep.Summary = opts.Summaries[key]
+		}
+		text = FrontMatter(ep, words, opts.Tags[key]) + text
+	}
+	filename := episodeFilename(config.ActiveLayout.MarkdownDir(outputBase, ep.Prefix), ep)
+	if err := storage.WriteFile(filename, []byte(text)); err != nil {
+		fmt.Printf("Error creating %s: %v\n", filename, err)
+	}
+}
+
+// writeChunk writes a chunk file from its frame and the episode text in
+// body, copying the body rather than holding it in memory. The file is
+// written via a temp file, so a crash never leaves a partial chunk under its
+// final name. A chunk that already has the same content is left untouched.
+func writeChunk(filename, header string, body *os.File, footer string, words int) (ChunkFile, error) {
+	chunk := ChunkFile{Path: filename, Words: words + len(strings.Fields(header)) + len(strings.Fields(footer)), Changed: true}
+	tmp := filename + ".tmp"
+	n, err := copyChunk(tmp, header, body, footer)
+	chunk.Bytes = n
+	if err == nil && sameContent(tmp, filename) {
+		chunk.Changed = false
+		removeChunk(tmp)
+		fmt.Printf("Unchanged %s (Words: approx %d, Bytes: %d)\n", filename, chunk.Words, n)
+		return chunk, nil
+	}
+	if err == nil {
+		err = storage.Rename(tmp, filename)
+	}
+	if err != nil {
+		fmt.Printf("Error creating %s: %v\n", filename, err)
+		return chunk, err
+	}
+	fmt.Printf("Written %s (Words: approx %d, Bytes: %d)\n", filename, chunk.Words, n)
+	return chunk, nil
+}
+
+// sameContent reports whether two files exist and have the same bytes.
+// Local files are compared by size, then a block at a time, so chunks of up
+// to MaxBytes are never held in memory; object storage offers no streaming
+// reads, so remote chunks are read whole, as their upload does.
+func sameContent(a, b string) bool {
+	if storage.IsRemote(a) || storage.IsRemote(b) {
+		old, err := storage.ReadFile(b)
+		if err != nil {
+			return false
+		}
+		data, err := storage.ReadFile(a)
+		return err == nil && bytes.Equal(data, old)
+	}
+	ia, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	ib, err := os.Stat(b)
+	if err != nil || ia.Size() != ib.Size() {
+		return false
+	}
+	fa, err := os.Open(a)
+	if err != nil {
+		return false
+	}
+	defer fa.Close()
+	fb, err := os.Open(b)
+	if err != nil {
+		return false
+	}
+	defer fb.Close()
+	bufA, bufB := make([]byte, 64<<10), make([]byte, 64<<10)
+	for {
+		na, errA := io.ReadFull(fa, bufA)
+		nb, errB := io.ReadFull(fb, bufB)
+		if na != nb || !bytes.Equal(bufA[:na], bufB[:nb]) {
+			return false
+		}
+		if errA == io.EOF || errA == io.ErrUnexpectedEOF {
+			return errA == errB
+		}
+		if errA != nil || errB != nil {
+			return false
+		}
+	}
+}
+
+// copyChunk writes header, the contents of body and footer to path,
+// returning the bytes written
+func copyChunk(path, header string, body *os.File, footer string) (int64, error) {
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	w, err := storage.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	bw := bufio.NewWriter(w)
+	n, err := bw.WriteString(header)
+	total := int64(n)
+	if err == nil {
+		var m int64
+		m, err = io.Copy(bw, body)
+		total += m
+	}
+	if err == nil {
+		n, err = bw.WriteString(footer)
+		total += int64(n)
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	return total, err
+}
@@ -0,0 +1,138 @@
+package converter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+const (
+	// ChunkNameCompat keeps the original chunk names:
+	// "SN_Transcripts_1-99.md", or "SN_Transcripts_2008_150_199.md" by year
+	ChunkNameCompat = "compat"
+	// ChunkNamePadded names chunks by their zero-padded episode range and
+	// the years they span: "SN_0001-0099_2005-2006.md"
+	ChunkNamePadded = "{prefix}_{start}-{end}_{years}"
+)
+
+// ChunkNumberWidth is the width episode numbers are zero-padded to by the
+// {start} and {end} placeholders of chunk name templates
+var ChunkNumberWidth = 4
+
+// chunkPlaceholderRegex matches the placeholders of chunk name templates
+var chunkPlaceholderRegex = regexp.MustCompile(`\{[a-z]*\}`)
+
+// chunkPlaceholders are the placeholders a chunk name template may use
+var chunkPlaceholders = map[string]bool{
+	"{prefix}": true, "{lang}": true, "{start}": true, "{end}": true,
+	"{year}": true, "{years}": true, "{from}": true, "{to}": true,
+}
+
+// ParseChunkName validates a chunk name setting: "compat" (or empty),
+// "padded", or a template of placeholders: {prefix}, {start} and {end}
+// (zero-padded episode numbers), {year} (the chunk's year), {years} (the
+// years of its first and last episodes, "2005" or "2005-2006"), {from} and
+// {to} (their dates, YYYY-MM-DD) and {lang} (the language of a separated
+// language's chunks). ".md" is added to the name.
+func ParseChunkName(s string) (string, error) {
+	switch strings.ToLower(s) {
+	case "", ChunkNameCompat:
+		return ChunkNameCompat, nil
+	case "padded":
+		return ChunkNamePadded, nil
+	}
+	if strings.ContainsAny(s, `/\`) {
+		return "", fmt.Errorf("chunk name template '%s' may not contain directories", s)
+	}
+	for _, p := range chunkPlaceholderRegex.FindAllString(s, -1) {
+		if !chunkPlaceholders[p] {
+			return "", fmt.Errorf("unknown placeholder %s in chunk name template '%s'", p, s)
+		}
+	}
+	if !strings.Contains(s, "{start}") && !strings.Contains(s, "{end}") && !strings.Contains(s, "{year") && !strings.Contains(s, "{from}") {
+		return "", fmt.Errorf("chunk name template '%s' needs an episode number or date placeholder to tell chunks apart", s)
+	}
+	return s, nil
+}
+
+// chunkSpan is what a chunk file is named after
+type chunkSpan struct {
+	prefix, lang string
+	start, end   int
+	year         int
+	group        string    // Period with Options.ChunkBy ("2008", "2000s")
+	first, last  time.Time // Dates of the chunk's first and last dated episodes
+}
+
+// chunkSeparatorRegex matches the runs of separators left where empty
+// placeholders were
+var chunkSeparatorRegex = regexp.MustCompile(`([_-])[_-]+`)
+
+// name returns the file name of a chunk under a ParseChunkName template.
+// With no {lang} placeholder, a separated language's code is added at the
+// end ("SN_0001-0099_2005_es.md").
+func (s chunkSpan) name(template string, byYear bool) string {
+	if template == "" || template == ChunkNameCompat {
+		if s.group != "" {
+			return compatChunkName(s.prefix, s.lang, 0, 0, 0, false, s.group)
+		}
+		return compatChunkName(s.prefix, s.lang, s.start, s.end, s.year, byYear, "")
+	}
+	date := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format("2006-01-02")
+	}
+	years := ""
+	if !s.first.IsZero() {
+		years = fmt.Sprint(s.first.Year())
+		if s.last.Year() != s.first.Year() {
+			years += fmt.Sprintf("-%d", s.last.Year())
+		}
+	}
+	year := s.group
+	if year == "" && s.year > 0 {
+		year = fmt.Sprint(s.year)
+	}
+	stem := strings.NewReplacer(
+		"{prefix}", s.prefix,
+		"{lang}", s.lang,
+		"{start}", fmt.Sprintf("%0*d", ChunkNumberWidth, s.start),
+		"{end}", fmt.Sprintf("%0*d", ChunkNumberWidth, s.end),
+		"{year}", year,
+		"{years}", years,
+		"{from}", date(s.first),
+		"{to}", date(s.last),
+	).Replace(template)
+	if s.lang != "" && !strings.Contains(template, "{lang}") {
+		stem += "_" + s.lang
+	}
+	stem = strings.Trim(chunkSeparatorRegex.ReplaceAllString(stem, "$1"), "_-")
+	return stem + ".md"
+}
+
+// compatChunkName is the original chunk name; chunks of a separated
+// language carry its code ("SN_Transcripts_es_1-5.md"), and chunks of a
+// period are named after it ("SN_Transcripts_2008.md")
+func compatChunkName(prefix, lang string, start, end, year int, byYear bool, group string) string {
+	stem := prefix + "_Transcripts_"
+	if lang != "" {
+		stem += lang + "_"
+	}
+	if group != "" {
+		return stem + group + ".md"
+	}
+	if byYear && year > 0 {
+		return fmt.Sprintf("%s%d_%d_%d.md", stem, year, start, end)
+	}
+	return fmt.Sprintf("%s%d-%d.md", stem, start, end)
+}
+
+// chunkPath returns where a chunk is written
+func chunkPath(base string, s chunkSpan, template string, byYear bool) string {
+	return storage.Join(base, s.name(template, byYear))
+}
@@ -0,0 +1,119 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseChunkName(t *testing.T) {
+	for in, want := range map[string]string{
+		"":                        ChunkNameCompat,
+		"compat":                  ChunkNameCompat,
+		"Padded":                  ChunkNamePadded,
+		"{prefix}-{start}_{lang}": "{prefix}-{start}_{lang}",
+	} {
+		if got, err := ParseChunkName(in); err != nil || got != want {
+			t.Errorf("ParseChunkName(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"{prefix}_{begin}", "{prefix}_{lang}", "chunks/{prefix}_{start}"} {
+		if _, err := ParseChunkName(bad); err == nil {
+			t.Errorf("ParseChunkName(%q) should fail", bad)
+		}
+	}
+}
+
+func TestChunkSpanName(t *testing.T) {
+	d := func(s string) time.Time {
+		t, _ := time.Parse("2006-01-02", s)
+		return t
+	}
+	span := chunkSpan{prefix: "SN", start: 1, end: 99, first: d("2005-08-19"), last: d("2006-07-13")}
+	tests := []struct {
+		span     chunkSpan
+		template string
+		byYear   bool
+		want     string
+	}{
+		{span, ChunkNameCompat, false, "SN_Transcripts_1-99.md"},
+		{chunkSpan{prefix: "SN", lang: "es", start: 150, end: 199, year: 2008}, "", true, "SN_Transcripts_es_2008_150_199.md"},
+		{span, ChunkNamePadded, false, "SN_0001-0099_2005-2006.md"},
+		{chunkSpan{prefix: "SN", start: 1, end: 5, first: d("2005-08-19"), last: d("2005-09-15")}, ChunkNamePadded, false, "SN_0001-0005_2005.md"},
+		// Undated episodes leave no stray separators
+		{chunkSpan{prefix: "SN", start: 1, end: 5}, ChunkNamePadded, false, "SN_0001-0005.md"},
+		{chunkSpan{prefix: "SN", lang: "es", start: 1, end: 5, first: d("2005-08-19"), last: d("2005-08-19")}, ChunkNamePadded, false, "SN_0001-0005_2005_es.md"},
+		{span, "{prefix}_{from}_to_{to}_{start}", false, "SN_2005-08-19_to_2006-07-13_0001.md"},
+		{chunkSpan{prefix: "SN", start: 12000, end: 12001}, "{prefix}_{start}-{end}", false, "SN_12000-12001.md"},
+		// Chunks of a period are named after it
+		{chunkSpan{prefix: "SN", start: 130, end: 177, group: "2008"}, "", false, "SN_Transcripts_2008.md"},
+		{chunkSpan{prefix: "SN", lang: "es", start: 1, end: 5, group: "2000s"}, ChunkNameCompat, false, "SN_Transcripts_es_2000s.md"},
+		{chunkSpan{prefix: "SN", start: 130, end: 177, group: "2008"}, "{prefix}_{year}_{start}-{end}", false, "SN_2008_0130-0177.md"},
+	}
+	for _, tt := range tests {
+		if got := tt.span.name(tt.template, tt.byYear); got != tt.want {
+			t.Errorf("name(%+v, %q) = %q, want %q", tt.span, tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestProcessPaddedChunkNames(t *testing.T) {
+	dir := t.TempDir()
+	for ep, date := range map[string]string{"1": "Dec 11th 2025", "2": "Jan 15th 2026"} {
+		page := `<h1 class="post-title">Ep ` + ep + `</h1><p class="byline">` + date + `</p><div class="body textual">Content ` + ep + `</div>`
+		os.WriteFile(filepath.Join(dir, "IM_"+ep+".html"), []byte(page), 0644)
+	}
+	if err := ProcessPrefixWithOptions("IM", dir, dir, Options{ChunkName: ChunkNamePadded}); err != nil {
+		t.Fatal(err)
+	}
+	if !exists(dir, "IM_0001-0002_2025-2026.md") {
+		entries, _ := os.ReadDir(dir)
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Fatalf("Expected IM_0001-0002_2025-2026.md, got %v", names)
+	}
+
+	// Switching back replaces the chunks under their original names
+	if err := ProcessPrefixWithOptions("IM", dir, dir, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if exists(dir, "IM_0001-0002_2025-2026.md") || !exists(dir, "IM_Transcripts_1-2.md") {
+		t.Error("Expected the padded chunk to be replaced by IM_Transcripts_1-2.md")
+	}
+}
+
+func TestChunkByPeriod(t *testing.T) {
+	dir := t.TempDir()
+	for ep, date := range map[string]string{"1": "Dec 11th 2009", "2": "Jan 15th 2010", "3": "Feb 12th 2010", "4": ""} {
+		page := `<h1 class="post-title">Ep ` + ep + `</h1><p class="byline">` + date + `</p><div class="body textual">Content ` + ep + `</div>`
+		os.WriteFile(filepath.Join(dir, "IM_"+ep+".html"), []byte(page), 0644)
+	}
+	// Periods are not split by size
+	if err := ProcessPrefixWithOptions("IM", dir, dir, Options{ChunkBy: GroupByYear, MaxWords: 3}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"IM_Transcripts_2009.md", "IM_Transcripts_2010.md", "IM_Transcripts_undated.md"} {
+		if !exists(dir, name) {
+			t.Errorf("Expected %s", name)
+		}
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "IM_Transcripts_2010.md"))
+	if !strings.Contains(string(data), "Content 2") || !strings.Contains(string(data), "Content 3") {
+		t.Errorf("Expected both 2010 episodes in one chunk:\n%s", data)
+	}
+
+	if err := ProcessPrefixWithOptions("IM", dir, dir, Options{ChunkBy: GroupByDecade}); err != nil {
+		t.Fatal(err)
+	}
+	if !exists(dir, "IM_Transcripts_2000s.md") || !exists(dir, "IM_Transcripts_2010s.md") || exists(dir, "IM_Transcripts_2009.md") {
+		t.Error("Expected the yearly chunks to be replaced by decades")
+	}
+
+	if _, err := ParseChunkGroup("week"); err == nil {
+		t.Error("ParseChunkGroup should reject unknown groupings")
+	}
+}
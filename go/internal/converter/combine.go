@@ -0,0 +1,84 @@
+package converter
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// ChunkNameCombined names the chunks of ProcessCombined by the dates they
+// span: "NETWORK_2020-03-02_2020-03-31.md"
+const ChunkNameCombined = "{prefix}_{from}_{to}"
+
+// combinedNameRegex matches valid names for combined chunks, which take the
+// place of a prefix
+var combinedNameRegex = regexp.MustCompile(`^[A-Z0-9]+$`)
+
+// ProcessCombined interleaves the transcripts of several shows by
+// publication date into one set of chunk files under name, for questions
+// that span the network ("everything said in March 2020"). Undated
+// transcripts follow the dated ones. Chunks are named by their dates unless
+// opts.ChunkName sets a template. The name may not be a show's prefix, as
+// its chunks would replace the show's.
+func ProcessCombined(name string, prefixes []string, dataDir, outputBase string, opts Options) error {
+	_, err := ProcessCombinedResult(name, prefixes, dataDir, outputBase, opts)
+	return err
+}
+
+// ProcessCombinedResult is ProcessCombined, returning what the run did
+func ProcessCombinedResult(name string, prefixes []string, dataDir, outputBase string, opts Options) (*Result, error) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if !combinedNameRegex.MatchString(name) {
+		return nil, fmt.Errorf("invalid name '%s' for combined chunks (want letters and digits, e.g. NETWORK)", name)
+	}
+	if _, ok := config.ResolveShow(name); ok {
+		return nil, fmt.Errorf("'%s' is a show; pick another name for combined chunks", name)
+	}
+	var files []string
+	for _, prefix := range prefixes {
+		if prefix == name {
+			return nil, fmt.Errorf("'%s' is a show; pick another name for combined chunks", name)
+		}
+		f, err := config.ActiveLayout.RawFiles(dataDir, prefix)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f...)
+	}
+	if len(files) == 0 {
+		fmt.Printf("No files found for %s\n", strings.Join(prefixes, ", "))
+		return &Result{Prefix: name}, nil
+	}
+
+	// Only the byline is needed to order the files; the episodes are
+	// parsed one at a time while chunking
+	dates := make(map[string]time.Time, len(files))
+	for _, f := range files {
+		if html, err := storage.ReadFile(f); err == nil {
+			dates[f], _ = PublishedDate(string(html))
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		a, b := dates[files[i]], dates[files[j]]
+		if !a.Equal(b) {
+			return !a.IsZero() && (b.IsZero() || a.Before(b))
+		}
+		pa, _, _ := strings.Cut(storage.Base(files[i]), "_")
+		pb, _, _ := strings.Cut(storage.Base(files[j]), "_")
+		if pa != pb {
+			return pa < pb
+		}
+		return GetEpNum(files[i]) < GetEpNum(files[j])
+	})
+
+	if opts.ChunkName == "" || opts.ChunkName == ChunkNameCompat {
+		opts.ChunkName = ChunkNameCombined
+	}
+	fmt.Printf("Combining %s by date into %s\n", strings.Join(prefixes, ", "), name)
+	return processFiles(name, files, dataDir, outputBase, opts)
+}
@@ -0,0 +1,49 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProcessCombined(t *testing.T) {
+	dir := t.TempDir()
+	pages := map[string]string{
+		"SN_10.html": "Mar 17th 2020",
+		"SN_11.html": "Apr 14th 2020",
+		"IM_20.html": "Mar 11th 2020",
+		"IM_21.html": "Mar 25th 2020",
+		"IM_22.html": "",
+	}
+	for name, date := range pages {
+		page := `<h1 class="post-title">` + name + `</h1><p class="byline">` + date + `</p><div class="body textual">Content of ` + name + `</div>`
+		os.WriteFile(filepath.Join(dir, name), []byte(page), 0644)
+	}
+
+	if err := ProcessCombined("SN", []string{"IM", "SN"}, dir, dir, Options{}); err == nil {
+		t.Error("Expected a show prefix to be rejected as the combined name")
+	}
+	if err := ProcessCombined("network", []string{"IM", "SN"}, dir, dir, Options{ChunkBy: GroupByMonth}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "NETWORK_2020-03-11_2020-03-25.md"))
+	if err != nil {
+		t.Fatalf("Expected a chunk for March 2020: %v", err)
+	}
+	text := string(data)
+	im20, sn10, im21 := strings.Index(text, "Content of IM_20"), strings.Index(text, "Content of SN_10"), strings.Index(text, "Content of IM_21")
+	if im20 < 0 || !(im20 < sn10 && sn10 < im21) {
+		t.Errorf("Expected the shows interleaved by date:\n%s", text)
+	}
+	if strings.Contains(text, "SN_11") {
+		t.Error("April episode in the March chunk")
+	}
+	if !exists(dir, "NETWORK_2020-04-14_2020-04-14.md") || !exists(dir, "NETWORK.md") {
+		t.Error("Expected chunks for April and for the undated episode")
+	}
+	if exists(dir, "SN_Transcripts_10-11.md") {
+		t.Error("Combined run should not write per-show chunks")
+	}
+}
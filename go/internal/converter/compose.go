@@ -0,0 +1,135 @@
+package converter
+
+// latinCompositions maps a base letter and a combining mark to the
+// precomposed character NFC uses for them. It covers Latin-1 Supplement,
+// Latin Extended-A/B and Latin Extended Additional (Vietnamese), which is
+// where decomposed text shows up in transcripts; it was derived from the
+// Unicode Character Database's canonical decompositions, less composition
+// exclusions.
+var latinCompositions = map[[2]rune]rune{
+	{'A', 0x0300}: 'À', {'A', 0x0301}: 'Á', {'A', 0x0302}: 'Â', {'A', 0x0303}: 'Ã',
+	{'A', 0x0308}: 'Ä', {'A', 0x030A}: 'Å', {'C', 0x0327}: 'Ç', {'E', 0x0300}: 'È',
+	{'E', 0x0301}: 'É', {'E', 0x0302}: 'Ê', {'E', 0x0308}: 'Ë', {'I', 0x0300}: 'Ì',
+	{'I', 0x0301}: 'Í', {'I', 0x0302}: 'Î', {'I', 0x0308}: 'Ï', {'N', 0x0303}: 'Ñ',
+	{'O', 0x0300}: 'Ò', {'O', 0x0301}: 'Ó', {'O', 0x0302}: 'Ô', {'O', 0x0303}: 'Õ',
+	{'O', 0x0308}: 'Ö', {'U', 0x0300}: 'Ù', {'U', 0x0301}: 'Ú', {'U', 0x0302}: 'Û',
+	{'U', 0x0308}: 'Ü', {'Y', 0x0301}: 'Ý', {'a', 0x0300}: 'à', {'a', 0x0301}: 'á',
+	{'a', 0x0302}: 'â', {'a', 0x0303}: 'ã', {'a', 0x0308}: 'ä', {'a', 0x030A}: 'å',
+	{'c', 0x0327}: 'ç', {'e', 0x0300}: 'è', {'e', 0x0301}: 'é', {'e', 0x0302}: 'ê',
+	{'e', 0x0308}: 'ë', {'i', 0x0300}: 'ì', {'i', 0x0301}: 'í', {'i', 0x0302}: 'î',
+	{'i', 0x0308}: 'ï', {'n', 0x0303}: 'ñ', {'o', 0x0300}: 'ò', {'o', 0x0301}: 'ó',
+	{'o', 0x0302}: 'ô', {'o', 0x0303}: 'õ', {'o', 0x0308}: 'ö', {'u', 0x0300}: 'ù',
+	{'u', 0x0301}: 'ú', {'u', 0x0302}: 'û', {'u', 0x0308}: 'ü', {'y', 0x0301}: 'ý',
+	{'y', 0x0308}: 'ÿ', {'A', 0x0304}: 'Ā', {'a', 0x0304}: 'ā', {'A', 0x0306}: 'Ă',
+	{'a', 0x0306}: 'ă', {'A', 0x0328}: 'Ą', {'a', 0x0328}: 'ą', {'C', 0x0301}: 'Ć',
+	{'c', 0x0301}: 'ć', {'C', 0x0302}: 'Ĉ', {'c', 0x0302}: 'ĉ', {'C', 0x0307}: 'Ċ',
+	{'c', 0x0307}: 'ċ', {'C', 0x030C}: 'Č', {'c', 0x030C}: 'č', {'D', 0x030C}: 'Ď',
+	{'d', 0x030C}: 'ď', {'E', 0x0304}: 'Ē', {'e', 0x0304}: 'ē', {'E', 0x0306}: 'Ĕ',
+	{'e', 0x0306}: 'ĕ', {'E', 0x0307}: 'Ė', {'e', 0x0307}: 'ė', {'E', 0x0328}: 'Ę',
+	{'e', 0x0328}: 'ę', {'E', 0x030C}: 'Ě', {'e', 0x030C}: 'ě', {'G', 0x0302}: 'Ĝ',
+	{'g', 0x0302}: 'ĝ', {'G', 0x0306}: 'Ğ', {'g', 0x0306}: 'ğ', {'G', 0x0307}: 'Ġ',
+	{'g', 0x0307}: 'ġ', {'G', 0x0327}: 'Ģ', {'g', 0x0327}: 'ģ', {'H', 0x0302}: 'Ĥ',
+	{'h', 0x0302}: 'ĥ', {'I', 0x0303}: 'Ĩ', {'i', 0x0303}: 'ĩ', {'I', 0x0304}: 'Ī',
+	{'i', 0x0304}: 'ī', {'I', 0x0306}: 'Ĭ', {'i', 0x0306}: 'ĭ', {'I', 0x0328}: 'Į',
+	{'i', 0x0328}: 'į', {'I', 0x0307}: 'İ', {'J', 0x0302}: 'Ĵ', {'j', 0x0302}: 'ĵ',
+	{'K', 0x0327}: 'Ķ', {'k', 0x0327}: 'ķ', {'L', 0x0301}: 'Ĺ', {'l', 0x0301}: 'ĺ',
+	{'L', 0x0327}: 'Ļ', {'l', 0x0327}: 'ļ', {'L', 0x030C}: 'Ľ', {'l', 0x030C}: 'ľ',
+	{'N', 0x0301}: 'Ń', {'n', 0x0301}: 'ń', {'N', 0x0327}: 'Ņ', {'n', 0x0327}: 'ņ',
+	{'N', 0x030C}: 'Ň', {'n', 0x030C}: 'ň', {'O', 0x0304}: 'Ō', {'o', 0x0304}: 'ō',
+	{'O', 0x0306}: 'Ŏ', {'o', 0x0306}: 'ŏ', {'O', 0x030B}: 'Ő', {'o', 0x030B}: 'ő',
+	{'R', 0x0301}: 'Ŕ', {'r', 0x0301}: 'ŕ', {'R', 0x0327}: 'Ŗ', {'r', 0x0327}: 'ŗ',
+	{'R', 0x030C}: 'Ř', {'r', 0x030C}: 'ř', {'S', 0x0301}: 'Ś', {'s', 0x0301}: 'ś',
+	{'S', 0x0302}: 'Ŝ', {'s', 0x0302}: 'ŝ', {'S', 0x0327}: 'Ş', {'s', 0x0327}: 'ş',
+	{'S', 0x030C}: 'Š', {'s', 0x030C}: 'š', {'T', 0x0327}: 'Ţ', {'t', 0x0327}: 'ţ',
+	{'T', 0x030C}: 'Ť', {'t', 0x030C}: 'ť', {'U', 0x0303}: 'Ũ', {'u', 0x0303}: 'ũ',
+	{'U', 0x0304}: 'Ū', {'u', 0x0304}: 'ū', {'U', 0x0306}: 'Ŭ', {'u', 0x0306}: 'ŭ',
+	{'U', 0x030A}: 'Ů', {'u', 0x030A}: 'ů', {'U', 0x030B}: 'Ű', {'u', 0x030B}: 'ű',
+	{'U', 0x0328}: 'Ų', {'u', 0x0328}: 'ų', {'W', 0x0302}: 'Ŵ', {'w', 0x0302}: 'ŵ',
+	{'Y', 0x0302}: 'Ŷ', {'y', 0x0302}: 'ŷ', {'Y', 0x0308}: 'Ÿ', {'Z', 0x0301}: 'Ź',
+	{'z', 0x0301}: 'ź', {'Z', 0x0307}: 'Ż', {'z', 0x0307}: 'ż', {'Z', 0x030C}: 'Ž',
+	{'z', 0x030C}: 'ž', {'O', 0x031B}: 'Ơ', {'o', 0x031B}: 'ơ', {'U', 0x031B}: 'Ư',
+	{'u', 0x031B}: 'ư', {'A', 0x030C}: 'Ǎ', {'a', 0x030C}: 'ǎ', {'I', 0x030C}: 'Ǐ',
+	{'i', 0x030C}: 'ǐ', {'O', 0x030C}: 'Ǒ', {'o', 0x030C}: 'ǒ', {'U', 0x030C}: 'Ǔ',
+	{'u', 0x030C}: 'ǔ', {'Ü', 0x0304}: 'Ǖ', {'ü', 0x0304}: 'ǖ', {'Ü', 0x0301}: 'Ǘ',
+	{'ü', 0x0301}: 'ǘ', {'Ü', 0x030C}: 'Ǚ', {'ü', 0x030C}: 'ǚ', {'Ü', 0x0300}: 'Ǜ',
+	{'ü', 0x0300}: 'ǜ', {'Ä', 0x0304}: 'Ǟ', {'ä', 0x0304}: 'ǟ', {'Ȧ', 0x0304}: 'Ǡ',
+	{'ȧ', 0x0304}: 'ǡ', {'Æ', 0x0304}: 'Ǣ', {'æ', 0x0304}: 'ǣ', {'G', 0x030C}: 'Ǧ',
+	{'g', 0x030C}: 'ǧ', {'K', 0x030C}: 'Ǩ', {'k', 0x030C}: 'ǩ', {'O', 0x0328}: 'Ǫ',
+	{'o', 0x0328}: 'ǫ', {'Ǫ', 0x0304}: 'Ǭ', {'ǫ', 0x0304}: 'ǭ', {'Ʒ', 0x030C}: 'Ǯ',
+	{'ʒ', 0x030C}: 'ǯ', {'j', 0x030C}: 'ǰ', {'G', 0x0301}: 'Ǵ', {'g', 0x0301}: 'ǵ',
+	{'N', 0x0300}: 'Ǹ', {'n', 0x0300}: 'ǹ', {'Å', 0x0301}: 'Ǻ', {'å', 0x0301}: 'ǻ',
+	{'Æ', 0x0301}: 'Ǽ', {'æ', 0x0301}: 'ǽ', {'Ø', 0x0301}: 'Ǿ', {'ø', 0x0301}: 'ǿ',
+	{'A', 0x030F}: 'Ȁ', {'a', 0x030F}: 'ȁ', {'A', 0x0311}: 'Ȃ', {'a', 0x0311}: 'ȃ',
+	{'E', 0x030F}: 'Ȅ', {'e', 0x030F}: 'ȅ', {'E', 0x0311}: 'Ȇ', {'e', 0x0311}: 'ȇ',
+	{'I', 0x030F}: 'Ȉ', {'i', 0x030F}: 'ȉ', {'I', 0x0311}: 'Ȋ', {'i', 0x0311}: 'ȋ',
+	{'O', 0x030F}: 'Ȍ', {'o', 0x030F}: 'ȍ', {'O', 0x0311}: 'Ȏ', {'o', 0x0311}: 'ȏ',
+	{'R', 0x030F}: 'Ȑ', {'r', 0x030F}: 'ȑ', {'R', 0x0311}: 'Ȓ', {'r', 0x0311}: 'ȓ',
+	{'U', 0x030F}: 'Ȕ', {'u', 0x030F}: 'ȕ', {'U', 0x0311}: 'Ȗ', {'u', 0x0311}: 'ȗ',
+	{'S', 0x0326}: 'Ș', {'s', 0x0326}: 'ș', {'T', 0x0326}: 'Ț', {'t', 0x0326}: 'ț',
+	{'H', 0x030C}: 'Ȟ', {'h', 0x030C}: 'ȟ', {'A', 0x0307}: 'Ȧ', {'a', 0x0307}: 'ȧ',
+	{'E', 0x0327}: 'Ȩ', {'e', 0x0327}: 'ȩ', {'Ö', 0x0304}: 'Ȫ', {'ö', 0x0304}: 'ȫ',
+	{'Õ', 0x0304}: 'Ȭ', {'õ', 0x0304}: 'ȭ', {'O', 0x0307}: 'Ȯ', {'o', 0x0307}: 'ȯ',
+	{'Ȯ', 0x0304}: 'Ȱ', {'ȯ', 0x0304}: 'ȱ', {'Y', 0x0304}: 'Ȳ', {'y', 0x0304}: 'ȳ',
+	{'A', 0x0325}: 'Ḁ', {'a', 0x0325}: 'ḁ', {'B', 0x0307}: 'Ḃ', {'b', 0x0307}: 'ḃ',
+	{'B', 0x0323}: 'Ḅ', {'b', 0x0323}: 'ḅ', {'B', 0x0331}: 'Ḇ', {'b', 0x0331}: 'ḇ',
+	{'Ç', 0x0301}: 'Ḉ', {'ç', 0x0301}: 'ḉ', {'D', 0x0307}: 'Ḋ', {'d', 0x0307}: 'ḋ',
+	{'D', 0x0323}: 'Ḍ', {'d', 0x0323}: 'ḍ', {'D', 0x0331}: 'Ḏ', {'d', 0x0331}: 'ḏ',
+	{'D', 0x0327}: 'Ḑ', {'d', 0x0327}: 'ḑ', {'D', 0x032D}: 'Ḓ', {'d', 0x032D}: 'ḓ',
+	{'Ē', 0x0300}: 'Ḕ', {'ē', 0x0300}: 'ḕ', {'Ē', 0x0301}: 'Ḗ', {'ē', 0x0301}: 'ḗ',
+	{'E', 0x032D}: 'Ḙ', {'e', 0x032D}: 'ḙ', {'E', 0x0330}: 'Ḛ', {'e', 0x0330}: 'ḛ',
+	{'Ȩ', 0x0306}: 'Ḝ', {'ȩ', 0x0306}: 'ḝ', {'F', 0x0307}: 'Ḟ', {'f', 0x0307}: 'ḟ',
+	{'G', 0x0304}: 'Ḡ', {'g', 0x0304}: 'ḡ', {'H', 0x0307}: 'Ḣ', {'h', 0x0307}: 'ḣ',
+	{'H', 0x0323}: 'Ḥ', {'h', 0x0323}: 'ḥ', {'H', 0x0308}: 'Ḧ', {'h', 0x0308}: 'ḧ',
+	{'H', 0x0327}: 'Ḩ', {'h', 0x0327}: 'ḩ', {'H', 0x032E}: 'Ḫ', {'h', 0x032E}: 'ḫ',
+	{'I', 0x0330}: 'Ḭ', {'i', 0x0330}: 'ḭ', {'Ï', 0x0301}: 'Ḯ', {'ï', 0x0301}: 'ḯ',
+	{'K', 0x0301}: 'Ḱ', {'k', 0x0301}: 'ḱ', {'K', 0x0323}: 'Ḳ', {'k', 0x0323}: 'ḳ',
+	{'K', 0x0331}: 'Ḵ', {'k', 0x0331}: 'ḵ', {'L', 0x0323}: 'Ḷ', {'l', 0x0323}: 'ḷ',
+	{'Ḷ', 0x0304}: 'Ḹ', {'ḷ', 0x0304}: 'ḹ', {'L', 0x0331}: 'Ḻ', {'l', 0x0331}: 'ḻ',
+	{'L', 0x032D}: 'Ḽ', {'l', 0x032D}: 'ḽ', {'M', 0x0301}: 'Ḿ', {'m', 0x0301}: 'ḿ',
+	{'M', 0x0307}: 'Ṁ', {'m', 0x0307}: 'ṁ', {'M', 0x0323}: 'Ṃ', {'m', 0x0323}: 'ṃ',
+	{'N', 0x0307}: 'Ṅ', {'n', 0x0307}: 'ṅ', {'N', 0x0323}: 'Ṇ', {'n', 0x0323}: 'ṇ',
+	{'N', 0x0331}: 'Ṉ', {'n', 0x0331}: 'ṉ', {'N', 0x032D}: 'Ṋ', {'n', 0x032D}: 'ṋ',
+	{'Õ', 0x0301}: 'Ṍ', {'õ', 0x0301}: 'ṍ', {'Õ', 0x0308}: 'Ṏ', {'õ', 0x0308}: 'ṏ',
+	{'Ō', 0x0300}: 'Ṑ', {'ō', 0x0300}: 'ṑ', {'Ō', 0x0301}: 'Ṓ', {'ō', 0x0301}: 'ṓ',
+	{'P', 0x0301}: 'Ṕ', {'p', 0x0301}: 'ṕ', {'P', 0x0307}: 'Ṗ', {'p', 0x0307}: 'ṗ',
+	{'R', 0x0307}: 'Ṙ', {'r', 0x0307}: 'ṙ', {'R', 0x0323}: 'Ṛ', {'r', 0x0323}: 'ṛ',
+	{'Ṛ', 0x0304}: 'Ṝ', {'ṛ', 0x0304}: 'ṝ', {'R', 0x0331}: 'Ṟ', {'r', 0x0331}: 'ṟ',
+	{'S', 0x0307}: 'Ṡ', {'s', 0x0307}: 'ṡ', {'S', 0x0323}: 'Ṣ', {'s', 0x0323}: 'ṣ',
+	{'Ś', 0x0307}: 'Ṥ', {'ś', 0x0307}: 'ṥ', {'Š', 0x0307}: 'Ṧ', {'š', 0x0307}: 'ṧ',
+	{'Ṣ', 0x0307}: 'Ṩ', {'ṣ', 0x0307}: 'ṩ', {'T', 0x0307}: 'Ṫ', {'t', 0x0307}: 'ṫ',
+	{'T', 0x0323}: 'Ṭ', {'t', 0x0323}: 'ṭ', {'T', 0x0331}: 'Ṯ', {'t', 0x0331}: 'ṯ',
+	{'T', 0x032D}: 'Ṱ', {'t', 0x032D}: 'ṱ', {'U', 0x0324}: 'Ṳ', {'u', 0x0324}: 'ṳ',
+	{'U', 0x0330}: 'Ṵ', {'u', 0x0330}: 'ṵ', {'U', 0x032D}: 'Ṷ', {'u', 0x032D}: 'ṷ',
+	{'Ũ', 0x0301}: 'Ṹ', {'ũ', 0x0301}: 'ṹ', {'Ū', 0x0308}: 'Ṻ', {'ū', 0x0308}: 'ṻ',
+	{'V', 0x0303}: 'Ṽ', {'v', 0x0303}: 'ṽ', {'V', 0x0323}: 'Ṿ', {'v', 0x0323}: 'ṿ',
+	{'W', 0x0300}: 'Ẁ', {'w', 0x0300}: 'ẁ', {'W', 0x0301}: 'Ẃ', {'w', 0x0301}: 'ẃ',
+	{'W', 0x0308}: 'Ẅ', {'w', 0x0308}: 'ẅ', {'W', 0x0307}: 'Ẇ', {'w', 0x0307}: 'ẇ',
+	{'W', 0x0323}: 'Ẉ', {'w', 0x0323}: 'ẉ', {'X', 0x0307}: 'Ẋ', {'x', 0x0307}: 'ẋ',
+	{'X', 0x0308}: 'Ẍ', {'x', 0x0308}: 'ẍ', {'Y', 0x0307}: 'Ẏ', {'y', 0x0307}: 'ẏ',
+	{'Z', 0x0302}: 'Ẑ', {'z', 0x0302}: 'ẑ', {'Z', 0x0323}: 'Ẓ', {'z', 0x0323}: 'ẓ',
+	{'Z', 0x0331}: 'Ẕ', {'z', 0x0331}: 'ẕ', {'h', 0x0331}: 'ẖ', {'t', 0x0308}: 'ẗ',
+	{'w', 0x030A}: 'ẘ', {'y', 0x030A}: 'ẙ', {'ſ', 0x0307}: 'ẛ', {'A', 0x0323}: 'Ạ',
+	{'a', 0x0323}: 'ạ', {'A', 0x0309}: 'Ả', {'a', 0x0309}: 'ả', {'Â', 0x0301}: 'Ấ',
+	{'â', 0x0301}: 'ấ', {'Â', 0x0300}: 'Ầ', {'â', 0x0300}: 'ầ', {'Â', 0x0309}: 'Ẩ',
+	{'â', 0x0309}: 'ẩ', {'Â', 0x0303}: 'Ẫ', {'â', 0x0303}: 'ẫ', {'Ạ', 0x0302}: 'Ậ',
+	{'ạ', 0x0302}: 'ậ', {'Ă', 0x0301}: 'Ắ', {'ă', 0x0301}: 'ắ', {'Ă', 0x0300}: 'Ằ',
+	{'ă', 0x0300}: 'ằ', {'Ă', 0x0309}: 'Ẳ', {'ă', 0x0309}: 'ẳ', {'Ă', 0x0303}: 'Ẵ',
+	{'ă', 0x0303}: 'ẵ', {'Ạ', 0x0306}: 'Ặ', {'ạ', 0x0306}: 'ặ', {'E', 0x0323}: 'Ẹ',
+	{'e', 0x0323}: 'ẹ', {'E', 0x0309}: 'Ẻ', {'e', 0x0309}: 'ẻ', {'E', 0x0303}: 'Ẽ',
+	{'e', 0x0303}: 'ẽ', {'Ê', 0x0301}: 'Ế', {'ê', 0x0301}: 'ế', {'Ê', 0x0300}: 'Ề',
+	{'ê', 0x0300}: 'ề', {'Ê', 0x0309}: 'Ể', {'ê', 0x0309}: 'ể', {'Ê', 0x0303}: 'Ễ',
+	{'ê', 0x0303}: 'ễ', {'Ẹ', 0x0302}: 'Ệ', {'ẹ', 0x0302}: 'ệ', {'I', 0x0309}: 'Ỉ',
+	{'i', 0x0309}: 'ỉ', {'I', 0x0323}: 'Ị', {'i', 0x0323}: 'ị', {'O', 0x0323}: 'Ọ',
+	{'o', 0x0323}: 'ọ', {'O', 0x0309}: 'Ỏ', {'o', 0x0309}: 'ỏ', {'Ô', 0x0301}: 'Ố',
+	{'ô', 0x0301}: 'ố', {'Ô', 0x0300}: 'Ồ', {'ô', 0x0300}: 'ồ', {'Ô', 0x0309}: 'Ổ',
+	{'ô', 0x0309}: 'ổ', {'Ô', 0x0303}: 'Ỗ', {'ô', 0x0303}: 'ỗ', {'Ọ', 0x0302}: 'Ộ',
+	{'ọ', 0x0302}: 'ộ', {'Ơ', 0x0301}: 'Ớ', {'ơ', 0x0301}: 'ớ', {'Ơ', 0x0300}: 'Ờ',
+	{'ơ', 0x0300}: 'ờ', {'Ơ', 0x0309}: 'Ở', {'ơ', 0x0309}: 'ở', {'Ơ', 0x0303}: 'Ỡ',
+	{'ơ', 0x0303}: 'ỡ', {'Ơ', 0x0323}: 'Ợ', {'ơ', 0x0323}: 'ợ', {'U', 0x0323}: 'Ụ',
+	{'u', 0x0323}: 'ụ', {'U', 0x0309}: 'Ủ', {'u', 0x0309}: 'ủ', {'Ư', 0x0301}: 'Ứ',
+	{'ư', 0x0301}: 'ứ', {'Ư', 0x0300}: 'Ừ', {'ư', 0x0300}: 'ừ', {'Ư', 0x0309}: 'Ử',
+	{'ư', 0x0309}: 'ử', {'Ư', 0x0303}: 'Ữ', {'ư', 0x0303}: 'ữ', {'Ư', 0x0323}: 'Ự',
+	{'ư', 0x0323}: 'ự', {'Y', 0x0300}: 'Ỳ', {'y', 0x0300}: 'ỳ', {'Y', 0x0323}: 'Ỵ',
+	{'y', 0x0323}: 'ỵ', {'Y', 0x0309}: 'Ỷ', {'y', 0x0309}: 'ỷ', {'Y', 0x0303}: 'Ỹ',
+	{'y', 0x0303}: 'ỹ',
+}
@@ -2,13 +2,13 @@ package converter
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
 	"regexp"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/errs"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
 )
 
 // Constants
@@ -19,6 +19,9 @@ const (
 	// We use slightly lower operational limits to ensure compatibility.
 	MaxWords = 490000
 	MaxBytes = 190 * 1024 * 1024
+
+	// AIGeneratedTag opens transcripts that carried the AI-generated disclaimer
+	AIGeneratedTag = "[AI-Generated Transcript]"
 )
 
 // Pre-compiled regular expressions for performance
@@ -27,23 +30,7 @@ var (
 	yearCaptureRegex   = regexp.MustCompile(`(\d{4})`)
 	episodeNumberRegex = regexp.MustCompile(`_(\d+)\.html`)
 
-	// HTML parsing regexes
-	scriptTagRegex  = regexp.MustCompile(`(?s)<script.*?</script>`)
-	styleTagRegex   = regexp.MustCompile(`(?s)<style.*?</style>`)
-	h1TagRegex      = regexp.MustCompile(`(?s)<h1[^>]*>(.*?)</h1>`)
-	h2TagRegex      = regexp.MustCompile(`(?s)<h2[^>]*>(.*?)</h2>`)
-	h3TagRegex      = regexp.MustCompile(`(?s)<h3[^>]*>(.*?)</h3>`)
-	pTagRegex       = regexp.MustCompile(`(?s)<p[^>]*>(.*?)</p>`)
-	brTagRegex      = regexp.MustCompile(`(?i)<br\s*/?>`)
-	boldTagRegex    = regexp.MustCompile(`(?s)<b[^>]*>(.*?)</b>`)
-	strongTagRegex  = regexp.MustCompile(`(?s)<strong[^>]*>(.*?)</strong>`)
-	italicTagRegex  = regexp.MustCompile(`(?s)<i[^>]*>(.*?)</i>`)
-	structTagRegex  = regexp.MustCompile(`(?i)</?(?:table|p|br|tr|div)[^>]*>`)
-	emTagRegex      = regexp.MustCompile(`(?s)<em[^>]*>(.*?)</em>`)
-	anchorTagRegex  = regexp.MustCompile(`(?s)<a\s+(?:[^>]*?\s+)?href="([^"]*)"[^>]*>(.*?)</a>`)
-	ulTagRegex      = regexp.MustCompile(`(?i)<ul[^>]*>`)
-	ulEndTagRegex   = regexp.MustCompile(`(?i)</ul>`)
-	liTagRegex      = regexp.MustCompile(`(?s)<li[^>]*>(.*?)</li>`)
+	// HTML parsing regexes (tags are handled by renderMarkup)
 	anyTagRegex     = regexp.MustCompile(`<[^>]+>`)
 	disclaimerRegex = regexp.MustCompile(`(?is)\*?Please be advised this transcript is AI-generated.*?(?:ad-supported version of the show|approximate times|word for word)\.?\*?`)
 
@@ -51,10 +38,12 @@ var (
 	postTitleRegex   = regexp.MustCompile(`<h1 class="post-title">(.*?)</h1>`)
 	bylineRegex      = regexp.MustCompile(`(?s)<p class="byline">(.*?)</p>`)
 	bodyContentRegex = regexp.MustCompile(`(?s)<div class="body textual">(.*?)</div>`)
+	mediaURLRegex    = regexp.MustCompile(`(?i)(?:href|src)="(https?://[^"]+?\.(?:mp3|m4a|ogg|opus|mp4|m4v|webm)(?:\?[^"]*)?)"`)
+	audioURLRegex    = regexp.MustCompile(`(?i)\.(?:mp3|m4a|ogg|opus)(?:\?|$)`)
 
 	// Timestamp Patterns
 	// Pattern 1: HH:MM:SS - Speaker (Standard)
-	tsPattern1 = regexp.MustCompile(`^(\d+:\d+(?::\d+)?)\s*(?:-\s*)?(.*)`)
+	tsPattern1 = regexp.MustCompile(`^(\d+:\d+(?::\d+)?)\s*(?:-\s*)?([^:]*)(?::\s*(.*))?`)
 	// Pattern 2: Speaker [HH:MM:SS]: (Secondary)
 	tsPattern2 = regexp.MustCompile(`^(.+?)\s*\[(\d+:\d+(?::\d+)?)\].*?\s*(.*)`)
 	// Pattern 3: Speaker (HH:MM:SS): (Discovered 2021-2023)
@@ -99,97 +88,39 @@ func extractEpFromTitle(title string) int {
 
 // parseDateYMD converts various date formats (e.g., "May 21st 2025") into "YY-MM-DD"
 func parseDateYMD(dateStr string) string {
-	if dateStr == "" || dateStr == "Unknown Date" {
-		return "00-01-01"
-	}
-
-	// Remove ordinal suffixes (st, nd, rd, th)
-	re := regexp.MustCompile(`(\d+)(st|nd|rd|th)`)
-	cleanDate := re.ReplaceAllString(dateStr, "$1")
-
-	// Common layouts for time.Parse
-	layouts := []string{
-		"January 02 2006",
-		"Jan 02 2006",
-		"Monday, January 02, 2006",
-		"January 02, 2006",
-		"Jan 02, 2006",
-	}
-
-	for _, layout := range layouts {
-		if t, err := time.Parse(layout, cleanDate); err == nil {
-			return t.Format("06-01-02") // YY-MM-DD
-		}
+	if t, ok := ParseDate(dateStr); ok {
+		return t.Format("06-01-02") // YY-MM-DD
 	}
-
 	return "00-01-01" // Fallback
 }
 
+// Turn is one speaker turn of a transcript, as standardized into a line of
+// its Markdown. The line does not delimit the speaker from the text, so the
+// speaker is recorded here.
+type Turn struct {
+	Speaker  string // Empty when the page does not say
+	Timecode string
+	Words    int
+}
+
 // HTMLToMarkdown converts raw HTML transcript content to Markdown with timestamp standardization
 func HTMLToMarkdown(html string, epNum int, dateYMD string) string {
+	md, _, _ := convertTranscript(html, epNum, dateYMD)
+	return md
+}
+
+// convertTranscript is HTMLToMarkdown, also returning the speaker turns and
+// whether the body needed the strict markup pass (see renderBody)
+func convertTranscript(html string, epNum int, dateYMD string) (string, []Turn, bool) {
 	if html == "" {
-		return ""
+		return "", nil, false
 	}
 
-	text := html
-	// Remove script/style
-	text = scriptTagRegex.ReplaceAllString(text, "")
-	text = styleTagRegex.ReplaceAllString(text, "")
-
-	// Remove AI-generated disclaimer
-	text = disclaimerRegex.ReplaceAllString(text, "")
-
-	// Headers
-	text = h1TagRegex.ReplaceAllString(text, "# $1\n\n")
-	text = h2TagRegex.ReplaceAllString(text, "## $1\n\n")
-	text = h3TagRegex.ReplaceAllString(text, "### $1\n\n")
-
-	// --- Structural Tag Preservation ---
-	// Replace table/paragraph/break tags with newlines to prevent text collapse
-	text = structTagRegex.ReplaceAllString(text, "\n")
-
-	// Bold
-	text = boldTagRegex.ReplaceAllString(text, "**$1**")
-	text = strongTagRegex.ReplaceAllString(text, "**$1**")
-
-	// Italic
-	text = italicTagRegex.ReplaceAllString(text, "*$1*")
-	text = emTagRegex.ReplaceAllString(text, "*$1*")
-
-	// Links
-	text = anchorTagRegex.ReplaceAllStringFunc(text, func(match string) string {
-		sub := anchorTagRegex.FindStringSubmatch(match)
-		if len(sub) < 3 {
-			return "" // Should not happen given the match
-		}
-		url := sub[1]
-		content := sub[2]
-
-		// Security: Only allow http(s) or relative paths
-		if strings.HasPrefix(url, "/") || strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
-			return fmt.Sprintf("[%s](%s)", content, url)
-		}
-		return content
-	})
-
-	// Lists
-	text = ulTagRegex.ReplaceAllString(text, "")
-	text = ulEndTagRegex.ReplaceAllString(text, "\n")
-	text = liTagRegex.ReplaceAllString(text, "* $1\n")
-
-	// Tags cleanup
-	text = anyTagRegex.ReplaceAllString(text, "")
-
-	// Decode entities
-	r := strings.NewReplacer(
-		"&nbsp;", " ",
-		"&amp;", "&",
-		"&lt;", "<",
-		"&gt;", ">",
-		"&quot;", "\"",
-		"&#39;", "'",
-	)
-	text = r.Replace(text)
+	// Remove AI-generated disclaimer, remembering that it was there
+	stripped := disclaimerRegex.ReplaceAllString(html, "")
+	aiGenerated := len(stripped) != len(html)
+	rendered, strict := renderBody(stripped)
+	text := applyTextFilters(Normalize.Apply(rendered))
 
 	// Split into lines for standardization
 	var rawLines []string
@@ -203,13 +134,34 @@ func HTMLToMarkdown(html string, epNum int, dateYMD string) string {
 	}
 
 	// --- Context-Tracking Pass ---
+	// Lines that carry speaker/timestamp metadata open a new turn; plain lines
+	// that follow are merged into the open turn so every output line holds
+	// EP, Date, TS, Speaker and Text together.
 	var finalLines []string
-	var currentTimestamp, currentSpeaker string
+	var currentTimestamp, currentSpeaker, turnPrefix string
+	var buffer []string
+	var turns []Turn
+	var turn Turn // The open turn
+
+	flush := func() {
+		if len(buffer) > 0 {
+			words := strings.Fields(strings.Join(buffer, " "))
+			finalLines = append(finalLines, turnPrefix+" "+strings.Join(words, " "))
+			turn.Words = len(words)
+			turns = append(turns, turn)
+			buffer = buffer[:0]
+		}
+	}
 
-	for i := 0; i < len(rawLines); i++ {
-		line := rawLines[i]
+	for _, line := range rawLines {
 		if line == "" {
-			finalLines = append(finalLines, "")
+			continue
+		}
+
+		// Markdown structure (list items, headings) is kept verbatim
+		if strings.HasPrefix(line, "* ") || strings.HasPrefix(line, "#") {
+			flush()
+			finalLines = append(finalLines, line)
 			continue
 		}
 
@@ -217,9 +169,20 @@ func HTMLToMarkdown(html string, epNum int, dateYMD string) string {
 		foundNewMetadata := false
 
 		// Try matching speaker/timestamp patterns
-		if matches := tsPattern1.FindStringSubmatch(line); len(matches) > 1 && len(line) > 0 && line[0] >= '0' && line[0] <= '9' {
+		if matches := tsPattern1.FindStringSubmatch(line); len(matches) > 2 && line[0] >= '0' && line[0] <= '9' {
 			currentTimestamp = matches[1]
-			content = strings.TrimSpace(matches[2])
+			potentialSpeaker := strings.TrimSpace(matches[2])
+			if len(potentialSpeaker) < 40 && !strings.ContainsAny(potentialSpeaker, ".!?") {
+				if potentialSpeaker != "" {
+					currentSpeaker = potentialSpeaker
+				}
+				content = strings.TrimSpace(matches[3])
+			} else {
+				content = potentialSpeaker
+				if matches[3] != "" {
+					content += ": " + matches[3]
+				}
+			}
 			foundNewMetadata = true
 		} else if matches := tsPattern2.FindStringSubmatch(line); len(matches) > 3 {
 			currentSpeaker = strings.TrimSpace(matches[1])
@@ -241,13 +204,8 @@ func HTMLToMarkdown(html string, epNum int, dateYMD string) string {
 			foundNewMetadata = true
 		}
 
-		if foundNewMetadata && strings.HasPrefix(content, ":") {
-			content = strings.TrimSpace(content[1:])
-		}
-
-		// If line only contains metadata, don't output yet, just keep the context
-		if foundNewMetadata && content == "" {
-			continue
+		if foundNewMetadata {
+			content = strings.TrimSpace(strings.TrimLeft(content, ": "))
 		}
 
 		// Build prefix
@@ -261,41 +219,64 @@ func HTMLToMarkdown(html string, epNum int, dateYMD string) string {
 			prefix += " -"
 		}
 
+		if foundNewMetadata {
+			flush()
+			turnPrefix = prefix
+			turn = Turn{Speaker: currentSpeaker, Timecode: currentTimestamp}
+		} else if turnPrefix == "" {
+			turnPrefix = prefix
+			turn = Turn{Speaker: currentSpeaker, Timecode: currentTimestamp}
+		}
 		if content != "" {
-			finalLines = append(finalLines, prefix+" "+content)
-		} else {
-			finalLines = append(finalLines, prefix)
+			buffer = append(buffer, content)
 		}
 	}
+	flush()
+
+	if aiGenerated {
+		finalLines = append([]string{AIGeneratedTag}, finalLines...)
+	}
 
-	return strings.TrimSpace(strings.Join(finalLines, "\n"))
+	return strings.TrimSpace(strings.Join(finalLines, "\n")), turns, strict
 }
 
 // ParseTranscriptFile extracts title, date, year and body from a file
 func ParseTranscriptFile(path string) (string, string, int, string, error) {
-	contentBytes, err := os.ReadFile(path)
+	contentBytes, err := storage.ReadFile(path)
 	if err != nil {
 		return "", "", 0, "", err
 	}
-	html := string(contentBytes)
+	p := parseTranscript(path, ExtractPage(string(contentBytes)))
+	return p.title, p.dateStr, p.year, p.content, nil
+}
+
+// parsedTranscript is what parseTranscript reads from a page
+type parsedTranscript struct {
+	title, dateStr string
+	year           int
+	content        string
+	turns          []Turn
+	strict         bool // The body needed the strict markup pass
+}
 
+// parseTranscript extracts title, date, year, body and speaker turns from a
+// page's extracted parts
+func parseTranscript(path string, x Extraction) parsedTranscript {
 	title := "Unknown Episode"
-	if matches := postTitleRegex.FindStringSubmatch(html); len(matches) > 1 {
-		title = strings.TrimSpace(matches[1])
+	if x.Title != "" {
+		title = strings.TrimSpace(applyTextFilters(Normalize.Apply(x.Title)))
 	}
 
 	dateStr := "Unknown Date"
-	if matches := bylineRegex.FindStringSubmatch(html); len(matches) > 1 {
-		dateStr = strings.TrimSpace(matches[1])
+	if x.Byline != "" {
 		// normalize whitespace
-		dateStr = strings.Join(strings.Fields(dateStr), " ")
+		dateStr = strings.Join(strings.Fields(x.Byline), " ")
 	}
 	year := extractYear(dateStr)
-
-	rawBody := ""
-	if matches := bodyContentRegex.FindStringSubmatch(html); len(matches) > 1 {
-		rawBody = matches[1]
+	if t, ok := ParseDate(dateStr); ok {
+		year = t.Year()
 	}
+	rawBody := x.Body
 
 	epNum := GetEpNum(path)
 	// Fallback: extract episode number from title if filename-based extraction returned 0
@@ -304,108 +285,68 @@ func ParseTranscriptFile(path string) (string, string, int, string, error) {
 	}
 	dateYMD := parseDateYMD(dateStr)
 
-	return title, dateStr, year, HTMLToMarkdown(rawBody, epNum, dateYMD), nil
+	md, turns, strict := convertTranscript(rawBody, epNum, dateYMD)
+	return parsedTranscript{title: title, dateStr: dateStr, year: year, content: md, turns: turns, strict: strict}
 }
 
-func GetEpNum(filename string) int {
-	matches := episodeNumberRegex.FindStringSubmatch(filename)
-	if len(matches) > 1 {
-		val, _ := strconv.Atoi(matches[1])
-		return val
+// CheckPage reports pages that cannot be transcripts: errs.ErrTruncated if
+// the document was cut off before its closing </html> tag, and errs.ErrParse
+// if no extraction strategy finds a title or transcript body in it
+func CheckPage(html string) error {
+	lower := strings.ToLower(html)
+	if strings.Contains(lower, "<html") && !strings.Contains(lower, "</html>") {
+		return fmt.Errorf("%w: no closing </html> tag", errs.ErrTruncated)
 	}
-	return 0
-}
-
-func ProcessPrefix(prefix, dataDir, outputBase string, byYear bool) error {
-	files, err := filepath.Glob(filepath.Join(dataDir, fmt.Sprintf("%s_*.html", prefix)))
-	if err != nil {
-		return err
+	if ExtractPage(html).Strategy == "" {
+		return fmt.Errorf("%w: no transcript title or body", errs.ErrParse)
 	}
+	return nil
+}
 
-	if len(files) == 0 {
-		fmt.Printf("No files found for prefix: %s\n", prefix)
-		return nil
+// PublishedDate reads the byline date from a transcript page
+func PublishedDate(html string) (time.Time, bool) {
+	byline := ExtractPage(html).Byline
+	if byline == "" {
+		return time.Time{}, false
 	}
+	return ParseDate(byline)
+}
 
-	// Sort by episode number
-	sort.Slice(files, func(i, j int) bool {
-		return GetEpNum(files[i]) < GetEpNum(files[j])
-	})
-
-	fmt.Printf("Processing %d files for %s (By Year: %v)...\n", len(files), prefix, byYear)
-
-	currentWordCount := 0
-	currentByteCount := 0
-	var currentChunk []string
-	var chunkStartEp, chunkEndEp int
-	currentChunkYear := -1
-	firstInChunk := true
-
-	for _, fpath := range files {
-		epNum := GetEpNum(fpath)
-		title, dateStr, epYear, content, err := ParseTranscriptFile(fpath)
-		if err != nil {
-			fmt.Printf("Error processing %s: %v. Skipping.\n", fpath, err)
-			continue
-		}
-
-		epText := fmt.Sprintf("# Episode: %s\n**Date:** %s\n\n%s\n\n---\n\n", title, dateStr, content)
-		epWords := len(strings.Fields(content))
-		epBytes := len([]byte(epText))
-
-		// Check if we need to split the chunk
-		splitNeeded := false
-		if (currentWordCount+epWords > MaxWords) || (currentByteCount+epBytes > MaxBytes) {
-			splitNeeded = true
-		} else if byYear && currentChunkYear != -1 && epYear != currentChunkYear {
-			splitNeeded = true
-		}
-
-		if splitNeeded && !firstInChunk {
-			writeChunk(outputBase, prefix, chunkStartEp, chunkEndEp, currentChunkYear, currentChunk, byYear)
-
-			// Reset
-			currentChunk = []string{}
-			currentWordCount = 0
-			currentByteCount = 0
-			firstInChunk = true
-		}
-
-		if firstInChunk {
-			chunkStartEp = epNum
-			currentChunkYear = epYear
-			firstInChunk = false
+// MediaURLs returns the audio and video files linked from a transcript page,
+// in page order without duplicates
+func MediaURLs(html string) []string {
+	seen := make(map[string]bool)
+	var urls []string
+	for _, m := range mediaURLRegex.FindAllStringSubmatch(html, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			urls = append(urls, m[1])
 		}
-
-		currentChunk = append(currentChunk, epText)
-		currentWordCount += epWords
-		currentByteCount += epBytes
-		chunkEndEp = epNum
-	}
-
-	if len(currentChunk) > 0 {
-		writeChunk(outputBase, prefix, chunkStartEp, chunkEndEp, currentChunkYear, currentChunk, byYear)
 	}
+	return urls
+}
 
-	return nil
+// IsAudioURL reports whether a media URL points at an audio file
+func IsAudioURL(u string) bool {
+	return audioURLRegex.MatchString(u)
 }
 
-func writeChunk(base, prefix string, start, end, year int, content []string, byYear bool) {
-	var filename string
-	if byYear && year > 0 {
-		filename = filepath.Join(base, fmt.Sprintf("%s_Transcripts_%d_%d_%d.md", prefix, year, start, end))
-	} else {
-		filename = filepath.Join(base, fmt.Sprintf("%s_Transcripts_%d-%d.md", prefix, start, end))
-	}
+// PageTitle reads the post title from a transcript page
+func PageTitle(html string) string {
+	return ExtractPage(html).Title
+}
 
-	f, err := os.Create(filename)
-	if err != nil {
-		fmt.Printf("Error creating %s: %v\n", filename, err)
-		return
+func GetEpNum(filename string) int {
+	matches := episodeNumberRegex.FindStringSubmatch(filename)
+	if len(matches) > 1 {
+		val, _ := strconv.Atoi(matches[1])
+		return val
 	}
-	defer f.Close()
+	return 0
+}
 
-	fullText := strings.Join(content, "")
-	f.WriteString(fullText)
-	fmt.Printf("Written %s (Words: approx %d, Bytes: %d)\n", filename, len(strings.Fields(fullText)), len([]byte(fullText)))
+// ProcessPrefix combines all transcripts for a prefix into Markdown chunks,
+// splitting between episodes by size (and optionally year)
+func ProcessPrefix(prefix, dataDir, outputBase string, byYear bool) error {
+	return ProcessPrefixWithOptions(prefix, dataDir, outputBase, Options{ByYear: byYear})
 }
@@ -1,10 +1,16 @@
 package converter
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/errs"
 )
 
 func TestHTMLToMarkdown(t *testing.T) {
@@ -97,6 +103,24 @@ func TestHTMLToMarkdownPattern5SpeakerNoTimestamp(t *testing.T) {
 	}
 }
 
+func TestConvertTranscriptTurns(t *testing.T) {
+	input := "<p>Please be advised this transcript is AI-generated and may not be word for word.</p>\n" +
+		"<h2>Intro</h2>\n<p>00:00:52 - Leo Laporte\nHello there</p>\n<p>continued line</p>\n" +
+		"<p>Steve Gibson (00:01:10): Hi Leo.</p>\n<p>(00:02:00): Nobody said this.</p>"
+	md, turns, _ := convertTranscript(input, 950, "22-05-10")
+	want := []Turn{
+		{Speaker: "Leo Laporte", Timecode: "00:00:52", Words: 4},
+		{Speaker: "Steve Gibson", Timecode: "00:01:10", Words: 2},
+		{Speaker: "Steve Gibson", Timecode: "00:02:00", Words: 3},
+	}
+	if !reflect.DeepEqual(turns, want) {
+		t.Errorf("Turns = %+v, want %+v", turns, want)
+	}
+	if n := strings.Count(md, "EP:950"); n != len(turns) {
+		t.Errorf("%d turn lines for %d turns:\n%s", n, len(turns), md)
+	}
+}
+
 func TestExtractEpFromTitle(t *testing.T) {
 	tests := []struct {
 		title    string
@@ -223,3 +247,127 @@ func TestProcessPrefixByYear(t *testing.T) {
 		t.Errorf("Expected 2025 output file, found %d", len(files2025))
 	}
 }
+
+func TestLoadEpisode(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "episodetest")
+	defer os.RemoveAll(tmpDir)
+
+	os.WriteFile(filepath.Join(tmpDir, "SN_1000.html"), []byte(`
+		<h1 class="post-title">Security Now 1000 Transcript</h1>
+		<p class="byline">Nov 19th 2024</p>
+		<div class="body textual">Steve Gibson: Hello</div>
+	`), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "TWIG_5.html"), []byte(`<h1 class="post-title">TWiG 5</h1>`), 0644)
+
+	ep, err := LoadEpisode(filepath.Join(tmpDir, "SN_1000.html"))
+	if err != nil {
+		t.Fatalf("LoadEpisode failed: %v", err)
+	}
+	if ep.Prefix != "SN" || ep.Number != 1000 || ep.Year != 2024 {
+		t.Errorf("Unexpected episode metadata: %+v", ep)
+	}
+	if ep.Date.Format("2006-01-02") != "2024-11-19" {
+		t.Errorf("Expected parsed date 2024-11-19, got %v", ep.Date)
+	}
+
+	// TWIG became IM, so its episodes are listed under the current prefix
+	prefixes, _ := ListPrefixes(tmpDir)
+	if strings.Join(prefixes, ",") != "IM,SN" {
+		t.Errorf("ListPrefixes = %v", prefixes)
+	}
+	config.SplitByEra = true
+	prefixes, _ = ListPrefixes(tmpDir)
+	config.SplitByEra = false
+	if strings.Join(prefixes, ",") != "SN,TWIG" {
+		t.Errorf("ListPrefixes with SplitByEra = %v", prefixes)
+	}
+
+	os.WriteFile(filepath.Join(tmpDir, "XYZ_1.html"), []byte(`<h1 class="post-title">XYZ 1</h1>`), 0644)
+	for arg, want := range map[string]string{"security now": "SN", "twig": "IM", "xyz": "XYZ"} {
+		if got, err := ResolvePrefix(arg, tmpDir); err != nil || got != want {
+			t.Errorf("ResolvePrefix(%q) = %q, %v; want %q", arg, got, err, want)
+		}
+	}
+	if _, err := ResolvePrefix("abc", tmpDir); err == nil {
+		t.Error("Expected an error for an unknown, unarchived prefix")
+	}
+}
+
+func TestProcessPrefixTurnSplit(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "processtestturn")
+	defer os.RemoveAll(tmpDir)
+
+	var body strings.Builder
+	for i := 0; i < 6; i++ {
+		fmt.Fprintf(&body, "<p>Leo Laporte: turn %d one two three four five six seven eight</p>", i)
+	}
+	os.WriteFile(filepath.Join(tmpDir, "IM_1.html"), []byte(`
+		<h1 class="post-title">Ep 1</h1>
+		<p class="byline">Feb 1st 2025</p>
+		<div class="body textual">`+body.String()+`</div>
+	`), 0644)
+
+	// Each standardized turn is 15 words, so three turns fit in a chunk
+	err := ProcessPrefixWithOptions("IM", tmpDir, tmpDir, Options{Mode: ChunkByTurn, MaxWords: 50, Overlap: 1})
+	if err != nil {
+		t.Fatalf("ProcessPrefixWithOptions failed: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(tmpDir, "IM_Transcripts_1-1*.md"))
+	if len(files) != 3 {
+		t.Fatalf("Expected 3 chunks, got %v", files)
+	}
+	for _, f := range files {
+		content, _ := os.ReadFile(f)
+		for _, line := range strings.Split(string(content), "\n") {
+			if strings.HasPrefix(line, "EP:") && !strings.HasSuffix(line, "seven eight") {
+				t.Errorf("Turn was split mid-sentence in %s: %q", f, line)
+			}
+		}
+	}
+
+	second, _ := os.ReadFile(filepath.Join(tmpDir, "IM_Transcripts_1-1_part2.md"))
+	if !strings.Contains(string(second), "(continued)") || !strings.Contains(string(second), "turn 2 ") {
+		t.Errorf("Second chunk should continue the episode with one overlapping turn:\n%s", second)
+	}
+}
+
+func TestTopicSegments(t *testing.T) {
+	c := &chunker{opts: Options{Mode: ChunkByTopic, MaxWords: 1000, MaxBytes: 100000}}
+	content := strings.Join([]string{
+		"EP:1 Date:25-01-01 - Leo Hello",
+		"EP:1 Date:25-01-01 - Jeff Hi",
+		"EP:1 Date:25-01-01 - Leo Let's take a break and talk about our sponsor",
+		"EP:1 Date:25-01-01 - Leo Back to the news",
+	}, "\n")
+	segs := c.segments(content)
+	if len(segs) != 2 || len(segs[0]) != 2 {
+		t.Errorf("Expected a segment boundary at the break marker, got %v", segs)
+	}
+}
+
+func TestMediaURLs(t *testing.T) {
+	html := `<a href="https://cdn.example.com/sn0950.mp3">MP3</a>
+<video src="https://cdn.example.com/sn0950_h264m.mp4?x=1"></video>
+<a href="https://cdn.example.com/sn0950.mp3">Again</a>
+<a href="https://example.com/page.html">Not media</a>`
+	urls := MediaURLs(html)
+	if len(urls) != 2 || urls[0] != "https://cdn.example.com/sn0950.mp3" || urls[1] != "https://cdn.example.com/sn0950_h264m.mp4?x=1" {
+		t.Errorf("Unexpected media URLs: %v", urls)
+	}
+	if !IsAudioURL(urls[0]) || IsAudioURL(urls[1]) {
+		t.Error("IsAudioURL misclassified a URL")
+	}
+}
+
+func TestCheckPage(t *testing.T) {
+	if err := CheckPage(`<html><h1 class="post-title">SN 1</h1></html>`); err != nil {
+		t.Errorf("Expected a valid page, got %v", err)
+	}
+	if err := CheckPage(`<html><h1 class="post-title">SN 1</h1><div class="body textual">Steve`); !errors.Is(err, errs.ErrTruncated) {
+		t.Errorf("Expected ErrTruncated, got %v", err)
+	}
+	if err := CheckPage(`<html><body>Access denied</body></html>`); !errors.Is(err, errs.ErrParse) {
+		t.Errorf("Expected ErrParse, got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package converter
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+)
+
+var (
+	// zonedDateLayouts are timestamps that carry their own zone, converted
+	// to config.Timezone
+	zonedDateLayouts = []string{
+		time.RFC3339,
+		time.RFC1123Z,
+		time.RFC1123,
+	}
+	// dateLayouts are read in config.Timezone, once cleanDate has removed
+	// weekdays, ordinal suffixes, commas and abbreviation dots
+	dateLayouts = []string{
+		"January 2 2006",
+		"Jan 2 2006",
+		"2 January 2006",
+		"2 Jan 2006",
+		"2006-01-02",
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05",
+		"2006/01/02",
+		"1/2/2006", // US order, as on the site
+	}
+
+	weekdayRegex = regexp.MustCompile(`(?i)^(?:mon|tue|tues|wed|thu|thur|thurs|fri|sat|sun)(?:day|nesday|urday|sday)?\.?,?\s+`)
+	// dayOrdinalRegex matches a day's ordinal suffix ("6th", "21st")
+	dayOrdinalRegex = regexp.MustCompile(`(?i)\b(\d{1,2})(?:st|nd|rd|th)\b`)
+	// monthDotRegex matches an abbreviated month's dot ("Feb.")
+	monthDotRegex = regexp.MustCompile(`(?i)\b([a-z]{3,4})\.`)
+	septRegex     = regexp.MustCompile(`(?i)\bsept\b`)
+	// bylineDateRegex finds a date in a longer byline ("By Leo Laporte on
+	// Feb 6th, 2024 at 10am")
+	bylineDateRegex = regexp.MustCompile(`(?i)\b(?:[a-z]{3,9}\.? \d{1,2}(?:st|nd|rd|th)?,? \d{4}|\d{1,2}(?:st|nd|rd|th)? [a-z]{3,9}\.?,? \d{4}|\d{4}-\d{2}-\d{2})\b`)
+)
+
+// ParseDate reads a date as written in bylines, feeds and on the command
+// line: "Feb 6th 2024", "February 6, 2024", "Tuesday, 6 Feb. 2024",
+// "2024-02-06", RFC 3339 and RFC 1123 timestamps, or a byline holding one
+// of these among other words. Dates are returned in config.Timezone; false
+// means no layout matched.
+func ParseDate(s string) (time.Time, bool) {
+	s = strings.Join(strings.Fields(s), " ")
+	if s == "" || s == "Unknown Date" {
+		return time.Time{}, false
+	}
+	for _, layout := range zonedDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.In(config.Timezone), true
+		}
+	}
+	if t, ok := parseInZone(cleanDate(s)); ok {
+		return t, true
+	}
+	if m := bylineDateRegex.FindString(s); m != "" && m != s {
+		return parseInZone(cleanDate(m))
+	}
+	return time.Time{}, false
+}
+
+// cleanDate reduces a written date to the forms of dateLayouts
+func cleanDate(s string) string {
+	s = weekdayRegex.ReplaceAllString(s, "")
+	s = dayOrdinalRegex.ReplaceAllString(s, "$1")
+	s = monthDotRegex.ReplaceAllString(s, "$1")
+	s = septRegex.ReplaceAllString(s, "Sep")
+	return strings.Join(strings.Fields(strings.ReplaceAll(s, ",", " ")), " ")
+}
+
+func parseInZone(s string) (time.Time, bool) {
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, config.Timezone); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,61 @@
+package converter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+)
+
+func TestParseDate(t *testing.T) {
+	for _, s := range []string{
+		"Feb 6th 2024",
+		"Feb 6 2024",
+		"Feb 06 2024",
+		"Feb. 6th, 2024",
+		"February 6, 2024",
+		"february 6th 2024",
+		"Tuesday, February 6, 2024",
+		"Tue, 6 Feb 2024",
+		"6 February 2024",
+		"2024-02-06",
+		"2024/02/06",
+		"2/6/2024",
+		"2024-02-06T10:30:00",
+		"By Leo Laporte on Feb 6th, 2024 at 10am",
+	} {
+		got, ok := ParseDate(s)
+		if !ok || got.Format("2006-01-02") != "2024-02-06" {
+			t.Errorf("ParseDate(%q) = %v, %v; want 2024-02-06", s, got, ok)
+		}
+	}
+	if got, ok := ParseDate("Sept 30th 2023"); !ok || got.Format("2006-01-02") != "2023-09-30" {
+		t.Errorf("ParseDate(Sept) = %v, %v", got, ok)
+	}
+	for _, s := range []string{"", "Unknown Date", "soon", "Feb 30 2024", "2024"} {
+		if got, ok := ParseDate(s); ok {
+			t.Errorf("ParseDate(%q) = %v; want no date", s, got)
+		}
+	}
+}
+
+func TestParseDateTimezone(t *testing.T) {
+	defer func() { config.Timezone = time.UTC }()
+	if err := config.SetTimezone("America/Los_Angeles"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Zoned timestamps are moved to the configured zone
+	got, ok := ParseDate("2024-02-07T03:00:00Z")
+	if !ok || got.Format("2006-01-02") != "2024-02-06" || got.Location() != config.Timezone {
+		t.Errorf("ParseDate(RFC 3339) = %v, %v; want Feb 6 in Los Angeles", got, ok)
+	}
+	// Plain dates are midnight there
+	got, _ = ParseDate("Feb 6th 2024")
+	if got.Location() != config.Timezone || got.Hour() != 0 {
+		t.Errorf("ParseDate = %v; want midnight in Los Angeles", got)
+	}
+	if f, _ := NewFilter("", "2024-02-06", "2024-02-06"); !f.MatchDate(got) {
+		t.Error("Date filters should compare in the configured zone")
+	}
+}
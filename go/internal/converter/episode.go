@@ -0,0 +1,176 @@
+package converter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// Episode is a parsed transcript together with its metadata
+type Episode struct {
+	Prefix  string
+	Number  int
+	Title   string
+	DateStr string    // Byline date as published
+	Date    time.Time // Zero if the byline could not be parsed
+	Year    int
+	Content string    // Standardized Markdown body
+	Turns   []Turn    // Speaker turns, one per turn line of Content
+	Path    string    // Source HTML file
+	URL     string    // Where the page was downloaded from, else the canonical URL it declares
+	Fetched time.Time // When the page was downloaded, zero if unknown
+	// Canonical is the canonical URL the page declares, else where it was
+	// downloaded from; pages republished under a new address share it
+	Canonical string
+	// Origin is what the page was generated from (OriginFeed,
+	// OriginCaptions, OriginASR), "" for a transcript page from the site
+	Origin string
+	Media  []string // Audio/video URLs linked from the page
+	Notes  ShowNotes
+	Roster Roster
+	// Language is the detected ISO 639-1 code ("en"), or "" if unknown
+	Language string
+	// Extraction names the strategy that found the transcript body: a
+	// PageLayout name or StrategyReadability
+	Extraction string
+	// StrictMarkup is set when HTML was left over after the usual markup
+	// pass and the body was converted again with the strict one
+	// (see renderBody). Residual lists what is still left (see ResidualHTML).
+	StrictMarkup bool
+	Residual     []string
+	// Pending is set for placeholder pages published before the transcript
+	Pending bool
+	// Summary is the generated abstract, when one was attached from the
+	// index (see twit-archiver summarize)
+	Summary *Summary
+}
+
+// Summary is a generated abstract and topic list for an episode
+type Summary struct {
+	Abstract string   `json:"abstract"`
+	Topics   []string `json:"topics,omitempty"`
+	Model    string   `json:"model,omitempty"`
+	// Source identifies the transcript version that was summarized
+	Source string `json:"source,omitempty"`
+}
+
+// LoadEpisode parses a single transcript file into an Episode
+func LoadEpisode(path string) (Episode, error) {
+	html, err := storage.ReadFile(path)
+	if err != nil {
+		return Episode{}, err
+	}
+	if err := CheckPage(string(html)); err != nil {
+		return Episode{}, err
+	}
+	x := ExtractPage(string(html))
+	p := parseTranscript(path, x)
+	base := storage.Base(path)
+	ep := Episode{
+		Number:  GetEpNum(base),
+		Title:   p.title,
+		DateStr: p.dateStr,
+		Year:    p.year,
+		Content: p.content,
+		Turns:   p.turns,
+		Path:    path,
+		URL:     PageURL(string(html)),
+		Fetched: fetchedAt(path, string(html)),
+		Origin:  PageOrigin(string(html)),
+		Media:   MediaURLs(string(html)),
+		Notes:   ExtractShowNotes(string(html)),
+		Roster:  ExtractRoster(string(html)),
+
+		Extraction:   x.Strategy,
+		StrictMarkup: p.strict,
+		Residual:     ResidualHTML(p.content),
+		Pending:      placeholderBody(x.Body),
+	}
+	if m := config.PrefixRegex.FindStringSubmatch(base); len(m) > 1 {
+		ep.Prefix = m[1]
+	}
+	ep.Canonical = ep.URL
+	if u, _, ok := PageSource(string(html)); ok {
+		ep.URL = u
+		if ep.Canonical == "" {
+			ep.Canonical = u
+		}
+	}
+	if ep.Number == 0 {
+		ep.Number = extractEpFromTitle(p.title)
+	}
+	if t, ok := ParseDate(p.dateStr); ok {
+		ep.Date = t
+	}
+	ep.Language = DetectLanguage(p.content)
+	return ep, nil
+}
+
+// LoadEpisodes parses every transcript for a prefix, sorted by episode number.
+// Files that fail to parse are reported and skipped.
+func LoadEpisodes(prefix, dataDir string) ([]Episode, error) {
+	files, err := config.ActiveLayout.RawFiles(dataDir, prefix)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return GetEpNum(files[i]) < GetEpNum(files[j])
+	})
+
+	var episodes []Episode
+	for _, f := range files {
+		ep, err := LoadEpisode(f)
+		if err != nil {
+			fmt.Printf("Error processing %s: %v. Skipping.\n", f, err)
+			continue
+		}
+		episodes = append(episodes, ep)
+	}
+	return episodes, nil
+}
+
+// ListPrefixes returns the sorted show prefixes present in the data
+// directory, with earlier eras of renamed shows under their current prefix
+func ListPrefixes(dataDir string) ([]string, error) {
+	files, err := storage.Glob(config.ActiveLayout.RawGlob(dataDir, "*"))
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var prefixes []string
+	for _, f := range files {
+		matches := config.PrefixRegex.FindStringSubmatch(storage.Base(f))
+		if len(matches) < 2 {
+			continue
+		}
+		if prefix := config.CanonicalPrefix(matches[1]); !seen[prefix] {
+			seen[prefix] = true
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	sort.Strings(prefixes)
+	return prefixes, nil
+}
+
+// ResolvePrefix maps a show argument, given as a prefix or show name, to its
+// prefix. Prefixes outside the show catalog are accepted if the data
+// directory has transcripts for them; otherwise the error suggests the
+// closest known shows.
+func ResolvePrefix(arg, dataDir string) (string, error) {
+	if prefix, ok := config.ResolveShow(arg); ok {
+		return prefix, nil
+	}
+	prefix := strings.ToUpper(strings.TrimSpace(arg))
+	if local, err := ListPrefixes(dataDir); err == nil {
+		for _, p := range local {
+			if p == prefix {
+				return prefix, nil
+			}
+		}
+	}
+	return "", config.UnknownShowError(arg)
+}
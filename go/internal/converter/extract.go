@@ -0,0 +1,174 @@
+package converter
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+)
+
+// PageLayout is one generation of the site's transcript page markup: the
+// patterns whose first group captures the title, byline and body. A nil
+// pattern is skipped.
+type PageLayout struct {
+	Name                string
+	Title, Byline, Body *regexp.Regexp
+}
+
+// PageLayouts are tried in order for each part of a page. Pages from before
+// the current design (pre-2015) use Drupal class names.
+var PageLayouts = []PageLayout{
+	{Name: "current", Title: postTitleRegex, Byline: bylineRegex, Body: bodyContentRegex},
+	{
+		Name:   "legacy",
+		Title:  regexp.MustCompile(`(?s)<h[12][^>]*class="[^"]*\b(?:page-title|node-title|entry-title|title)\b[^"]*"[^>]*>(.*?)</h[12]>`),
+		Byline: regexp.MustCompile(`(?s)<(?:span|div|p)[^>]*class="[^"]*\b(?:date-display-single|submitted|post-date|entry-date)\b[^"]*"[^>]*>(.*?)</(?:span|div|p)>`),
+		Body:   regexp.MustCompile(`(?s)<div[^>]*class="[^"]*\b(?:field-name-body|field-item|node-content|entry-content|transcript)\b[^"]*"[^>]*>(.*?)</div>`),
+	},
+}
+
+// pageLayouts returns PageLayouts, preceded by the patterns of
+// config.ActiveSource if one is set: its first title, byline and body
+// patterns form the first layout, the second ones the next, and so on.
+// Layouts a source has fewer patterns for leave those parts nil.
+func pageLayouts() []PageLayout {
+	s := config.ActiveSource
+	if s == nil {
+		return PageLayouts
+	}
+	var layouts []PageLayout
+	for i := 0; i < len(s.Title) || i < len(s.Byline) || i < len(s.Body); i++ {
+		l := PageLayout{Name: s.Name}
+		if i < len(s.Title) {
+			l.Title = s.Title[i]
+		}
+		if i < len(s.Byline) {
+			l.Byline = s.Byline[i]
+		}
+		if i < len(s.Body) {
+			l.Body = s.Body[i]
+		}
+		layouts = append(layouts, l)
+	}
+	return append(layouts, PageLayouts...)
+}
+
+// StrategyReadability names main-content extraction, used when no layout
+// finds a transcript body
+const StrategyReadability = "readability"
+
+// minReadableText is the least text (in characters) main-content extraction
+// accepts as a transcript body
+const minReadableText = 500
+
+var (
+	// Elements that never hold the transcript
+	boilerplateRegex = regexp.MustCompile(`(?is)<script\b.*?</script>|<style\b.*?</style>|<nav\b.*?</nav>|<header\b.*?</header>|<footer\b.*?</footer>|<aside\b.*?</aside>|<form\b.*?</form>`)
+	paragraphRegex   = regexp.MustCompile(`(?is)<p\b[^>]*>.*?</p>`)
+	htmlTitleRegex   = regexp.MustCompile(`(?is)<title>(.*?)</title>`)
+	anyH1Regex       = regexp.MustCompile(`(?is)<h1\b[^>]*>(.*?)</h1>`)
+	timeTagRegex     = regexp.MustCompile(`(?is)<time[^>]+datetime="(\d{4}-\d{2}-\d{2})`)
+	publishedRegex   = regexp.MustCompile(`(?i)<meta[^>]+property="article:published_time"[^>]+content="(\d{4}-\d{2}-\d{2})`)
+	// Site name appended to the <title> ("... | TWiT.TV")
+	titleSiteRegex = regexp.MustCompile(`\s*[|\-–]\s*TWiT(?:\.tv)?\s*$`)
+)
+
+// Extraction is the raw HTML of a page's parts, and the strategy that found
+// its body: a PageLayout name, StrategyReadability, the name of the layout
+// that found only the title, or "" if the page does not look like a
+// transcript at all
+type Extraction struct {
+	Title, Byline, Body string
+	Strategy            string
+}
+
+// ExtractPage finds the title, byline and transcript body of a page. Each
+// part comes from the first layout that matches it, so a page mixing old
+// and new markup still parses. Without a layout body, the densest run of
+// paragraphs is taken as the body; without a layout title, the document
+// title or first heading.
+func ExtractPage(html string) Extraction {
+	var x Extraction
+	titleLayout := ""
+	for _, l := range pageLayouts() {
+		if x.Title == "" && l.Title != nil {
+			if m := l.Title.FindStringSubmatch(html); m != nil {
+				x.Title = m[1]
+				titleLayout = l.Name
+			}
+		}
+		if x.Byline == "" && l.Byline != nil {
+			if m := l.Byline.FindStringSubmatch(html); m != nil {
+				x.Byline = m[1]
+			}
+		}
+		if x.Strategy == "" && l.Body != nil {
+			if m := l.Body.FindStringSubmatch(html); m != nil {
+				x.Body, x.Strategy = m[1], l.Name
+			}
+		}
+	}
+
+	if x.Strategy == "" {
+		if body := mainContent(html); body != "" {
+			x.Body, x.Strategy = body, StrategyReadability
+		} else {
+			x.Strategy = titleLayout
+		}
+	}
+	if x.Title == "" {
+		if m := htmlTitleRegex.FindStringSubmatch(html); m != nil {
+			x.Title = titleSiteRegex.ReplaceAllString(m[1], "")
+		} else if m := anyH1Regex.FindStringSubmatch(html); m != nil {
+			x.Title = m[1]
+		}
+	}
+	if x.Byline == "" {
+		m := timeTagRegex.FindStringSubmatch(html)
+		if m == nil {
+			m = publishedRegex.FindStringSubmatch(html)
+		}
+		if m != nil {
+			// In the bylines' own format, as shown in the output
+			if t, err := time.Parse("2006-01-02", m[1]); err == nil {
+				x.Byline = t.Format("Jan 02 2006")
+			}
+		}
+	}
+	x.Title = strings.TrimSpace(anyTagRegex.ReplaceAllString(x.Title, ""))
+	return x
+}
+
+// mainContent returns the paragraphs of the page's largest block of running
+// text, readability-style: paragraphs separated only by markup or a little
+// text belong to the same block. Returns "" if no block is long enough to be
+// a transcript.
+func mainContent(html string) string {
+	html = boilerplateRegex.ReplaceAllString(html, "")
+	locs := paragraphRegex.FindAllStringIndex(html, -1)
+	textLen := func(s string) int {
+		return len(strings.TrimSpace(anyTagRegex.ReplaceAllString(s, "")))
+	}
+
+	bestStart, bestEnd, bestScore := -1, -1, 0
+	start, score := 0, 0
+	for i, loc := range locs {
+		if i > 0 && textLen(html[locs[i-1][1]:loc[0]]) > 20 {
+			start, score = i, 0
+		}
+		score += textLen(html[loc[0]:loc[1]])
+		if score > bestScore {
+			bestStart, bestEnd, bestScore = start, i, score
+		}
+	}
+	if bestScore < minReadableText {
+		return ""
+	}
+	var b strings.Builder
+	for _, loc := range locs[bestStart : bestEnd+1] {
+		b.WriteString(html[loc[0]:loc[1]])
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,109 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+)
+
+func TestExtractPageLegacy(t *testing.T) {
+	html := `<html><head><title>Security Now 120 | TWiT.TV</title></head><body>
+<h1 class="title"><a href="/sn/120">Security Now 120: Rootkits</a></h1>
+<span class="date-display-single">December 07 2007</span>
+<div class="field field-name-body"><div class="field-item even"><p>Leo Laporte: It's time for Security Now!</p></div></div>
+</body></html>`
+	x := ExtractPage(html)
+	if x.Strategy != "legacy" {
+		t.Errorf("Strategy = %q, want legacy", x.Strategy)
+	}
+	if x.Title != "Security Now 120: Rootkits" {
+		t.Errorf("Title = %q", x.Title)
+	}
+	if x.Byline != "December 07 2007" {
+		t.Errorf("Byline = %q", x.Byline)
+	}
+	if !strings.Contains(x.Body, "It's time for Security Now!") {
+		t.Errorf("Body = %q", x.Body)
+	}
+	if d, ok := PublishedDate(html); !ok || d.Year() != 2007 {
+		t.Errorf("PublishedDate = %v, %v", d, ok)
+	}
+}
+
+func TestExtractPageSource(t *testing.T) {
+	config.ActiveSource = &config.Source{
+		Name:  "maxfun",
+		Title: []*regexp.Regexp{regexp.MustCompile(`<h1 class="entry">(.*?)</h1>`)},
+		Body: []*regexp.Regexp{
+			regexp.MustCompile(`(?s)<section class="transcript">(.*?)</section>`),
+			regexp.MustCompile(`(?s)<div id="old-transcript">(.*?)</div>`),
+		},
+	}
+	defer func() { config.ActiveSource = nil }()
+
+	html := `<h1 class="entry">Jordan Jesse Go 12</h1><time datetime="2024-02-06">Feb 6</time>
+<div id="old-transcript"><p>Jordan: Hello</p></div>`
+	x := ExtractPage(html)
+	if x.Strategy != "maxfun" || x.Title != "Jordan Jesse Go 12" || !strings.Contains(x.Body, "Jordan: Hello") {
+		t.Errorf("ExtractPage = %+v", x)
+	}
+	// Parts the source has no pattern for come from the built-in extraction
+	if x.Byline != "Feb 06 2024" {
+		t.Errorf("Byline = %q", x.Byline)
+	}
+}
+
+func TestExtractPageReadability(t *testing.T) {
+	para := "<p>Steve Gibson: " + strings.Repeat("We talk about certificates and browsers. ", 8) + "</p>\n"
+	html := `<html><head><title>Security Now 99 - TWiT</title>
+<meta property="article:published_time" content="2007-06-21T10:00:00Z"></head><body>
+<nav><p>` + strings.Repeat("Home Shows About ", 40) + `</p></nav>
+<div id="main">` + para + para + `</div>
+<footer><p>Copyright TWiT</p></footer></body></html>`
+
+	x := ExtractPage(html)
+	if x.Strategy != StrategyReadability {
+		t.Fatalf("Strategy = %q, want %s", x.Strategy, StrategyReadability)
+	}
+	if x.Title != "Security Now 99" {
+		t.Errorf("Title = %q", x.Title)
+	}
+	if x.Byline != "Jun 21 2007" {
+		t.Errorf("Byline = %q", x.Byline)
+	}
+	if strings.Contains(x.Body, "Home Shows") || strings.Contains(x.Body, "Copyright") {
+		t.Errorf("Body kept boilerplate: %q", x.Body)
+	}
+	if strings.Count(x.Body, "Steve Gibson:") != 2 {
+		t.Errorf("Body = %q", x.Body)
+	}
+
+	// Too little text for a transcript
+	if x := ExtractPage(`<html><body><p>Page not found</p></body></html>`); x.Strategy != "" {
+		t.Errorf("Strategy = %q for an error page", x.Strategy)
+	}
+}
+
+func TestLoadEpisodeExtraction(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sn-0120.html")
+	html := `<html><h1 class="node-title">Security Now 120</h1>
+<div class="node-content"><p>Leo Laporte: Hello.</p></div></html>`
+	if err := os.WriteFile(path, []byte(html), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ep, err := LoadEpisode(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ep.Extraction != "legacy" || ep.Title != "Security Now 120" {
+		t.Errorf("Extraction = %q, Title = %q", ep.Extraction, ep.Title)
+	}
+	if !strings.Contains(ep.Content, "Hello.") {
+		t.Errorf("Content = %q", ep.Content)
+	}
+}
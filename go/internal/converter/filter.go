@@ -0,0 +1,108 @@
+package converter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter selects a slice of a show by episode number and publication date.
+// Zero fields are unbounded.
+type Filter struct {
+	FromEp, ToEp int
+	Since, Until time.Time
+}
+
+// ParseEpisodeRange parses "900-950", "900-", "-950" or "900" into a filter's
+// episode bounds
+func ParseEpisodeRange(s string) (from, to int, err error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, 0, nil
+	}
+	lo, hi, isRange := strings.Cut(s, "-")
+	if !isRange {
+		hi = lo
+	}
+	if lo != "" {
+		if from, err = strconv.Atoi(lo); err != nil {
+			return 0, 0, fmt.Errorf("invalid episode range '%s'", s)
+		}
+	}
+	if hi != "" {
+		if to, err = strconv.Atoi(hi); err != nil {
+			return 0, 0, fmt.Errorf("invalid episode range '%s'", s)
+		}
+	}
+	if to > 0 && from > to {
+		return 0, 0, fmt.Errorf("invalid episode range '%s': start is after end", s)
+	}
+	return from, to, nil
+}
+
+// ParseFilterDate parses a date in any form ParseDate reads, such as
+// YYYY-MM-DD; an empty string is no bound
+func ParseFilterDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, ok := ParseDate(s)
+	if !ok {
+		return time.Time{}, fmt.Errorf("invalid date '%s' (want YYYY-MM-DD)", s)
+	}
+	return t, nil
+}
+
+// NewFilter builds a filter from the --episodes, --since and --until flag values
+func NewFilter(episodes, since, until string) (Filter, error) {
+	var f Filter
+	var err error
+	if f.FromEp, f.ToEp, err = ParseEpisodeRange(episodes); err != nil {
+		return f, err
+	}
+	if f.Since, err = ParseFilterDate(since); err != nil {
+		return f, err
+	}
+	if f.Until, err = ParseFilterDate(until); err != nil {
+		return f, err
+	}
+	if !f.Since.IsZero() && !f.Until.IsZero() && f.Since.After(f.Until) {
+		return f, fmt.Errorf("--since %s is after --until %s", since, until)
+	}
+	return f, nil
+}
+
+// HasDates reports whether the filter bounds the publication date
+func (f Filter) HasDates() bool {
+	return !f.Since.IsZero() || !f.Until.IsZero()
+}
+
+// MatchEpisode reports whether an episode number is in range. Unknown (zero)
+// numbers only match when no episode range is set.
+func (f Filter) MatchEpisode(n int) bool {
+	if f.FromEp == 0 && f.ToEp == 0 {
+		return true
+	}
+	return n > 0 && n >= f.FromEp && (f.ToEp == 0 || n <= f.ToEp)
+}
+
+// MatchDate reports whether a publication date is in range (Until is
+// inclusive). Unknown (zero) dates only match when no date range is set.
+func (f Filter) MatchDate(t time.Time) bool {
+	if !f.HasDates() {
+		return true
+	}
+	if t.IsZero() {
+		return false
+	}
+	if !f.Since.IsZero() && t.Before(f.Since) {
+		return false
+	}
+	return f.Until.IsZero() || t.Before(f.Until.AddDate(0, 0, 1))
+}
+
+// Match reports whether an episode passes the filter
+func (f Filter) Match(ep Episode) bool {
+	return f.MatchEpisode(ep.Number) && f.MatchDate(ep.Date)
+}
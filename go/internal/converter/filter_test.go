@@ -0,0 +1,91 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseEpisodeRange(t *testing.T) {
+	tests := []struct {
+		input    string
+		from, to int
+		wantErr  bool
+	}{
+		{"", 0, 0, false},
+		{"900-950", 900, 950, false},
+		{"900-", 900, 0, false},
+		{"-950", 0, 950, false},
+		{"42", 42, 42, false},
+		{"950-900", 0, 0, true},
+		{"abc", 0, 0, true},
+	}
+	for _, tt := range tests {
+		from, to, err := ParseEpisodeRange(tt.input)
+		if (err != nil) != tt.wantErr || from != tt.from || to != tt.to {
+			t.Errorf("ParseEpisodeRange(%q) = %d, %d, %v", tt.input, from, to, err)
+		}
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	f, err := NewFilter("10-20", "2023-01-01", "2023-12-31")
+	if err != nil {
+		t.Fatalf("NewFilter failed: %v", err)
+	}
+	date := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	tests := []struct {
+		ep   Episode
+		want bool
+	}{
+		{Episode{Number: 15, Date: date("2023-06-01")}, true},
+		{Episode{Number: 20, Date: date("2023-12-31")}, true},
+		{Episode{Number: 9, Date: date("2023-06-01")}, false},
+		{Episode{Number: 15, Date: date("2022-12-31")}, false},
+		{Episode{Number: 15, Date: date("2024-01-01")}, false},
+		{Episode{Number: 15}, false}, // Unknown date
+	}
+	for _, tt := range tests {
+		if got := f.Match(tt.ep); got != tt.want {
+			t.Errorf("Match(%d, %v) = %v, want %v", tt.ep.Number, tt.ep.Date, got, tt.want)
+		}
+	}
+
+	if !(Filter{}).Match(Episode{}) {
+		t.Error("Empty filter should match everything")
+	}
+	if _, err := NewFilter("", "2024-01-01", "2023-01-01"); err == nil {
+		t.Error("Expected an error for --since after --until")
+	}
+}
+
+func TestProcessPrefixFiltered(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "twittest")
+	defer os.RemoveAll(tmpDir)
+
+	for _, ep := range []struct{ num, date string }{{"1", "January 10th 2022"}, {"2", "March 10th 2023"}, {"3", "May 10th 2023"}, {"4", "June 10th 2024"}} {
+		os.WriteFile(filepath.Join(tmpDir, "TWIG_"+ep.num+".html"), []byte(`
+		<h1 class="post-title">Ep `+ep.num+`</h1>
+		<p class="byline">`+ep.date+`</p>
+		<div class="body textual">Content</div>`), 0644)
+	}
+
+	filter, _ := NewFilter("2-", "2023-01-01", "")
+	if err := ProcessPrefixWithOptions("TWIG", tmpDir, tmpDir, Options{Filter: filter}); err != nil {
+		t.Fatalf("ProcessPrefixWithOptions failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "TWIG_Transcripts_2-4.md")); err != nil {
+		t.Errorf("Expected chunk for episodes 2-4: %v", err)
+	}
+
+	filter, _ = NewFilter("", "", "2023-04-01")
+	os.RemoveAll(filepath.Join(tmpDir, "TWIG_Transcripts_2-4.md"))
+	ProcessPrefixWithOptions("TWIG", tmpDir, tmpDir, Options{Filter: filter})
+	if _, err := os.Stat(filepath.Join(tmpDir, "TWIG_Transcripts_1-2.md")); err != nil {
+		t.Errorf("Expected chunk for episodes 1-2: %v", err)
+	}
+}
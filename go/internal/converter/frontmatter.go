@@ -0,0 +1,77 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// canonicalURLRegex matches the page's canonical or og:url link
+var canonicalURLRegex = regexp.MustCompile(`<(?:link[^>]+rel="canonical"[^>]+href|meta[^>]+property="og:url"[^>]+content)="([^"]+)"`)
+
+// PageURL returns the canonical URL a transcript page declares, if any
+func PageURL(html string) string {
+	if m := canonicalURLRegex.FindStringSubmatch(html); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// FrontMatter renders YAML front matter for an episode, as used by Hugo,
+// Jekyll and Obsidian. Strings are written as double-quoted scalars.
+func FrontMatter(ep Episode, words int, tags []string) string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "title: %s\n", yamlString(ep.Title))
+	fmt.Fprintf(&b, "show: %s\n", yamlString(config.ShowName(ep.Prefix)))
+	fmt.Fprintf(&b, "prefix: %s\n", yamlString(ep.Prefix))
+	fmt.Fprintf(&b, "episode: %d\n", ep.Number)
+	if !ep.Date.IsZero() {
+		fmt.Fprintf(&b, "date: %s\n", ep.Date.Format("2006-01-02"))
+	}
+	if ep.URL != "" {
+		fmt.Fprintf(&b, "url: %s\n", yamlString(ep.URL))
+	}
+	if !ep.Fetched.IsZero() {
+		fmt.Fprintf(&b, "fetched: %s\n", ep.Fetched.UTC().Format(time.RFC3339))
+	}
+	fmt.Fprintf(&b, "converter: %s\n", yamlString("twit-transcript-archiver "+config.Version))
+	fmt.Fprintf(&b, "words: %d\n", words)
+	if ep.Language != "" {
+		fmt.Fprintf(&b, "language: %s\n", yamlString(ep.Language))
+	}
+	if ep.Summary != nil {
+		fmt.Fprintf(&b, "summary: %s\n", yamlString(ep.Summary.Abstract))
+		if len(ep.Summary.Topics) > 0 {
+			b.WriteString("topics:\n")
+			for _, t := range ep.Summary.Topics {
+				fmt.Fprintf(&b, "  - %s\n", yamlString(t))
+			}
+		}
+	}
+	if len(tags) > 0 {
+		b.WriteString("tags:\n")
+		for _, t := range tags {
+			fmt.Fprintf(&b, "  - %s\n", yamlString(t))
+		}
+	}
+	b.WriteString("---\n\n")
+	return b.String()
+}
+
+// yamlString quotes s as a YAML double-quoted scalar. JSON string escapes
+// are a subset of YAML's, so encoding/json does the escaping.
+func yamlString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+// episodeFilename is the per-episode Markdown file for an episode
+func episodeFilename(dir string, ep Episode) string {
+	return storage.Join(dir, fmt.Sprintf("%s_%d.md", ep.Prefix, ep.Number))
+}
@@ -0,0 +1,65 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFrontMatter(t *testing.T) {
+	ep := Episode{
+		Prefix: "SN", Number: 950, Title: `Security Now 950: "Passkeys"`,
+		Date: time.Date(2023, 11, 11, 0, 0, 0, 0, time.UTC), URL: "https://twit.tv/posts/transcripts/security-now-950-transcript",
+		Fetched: time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC),
+	}
+	got := FrontMatter(ep, 1234, []string{"security", "Apple"})
+	want := `---
+title: "Security Now 950: \"Passkeys\""
+show: "security now"
+prefix: "SN"
+episode: 950
+date: 2023-11-11
+url: "https://twit.tv/posts/transcripts/security-now-950-transcript"
+fetched: 2024-03-01T12:30:00Z
+converter: "twit-transcript-archiver dev"
+words: 1234
+tags:
+  - "security"
+  - "Apple"
+---
+
+`
+	if got != want {
+		t.Errorf("Unexpected front matter:\n%s", got)
+	}
+
+	ep.Summary = &Summary{Abstract: "Steve explains passkeys.", Topics: []string{"passkeys", "FIDO"}}
+	if got := FrontMatter(ep, 1234, nil); !strings.Contains(got, "words: 1234\nsummary: \"Steve explains passkeys.\"\ntopics:\n  - \"passkeys\"\n  - \"FIDO\"\n---") {
+		t.Errorf("Unexpected front matter with summary:\n%s", got)
+	}
+}
+
+func TestProcessPrefixPerEpisode(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "twittest")
+	defer os.RemoveAll(tmpDir)
+	os.WriteFile(filepath.Join(tmpDir, "SN_950.html"), []byte(`<link rel="canonical" href="https://twit.tv/x">
+<h1 class="post-title">Security Now 950</h1><p class="byline">Nov 11th 2023</p><div class="body textual">Hello there</div>`), 0644)
+
+	opts := Options{PerEpisode: true, FrontMatter: true, Tags: map[string][]string{"SN_950": {"security"}}}
+	ProcessPrefixWithOptions("SN", tmpDir, tmpDir, opts)
+
+	out, err := os.ReadFile(filepath.Join(tmpDir, "SN_950.md"))
+	if err != nil {
+		t.Fatalf("Per-episode file not written: %v", err)
+	}
+	for _, want := range []string{"---\ntitle: \"Security Now 950\"", "url: \"https://twit.tv/x\"", "  - \"security\"", "# Episode: Security Now 950"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "SN_Transcripts_950-950.md")); err != nil {
+		t.Error("Chunk file should still be written")
+	}
+}
@@ -0,0 +1,109 @@
+package converter
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Run "go test ./internal/converter -run TestGolden -update" after an
+// intended change to the output, and review the diff of testdata/pages
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// goldenEpisode renders everything extracted from a page in a stable,
+// readable form
+func goldenEpisode(ep Episode) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Prefix: %s\nNumber: %d\nTitle: %s\nByline: %s\n", ep.Prefix, ep.Number, ep.Title, ep.DateStr)
+	if !ep.Date.IsZero() {
+		fmt.Fprintf(&b, "Date: %s\n", ep.Date.Format("2006-01-02"))
+	}
+	fmt.Fprintf(&b, "URL: %s\n", ep.URL)
+	for _, m := range ep.Media {
+		fmt.Fprintf(&b, "Media: %s\n", m)
+	}
+	fmt.Fprintf(&b, "Hosts: %s\nGuests: %s\n", strings.Join(ep.Roster.Hosts, ", "), strings.Join(ep.Roster.Guests, ", "))
+	if ep.StrictMarkup {
+		b.WriteString("Markup: strict\n")
+	}
+	for _, r := range ep.Residual {
+		fmt.Fprintf(&b, "Residual: %s\n", r)
+	}
+	if ep.Notes.EpisodeURL != "" {
+		fmt.Fprintf(&b, "Episode page: %s\n", ep.Notes.EpisodeURL)
+	}
+	for _, l := range ep.Notes.Links {
+		fmt.Fprintf(&b, "Link: %s <%s>\n", l.Text, l.URL)
+	}
+	// The fetch time of unstamped pages is their file's modification time,
+	// which differs with every checkout
+	ep.Fetched = time.Time{}
+	text, _ := DefaultTemplates().RenderEpisode(ep, ep.Content, false)
+	b.WriteString("\n" + text)
+	return b.String()
+}
+
+// TestGolden converts the transcript pages in testdata/pages and compares
+// the result with the .md file next to each. The pages follow the markup
+// of twit.tv transcripts from different years, so a change that breaks
+// real-world extraction shows up as a diff here.
+func TestGolden(t *testing.T) {
+	pages, err := filepath.Glob(filepath.Join("testdata", "pages", "*.html"))
+	if err != nil || len(pages) == 0 {
+		t.Fatalf("No golden pages found: %v", err)
+	}
+	for _, page := range pages {
+		t.Run(filepath.Base(page), func(t *testing.T) {
+			html, _ := os.ReadFile(page)
+			if err := CheckPage(string(html)); err != nil {
+				t.Fatalf("CheckPage: %v", err)
+			}
+			ep, err := LoadEpisode(page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := goldenEpisode(ep)
+			golden := strings.TrimSuffix(page, ".html") + ".md"
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if got != string(want) {
+				t.Errorf("Output of %s drifted from %s:\n%s", page, golden, lineDiff(string(want), got))
+			}
+		})
+	}
+}
+
+// lineDiff lists the lines that differ between want and got
+func lineDiff(want, got string) string {
+	w, g := strings.Split(want, "\n"), strings.Split(got, "\n")
+	var b strings.Builder
+	n := len(w)
+	if len(g) > n {
+		n = len(g)
+	}
+	for i := 0; i < n; i++ {
+		var wl, gl string
+		if i < len(w) {
+			wl = w[i]
+		}
+		if i < len(g) {
+			gl = g[i]
+		}
+		if wl != gl {
+			fmt.Fprintf(&b, "line %d:\n  want: %q\n  got:  %q\n", i+1, wl, gl)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,157 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// English is the language code of the archive's own transcripts
+const English = "en"
+
+// languageStopwords are frequent short words of each language detected by
+// DetectLanguage, chosen so most appear in only one or two lists
+var languageStopwords = map[string][]string{
+	"en": {"the", "and", "is", "that", "of", "to", "you", "it", "was", "for", "with", "this", "have", "are", "what", "i", "they", "just"},
+	"es": {"el", "la", "los", "las", "que", "de", "y", "es", "por", "con", "para", "una", "pero", "muy", "como", "está", "lo", "del"},
+	"fr": {"le", "la", "les", "et", "est", "que", "des", "une", "pas", "pour", "dans", "avec", "je", "vous", "ce", "c'est", "du", "il"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ich", "mit", "sie", "ein", "eine", "auch", "auf", "wir", "zu", "es", "den", "dass"},
+	"it": {"il", "che", "di", "e", "non", "la", "per", "sono", "una", "con", "mi", "questo", "ma", "è", "del", "gli", "anche"},
+	"pt": {"o", "que", "de", "e", "não", "um", "uma", "para", "com", "é", "os", "se", "mas", "você", "do", "da", "isso"},
+	"nl": {"de", "het", "een", "en", "is", "niet", "dat", "van", "ik", "je", "met", "op", "wat", "zijn", "ook", "maar"},
+}
+
+// stopwordLanguages maps each stopword to the languages that use it
+var stopwordLanguages = func() map[string][]string {
+	m := make(map[string][]string)
+	for lang, words := range languageStopwords {
+		for _, w := range words {
+			m[w] = append(m[w], lang)
+		}
+	}
+	return m
+}()
+
+// scriptLanguages maps writing systems to the language assumed for text
+// mostly written in them. Han text containing kana is Japanese.
+var scriptLanguages = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Hangul, "ko"},
+	{unicode.Han, "zh"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Arabic, "ar"},
+	{unicode.Greek, "el"},
+	{unicode.Hebrew, "he"},
+}
+
+const (
+	// languageSampleWords caps how much of a transcript is examined
+	languageSampleWords = 5000
+	// minLanguageHits is the fewest stopwords needed to name a language
+	minLanguageHits = 10
+)
+
+// DetectLanguage guesses the language of a transcript's Markdown (or any
+// text), returning an ISO 639-1 code, or "" when there is too little text
+// to tell. Text mostly in a non-Latin script is named by its script;
+// otherwise the language whose common words occur most often wins.
+func DetectLanguage(content string) string {
+	var words []string
+	for _, line := range strings.Split(content, "\n") {
+		if line == AIGeneratedTag {
+			continue
+		}
+		words = append(words, strings.Fields(strings.ToLower(turnText(line)))...)
+		if len(words) >= languageSampleWords {
+			words = words[:languageSampleWords]
+			break
+		}
+	}
+
+	if lang := scriptLanguage(words); lang != "" {
+		return lang
+	}
+
+	hits := make(map[string]int)
+	for _, w := range words {
+		w = strings.TrimFunc(w, func(r rune) bool { return !unicode.IsLetter(r) && r != '\'' })
+		for _, lang := range stopwordLanguages[w] {
+			hits[lang]++
+		}
+	}
+	best, bestHits := "", 0
+	for lang, n := range hits {
+		if n > bestHits || n == bestHits && lang < best {
+			best, bestHits = lang, n
+		}
+	}
+	if bestHits < minLanguageHits {
+		return ""
+	}
+	return best
+}
+
+// scriptLanguage returns the language of the non-Latin script most letters
+// are written in, if any
+func scriptLanguage(words []string) string {
+	letters := 0
+	counts := make(map[string]int)
+	for _, w := range words {
+		for _, r := range w {
+			if !unicode.IsLetter(r) {
+				continue
+			}
+			letters++
+			for _, s := range scriptLanguages {
+				if unicode.Is(s.table, r) {
+					counts[s.lang]++
+					break
+				}
+			}
+		}
+	}
+	if counts["ja"] > 0 {
+		counts["ja"] += counts["zh"]
+		delete(counts, "zh")
+	}
+	for _, s := range scriptLanguages {
+		if n := counts[s.lang]; n > 0 && 2*n > letters {
+			return s.lang
+		}
+	}
+	return ""
+}
+
+// LanguageMode selects what happens to episodes not detected as English
+type LanguageMode string
+
+const (
+	// LanguageKeep chunks every episode together, whatever its language
+	LanguageKeep LanguageMode = "keep"
+	// LanguageExclude leaves non-English episodes out of the chunks
+	LanguageExclude LanguageMode = "exclude"
+	// LanguageSeparate writes non-English episodes to chunk files of their
+	// own per language
+	LanguageSeparate LanguageMode = "separate"
+)
+
+// ParseLanguageMode validates a --non-english value
+func ParseLanguageMode(s string) (LanguageMode, error) {
+	switch m := LanguageMode(strings.ToLower(s)); m {
+	case "", LanguageKeep:
+		return LanguageKeep, nil
+	case LanguageExclude, LanguageSeparate:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown non-English mode '%s' (want keep, exclude or separate)", s)
+}
+
+// isEnglish reports whether an episode counts as English for LanguageMode.
+// Episodes whose language could not be detected are kept with English ones.
+func isEnglish(lang string) bool {
+	return lang == "" || lang == English
+}
@@ -0,0 +1,109 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var languageSamples = map[string]string{
+	"en": "So the question is whether the browser vendors will follow along, and I think they will. That's what I was saying last week: it is just a matter of time, and you have to give them that.",
+	"es": "Bienvenidos al programa. Hoy vamos a hablar de la seguridad de los navegadores, que es un tema muy importante para todos los usuarios, pero también para las empresas que los desarrollan y para el resto del mundo.",
+	"fr": "Bonjour et bienvenue dans l'émission. Aujourd'hui nous parlons de la sécurité des navigateurs, et c'est un sujet que je trouve très important pour vous et pour les entreprises qui ne sont pas encore prêtes dans ce domaine.",
+	"de": "Willkommen zur Sendung. Heute sprechen wir über die Sicherheit der Browser, und das ist ein Thema, das ich nicht unterschätzen würde, denn es ist auch für die Firmen wichtig, mit denen wir zu tun haben und die auf uns zählen.",
+	"ja": "こんにちは、今日はブラウザのセキュリティについて話しましょう。とても大切な話題です。",
+	"ru": "Добро пожаловать на передачу. Сегодня мы поговорим о безопасности браузеров.",
+}
+
+func TestDetectLanguage(t *testing.T) {
+	for want, text := range languageSamples {
+		if got := DetectLanguage(text); got != want {
+			t.Errorf("DetectLanguage(%.30q...) = %q, want %q", text, got, want)
+		}
+	}
+
+	// Turn metadata and the AI-generated tag are not counted
+	md := AIGeneratedTag + "\nEP:950 Date:22-05-10 TS:00:00:01 - Leo Laporte " + languageSamples["es"]
+	if got := DetectLanguage(md); got != "es" {
+		t.Errorf("DetectLanguage of a turn line = %q, want es", got)
+	}
+	for _, short := range []string{"", "Hello there.", "EP:1 Date:25-01-01 TS:00:00:01 - Leo Laporte Yes."} {
+		if got := DetectLanguage(short); got != "" {
+			t.Errorf("DetectLanguage(%q) = %q, want undetermined", short, got)
+		}
+	}
+}
+
+func TestParseLanguageMode(t *testing.T) {
+	for in, want := range map[string]LanguageMode{"": LanguageKeep, "keep": LanguageKeep, "Exclude": LanguageExclude, "separate": LanguageSeparate} {
+		if got, err := ParseLanguageMode(in); err != nil || got != want {
+			t.Errorf("ParseLanguageMode(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := ParseLanguageMode("drop"); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+}
+
+func TestProcessPrefixNonEnglish(t *testing.T) {
+	langs := map[int]string{1: "en", 2: "es", 3: "en", 4: "es", 5: "fr"}
+	setup := func(t *testing.T) string {
+		tmpDir := t.TempDir()
+		for ep, lang := range langs {
+			os.WriteFile(filepath.Join(tmpDir, fmt.Sprintf("SN_%d.html", ep)), []byte(fmt.Sprintf(
+				"<h1 class=\"post-title\">Security Now %d Transcript</h1>\n<p class=\"byline\">Feb 4th 2025</p>\n<div class=\"body textual\"><p>Leo (00:00:01): %s</p></div>", ep, languageSamples[lang])), 0644)
+		}
+		return tmpDir
+	}
+	chunks := func(dir string) []string {
+		files, _ := filepath.Glob(filepath.Join(dir, "SN_Transcripts_*.md"))
+		for i, f := range files {
+			files[i] = filepath.Base(f)
+		}
+		return files
+	}
+	episodes := func(dir, name string) string {
+		data, _ := os.ReadFile(filepath.Join(dir, name))
+		var nums []string
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.HasPrefix(line, "# Episode: Security Now ") {
+				nums = append(nums, strings.Fields(line)[4])
+			}
+		}
+		return strings.Join(nums, ",")
+	}
+
+	dir := setup(t)
+	ProcessPrefixWithOptions("SN", dir, dir, Options{})
+	if got := chunks(dir); len(got) != 1 || episodes(dir, got[0]) != "1,2,3,4,5" {
+		t.Errorf("keep: chunks %v", got)
+	}
+
+	dir = setup(t)
+	ProcessPrefixWithOptions("SN", dir, dir, Options{NonEnglish: LanguageExclude, PerEpisode: true, FrontMatter: true})
+	if got := chunks(dir); len(got) != 1 || episodes(dir, got[0]) != "1,3" {
+		t.Errorf("exclude: chunks %v", got)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "SN_2.md")); !strings.Contains(string(data), "language: \"es\"\n") {
+		t.Errorf("An excluded episode should still get its per-episode file:\n%s", data)
+	}
+
+	dir = setup(t)
+	ProcessPrefixWithOptions("SN", dir, dir, Options{NonEnglish: LanguageSeparate})
+	want := map[string]string{
+		"SN_Transcripts_1-3.md":    "1,3",
+		"SN_Transcripts_es_2-4.md": "2,4",
+		"SN_Transcripts_fr_5-5.md": "5",
+	}
+	got := chunks(dir)
+	if len(got) != len(want) {
+		t.Fatalf("separate: chunks %v", got)
+	}
+	for name, eps := range want {
+		if e := episodes(dir, name); e != eps {
+			t.Errorf("separate: %s has episodes %q, want %q", name, e, eps)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// LinkIndexFile is the index of where each episode is in the chunk files,
+// kept at the root of the output directory
+const LinkIndexFile = "links.json"
+
+// EpisodeLink is a chunk file holding an episode, or part of one, and the
+// anchor before its heading. Link to File#Anchor.
+type EpisodeLink struct {
+	// Set is the prefix the chunks were written under: the show's, or the
+	// name given to ProcessCombined
+	Set  string `json:"set"`
+	File string `json:"file"` // Relative to the output directory
+	// Anchor is the EpisodeAnchor id, empty if the chunks were written
+	// without anchors (Options.TOC off)
+	Anchor    string `json:"anchor,omitempty"`
+	Continued bool   `json:"continued,omitempty"` // The episode started in an earlier chunk
+}
+
+// LinkIndex maps episodes, by index key ("SN_950"), to their chunk files.
+// Every processing run updates the entries of the chunks it wrote, so links
+// built from it follow episodes when chunk boundaries move.
+type LinkIndex struct {
+	Updated  time.Time                `json:"updated"`
+	Episodes map[string][]EpisodeLink `json:"episodes"`
+}
+
+// LoadLinks reads the link index of an output directory. A missing index is
+// not an error; an empty one is returned.
+func LoadLinks(outputBase string) (*LinkIndex, error) {
+	ix := &LinkIndex{Episodes: make(map[string][]EpisodeLink)}
+	data, err := storage.ReadFile(storage.Join(outputBase, LinkIndexFile))
+	if errors.Is(err, storage.ErrNotExist) {
+		return ix, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, ix); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", LinkIndexFile, err)
+	}
+	if ix.Episodes == nil {
+		ix.Episodes = make(map[string][]EpisodeLink)
+	}
+	return ix, nil
+}
+
+// Find returns the chunk files holding an episode
+func (ix *LinkIndex) Find(prefix string, number int) []EpisodeLink {
+	return ix.Episodes[fmt.Sprintf("%s_%d", prefix, number)]
+}
+
+// chunkLinks collects the episodes of the chunks written by a run
+type chunkLinks struct {
+	set, outputBase string
+	found           map[string][]EpisodeLink
+	files           map[string]bool
+}
+
+func newChunkLinks(set, outputBase string) *chunkLinks {
+	return &chunkLinks{set: set, outputBase: outputBase, found: make(map[string][]EpisodeLink), files: make(map[string]bool)}
+}
+
+// linkedEpisode is an episode added to an open chunk
+type linkedEpisode struct {
+	key, anchor string
+	continued   bool
+}
+
+// record adds the episodes of a chunk that was written
+func (l *chunkLinks) record(filename string, episodes []linkedEpisode) {
+	rel := storage.Rel(l.outputBase, filename)
+	l.files[rel] = true
+	for _, e := range episodes {
+		l.found[e.key] = append(l.found[e.key], EpisodeLink{Set: l.set, File: rel, Anchor: e.anchor, Continued: e.continued})
+	}
+}
+
+// save merges the run's links into the link index. After a complete run
+// every earlier link to the set's chunks is replaced; after a partial one
+// only those to chunks it rewrote or that are gone. An index the run
+// leaves as it was is not written again.
+func (l *chunkLinks) save(complete bool) error {
+	ix, err := LoadLinks(l.outputBase)
+	if err != nil {
+		return err
+	}
+	before, err := json.Marshal(ix.Episodes)
+	if err != nil {
+		return err
+	}
+	for key, links := range ix.Episodes {
+		kept := links[:0]
+		for _, link := range links {
+			stale := link.Set == l.set && (complete || l.files[link.File] || !storage.Exists(storage.Join(l.outputBase, link.File)))
+			if !stale {
+				kept = append(kept, link)
+			}
+		}
+		if len(kept) == 0 {
+			delete(ix.Episodes, key)
+		} else {
+			ix.Episodes[key] = kept
+		}
+	}
+	for key, links := range l.found {
+		ix.Episodes[key] = append(ix.Episodes[key], links...)
+	}
+	if after, err := json.Marshal(ix.Episodes); err == nil && bytes.Equal(before, after) && storage.Exists(storage.Join(l.outputBase, LinkIndexFile)) {
+		return nil
+	}
+	ix.Updated = time.Now().UTC()
+	data, err := json.MarshalIndent(ix, "", "  ")
+	if err != nil {
+		return err
+	}
+	return storage.WriteFile(storage.Join(l.outputBase, LinkIndexFile), append(data, '\n'))
+}
@@ -0,0 +1,53 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLinkIndex(t *testing.T) {
+	dir := t.TempDir()
+	for _, n := range []string{"1", "2", "3"} {
+		page := `<h1 class="post-title">Ep ` + n + `</h1><p class="byline">Feb 11th 2025</p><div class="body textual">` + strings.Repeat("word ", 40) + `</div>`
+		os.WriteFile(filepath.Join(dir, "SN_"+n+".html"), []byte(page), 0644)
+	}
+	if err := ProcessPrefixWithOptions("SN", dir, dir, Options{TOC: true, MaxWords: 100}); err != nil {
+		t.Fatal(err)
+	}
+	ix, err := LoadLinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	links := ix.Find("SN", 3)
+	if len(links) != 1 || links[0].File != "SN_Transcripts_3-3.md" || links[0].Anchor != "sn-3" || links[0].Set != "SN" {
+		t.Fatalf("Unexpected links for SN 3: %+v", links)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, links[0].File))
+	if !strings.Contains(string(data), `<a id="sn-3">`) {
+		t.Error("Anchor missing from the chunk file")
+	}
+
+	// Regenerated chunks replace the links, with episodes in new places
+	if err := ProcessPrefixWithOptions("SN", dir, dir, Options{TOC: true}); err != nil {
+		t.Fatal(err)
+	}
+	ix, _ = LoadLinks(dir)
+	if links := ix.Find("SN", 3); len(links) != 1 || links[0].File != "SN_Transcripts_1-3.md" {
+		t.Errorf("Links not updated after re-chunking: %+v", links)
+	}
+
+	// A filtered run only replaces the links to the chunks it rewrote, and
+	// other sets are left alone
+	if err := ProcessCombined("ALL", []string{"SN"}, dir, dir, Options{Filter: Filter{FromEp: 2, ToEp: 2}}); err != nil {
+		t.Fatal(err)
+	}
+	ix, _ = LoadLinks(dir)
+	if links := ix.Find("SN", 2); len(links) != 2 || links[1].Set != "ALL" || links[1].Anchor != "" {
+		t.Errorf("Expected show and combined links for SN 2: %+v", links)
+	}
+	if links := ix.Find("SN", 1); len(links) != 1 {
+		t.Errorf("Expected only the show link for SN 1: %+v", links)
+	}
+}
@@ -0,0 +1,201 @@
+package converter
+
+import (
+	"regexp"
+	"strings"
+)
+
+// htmlEntities decodes the entities found in transcript pages
+var htmlEntities = strings.NewReplacer(
+	"&nbsp;", " ",
+	"&amp;", "&",
+	"&lt;", "<",
+	"&gt;", ">",
+	"&quot;", "\"",
+	"&#39;", "'",
+)
+
+// anchorHrefRegex reads the href of an <a> tag (without its angle brackets)
+var anchorHrefRegex = regexp.MustCompile(`(?s)^a\s+(?:[^>]*?\s+)?href="([^"]*)"`)
+
+// markupTags maps the paired tags rendered as Markdown to the text written
+// for their opening and closing tags. Names are case-sensitive, as in the
+// pages the archive holds.
+var markupTags = map[string][2]string{
+	"h1":     {"# ", "\n\n"},
+	"h2":     {"## ", "\n\n"},
+	"h3":     {"### ", "\n\n"},
+	"b":      {"**", "**"},
+	"strong": {"**", "**"},
+	"i":      {"*", "*"},
+	"em":     {"*", "*"},
+	"li":     {"* ", "\n"},
+	"a":      {"[", "]"},
+}
+
+// blockTags are replaced with a line break so text does not run together
+var blockTags = map[string]bool{"table": true, "p": true, "br": true, "tr": true, "div": true}
+
+// htmlToken is a run of text or a single tag
+type htmlToken struct {
+	text  string // Text, or the tag without its angle brackets
+	tag   bool
+	name  string // Tag name
+	close bool
+	href  string // For <a href="..."> tags
+	link  bool   // Whether href was present
+}
+
+// tokenizeHTML splits a page into text and tags in one scan. Script and
+// style elements are dropped whole. As with the pattern <[^>]+>, a "<" that
+// is not closed by a later ">" (or is directly followed by one) is text.
+func tokenizeHTML(html string) []htmlToken {
+	var tokens []htmlToken
+	text := func(s string) {
+		if s == "" {
+			return
+		}
+		if n := len(tokens); n > 0 && !tokens[n-1].tag {
+			tokens[n-1].text += s
+			return
+		}
+		tokens = append(tokens, htmlToken{text: s})
+	}
+
+	for i := 0; i < len(html); {
+		lt := strings.IndexByte(html[i:], '<')
+		if lt < 0 {
+			text(html[i:])
+			break
+		}
+		text(html[i : i+lt])
+		i += lt
+
+		skipped := false
+		for _, el := range []string{"script", "style"} {
+			if strings.HasPrefix(html[i+1:], el) {
+				if end := strings.Index(html[i:], "</"+el+">"); end >= 0 {
+					i += end + len(el) + 3
+					skipped = true
+				}
+				break
+			}
+		}
+		if skipped {
+			continue
+		}
+
+		gt := strings.IndexByte(html[i+1:], '>')
+		if gt <= 0 {
+			text("<")
+			i++
+			continue
+		}
+		raw := html[i+1 : i+1+gt]
+		i += gt + 2
+
+		t := htmlToken{text: raw, tag: true}
+		name := raw
+		if strings.HasPrefix(name, "/") {
+			t.close = true
+			name = name[1:]
+		}
+		end := 0
+		for end < len(name) && isTagNameByte(name[end]) {
+			end++
+		}
+		t.name = name[:end]
+		if t.name == "a" && !t.close {
+			if m := anchorHrefRegex.FindStringSubmatch(raw); m != nil {
+				t.href, t.link = m[1], true
+			}
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens
+}
+
+func isTagNameByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// pairTags returns, for each opening markup tag that is closed, the index of
+// its closing tag (and the reverse). Like a non-greedy match, an opening tag
+// pairs with the next closing tag of the same name, and openings in between
+// are ignored.
+func pairTags(tokens []htmlToken) map[int]int {
+	pairs := make(map[int]int)
+	open := make(map[string]int)
+	for i, t := range tokens {
+		if !t.tag {
+			continue
+		}
+		if _, ok := markupTags[t.name]; !ok {
+			continue
+		}
+		// Only a full closing tag such as "</b>" ends an element
+		if t.close {
+			if j, ok := open[t.name]; ok && t.text == "/"+t.name {
+				pairs[j], pairs[i] = i, j
+				delete(open, t.name)
+			}
+			continue
+		}
+		if _, ok := open[t.name]; !ok && (t.name != "a" || t.link) {
+			open[t.name] = i
+		}
+	}
+	return pairs
+}
+
+// renderMarkup converts a page to text with Markdown headings, emphasis,
+// links and list items, in a single pass over its tokens. Block tags become
+// line breaks, other tags are dropped and entities are decoded.
+func renderMarkup(html string) string {
+	tokens := tokenizeHTML(html)
+	pairs := pairTags(tokens)
+	var b strings.Builder
+	b.Grow(len(html))
+	for i, t := range tokens {
+		if !t.tag {
+			b.WriteString(htmlEntities.Replace(t.text))
+			continue
+		}
+		lower := strings.ToLower(t.name)
+		switch {
+		case blockTags[lower]:
+			b.WriteString("\n")
+			continue
+		case lower == "ul":
+			if t.text[0] == '/' && strings.EqualFold(t.text, "/ul") {
+				b.WriteString("\n")
+			}
+			continue
+		}
+		j, paired := pairs[i]
+		if !paired {
+			continue
+		}
+		marks := markupTags[t.name]
+		if t.name == "a" {
+			// Only http(s) and site-relative links are kept
+			open := tokens[i]
+			if t.close {
+				open = tokens[j]
+			}
+			if !strings.HasPrefix(open.href, "/") && !strings.HasPrefix(open.href, "http://") && !strings.HasPrefix(open.href, "https://") {
+				continue
+			}
+			if t.close {
+				b.WriteString("](" + htmlEntities.Replace(open.href) + ")")
+				continue
+			}
+		}
+		if t.close {
+			b.WriteString(marks[1])
+		} else {
+			b.WriteString(marks[0])
+		}
+	}
+	return b.String()
+}
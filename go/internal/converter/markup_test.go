@@ -0,0 +1,25 @@
+package converter
+
+import "testing"
+
+func TestRenderMarkup(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"<h2 id=x>Intro</h2>Hi", "## Intro\n\nHi"},
+		{"<p>One<br/>Two</p>", "\nOne\nTwo\n"},
+		{"<strong>Leo:</strong> <em>hi</em> <b>there</b>", "**Leo:** *hi* **there**"},
+		{`<a class="x" href="https://twit.tv/?a=1&amp;b=2"><b>TWiT</b></a>`, "[**TWiT**](https://twit.tv/?a=1&b=2)"},
+		{`<a href="javascript:alert(1)">click</a>`, "click"},
+		{`<a name="top">anchor</a>`, "anchor"},
+		{"<ul><li>A</li><li>B</li></ul>", "* A\n* B\n\n"},
+		{"<script>var x = '<b>';</script>Text<style>p{}</style>", "Text"},
+		{"<b>unclosed <i>italic</i>", "unclosed *italic*"},
+		{"<b>a<b>b</b>c</b>", "**ab**c"},
+		{"&lt;b&gt; &amp; <> x > y", "<b> & <> x > y"},
+		{"<SPAN>Plain</SPAN>", "Plain"},
+	}
+	for _, tt := range tests {
+		if got := renderMarkup(tt.in); got != tt.want {
+			t.Errorf("renderMarkup(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package converter
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Normalization selects the clean-up applied to converted text
+type Normalization struct {
+	// Compose joins letters and combining accents into single characters,
+	// as NFC does (for Latin scripts; see latinCompositions)
+	Compose bool
+	// StripControl removes control characters other than newlines and tabs,
+	// and the invisible byte-order marks, zero-width spaces and soft hyphens
+	// the CMS leaves behind
+	StripControl bool
+	// ASCIIQuotes replaces curly quotes, dashes, ellipses and non-breaking
+	// spaces with their plain ASCII forms
+	ASCIIQuotes bool
+}
+
+// Normalize is applied to the titles and bodies of converted transcripts
+var Normalize = Normalization{Compose: true, StripControl: true}
+
+// asciiPunctuation replaces typographic punctuation for ASCIIQuotes
+var asciiPunctuation = strings.NewReplacer(
+	"‘", "'", "’", "'", "‚", "'", "‛", "'", "′", "'",
+	"“", "\"", "”", "\"", "„", "\"", "‟", "\"", "″", "\"",
+	"«", "\"", "»", "\"",
+	"‐", "-", "‑", "-", "‒", "-", "–", "-",
+	"—", "--", "―", "--",
+	"…", "...",
+	"\u00A0", " ", "\u2009", " ", "\u202F", " ",
+)
+
+// invisibleRunes are format characters stripped with StripControl
+var invisibleRunes = map[rune]bool{'\uFEFF': true, '\u200B': true, '\u00AD': true}
+
+// Apply normalizes s
+func (n Normalization) Apply(s string) string {
+	if n.StripControl {
+		s = strings.Map(func(r rune) rune {
+			if r == '\n' || r == '\t' {
+				return r
+			}
+			if unicode.IsControl(r) || invisibleRunes[r] {
+				return -1
+			}
+			return r
+		}, s)
+	}
+	if n.Compose {
+		s = composeLatin(s)
+	}
+	if n.ASCIIQuotes {
+		s = asciiPunctuation.Replace(s)
+	}
+	return s
+}
+
+// composeLatin replaces letter + combining mark sequences that have a
+// precomposed form, so "é" and "é" compare and search alike
+func composeLatin(s string) string {
+	// Fast path: nothing to compose without combining marks
+	if strings.IndexFunc(s, isCombiningMark) < 0 {
+		return s
+	}
+	out := make([]rune, 0, len(s))
+	for _, r := range s {
+		if n := len(out); n > 0 && isCombiningMark(r) {
+			if c, ok := latinCompositions[[2]rune{out[n-1], r}]; ok {
+				out[n-1] = c
+				continue
+			}
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+func isCombiningMark(r rune) bool {
+	return r >= 0x300 && r <= 0x36F
+}
@@ -0,0 +1,46 @@
+package converter
+
+import "testing"
+
+func TestNormalization(t *testing.T) {
+	// A BOM, a decomposed "é", curly quotes, an em dash, a bell and a
+	// carriage return, a soft hyphen and a zero-width space
+	in := "\uFEFFCafe\u0301 “quoted” — it’s…\x07\r\n\tne\u00ADver\u200B end"
+	tests := []struct {
+		n    Normalization
+		want string
+	}{
+		{Normalization{}, in},
+		{Normalization{Compose: true}, "\uFEFFCafé “quoted” — it’s…\x07\r\n\tne\u00ADver\u200B end"},
+		{Normalize, "Café “quoted” — it’s…\n\tnever end"},
+		{Normalization{Compose: true, StripControl: true, ASCIIQuotes: true}, "Café \"quoted\" -- it's...\n\tnever end"},
+	}
+	for _, tt := range tests {
+		if got := tt.n.Apply(in); got != tt.want {
+			t.Errorf("%+v.Apply =\n%q\nwant\n%q", tt.n, got, tt.want)
+		}
+	}
+
+	// Vietnamese letters compose in two steps: e + dot below, then + circumflex
+	if got := composeLatin("Vie\u0323\u0302t"); got != "Việt" {
+		t.Errorf("composeLatin = %q, want %q", got, "Việt")
+	}
+	// Marks with no precomposed form are left alone
+	if got := composeLatin("q\u0301"); got != "q\u0301" {
+		t.Errorf("composeLatin = %q", got)
+	}
+}
+
+func TestHTMLToMarkdownNormalizes(t *testing.T) {
+	saved := Normalize
+	defer func() { Normalize = saved }()
+
+	html := "<p>Leo Laporte (00:00:01): “Welcome” to Cafe\u0301 — the show\x0b.</p>"
+	if got := HTMLToMarkdown(html, 1, "25-01-01"); got != "EP:1 Date:25-01-01 TS:00:00:01 - Leo Laporte “Welcome” to Café — the show." {
+		t.Errorf("Default normalization gave %q", got)
+	}
+	Normalize.ASCIIQuotes = true
+	if got := HTMLToMarkdown(html, 1, "25-01-01"); got != "EP:1 Date:25-01-01 TS:00:00:01 - Leo Laporte \"Welcome\" to Café -- the show." {
+		t.Errorf("ASCII quotes gave %q", got)
+	}
+}
@@ -0,0 +1,97 @@
+package converter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Link is a related link from an episode's show notes
+type Link struct {
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+// ShowNotes holds the notes and related links published with an episode
+type ShowNotes struct {
+	Text  string // Plain-text notes, one paragraph per line
+	Links []Link
+	// EpisodeURL is the parent episode page linked from the transcript, if any
+	EpisodeURL string
+}
+
+var (
+	// showNotesRegex matches the notes/links blocks of transcript and
+	// episode pages
+	showNotesRegex = regexp.MustCompile(`(?s)<div class="[^"]*\b(?:show-?notes|episode-?notes|links-?list|field-links)\b[^"]*">(.*?)</div>`)
+	// episodePageRegex matches a link to the parent episode page
+	episodePageRegex = regexp.MustCompile(`href="((?:https?://twit\.tv)?/shows/[^"/]+/episodes/\d+)"`)
+	// externalLinkRegex matches absolute links inside a notes block
+	externalLinkRegex = regexp.MustCompile(`(?s)<a\s+(?:[^>]*?\s+)?href="(https?://[^"]+)"[^>]*>(.*?)</a>`)
+	// blockBreakRegex matches tags that end a paragraph of notes
+	blockBreakRegex = regexp.MustCompile(`(?i)</?(?:p|li|ul|ol|br|div|h\d)[^>]*>`)
+)
+
+// ExtractShowNotes pulls show notes and related links out of a page. Only
+// the page's notes blocks are read, so links in the navigation, footer and
+// transcript body are not picked up.
+func ExtractShowNotes(html string) ShowNotes {
+	var notes ShowNotes
+	if m := episodePageRegex.FindStringSubmatch(html); m != nil {
+		notes.EpisodeURL = m[1]
+	}
+
+	seen := make(map[string]bool)
+	var paragraphs []string
+	for _, block := range showNotesRegex.FindAllStringSubmatch(html, -1) {
+		for _, l := range externalLinkRegex.FindAllStringSubmatch(block[1], -1) {
+			if seen[l[1]] {
+				continue
+			}
+			seen[l[1]] = true
+			text := cleanText(l[2])
+			if text == "" {
+				text = l[1]
+			}
+			notes.Links = append(notes.Links, Link{Text: text, URL: l[1]})
+		}
+		for _, p := range blockBreakRegex.Split(block[1], -1) {
+			if text := cleanText(p); text != "" {
+				paragraphs = append(paragraphs, text)
+			}
+		}
+	}
+	notes.Text = strings.Join(paragraphs, "\n")
+	return notes
+}
+
+// Empty reports whether no notes or links were found
+func (n ShowNotes) Empty() bool {
+	return n.Text == "" && len(n.Links) == 0
+}
+
+// Markdown renders the notes as a "Show Notes" section
+func (n ShowNotes) Markdown() string {
+	if n.Empty() {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("## Show Notes\n\n")
+	if n.Text != "" {
+		b.WriteString(n.Text + "\n\n")
+	}
+	if len(n.Links) > 0 {
+		b.WriteString("### Links\n\n")
+		for _, l := range n.Links {
+			fmt.Fprintf(&b, "- [%s](%s)\n", l.Text, l.URL)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// cleanText strips tags, decodes common entities and collapses whitespace
+func cleanText(s string) string {
+	s = anyTagRegex.ReplaceAllString(s, "")
+	s = strings.NewReplacer("&nbsp;", " ", "&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", "\"", "&#39;", "'").Replace(s)
+	return strings.Join(strings.Fields(applyTextFilters(Normalize.Apply(s))), " ")
+}
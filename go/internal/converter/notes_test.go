@@ -0,0 +1,52 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const notesPage = `<h1 class="post-title">Security Now 950 Transcript</h1>
+<p class="byline">Nov 11th 2023</p>
+<a href="/shows/security-now/episodes/950">Episode page</a>
+<div class="body textual"><p>Leo Laporte: Hello <a href="https://example.com/in-body">in body</a></p></div>
+<div class="show-notes"><p>This week: passkeys &amp; more.</p>
+<ul><li><a href="https://grc.com/sn/sn-950.htm">Show notes PDF</a></li>
+<li><a href="https://example.com/passkeys">Passkeys explained</a></li></ul></div>
+<footer><a href="https://twitter.com/twit">Twitter</a></footer>`
+
+func TestExtractShowNotes(t *testing.T) {
+	notes := ExtractShowNotes(notesPage)
+	if notes.EpisodeURL != "/shows/security-now/episodes/950" {
+		t.Errorf("Unexpected episode URL %q", notes.EpisodeURL)
+	}
+	if len(notes.Links) != 2 || notes.Links[0].URL != "https://grc.com/sn/sn-950.htm" || notes.Links[1].Text != "Passkeys explained" {
+		t.Errorf("Unexpected links: %+v", notes.Links)
+	}
+	if !strings.HasPrefix(notes.Text, "This week: passkeys & more.") {
+		t.Errorf("Unexpected notes text %q", notes.Text)
+	}
+	md := notes.Markdown()
+	if !strings.Contains(md, "## Show Notes") || !strings.Contains(md, "- [Show notes PDF](https://grc.com/sn/sn-950.htm)") {
+		t.Errorf("Unexpected Markdown:\n%s", md)
+	}
+	if !(ShowNotes{}).Empty() || (ShowNotes{}).Markdown() != "" {
+		t.Error("Empty notes should render nothing")
+	}
+}
+
+func TestProcessPrefixShowNotes(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "twittest")
+	defer os.RemoveAll(tmpDir)
+	os.WriteFile(filepath.Join(tmpDir, "SN_950.html"), []byte(notesPage), 0644)
+
+	ProcessPrefixWithOptions("SN", tmpDir, tmpDir, Options{ShowNotes: true})
+	out, err := os.ReadFile(filepath.Join(tmpDir, "SN_Transcripts_950-950.md"))
+	if err != nil {
+		t.Fatalf("Chunk not written: %v", err)
+	}
+	if !strings.Contains(string(out), "### Links") {
+		t.Errorf("Show notes missing from output:\n%s", out)
+	}
+}
@@ -0,0 +1,46 @@
+package converter
+
+import "strings"
+
+// PlaceholderPhrases are what the site shows on an episode page published
+// before its transcript is ready. Matched case-insensitively.
+var PlaceholderPhrases = []string{
+	"transcript coming soon",
+	"transcript will be available",
+	"transcript will be posted",
+	"transcript is not yet available",
+	"transcript not yet available",
+	"transcript is being prepared",
+	"transcripts are usually available",
+}
+
+// PlaceholderWords is the word count below which a transcript body is taken
+// to be a placeholder. Pages using a PlaceholderPhrase count as placeholders
+// up to ten times as many words, for announcements with a blurb attached.
+var PlaceholderWords = 50
+
+// IsPlaceholder reports whether a page is a stand-in for a transcript that
+// has not been published yet
+func IsPlaceholder(html string) bool {
+	return placeholderBody(ExtractPage(html).Body)
+}
+
+// placeholderBody reports whether an extracted transcript body is a
+// placeholder
+func placeholderBody(body string) bool {
+	text := strings.ToLower(anyTagRegex.ReplaceAllString(body, " "))
+	words := len(strings.Fields(text))
+	if words < PlaceholderWords {
+		return true
+	}
+	if words >= 10*PlaceholderWords {
+		return false
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	for _, p := range PlaceholderPhrases {
+		if strings.Contains(text, p) {
+			return true
+		}
+	}
+	return false
+}
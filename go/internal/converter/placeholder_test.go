@@ -0,0 +1,25 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsPlaceholder(t *testing.T) {
+	tests := []struct {
+		body string
+		want bool
+	}{
+		{"", true},
+		{"<p>Transcript coming soon.</p>", true},
+		{"<p>The transcript will be available in a few days. " + strings.Repeat("About this episode. ", 30) + "</p>", true},
+		{"<p>" + strings.Repeat("Leo Laporte: Welcome to the show. ", 20) + "</p>", false},
+		{"<p>" + strings.Repeat("Steve Gibson: The transcript will be available when I say so. ", 100) + "</p>", false},
+	}
+	for _, tt := range tests {
+		html := `<h1 class="post-title">Security Now 1</h1><div class="body textual">` + tt.body + `</div>`
+		if got := IsPlaceholder(html); got != tt.want {
+			t.Errorf("IsPlaceholder(%.60q) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// sourceStampRegex matches the comment StampSource puts at the top of a
+// saved page
+var sourceStampRegex = regexp.MustCompile(`^<!-- archived from (\S+) at (\S+) -->\n`)
+
+// Origins of transcripts that were not published as transcript pages, as
+// recorded by CaptionsPage and returned by PageOrigin
+const (
+	OriginFeed     = "feed"     // A podcast:transcript file linked from a feed
+	OriginCaptions = "captions" // Video captions (YouTube)
+	OriginASR      = "asr"      // Speech recognition run on the audio
+)
+
+// originRegex matches the marker CaptionsPage puts on the pages it writes
+var originRegex = regexp.MustCompile(`<meta name="twit-archiver-origin" content="([a-z-]+)">`)
+
+// PageOrigin returns what a page was generated from (OriginFeed,
+// OriginCaptions or OriginASR), or "" for pages downloaded from the site
+func PageOrigin(html string) string {
+	if m := originRegex.FindStringSubmatch(html); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// StampSource records where and when a page was downloaded in a comment at
+// its top, in the way browsers mark saved pages ("saved from url=..."). A
+// stamp already on the page is replaced.
+func StampSource(html, url string, at time.Time) string {
+	html = sourceStampRegex.ReplaceAllString(html, "")
+	return fmt.Sprintf("<!-- archived from %s at %s -->\n", strings.ReplaceAll(url, "--", "%2D%2D"), at.UTC().Format(time.RFC3339)) + html
+}
+
+// PageSource returns the URL and download time StampSource recorded on a
+// page
+func PageSource(html string) (string, time.Time, bool) {
+	m := sourceStampRegex.FindStringSubmatch(html)
+	if m == nil {
+		return "", time.Time{}, false
+	}
+	at, err := time.Parse(time.RFC3339, m[2])
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return m[1], at, true
+}
+
+// fetchedAt returns when a transcript file was downloaded: the time stamped
+// on the page, else, for pages saved before stamping, the local file's
+// modification time. Zero if unknown.
+func fetchedAt(path, html string) time.Time {
+	if _, at, ok := PageSource(html); ok {
+		return at
+	}
+	if !storage.IsRemote(path) {
+		if info, err := os.Stat(path); err == nil {
+			return info.ModTime().UTC()
+		}
+	}
+	return time.Time{}
+}
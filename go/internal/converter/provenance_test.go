@@ -0,0 +1,44 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStampSource(t *testing.T) {
+	at := time.Date(2025, 2, 11, 8, 0, 0, 0, time.FixedZone("PST", -8*3600))
+	page := `<html><h1 class="post-title">Security Now 1000</h1><p class="byline">Feb 11th 2025</p><div class="body textual">Steve is here.</div></html>`
+	stamped := StampSource(page, "https://twit.tv/posts/transcripts/security-now-1000-transcript", at)
+	if u, got, ok := PageSource(stamped); !ok || u != "https://twit.tv/posts/transcripts/security-now-1000-transcript" || !got.Equal(at) {
+		t.Fatalf("PageSource = %q, %v, %v", u, got, ok)
+	}
+	// A page downloaded again keeps a single stamp
+	restamped := StampSource(stamped, "https://twit.tv/x", at.Add(time.Hour))
+	if restamped[len(restamped)-len(page):] != page || len(restamped) != len(StampSource(page, "https://twit.tv/x", at)) {
+		t.Errorf("Expected the stamp to be replaced:\n%s", restamped)
+	}
+	if _, _, ok := PageSource(page); ok {
+		t.Error("Unstamped page reported a source")
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "SN_1000.html")
+	os.WriteFile(path, []byte(stamped), 0644)
+	ep, err := LoadEpisode(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ep.URL != "https://twit.tv/posts/transcripts/security-now-1000-transcript" || !ep.Fetched.Equal(at) {
+		t.Errorf("Unexpected provenance: %q, %v", ep.URL, ep.Fetched)
+	}
+
+	// Pages saved before stamping fall back to the file's modification time
+	os.WriteFile(path, []byte(page), 0644)
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	os.Chtimes(path, mtime, mtime)
+	if ep, _ := LoadEpisode(path); !ep.Fetched.Equal(mtime) || ep.URL != "" {
+		t.Errorf("Unexpected fallback provenance: %q, %v", ep.URL, ep.Fetched)
+	}
+}
@@ -0,0 +1,80 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// QuarantineDir is the directory of the data directory that downloads
+// failing extraction are moved to, so later runs do not trip over them
+const QuarantineDir = "quarantine"
+
+// reasonSuffix names the file recording why a file was quarantined
+const reasonSuffix = ".reason.json"
+
+// QuarantineItem is a file set aside by Quarantine
+type QuarantineItem struct {
+	Path   string    `json:"-"`    // Where the file is now
+	From   string    `json:"from"` // Where it was
+	Reason string    `json:"reason"`
+	At     time.Time `json:"at"`
+}
+
+// Quarantine moves a file into the data directory's quarantine, next to a
+// <name>.reason.json file recording where it came from and why, and
+// returns its new path. A file of the same name quarantined before is
+// replaced.
+func Quarantine(dataDir, path string, reason error) (string, error) {
+	dest := storage.Join(dataDir, QuarantineDir, storage.Base(path))
+	if err := storage.Rename(path, dest); err != nil {
+		return "", err
+	}
+	item := QuarantineItem{From: storage.Rel(dataDir, path), Reason: reason.Error(), At: time.Now().UTC()}
+	data, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return dest, err
+	}
+	return dest, storage.WriteFile(dest+reasonSuffix, append(data, '\n'))
+}
+
+// Quarantined lists the files in a data directory's quarantine, most
+// recently quarantined first
+func Quarantined(dataDir string) ([]QuarantineItem, error) {
+	reasons, err := storage.Glob(storage.Join(dataDir, QuarantineDir, "*"+reasonSuffix))
+	if err != nil {
+		return nil, err
+	}
+	var items []QuarantineItem
+	for _, r := range reasons {
+		data, err := storage.ReadFile(r)
+		if err != nil {
+			return nil, err
+		}
+		var item QuarantineItem
+		if err := json.Unmarshal(data, &item); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", r, err)
+		}
+		item.Path = strings.TrimSuffix(r, reasonSuffix)
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].At.Equal(items[j].At) {
+			return items[i].At.After(items[j].At)
+		}
+		return items[i].Path < items[j].Path
+	})
+	return items, nil
+}
+
+// RemoveQuarantined deletes a quarantined file and its reason file
+func RemoveQuarantined(path string) error {
+	if err := storage.Remove(path); err != nil {
+		return err
+	}
+	return storage.Remove(path + reasonSuffix)
+}
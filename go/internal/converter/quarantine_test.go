@@ -0,0 +1,61 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/errs"
+)
+
+func TestQuarantineUnparseable(t *testing.T) {
+	dir := t.TempDir()
+	writeTestEpisodes(t, dir)
+	os.WriteFile(filepath.Join(dir, "IM_3.html"), []byte("<html><body>Access denied</body></html>"), 0644)
+
+	report := errs.NewReport("test")
+	if err := ProcessPrefixWithOptions("IM", dir, dir, Options{Report: report, Quarantine: true}); err != nil {
+		t.Fatal(err)
+	}
+	if report.Len() != 1 {
+		t.Errorf("Expected the bad file in the report, got %d failures", report.Len())
+	}
+	if exists(dir, "IM_3.html") {
+		t.Error("The unparseable file should have been moved")
+	}
+	if !exists(dir, filepath.Join(QuarantineDir, "IM_3.html")) || !exists(dir, "IM_1.html") {
+		t.Error("Expected the bad file in quarantine and the good ones in place")
+	}
+
+	items, err := Quarantined(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("Quarantined = %+v, want one item", items)
+	}
+	q := items[0]
+	if q.From != "IM_3.html" || !strings.Contains(q.Reason, "parse error") || q.At.IsZero() || filepath.Base(q.Path) != "IM_3.html" {
+		t.Errorf("Unexpected item: %+v", q)
+	}
+
+	// The next run no longer sees it
+	report = errs.NewReport("test")
+	ProcessPrefixWithOptions("IM", dir, dir, Options{Report: report, Quarantine: true})
+	if report.Len() != 0 {
+		t.Errorf("Expected a clean second run, got %d failures", report.Len())
+	}
+}
+
+func TestQuarantineOff(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "IM_3.html"), []byte("<html><body>Access denied</body></html>"), 0644)
+	ProcessPrefixWithOptions("IM", dir, dir, Options{})
+	if !exists(dir, "IM_3.html") {
+		t.Error("Files should stay in place unless Quarantine is set")
+	}
+	if items, err := Quarantined(dir); err != nil || len(items) != 0 {
+		t.Errorf("Quarantined = %v, %v, want none", items, err)
+	}
+}
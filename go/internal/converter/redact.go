@@ -0,0 +1,129 @@
+package converter
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// TextFilter rewrites converted text, e.g. to redact it
+type TextFilter interface {
+	Filter(text string) string
+}
+
+// TextFilters run, in order, on every converted title, transcript and show
+// notes text, after Normalize. Set them before loading episodes.
+var TextFilters []TextFilter
+
+func applyTextFilters(s string) string {
+	for _, f := range TextFilters {
+		s = f.Filter(s)
+	}
+	return s
+}
+
+// DefaultRedaction replaces redacted text unless a Redactor says otherwise
+const DefaultRedaction = "[REDACTED]"
+
+// PIIPatterns are the built-in redaction rules, named "@email" and "@phone"
+// in rules files. Phone numbers need separators ("555-123-4567",
+// "(555) 123 4567", "+1 555.123.4567") so episode and CVE numbers are left
+// alone.
+var PIIPatterns = map[string]*regexp.Regexp{
+	"email": regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+	"phone": regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{3}\)[ .-]?|\b\d{3}[ .-])\d{3}[ .-]\d{4}\b`),
+}
+
+// Redactor is a TextFilter that masks every match of its rules
+type Redactor struct {
+	Rules       []*regexp.Regexp
+	Replacement string
+}
+
+// NewRedactor returns a Redactor with no rules that masks with replacement
+// (DefaultRedaction if empty)
+func NewRedactor(replacement string) *Redactor {
+	if replacement == "" {
+		replacement = DefaultRedaction
+	}
+	return &Redactor{Replacement: replacement}
+}
+
+// Filter masks the matches of every rule in text
+func (r *Redactor) Filter(text string) string {
+	for _, re := range r.Rules {
+		text = re.ReplaceAllLiteralString(text, r.Replacement)
+	}
+	return text
+}
+
+// AddPII adds the built-in rules for email addresses and phone numbers
+func (r *Redactor) AddPII() {
+	r.Rules = append(r.Rules, PIIPatterns["email"], PIIPatterns["phone"])
+}
+
+// AddRule adds a rule in rules-file syntax: "@email" or "@phone" for a
+// built-in pattern, "/pattern/" for a regular expression, and anything else
+// for a literal word or phrase, matched case-insensitively as whole words
+func (r *Redactor) AddRule(rule string) error {
+	switch {
+	case strings.HasPrefix(rule, "@"):
+		re, ok := PIIPatterns[rule[1:]]
+		if !ok {
+			return fmt.Errorf("unknown built-in rule '%s' (want @email or @phone)", rule)
+		}
+		r.Rules = append(r.Rules, re)
+	case len(rule) > 2 && strings.HasPrefix(rule, "/") && strings.HasSuffix(rule, "/"):
+		re, err := regexp.Compile(rule[1 : len(rule)-1])
+		if err != nil {
+			return fmt.Errorf("invalid pattern %s: %w", rule, err)
+		}
+		r.Rules = append(r.Rules, re)
+	default:
+		r.Rules = append(r.Rules, regexp.MustCompile(`(?i)\b`+regexp.QuoteMeta(rule)+`\b`))
+	}
+	return nil
+}
+
+// LoadRedactionRules adds the rules in a file, one per line (see AddRule).
+// Blank lines and lines starting with '#' are ignored.
+func (r *Redactor) LoadRedactionRules(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := r.AddRule(line); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// SetupRedaction installs a Redactor in TextFilters from the redaction
+// flags the tools share: a rules file and/or the built-in PII rules.
+// It does nothing if neither is given.
+func SetupRedaction(rulesPath string, pii bool, replacement string) error {
+	if rulesPath == "" && !pii {
+		return nil
+	}
+	r := NewRedactor(replacement)
+	if pii {
+		r.AddPII()
+	}
+	if rulesPath != "" {
+		if err := r.LoadRedactionRules(rulesPath); err != nil {
+			return err
+		}
+	}
+	TextFilters = append(TextFilters, r)
+	return nil
+}
@@ -0,0 +1,68 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRedactorPII(t *testing.T) {
+	r := NewRedactor("")
+	r.AddPII()
+	tests := map[string]string{
+		"Write to leo@twit.tv or steve.gibson@grc.example.com.": "Write to [REDACTED] or [REDACTED].",
+		"Call 555-123-4567, (555) 123 4567 or +1 555.123.4567":  "Call [REDACTED], [REDACTED] or [REDACTED]",
+		// Episode numbers, timecodes, dates and CVEs are not phone numbers
+		"Episode 1000 at 01:23:45 on 2024-11-19 about CVE-2024-12345 and 5551234567": "Episode 1000 at 01:23:45 on 2024-11-19 about CVE-2024-12345 and 5551234567",
+	}
+	for in, want := range tests {
+		if got := r.Filter(in); got != want {
+			t.Errorf("Filter(%q) =\n%q\nwant\n%q", in, got, want)
+		}
+	}
+}
+
+func TestLoadRedactionRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redact.txt")
+	os.WriteFile(path, []byte("# Rules\n\n@email\nJohn Smith\n/ACME-\\d+/\n"), 0644)
+	r := NewRedactor("***")
+	if err := r.LoadRedactionRules(path); err != nil {
+		t.Fatal(err)
+	}
+	in := "JOHN SMITH (john@example.com) filed ACME-42; Johnsmithson did not."
+	if got, want := r.Filter(in), "*** (***) filed ***; Johnsmithson did not."; got != want {
+		t.Errorf("Filter = %q, want %q", got, want)
+	}
+
+	for _, bad := range []string{"@ssn\n", "/(/\n"} {
+		os.WriteFile(path, []byte(bad), 0644)
+		if err := NewRedactor("").LoadRedactionRules(path); err == nil {
+			t.Errorf("Expected an error for rule %q", bad)
+		}
+	}
+}
+
+func TestTextFiltersInConversion(t *testing.T) {
+	saved := TextFilters
+	defer func() { TextFilters = saved }()
+	TextFilters = nil
+	if err := SetupRedaction("", true, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "SN_1.html")
+	os.WriteFile(path, []byte(`<h1 class="post-title">Security Now 1 with leo@twit.tv</h1>
+<p class="byline">Feb 14th 2025</p>
+<div class="body textual"><p>Leo (00:00:01): Email me at leo@twit.tv or call 555-123-4567.</p></div>`), 0644)
+	ep, err := LoadEpisode(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ep.Title != "Security Now 1 with [REDACTED]" {
+		t.Errorf("Title = %q", ep.Title)
+	}
+	if want := "EP:1 Date:25-02-14 TS:00:00:01 - Leo Email me at [REDACTED] or call [REDACTED]."; ep.Content != want {
+		t.Errorf("Content = %q, want %q", ep.Content, want)
+	}
+}
@@ -0,0 +1,81 @@
+package converter
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	// residualHTMLRegex matches what looks like markup in converted text:
+	// tags, closing tags cut short ("</div") and entities, including ones
+	// left by double encoding ("&amp;nbsp;" decodes to "&nbsp;")
+	residualHTMLRegex = regexp.MustCompile(`</?[A-Za-z][A-Za-z0-9-]*(?:\s[^<>]*)?/?>|</[A-Za-z][A-Za-z0-9-]*\b|&(?:[A-Za-z][A-Za-z0-9]*|#[0-9]+|#[xX][0-9A-Fa-f]+);`)
+
+	// htmlCommentRegex matches comments, which the usual pass reads as tags
+	// and ends at their first ">"
+	htmlCommentRegex = regexp.MustCompile(`(?s)<!--.*?-->`)
+	// quotedTagRegex matches a tag whose attribute values may hold ">"
+	quotedTagRegex = regexp.MustCompile(`<[A-Za-z/](?:"[^"]*"|'[^']*'|[^'"<>])*>`)
+	// spacedTagRegex matches tags split oddly: "< /div>", "</ div>", "</div\n>"
+	spacedTagRegex = regexp.MustCompile(`<\s+/?\s*[A-Za-z][A-Za-z0-9]*[^<>]*>|</\s+[A-Za-z][A-Za-z0-9]*\s*>|</[A-Za-z][A-Za-z0-9]*\s+>`)
+)
+
+// ResidualHTML returns the distinct HTML-looking sequences in converted
+// text, in order of appearance. Transcripts should have none; those left
+// are usually tags split oddly in the page or entities the usual pass does
+// not decode.
+func ResidualHTML(text string) []string {
+	var found []string
+	seen := make(map[string]bool)
+	for _, m := range residualHTMLRegex.FindAllString(text, -1) {
+		if !seen[m] {
+			seen[m] = true
+			found = append(found, m)
+		}
+	}
+	return found
+}
+
+// renderBody renders a transcript body with renderMarkup. If that leaves
+// HTML-looking sequences behind, the body is rendered again with
+// renderStrict, which is slower but handles malformed markup; the second
+// result reports true.
+func renderBody(body string) (string, bool) {
+	text := renderMarkup(body)
+	if !residualHTMLRegex.MatchString(text) {
+		return text, false
+	}
+	return renderStrict(body), true
+}
+
+// renderStrict is renderMarkup for malformed pages. Comments are dropped,
+// ">" inside attribute values is escaped and oddly split tags are joined
+// up before rendering; afterwards every entity is decoded (twice more for
+// double-encoded text) and tags still left are removed.
+func renderStrict(body string) string {
+	body = htmlCommentRegex.ReplaceAllString(body, "")
+	body = quotedTagRegex.ReplaceAllStringFunc(body, func(tag string) string {
+		return strings.ReplaceAll(tag[:len(tag)-1], ">", "&gt;") + ">"
+	})
+	body = spacedTagRegex.ReplaceAllStringFunc(body, func(tag string) string {
+		return "<" + strings.Join(strings.Fields(strings.Trim(tag, "<>")), " ") + ">"
+	})
+	body = strings.ReplaceAll(body, "< /", "</")
+
+	text := renderMarkup(body)
+	for i := 0; i < 3; i++ {
+		decoded := html.UnescapeString(text)
+		if decoded == text {
+			break
+		}
+		text = decoded
+	}
+	text = strings.ReplaceAll(text, "\u00a0", " ")
+	return anyTagRegex.ReplaceAllStringFunc(text, func(tag string) string {
+		if residualHTMLRegex.MatchString(tag) {
+			return ""
+		}
+		return tag
+	})
+}
@@ -0,0 +1,42 @@
+package converter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResidualHTML(t *testing.T) {
+	got := ResidualHTML("Leo <span class=\"x\">said</span> &mdash; and &amp;nbsp; then </div and </div")
+	want := []string{`<span class="x">`, "</span>", "&mdash;", "&amp;", "</div"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ResidualHTML = %q, want %q", got, want)
+	}
+	if got := ResidualHTML("Steve: 5 < 6 & 7 > 3, I <3 this"); len(got) != 0 {
+		t.Errorf("Plain text reported as HTML: %q", got)
+	}
+}
+
+func TestStrictMarkupPass(t *testing.T) {
+	for _, tc := range []struct{ name, body, want string }{
+		{"entity", "<p>00:01 - Leo Hello &mdash; there &#8220;friend&#8221;</p>", "Hello — there “friend”"},
+		{"double encoded", "<p>00:01 - Leo Rock &amp;amp; roll&amp;nbsp;forever</p>", "Rock & roll forever"},
+		{"quoted >", `<p>00:01 - Leo <span title="a > b">Hi</span> there</p>`, "Leo Hi there"},
+		{"comment", "<p>00:01 - Leo Hello <!-- <div> --> there</p>", "Hello there"},
+		{"split tags", "<p>00:01 - Leo Hello</p\n>< /div>there</ div>", "Hello"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			text := renderStrict(tc.body)
+			if !strings.Contains(strings.Join(strings.Fields(text), " "), tc.want) || len(ResidualHTML(text)) > 0 {
+				t.Errorf("Strict rendering %q, want %q without leftovers", text, tc.want)
+			}
+		})
+	}
+
+	if text, strict := renderBody("<p>00:01 - Leo Hello &mdash; there</p>"); !strict || !strings.Contains(text, "Hello — there") {
+		t.Errorf("renderBody = %q, %v; want the strict pass to decode the entity", text, strict)
+	}
+	if _, strict := renderBody("<p>00:01 - Leo <b>Hello</b> &amp; welcome</p>"); strict {
+		t.Error("Clean markup should not need the strict pass")
+	}
+}
@@ -0,0 +1,84 @@
+package converter
+
+import (
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/errs"
+)
+
+// Result is what processing a show, or a combined set of shows, did. The
+// same transcripts and options always give the same chunks, and chunks
+// whose content did not change are left untouched, so a second run reports
+// every chunk as unchanged.
+type Result struct {
+	Prefix string `json:"prefix"`
+	// Files is the number of transcript files found
+	Files int `json:"files"`
+	// Processed lists the files rendered into the output
+	Processed []string `json:"processed"`
+	// Skipped lists the files left out on purpose, with the reason
+	Skipped []SkippedFile `json:"skipped"`
+	// Failed lists the files that could not be processed and the chunks
+	// that could not be written
+	Failed []errs.Failure `json:"failed"`
+	// Quarantined lists where files that are not transcripts were moved
+	Quarantined []string `json:"quarantined,omitempty"`
+	// Chunks lists the chunk files of this run, in the order written
+	Chunks []ChunkFile `json:"chunks"`
+	// Removed lists the chunks of the previous run that were deleted
+	// because their episode ranges changed
+	Removed []string `json:"removed,omitempty"`
+	// Complete is set when the run covered the whole show, rather than a
+	// filtered part of it or one stopped by a --strict error class
+	Complete bool `json:"complete"`
+	// Stopped is set when Options.Stop ended the run before every file was
+	// processed
+	Stopped bool `json:"stopped,omitempty"`
+}
+
+// SkippedFile is a transcript file left out of a run
+type SkippedFile struct {
+	File   string `json:"file"`
+	Reason string `json:"reason"`
+}
+
+// ChunkFile is one chunk file written (or found unchanged) by a run
+type ChunkFile struct {
+	Path string `json:"path"`
+	// Lang is the language of a separate non-English chunk, "" for the
+	// main ones
+	Lang string `json:"lang,omitempty"`
+	// Start and End are the first and last episode numbers in the chunk
+	Start int `json:"start"`
+	End   int `json:"end"`
+	// First and Last are the dates of the first and last dated episodes
+	First time.Time `json:"first"`
+	Last  time.Time `json:"last"`
+	// Episodes counts the episodes, or parts of split ones, in the chunk
+	Episodes int   `json:"episodes"`
+	Words    int   `json:"words"`
+	Bytes    int64 `json:"bytes"`
+	// Changed is false if the file already had this content and was kept
+	Changed bool `json:"changed"`
+}
+
+// Changed returns the number of chunks whose content changed
+func (r *Result) Changed() int {
+	n := 0
+	for _, c := range r.Chunks {
+		if c.Changed {
+			n++
+		}
+	}
+	return n
+}
+
+// skip records a file left out of the run
+func (r *Result) skip(file, reason string) {
+	r.Skipped = append(r.Skipped, SkippedFile{File: file, Reason: reason})
+}
+
+// fail records a file or chunk that failed
+func (r *Result) fail(target string, err error) {
+	r.Failed = append(r.Failed, errs.Failure{Target: target, Class: errs.Class(err), Error: err.Error(), Time: time.Now().UTC()})
+}
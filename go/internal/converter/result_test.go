@@ -0,0 +1,128 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/utils"
+)
+
+func TestProcessPrefixResult(t *testing.T) {
+	tmpDir := t.TempDir()
+	for name, body := range map[string]string{
+		"IM_1.html": `<h1 class="post-title">Ep 1</h1><p class="byline">Feb 1st 2025</p><div class="body textual">Content 1</div>`,
+		"IM_2.html": `<h1 class="post-title">Ep 2</h1><p class="byline">Feb 2nd 2025</p><div class="body textual">Content 2</div>`,
+		"IM_3.html": `<html><body>Not a transcript</body></html>`,
+	} {
+		os.WriteFile(filepath.Join(tmpDir, name), []byte(body), 0644)
+	}
+
+	res, err := ProcessPrefixResult("IM", tmpDir, tmpDir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Files != 3 || len(res.Processed) != 2 || len(res.Skipped) != 0 || !res.Complete {
+		t.Errorf("Result = %+v", res)
+	}
+	if len(res.Failed) != 1 || res.Failed[0].Class != "parse" {
+		t.Errorf("Failed = %+v, want IM_3.html as a parse error", res.Failed)
+	}
+	if len(res.Chunks) != 1 {
+		t.Fatalf("Chunks = %+v", res.Chunks)
+	}
+	c := res.Chunks[0]
+	if filepath.Base(c.Path) != "IM_Transcripts_1-2.md" || c.Start != 1 || c.End != 2 || c.Episodes != 2 || !c.Changed {
+		t.Errorf("Chunk = %+v", c)
+	}
+	if info, err := os.Stat(c.Path); err != nil || info.Size() != c.Bytes {
+		t.Errorf("Chunk size %d does not match the file: %v, %v", c.Bytes, info, err)
+	}
+
+	// A second run writes no chunk and leaves the link index alone
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(c.Path, old, old)
+	links := filepath.Join(tmpDir, LinkIndexFile)
+	os.Chtimes(links, old, old)
+	res, err = ProcessPrefixResult("IM", tmpDir, tmpDir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Chunks) != 1 || res.Changed() != 0 {
+		t.Errorf("Second run = %+v, want one unchanged chunk", res.Chunks)
+	}
+	if info, _ := os.Stat(c.Path); !info.ModTime().Equal(old) {
+		t.Error("An unchanged chunk should not be rewritten")
+	}
+	if info, err := os.Stat(links); err != nil || !info.ModTime().Equal(old) {
+		t.Errorf("An unchanged link index should not be rewritten: %v", err)
+	}
+
+	filter, _ := NewFilter("2-", "", "")
+	res, err = ProcessPrefixResult("IM", tmpDir, tmpDir, Options{Filter: filter})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Skipped) != 1 || res.Skipped[0].File != filepath.Join(tmpDir, "IM_1.html") || res.Complete {
+		t.Errorf("Filtered run = %+v", res)
+	}
+}
+
+func TestSameContent(t *testing.T) {
+	tmpDir := t.TempDir()
+	big := strings.Repeat("0123456789abcdef", 10000) // Several compare blocks
+	files := map[string]string{"a": big, "b": big, "c": big[:len(big)-1] + "X", "d": big + "!"}
+	for name, body := range files {
+		os.WriteFile(filepath.Join(tmpDir, name), []byte(body), 0644)
+	}
+	path := func(name string) string { return filepath.Join(tmpDir, name) }
+	if !sameContent(path("a"), path("b")) {
+		t.Error("Identical files should match")
+	}
+	if sameContent(path("a"), path("c")) || sameContent(path("a"), path("d")) {
+		t.Error("Files differing in the last block or in size should not match")
+	}
+	if sameContent(path("a"), path("missing")) {
+		t.Error("A missing file should not match")
+	}
+}
+
+func TestProcessPrefixDeadline(t *testing.T) {
+	tmpDir := t.TempDir()
+	for i := 1; i <= 2; i++ {
+		body := fmt.Sprintf(`<h1 class="post-title">Ep %d</h1><p class="byline">Feb %dth 2025</p><div class="body textual">Content %d</div>`, i, i, i)
+		os.WriteFile(filepath.Join(tmpDir, fmt.Sprintf("IM_%d.html", i)), []byte(body), 0644)
+	}
+	if _, err := ProcessPrefixResult("IM", tmpDir, tmpDir, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Past the deadline no file is processed, the chunks are kept and the
+	// show is left outdated for the next run
+	deadline := utils.NewDeadline(time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	res, err := ProcessPrefixResult("IM", tmpDir, tmpDir, Options{Stop: deadline.Passed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Stopped || res.Complete || len(res.Processed) != 0 {
+		t.Errorf("Result = %+v, want stopped before any file", res)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "IM_Transcripts_1-2.md")); err != nil {
+		t.Errorf("The chunk of the last complete run is gone: %v", err)
+	}
+	if outdated, _, err := Outdated("IM", tmpDir); err != nil || !outdated {
+		t.Errorf("Outdated = %v, %v; want true after a stopped run", outdated, err)
+	}
+
+	res, err = ProcessPrefixResult("IM", tmpDir, tmpDir, Options{})
+	if err != nil || res.Stopped || !res.Complete {
+		t.Errorf("Resumed run = %+v, %v", res, err)
+	}
+	if outdated, _, _ := Outdated("IM", tmpDir); outdated {
+		t.Error("The show should be up to date after the resumed run")
+	}
+}
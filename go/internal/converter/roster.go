@@ -0,0 +1,65 @@
+package converter
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Roster lists the people credited on an episode page
+type Roster struct {
+	Hosts  []string
+	Guests []string
+}
+
+var (
+	// rosterBlockRegex matches host and guest sections, capturing the role
+	rosterBlockRegex = regexp.MustCompile(`(?s)<(div|section|ul)[^>]*class="[^"]*\b(hosts?|co-?hosts?|guests?)\b[^"]*"[^>]*>(.*?)</(?:div|section|ul)>`)
+	// personLinkRegex matches a link to a person's page
+	personLinkRegex = regexp.MustCompile(`(?s)<a[^>]+href="[^"]*/people/[^"]+"[^>]*>(.*?)</a>`)
+	// rosterLineRegex matches "Hosts: A, B and C" style credit lines
+	rosterLineRegex = regexp.MustCompile(`(?i)^\s*(hosts?|co-?hosts?|guests?)\s*:\s*(.+)$`)
+	// rosterSplitRegex separates names in a credit line
+	rosterSplitRegex = regexp.MustCompile(`\s*(?:,|&|\band\b)\s*`)
+)
+
+// ExtractRoster finds hosts and guests from an episode page's host/guest
+// sections (people links), falling back to "Hosts:"/"Guests:" credit lines
+func ExtractRoster(html string) Roster {
+	var r Roster
+	for _, block := range rosterBlockRegex.FindAllStringSubmatch(html, -1) {
+		for _, m := range personLinkRegex.FindAllStringSubmatch(block[3], -1) {
+			r.add(block[2], cleanText(m[1]))
+		}
+	}
+	if len(r.Hosts) > 0 || len(r.Guests) > 0 {
+		return r
+	}
+
+	for _, line := range blockBreakRegex.Split(html, -1) {
+		m := rosterLineRegex.FindStringSubmatch(cleanText(line))
+		if m == nil {
+			continue
+		}
+		for _, name := range rosterSplitRegex.Split(m[2], -1) {
+			r.add(m[1], name)
+		}
+	}
+	return r
+}
+
+func (r *Roster) add(role, name string) {
+	name = strings.TrimSpace(strings.TrimRight(name, "."))
+	if name == "" || len(name) > 60 {
+		return
+	}
+	list := &r.Hosts
+	if strings.HasPrefix(strings.ToLower(role), "guest") {
+		list = &r.Guests
+	}
+	for _, n := range *list {
+		if strings.EqualFold(n, name) {
+			return
+		}
+	}
+	*list = append(*list, name)
+}
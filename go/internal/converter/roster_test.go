@@ -0,0 +1,29 @@
+package converter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractRosterSections(t *testing.T) {
+	html := `<div class="hosts"><a href="/people/leo-laporte">Leo Laporte</a>
+<a href="/people/steve-gibson">Steve Gibson</a></div>
+<div class="guests"><a href="https://twit.tv/people/jane-doe">Jane Doe</a></div>
+<a href="/people/someone-else">Not credited</a>`
+	r := ExtractRoster(html)
+	if !reflect.DeepEqual(r.Hosts, []string{"Leo Laporte", "Steve Gibson"}) || !reflect.DeepEqual(r.Guests, []string{"Jane Doe"}) {
+		t.Errorf("Unexpected roster: %+v", r)
+	}
+}
+
+func TestExtractRosterCreditLines(t *testing.T) {
+	html := `<div class="show-notes"><p>Hosts: Leo Laporte &amp; Jeff Jarvis</p>
+<p>Guests: Jane Doe, John Roe and Ann Example.</p></div>`
+	r := ExtractRoster(html)
+	if !reflect.DeepEqual(r.Hosts, []string{"Leo Laporte", "Jeff Jarvis"}) {
+		t.Errorf("Unexpected hosts: %v", r.Hosts)
+	}
+	if !reflect.DeepEqual(r.Guests, []string{"Jane Doe", "John Roe", "Ann Example"}) {
+		t.Errorf("Unexpected guests: %v", r.Guests)
+	}
+}
@@ -0,0 +1,269 @@
+package converter
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+)
+
+// SpeakerProfile describes a person of a show well enough to recognize
+// them in a transcript whose speakers are only numbered, as speaker
+// diarization labels them ("SPEAKER_01")
+type SpeakerProfile struct {
+	Name string
+	Host bool // Credited as a host on the show's episode pages
+	// Phrases are three-word phrases (normalized as by phraseWords) the
+	// person says often and others rarely, most frequent first
+	Phrases []string
+}
+
+// Learning speaker profiles from published transcripts
+var (
+	// SpeakerSample is how many of a show's most recent published
+	// transcripts ShowSpeakers learns from
+	SpeakerSample = 30
+	// minPhraseCount is how often a person must say a phrase for it to
+	// be theirs, and phraseShare the share of all its uses they must have
+	minPhraseCount = 3
+	phraseShare    = 0.8
+	// maxPhrases caps the phrases kept per person
+	maxPhrases = 200
+)
+
+var (
+	// diarizationLabelRegex matches the numbered labels of speaker
+	// diarization: "SPEAKER_01" (pyannote, WhisperX), "Speaker 1", "spk0"
+	diarizationLabelRegex = regexp.MustCompile(`^(?i:speaker|spk)[ _-]?\d+$`)
+	// diarizedLineRegex matches a caption line starting with such a label,
+	// "[SPEAKER_01]: text" or "SPEAKER_01: text"
+	diarizedLineRegex = regexp.MustCompile(`^\[?((?i:speaker|spk)[ _-]?\d+)\]?\s*:\s*(.*)`)
+	// introRegex matches a speaker introducing themselves, capturing the name
+	introRegex = regexp.MustCompile(`\b(?:I'm|I am|[Tt]his is|[Mm]y name is)\s+(\p{Lu}[\p{L}'.\-]*(?:\s+\p{Lu}[\p{L}'.\-]*)?)`)
+)
+
+// IsDiarizationLabel reports whether a speaker name is only a diarization
+// label
+func IsDiarizationLabel(speaker string) bool {
+	return diarizationLabelRegex.MatchString(strings.TrimSpace(speaker))
+}
+
+// ShowSpeakers learns the profiles of a show's recurring speakers from its
+// SpeakerSample most recent transcripts that were published as such (not
+// made from captions or speech recognition)
+func ShowSpeakers(prefix, dataDir string) ([]SpeakerProfile, error) {
+	files, err := config.ActiveLayout.RawFiles(dataDir, prefix)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return GetEpNum(files[i]) > GetEpNum(files[j]) })
+	var episodes []Episode
+	for _, f := range files {
+		if len(episodes) >= SpeakerSample {
+			break
+		}
+		ep, err := LoadEpisode(f)
+		if err != nil || ep.Origin != "" {
+			continue
+		}
+		episodes = append(episodes, ep)
+	}
+	return LearnSpeakers(episodes), nil
+}
+
+// LearnSpeakers builds speaker profiles from episodes with named speaker
+// turns: every credited host, and everyone else who speaks in at least two
+// episodes, with the phrases that set them apart. Hosts come first.
+func LearnSpeakers(episodes []Episode) []SpeakerProfile {
+	hosts := make(map[string]bool)
+	inEpisodes := make(map[string]int)
+	uses := make(map[string]map[string]int) // Phrase -> speaker -> count
+	for _, ep := range episodes {
+		for _, h := range ep.Roster.Hosts {
+			hosts[h] = true
+		}
+		spoke := make(map[string]bool)
+		for i, seg := range Turns(ep.Content) {
+			if i >= len(ep.Turns) || ep.Turns[i].Speaker == "" {
+				continue
+			}
+			name := ep.Turns[i].Speaker
+			if IsDiarizationLabel(name) {
+				continue
+			}
+			spoke[name] = true
+			for _, p := range phrases(strings.TrimPrefix(seg.Text, name)) {
+				if uses[p] == nil {
+					uses[p] = make(map[string]int)
+				}
+				uses[p][name]++
+			}
+		}
+		for name := range spoke {
+			inEpisodes[name]++
+		}
+	}
+
+	type phraseCount struct {
+		phrase string
+		n      int
+	}
+	own := make(map[string][]phraseCount)
+	for p, by := range uses {
+		total := 0
+		for _, n := range by {
+			total += n
+		}
+		for name, n := range by {
+			if n >= minPhraseCount && float64(n) >= phraseShare*float64(total) {
+				own[name] = append(own[name], phraseCount{p, n})
+			}
+		}
+	}
+
+	var profiles []SpeakerProfile
+	for name := range hosts {
+		profiles = append(profiles, SpeakerProfile{Name: name, Host: true})
+	}
+	for name, n := range inEpisodes {
+		if n >= 2 && !hosts[name] {
+			profiles = append(profiles, SpeakerProfile{Name: name})
+		}
+	}
+	for i := range profiles {
+		pcs := own[profiles[i].Name]
+		sort.Slice(pcs, func(a, b int) bool {
+			if pcs[a].n != pcs[b].n {
+				return pcs[a].n > pcs[b].n
+			}
+			return pcs[a].phrase < pcs[b].phrase
+		})
+		for k := 0; k < len(pcs) && k < maxPhrases; k++ {
+			profiles[i].Phrases = append(profiles[i].Phrases, pcs[k].phrase)
+		}
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		if profiles[i].Host != profiles[j].Host {
+			return profiles[i].Host
+		}
+		return profiles[i].Name < profiles[j].Name
+	})
+	return profiles
+}
+
+// ReconcileSpeakers renames the diarization labels of cues to the people
+// of profiles. A label is matched to a person who introduces themselves
+// under it ("I'm Leo Laporte", "this is Steve"), or else whose phrases it
+// uses most, taking the strongest matches first and each person once.
+// Labels without a convincing match are left as they are. Returns the
+// labels that were renamed and their names.
+func ReconcileSpeakers(cues []Cue, profiles []SpeakerProfile) map[string]string {
+	type label struct {
+		words   int
+		phrases map[string]int
+		intros  []string
+	}
+	labels := make(map[string]*label)
+	var order []string
+	for _, c := range cues {
+		if !IsDiarizationLabel(c.Speaker) {
+			continue
+		}
+		l := labels[c.Speaker]
+		if l == nil {
+			l = &label{phrases: make(map[string]int)}
+			labels[c.Speaker] = l
+			order = append(order, c.Speaker)
+		}
+		l.words += len(phraseWords(c.Text))
+		for _, p := range phrases(c.Text) {
+			l.phrases[p]++
+		}
+		for _, m := range introRegex.FindAllStringSubmatch(c.Text, -1) {
+			l.intros = append(l.intros, strings.TrimRight(m[1], ".'-"))
+		}
+	}
+	if len(labels) == 0 || len(profiles) == 0 {
+		return nil
+	}
+
+	// A self-introduction outweighs any number of phrases
+	const introScore = 1e6
+	type match struct {
+		label   string
+		profile int
+		score   float64
+	}
+	var matches []match
+	for _, name := range order {
+		l := labels[name]
+		for i, p := range profiles {
+			score := 0.0
+			for _, intro := range l.intros {
+				if namesPerson(intro, p.Name) {
+					score = introScore
+				}
+			}
+			hits := 0
+			for _, ph := range p.Phrases {
+				hits += l.phrases[ph]
+			}
+			if hits >= minPhraseCount {
+				// Per thousand words, so long-winded labels do not win
+				// every person
+				score += 1000 * float64(hits) / float64(l.words)
+			}
+			if score > 0 {
+				matches = append(matches, match{name, i, score})
+			}
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	renamed := make(map[string]string)
+	taken := make(map[int]bool)
+	for _, m := range matches {
+		if _, done := renamed[m.label]; done || taken[m.profile] {
+			continue
+		}
+		renamed[m.label] = profiles[m.profile].Name
+		taken[m.profile] = true
+	}
+	for i := range cues {
+		if name, ok := renamed[cues[i].Speaker]; ok {
+			cues[i].Speaker = name
+		}
+	}
+	return renamed
+}
+
+// namesPerson reports whether a name someone introduced themselves with is
+// a person's full or first name
+func namesPerson(intro, name string) bool {
+	if strings.EqualFold(intro, name) {
+		return true
+	}
+	fields := strings.Fields(name)
+	return !strings.Contains(intro, " ") && len(fields) > 1 && strings.EqualFold(intro, fields[0])
+}
+
+// phraseWords lowercases text and splits it into words, dropping
+// punctuation and apostrophes
+func phraseWords(s string) []string {
+	s = strings.NewReplacer("'", "", "’", "").Replace(strings.ToLower(s))
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// phrases returns the three-word phrases of a text
+func phrases(s string) []string {
+	words := phraseWords(s)
+	var out []string
+	for i := 0; i+3 <= len(words); i++ {
+		out = append(out, words[i]+" "+words[i+1]+" "+words[i+2])
+	}
+	return out
+}
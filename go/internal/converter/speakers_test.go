@@ -0,0 +1,98 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// speakerPage is a transcript page whose hosts are credited in its header,
+// with one turn per speaker and line
+func speakerPage(n int, turns ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<h1 class="post-title">Security Now %d</h1><p class="byline">Feb 11th 2025</p>`, n)
+	b.WriteString(`<div class="hosts"><a href="/people/leo-laporte">Leo Laporte</a><a href="/people/steve-gibson">Steve Gibson</a></div>`)
+	b.WriteString(`<div class="body textual">`)
+	for i := 0; i+1 < len(turns); i += 2 {
+		fmt.Fprintf(&b, "<p>0:00:%02d - %s<br>\n%s</p>\n", i, turns[i], turns[i+1])
+	}
+	b.WriteString(`</div>`)
+	return b.String()
+}
+
+func TestShowSpeakers(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "twittest")
+	defer os.RemoveAll(tmpDir)
+	for n := 1; n <= 2; n++ {
+		page := speakerPage(n,
+			"Leo Laporte", "Hello everybody, let's take a break. Let's take a break now.",
+			"Steve Gibson", "Our picture of the week, the picture of the week.",
+			"Jane Doe", "Let's take a look at the picture of the week.",
+		)
+		os.WriteFile(filepath.Join(tmpDir, fmt.Sprintf("SN_%d.html", n)), []byte(page), 0644)
+	}
+	// "lets take a" is Jane's too often to be Leo's. Transcripts made from
+	// speech recognition are not learned from
+	asr := speakerPage(3, "Leo Laporte", "Yabba dabba doo. Yabba dabba doo. Yabba dabba doo.")
+	os.WriteFile(filepath.Join(tmpDir, "SN_3.html"), []byte(strings.Replace(asr, "<h1", `<meta name="twit-archiver-origin" content="asr"><h1`, 1)), 0644)
+
+	profiles, err := ShowSpeakers("SN", tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SpeakerProfile{
+		{Name: "Leo Laporte", Host: true, Phrases: []string{"take a break"}},
+		{Name: "Steve Gibson", Host: true},
+		{Name: "Jane Doe"},
+	}
+	if !reflect.DeepEqual(profiles, want) {
+		t.Errorf("ShowSpeakers =\n%+v\nwant\n%+v", profiles, want)
+	}
+}
+
+func TestReconcileSpeakers(t *testing.T) {
+	profiles := []SpeakerProfile{
+		{Name: "Leo Laporte", Host: true, Phrases: []string{"take a break", "its time for"}},
+		{Name: "Steve Gibson", Host: true, Phrases: []string{"picture of the", "of the week"}},
+		{Name: "Jane Doe"},
+	}
+	cues := []Cue{
+		{Speaker: "SPEAKER_00", Text: "It's time for Security Now. Let's take a break, take a break."},
+		{Speaker: "SPEAKER_01", Text: "Here's the picture of the week. The picture of the week."},
+		{Speaker: "SPEAKER_02", Text: "Hi, I'm Jane and I found the bug."},
+		{Speaker: "SPEAKER_03", Text: "Nothing to go by."},
+		{Speaker: "Leo Laporte", Text: "Named already."},
+	}
+	renamed := ReconcileSpeakers(cues, profiles)
+	want := map[string]string{"SPEAKER_00": "Leo Laporte", "SPEAKER_01": "Steve Gibson", "SPEAKER_02": "Jane Doe"}
+	if !reflect.DeepEqual(renamed, want) {
+		t.Errorf("ReconcileSpeakers = %v, want %v", renamed, want)
+	}
+	var got []string
+	for _, c := range cues {
+		got = append(got, c.Speaker)
+	}
+	if !reflect.DeepEqual(got, []string{"Leo Laporte", "Steve Gibson", "Jane Doe", "SPEAKER_03", "Leo Laporte"}) {
+		t.Errorf("Speakers after reconciling = %v", got)
+	}
+
+	// The same person is not given to two labels
+	cues = []Cue{
+		{Speaker: "Speaker 1", Text: "This is Leo Laporte."},
+		{Speaker: "Speaker 2", Text: "Take a break, take a break, take a break."},
+	}
+	if renamed := ReconcileSpeakers(cues, profiles); !reflect.DeepEqual(renamed, map[string]string{"Speaker 1": "Leo Laporte"}) {
+		t.Errorf("ReconcileSpeakers = %v", renamed)
+	}
+}
+
+func TestIsDiarizationLabel(t *testing.T) {
+	for s, want := range map[string]bool{"SPEAKER_01": true, "Speaker 2": true, "spk0": true, "Leo Laporte": false, "Speaker": false} {
+		if got := IsDiarizationLabel(s); got != want {
+			t.Errorf("IsDiarizationLabel(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package converter
+
+import (
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+)
+
+// Default templates, matching the original hardcoded output
+const (
+	DefaultEpisodeTemplate = "# Episode: {{.Title}}{{if .Continued}} (continued){{end}}\n**Date:** {{.DateStr}}\n" +
+		"{{with .URL}}**Source:** {{.}}{{with $.Origin}} ({{.}}){{end}}\n{{end}}" +
+		"**Archived:** {{with date \"2006-01-02 15:04 UTC\" .Fetched}}fetched {{.}}, {{end}}converted by twit-transcript-archiver {{.Converter}}\n" +
+		"\n{{.Content}}\n\n---\n\n"
+	DefaultChunkTemplate = "{{.TOC}}{{.Body}}"
+)
+
+// EpisodeData is the value an episode template is executed with. When an
+// episode is split across chunks the template runs once per part, with
+// Content holding that part and Continued set on all but the first.
+type EpisodeData struct {
+	Prefix    string
+	Show      string
+	Number    int
+	Title     string
+	DateStr   string    // Byline date as published
+	Date      time.Time // Zero if the byline could not be parsed
+	Year      int
+	Content   string
+	Continued bool
+	Anchor    string  // Id of the episode's anchor in chunk files (see EpisodeAnchor)
+	Episode   Episode // The full parsed episode
+	// Provenance: where and when the page was downloaded (empty and zero if
+	// unknown) and the archiver version that converted it
+	URL       string
+	Fetched   time.Time
+	Converter string
+	// Origin is Episode.Origin: "feed", "captions" or "asr" for
+	// transcripts that were not published as pages
+	Origin string
+}
+
+// ChunkData is the value a chunk template is executed with
+type ChunkData struct {
+	Prefix     string
+	Show       string
+	Start, End int // First and last episode numbers
+	Year       int
+	Episodes   []TOCEntry // The episodes in the chunk, empty if Options.TOC is off
+	TOC        string     // Episodes rendered as a Markdown table of contents
+	Body       string     // The rendered episodes
+}
+
+// Templates renders episodes and chunk files
+type Templates struct {
+	Episode *template.Template
+	Chunk   *template.Template
+}
+
+// templateFuncs are available in every template
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"join":  strings.Join,
+	"date": func(layout string, t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(layout)
+	},
+}
+
+// DefaultTemplates returns the built-in templates
+func DefaultTemplates() *Templates {
+	t, _ := ParseTemplates(DefaultEpisodeTemplate, DefaultChunkTemplate)
+	return t
+}
+
+// ParseTemplates compiles episode and chunk templates. An empty text selects
+// the default.
+func ParseTemplates(episode, chunk string) (*Templates, error) {
+	if episode == "" {
+		episode = DefaultEpisodeTemplate
+	}
+	if chunk == "" {
+		chunk = DefaultChunkTemplate
+	}
+	ep, err := template.New("episode").Funcs(templateFuncs).Parse(episode)
+	if err != nil {
+		return nil, err
+	}
+	ch, err := template.New("chunk").Funcs(templateFuncs).Parse(chunk)
+	if err != nil {
+		return nil, err
+	}
+	return &Templates{Episode: ep, Chunk: ch}, nil
+}
+
+// LoadTemplates reads episode and chunk templates from files. An empty path
+// selects the default.
+func LoadTemplates(episodePath, chunkPath string) (*Templates, error) {
+	read := func(path string) (string, error) {
+		if path == "" {
+			return "", nil
+		}
+		data, err := os.ReadFile(path)
+		return string(data), err
+	}
+	episode, err := read(episodePath)
+	if err != nil {
+		return nil, err
+	}
+	chunk, err := read(chunkPath)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTemplates(episode, chunk)
+}
+
+// RenderEpisode renders one episode, or one part of a split episode
+func (t *Templates) RenderEpisode(ep Episode, content string, continued bool) (string, error) {
+	var b strings.Builder
+	err := t.Episode.Execute(&b, EpisodeData{
+		Prefix:    ep.Prefix,
+		Show:      config.ShowName(ep.Prefix),
+		Number:    ep.Number,
+		Title:     ep.Title,
+		DateStr:   ep.DateStr,
+		Date:      ep.Date,
+		Year:      ep.Year,
+		Content:   content,
+		Continued: continued,
+		Anchor:    EpisodeAnchor(ep.Prefix, ep.Number),
+		Episode:   ep,
+		URL:       ep.URL,
+		Fetched:   ep.Fetched,
+		Converter: config.Version,
+		Origin:    ep.Origin,
+	})
+	return b.String(), err
+}
+
+// RenderChunk renders a chunk file around its episodes
+func (t *Templates) RenderChunk(d ChunkData) (string, error) {
+	var b strings.Builder
+	err := t.Chunk.Execute(&b, d)
+	return b.String(), err
+}
+
+// bodyPlaceholder stands in for .Body when rendering a chunk's frame
+const bodyPlaceholder = "\x00body\x00"
+
+// ChunkFrame renders a chunk file without its episodes, returning the text
+// before and after .Body, so the body can be streamed in between. A template
+// that uses .Body more than once only gets it at the first use.
+func (t *Templates) ChunkFrame(d ChunkData) (header, footer string, err error) {
+	d.Body = bodyPlaceholder
+	text, err := t.RenderChunk(d)
+	if err != nil {
+		return "", "", err
+	}
+	header, footer, _ = strings.Cut(text, bodyPlaceholder)
+	return header, strings.ReplaceAll(footer, bodyPlaceholder, ""), nil
+}
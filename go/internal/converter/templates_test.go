@@ -0,0 +1,70 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefaultTemplates(t *testing.T) {
+	tmpl := DefaultTemplates()
+	ep := Episode{Title: "Ep 1", DateStr: "Feb 1st 2025"}
+	got, err := tmpl.RenderEpisode(ep, "Body", false)
+	if err != nil || got != "# Episode: Ep 1\n**Date:** Feb 1st 2025\n**Archived:** converted by twit-transcript-archiver dev\n\nBody\n\n---\n\n" {
+		t.Errorf("Unexpected default rendering %q, %v", got, err)
+	}
+	ep.URL, ep.Fetched = "https://twit.tv/posts/transcripts/ep-1", time.Date(2025, 2, 2, 9, 5, 0, 0, time.UTC)
+	got, _ = tmpl.RenderEpisode(ep, "Body", false)
+	if !strings.Contains(got, "**Source:** https://twit.tv/posts/transcripts/ep-1\n**Archived:** fetched 2025-02-02 09:05 UTC, converted by twit-transcript-archiver dev\n") {
+		t.Errorf("Unexpected provenance %q", got)
+	}
+	ep.Origin = OriginCaptions
+	if got, _ = tmpl.RenderEpisode(ep, "Body", false); !strings.Contains(got, "**Source:** https://twit.tv/posts/transcripts/ep-1 (captions)\n") {
+		t.Errorf("Unexpected origin %q", got)
+	}
+	got, _ = tmpl.RenderEpisode(ep, "More", true)
+	if !strings.HasPrefix(got, "# Episode: Ep 1 (continued)\n") {
+		t.Errorf("Unexpected continued rendering %q", got)
+	}
+}
+
+func TestCustomTemplates(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "twittest")
+	defer os.RemoveAll(tmpDir)
+	os.WriteFile(filepath.Join(tmpDir, "SN_1.html"), []byte(`<h1 class="post-title">Security Now 1</h1><p class="byline">Feb 11th 2025</p><div class="body textual">Hello</div>`), 0644)
+
+	tmpl, err := ParseTemplates(
+		"== {{.Show | upper}} #{{.Number}} ({{date \"2006-01-02\" .Date}}) ==\n{{.Content}}\n",
+		"<!-- {{.Prefix}} {{.Start}}-{{.End}} -->\n{{.Body}}",
+	)
+	if err != nil {
+		t.Fatalf("ParseTemplates failed: %v", err)
+	}
+	ProcessPrefixWithOptions("SN", tmpDir, tmpDir, Options{Templates: tmpl})
+
+	out, _ := os.ReadFile(filepath.Join(tmpDir, "SN_Transcripts_1-1.md"))
+	want := "<!-- SN 1-1 -->\n== SECURITY NOW #1 (2025-02-11) ==\nEP:1 Date:25-02-11 - Hello\n"
+	if string(out) != want {
+		t.Errorf("Unexpected output:\n%q\nwant\n%q", out, want)
+	}
+
+	if _, err := ParseTemplates("{{.Nope", ""); err == nil {
+		t.Error("Expected a parse error")
+	}
+}
+
+func TestChunkFrame(t *testing.T) {
+	tmpl, _ := ParseTemplates("", "<!-- {{.Start}}-{{.End}} -->\n{{.Body}}\n<!-- end {{.Body}}-->")
+	header, footer, err := tmpl.ChunkFrame(ChunkData{Start: 1, End: 9})
+	if err != nil || header != "<!-- 1-9 -->\n" || footer != "\n<!-- end -->" {
+		t.Errorf("ChunkFrame = %q, %q, %v", header, footer, err)
+	}
+
+	tmpl, _ = ParseTemplates("", "no body")
+	header, footer, _ = tmpl.ChunkFrame(ChunkData{})
+	if header != "no body" || footer != "" {
+		t.Errorf("ChunkFrame without .Body = %q, %q", header, footer)
+	}
+}
@@ -0,0 +1,139 @@
+package converter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimestampMode controls how timecodes appear in the Markdown output
+type TimestampMode string
+
+const (
+	// TimestampsKeep leaves timecodes as they appear in the transcript
+	TimestampsKeep TimestampMode = "keep"
+	// TimestampsNormalize rewrites every timecode as zero-padded HH:MM:SS
+	TimestampsNormalize TimestampMode = "normalize"
+	// TimestampsStrip removes timecodes for clean prose
+	TimestampsStrip TimestampMode = "strip"
+)
+
+var (
+	// turnTimestampRegex matches the TS field of a standardized turn prefix
+	turnTimestampRegex = regexp.MustCompile(`^(EP:\d+ Date:\S+) TS:(\S+)`)
+	// inlineTimecodeRegex matches timecodes in running text, e.g. "(00:12:34)"
+	inlineTimecodeRegex = regexp.MustCompile(`\s*[(\[](\d{1,2}:\d{2}(?::\d{2})?)[)\]]`)
+)
+
+// ParseTimestampMode validates a timestamp mode name
+func ParseTimestampMode(s string) (TimestampMode, error) {
+	switch m := TimestampMode(strings.ToLower(s)); m {
+	case "":
+		return TimestampsKeep, nil
+	case TimestampsKeep, TimestampsNormalize, TimestampsStrip:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown timestamp mode '%s' (want keep, normalize or strip)", s)
+}
+
+// ParseTimecode converts "MM:SS" or "H:MM:SS" to an offset into the episode
+func ParseTimecode(s string) (time.Duration, bool) {
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, false
+	}
+	var total time.Duration
+	for _, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return 0, false
+		}
+		total = total*60 + time.Duration(n)
+	}
+	return total * time.Second, true
+}
+
+// FormatTimecode renders an offset as HH:MM:SS
+func FormatTimecode(d time.Duration) string {
+	s := int(d / time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", s/3600, s/60%60, s%60)
+}
+
+// Segment is a timed speaker turn
+type Segment struct {
+	Start    time.Duration
+	Timecode string // Start as HH:MM:SS
+	Line     int    // Index of the turn in the episode content
+	Text     string // Speaker and text, without the EP/Date/TS prefix
+}
+
+// Turns extracts every speaker turn from standardized Markdown. A turn's
+// time comes from its TS field or, failing that, from the first inline
+// timecode in its text; untimed turns have an empty Timecode.
+func Turns(content string) []Segment {
+	var segs []Segment
+	for i, line := range strings.Split(content, "\n") {
+		if !strings.HasPrefix(line, "EP:") {
+			continue
+		}
+		tc := ""
+		if m := turnTimestampRegex.FindStringSubmatch(line); m != nil {
+			tc = m[2]
+		} else if m := inlineTimecodeRegex.FindStringSubmatch(line); m != nil {
+			tc = m[1]
+		}
+		seg := Segment{Line: i}
+		if d, ok := ParseTimecode(tc); ok {
+			seg.Start = d
+			seg.Timecode = FormatTimecode(d)
+		}
+		text := inlineTimecodeRegex.ReplaceAllString(turnText(line), "")
+		seg.Text = strings.TrimSpace(text)
+		segs = append(segs, seg)
+	}
+	return segs
+}
+
+// Segments extracts the timed turns from standardized Markdown, skipping
+// untimed ones
+func Segments(content string) []Segment {
+	var segs []Segment
+	for _, seg := range Turns(content) {
+		if seg.Timecode != "" {
+			segs = append(segs, seg)
+		}
+	}
+	return segs
+}
+
+// ApplyTimestampMode normalizes or strips the timecodes in standardized
+// Markdown
+func ApplyTimestampMode(content string, mode TimestampMode) string {
+	if mode == "" || mode == TimestampsKeep {
+		return content
+	}
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if mode == TimestampsStrip {
+			line = turnTimestampRegex.ReplaceAllString(line, "$1")
+			line = inlineTimecodeRegex.ReplaceAllString(line, "")
+		} else {
+			line = turnTimestampRegex.ReplaceAllStringFunc(line, func(m string) string {
+				sub := turnTimestampRegex.FindStringSubmatch(m)
+				if d, ok := ParseTimecode(sub[2]); ok {
+					return sub[1] + " TS:" + FormatTimecode(d)
+				}
+				return m
+			})
+			line = inlineTimecodeRegex.ReplaceAllStringFunc(line, func(m string) string {
+				sub := inlineTimecodeRegex.FindStringSubmatch(m)
+				d, _ := ParseTimecode(sub[1])
+				return strings.Replace(m, sub[1], FormatTimecode(d), 1)
+			})
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,81 @@
+package converter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimecode(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Duration
+		ok    bool
+	}{
+		{"12:34", 12*time.Minute + 34*time.Second, true},
+		{"1:02:03", time.Hour + 2*time.Minute + 3*time.Second, true},
+		{"00:00:05", 5 * time.Second, true},
+		{"12", 0, false},
+		{"a:b", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseTimecode(tt.input)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseTimecode(%q) = %v, %v", tt.input, got, ok)
+		}
+	}
+	if s := FormatTimecode(time.Hour + 2*time.Minute + 3*time.Second); s != "01:02:03" {
+		t.Errorf("FormatTimecode = %q", s)
+	}
+}
+
+const timedContent = `EP:1 Date:24-01-01 TS:0:05 - Leo Laporte Hello there.
+EP:1 Date:24-01-01 - Steve Gibson As I said (1:02:03) earlier.
+EP:1 Date:24-01-01 - Steve Gibson No time here.
+# Heading`
+
+func TestSegments(t *testing.T) {
+	segs := Segments(timedContent)
+	if len(segs) != 2 {
+		t.Fatalf("Expected 2 segments, got %+v", segs)
+	}
+	if segs[0].Timecode != "00:00:05" || segs[0].Line != 0 || segs[0].Text != "Leo Laporte Hello there." {
+		t.Errorf("Unexpected first segment: %+v", segs[0])
+	}
+	if segs[1].Start != time.Hour+2*time.Minute+3*time.Second || segs[1].Text != "Steve Gibson As I said earlier." {
+		t.Errorf("Unexpected second segment: %+v", segs[1])
+	}
+}
+
+func TestTurns(t *testing.T) {
+	turns := Turns(timedContent)
+	if len(turns) != 3 {
+		t.Fatalf("Expected 3 turns, got %+v", turns)
+	}
+	if turns[2].Timecode != "" || turns[2].Text != "Steve Gibson No time here." {
+		t.Errorf("Unexpected untimed turn: %+v", turns[2])
+	}
+}
+
+func TestApplyTimestampMode(t *testing.T) {
+	normalized := ApplyTimestampMode(timedContent, TimestampsNormalize)
+	want := `EP:1 Date:24-01-01 TS:00:00:05 - Leo Laporte Hello there.
+EP:1 Date:24-01-01 - Steve Gibson As I said (01:02:03) earlier.
+EP:1 Date:24-01-01 - Steve Gibson No time here.
+# Heading`
+	if normalized != want {
+		t.Errorf("Normalize:\n%s", normalized)
+	}
+
+	stripped := ApplyTimestampMode(timedContent, TimestampsStrip)
+	want = `EP:1 Date:24-01-01 - Leo Laporte Hello there.
+EP:1 Date:24-01-01 - Steve Gibson As I said earlier.
+EP:1 Date:24-01-01 - Steve Gibson No time here.
+# Heading`
+	if stripped != want {
+		t.Errorf("Strip:\n%s", stripped)
+	}
+
+	if ApplyTimestampMode(timedContent, TimestampsKeep) != timedContent {
+		t.Error("Keep should not change the content")
+	}
+}
@@ -0,0 +1,48 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TOCEntry is an episode listed in a chunk file's table of contents
+type TOCEntry struct {
+	Number    int
+	Title     string
+	DateStr   string
+	Anchor    string
+	Continued bool // The episode started in an earlier chunk
+}
+
+// EpisodeAnchor returns the id of the anchor written before an episode in
+// chunk files ("sn-950"). It depends only on the show and number, so links
+// to it survive re-processing.
+func EpisodeAnchor(prefix string, number int) string {
+	return fmt.Sprintf("%s-%d", strings.ToLower(prefix), number)
+}
+
+// anchorTag marks the start of an episode for TOC links
+func anchorTag(anchor string) string {
+	return `<a id="` + anchor + `"></a>` + "\n"
+}
+
+// RenderTOC renders a chunk's table of contents as a Markdown list
+func RenderTOC(entries []TOCEntry) string {
+	if len(entries) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("## Contents\n\n")
+	for _, e := range entries {
+		fmt.Fprintf(&b, "* [Episode %d](#%s): %s", e.Number, e.Anchor, e.Title)
+		if e.DateStr != "" {
+			fmt.Fprintf(&b, " (%s)", e.DateStr)
+		}
+		if e.Continued {
+			b.WriteString(", continued")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n---\n\n")
+	return b.String()
+}
@@ -0,0 +1,88 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderTOC(t *testing.T) {
+	if got := RenderTOC(nil); got != "" {
+		t.Errorf("Empty TOC = %q", got)
+	}
+	got := RenderTOC([]TOCEntry{
+		{Number: 950, Title: "Security Now 950 Transcript", DateStr: "May 10th 2022", Anchor: "sn-950", Continued: true},
+		{Number: 951, Title: "Security Now 951 Transcript", Anchor: "sn-951"},
+	})
+	want := "## Contents\n\n" +
+		"* [Episode 950](#sn-950): Security Now 950 Transcript (May 10th 2022), continued\n" +
+		"* [Episode 951](#sn-951): Security Now 951 Transcript\n" +
+		"\n---\n\n"
+	if got != want {
+		t.Errorf("RenderTOC =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestProcessPrefixTOC(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "toctest")
+	defer os.RemoveAll(tmpDir)
+
+	for ep := 1; ep <= 3; ep++ {
+		var turns strings.Builder
+		for i := 0; i < 20; i++ {
+			fmt.Fprintf(&turns, "<p>Leo (00:00:%02d): Turn %d of episode %d.</p>\n", i, i, ep)
+		}
+		os.WriteFile(filepath.Join(tmpDir, fmt.Sprintf("SN_%d.html", ep)), []byte(fmt.Sprintf(
+			"<h1 class=\"post-title\">Security Now %d Transcript</h1>\n<p class=\"byline\">Feb %dth 2025</p>\n<div class=\"body textual\">%s</div>", ep, ep+3, turns.String())), 0644)
+	}
+
+	// Each episode is about 200 words, so each is split across chunks
+	opts := Options{Mode: ChunkByTurn, MaxWords: 150, TOC: true}
+	if err := ProcessPrefixWithOptions("SN", tmpDir, tmpDir, opts); err != nil {
+		t.Fatal(err)
+	}
+	chunks, _ := filepath.Glob(filepath.Join(tmpDir, "SN_Transcripts_*.md"))
+	if len(chunks) < 2 {
+		t.Fatalf("Expected several chunks, got %v", chunks)
+	}
+	continued := false
+	for _, path := range chunks {
+		data, _ := os.ReadFile(path)
+		text := string(data)
+		if !strings.HasPrefix(text, "## Contents\n\n* [Episode ") {
+			t.Errorf("%s does not start with a TOC:\n%s", path, text)
+		}
+		// Every TOC link has a matching anchor in the same file
+		for _, line := range strings.Split(text, "\n") {
+			start := strings.Index(line, "](#")
+			if !strings.HasPrefix(line, "* [Episode ") || start < 0 {
+				continue
+			}
+			anchor := line[start+3 : strings.Index(line, "):")]
+			if !strings.Contains(text, `<a id="`+anchor+`"></a>`+"\n# Episode: ") {
+				t.Errorf("%s: no anchor for %q", path, anchor)
+			}
+			continued = continued || strings.HasSuffix(line, ", continued")
+		}
+	}
+	if !continued {
+		t.Error("No TOC entry marked as continued")
+	}
+
+	// Without TOC the output is unchanged
+	os.RemoveAll(filepath.Join(tmpDir, "SN_chunks.json"))
+	for _, path := range chunks {
+		os.Remove(path)
+	}
+	opts.TOC = false
+	ProcessPrefixWithOptions("SN", tmpDir, tmpDir, opts)
+	chunks, _ = filepath.Glob(filepath.Join(tmpDir, "SN_Transcripts_*.md"))
+	for _, path := range chunks {
+		data, _ := os.ReadFile(path)
+		if !strings.HasPrefix(string(data), "# Episode: ") || strings.Contains(string(data), "<a id=") {
+			t.Errorf("%s has TOC output with TOC off:\n%.200s", path, data)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+// Package digest summarizes what changed in the archive since the last
+// digest: new episodes, revisions set aside as alternatives, new gaps in
+// the episode numbers and failed downloads, for mailing to maintainers who
+// do not watch the logs
+package digest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/errs"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+	"github.com/aramova/twit-transcript-archiver/go/internal/scraper"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// StateFile records what the last digest reported, in the data directory
+const StateFile = "digest.json"
+
+// State is what the archive held when the last digest was sent
+type State struct {
+	Sent         time.Time        `json:"sent"`
+	Episodes     []string         `json:"episodes"`     // Index keys
+	Alternatives []string         `json:"alternatives"` // Paths relative to the data directory
+	Gaps         map[string][]int `json:"gaps"`
+}
+
+// LoadState reads the state of the last digest; nil if none was sent
+func LoadState(dataDir string) (*State, error) {
+	data, err := storage.ReadFile(storage.Join(dataDir, StateFile))
+	if errors.Is(err, storage.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := &State{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", StateFile, err)
+	}
+	return s, nil
+}
+
+// Save writes the state for the next digest to start from
+func (s *State) Save(dataDir string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return storage.WriteFile(storage.Join(dataDir, StateFile), append(data, '\n'))
+}
+
+// Revision is a copy of an episode that was set aside as an alternative
+type Revision struct {
+	Prefix string
+	Number int
+	Kind   string
+	Path   string // Relative to the data directory
+}
+
+// Digest is what changed between two digests
+type Digest struct {
+	Since, Until time.Time
+	// First is set when no digest was sent before: everything archived
+	// is the baseline, not news
+	First    bool
+	Episodes int // Episodes in the archive now
+	New      []*index.Entry
+	// Revisions are the alternatives that appeared since
+	Revisions []Revision
+	// NewGaps are the missing episode numbers that were not missing before,
+	// Gaps counts all missing episodes by show
+	NewGaps map[string][]int
+	Gaps    map[string]int
+	// Failures are those recorded in the errors report since
+	Failures []errs.Failure
+}
+
+// Build compares the archive with the state of the last digest (nil for
+// none) and returns the digest and the state to save once it is sent.
+// reportPath is the errors report of the latest run.
+func Build(dataDir, reportPath string, last *State, now time.Time) (*Digest, *State, error) {
+	ix, err := index.Load(dataDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	d := &Digest{Until: now, First: last == nil, NewGaps: make(map[string][]int), Gaps: make(map[string]int)}
+	next := &State{Sent: now, Gaps: make(map[string][]int)}
+	known := make(map[string]bool)
+	if last != nil {
+		d.Since = last.Sent
+		for _, k := range last.Episodes {
+			known[k] = true
+		}
+		for _, p := range last.Alternatives {
+			known[p] = true
+		}
+	}
+
+	prefixes := make(map[string]bool)
+	for _, e := range ix.Sorted() {
+		if e.Pending {
+			continue // Counted once its transcript is published
+		}
+		key := index.Key(e.File)
+		next.Episodes = append(next.Episodes, key)
+		prefixes[e.Prefix] = true
+		d.Episodes++
+		if last != nil && !known[key] {
+			d.New = append(d.New, e)
+		}
+	}
+
+	var shows []string
+	for prefix := range prefixes {
+		shows = append(shows, prefix)
+	}
+	sort.Strings(shows)
+	for _, prefix := range shows {
+		alts, err := converter.Alternatives(dataDir, prefix)
+		if err != nil {
+			return nil, nil, err
+		}
+		var eps []int
+		for ep := range alts {
+			eps = append(eps, ep)
+		}
+		sort.Ints(eps)
+		for _, ep := range eps {
+			for _, c := range alts[ep] {
+				rel := storage.Rel(dataDir, c.Path)
+				next.Alternatives = append(next.Alternatives, rel)
+				if last != nil && !known[rel] {
+					d.Revisions = append(d.Revisions, Revision{Prefix: prefix, Number: ep, Kind: c.Kind, Path: rel})
+				}
+			}
+		}
+
+		nums, err := scraper.LocalEpisodes(prefix, dataDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		gaps := scraper.Gaps(nums)
+		next.Gaps[prefix] = gaps
+		if len(gaps) > 0 {
+			d.Gaps[prefix] = len(gaps)
+		}
+		if last == nil {
+			continue
+		}
+		before := make(map[int]bool)
+		for _, n := range last.Gaps[prefix] {
+			before[n] = true
+		}
+		for _, n := range gaps {
+			if !before[n] {
+				d.NewGaps[prefix] = append(d.NewGaps[prefix], n)
+			}
+		}
+	}
+
+	report, err := errs.LoadReport(reportPath)
+	if err != nil && !errors.Is(err, storage.ErrNotExist) {
+		return nil, nil, err
+	}
+	if report != nil {
+		for _, f := range report.Failures {
+			if f.Time.After(d.Since) {
+				d.Failures = append(d.Failures, f)
+			}
+		}
+	}
+	return d, next, nil
+}
+
+// Empty reports whether nothing happened worth mailing
+func (d *Digest) Empty() bool {
+	return !d.First && len(d.New) == 0 && len(d.Revisions) == 0 && len(d.NewGaps) == 0 && len(d.Failures) == 0
+}
+
+// Subject is the digest's mail subject
+func (d *Digest) Subject() string {
+	if d.First {
+		return fmt.Sprintf("Archive digest: tracking %d episodes", d.Episodes)
+	}
+	newGaps := 0
+	for _, g := range d.NewGaps {
+		newGaps += len(g)
+	}
+	return fmt.Sprintf("Archive digest: %s, %s, %s, %s",
+		plural(len(d.New), "new episode"), plural(len(d.Revisions), "revision"),
+		plural(newGaps, "new gap"), plural(len(d.Failures), "failure"))
+}
+
+// Text is the digest's plain-text mail body
+func (d *Digest) Text() string {
+	var b strings.Builder
+	if d.First {
+		fmt.Fprintf(&b, "This is the first digest. The archive holds %d episodes; later digests\nreport what changes.\n", d.Episodes)
+	} else {
+		fmt.Fprintf(&b, "Changes to the archive from %s to %s.\n", d.Since.Format("2006-01-02 15:04"), d.Until.Format("2006-01-02 15:04"))
+	}
+
+	if len(d.New) > 0 {
+		fmt.Fprintf(&b, "\nNew episodes (%d)\n", len(d.New))
+		for _, e := range d.New {
+			fmt.Fprintf(&b, "  %s %d  %s  %s\n", e.Prefix, e.Number, orDash(e.Date), e.Title)
+		}
+	}
+	if len(d.Revisions) > 0 {
+		fmt.Fprintf(&b, "\nRevisions and other copies set aside (%d)\n", len(d.Revisions))
+		for _, r := range d.Revisions {
+			fmt.Fprintf(&b, "  %s %d  %s  %s\n", r.Prefix, r.Number, r.Kind, r.Path)
+		}
+	}
+	if len(d.NewGaps) > 0 {
+		fmt.Fprintln(&b, "\nNew gaps")
+		for _, prefix := range showsWithGaps(d.NewGaps) {
+			fmt.Fprintf(&b, "  %s: %s\n", prefix, scraper.FormatGaps(d.NewGaps[prefix]))
+		}
+	}
+	if len(d.Failures) > 0 {
+		fmt.Fprintf(&b, "\nFailures (%d)\n", len(d.Failures))
+		for _, f := range d.Failures {
+			fmt.Fprintf(&b, "  %s  %s: %s\n", f.Class, f.Target, f.Error)
+		}
+	}
+
+	total := 0
+	var shows []string
+	for prefix, n := range d.Gaps {
+		total += n
+		shows = append(shows, prefix)
+	}
+	if total > 0 {
+		sort.Strings(shows)
+		var parts []string
+		for _, prefix := range shows {
+			parts = append(parts, fmt.Sprintf("%s %d", prefix, d.Gaps[prefix]))
+		}
+		fmt.Fprintf(&b, "\n%s missing in all (%s). Run 'fetch-transcripts --fill-gaps' to look for them.\n", plural(total, "episode"), strings.Join(parts, ", "))
+	}
+	return b.String()
+}
+
+func showsWithGaps(gaps map[string][]int) []string {
+	var shows []string
+	for prefix := range gaps {
+		shows = append(shows, prefix)
+	}
+	sort.Strings(shows)
+	return shows
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,107 @@
+package digest
+
+import (
+	"fmt"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/errs"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+)
+
+func TestBuild(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		p := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ix := index.New()
+	add := func(ep int) {
+		name := fmt.Sprintf("SN_%d.html", ep)
+		write(name, "<html></html>")
+		ix.Entries[index.Key(name)] = &index.Entry{File: name, Prefix: "SN", Number: ep, Title: fmt.Sprintf("Security Now %d", ep)}
+	}
+	add(1)
+	add(3)
+	if err := ix.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+	report := filepath.Join(dir, errs.ReportFile)
+
+	week0 := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	d, state, err := Build(dir, report, nil, week0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.First || d.Episodes != 2 || d.Empty() || d.Gaps["SN"] != 1 {
+		t.Fatalf("First digest = %+v", d)
+	}
+	if err := state.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// A week on: episode 4 arrived, 1 was revised, a download failed
+	add(4)
+	ix.Save(dir)
+	write("alt/SN/SN_1.site-20260901T000000Z.html", "<html></html>")
+	r := errs.NewReport("fetch-transcripts")
+	r.Add("https://twit.tv/posts/transcripts/sn-5", errs.ErrRateLimited)
+	r.Failures[0].Time = week0.AddDate(0, 0, 3)
+	r.Write(report)
+
+	last, err := LoadState(dir)
+	if err != nil || last == nil || !last.Sent.Equal(week0) {
+		t.Fatalf("LoadState = %+v, %v", last, err)
+	}
+	d, _, err = Build(dir, report, last, week0.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.First || len(d.New) != 1 || d.New[0].Number != 4 || len(d.Revisions) != 1 || len(d.Failures) != 1 || d.Gaps["SN"] != 1 {
+		t.Fatalf("Digest = %+v", d)
+	}
+	if len(d.NewGaps) != 0 {
+		t.Errorf("SN 2 was missing before, so no gap is new: %v", d.NewGaps)
+	}
+	if s := d.Subject(); s != "Archive digest: 1 new episode, 1 revision, 0 new gaps, 1 failure" {
+		t.Errorf("Subject = %q", s)
+	}
+	text := d.Text()
+	for _, want := range []string{"Security Now 4", "alt/SN/SN_1.site-20260901T000000Z.html", "rate_limited", "1 episode missing"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text lacks %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestMailerSend(t *testing.T) {
+	defer func() { sendMail = smtp.SendMail }()
+	var gotAddr string
+	var gotTo []string
+	var msg []byte
+	sendMail = func(addr string, a smtp.Auth, from string, to []string, m []byte) error {
+		gotAddr, gotTo, msg = addr, to, m
+		return nil
+	}
+	m := &Mailer{Addr: "mail.example.com:587", From: "archiver@example.com", To: []string{"a@example.com", "b@example.com"}}
+	if err := m.Send("Archive digest", "line 1\nline 2\n"); err != nil {
+		t.Fatal(err)
+	}
+	if gotAddr != m.Addr || len(gotTo) != 2 {
+		t.Errorf("sent to %s %v", gotAddr, gotTo)
+	}
+	s := string(msg)
+	if !strings.Contains(s, "Subject: Archive digest\r\n") || !strings.HasSuffix(s, "\r\n\r\nline 1\r\nline 2\r\n") {
+		t.Errorf("message:\n%q", s)
+	}
+	if err := (&Mailer{From: "x@example.com"}).Send("s", "b"); err == nil {
+		t.Error("Send without a server or recipients should fail")
+	}
+}
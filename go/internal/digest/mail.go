@@ -0,0 +1,57 @@
+package digest
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Mailer sends digests through an SMTP server. The connection is upgraded
+// with STARTTLS when the server offers it; a Username enables PLAIN
+// authentication, which net/smtp only allows over TLS or to localhost.
+type Mailer struct {
+	Addr     string // host:port
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// sendMail is smtp.SendMail, replaced in tests
+var sendMail = smtp.SendMail
+
+// Send mails a plain-text message to every recipient
+func (m *Mailer) Send(subject, body string) error {
+	if m.Addr == "" || m.From == "" || len(m.To) == 0 {
+		return fmt.Errorf("mail needs an SMTP server, a sender and at least one recipient")
+	}
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return fmt.Errorf("invalid SMTP server '%s' (want host:port)", m.Addr)
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	if err := sendMail(m.Addr, auth, m.From, m.To, m.message(subject, body, time.Now())); err != nil {
+		return fmt.Errorf("sending mail via %s: %w", m.Addr, err)
+	}
+	return nil
+}
+
+// message builds the mail with its headers, with CRLF line endings
+func (m *Mailer) message(subject, body string, now time.Time) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", m.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	body = strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n")
+	b.WriteString(body)
+	return []byte(b.String())
+}
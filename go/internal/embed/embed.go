@@ -0,0 +1,216 @@
+package embed
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/utils"
+)
+
+// Passage is a window of transcript text ready for embedding
+type Passage struct {
+	ID      string `json:"id"`
+	Prefix  string `json:"prefix"`
+	Episode int    `json:"episode"`
+	Title   string `json:"title"`
+	Date    string `json:"date,omitempty"`
+	Index   int    `json:"chunk"`
+	// Start is the first timecode in the passage (HH:MM:SS), if any
+	Start string `json:"start,omitempty"`
+	Text  string `json:"text"`
+}
+
+// passageTimecodeRegex finds the TS field of a turn inside a passage
+var passageTimecodeRegex = regexp.MustCompile(`\bTS:(\d+:\d+(?::\d+)?)`)
+
+// Record is a passage together with its embedding vector
+type Record struct {
+	Passage
+	Model     string    `json:"model"`
+	Embedding []float64 `json:"embedding"`
+}
+
+// Passages splits an episode into overlapping word windows. Windows are
+// built from whole transcript lines where possible so a speaker turn is only
+// cut when it alone exceeds the window size.
+func Passages(ep converter.Episode, size, overlap int) []Passage {
+	if size <= 0 {
+		size = 200
+	}
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+
+	var words []string
+	var lineStarts []int // Word offsets at which a transcript line begins
+	for _, line := range strings.Split(ep.Content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		lineStarts = append(lineStarts, len(words))
+		words = append(words, fields...)
+	}
+
+	date := ""
+	if !ep.Date.IsZero() {
+		date = ep.Date.Format("2006-01-02")
+	}
+
+	var passages []Passage
+	for start := 0; start < len(words); {
+		end := start + size
+		if end >= len(words) {
+			end = len(words)
+		} else if b := lastBoundary(lineStarts, start, end); b > start+size/2 {
+			// Prefer ending on a line boundary if it keeps at least half the window
+			end = b
+		}
+
+		text := strings.Join(words[start:end], " ")
+		p := Passage{
+			ID:      fmt.Sprintf("%s_%d_%d", ep.Prefix, ep.Number, len(passages)),
+			Prefix:  ep.Prefix,
+			Episode: ep.Number,
+			Title:   ep.Title,
+			Date:    date,
+			Index:   len(passages),
+			Text:    text,
+		}
+		if m := passageTimecodeRegex.FindStringSubmatch(text); m != nil {
+			if d, ok := converter.ParseTimecode(m[1]); ok {
+				p.Start = converter.FormatTimecode(d)
+			}
+		}
+		passages = append(passages, p)
+
+		if end == len(words) {
+			break
+		}
+		next := end - overlap
+		if next <= start {
+			next = end
+		}
+		start = next
+	}
+	return passages
+}
+
+// lastBoundary returns the last line start in (start, end], or -1
+func lastBoundary(lineStarts []int, start, end int) int {
+	i := sort.SearchInts(lineStarts, end+1) - 1
+	if i >= 0 && lineStarts[i] > start {
+		return lineStarts[i]
+	}
+	return -1
+}
+
+// Client calls an OpenAI-compatible /embeddings endpoint. This covers the
+// OpenAI API as well as local servers such as Ollama, llama.cpp and vLLM.
+type Client struct {
+	Endpoint string // Base URL, e.g. https://api.openai.com/v1
+	Model    string
+	APIKey   string
+	HTTP     *http.Client
+}
+
+// NewClient creates a client with a default HTTP timeout
+func NewClient(endpoint, model, apiKey string) *Client {
+	return &Client{
+		Endpoint: strings.TrimRight(endpoint, "/"),
+		Model:    model,
+		APIKey:   apiKey,
+		HTTP:     &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+type embeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed returns one vector per input text, in input order
+func (c *Client) Embed(texts []string) ([][]float64, error) {
+	body, err := json.Marshal(embeddingRequest{Model: c.Model, Input: texts})
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := utils.PostJSON(c.HTTP, c.Endpoint+"/embeddings", body, c.APIKey)
+	if err != nil {
+		return nil, fmt.Errorf("embedding request failed: %v", err)
+	}
+	var parsed embeddingResponse
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return nil, fmt.Errorf("invalid embedding response: %v", err)
+	}
+	if len(parsed.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(parsed.Data))
+	}
+	vectors := make([][]float64, len(texts))
+	for _, d := range parsed.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
+
+// Writer streams embedded passages as JSON Lines
+type Writer struct {
+	client    *Client
+	enc       *json.Encoder
+	batchSize int
+	Written   int
+}
+
+// NewWriter creates a writer that embeds passages in batches
+func NewWriter(w io.Writer, client *Client, batchSize int) *Writer {
+	if batchSize <= 0 {
+		batchSize = 32
+	}
+	return &Writer{client: client, enc: json.NewEncoder(w), batchSize: batchSize}
+}
+
+// Write embeds the passages and appends one record per passage
+func (w *Writer) Write(passages []Passage) error {
+	for start := 0; start < len(passages); start += w.batchSize {
+		end := start + w.batchSize
+		if end > len(passages) {
+			end = len(passages)
+		}
+		batch := passages[start:end]
+
+		texts := make([]string, len(batch))
+		for i, p := range batch {
+			texts[i] = p.Text
+		}
+		vectors, err := w.client.Embed(texts)
+		if err != nil {
+			return err
+		}
+
+		for i, p := range batch {
+			if err := w.enc.Encode(Record{Passage: p, Model: w.client.Model, Embedding: vectors[i]}); err != nil {
+				return err
+			}
+			w.Written++
+		}
+	}
+	return nil
+}
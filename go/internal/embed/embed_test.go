@@ -0,0 +1,83 @@
+package embed
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+func TestPassagesOverlap(t *testing.T) {
+	var words []string
+	for i := 0; i < 25; i++ {
+		words = append(words, fmt.Sprintf("w%d", i))
+	}
+	ep := converter.Episode{Prefix: "SN", Number: 1, Content: strings.Join(words, " ")}
+
+	passages := Passages(ep, 10, 3)
+	if len(passages) != 4 {
+		t.Fatalf("Expected 4 passages, got %d", len(passages))
+	}
+	if !strings.HasPrefix(passages[1].Text, "w7 ") {
+		t.Errorf("Second passage should start with the overlap, got %q", passages[1].Text)
+	}
+	if passages[3].ID != "SN_1_3" || passages[3].Text != "w21 w22 w23 w24" {
+		t.Errorf("Unexpected final passage: %+v", passages[3])
+	}
+}
+
+func TestPassagesPreferLineBoundaries(t *testing.T) {
+	content := "a b c d e f g\nh i j k l m n o p q"
+	passages := Passages(converter.Episode{Content: content}, 10, 0)
+	if passages[0].Text != "a b c d e f g" {
+		t.Errorf("Expected first passage to end at the line boundary, got %q", passages[0].Text)
+	}
+}
+
+func TestPassageStart(t *testing.T) {
+	content := "EP:1 Date:24-01-01 TS:5:07 - Leo Hi\nEP:1 Date:24-01-01 TS:6:00 - Steve Hello"
+	passages := Passages(converter.Episode{Content: content}, 100, 0)
+	if passages[0].Start != "00:05:07" {
+		t.Errorf("Expected start 00:05:07, got %q", passages[0].Start)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var req embeddingRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		var data []string
+		// Return results in reverse order to check index handling
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			data = append(data, fmt.Sprintf(`{"index":%d,"embedding":[%d,0.5]}`, i, len(req.Input[i])))
+		}
+		fmt.Fprintf(w, `{"data":[%s]}`, strings.Join(data, ","))
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf, NewClient(ts.URL+"/v1", "test-model", "key"), 2)
+	err := w.Write([]Passage{{ID: "a", Text: "x"}, {ID: "b", Text: "yy"}, {ID: "c", Text: "zzz"}})
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || w.Written != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(lines))
+	}
+	var rec Record
+	json.Unmarshal([]byte(lines[1]), &rec)
+	if rec.ID != "b" || rec.Model != "test-model" || len(rec.Embedding) != 2 || rec.Embedding[0] != 2 {
+		t.Errorf("Unexpected record: %+v", rec)
+	}
+}
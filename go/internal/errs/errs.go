@@ -0,0 +1,197 @@
+// Package errs defines the error classes shared by the scraper and converter
+// and the errors.json report the tools write at the end of a run, so failed
+// downloads and files can be retried programmatically.
+package errs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// ReportFile is the default name of the report, kept in the data directory
+const ReportFile = "errors.json"
+
+var (
+	// ErrNotFound is returned for pages the site reports as missing (404/410)
+	ErrNotFound = errors.New("not found")
+	// ErrRateLimited is returned when the site asks us to slow down (429/503)
+	ErrRateLimited = errors.New("rate limited")
+	// ErrParse is returned for pages that do not look like transcripts
+	ErrParse = errors.New("parse error")
+	// ErrTruncated is returned for incomplete downloads and pages
+	ErrTruncated = errors.New("truncated")
+)
+
+// Class names the class of an error for the report: "not_found",
+// "rate_limited", "parse", "truncated" or "other"
+func Class(err error) string {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return "not_found"
+	case errors.Is(err, ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, ErrParse):
+		return "parse"
+	case errors.Is(err, ErrTruncated):
+		return "truncated"
+	}
+	return "other"
+}
+
+// Exit codes shared by the command-line tools
+const (
+	ExitOK       = 0 // Everything succeeded
+	ExitError    = 1 // The run could not start or failed as a whole
+	ExitUsage    = 2 // Invalid flags or arguments
+	ExitFailures = 3 // The run finished, but some targets failed
+	ExitStrict   = 4 // The run stopped early on a --strict error class
+	ExitLocked   = 5 // Another run holds the data directory lock
+)
+
+// Classes is a set of error classes, usable as a flag: "--strict" alone
+// selects every class, "--strict=rate_limited,truncated" only those
+type Classes map[string]bool
+
+// String implements flag.Value
+func (c Classes) String() string {
+	var names []string
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// Set implements flag.Value
+func (c Classes) Set(v string) error {
+	for _, name := range strings.Split(v, ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "true", "all":
+			c["all"] = true
+		case "false":
+			for k := range c {
+				delete(c, k)
+			}
+		case "not_found", "rate_limited", "parse", "truncated", "other":
+			c[name] = true
+		default:
+			return fmt.Errorf("unknown error class '%s' (want all, not_found, rate_limited, parse, truncated or other)", name)
+		}
+	}
+	return nil
+}
+
+// IsBoolFlag lets "--strict" be given without a value
+func (c Classes) IsBoolFlag() bool { return true }
+
+// Match reports whether err belongs to one of the classes
+func (c Classes) Match(err error) bool {
+	return c["all"] || c[Class(err)]
+}
+
+// Failure is one failed URL or file
+type Failure struct {
+	Target string    `json:"target"` // URL or file path
+	Class  string    `json:"class"`
+	Error  string    `json:"error"`
+	Time   time.Time `json:"time"`
+}
+
+// Report collects a run's failures. A nil *Report ignores them. It is safe
+// for concurrent use.
+type Report struct {
+	Tool     string    `json:"tool"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Failures []Failure `json:"failures"`
+	// Stopped is set when a failure matched StopOn
+	Stopped bool `json:"stopped,omitempty"`
+
+	// StopOn lists the error classes that end the run (--strict)
+	StopOn Classes `json:"-"`
+
+	mu sync.Mutex
+}
+
+// NewReport starts a report for a tool's run
+func NewReport(tool string) *Report {
+	return &Report{Tool: tool, Started: time.Now().UTC(), Failures: []Failure{}}
+}
+
+// LoadReport reads a report written by Write
+func LoadReport(path string) (*Report, error) {
+	data, err := storage.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &Report{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Add records a failure
+func (r *Report) Add(target string, err error) {
+	if r == nil || err == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Failures = append(r.Failures, Failure{Target: target, Class: Class(err), Error: err.Error(), Time: time.Now().UTC()})
+	if r.StopOn.Match(err) {
+		r.Stopped = true
+	}
+}
+
+// ShouldStop reports whether a --strict error class has been hit
+func (r *Report) ShouldStop() bool {
+	if r == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Stopped
+}
+
+// ExitCode returns ExitStrict if the run was stopped, ExitFailures if
+// anything failed and ExitOK otherwise
+func (r *Report) ExitCode() int {
+	switch {
+	case r.ShouldStop():
+		return ExitStrict
+	case r.Len() > 0:
+		return ExitFailures
+	}
+	return ExitOK
+}
+
+// Len returns the number of failures recorded
+func (r *Report) Len() int {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.Failures)
+}
+
+// Write saves the report as JSON. The file is written even when nothing
+// failed, so a clean run replaces the report of a failed one.
+func (r *Report) Write(path string) error {
+	r.mu.Lock()
+	r.Finished = time.Now().UTC()
+	data, err := json.MarshalIndent(r, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return storage.WriteFile(path, data)
+}
@@ -0,0 +1,96 @@
+package errs
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClass(t *testing.T) {
+	tests := map[error]string{
+		fmt.Errorf("%w: status code 404", ErrNotFound):       "not_found",
+		fmt.Errorf("retries: %w", ErrRateLimited):            "rate_limited",
+		fmt.Errorf("SN_1.html: %w: no transcript", ErrParse): "parse",
+		ErrTruncated:                   "truncated",
+		fmt.Errorf("connection reset"): "other",
+	}
+	for err, want := range tests {
+		if got := Class(err); got != want {
+			t.Errorf("Class(%v) = %q, want %q", err, got, want)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "errstest")
+	defer os.RemoveAll(dir)
+
+	var nilReport *Report
+	nilReport.Add("ignored", ErrParse)
+
+	r := NewReport("fetch-transcripts")
+	r.Add("https://twit.tv/posts/transcripts/sn-1", fmt.Errorf("%w: status code 404", ErrNotFound))
+	r.Add("unused", nil)
+	if r.Len() != 1 {
+		t.Fatalf("Expected 1 failure, got %d", r.Len())
+	}
+	path := filepath.Join(dir, ReportFile)
+	if err := r.Write(path); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	var got Report
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Invalid report: %v", err)
+	}
+	if got.Tool != "fetch-transcripts" || len(got.Failures) != 1 || got.Failures[0].Class != "not_found" {
+		t.Errorf("Unexpected report: %s", data)
+	}
+
+	loaded, err := LoadReport(path)
+	if err != nil || loaded.Len() != 1 || loaded.Failures[0].Target != "https://twit.tv/posts/transcripts/sn-1" {
+		t.Errorf("LoadReport = %+v, %v", loaded, err)
+	}
+}
+
+func TestStrict(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	strict := Classes{}
+	fs.Var(strict, "strict", "")
+	if err := fs.Parse([]string{"--strict=rate_limited,truncated"}); err != nil {
+		t.Fatal(err)
+	}
+	if strict.String() != "rate_limited,truncated" {
+		t.Errorf("Unexpected classes: %s", strict)
+	}
+	if err := fs.Parse([]string{"--strict=bogus"}); err == nil {
+		t.Error("Expected an error for an unknown class")
+	}
+
+	r := NewReport("test")
+	r.StopOn = strict
+	r.Add("a", fmt.Errorf("%w: status code 404", ErrNotFound))
+	if r.ShouldStop() || r.ExitCode() != ExitFailures {
+		t.Errorf("A not_found failure should not stop a rate_limited,truncated run")
+	}
+	r.Add("b", ErrTruncated)
+	if !r.ShouldStop() || r.ExitCode() != ExitStrict {
+		t.Errorf("A truncated failure should stop the run")
+	}
+	if NewReport("clean").ExitCode() != ExitOK {
+		t.Error("Expected ExitOK for a clean run")
+	}
+
+	all := Classes{}
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(all, "strict", "")
+	fs.Parse([]string{"--strict"})
+	if !all.Match(errors.New("anything")) {
+		t.Error("--strict alone should match every class")
+	}
+}
@@ -0,0 +1,55 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// CSVHeader is the header row written by CSV
+var CSVHeader = []string{"show", "prefix", "episode", "title", "date", "byline_date", "words", "html_file", "markdown_file"}
+
+// CSV writes one row of metadata per episode, for browsing the catalog in a
+// spreadsheet. comma selects the separator (',' for CSV, '\t' for TSV).
+// File paths are relative to dataDir; markdown_file is empty unless a
+// per-episode Markdown file exists.
+func CSV(episodes []converter.Episode, w io.Writer, dataDir string, comma rune) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	if err := cw.Write(CSVHeader); err != nil {
+		return err
+	}
+	for _, ep := range episodes {
+		date := ""
+		if !ep.Date.IsZero() {
+			date = ep.Date.Format("2006-01-02")
+		}
+		md := storage.Join(config.ActiveLayout.MarkdownDir(dataDir, ep.Prefix), fmt.Sprintf("%s_%d.md", ep.Prefix, ep.Number))
+		mdRel := ""
+		if storage.Exists(md) {
+			mdRel = storage.Rel(dataDir, md)
+		}
+		row := []string{
+			config.ShowName(ep.Prefix),
+			ep.Prefix,
+			strconv.Itoa(ep.Number),
+			ep.Title,
+			date,
+			ep.DateStr,
+			strconv.Itoa(len(strings.Fields(ep.Content))),
+			storage.Rel(dataDir, ep.Path),
+			mdRel,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,51 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+func TestCSV(t *testing.T) {
+	dir, err := os.MkdirTemp("", "csv_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, "SN_1000.md"), []byte("# SN 1000"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	episodes := []converter.Episode{
+		{Prefix: "SN", Number: 1000, Title: "Security Now 1000, the big one", DateStr: "Nov 19th 2024",
+			Date: time.Date(2024, 11, 19, 0, 0, 0, 0, time.UTC), Content: "one two three", Path: filepath.Join(dir, "SN_1000.html")},
+		{Prefix: "TWIG", Number: 5, Title: "Untitled", Content: "", Path: filepath.Join(dir, "TWIG_5.html")},
+	}
+	var buf bytes.Buffer
+	if err := CSV(episodes, &buf, dir, '\t'); err != nil {
+		t.Fatal(err)
+	}
+	r := csv.NewReader(&buf)
+	r.Comma = '\t'
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("Invalid TSV: %v", err)
+	}
+	if len(rows) != 3 || len(rows[0]) != len(CSVHeader) {
+		t.Fatalf("Unexpected rows: %q", rows)
+	}
+	want := []string{"security now", "SN", "1000", "Security Now 1000, the big one", "2024-11-19", "Nov 19th 2024", "3", "SN_1000.html", "SN_1000.md"}
+	for i, v := range want {
+		if rows[1][i] != v {
+			t.Errorf("Column %s = %q, want %q", CSVHeader[i], rows[1][i], v)
+		}
+	}
+	if rows[2][4] != "" || rows[2][8] != "" {
+		t.Errorf("Expected empty date and markdown file, got %q", rows[2])
+	}
+}
@@ -0,0 +1,259 @@
+package export
+
+import (
+	"archive/zip"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+// EPUBPublisher is the publisher recorded in EPUB metadata
+var EPUBPublisher = "TWiT.tv"
+
+// EPUBName returns the file name of an episode's EPUB
+func EPUBName(ep converter.Episode) string {
+	return fmt.Sprintf("%s_%d.epub", ep.Prefix, ep.Number)
+}
+
+// EPUB writes an episode as an EPUB 3 book. The package document carries
+// Calibre's series metadata (the show as series, the episode number as
+// series index) alongside the EPUB 3 collection equivalent, so libraries
+// group the episodes of a show in order. A cover naming the show and
+// episode is generated as SVG.
+func EPUB(ep converter.Episode, w io.Writer) error {
+	z := zip.NewWriter(w)
+	// The mimetype entry must come first and be stored uncompressed
+	mt, err := z.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(mt, "application/epub+zip"); err != nil {
+		return err
+	}
+	files := []struct{ name, content string }{
+		{"META-INF/container.xml", epubContainer},
+		{"OEBPS/content.opf", OPF(ep)},
+		{"OEBPS/nav.xhtml", epubNav(ep)},
+		{"OEBPS/cover.svg", EPUBCover(ep)},
+		{"OEBPS/cover.xhtml", epubPage(ep, "Cover", `<div class="cover"><img src="cover.svg" alt="Cover"/></div>`)},
+		{"OEBPS/transcript.xhtml", epubPage(ep, ep.Title, epubBody(ep))},
+	}
+	for _, f := range files {
+		fw, err := z.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f.content); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+// OPF returns the package document of an episode's EPUB
+func OPF(ep converter.Episode) string {
+	x := html.EscapeString
+	show := SeriesName(ep)
+	lang := ep.Language
+	if lang == "" {
+		lang = "en"
+	}
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" prefix="calibre: https://calibre-ebook.com">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:opf="http://www.idpf.org/2007/opf">
+`)
+	fmt.Fprintf(&b, "    <dc:identifier id=\"book-id\">urn:twit:%s:%d</dc:identifier>\n", x(ep.Prefix), ep.Number)
+	fmt.Fprintf(&b, "    <dc:title>%s</dc:title>\n", x(ep.Title))
+	fmt.Fprintf(&b, "    <dc:language>%s</dc:language>\n", x(lang))
+	fmt.Fprintf(&b, "    <dc:publisher>%s</dc:publisher>\n", x(EPUBPublisher))
+	for i, host := range ep.Roster.Hosts {
+		fmt.Fprintf(&b, "    <dc:creator id=\"host%d\">%s</dc:creator>\n", i, x(host))
+		fmt.Fprintf(&b, "    <meta refines=\"#host%d\" property=\"role\" scheme=\"marc:relators\">aut</meta>\n", i)
+	}
+	for i, guest := range ep.Roster.Guests {
+		fmt.Fprintf(&b, "    <dc:contributor id=\"guest%d\">%s</dc:contributor>\n", i, x(guest))
+		fmt.Fprintf(&b, "    <meta refines=\"#guest%d\" property=\"role\" scheme=\"marc:relators\">ctb</meta>\n", i)
+	}
+	if !ep.Date.IsZero() {
+		fmt.Fprintf(&b, "    <dc:date>%s</dc:date>\n", ep.Date.Format("2006-01-02"))
+	}
+	if ep.URL != "" {
+		fmt.Fprintf(&b, "    <dc:source>%s</dc:source>\n", x(ep.URL))
+	}
+	fmt.Fprintf(&b, "    <dc:subject>%s</dc:subject>\n", x(show))
+	if ep.Summary != nil {
+		fmt.Fprintf(&b, "    <dc:description>%s</dc:description>\n", x(ep.Summary.Abstract))
+		for _, t := range ep.Summary.Topics {
+			fmt.Fprintf(&b, "    <dc:subject>%s</dc:subject>\n", x(t))
+		}
+	}
+	fmt.Fprintf(&b, "    <meta property=\"dcterms:modified\">%s</meta>\n", epubModified(ep))
+	fmt.Fprintf(&b, "    <meta property=\"belongs-to-collection\" id=\"series\">%s</meta>\n", x(show))
+	b.WriteString("    <meta refines=\"#series\" property=\"collection-type\">series</meta>\n")
+	fmt.Fprintf(&b, "    <meta refines=\"#series\" property=\"group-position\">%d</meta>\n", ep.Number)
+	fmt.Fprintf(&b, "    <meta name=\"calibre:series\" content=\"%s\"/>\n", x(show))
+	fmt.Fprintf(&b, "    <meta name=\"calibre:series_index\" content=\"%d\"/>\n", ep.Number)
+	b.WriteString(`    <meta name="cover" content="cover-image"/>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="cover-image" href="cover.svg" media-type="image/svg+xml" properties="cover-image"/>
+    <item id="cover" href="cover.xhtml" media-type="application/xhtml+xml"/>
+    <item id="transcript" href="transcript.xhtml" media-type="application/xhtml+xml"/>
+  </manifest>
+  <spine>
+    <itemref idref="cover" linear="no"/>
+    <itemref idref="transcript"/>
+  </spine>
+</package>
+`)
+	return b.String()
+}
+
+// SeriesName returns the show name as written in the episode title ("This
+// Week in Tech 1000" -> "This Week in Tech"), falling back to the
+// capitalized catalog name
+func SeriesName(ep converter.Episode) string {
+	if i := strings.Index(ep.Title, fmt.Sprintf(" %d", ep.Number)); i > 0 {
+		return strings.TrimSpace(ep.Title[:i])
+	}
+	words := strings.Fields(config.ShowName(ep.Prefix))
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// epubModified is the dcterms:modified stamp EPUB 3 requires. The episode
+// date keeps exports reproducible.
+func epubModified(ep converter.Episode) string {
+	if ep.Date.IsZero() {
+		return "2000-01-01T00:00:00Z"
+	}
+	return ep.Date.UTC().Format("2006-01-02T15:04:05Z")
+}
+
+// EPUBCover returns a generated SVG cover with the show name, episode
+// number, title and date
+func EPUBCover(ep converter.Episode) string {
+	x := html.EscapeString
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" width="1600" height="2400" viewBox="0 0 1600 2400">
+  <rect width="1600" height="2400" fill="#1d2733"/>
+  <rect x="80" y="80" width="1440" height="2240" fill="none" stroke="#e2a33b" stroke-width="8"/>
+`)
+	fmt.Fprintf(&b, "  <text x=\"800\" y=\"600\" font-family=\"sans-serif\" font-size=\"110\" fill=\"#e2a33b\" text-anchor=\"middle\">%s</text>\n", x(SeriesName(ep)))
+	fmt.Fprintf(&b, "  <text x=\"800\" y=\"1100\" font-family=\"sans-serif\" font-size=\"320\" font-weight=\"bold\" fill=\"#ffffff\" text-anchor=\"middle\">%d</text>\n", ep.Number)
+	for i, line := range wrapWords(coverSubtitle(ep), 24) {
+		if i == 4 {
+			break
+		}
+		fmt.Fprintf(&b, "  <text x=\"800\" y=\"%d\" font-family=\"serif\" font-size=\"90\" fill=\"#ffffff\" text-anchor=\"middle\">%s</text>\n", 1500+i*120, x(line))
+	}
+	if !ep.Date.IsZero() {
+		fmt.Fprintf(&b, "  <text x=\"800\" y=\"2150\" font-family=\"sans-serif\" font-size=\"70\" fill=\"#9fb0c2\" text-anchor=\"middle\">%s</text>\n", ep.Date.Format("January 2, 2006"))
+	}
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+// coverSubtitle is the part of the title that does not repeat the show name
+// and episode number ("Security Now 1000: The Big One" -> "The Big One")
+func coverSubtitle(ep converter.Episode) string {
+	title := ep.Title
+	if i := strings.Index(title, ":"); i >= 0 && strings.Contains(title[:i], fmt.Sprint(ep.Number)) {
+		title = title[i+1:]
+	}
+	return strings.TrimSpace(title)
+}
+
+// wrapWords breaks s into lines of at most width characters, at spaces
+func wrapWords(s string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func epubNav(ep converter.Episode) string {
+	return epubPage(ep, "Contents", fmt.Sprintf(`<nav epub:type="toc" id="toc">
+  <ol>
+    <li><a href="transcript.xhtml">%s</a></li>
+  </ol>
+</nav>`, html.EscapeString(ep.Title)))
+}
+
+// epubPage wraps body in an XHTML document
+func epubPage(ep converter.Episode, title, body string) string {
+	lang := ep.Language
+	if lang == "" {
+		lang = "en"
+	}
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="%[1]s" lang="%[1]s">
+<head>
+<meta charset="UTF-8"/>
+<title>%[2]s</title>
+<style>
+.cover { text-align: center; } .cover img { max-width: 100%%; max-height: 100%%; }
+.ts { color: #777; font-size: 0.8em; }
+</style>
+</head>
+<body>
+%[3]s
+</body>
+</html>
+`, html.EscapeString(lang), html.EscapeString(title), body)
+}
+
+// epubBody renders the transcript as one paragraph per turn, the speaker
+// in bold and the timecode, if any, before it
+func epubBody(ep converter.Episode) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<h1>%s</h1>\n", html.EscapeString(ep.Title))
+	if ep.Summary != nil {
+		fmt.Fprintf(&b, "<blockquote class=\"abstract\"><p>%s</p></blockquote>\n", html.EscapeString(ep.Summary.Abstract))
+	}
+	for i, turn := range converter.Turns(ep.Content) {
+		b.WriteString("<p>")
+		if turn.Timecode != "" {
+			fmt.Fprintf(&b, `<span class="ts">[%s]</span> `, turn.Timecode)
+		}
+		text := turn.Text
+		if i < len(ep.Turns) && ep.Turns[i].Speaker != "" && strings.HasPrefix(text, ep.Turns[i].Speaker) {
+			who := ep.Turns[i].Speaker
+			fmt.Fprintf(&b, "<b>%s</b>", html.EscapeString(who))
+			text = text[len(who):]
+		}
+		b.WriteString(html.EscapeString(text))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,89 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+func TestEPUB(t *testing.T) {
+	ep := converter.Episode{
+		Prefix: "SN", Number: 1000, Title: "Security Now 1000: Q&A <Special>",
+		Date:    time.Date(2024, 11, 19, 0, 0, 0, 0, time.UTC),
+		Content: "EP:1000 Date:24-11-19 TS:1:02:03 - Steve Gibson Hello & welcome\nEP:1000 Date:24-11-19 - Leo Laporte Hi",
+		Turns:   []converter.Turn{{Speaker: "Steve Gibson"}, {Speaker: "Leo Laporte"}},
+		Roster:  converter.Roster{Hosts: []string{"Steve Gibson", "Leo Laporte"}},
+		Summary: &converter.Summary{Abstract: "Steve answers questions.", Topics: []string{"Q&A"}},
+	}
+	var buf bytes.Buffer
+	if err := EPUB(ep, &buf); err != nil {
+		t.Fatal(err)
+	}
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if z.File[0].Name != "mimetype" || z.File[0].Method != zip.Store {
+		t.Errorf("First entry = %s (method %d), want stored mimetype", z.File[0].Name, z.File[0].Method)
+	}
+	files := map[string]string{}
+	for _, f := range z.File {
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+		if strings.HasSuffix(f.Name, "xml") || strings.HasSuffix(f.Name, ".opf") || strings.HasSuffix(f.Name, ".svg") || strings.HasSuffix(f.Name, "xhtml") {
+			d := xml.NewDecoder(strings.NewReader(string(data)))
+			d.Entity = xml.HTMLEntity
+			for {
+				if _, err := d.Token(); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Errorf("%s is not well-formed: %v", f.Name, err)
+					break
+				}
+			}
+		}
+	}
+
+	opf := files["OEBPS/content.opf"]
+	for _, want := range []string{
+		`<meta name="calibre:series" content="Security Now"/>`,
+		`<meta name="calibre:series_index" content="1000"/>`,
+		`<meta refines="#series" property="group-position">1000</meta>`,
+		`<dc:title>Security Now 1000: Q&amp;A &lt;Special&gt;</dc:title>`,
+		`<dc:creator id="host1">Leo Laporte</dc:creator>`,
+		`<dc:date>2024-11-19</dc:date>`,
+		`properties="cover-image"`,
+		`<dc:description>Steve answers questions.</dc:description>`,
+		`<dc:subject>Q&amp;A</dc:subject>`,
+	} {
+		if !strings.Contains(opf, want) {
+			t.Errorf("OPF lacks %s:\n%s", want, opf)
+		}
+	}
+	if cover := files["OEBPS/cover.svg"]; !strings.Contains(cover, ">Q&amp;A &lt;Special&gt;<") || !strings.Contains(cover, ">1000<") {
+		t.Errorf("Cover:\n%s", cover)
+	}
+	if body := files["OEBPS/transcript.xhtml"]; !strings.Contains(body, `<p><span class="ts">[01:02:03]</span> <b>Steve Gibson</b> Hello &amp; welcome</p>`) {
+		t.Errorf("Transcript:\n%s", body)
+	}
+}
+
+func TestSeriesName(t *testing.T) {
+	for _, ep := range []converter.Episode{
+		{Prefix: "TWIT", Number: 1000, Title: "This Week in Tech 1000"},
+		{Prefix: "SN", Number: 7, Title: "Holiday Special"},
+	} {
+		got := SeriesName(ep)
+		if want := map[string]string{"TWIT": "This Week in Tech", "SN": "Security Now"}[ep.Prefix]; got != want {
+			t.Errorf("SeriesName(%q) = %q, want %q", ep.Title, got, want)
+		}
+	}
+}
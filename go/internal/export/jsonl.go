@@ -0,0 +1,186 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// EpisodeRecord is one episode, as written by JSONL in episode mode
+type EpisodeRecord struct {
+	ID       string   `json:"id"`
+	Prefix   string   `json:"prefix"`
+	Show     string   `json:"show"`
+	Episode  int      `json:"episode"`
+	Title    string   `json:"title"`
+	Date     string   `json:"date,omitempty"`
+	URL      string   `json:"url,omitempty"`
+	Hosts    []string `json:"hosts,omitempty"`
+	Guests   []string `json:"guests,omitempty"`
+	Words    int      `json:"words"`
+	Language string   `json:"language,omitempty"`
+	Text     string   `json:"text"`
+}
+
+// TurnRecord is one speaker turn, as written by JSONL in turn mode
+type TurnRecord struct {
+	ID       string  `json:"id"`
+	Prefix   string  `json:"prefix"`
+	Episode  int     `json:"episode"`
+	Title    string  `json:"title"`
+	Date     string  `json:"date,omitempty"`
+	Turn     int     `json:"turn"`
+	Start    float64 `json:"start_seconds,omitempty"`
+	Timecode string  `json:"timecode,omitempty"`
+	Text     string  `json:"text"`
+}
+
+// ShardWriter writes JSON Lines to a series of size-capped files. With a
+// zero Max everything goes to the single file at Path; otherwise shards are
+// named after Path with a sequence number ("corpus.jsonl" ->
+// "corpus-00000.jsonl", "corpus-00001.jsonl", ...). A record larger than
+// Max gets a shard of its own.
+type ShardWriter struct {
+	Path  string
+	Max   int64
+	Files []string // Files written so far
+
+	cur  io.WriteCloser
+	size int64
+}
+
+// NewShardWriter returns a writer for path, capping shards at max bytes
+func NewShardWriter(path string, max int64) *ShardWriter {
+	return &ShardWriter{Path: path, Max: max}
+}
+
+// WriteRecord encodes v as one JSON line
+func (s *ShardWriter) WriteRecord(v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if s.cur == nil || (s.Max > 0 && s.size > 0 && s.size+int64(len(line)) > s.Max) {
+		if err := s.next(); err != nil {
+			return err
+		}
+	}
+	n, err := s.cur.Write(line)
+	s.size += int64(n)
+	return err
+}
+
+func (s *ShardWriter) next() error {
+	if err := s.Close(); err != nil {
+		return err
+	}
+	name := s.Path
+	if s.Max > 0 {
+		ext := ".jsonl"
+		stem := s.Path
+		if i := strings.LastIndex(s.Path, "."); i > strings.LastIndex(s.Path, "/") {
+			stem, ext = s.Path[:i], s.Path[i:]
+		}
+		name = fmt.Sprintf("%s-%05d%s", stem, len(s.Files), ext)
+	}
+	f, err := storage.Create(name)
+	if err != nil {
+		return err
+	}
+	s.cur = f
+	s.size = 0
+	s.Files = append(s.Files, name)
+	return nil
+}
+
+// Close finishes the current shard
+func (s *ShardWriter) Close() error {
+	if s.cur == nil {
+		return nil
+	}
+	err := s.cur.Close()
+	s.cur = nil
+	return err
+}
+
+// JSONL writes the episodes as a training corpus, one record per episode
+// or, with byTurn, one per speaker turn. Text is the transcript without the
+// EP/Date/TS line prefixes. Returns the number of records written.
+func JSONL(episodes []converter.Episode, w *ShardWriter, byTurn bool) (int, error) {
+	n := 0
+	for _, ep := range episodes {
+		if byTurn {
+			for _, rec := range NewTurnRecords(ep) {
+				if err := w.WriteRecord(rec); err != nil {
+					return n, err
+				}
+				n++
+			}
+			continue
+		}
+
+		if err := w.WriteRecord(NewEpisodeRecord(ep)); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// NewTurnRecords returns a record for each speaker turn of an episode
+func NewTurnRecords(ep converter.Episode) []TurnRecord {
+	id := fmt.Sprintf("%s_%d", ep.Prefix, ep.Number)
+	date := ""
+	if !ep.Date.IsZero() {
+		date = ep.Date.Format("2006-01-02")
+	}
+	var recs []TurnRecord
+	for i, turn := range converter.Turns(ep.Content) {
+		recs = append(recs, TurnRecord{
+			ID:       fmt.Sprintf("%s_%d", id, i),
+			Prefix:   ep.Prefix,
+			Episode:  ep.Number,
+			Title:    ep.Title,
+			Date:     date,
+			Turn:     i,
+			Start:    turn.Start.Seconds(),
+			Timecode: turn.Timecode,
+			Text:     turn.Text,
+		})
+	}
+	return recs
+}
+
+// NewEpisodeRecord returns an episode's record, its text being the turns
+// without their EP:/Date: line prefixes
+func NewEpisodeRecord(ep converter.Episode) EpisodeRecord {
+	turns := converter.Turns(ep.Content)
+	lines := make([]string, len(turns))
+	for i, turn := range turns {
+		lines[i] = turn.Text
+	}
+	text := strings.Join(lines, "\n")
+	rec := EpisodeRecord{
+		ID:       fmt.Sprintf("%s_%d", ep.Prefix, ep.Number),
+		Prefix:   ep.Prefix,
+		Show:     config.ShowName(ep.Prefix),
+		Episode:  ep.Number,
+		Title:    ep.Title,
+		URL:      ep.URL,
+		Hosts:    ep.Roster.Hosts,
+		Guests:   ep.Roster.Guests,
+		Words:    len(strings.Fields(text)),
+		Language: ep.Language,
+		Text:     text,
+	}
+	if !ep.Date.IsZero() {
+		rec.Date = ep.Date.Format("2006-01-02")
+	}
+	return rec
+}
@@ -0,0 +1,72 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+func TestJSONL(t *testing.T) {
+	dir, err := os.MkdirTemp("", "jsonl_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ep := converter.Episode{
+		Prefix: "SN", Number: 1000, Title: "Security Now 1000",
+		Content: "EP:1000 Date:24-11-19 TS:1:02:03 - Steve Gibson Hello\nEP:1000 Date:24-11-19 - Leo Laporte Untimed",
+	}
+
+	// Episode mode, single file
+	out := filepath.Join(dir, "corpus.jsonl")
+	w := NewShardWriter(out, 0)
+	n, err := JSONL([]converter.Episode{ep}, w, false)
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil || n != 1 || len(w.Files) != 1 || w.Files[0] != out {
+		t.Fatalf("JSONL = %d, %v, files %v", n, err, w.Files)
+	}
+	data, _ := os.ReadFile(out)
+	var rec EpisodeRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		t.Fatalf("Invalid JSON line: %v", err)
+	}
+	if rec.ID != "SN_1000" || rec.Text != "Steve Gibson Hello\nLeo Laporte Untimed" || rec.Words != 6 {
+		t.Errorf("Unexpected record: %+v", rec)
+	}
+
+	// Turn mode, one record per shard
+	w = NewShardWriter(out, 10)
+	n, err = JSONL([]converter.Episode{ep}, w, true)
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil || n != 2 || len(w.Files) != 2 {
+		t.Fatalf("JSONL by turn = %d, %v, files %v", n, err, w.Files)
+	}
+	if filepath.Base(w.Files[1]) != "corpus-00001.jsonl" {
+		t.Errorf("Unexpected shard name: %s", w.Files[1])
+	}
+	f, err := os.Open(w.Files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	var turn TurnRecord
+	if !sc.Scan() || json.Unmarshal(sc.Bytes(), &turn) != nil {
+		t.Fatal("Expected a turn record in the first shard")
+	}
+	if turn.ID != "SN_1000_0" || turn.Start != 3723 || turn.Text != "Steve Gibson Hello" {
+		t.Errorf("Unexpected turn: %+v", turn)
+	}
+	if sc.Scan() {
+		t.Error("Expected one record per shard")
+	}
+}
@@ -0,0 +1,302 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+// EpisodeMapping is the default index body (mappings) for episode documents
+const EpisodeMapping = `{
+  "mappings": {
+    "properties": {
+      "id":       {"type": "keyword"},
+      "prefix":   {"type": "keyword"},
+      "show":     {"type": "keyword"},
+      "episode":  {"type": "integer"},
+      "title":    {"type": "text"},
+      "date":     {"type": "date", "format": "yyyy-MM-dd"},
+      "url":      {"type": "keyword", "index": false},
+      "hosts":    {"type": "keyword"},
+      "guests":   {"type": "keyword"},
+      "words":    {"type": "integer"},
+      "language": {"type": "keyword"},
+      "text":     {"type": "text"}
+    }
+  }
+}`
+
+// SegmentMapping is the default index body (mappings) for speaker turns
+const SegmentMapping = `{
+  "mappings": {
+    "properties": {
+      "id":            {"type": "keyword"},
+      "prefix":        {"type": "keyword"},
+      "show":          {"type": "keyword"},
+      "episode":       {"type": "integer"},
+      "title":         {"type": "text"},
+      "date":          {"type": "date", "format": "yyyy-MM-dd"},
+      "turn":          {"type": "integer"},
+      "speaker":       {"type": "keyword"},
+      "start_seconds": {"type": "float"},
+      "timecode":      {"type": "keyword", "index": false},
+      "text":          {"type": "text"}
+    }
+  }
+}`
+
+// BulkBackoff is the wait before the first retry of a bulk request; it
+// doubles with each further attempt
+var BulkBackoff = 2 * time.Second
+
+// SegmentDoc is a speaker turn as indexed by OpenSearch
+type SegmentDoc struct {
+	TurnRecord
+	Show    string `json:"show"`
+	Speaker string `json:"speaker,omitempty"`
+}
+
+// OpenSearch pushes episodes and their speaker turns into an Elasticsearch
+// or OpenSearch cluster through the bulk API. Episodes go to the index
+// <Index>-episodes and turns to <Index>-segments; documents are keyed by
+// their IDs, so pushing an episode again replaces it.
+type OpenSearch struct {
+	Endpoint string // Cluster URL; basic auth credentials may be given in it
+	Index    string
+	APIKey   string // Sent as "Authorization: ApiKey ..." when set
+	Batch    int    // Documents per bulk request
+	Retries  int    // Attempts per bulk request after the first
+
+	// Index bodies used when an index does not exist yet
+	EpisodeMapping string
+	SegmentMapping string
+
+	HTTP *http.Client
+}
+
+// NewOpenSearch returns a client for the cluster at endpoint with the
+// default mappings
+func NewOpenSearch(endpoint, index string) *OpenSearch {
+	return &OpenSearch{
+		Endpoint:       strings.TrimRight(endpoint, "/"),
+		Index:          strings.ToLower(index),
+		Batch:          500,
+		Retries:        3,
+		EpisodeMapping: EpisodeMapping,
+		SegmentMapping: SegmentMapping,
+		HTTP:           &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// PushResult counts the documents a push indexed
+type PushResult struct {
+	Episodes int
+	Segments int
+	Failed   int
+}
+
+// bulkDoc is one document of a bulk request
+type bulkDoc struct {
+	index, id string
+	source    []byte
+}
+
+// Push creates the indices if needed and indexes the episodes and their
+// turns. Documents the cluster rejects for lack of capacity (429) are
+// retried; other rejections are counted as failed and reported in the error.
+func (o *OpenSearch) Push(episodes []converter.Episode) (PushResult, error) {
+	var res PushResult
+	episodeIndex, segmentIndex := o.Index+"-episodes", o.Index+"-segments"
+	if err := o.ensureIndex(episodeIndex, o.EpisodeMapping); err != nil {
+		return res, err
+	}
+	if err := o.ensureIndex(segmentIndex, o.SegmentMapping); err != nil {
+		return res, err
+	}
+
+	var batch []bulkDoc
+	var failures []string
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		failed, err := o.bulk(batch)
+		if err != nil {
+			return err
+		}
+		for _, d := range batch {
+			if _, bad := failed[d.id]; bad {
+				continue
+			}
+			if d.index == episodeIndex {
+				res.Episodes++
+			} else {
+				res.Segments++
+			}
+		}
+		for id, reason := range failed {
+			res.Failed++
+			failures = append(failures, id+": "+reason)
+		}
+		batch = batch[:0]
+		return nil
+	}
+	add := func(index, id string, v interface{}) error {
+		source, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		batch = append(batch, bulkDoc{index, id, source})
+		if len(batch) >= o.Batch {
+			return flush()
+		}
+		return nil
+	}
+
+	for _, ep := range episodes {
+		rec := NewEpisodeRecord(ep)
+		if err := add(episodeIndex, rec.ID, rec); err != nil {
+			return res, err
+		}
+		for i, turn := range NewTurnRecords(ep) {
+			doc := SegmentDoc{TurnRecord: turn, Show: rec.Show}
+			if i < len(ep.Turns) {
+				doc.Speaker = ep.Turns[i].Speaker
+			}
+			if err := add(segmentIndex, turn.ID, doc); err != nil {
+				return res, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return res, err
+	}
+	if len(failures) > 0 {
+		return res, fmt.Errorf("%d documents were rejected (first: %s)", len(failures), failures[0])
+	}
+	return res, nil
+}
+
+// ensureIndex creates an index with the given body unless it exists
+func (o *OpenSearch) ensureIndex(name, body string) error {
+	resp, err := o.do("HEAD", "/"+name, nil, "")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("checking index %s: status code %d", name, resp.StatusCode)
+	}
+	resp, err = o.do("PUT", "/"+name, []byte(body), "application/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("creating index %s: status code %d: %s", name, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		ID     string          `json:"_id"`
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+// bulk indexes docs, retrying the whole request on network errors, 429s
+// and server errors, and the documents rejected with 429 individually.
+// Returns the reasons of documents that failed for good, by ID.
+func (o *OpenSearch) bulk(docs []bulkDoc) (map[string]string, error) {
+	failed := make(map[string]string)
+	pending := docs
+	wait := BulkBackoff
+	var lastErr error
+	for attempt := 0; attempt <= o.Retries && len(pending) > 0; attempt++ {
+		if attempt > 0 {
+			time.Sleep(wait)
+			wait *= 2
+		}
+		var body bytes.Buffer
+		for _, d := range pending {
+			action, _ := json.Marshal(map[string]map[string]string{"index": {"_index": d.index, "_id": d.id}})
+			body.Write(action)
+			body.WriteByte('\n')
+			body.Write(d.source)
+			body.WriteByte('\n')
+		}
+		resp, err := o.do("POST", "/_bulk", body.Bytes(), "application/x-ndjson")
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			lastErr = fmt.Errorf("bulk request: status code %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+			// Client errors other than throttling will not succeed on retry
+			if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+				return nil, lastErr
+			}
+			continue
+		}
+
+		var parsed bulkResponse
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			return nil, fmt.Errorf("invalid bulk response: %v", err)
+		}
+		if len(parsed.Items) != len(pending) {
+			return nil, fmt.Errorf("bulk response has %d items for %d documents", len(parsed.Items), len(pending))
+		}
+		var retry []bulkDoc
+		for i, item := range parsed.Items {
+			for _, r := range item {
+				switch {
+				case r.Status == http.StatusTooManyRequests:
+					retry = append(retry, pending[i])
+				case r.Status >= 300:
+					failed[pending[i].id] = string(r.Error)
+				}
+			}
+		}
+		pending, lastErr = retry, nil
+		if len(retry) > 0 {
+			lastErr = fmt.Errorf("%d documents still rejected with status 429", len(retry))
+		}
+	}
+	if len(pending) > 0 {
+		return nil, lastErr
+	}
+	return failed, nil
+}
+
+func (o *OpenSearch) do(method, path string, body []byte, contentType string) (*http.Response, error) {
+	req, err := http.NewRequest(method, o.Endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if o.APIKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+o.APIKey)
+	}
+	return o.HTTP.Do(req)
+}
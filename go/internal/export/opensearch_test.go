@@ -0,0 +1,110 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+func TestOpenSearchPush(t *testing.T) {
+	BulkBackoff = 0
+	var mu sync.Mutex
+	created := map[string]string{}
+	indexed := map[string]string{} // _id -> _index
+	throttled := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("Authorization") != "ApiKey secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		switch {
+		case r.Method == "HEAD":
+			if name == "twit-episodes" {
+				w.WriteHeader(http.StatusOK) // Already exists
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == "PUT":
+			body, _ := io.ReadAll(r.Body)
+			created[name] = string(body)
+		case r.URL.Path == "/_bulk":
+			if r.Header.Get("Content-Type") != "application/x-ndjson" {
+				t.Errorf("bulk content type = %q", r.Header.Get("Content-Type"))
+			}
+			var items []string
+			sc := bufio.NewScanner(r.Body)
+			sc.Buffer(nil, 1<<20)
+			for sc.Scan() {
+				var action struct {
+					Index struct {
+						Index string `json:"_index"`
+						ID    string `json:"_id"`
+					} `json:"index"`
+				}
+				json.Unmarshal(sc.Bytes(), &action)
+				sc.Scan() // Source
+				id := action.Index.ID
+				status := 201
+				switch {
+				case id == "SN_1000_1" && !throttled:
+					throttled, status = true, 429
+				case id == "SN_1000_2":
+					status = 400
+				default:
+					indexed[id] = action.Index.Index
+				}
+				items = append(items, fmt.Sprintf(`{"index":{"_id":%q,"status":%d,"error":{"type":"x"}}}`, id, status))
+			}
+			fmt.Fprintf(w, `{"errors":true,"items":[%s]}`, strings.Join(items, ","))
+		}
+	}))
+	defer srv.Close()
+
+	ep := converter.Episode{
+		Prefix: "SN", Number: 1000, Title: "Security Now 1000",
+		Content: "EP:1000 Date:24-11-19 TS:1:02:03 - Steve Gibson Hello\nEP:1000 Date:24-11-19 - Leo Laporte Hi\nEP:1000 Date:24-11-19 - Steve Gibson Bad",
+		Turns:   []converter.Turn{{Speaker: "Steve Gibson"}, {Speaker: "Leo Laporte"}, {Speaker: "Steve Gibson"}},
+	}
+	o := NewOpenSearch(srv.URL+"/", "TWIT")
+	o.APIKey = "secret"
+	o.Batch = 2
+	res, err := o.Push([]converter.Episode{ep})
+	if err == nil || !strings.Contains(err.Error(), "SN_1000_2") {
+		t.Errorf("Push error = %v, want the rejected document", err)
+	}
+	if res != (PushResult{Episodes: 1, Segments: 2, Failed: 1}) {
+		t.Errorf("Push = %+v", res)
+	}
+	if _, ok := created["twit-episodes"]; ok || created["twit-segments"] != SegmentMapping {
+		t.Errorf("Created indices: %v", created)
+	}
+	if indexed["SN_1000"] != "twit-episodes" || indexed["SN_1000_0"] != "twit-segments" || indexed["SN_1000_1"] != "twit-segments" {
+		t.Errorf("Indexed: %v", indexed)
+	}
+}
+
+func TestOpenSearchBulkClientError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" {
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("malformed"))
+	}))
+	defer srv.Close()
+	_, err := NewOpenSearch(srv.URL, "twit").Push([]converter.Episode{{Prefix: "SN", Number: 1, Content: "EP:1 Date:24-11-19 - A B"}})
+	if err == nil || !strings.Contains(err.Error(), "malformed") {
+		t.Errorf("Push error = %v", err)
+	}
+}
@@ -0,0 +1,276 @@
+package export
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// SyncStateFile records, per search target, what each episode looked like
+// when it was last pushed
+const SyncStateFile = "search_sync.json"
+
+// SearchEngine is a search server that episode records can be pushed to
+type SearchEngine interface {
+	// Target identifies the server and index in the sync state
+	Target() string
+	// Upsert adds the records, replacing any with the same ID
+	Upsert(recs []EpisodeRecord) error
+}
+
+// SyncResult counts the episodes a sync pushed and skipped
+type SyncResult struct {
+	Pushed    int
+	Unchanged int
+}
+
+// SyncState maps search targets to the hashes of the records pushed there,
+// by record ID
+type SyncState map[string]map[string]string
+
+// LoadSyncState reads the sync state of a data directory; a missing file is
+// an empty state
+func LoadSyncState(dataDir string) (SyncState, error) {
+	state := make(SyncState)
+	data, err := storage.ReadFile(storage.Join(dataDir, SyncStateFile))
+	if errors.Is(err, storage.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", SyncStateFile, err)
+	}
+	return state, nil
+}
+
+// Save writes the sync state to a data directory
+func (s SyncState) Save(dataDir string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return storage.WriteFile(storage.Join(dataDir, SyncStateFile), append(data, '\n'))
+}
+
+// Sync pushes the episodes that are new or changed since they were last
+// pushed to the engine, batch records at a time, and records them in state.
+// With full, every episode is pushed.
+func Sync(engine SearchEngine, episodes []converter.Episode, state SyncState, batch int, full bool) (SyncResult, error) {
+	var res SyncResult
+	if batch <= 0 {
+		batch = 100
+	}
+	pushed := state[engine.Target()]
+	if pushed == nil {
+		pushed = make(map[string]string)
+		state[engine.Target()] = pushed
+	}
+
+	var recs []EpisodeRecord
+	var hashes []string
+	flush := func() error {
+		if len(recs) == 0 {
+			return nil
+		}
+		if err := engine.Upsert(recs); err != nil {
+			return err
+		}
+		for i, rec := range recs {
+			pushed[rec.ID] = hashes[i]
+		}
+		res.Pushed += len(recs)
+		recs, hashes = recs[:0], hashes[:0]
+		return nil
+	}
+	for _, ep := range episodes {
+		rec := NewEpisodeRecord(ep)
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return res, err
+		}
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:8])
+		if !full && pushed[rec.ID] == hash {
+			res.Unchanged++
+			continue
+		}
+		recs, hashes = append(recs, rec), append(hashes, hash)
+		if len(recs) >= batch {
+			if err := flush(); err != nil {
+				return res, err
+			}
+		}
+	}
+	return res, flush()
+}
+
+// Meilisearch pushes records to a Meilisearch index, which it creates on
+// first use
+type Meilisearch struct {
+	Endpoint string
+	Index    string
+	APIKey   string
+	HTTP     *http.Client
+}
+
+// NewMeilisearch returns a client for an index of the server at endpoint
+func NewMeilisearch(endpoint, index, apiKey string) *Meilisearch {
+	return &Meilisearch{
+		Endpoint: strings.TrimRight(endpoint, "/"),
+		Index:    index,
+		APIKey:   apiKey,
+		HTTP:     &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// Target implements SearchEngine
+func (m *Meilisearch) Target() string {
+	return "meilisearch " + m.Endpoint + "/" + m.Index
+}
+
+// Upsert implements SearchEngine. Meilisearch applies documents
+// asynchronously; the records are searchable once its task completes.
+func (m *Meilisearch) Upsert(recs []EpisodeRecord) error {
+	body, err := json.Marshal(recs)
+	if err != nil {
+		return err
+	}
+	header := http.Header{"Content-Type": {"application/json"}}
+	if m.APIKey != "" {
+		header.Set("Authorization", "Bearer "+m.APIKey)
+	}
+	_, err = pushRequest(m.HTTP, "POST", m.Endpoint+"/indexes/"+url.PathEscape(m.Index)+"/documents?primaryKey=id", header, body)
+	return err
+}
+
+// TypesenseSchema is the collection schema created for episode records
+var TypesenseSchema = []map[string]interface{}{
+	{"name": "prefix", "type": "string", "facet": true},
+	{"name": "show", "type": "string", "facet": true},
+	{"name": "episode", "type": "int32"},
+	{"name": "title", "type": "string"},
+	{"name": "date", "type": "string", "optional": true, "sort": true},
+	{"name": "url", "type": "string", "optional": true, "index": false},
+	{"name": "hosts", "type": "string[]", "optional": true, "facet": true},
+	{"name": "guests", "type": "string[]", "optional": true, "facet": true},
+	{"name": "words", "type": "int32"},
+	{"name": "language", "type": "string", "optional": true, "facet": true},
+	{"name": "text", "type": "string"},
+}
+
+// Typesense pushes records to a Typesense collection, which it creates
+// with TypesenseSchema if it does not exist
+type Typesense struct {
+	Endpoint   string
+	Collection string
+	APIKey     string
+	HTTP       *http.Client
+
+	checked bool
+}
+
+// NewTypesense returns a client for a collection of the server at endpoint
+func NewTypesense(endpoint, collection, apiKey string) *Typesense {
+	return &Typesense{
+		Endpoint:   strings.TrimRight(endpoint, "/"),
+		Collection: collection,
+		APIKey:     apiKey,
+		HTTP:       &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// Target implements SearchEngine
+func (t *Typesense) Target() string {
+	return "typesense " + t.Endpoint + "/" + t.Collection
+}
+
+// Upsert implements SearchEngine, importing the records as JSON Lines
+func (t *Typesense) Upsert(recs []EpisodeRecord) error {
+	header := http.Header{"X-Typesense-Api-Key": {t.APIKey}}
+	base := t.Endpoint + "/collections"
+	if !t.checked {
+		status, err := pushRequest(t.HTTP, "GET", base+"/"+url.PathEscape(t.Collection), header, nil)
+		if status == http.StatusNotFound {
+			schema, _ := json.Marshal(map[string]interface{}{"name": t.Collection, "fields": TypesenseSchema})
+			header.Set("Content-Type", "application/json")
+			_, err = pushRequest(t.HTTP, "POST", base, header, schema)
+		}
+		if err != nil {
+			return err
+		}
+		t.checked = true
+	}
+
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, rec := range recs {
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	header.Set("Content-Type", "text/plain")
+	req, err := http.NewRequest("POST", base+"/"+url.PathEscape(t.Collection)+"/documents/import?action=upsert", &body)
+	if err != nil {
+		return err
+	}
+	req.Header = header
+	resp, err := t.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("typesense import: status code %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	// The import answers with one result line per document
+	for i, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var r struct {
+			Success bool   `json:"success"`
+			Error   string `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			return fmt.Errorf("invalid typesense import response: %v", err)
+		}
+		if !r.Success && i < len(recs) {
+			return fmt.Errorf("typesense rejected %s: %s", recs[i].ID, r.Error)
+		}
+	}
+	return nil
+}
+
+// pushRequest sends a request to a search server, returning its status
+// and an error for any status but 2xx
+func pushRequest(client *http.Client, method, u string, header http.Header, body []byte) (int, error) {
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header = header.Clone()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, fmt.Errorf("%s %s: status code %d: %s", method, req.URL.Path, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,115 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+func syncEpisodes() []converter.Episode {
+	return []converter.Episode{
+		{Prefix: "SN", Number: 1000, Title: "Security Now 1000", Content: "EP:1000 Date:24-11-19 - Steve Gibson Hello"},
+		{Prefix: "SN", Number: 1001, Title: "Security Now 1001", Content: "EP:1001 Date:24-11-26 - Steve Gibson Again"},
+	}
+}
+
+func TestSyncMeilisearch(t *testing.T) {
+	var got [][]EpisodeRecord
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/indexes/twit/documents" || r.URL.Query().Get("primaryKey") != "id" || r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+		var recs []EpisodeRecord
+		json.NewDecoder(r.Body).Decode(&recs)
+		got = append(got, recs)
+		w.WriteHeader(http.StatusAccepted)
+		io.WriteString(w, `{"taskUid":1}`)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	m := NewMeilisearch(srv.URL, "twit", "key")
+	state, err := LoadSyncState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eps := syncEpisodes()
+	if res, err := Sync(m, eps, state, 1, false); err != nil || res != (SyncResult{Pushed: 2}) || len(got) != 2 {
+		t.Fatalf("First sync = %+v, %v (%d requests)", res, err, len(got))
+	}
+	if err := state.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the changed episode is pushed again
+	state, _ = LoadSyncState(dir)
+	eps[1].Title = "Security Now 1001: Fixed"
+	res, err := Sync(m, eps, state, 10, false)
+	if err != nil || res != (SyncResult{Pushed: 1, Unchanged: 1}) {
+		t.Fatalf("Second sync = %+v, %v", res, err)
+	}
+	if last := got[len(got)-1]; len(last) != 1 || last[0].ID != "SN_1001" || last[0].Title != "Security Now 1001: Fixed" {
+		t.Errorf("Second sync pushed %+v", last)
+	}
+	if res, _ := Sync(m, eps, state, 10, true); res.Pushed != 2 {
+		t.Errorf("Full sync = %+v", res)
+	}
+}
+
+func TestSyncTypesense(t *testing.T) {
+	var created bool
+	var imported []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Typesense-Api-Key") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/collections/twit":
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == "POST" && r.URL.Path == "/collections":
+			var schema struct{ Name string }
+			json.NewDecoder(r.Body).Decode(&schema)
+			created = schema.Name == "twit"
+			w.WriteHeader(http.StatusCreated)
+		case r.URL.Path == "/collections/twit/documents/import" && r.URL.Query().Get("action") == "upsert":
+			sc := bufio.NewScanner(r.Body)
+			sc.Buffer(nil, 1<<20)
+			var results []string
+			for sc.Scan() {
+				var rec EpisodeRecord
+				json.Unmarshal(sc.Bytes(), &rec)
+				imported = append(imported, rec.ID)
+				if rec.Episode == 1001 {
+					results = append(results, `{"success":false,"error":"Bad field"}`)
+				} else {
+					results = append(results, `{"success":true}`)
+				}
+			}
+			io.WriteString(w, strings.Join(results, "\n"))
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer srv.Close()
+
+	state := make(SyncState)
+	ts := NewTypesense(srv.URL, "twit", "key")
+	_, err := Sync(ts, syncEpisodes(), state, 10, false)
+	if err == nil || !strings.Contains(err.Error(), "SN_1001: Bad field") {
+		t.Errorf("Sync error = %v", err)
+	}
+	if !created || strings.Join(imported, ",") != "SN_1000,SN_1001" {
+		t.Errorf("created %v, imported %v", created, imported)
+	}
+	// A failed batch is not recorded, so it is retried on the next sync
+	if len(state[ts.Target()]) != 0 {
+		t.Errorf("State after failure: %v", state)
+	}
+}
@@ -0,0 +1,43 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+// SegmentRecord is one timed speaker turn, as written by Segments
+type SegmentRecord struct {
+	Prefix   string  `json:"prefix"`
+	Episode  int     `json:"episode"`
+	Title    string  `json:"title"`
+	Start    float64 `json:"start_seconds"`
+	Timecode string  `json:"timecode"`
+	Text     string  `json:"text"`
+}
+
+// Segments writes every timed turn of the episodes as JSON Lines, so tools
+// can jump from a passage to its position in the episode audio. Returns the
+// number of segments written.
+func Segments(episodes []converter.Episode, w io.Writer) (int, error) {
+	enc := json.NewEncoder(w)
+	n := 0
+	for _, ep := range episodes {
+		for _, seg := range converter.Segments(ep.Content) {
+			rec := SegmentRecord{
+				Prefix:   ep.Prefix,
+				Episode:  ep.Number,
+				Title:    ep.Title,
+				Start:    seg.Start.Seconds(),
+				Timecode: seg.Timecode,
+				Text:     seg.Text,
+			}
+			if err := enc.Encode(rec); err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,29 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+func TestSegments(t *testing.T) {
+	ep := converter.Episode{
+		Prefix: "SN", Number: 1000, Title: "Security Now 1000",
+		Content: "EP:1000 Date:24-11-19 TS:1:02:03 - Steve Gibson Hello\nEP:1000 Date:24-11-19 - Leo Laporte Untimed",
+	}
+	var buf bytes.Buffer
+	n, err := Segments([]converter.Episode{ep}, &buf)
+	if err != nil || n != 1 {
+		t.Fatalf("Segments = %d, %v", n, err)
+	}
+	var rec SegmentRecord
+	if err := json.Unmarshal([]byte(strings.TrimSpace(buf.String())), &rec); err != nil {
+		t.Fatalf("Invalid JSON line: %v", err)
+	}
+	if rec.Start != 3723 || rec.Timecode != "01:02:03" || rec.Text != "Steve Gibson Hello" || rec.Episode != 1000 {
+		t.Errorf("Unexpected record: %+v", rec)
+	}
+}
@@ -0,0 +1,62 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/analysis"
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+// SpeakerCSVHeader is the header row written by SpeakerCSV
+var SpeakerCSVHeader = []string{"show", "prefix", "episode", "speaker", "episodes", "turns", "words", "share"}
+
+// SpeakerCSV writes words per speaker for each episode, followed by each
+// show's totals (with an empty episode column). episodes must be grouped by
+// show, as LoadEpisodes returns them.
+func SpeakerCSV(episodes []converter.Episode, w io.Writer, comma rune) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	if err := cw.Write(SpeakerCSVHeader); err != nil {
+		return err
+	}
+	write := func(prefix, episode string, stats []analysis.SpeakerStats) error {
+		for _, s := range stats {
+			row := []string{
+				config.ShowName(prefix),
+				prefix,
+				episode,
+				s.Speaker,
+				strconv.Itoa(s.Episodes),
+				strconv.Itoa(s.Turns),
+				strconv.Itoa(s.Words),
+				strconv.FormatFloat(s.Share, 'f', 4, 64),
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for start := 0; start < len(episodes); {
+		end := start
+		for end < len(episodes) && episodes[end].Prefix == episodes[start].Prefix {
+			end++
+		}
+		show := episodes[start:end]
+		for _, ep := range show {
+			if err := write(ep.Prefix, strconv.Itoa(ep.Number), analysis.EpisodeSpeakers(ep)); err != nil {
+				return err
+			}
+		}
+		if err := write(show[0].Prefix, "", analysis.ShowSpeakers(show)); err != nil {
+			return err
+		}
+		start = end
+	}
+	cw.Flush()
+	return cw.Error()
+}
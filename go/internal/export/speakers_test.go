@@ -0,0 +1,32 @@
+package export
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+func TestSpeakerCSV(t *testing.T) {
+	episodes := []converter.Episode{
+		{Prefix: "SN", Number: 950, Turns: []converter.Turn{{Speaker: "Steve Gibson", Words: 75}, {Speaker: "Leo Laporte", Words: 25}}},
+		{Prefix: "SN", Number: 951, Turns: []converter.Turn{{Speaker: "Steve Gibson", Words: 100}}},
+		{Prefix: "IM", Number: 800, Turns: []converter.Turn{{Words: 10}}},
+	}
+	var b strings.Builder
+	if err := SpeakerCSV(episodes, &b, ','); err != nil {
+		t.Fatal(err)
+	}
+	want := `show,prefix,episode,speaker,episodes,turns,words,share
+security now,SN,950,Steve Gibson,1,1,75,0.7500
+security now,SN,950,Leo Laporte,1,1,25,0.2500
+security now,SN,951,Steve Gibson,1,1,100,1.0000
+security now,SN,,Steve Gibson,2,2,175,0.8750
+security now,SN,,Leo Laporte,1,1,25,0.1250
+intelligent machines,IM,800,(unknown),1,1,10,1.0000
+intelligent machines,IM,,(unknown),1,1,10,1.0000
+`
+	if b.String() != want {
+		t.Errorf("SpeakerCSV =\n%s\nwant\n%s", b.String(), want)
+	}
+}
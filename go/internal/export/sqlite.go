@@ -0,0 +1,151 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// SQLiteBinary is the sqlite3 command-line shell used to build databases.
+// The export is generated as SQL and loaded through the shell so the module
+// stays free of cgo and third-party drivers.
+var SQLiteBinary = "sqlite3"
+
+const sqliteSchema = `PRAGMA journal_mode = OFF;
+PRAGMA synchronous = OFF;
+DROP TABLE IF EXISTS episodes_fts;
+DROP TABLE IF EXISTS episodes;
+CREATE TABLE episodes (
+	id INTEGER PRIMARY KEY,
+	show TEXT NOT NULL,
+	prefix TEXT NOT NULL,
+	episode INTEGER NOT NULL,
+	title TEXT NOT NULL,
+	byline_date TEXT,
+	date TEXT,
+	year INTEGER,
+	words INTEGER NOT NULL,
+	source_file TEXT NOT NULL,
+	content TEXT NOT NULL
+);
+CREATE INDEX episodes_prefix_episode ON episodes (prefix, episode);
+CREATE INDEX episodes_date ON episodes (date);
+CREATE VIRTUAL TABLE episodes_fts USING fts5(
+	title, content,
+	content='episodes', content_rowid='id',
+	tokenize='porter unicode61'
+);
+`
+
+// SQLite writes episodes to a self-contained SQLite database with an FTS5
+// full-text table. dbPath may be a local path or an object storage URL. The
+// database is built in a temp file and only replaces dbPath once sqlite3 has
+// succeeded, so a failed export leaves the previous one in place.
+func SQLite(episodes []converter.Episode, dbPath string) error {
+	if _, err := exec.LookPath(SQLiteBinary); err != nil {
+		return fmt.Errorf("sqlite export requires the %s command-line shell: %w", SQLiteBinary, err)
+	}
+	if err := storage.CheckWritable(dbPath); err != nil {
+		return err
+	}
+
+	var localPath string
+	if storage.IsRemote(dbPath) {
+		tmpDir, err := os.MkdirTemp("", "twit-export")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmpDir)
+		localPath = filepath.Join(tmpDir, storage.Base(dbPath))
+	} else {
+		// Next to dbPath, so the rename below stays on one file system
+		f, err := os.CreateTemp(filepath.Dir(dbPath), "."+filepath.Base(dbPath)+".*.tmp")
+		if err != nil {
+			return err
+		}
+		f.Close()
+		localPath = f.Name()
+		defer os.Remove(localPath)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		w := bufio.NewWriter(pw)
+		err := SQLiteScript(w, episodes)
+		if err == nil {
+			err = w.Flush()
+		}
+		pw.CloseWithError(err)
+	}()
+	cmd := exec.Command(SQLiteBinary, "-bail", localPath)
+	cmd.Stdin = pr
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	// Unblocks the writer if sqlite3 stopped reading early
+	pr.Close()
+	if err != nil {
+		return fmt.Errorf("sqlite3 failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	if storage.IsRemote(dbPath) {
+		data, err := os.ReadFile(localPath)
+		if err != nil {
+			return err
+		}
+		return storage.WriteFile(dbPath, data)
+	}
+	return storage.Rename(localPath, dbPath)
+}
+
+// SQLiteScript writes the SQL that creates and populates the database
+func SQLiteScript(w io.Writer, episodes []converter.Episode) error {
+	if _, err := io.WriteString(w, sqliteSchema+"BEGIN;\n"); err != nil {
+		return err
+	}
+	for i, ep := range episodes {
+		date := ""
+		if !ep.Date.IsZero() {
+			date = ep.Date.Format("2006-01-02")
+		}
+		_, err := fmt.Fprintf(w, "INSERT INTO episodes VALUES (%d, %s, %s, %d, %s, %s, %s, %d, %d, %s, %s);\n",
+			i+1,
+			sqlQuote(config.ShowName(ep.Prefix)),
+			sqlQuote(ep.Prefix),
+			ep.Number,
+			sqlQuote(ep.Title),
+			sqlQuote(ep.DateStr),
+			sqlNullable(date),
+			ep.Year,
+			len(strings.Fields(ep.Content)),
+			sqlQuote(storage.Base(ep.Path)),
+			sqlQuote(ep.Content),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "INSERT INTO episodes_fts (episodes_fts) VALUES ('rebuild');\nCOMMIT;\n")
+	return err
+}
+
+func sqlQuote(s string) string {
+	s = strings.ReplaceAll(s, "\x00", "")
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func sqlNullable(s string) string {
+	if s == "" {
+		return "NULL"
+	}
+	return sqlQuote(s)
+}
@@ -0,0 +1,94 @@
+package export
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+func testEpisodes() []converter.Episode {
+	return []converter.Episode{
+		{
+			Prefix: "SN", Number: 1000, Title: "Security Now 1000 Transcript",
+			DateStr: "Nov 19th 2024", Date: time.Date(2024, 11, 19, 0, 0, 0, 0, time.UTC), Year: 2024,
+			Content: "EP:1000 Date:24-11-19 - Steve Gibson It's Steve's OpenSSL episode",
+			Path:    "/data/SN_1000.html",
+		},
+		{
+			Prefix: "TWIG", Number: 233, Title: "This Week in Google 233",
+			DateStr: "Unknown Date", Year: 0,
+			Content: "EP:233 Date:00-01-01 - Jeff Jarvis Google's new phone",
+			Path:    "/data/TWIG_233.html",
+		},
+	}
+}
+
+func TestSQLiteScript(t *testing.T) {
+	var b strings.Builder
+	if err := SQLiteScript(&b, testEpisodes()); err != nil {
+		t.Fatal(err)
+	}
+	script := b.String()
+	if !strings.Contains(script, "It''s Steve''s OpenSSL") {
+		t.Error("Single quotes were not escaped")
+	}
+	if !strings.Contains(script, "'this week in google'") {
+		t.Error("Show name not resolved from prefix")
+	}
+	if !strings.Contains(script, "NULL") {
+		t.Error("Unparsed date should be stored as NULL")
+	}
+	if !strings.Contains(script, "USING fts5") {
+		t.Error("FTS5 table missing from schema")
+	}
+}
+
+func TestSQLiteExport(t *testing.T) {
+	if _, err := exec.LookPath(SQLiteBinary); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+	tmpDir, _ := os.MkdirTemp("", "exporttest")
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := filepath.Join(tmpDir, "archive.db")
+	if err := SQLite(testEpisodes(), dbPath); err != nil {
+		t.Fatalf("SQLite export failed: %v", err)
+	}
+
+	out, err := exec.Command(SQLiteBinary, dbPath,
+		"SELECT e.prefix || ':' || e.episode FROM episodes_fts JOIN episodes e ON e.id = episodes_fts.rowid WHERE episodes_fts MATCH 'openssl'").Output()
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if strings.TrimSpace(string(out)) != "SN:1000" {
+		t.Errorf("Unexpected FTS result: %q", out)
+	}
+}
+
+func TestSQLiteExportKeepsOldOnFailure(t *testing.T) {
+	if _, err := exec.LookPath("false"); err != nil {
+		t.Skip("false not installed")
+	}
+	defer func(bin string) { SQLiteBinary = bin }(SQLiteBinary)
+	SQLiteBinary = "false"
+
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "archive.db")
+	if err := os.WriteFile(dbPath, []byte("previous export"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := SQLite(testEpisodes(), dbPath); err == nil {
+		t.Fatal("SQLite should fail when sqlite3 does")
+	}
+	if data, err := os.ReadFile(dbPath); err != nil || string(data) != "previous export" {
+		t.Errorf("The previous database was not kept: %q, %v", data, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Temp files were left behind: %v", entries)
+	}
+}
@@ -0,0 +1,147 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+// CueWords caps the words of one subtitle cue (about two lines); longer
+// turns are split across cues that share the turn's time span
+var CueWords = 16
+
+// SpeechRate is the assumed speaking pace, in words per second, for a turn
+// with no later timecode to end it
+var SpeechRate = 2.5
+
+// Cue is one subtitle
+type Cue struct {
+	Start   time.Duration
+	End     time.Duration
+	Speaker string // Set on the first cue of a turn, when known
+	Text    string
+}
+
+// Cues builds subtitles from the timed turns of an episode. A timed turn
+// runs until the next timed turn starts, and untimed turns are folded into
+// the timed turn before them; text before the first timecode starts at
+// zero. Episodes without any timecodes have no cues.
+func Cues(ep converter.Episode) []Cue {
+	turns := converter.Turns(ep.Content)
+	timed := false
+	for _, t := range turns {
+		if t.Timecode != "" {
+			timed = true
+			break
+		}
+	}
+	if !timed {
+		return nil
+	}
+
+	// A span is a run of turns between two timecodes
+	type span struct {
+		start time.Duration
+		turns []int
+	}
+	var spans []span
+	for i, t := range turns {
+		if t.Timecode != "" || len(spans) == 0 {
+			spans = append(spans, span{start: t.Start})
+		}
+		spans[len(spans)-1].turns = append(spans[len(spans)-1].turns, i)
+	}
+
+	var cues []Cue
+	for si, sp := range spans {
+		// Split the span's text into cue-sized chunks
+		type chunk struct {
+			speaker string
+			words   []string
+		}
+		var chunks []chunk
+		words := 0
+		for _, i := range sp.turns {
+			text, who := turns[i].Text, ""
+			if i < len(ep.Turns) && ep.Turns[i].Speaker != "" && strings.HasPrefix(text, ep.Turns[i].Speaker) {
+				who = ep.Turns[i].Speaker
+				text = text[len(who):]
+			}
+			fields := strings.Fields(text)
+			for j := 0; j < len(fields); j += CueWords {
+				end := j + CueWords
+				if end > len(fields) {
+					end = len(fields)
+				}
+				c := chunk{words: fields[j:end]}
+				if j == 0 {
+					c.speaker = who
+				}
+				chunks = append(chunks, c)
+				words += end - j
+			}
+		}
+		if words == 0 {
+			continue
+		}
+
+		end := sp.start + time.Duration(float64(words)/SpeechRate*float64(time.Second))
+		if si+1 < len(spans) && spans[si+1].start > sp.start {
+			end = spans[si+1].start
+		}
+		// Share the span's time among its chunks by word count
+		at, done := sp.start, 0
+		for _, c := range chunks {
+			done += len(c.words)
+			next := sp.start + (end-sp.start)*time.Duration(done)/time.Duration(words)
+			cues = append(cues, Cue{Start: at, End: next, Speaker: c.speaker, Text: strings.Join(c.words, " ")})
+			at = next
+		}
+	}
+	return cues
+}
+
+// SRT writes cues as a SubRip subtitle file, the speaker leading the first
+// cue of each turn
+func SRT(cues []Cue, w io.Writer) error {
+	for i, c := range cues {
+		text := c.Text
+		if c.Speaker != "" {
+			text = c.Speaker + ": " + text
+		}
+		if _, err := fmt.Fprintf(w, "%d\n%s --> %s\n%s\n\n", i+1, subtitleTime(c.Start, ','), subtitleTime(c.End, ','), text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// vttEscaper escapes the characters WebVTT cue text reserves, which also
+// keeps "-->" out of it
+var vttEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// VTT writes cues as a WebVTT file, marking speakers with voice spans
+func VTT(cues []Cue, w io.Writer) error {
+	if _, err := io.WriteString(w, "WEBVTT\n\n"); err != nil {
+		return err
+	}
+	for _, c := range cues {
+		text := vttEscaper.Replace(c.Text)
+		if c.Speaker != "" {
+			text = "<v " + vttEscaper.Replace(c.Speaker) + ">" + text + "</v>"
+		}
+		if _, err := fmt.Fprintf(w, "%s --> %s\n%s\n\n", subtitleTime(c.Start, '.'), subtitleTime(c.End, '.'), text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// subtitleTime renders an offset as HH:MM:SS,mmm (or with a '.' separator)
+func subtitleTime(d time.Duration, sep byte) string {
+	ms := int(d / time.Millisecond)
+	return fmt.Sprintf("%02d:%02d:%02d%c%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
@@ -0,0 +1,61 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+func TestCues(t *testing.T) {
+	ep := converter.Episode{
+		Prefix: "SN", Number: 1000,
+		Content: strings.Join([]string{
+			"EP:1000 Date:24-11-19 - Leo Laporte Before the clock",
+			"EP:1000 Date:24-11-19 TS:0:10 - Steve Gibson one two three four five six",
+			"EP:1000 Date:24-11-19 - Leo Laporte Right & <true>",
+			"EP:1000 Date:24-11-19 TS:0:40 - Steve Gibson Last words here now",
+		}, "\n"),
+		Turns: []converter.Turn{{Speaker: "Leo Laporte"}, {Speaker: "Steve Gibson"}, {Speaker: "Leo Laporte"}, {Speaker: "Steve Gibson"}},
+	}
+	CueWords = 4
+	defer func() { CueWords = 16 }()
+	cues := Cues(ep)
+	want := []Cue{
+		{0, 10 * time.Second, "Leo Laporte", "Before the clock"},
+		// The 30s from 0:10 to 0:40 are shared by 9 words in three cues
+		{10 * time.Second, 10*time.Second + 30*time.Second*4/9, "Steve Gibson", "one two three four"},
+		{10*time.Second + 30*time.Second*4/9, 10*time.Second + 30*time.Second*6/9, "", "five six"},
+		{10*time.Second + 30*time.Second*6/9, 40 * time.Second, "Leo Laporte", "Right & <true>"},
+		// The last turn is paced at SpeechRate
+		{40 * time.Second, 40*time.Second + 1600*time.Millisecond, "Steve Gibson", "Last words here now"},
+	}
+	if len(cues) != len(want) {
+		t.Fatalf("Cues = %+v", cues)
+	}
+	for i := range want {
+		if cues[i] != want[i] {
+			t.Errorf("Cue %d = %+v, want %+v", i, cues[i], want[i])
+		}
+	}
+
+	var srt, vtt bytes.Buffer
+	if err := SRT(cues[:2], &srt); err != nil {
+		t.Fatal(err)
+	}
+	if want := "1\n00:00:00,000 --> 00:00:10,000\nLeo Laporte: Before the clock\n\n2\n00:00:10,000 --> 00:00:23,333\nSteve Gibson: one two three four\n\n"; srt.String() != want {
+		t.Errorf("SRT =\n%s", srt.String())
+	}
+	if err := VTT(cues[3:4], &vtt); err != nil {
+		t.Fatal(err)
+	}
+	if want := "WEBVTT\n\n00:00:30,000 --> 00:00:40,000\n<v Leo Laporte>Right &amp; &lt;true&gt;</v>\n\n"; vtt.String() != strings.ReplaceAll(want, ",000", ".000") {
+		t.Errorf("VTT =\n%s", vtt.String())
+	}
+
+	if cues := Cues(converter.Episode{Content: "EP:1 Date:24-11-19 - A Untimed"}); cues != nil {
+		t.Errorf("Untimed episode has cues %+v", cues)
+	}
+}
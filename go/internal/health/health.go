@@ -0,0 +1,226 @@
+// Package health lets process supervisors and monitoring scripts tell a
+// working archiver from a hung one. A Monitor records each sign of
+// progress as a heartbeat in a JSON health file and, under systemd, sends
+// readiness, status and watchdog notifications (see Notify).
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// FileName is the default health file, kept in the data directory
+const FileName = "health.json"
+
+// Phases a Status reports
+const (
+	Starting   = "starting"
+	Fetching   = "fetching"
+	Converting = "converting"
+	Exporting  = "exporting"
+	Idle       = "idle"
+	Stopped    = "stopped"
+)
+
+// WriteInterval is the least time between two writes of the health file
+// for heartbeats within the same phase
+var WriteInterval = 5 * time.Second
+
+// Run is the outcome of one pass of a daemon
+type Run struct {
+	Started    time.Time `json:"started"`
+	Finished   time.Time `json:"finished"`
+	Downloaded int       `json:"downloaded"`
+	Failed     int       `json:"failed"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Status is the content of the health file
+type Status struct {
+	PID       int        `json:"pid"`
+	Host      string     `json:"host"`
+	Command   string     `json:"command"`
+	Started   time.Time  `json:"started"`
+	Heartbeat time.Time  `json:"heartbeat"`
+	Phase     string     `json:"phase"`
+	Detail    string     `json:"detail,omitempty"` // What is being worked on
+	Runs      int        `json:"runs"`
+	LastRun   *Run       `json:"last_run,omitempty"`
+	NextRun   *time.Time `json:"next_run,omitempty"`
+}
+
+// Monitor keeps a Status up to date. A nil Monitor does nothing, so code
+// can report progress whether or not it runs as a daemon.
+type Monitor struct {
+	// Path is the health file, "" to only notify systemd
+	Path string
+
+	mu        sync.Mutex
+	status    Status
+	run       *Run
+	lastWrite time.Time
+	watchdog  time.Duration
+}
+
+// NewMonitor starts monitoring command, writing its health to path
+func NewMonitor(path, command string) *Monitor {
+	host, _ := os.Hostname()
+	now := time.Now().UTC()
+	return &Monitor{
+		Path:     path,
+		status:   Status{PID: os.Getpid(), Host: host, Command: command, Started: now, Heartbeat: now, Phase: Starting},
+		watchdog: WatchdogInterval(),
+	}
+}
+
+// Watchdog returns how often systemd expects a watchdog ping, 0 if it
+// expects none
+func (m *Monitor) Watchdog() time.Duration {
+	if m == nil {
+		return 0
+	}
+	return m.watchdog
+}
+
+// Ready tells systemd that start-up is done
+func (m *Monitor) Ready() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.status.Heartbeat = time.Now().UTC()
+	m.write(true)
+	m.notify("READY=1")
+}
+
+// Beat records progress in a phase: the health file's heartbeat moves on
+// and systemd's watchdog is pinged
+func (m *Monitor) Beat(phase, detail string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	changed := phase != m.status.Phase
+	m.status.Phase, m.status.Detail = phase, detail
+	m.status.Heartbeat = time.Now().UTC()
+	m.write(changed)
+	m.notify("WATCHDOG=1")
+}
+
+// StartRun records the start of a pass
+func (m *Monitor) StartRun() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.run = &Run{Started: time.Now().UTC()}
+	m.status.NextRun = nil
+	m.mu.Unlock()
+	m.Beat(Starting, "")
+}
+
+// FinishRun records the outcome of the pass begun by StartRun
+func (m *Monitor) FinishRun(downloaded, failed int, err error) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.run == nil {
+		m.run = &Run{Started: m.status.Started}
+	}
+	m.run.Finished = time.Now().UTC()
+	m.run.Downloaded, m.run.Failed = downloaded, failed
+	if err != nil {
+		m.run.Error = err.Error()
+	}
+	m.status.LastRun, m.run = m.run, nil
+	m.status.Runs++
+	m.status.Heartbeat = m.status.LastRun.Finished
+	m.write(true)
+}
+
+// Idle records that the daemon waits for its next pass at next
+func (m *Monitor) Idle(next time.Time) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	next = next.UTC()
+	m.status.NextRun = &next
+	m.mu.Unlock()
+	m.Beat(Idle, "")
+}
+
+// Stop records that the daemon is shutting down
+func (m *Monitor) Stop() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.notify("STOPPING=1")
+	m.status.Phase, m.status.Detail, m.status.NextRun = Stopped, "", nil
+	m.status.Heartbeat = time.Now().UTC()
+	m.write(true)
+}
+
+// write saves the health file, at most every WriteInterval unless forced.
+// Failures are reported but do not stop the daemon.
+func (m *Monitor) write(force bool) {
+	if m.Path == "" || (!force && time.Since(m.lastWrite) < WriteInterval) {
+		return
+	}
+	data, err := json.MarshalIndent(m.status, "", "  ")
+	if err == nil {
+		err = storage.WriteFile(m.Path, append(data, '\n'))
+	}
+	if err != nil {
+		fmt.Printf("Warning: could not write %s: %v\n", m.Path, err)
+		return
+	}
+	m.lastWrite = time.Now()
+}
+
+// notify sends state to systemd along with the current status line
+func (m *Monitor) notify(state string) {
+	line := m.status.Phase
+	if m.status.Detail != "" {
+		line += ": " + m.status.Detail
+	}
+	if err := Notify(state + "\nSTATUS=" + line); err != nil {
+		fmt.Printf("Warning: systemd notification failed: %v\n", err)
+	}
+}
+
+// Load reads a health file
+func Load(path string) (*Status, error) {
+	data, err := storage.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Status{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", path, err)
+	}
+	return s, nil
+}
+
+// Check returns an error if the status shows a stopped daemon or one whose
+// last heartbeat is older than maxAge
+func Check(s *Status, maxAge time.Duration, now time.Time) error {
+	if s.Phase == Stopped {
+		return fmt.Errorf("%s (pid %d on %s) stopped at %s", s.Command, s.PID, s.Host, s.Heartbeat.Local().Format("2006-01-02 15:04:05"))
+	}
+	if age := now.Sub(s.Heartbeat); age > maxAge {
+		return fmt.Errorf("%s (pid %d on %s) has shown no progress for %s, while %s", s.Command, s.PID, s.Host, age.Round(time.Second), s.Phase)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package health
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMonitor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	m := NewMonitor(path, "twit-archiver run")
+	m.Ready()
+	m.StartRun()
+	m.Beat(Fetching, "SN 1000")
+	m.FinishRun(3, 1, nil)
+	next := time.Now().Add(time.Hour)
+	m.Idle(next)
+
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Phase != Idle || s.Runs != 1 || s.LastRun == nil || s.LastRun.Downloaded != 3 || s.NextRun == nil || !s.NextRun.Equal(next.UTC().Round(0)) {
+		t.Errorf("Status = %+v", s)
+	}
+	if err := Check(s, time.Minute, time.Now()); err != nil {
+		t.Errorf("Check = %v", err)
+	}
+	if err := Check(s, time.Minute, time.Now().Add(time.Hour)); err == nil || !strings.Contains(err.Error(), "no progress") {
+		t.Errorf("Check of a stale heartbeat = %v", err)
+	}
+	m.Stop()
+	if s, _ = Load(path); Check(s, time.Minute, time.Now()) == nil {
+		t.Error("Check should fail for a stopped daemon")
+	}
+
+	var nilMonitor *Monitor
+	nilMonitor.Beat(Fetching, "") // Must not panic
+}
+
+func TestNotify(t *testing.T) {
+	dir, err := os.MkdirTemp("", "notify") // Short, for the socket path limit
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "s")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("no unixgram sockets: %v", err)
+	}
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", socket)
+
+	if err := Notify("READY=1"); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 64)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil || string(buf[:n]) != "READY=1" {
+		t.Errorf("received %q, %v", buf[:n], err)
+	}
+
+	t.Setenv("WATCHDOG_USEC", "20000000")
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	if d := WatchdogInterval(); d != 10*time.Second {
+		t.Errorf("WatchdogInterval = %v", d)
+	}
+	t.Setenv("WATCHDOG_PID", "1")
+	if d := WatchdogInterval(); d != 0 {
+		t.Errorf("WatchdogInterval for another process = %v", d)
+	}
+}
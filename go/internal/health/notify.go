@@ -0,0 +1,44 @@
+package health
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Notify sends a state such as "READY=1" or "WATCHDOG=1" to the service
+// manager, following the sd_notify protocol: a datagram to the Unix socket
+// named by NOTIFY_SOCKET ("@" for the abstract namespace). Without
+// NOTIFY_SOCKET, e.g. when not started by systemd, it does nothing.
+func Notify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// WatchdogInterval returns how often the service manager expects
+// "WATCHDOG=1", from WATCHDOG_USEC (WatchdogSec= in the unit), halved to
+// leave a margin. It is 0 if no watchdog is set or it is meant for another
+// process (WATCHDOG_PID).
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond / 2
+}
@@ -0,0 +1,210 @@
+// Package httplog records HTTP exchanges with their headers and timings,
+// as a plain text log or a HAR 1.2 file, so failed parses and rate-limit
+// incidents can be looked into after a run.
+package httplog
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Exchange is one request and what came of it
+type Exchange struct {
+	Started  time.Time
+	Request  *http.Request
+	Response *http.Response // Nil if no response arrived
+	Body     []byte         // The response body, if bodies are recorded
+	BodySize int64          // Bytes of response body read
+	Wait     time.Duration  // From sending the request to the response headers
+	Receive  time.Duration  // Reading the response body
+	Err      error          // Why the request or the body read failed
+}
+
+// Writer records exchanges. Text logs are written as exchanges come in; a
+// HAR file is written whole by Close. It is safe for concurrent use.
+type Writer struct {
+	Bodies  bool // Record response bodies
+	Records int  // Exchanges recorded so far
+
+	mu       sync.Mutex
+	w        io.Writer
+	har      bool
+	software string
+	entries  []harEntry
+}
+
+// NewWriter returns a writer of a text log, or with har of a HAR file
+// naming software as its creator
+func NewWriter(w io.Writer, har bool, software string) *Writer {
+	return &Writer{w: w, har: har, software: software}
+}
+
+// IsHAR reports whether a log path should be written as HAR
+func IsHAR(path string) bool {
+	return strings.HasSuffix(strings.ToLower(path), ".har")
+}
+
+// Record adds an exchange to the log
+func (lw *Writer) Record(x Exchange) error {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	lw.Records++
+	if lw.har {
+		lw.entries = append(lw.entries, newHAREntry(x, lw.Bodies))
+		return nil
+	}
+	_, err := io.WriteString(lw.w, text(x, lw.Bodies))
+	return err
+}
+
+// Close writes the HAR document; a text log needs nothing more
+func (lw *Writer) Close() error {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	if !lw.har {
+		return nil
+	}
+	name, version, _ := strings.Cut(lw.software, "/")
+	doc := map[string]interface{}{"log": map[string]interface{}{
+		"version": "1.2",
+		"creator": map[string]string{"name": name, "version": version},
+		"entries": lw.entries,
+	}}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = lw.w.Write(append(data, '\n'))
+	return err
+}
+
+// text renders an exchange for the plain log
+func text(x Exchange, bodies bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "=== %s %s %s\n", x.Started.UTC().Format("2006-01-02T15:04:05.000Z"), x.Request.Method, x.Request.URL)
+	writeHeaders(&b, "> ", x.Request.Header)
+	if x.Response != nil {
+		fmt.Fprintf(&b, "< %s %s\n", x.Response.Proto, x.Response.Status)
+		writeHeaders(&b, "< ", x.Response.Header)
+	}
+	fmt.Fprintf(&b, "wait %v, receive %v, %d bytes\n", x.Wait.Round(time.Millisecond), x.Receive.Round(time.Millisecond), x.BodySize)
+	if x.Err != nil {
+		fmt.Fprintf(&b, "error: %v\n", x.Err)
+	}
+	if bodies && len(x.Body) > 0 {
+		b.Write(x.Body)
+		if x.Body[len(x.Body)-1] != '\n' {
+			b.WriteByte('\n')
+		}
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
+
+func writeHeaders(b *strings.Builder, mark string, h http.Header) {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range h[name] {
+			fmt.Fprintf(b, "%s%s: %s\n", mark, name, v)
+		}
+	}
+}
+
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harEntry struct {
+	Started  string                 `json:"startedDateTime"`
+	Time     float64                `json:"time"`
+	Request  map[string]interface{} `json:"request"`
+	Response map[string]interface{} `json:"response"`
+	Cache    struct{}               `json:"cache"`
+	Timings  map[string]float64     `json:"timings"`
+	Error    string                 `json:"_error,omitempty"`
+}
+
+func newHAREntry(x Exchange, bodies bool) harEntry {
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	query := []harHeader{}
+	for name, values := range x.Request.URL.Query() {
+		for _, v := range values {
+			query = append(query, harHeader{name, v})
+		}
+	}
+	sort.SliceStable(query, func(i, j int) bool { return query[i].Name < query[j].Name })
+	e := harEntry{
+		Started: x.Started.UTC().Format("2006-01-02T15:04:05.000Z"),
+		Time:    ms(x.Wait + x.Receive),
+		Request: map[string]interface{}{
+			"method":      x.Request.Method,
+			"url":         x.Request.URL.String(),
+			"httpVersion": "HTTP/1.1",
+			"headers":     harHeaders(x.Request.Header),
+			"queryString": query,
+			"cookies":     []harHeader{},
+			"headersSize": -1,
+			"bodySize":    -1,
+		},
+		Timings: map[string]float64{"send": 0, "wait": ms(x.Wait), "receive": ms(x.Receive)},
+	}
+	// HAR has no place for a failed request, so it gets status 0 and _error
+	resp := map[string]interface{}{
+		"status":      0,
+		"statusText":  "",
+		"httpVersion": "",
+		"headers":     []harHeader{},
+		"cookies":     []harHeader{},
+		"redirectURL": "",
+		"headersSize": -1,
+		"bodySize":    x.BodySize,
+	}
+	content := map[string]interface{}{"size": x.BodySize, "mimeType": ""}
+	if r := x.Response; r != nil {
+		e.Request["httpVersion"] = r.Proto
+		resp["status"] = r.StatusCode
+		resp["statusText"] = strings.TrimSpace(strings.TrimPrefix(r.Status, fmt.Sprint(r.StatusCode)))
+		resp["httpVersion"] = r.Proto
+		resp["headers"] = harHeaders(r.Header)
+		resp["redirectURL"] = r.Header.Get("Location")
+		content["mimeType"] = r.Header.Get("Content-Type")
+	}
+	if bodies && len(x.Body) > 0 {
+		if utf8.Valid(x.Body) {
+			content["text"] = string(x.Body)
+		} else {
+			content["text"] = base64.StdEncoding.EncodeToString(x.Body)
+			content["encoding"] = "base64"
+		}
+	}
+	resp["content"] = content
+	e.Response = resp
+	if x.Err != nil {
+		e.Error = x.Err.Error()
+	}
+	return e
+}
+
+func harHeaders(h http.Header) []harHeader {
+	headers := []harHeader{}
+	for name, values := range h {
+		for _, v := range values {
+			headers = append(headers, harHeader{name, v})
+		}
+	}
+	sort.SliceStable(headers, func(i, j int) bool { return headers[i].Name < headers[j].Name })
+	return headers
+}
@@ -0,0 +1,135 @@
+package httplog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func exchange() Exchange {
+	req, _ := http.NewRequest("GET", "https://twit.tv/posts/transcripts?page=2", nil)
+	req.Header.Set("User-Agent", "twit-transcript-archiver/dev")
+	return Exchange{
+		Started: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Request: req,
+		Response: &http.Response{
+			Status: "429 Too Many Requests", StatusCode: 429, Proto: "HTTP/2.0",
+			Header: http.Header{"Retry-After": {"120"}, "Content-Type": {"text/html"}},
+		},
+		Body:     []byte("<html>slow down</html>"),
+		BodySize: 22,
+		Wait:     150 * time.Millisecond,
+		Receive:  5 * time.Millisecond,
+	}
+}
+
+func TestTextLog(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, false, "twit-transcript-archiver/dev")
+	if err := w.Record(exchange()); err != nil {
+		t.Fatal(err)
+	}
+	failed := exchange()
+	failed.Response, failed.Body, failed.BodySize = nil, nil, 0
+	failed.Err = errors.New("connection reset")
+	w.Record(failed)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"=== 2024-05-01T12:00:00.000Z GET https://twit.tv/posts/transcripts?page=2\n",
+		"> User-Agent: twit-transcript-archiver/dev\n",
+		"< HTTP/2.0 429 Too Many Requests\n< Content-Type: text/html\n< Retry-After: 120\n",
+		"wait 150ms, receive 5ms, 22 bytes\n",
+		"error: connection reset\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Log lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "slow down") {
+		t.Error("Bodies should only be logged when asked for")
+	}
+	if w.Records != 2 {
+		t.Errorf("Records = %d, want 2", w.Records)
+	}
+}
+
+func TestHAR(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, true, "twit-transcript-archiver/1.2.0")
+	w.Bodies = true
+	w.Record(exchange())
+	binary := exchange()
+	binary.Body = []byte{0xff, 0xfe}
+	w.Record(binary)
+	if buf.Len() != 0 {
+		t.Fatal("A HAR file should only be written on Close")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Log struct {
+			Version string            `json:"version"`
+			Creator map[string]string `json:"creator"`
+			Entries []struct {
+				Started string  `json:"startedDateTime"`
+				Time    float64 `json:"time"`
+				Request struct {
+					URL         string      `json:"url"`
+					QueryString []harHeader `json:"queryString"`
+				} `json:"request"`
+				Response struct {
+					Status     int         `json:"status"`
+					StatusText string      `json:"statusText"`
+					Headers    []harHeader `json:"headers"`
+					Content    struct {
+						Size     int64  `json:"size"`
+						MimeType string `json:"mimeType"`
+						Text     string `json:"text"`
+						Encoding string `json:"encoding"`
+					} `json:"content"`
+				} `json:"response"`
+				Timings map[string]float64 `json:"timings"`
+			} `json:"entries"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Log.Version != "1.2" || doc.Log.Creator["name"] != "twit-transcript-archiver" || doc.Log.Creator["version"] != "1.2.0" {
+		t.Errorf("Unexpected log header: %+v", doc.Log)
+	}
+	if len(doc.Log.Entries) != 2 {
+		t.Fatalf("Got %d entries, want 2", len(doc.Log.Entries))
+	}
+	e := doc.Log.Entries[0]
+	if e.Started != "2024-05-01T12:00:00.000Z" || e.Time != 155 || e.Timings["wait"] != 150 {
+		t.Errorf("Unexpected timing: %+v", e)
+	}
+	if e.Response.Status != 429 || e.Response.StatusText != "Too Many Requests" || e.Response.Content.MimeType != "text/html" {
+		t.Errorf("Unexpected response: %+v", e.Response)
+	}
+	if len(e.Request.QueryString) != 1 || e.Request.QueryString[0] != (harHeader{"page", "2"}) {
+		t.Errorf("Unexpected query: %+v", e.Request.QueryString)
+	}
+	if e.Response.Content.Text != "<html>slow down</html>" {
+		t.Errorf("Body = %q", e.Response.Content.Text)
+	}
+	if c := doc.Log.Entries[1].Response.Content; c.Encoding != "base64" || c.Text != "//4=" {
+		t.Errorf("Binary body = %+v", c)
+	}
+}
+
+func TestIsHAR(t *testing.T) {
+	if !IsHAR("run.HAR") || IsHAR("run.log") {
+		t.Error("IsHAR should go by the .har extension")
+	}
+}
@@ -0,0 +1,259 @@
+package index
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// FileName is the index file kept at the root of the data directory
+const FileName = "index.json"
+
+// Entry describes one archived episode
+type Entry struct {
+	File   string `json:"file"` // Path relative to the data directory
+	Prefix string `json:"prefix"`
+	Number int    `json:"episode"`
+	Title  string `json:"title"`
+	Date   string `json:"date,omitempty"` // YYYY-MM-DD
+	Words  int    `json:"words"`
+	// Language is the detected language code, empty if undetermined
+	Language string `json:"language,omitempty"`
+	// Extraction is how the transcript was found in its page: "current"
+	// for the site's current markup, else the fallback that was needed
+	Extraction string `json:"extraction,omitempty"`
+	// Origin is "feed", "captions" or "asr" for transcripts ingested from
+	// a podcast feed, video captions or speech recognition rather than a
+	// transcript page
+	Origin string `json:"origin,omitempty"`
+	// Pending marks a placeholder page whose transcript was not published
+	// yet; fetch downloads it again until it is
+	Pending bool `json:"pending,omitempty"`
+	// URL is the canonical address of the transcript page (see
+	// converter.Episode.Canonical), and Aliases other addresses that led to
+	// the same page: links that redirected to it, or earlier slugs of a
+	// republished transcript. Fetch skips listed transcripts at any of them.
+	URL     string   `json:"url,omitempty"`
+	Aliases []string `json:"aliases,omitempty"`
+	// Media lists the episode's audio/video URLs
+	Media []string `json:"media,omitempty"`
+	// Hosts and Guests are the people credited on the episode page
+	Hosts  []string `json:"hosts,omitempty"`
+	Guests []string `json:"guests,omitempty"`
+	// EpisodeURL and Links come from the page's show notes
+	EpisodeURL string           `json:"episode_url,omitempty"`
+	Links      []converter.Link `json:"links,omitempty"`
+	// Entities maps a category (company, product, person, cve) to names
+	Entities map[string][]string `json:"entities,omitempty"`
+	Topics   []string            `json:"topics,omitempty"`
+	// Tags is the flattened, de-duplicated set of entity names and topics
+	Tags []string `json:"tags,omitempty"`
+	// Summary is the abstract written by 'twit-archiver summarize'
+	Summary *converter.Summary `json:"summary,omitempty"`
+}
+
+// Index is the archive's episode catalog, stored as JSON
+type Index struct {
+	Updated time.Time         `json:"updated"`
+	Entries map[string]*Entry `json:"entries"`
+
+	root string // Data directory the index was loaded from
+}
+
+// Key returns the index key for a transcript file ("SN_1000.html" -> "SN_1000")
+func Key(file string) string {
+	return strings.TrimSuffix(storage.Base(file), ".html")
+}
+
+// New returns an empty index
+func New() *Index {
+	return &Index{Entries: make(map[string]*Entry)}
+}
+
+// Load reads the index from the data directory. A missing index is not an
+// error; an empty one is returned.
+func Load(dataDir string) (*Index, error) {
+	data, err := storage.ReadFile(storage.Join(dataDir, FileName))
+	if errors.Is(err, storage.ErrNotExist) {
+		ix := New()
+		ix.root = dataDir
+		return ix, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ix := New()
+	ix.root = dataDir
+	if err := json.Unmarshal(data, ix); err != nil {
+		return nil, err
+	}
+	if ix.Entries == nil {
+		ix.Entries = make(map[string]*Entry)
+	}
+	return ix, nil
+}
+
+// Save writes the index to the data directory
+func (ix *Index) Save(dataDir string) error {
+	ix.Updated = time.Now().UTC()
+	data, err := json.MarshalIndent(ix, "", "  ")
+	if err != nil {
+		return err
+	}
+	return storage.WriteFile(storage.Join(dataDir, FileName), data)
+}
+
+// Upsert records an episode's metadata, keeping fields set by other passes
+func (ix *Index) Upsert(ep converter.Episode) *Entry {
+	key := Key(ep.Path)
+	e, ok := ix.Entries[key]
+	if !ok {
+		e = &Entry{}
+		ix.Entries[key] = e
+	}
+	e.File = storage.Base(ep.Path)
+	if ix.root != "" {
+		e.File = storage.Rel(ix.root, ep.Path)
+	}
+	e.Prefix = ep.Prefix
+	e.Number = ep.Number
+	e.Title = ep.Title
+	e.Date = ""
+	if !ep.Date.IsZero() {
+		e.Date = ep.Date.Format("2006-01-02")
+	}
+	e.Words = len(strings.Fields(ep.Content))
+	e.Language = ep.Language
+	e.Extraction = ep.Extraction
+	e.Origin = ep.Origin
+	e.Pending = ep.Pending
+	e.URL = ep.Canonical
+	e.Media = ep.Media
+	e.Hosts = ep.Roster.Hosts
+	e.Guests = ep.Roster.Guests
+	e.EpisodeURL = ep.Notes.EpisodeURL
+	e.Links = ep.Notes.Links
+	return e
+}
+
+// AddFile parses a transcript and records it in the data directory's index
+func AddFile(dataDir, path string) (*Entry, error) {
+	ep, err := converter.LoadEpisode(path)
+	if err != nil {
+		return nil, err
+	}
+	ix, err := Load(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	e := ix.Upsert(ep)
+	return e, ix.Save(dataDir)
+}
+
+// SetTags stores entities and topics on an entry and rebuilds its tag list
+func (e *Entry) SetTags(entities map[string][]string, topics []string) {
+	e.Entities = entities
+	e.Topics = topics
+	seen := make(map[string]bool)
+	e.Tags = nil
+	for _, names := range entities {
+		for _, n := range names {
+			if !seen[n] {
+				seen[n] = true
+				e.Tags = append(e.Tags, n)
+			}
+		}
+	}
+	for _, t := range topics {
+		if !seen[t] {
+			seen[t] = true
+			e.Tags = append(e.Tags, t)
+		}
+	}
+	sort.Strings(e.Tags)
+}
+
+// HasTag reports whether the entry carries a tag (case-insensitive)
+func (e *Entry) HasTag(tag string) bool {
+	return containsFold(e.Tags, tag)
+}
+
+// HasHost reports whether a person hosted the episode (case-insensitive)
+func (e *Entry) HasHost(name string) bool {
+	return containsFold(e.Hosts, name)
+}
+
+// HasGuest reports whether a person was a guest on the episode (case-insensitive)
+func (e *Entry) HasGuest(name string) bool {
+	return containsFold(e.Guests, name)
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// URLKey reduces a page URL to what identifies the page, so the same page
+// matches whatever the scheme, host case, query, fragment or trailing slash
+// ("https://TWiT.tv/posts/x/?a=1" and "http://twit.tv/posts/x" agree)
+func URLKey(u string) string {
+	parsed, err := url.Parse(strings.TrimSpace(u))
+	if err != nil || parsed.Host == "" {
+		return strings.TrimSuffix(strings.TrimSpace(u), "/")
+	}
+	return strings.ToLower(parsed.Host) + strings.TrimSuffix(parsed.EscapedPath(), "/")
+}
+
+// AddAlias records another URL of the entry's page, unless it is the
+// canonical one or already known. It reports whether the entry changed.
+func (e *Entry) AddAlias(u string) bool {
+	key := URLKey(u)
+	if key == "" || key == URLKey(e.URL) {
+		return false
+	}
+	for _, a := range e.Aliases {
+		if URLKey(a) == key {
+			return false
+		}
+	}
+	e.Aliases = append(e.Aliases, u)
+	return true
+}
+
+// ByURL maps the URLKey of every entry's URL and aliases to the entry's key
+func (ix *Index) ByURL() map[string]string {
+	byURL := make(map[string]string)
+	for key, e := range ix.Entries {
+		for _, u := range append([]string{e.URL}, e.Aliases...) {
+			if u != "" {
+				byURL[URLKey(u)] = key
+			}
+		}
+	}
+	return byURL
+}
+
+// Sorted returns the entries ordered by prefix and episode number
+func (ix *Index) Sorted() []*Entry {
+	entries := make([]*Entry, 0, len(ix.Entries))
+	for _, e := range ix.Entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Prefix != entries[j].Prefix {
+			return entries[i].Prefix < entries[j].Prefix
+		}
+		return entries[i].Number < entries[j].Number
+	})
+	return entries
+}
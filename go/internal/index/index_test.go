@@ -0,0 +1,102 @@
+package index
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+func TestIndexRoundTrip(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "indextest")
+	defer os.RemoveAll(tmpDir)
+
+	ix, err := Load(tmpDir)
+	if err != nil || len(ix.Entries) != 0 {
+		t.Fatalf("Loading a missing index should return an empty one: %v", err)
+	}
+
+	e := ix.Upsert(converter.Episode{
+		Prefix: "SN", Number: 1000, Title: "Security Now 1000",
+		Date: time.Date(2024, 11, 19, 0, 0, 0, 0, time.UTC), Content: "one two three",
+		Path: tmpDir + "/SN_1000.html",
+	})
+	e.SetTags(map[string][]string{"product": {"OpenSSL"}, "company": {"Microsoft"}}, []string{"security", "OpenSSL"})
+
+	if err := ix.Save(tmpDir); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := Load(tmpDir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	got := loaded.Entries["SN_1000"]
+	if got == nil || got.Date != "2024-11-19" || got.Words != 3 || got.File != "SN_1000.html" {
+		t.Fatalf("Unexpected entry: %+v", got)
+	}
+	if len(got.Tags) != 3 || !got.HasTag("openssl") {
+		t.Errorf("Tags not flattened and de-duplicated: %v", got.Tags)
+	}
+}
+
+func TestAddFile(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "indextest")
+	defer os.RemoveAll(tmpDir)
+
+	path := tmpDir + "/WW_900.html"
+	os.WriteFile(path, []byte(`<h1 class="post-title">Windows Weekly 900</h1><p class="byline">Oct 11th 2024</p><div class="body textual">Hello</div>`), 0644)
+	if _, err := AddFile(tmpDir, path); err != nil {
+		t.Fatalf("AddFile failed: %v", err)
+	}
+	ix, _ := Load(tmpDir)
+	if e := ix.Entries["WW_900"]; e == nil || e.Prefix != "WW" || e.Date != "2024-10-11" || e.Origin != "" {
+		t.Errorf("Unexpected entry: %+v", e)
+	}
+
+	path = tmpDir + "/WW_901.html"
+	cues := []converter.Cue{{Start: time.Second, Timed: true, Speaker: "Paul Thurrott", Text: "Hello"}}
+	os.WriteFile(path, []byte(converter.CaptionsPage("Windows Weekly 901", time.Time{}, converter.OriginCaptions, cues)), 0644)
+	if e, err := AddFile(tmpDir, path); err != nil || e.Origin != converter.OriginCaptions {
+		t.Errorf("AddFile for captions = %+v, %v", e, err)
+	}
+}
+
+func TestRosterQueries(t *testing.T) {
+	ix := New()
+	var roster converter.Roster
+	roster.Hosts = []string{"Leo Laporte"}
+	roster.Guests = []string{"Steve Gibson"}
+	e := ix.Upsert(converter.Episode{Prefix: "TWIT", Number: 1, Path: "TWIT_1.html", Roster: roster})
+	if !e.HasGuest("steve gibson") || e.HasGuest("Leo Laporte") || !e.HasHost("Leo Laporte") {
+		t.Errorf("Unexpected roster matching: %+v", e)
+	}
+}
+
+func TestURLKeyAndAliases(t *testing.T) {
+	if a, b := URLKey("https://TWiT.tv/posts/transcripts/sn-1000/?utm=x#top"), URLKey("http://twit.tv/posts/transcripts/sn-1000"); a != b {
+		t.Errorf("URLKey: %q != %q", a, b)
+	}
+	if URLKey("https://twit.tv/posts/a") == URLKey("https://twit.tv/posts/b") {
+		t.Error("Different pages should have different keys")
+	}
+
+	ix := New()
+	e := ix.Upsert(converter.Episode{Prefix: "SN", Number: 1000, Path: "SN_1000.html", Canonical: "https://twit.tv/posts/sn-1000"})
+	if e.AddAlias("https://twit.tv/posts/sn-1000/") {
+		t.Error("The canonical URL is not an alias")
+	}
+	if !e.AddAlias("https://twit.tv/posts/old-slug") || e.AddAlias("http://twit.tv/posts/old-slug") {
+		t.Errorf("Aliases = %v, want the old slug once", e.Aliases)
+	}
+	byURL := ix.ByURL()
+	if byURL[URLKey("https://twit.tv/posts/sn-1000")] != "SN_1000" || byURL[URLKey("https://twit.tv/posts/old-slug")] != "SN_1000" || len(byURL) != 2 {
+		t.Errorf("ByURL = %v", byURL)
+	}
+
+	// Aliases survive an upsert from the page
+	ix.Upsert(converter.Episode{Prefix: "SN", Number: 1000, Path: "SN_1000.html", Canonical: "https://twit.tv/posts/sn-1000"})
+	if len(ix.Entries["SN_1000"].Aliases) != 1 {
+		t.Errorf("Aliases after Upsert = %v", ix.Entries["SN_1000"].Aliases)
+	}
+}
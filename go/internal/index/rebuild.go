@@ -0,0 +1,159 @@
+package index
+
+import (
+	"encoding/json"
+	"reflect"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// RebuildOptions controls Rebuild
+type RebuildOptions struct {
+	// Workers is how many transcripts are parsed at once; zero uses one
+	// per CPU
+	Workers int
+	// Progress, if set, is called after each transcript with the number
+	// parsed so far and the total
+	Progress func(done, total int)
+}
+
+// RebuildResult is what Rebuild produced
+type RebuildResult struct {
+	Index *Index
+	// Failed maps the transcripts that could not be parsed to the error
+	Failed map[string]error
+}
+
+// Rebuild derives a new index from every transcript page in the data
+// directory, parsing them in parallel. Tags, entities, topics, summaries
+// and URL aliases, which 'tag', 'summarize' and fetch add and the pages do
+// not hold, are carried over from old (which may be nil) for the episodes in both.
+// The new index is not saved.
+func Rebuild(dataDir string, old *Index, opts RebuildOptions) (*RebuildResult, error) {
+	files, err := storage.Glob(config.ActiveLayout.RawGlob(dataDir, "*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	workers := opts.Workers
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	type parsed struct {
+		path string
+		ep   converter.Episode
+		err  error
+	}
+	paths := make(chan string)
+	results := make(chan parsed)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(files); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				ep, err := converter.LoadEpisode(path)
+				results <- parsed{path, ep, err}
+			}
+		}()
+	}
+	go func() {
+		for _, f := range files {
+			paths <- f
+		}
+		close(paths)
+		wg.Wait()
+		close(results)
+	}()
+
+	ix := New()
+	ix.root = dataDir
+	res := &RebuildResult{Index: ix, Failed: make(map[string]error)}
+	done := 0
+	for r := range results {
+		done++
+		if r.err != nil {
+			res.Failed[r.path] = r.err
+		} else {
+			e := ix.Upsert(r.ep)
+			if old != nil {
+				if prev, ok := old.Entries[Key(r.path)]; ok {
+					e.Entities, e.Topics, e.Tags = prev.Entities, prev.Topics, prev.Tags
+					e.Summary = prev.Summary
+					e.Aliases = prev.Aliases
+				}
+			}
+		}
+		if opts.Progress != nil {
+			opts.Progress(done, len(files))
+		}
+	}
+	return res, nil
+}
+
+// Change kinds, as reported in Change.Kind
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+)
+
+// Change is an episode whose entry differs between two indexes
+type Change struct {
+	Key  string
+	Kind string
+	// Fields lists the JSON names of the fields that differ, for Changed
+	Fields []string
+}
+
+// Diff lists the entries added, removed or changed from old to cur,
+// ordered by key
+func Diff(old, cur *Index) []Change {
+	var changes []Change
+	for key, e := range cur.Entries {
+		prev, ok := old.Entries[key]
+		if !ok {
+			changes = append(changes, Change{Key: key, Kind: Added})
+		} else if fields := diffFields(prev, e); len(fields) > 0 {
+			changes = append(changes, Change{Key: key, Kind: Changed, Fields: fields})
+		}
+	}
+	for key := range old.Entries {
+		if _, ok := cur.Entries[key]; !ok {
+			changes = append(changes, Change{Key: key, Kind: Removed})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// diffFields compares two entries as they are stored, field by field
+func diffFields(a, b *Entry) []string {
+	fa, fb := entryFields(a), entryFields(b)
+	var fields []string
+	for name, va := range fa {
+		if !reflect.DeepEqual(va, fb[name]) {
+			fields = append(fields, name)
+		}
+	}
+	for name := range fb {
+		if _, ok := fa[name]; !ok {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func entryFields(e *Entry) map[string]interface{} {
+	var fields map[string]interface{}
+	data, _ := json.Marshal(e)
+	json.Unmarshal(data, &fields)
+	return fields
+}
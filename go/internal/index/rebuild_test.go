@@ -0,0 +1,78 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+func writePage(t *testing.T, dir, name, title string) {
+	t.Helper()
+	html := `<h1 class="post-title">` + title + `</h1><p class="byline">Feb 6th 2024</p><div class="body textual"><p>00:00:01 - Leo Laporte Hello and welcome to the show.</p></div>`
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(html), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	dir := t.TempDir()
+	writePage(t, dir, "SN_1.html", "Security Now 1 Transcript")
+	writePage(t, dir, "SN_2.html", "Security Now 2 Transcript")
+	writePage(t, dir, "SN_3.html", "Security Now 3 Transcript")
+
+	old := New()
+	old.root = dir
+	ep, err := converter.LoadEpisode(filepath.Join(dir, "SN_1.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	old.Upsert(ep)
+	old.Entries["SN_1"].SetTags(nil, []string{"security"})
+	old.Entries["SN_1"].Summary = &converter.Summary{Abstract: "First"}
+	old.Entries["SN_2"] = &Entry{File: "SN_2.html", Prefix: "SN", Number: 2, Title: "Old title"}
+	old.Entries["SN_9"] = &Entry{File: "SN_9.html", Prefix: "SN", Number: 9}
+
+	var calls int32
+	res, err := Rebuild(dir, old, RebuildOptions{Workers: 2, Progress: func(done, total int) {
+		atomic.AddInt32(&calls, 1)
+		if total != 3 {
+			t.Errorf("Progress total = %d, want 3", total)
+		}
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 || len(res.Failed) != 0 || len(res.Index.Entries) != 3 {
+		t.Fatalf("Rebuild: %d progress calls, %d failed, %d entries", calls, len(res.Failed), len(res.Index.Entries))
+	}
+	e := res.Index.Entries["SN_1"]
+	if !e.HasTag("security") || e.Summary == nil || e.Date != "2024-02-06" {
+		t.Errorf("Tags and summary not carried over: %+v", e)
+	}
+
+	changes := Diff(old, res.Index)
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.Kind+" "+c.Key)
+	}
+	want := []string{"changed SN_2", "added SN_3", "removed SN_9"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Diff = %v, want %v", got, want)
+	}
+	if fields := changes[0].Fields; len(fields) == 0 || !contains(fields, "title") || contains(fields, "prefix") {
+		t.Errorf("Changed fields of SN_2 = %v", fields)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,211 @@
+// Package lock keeps two runs from working on the same data directory at
+// once, e.g. when cron starts a fetch before the previous one has finished.
+// The lock is a file in the data directory recording who holds it, so a run
+// that died without releasing it can be detected and its lock taken over.
+package lock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// FileName is the lock file kept at the root of the data directory
+const FileName = ".twit-archiver.lock"
+
+// ErrLocked is returned when another run holds the lock
+var ErrLocked = errors.New("data directory is in use by another run")
+
+// StaleAfter is the age after which a lock is taken over even if its
+// holder cannot be shown to have exited (e.g. it ran on another host)
+var StaleAfter = 24 * time.Hour
+
+// PollInterval is how often a waiting run checks the lock again
+var PollInterval = time.Second
+
+// Info describes the run holding a lock
+type Info struct {
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Command string    `json:"command"`
+	Started time.Time `json:"started"`
+}
+
+func (i Info) String() string {
+	return fmt.Sprintf("%s (pid %d on %s, since %s)", i.Command, i.PID, i.Host, i.Started.Local().Format("2006-01-02 15:04:05"))
+}
+
+// stale reports whether the holder has exited or the lock is too old
+func (i Info) stale() bool {
+	if time.Since(i.Started) > StaleAfter {
+		return true
+	}
+	host, _ := os.Hostname()
+	return i.Host == host && !processAlive(i.PID)
+}
+
+// Lock is a held data directory lock
+type Lock struct {
+	path string
+	info Info
+}
+
+// Acquire takes the data directory's lock for command. If another run holds
+// it, Acquire waits up to wait for it to be released, then fails with an
+// error wrapping ErrLocked that names the holder. Stale locks, left by runs
+// that exited without releasing them, are taken over with a warning.
+func Acquire(dataDir, command string, wait time.Duration) (*Lock, error) {
+	host, _ := os.Hostname()
+	l := &Lock{
+		path: storage.Join(dataDir, FileName),
+		info: Info{PID: os.Getpid(), Host: host, Command: command, Started: time.Now().UTC()},
+	}
+	deadline := time.Now().Add(wait)
+	announced := false
+	for {
+		holder, err := l.tryCreate()
+		if err == nil {
+			return l, nil
+		}
+		if !errors.Is(err, ErrLocked) {
+			return nil, err
+		}
+		if holder.stale() {
+			took, err := l.takeOver()
+			if err != nil {
+				return nil, err
+			}
+			if took {
+				fmt.Printf("Warning: taking over the stale lock of %s\n", holder)
+			}
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w: %s. Wait for it with --wait, or remove %s if that run is gone", ErrLocked, holder, l.path)
+		}
+		if !announced {
+			fmt.Printf("Waiting for %s to finish...\n", holder)
+			announced = true
+		}
+		time.Sleep(PollInterval)
+	}
+}
+
+// tryCreate writes the lock file unless it exists, in which case the
+// current holder is returned with ErrLocked. Local files are created
+// atomically; object storage has no exclusive create, so there the check
+// is best effort.
+func (l *Lock) tryCreate() (Info, error) {
+	data, err := json.MarshalIndent(l.info, "", "  ")
+	if err != nil {
+		return Info{}, err
+	}
+	if err := storage.CheckWritable(l.path); err != nil {
+		return Info{}, err
+	}
+	if !storage.IsRemote(l.path) {
+		if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+			return Info{}, err
+		}
+		f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = f.Write(data)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			return Info{}, err
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return Info{}, err
+		}
+	} else if !storage.Exists(l.path) {
+		return Info{}, storage.WriteFile(l.path, data)
+	}
+
+	holder, err := holderOf(l.path)
+	if errors.Is(err, storage.ErrNotExist) {
+		// Released in the meantime; try again
+		return l.tryCreate()
+	}
+	return holder, ErrLocked
+}
+
+// holderOf returns the holder recorded in a lock file. A file that cannot
+// be parsed is being written by another run, or was left by one that died
+// mid-write; it is dated by its modification time, so it goes stale by age.
+func holderOf(path string) (Info, error) {
+	holder, err := Read(path)
+	if errors.Is(err, storage.ErrNotExist) {
+		return holder, err
+	}
+	if err != nil {
+		holder = Info{Command: "an unknown run", Started: time.Now()}
+		if info, serr := os.Stat(path); serr == nil {
+			holder.Started = info.ModTime()
+		}
+	}
+	return holder, nil
+}
+
+// takeOver removes a lock file judged stale, reporting whether it did.
+// Waiters may judge the same lock stale at once, and by the time one acts
+// another may have replaced it with its own. Local lock files are
+// therefore renamed aside first, which only one waiter can do to a given
+// file, and checked again: a lock that is not stale after all is put back.
+// Object storage has no atomic rename, so there the file is just removed.
+func (l *Lock) takeOver() (bool, error) {
+	if storage.IsRemote(l.path) {
+		err := storage.Remove(l.path)
+		if errors.Is(err, storage.ErrNotExist) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	if err := storage.CheckWritable(l.path); err != nil {
+		return false, err
+	}
+	aside := fmt.Sprintf("%s.stale-%d-%d", l.path, os.Getpid(), time.Now().UnixNano())
+	if err := os.Rename(l.path, aside); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil // Taken over or released by another run
+		}
+		return false, err
+	}
+	defer os.Remove(aside)
+	if moved, err := holderOf(aside); err == nil && !moved.stale() {
+		// A live lock: put it back unless yet another run holds the lock
+		if err := os.Link(aside, l.path); err != nil && !errors.Is(err, os.ErrExist) {
+			return false, err
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+// Read returns the holder recorded in a lock file
+func Read(path string) (Info, error) {
+	data, err := storage.ReadFile(path)
+	if err != nil {
+		return Info{}, err
+	}
+	var info Info
+	err = json.Unmarshal(data, &info)
+	return info, err
+}
+
+// Release removes the lock file, if it is still ours
+func (l *Lock) Release() error {
+	if l == nil {
+		return nil
+	}
+	holder, err := Read(l.path)
+	if err != nil || holder.PID != l.info.PID || holder.Host != l.info.Host || !holder.Started.Equal(l.info.Started) {
+		return nil
+	}
+	return storage.Remove(l.path)
+}
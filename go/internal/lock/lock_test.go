@@ -0,0 +1,132 @@
+package lock
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAcquireRelease(t *testing.T) {
+	dir := t.TempDir()
+	l, err := Acquire(dir, "fetch-transcripts", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := Read(filepath.Join(dir, FileName))
+	if err != nil || info.PID != os.Getpid() || info.Command != "fetch-transcripts" {
+		t.Errorf("Unexpected lock file: %+v, %v", info, err)
+	}
+
+	// A second run is refused, naming the holder
+	if _, err := Acquire(dir, "process-transcripts", 0); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked, got %v", err)
+	}
+
+	// ...or waits until the first releases the lock
+	PollInterval = 10 * time.Millisecond
+	defer func() { PollInterval = time.Second }()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		l.Release()
+	}()
+	l2, err := Acquire(dir, "process-transcripts", 5*time.Second)
+	if err != nil {
+		t.Fatalf("Waiting for the lock failed: %v", err)
+	}
+	if err := l2.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, FileName)); !os.IsNotExist(err) {
+		t.Error("Release should remove the lock file")
+	}
+}
+
+func TestAcquireStale(t *testing.T) {
+	dir := t.TempDir()
+	host, _ := os.Hostname()
+	write := func(info Info) {
+		data, _ := json.Marshal(info)
+		os.WriteFile(filepath.Join(dir, FileName), data, 0644)
+	}
+
+	// A run on this host that no longer exists
+	write(Info{PID: 999999999, Host: host, Command: "fetch-transcripts", Started: time.Now()})
+	l, err := Acquire(dir, "run", 0)
+	if err != nil {
+		t.Fatalf("A dead holder's lock should be taken over: %v", err)
+	}
+	l.Release()
+
+	// A run on another host is only stale once old
+	write(Info{PID: 1, Host: "elsewhere", Command: "fetch-transcripts", Started: time.Now()})
+	if _, err := Acquire(dir, "run", 0); !errors.Is(err, ErrLocked) {
+		t.Errorf("A live lock from another host should hold: %v", err)
+	}
+	write(Info{PID: 1, Host: "elsewhere", Command: "fetch-transcripts", Started: time.Now().Add(-2 * StaleAfter)})
+	if l, err = Acquire(dir, "run", 0); err != nil {
+		t.Errorf("An old lock should be taken over: %v", err)
+	}
+
+	// Release leaves a lock that was taken over by someone else alone
+	write(Info{PID: 1, Host: "elsewhere", Command: "other", Started: time.Now()})
+	l.Release()
+	if info, _ := Read(filepath.Join(dir, FileName)); info.Command != "other" {
+		t.Error("Release removed another run's lock")
+	}
+}
+
+func TestTakeOverRace(t *testing.T) {
+	host, _ := os.Hostname()
+	for round := 0; round < 20; round++ {
+		dir := t.TempDir()
+		data, _ := json.Marshal(Info{PID: 999999999, Host: host, Command: "fetch-transcripts", Started: time.Now()})
+		os.WriteFile(filepath.Join(dir, FileName), data, 0644)
+
+		// Every waiter finds the same stale lock; only one may end up with it
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		held := 0
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := Acquire(dir, "run", 0); err == nil {
+					mu.Lock()
+					held++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if held != 1 {
+			t.Fatalf("round %d: %d runs hold the lock, want 1", round, held)
+		}
+		if leftovers, _ := filepath.Glob(filepath.Join(dir, FileName+".stale-*")); len(leftovers) != 0 {
+			t.Errorf("Stale lock files left behind: %v", leftovers)
+		}
+	}
+}
+
+func TestTakeOverLiveLock(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+	live, err := Acquire(dir, "fetch-transcripts", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, _ := os.ReadFile(path)
+
+	// A waiter that judged an earlier holder stale acts too late
+	l := &Lock{path: path}
+	if took, err := l.takeOver(); took || err != nil {
+		t.Errorf("takeOver = %v, %v; want a live lock left alone", took, err)
+	}
+	if after, err := os.ReadFile(path); err != nil || string(after) != string(before) {
+		t.Errorf("The live lock changed: %q, %v", after, err)
+	}
+	live.Release()
+}
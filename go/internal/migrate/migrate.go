@@ -0,0 +1,167 @@
+package migrate
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// Move relocates one file
+type Move struct {
+	From, To string
+}
+
+var (
+	// Chunks by the original names or by episode range ("SN_0001-0099_2005.md")
+	chunkFileRegex   = regexp.MustCompile(`^([A-Z0-9]+)_(?:Transcripts_.*|\d+-\d+(?:_.*)?)\.md$`)
+	episodeMDRegex   = regexp.MustCompile(`^([A-Z0-9]+)_\d+\.md$`)
+	listPageRegex    = regexp.MustCompile(`^transcripts_page_\d+\.html$`)
+	rawTranscriptRgx = regexp.MustCompile(`^([A-Z0-9]+)_\d+\.html$`)
+	mediaFileRegex   = regexp.MustCompile(`^([A-Z0-9]+)_\d+\.(?:mp3|m4a|ogg|opus)$`)
+)
+
+// Plan lists the moves needed to convert a data directory from one layout
+// to another. Files already in place are not included.
+func Plan(dataDir string, from, to config.Layout) ([]Move, error) {
+	var moves []Move
+	add := func(pattern string, re *regexp.Regexp, dest func(prefix string) string) error {
+		files, err := storage.Glob(pattern)
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			m := re.FindStringSubmatch(storage.Base(f))
+			if m == nil {
+				continue
+			}
+			prefix := ""
+			if len(m) > 1 {
+				prefix = m[1]
+			}
+			target := storage.Join(dest(prefix), storage.Base(f))
+			if target != f {
+				moves = append(moves, Move{From: f, To: target})
+			}
+		}
+		return nil
+	}
+
+	steps := []struct {
+		pattern string
+		re      *regexp.Regexp
+		dest    func(string) string
+	}{
+		{from.RawGlob(dataDir, "*"), rawTranscriptRgx, func(p string) string { return to.RawDir(dataDir, p) }},
+		{storage.Join(from.ChunkDir(dataDir, "*"), "*_*.md"), chunkFileRegex, func(p string) string { return to.ChunkDir(dataDir, p) }},
+		{storage.Join(from.MarkdownDir(dataDir, "*"), "*_*.md"), episodeMDRegex, func(p string) string { return to.MarkdownDir(dataDir, p) }},
+		{storage.Join(from.ListPageDir(dataDir), "transcripts_page_*.html"), listPageRegex, func(string) string { return to.ListPageDir(dataDir) }},
+		{storage.Join(from.MediaDir(dataDir, "*"), "*_*.*"), mediaFileRegex, func(p string) string { return to.MediaDir(dataDir, p) }},
+	}
+	for _, step := range steps {
+		if err := add(step.pattern, step.re, step.dest); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(moves, func(i, j int) bool { return moves[i].From < moves[j].From })
+	return moves, nil
+}
+
+// Apply performs the moves, refusing to overwrite existing files
+func Apply(moves []Move) error {
+	for _, m := range moves {
+		if storage.Exists(m.To) {
+			return fmt.Errorf("refusing to overwrite %s", m.To)
+		}
+		if err := storage.Rename(m.From, m.To); err != nil {
+			return fmt.Errorf("moving %s: %v", m.From, err)
+		}
+	}
+	return nil
+}
+
+// UpdateIndex rewrites the file paths of moved episodes in the index
+func UpdateIndex(ix *index.Index, dataDir string, moves []Move) int {
+	byFrom := make(map[string]string, len(moves))
+	for _, m := range moves {
+		byFrom[storage.Rel(dataDir, m.From)] = storage.Rel(dataDir, m.To)
+	}
+	updated := 0
+	for _, e := range ix.Entries {
+		if to, ok := byFrom[e.File]; ok {
+			e.File = to
+			updated++
+		}
+	}
+	return updated
+}
+
+// Counts tallies the archive's files by kind
+type Counts struct {
+	Raw, Chunks, Markdown, ListPages, Media int
+}
+
+// Count tallies the files in a data directory laid out as l
+func Count(dataDir string, l config.Layout) (Counts, error) {
+	var c Counts
+	for _, step := range []struct {
+		pattern string
+		re      *regexp.Regexp
+		n       *int
+	}{
+		{l.RawGlob(dataDir, "*"), rawTranscriptRgx, &c.Raw},
+		{storage.Join(l.ChunkDir(dataDir, "*"), "*_*.md"), chunkFileRegex, &c.Chunks},
+		{storage.Join(l.MarkdownDir(dataDir, "*"), "*_*.md"), episodeMDRegex, &c.Markdown},
+		{storage.Join(l.ListPageDir(dataDir), "transcripts_page_*.html"), listPageRegex, &c.ListPages},
+		{storage.Join(l.MediaDir(dataDir, "*"), "*_*.*"), mediaFileRegex, &c.Media},
+	} {
+		files, err := storage.Glob(step.pattern)
+		if err != nil {
+			return c, err
+		}
+		for _, f := range files {
+			if step.re.MatchString(storage.Base(f)) {
+				*step.n++
+			}
+		}
+	}
+	return c, nil
+}
+
+// IsLegacyFlat reports whether the data directory still has transcripts in
+// the old flat naming scheme (PREFIX_N.html directly in the data directory)
+func IsLegacyFlat(dataDir string) bool {
+	files, _ := storage.Glob(config.FlatLayout.RawGlob(dataDir, "*"))
+	for _, f := range files {
+		if rawTranscriptRgx.MatchString(storage.Base(f)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Backfill parses every transcript found through config.ActiveLayout and
+// records its metadata in the index. Tags set by other passes are preserved.
+func Backfill(ix *index.Index, dataDir string) (int, error) {
+	prefixes, err := converter.ListPrefixes(dataDir)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, prefix := range prefixes {
+		episodes, err := converter.LoadEpisodes(prefix, dataDir)
+		if err != nil {
+			return n, err
+		}
+		for _, ep := range episodes {
+			ix.Upsert(ep)
+			n++
+		}
+	}
+	return n, nil
+}
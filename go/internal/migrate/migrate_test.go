@@ -0,0 +1,94 @@
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+	"github.com/aramova/twit-transcript-archiver/go/internal/utils"
+)
+
+func TestFlatToStructured(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "migratetest")
+	defer os.RemoveAll(tmpDir)
+
+	for _, name := range []string{"SN_1.html", "SN_2.html", "IM_5.html", "SN_Transcripts_1-2.md", "IM_0001-0005_2024.md", "transcripts_page_3.html", "notes.txt"} {
+		os.WriteFile(filepath.Join(tmpDir, name), []byte(name), 0644)
+	}
+	ix := index.New()
+	ix.Entries["SN_1"] = &index.Entry{File: "SN_1.html", Prefix: "SN", Number: 1}
+
+	moves, err := Plan(tmpDir, config.FlatLayout, config.StructuredLayout)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(moves) != 6 {
+		t.Fatalf("Expected 6 moves, got %v", moves)
+	}
+	if err := Apply(moves); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	for _, p := range []string{"raw/SN/SN_1.html", "raw/SN/SN_2.html", "raw/IM/IM_5.html", "chunks/SN/SN_Transcripts_1-2.md", "chunks/IM/IM_0001-0005_2024.md", "lists/transcripts_page_3.html", "notes.txt"} {
+		if !utils.FileExists(filepath.Join(tmpDir, p)) {
+			t.Errorf("Expected %s after migration", p)
+		}
+	}
+
+	if n := UpdateIndex(ix, tmpDir, moves); n != 1 || ix.Entries["SN_1"].File != "raw/SN/SN_1.html" {
+		t.Errorf("Index not updated: %d, %+v", n, ix.Entries["SN_1"])
+	}
+
+	// A second run has nothing to do
+	moves, _ = Plan(tmpDir, config.StructuredLayout, config.StructuredLayout)
+	if len(moves) != 0 {
+		t.Errorf("Expected no moves for an already migrated directory, got %v", moves)
+	}
+}
+
+func TestCountAndBackfill(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "migratetest")
+	defer os.RemoveAll(tmpDir)
+
+	for _, n := range []string{"1", "2"} {
+		html := `<h1 class="post-title">Ep ` + n + `</h1><p class="byline">Feb 11th 2025</p><div class="body textual">Content ` + n + `</div>`
+		os.WriteFile(filepath.Join(tmpDir, "SN_"+n+".html"), []byte(html), 0644)
+	}
+	os.WriteFile(filepath.Join(tmpDir, "SN_Transcripts_1-2.md"), []byte("x"), 0644)
+
+	if !IsLegacyFlat(tmpDir) {
+		t.Fatal("Expected flat layout to be detected")
+	}
+	before, _ := Count(tmpDir, config.FlatLayout)
+	if before.Raw != 2 || before.Chunks != 1 {
+		t.Fatalf("Unexpected counts: %+v", before)
+	}
+
+	moves, _ := Plan(tmpDir, config.FlatLayout, config.StructuredLayout)
+	if err := Apply(moves); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if IsLegacyFlat(tmpDir) {
+		t.Error("Flat layout still detected after migration")
+	}
+	if after, _ := Count(tmpDir, config.StructuredLayout); after != before {
+		t.Errorf("Counts changed: %+v -> %+v", before, after)
+	}
+
+	saved := config.ActiveLayout
+	config.ActiveLayout = config.StructuredLayout
+	defer func() { config.ActiveLayout = saved }()
+
+	ix, _ := index.Load(tmpDir)
+	ix.Entries["SN_1"] = &index.Entry{Tags: []string{"security"}}
+	n, err := Backfill(ix, tmpDir)
+	if err != nil || n != 2 {
+		t.Fatalf("Backfill = %d, %v", n, err)
+	}
+	e := ix.Entries["SN_1"]
+	if e.File != "raw/SN/SN_1.html" || e.Date != "2025-02-11" || e.Title != "Ep 1" || !e.HasTag("security") {
+		t.Errorf("Unexpected entry: %+v", e)
+	}
+}
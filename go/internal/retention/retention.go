@@ -0,0 +1,373 @@
+// Package retention decides which of the files a long-running archive
+// accumulates can go: old listing pages, recognizer output, downloaded
+// audio, superseded revisions, quarantined downloads and backups. A Policy
+// says how long each class of file is kept; Plan lists what it lets go and
+// Remove deletes it.
+package retention
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/backup"
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/scraper"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+	"github.com/aramova/twit-transcript-archiver/go/internal/transcribe"
+)
+
+// Classes of file a policy covers
+const (
+	Raw        = "raw"        // Archived transcript HTML, only ever kept forever
+	Lists      = "lists"      // Cached listing pages
+	Alt        = "alt"        // Alternatives: older revisions and other copies
+	ASR        = "asr"        // Recognizer output kept by transcribe --compare
+	Media      = "media"      // Downloaded episode audio
+	Quarantine = "quarantine" // Downloads that failed extraction
+	Backups    = "backups"    // Snapshots of the state files
+)
+
+// Classes lists every class, in the order Plan reports them
+var Classes = []string{Raw, Lists, Alt, ASR, Media, Quarantine, Backups}
+
+// counted are the classes a rule may keep the latest N of: alternatives
+// per episode, quarantine and backups overall
+var counted = map[string]bool{Alt: true, Quarantine: true, Backups: true}
+
+// mediaExts are the extensions of downloaded audio and video, which in the
+// flat layout share the data directory with everything else
+var mediaExts = map[string]bool{".mp3": true, ".m4a": true, ".aac": true, ".ogg": true, ".opus": true, ".mp4": true, ".m4v": true, ".webm": true}
+
+// Rule says how long files of a class are kept. A zero MaxAge keeps files
+// of any age; a zero Latest keeps any number.
+type Rule struct {
+	MaxAge time.Duration
+	Latest int
+}
+
+// Forever reports whether the rule keeps everything
+func (r Rule) Forever() bool {
+	return r.MaxAge == 0 && r.Latest == 0
+}
+
+func (r Rule) String() string {
+	switch {
+	case r.Latest > 0:
+		return fmt.Sprintf("latest %d", r.Latest)
+	case r.MaxAge > 0:
+		return "for " + formatAge(r.MaxAge)
+	}
+	return "forever"
+}
+
+// Policy maps classes to their rules; classes without one are kept forever
+type Policy map[string]Rule
+
+// ParsePolicy parses --keep: comma-separated "CLASS=RULE" entries
+// ("raw=forever,asr=90d,alt=2") where RULE is "forever", an age (a Go
+// duration, with "d" for days allowed) or a number of files to keep, the
+// newest first: per episode for alt, overall for quarantine and backups.
+func ParsePolicy(s string) (Policy, error) {
+	p := make(Policy)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		class, value, ok := strings.Cut(entry, "=")
+		class, value = strings.TrimSpace(class), strings.TrimSpace(value)
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid retention rule '%s' (want CLASS=RULE, e.g. asr=90d)", entry)
+		}
+		if !isClass(class) {
+			return nil, fmt.Errorf("unknown retention class '%s' (want %s)", class, strings.Join(Classes, ", "))
+		}
+		var r Rule
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			if !counted[class] {
+				return nil, fmt.Errorf("%s: only alt, quarantine and backups can keep a number of files", entry)
+			}
+			r.Latest = n
+		} else if value != "forever" && value != "never" {
+			age, err := scraper.ParseAge(value)
+			if err != nil || age == 0 {
+				return nil, fmt.Errorf("invalid retention rule '%s' (want forever, an age such as 90d, or a count)", entry)
+			}
+			r.MaxAge = age
+		}
+		if class == Raw && !r.Forever() {
+			// The index would point at missing files, and fetch would
+			// download the transcripts again
+			return nil, fmt.Errorf("%s: archived transcripts (raw) can only be kept forever", entry)
+		}
+		p[class] = r
+	}
+	return p, nil
+}
+
+func isClass(name string) bool {
+	for _, c := range Classes {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// formatAge writes whole days as "90d", other ages as Go durations
+func formatAge(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	return d.String()
+}
+
+// File is a file a policy lets go
+type File struct {
+	Path  string
+	Class string
+	Size  int64     // -1 when unknown (remote storage)
+	Date  time.Time // When it was fetched, made or set aside
+	// Page is the listing page number of a cached listing page
+	Page int
+	// Reason is the rule that lets it go, e.g. "older than 90d"
+	Reason string
+}
+
+// candidate is a file of a class with the group its count is kept in
+type candidate struct {
+	File
+	group string
+}
+
+// Plan lists the files of a data directory that the policy lets go as of
+// now, class by class in the order of Classes, oldest first within each.
+// Files without a known date are only let go by a count.
+func Plan(dataDir string, p Policy, now time.Time) ([]File, error) {
+	var out []File
+	for _, class := range Classes {
+		rule, ok := p[class]
+		if !ok || rule.Forever() {
+			continue
+		}
+		files, err := collect(dataDir, class)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", class, err)
+		}
+		out = append(out, apply(files, rule, now)...)
+	}
+	return out, nil
+}
+
+// apply returns the files a rule lets go
+func apply(files []candidate, rule Rule, now time.Time) []File {
+	var out []File
+	gone := make(map[string]bool)
+	if rule.Latest > 0 {
+		groups := make(map[string][]candidate)
+		var keys []string
+		for _, f := range files {
+			if _, ok := groups[f.group]; !ok {
+				keys = append(keys, f.group)
+			}
+			groups[f.group] = append(groups[f.group], f)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			g := groups[k]
+			sort.SliceStable(g, func(i, j int) bool { return g[i].Date.After(g[j].Date) })
+			for _, f := range g[min(rule.Latest, len(g)):] {
+				f.Reason = fmt.Sprintf("beyond the latest %d", rule.Latest)
+				out = append(out, f.File)
+				gone[f.Path] = true
+			}
+		}
+	}
+	if rule.MaxAge > 0 {
+		cutoff := now.Add(-rule.MaxAge)
+		for _, f := range files {
+			if !gone[f.Path] && !f.Date.IsZero() && f.Date.Before(cutoff) {
+				f.Reason = "older than " + formatAge(rule.MaxAge)
+				out = append(out, f.File)
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if !out[i].Date.Equal(out[j].Date) {
+			return out[i].Date.Before(out[j].Date)
+		}
+		return out[i].Path < out[j].Path
+	})
+	return out
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// collect lists the files of a class
+func collect(dataDir, class string) ([]candidate, error) {
+	var out []candidate
+	add := func(p, group string, date time.Time) {
+		size, mod := stat(p)
+		if date.IsZero() {
+			date = mod
+		}
+		out = append(out, candidate{File: File{Path: p, Class: class, Size: size, Date: date}, group: group})
+	}
+	switch class {
+	case Lists:
+		pages, err := scraper.CachedListPages(dataDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range pages {
+			out = append(out, candidate{File: File{Path: f.Path, Class: class, Size: f.Size, Date: f.Fetched, Page: f.Page}})
+		}
+	case Alt:
+		prefixes, err := showDirs(storage.Join(dataDir, converter.AltDir))
+		if err != nil {
+			return nil, err
+		}
+		for _, prefix := range prefixes {
+			alts, err := converter.Alternatives(dataDir, prefix)
+			if err != nil {
+				return nil, err
+			}
+			for ep, copies := range alts {
+				for _, c := range copies {
+					add(c.Path, fmt.Sprintf("%s_%d", prefix, ep), c.Fetched)
+				}
+			}
+		}
+	case ASR:
+		prefixes, err := showDirs(storage.Join(dataDir, transcribe.Dir))
+		if err != nil {
+			return nil, err
+		}
+		for _, prefix := range prefixes {
+			outputs, err := transcribe.Outputs(dataDir, prefix)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range outputs {
+				add(p, "", time.Time{})
+			}
+		}
+	case Media:
+		if storage.IsRemote(dataDir) {
+			return nil, nil // Media is only downloaded to local directories
+		}
+		prefixes, err := converter.ListPrefixes(dataDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, prefix := range prefixes {
+			matches, err := storage.Glob(storage.Join(config.ActiveLayout.MediaDir(dataDir, prefix), prefix+"_*"))
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range matches {
+				if mediaExts[strings.ToLower(path.Ext(m))] {
+					add(m, "", time.Time{})
+				}
+			}
+		}
+	case Quarantine:
+		items, err := converter.Quarantined(dataDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, it := range items {
+			add(it.Path, "", it.At)
+		}
+	case Backups:
+		files, err := backup.List(dataDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			at, _ := backup.Time(f)
+			add(f, "", at)
+		}
+	}
+	return out, nil
+}
+
+// showDirs returns the show prefixes of the per-show directories under dir
+func showDirs(dir string) ([]string, error) {
+	matches, err := storage.Glob(storage.Join(dir, "*", "*"))
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var prefixes []string
+	for _, m := range matches {
+		parent, _ := storage.Split(m)
+		if prefix := storage.Base(parent); !seen[prefix] {
+			seen[prefix] = true
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	sort.Strings(prefixes)
+	return prefixes, nil
+}
+
+// stat returns a local file's size and modification time; -1 and zero on
+// remote storage
+func stat(path string) (int64, time.Time) {
+	if storage.IsRemote(path) {
+		return -1, time.Time{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return -1, time.Time{}
+	}
+	return info.Size(), info.ModTime()
+}
+
+// Size totals the known sizes of files
+func Size(files []File) int64 {
+	var total int64
+	for _, f := range files {
+		if f.Size > 0 {
+			total += f.Size
+		}
+	}
+	return total
+}
+
+// Remove deletes the files Plan returned: quarantined files with their
+// reason files, listing pages with their recorded fetch times. It returns
+// how many were removed.
+func Remove(dataDir string, files []File) (int, error) {
+	removed := 0
+	var pages []scraper.CachedFile
+	for _, f := range files {
+		if f.Class == Lists {
+			pages = append(pages, scraper.CachedFile{Path: f.Path, Page: f.Page, Size: f.Size, Fetched: f.Date})
+			continue
+		}
+		var err error
+		if f.Class == Quarantine {
+			err = converter.RemoveQuarantined(f.Path)
+		} else {
+			err = storage.Remove(f.Path)
+		}
+		if err != nil {
+			return removed, fmt.Errorf("removing %s: %w", f.Path, err)
+		}
+		removed++
+	}
+	n, _, err := scraper.RemoveCached(dataDir, pages)
+	return removed + n, err
+}
@@ -0,0 +1,112 @@
+package retention
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/backup"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy("raw=forever, asr=90d,alt=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p[Raw].Forever() || p[ASR].MaxAge != 90*24*time.Hour || p[Alt].Latest != 2 {
+		t.Errorf("ParsePolicy = %+v", p)
+	}
+	if s := p[ASR].String(); s != "for 90d" {
+		t.Errorf("String = %q", s)
+	}
+	for _, bad := range []string{"asr", "tmp=1d", "asr=2", "alt=soon", "lists=0", "raw=90d"} {
+		if _, err := ParsePolicy(bad); err == nil {
+			t.Errorf("ParsePolicy(%q) should fail", bad)
+		}
+	}
+}
+
+func TestPlanAndRemove(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	write := func(name string, mod time.Time) string {
+		p := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(p, mod, mod)
+		return p
+	}
+	old := now.AddDate(0, -6, 0)
+	write("SN_1.html", old)
+	write("alt/SN/SN_1.site-20250101T000000Z.html", now)
+	write("alt/SN/SN_1.site-20250201T000000Z.html", now)
+	newest := write("alt/SN/SN_1.site-20250301T000000Z.html", now)
+	write("alt/SN/SN_2.wayback-20250101T000000Z.html", now)
+	write("asr/SN/SN_1.vtt", old)
+	write("asr/SN/SN_2.vtt", now)
+	write("SN_1.mp3", old)
+	write(filepath.Join(backup.Dir, backup.Name(old)), old)
+	write(filepath.Join(backup.Dir, backup.Name(now)), now)
+	bad := write("SN_3.html", now)
+	quarantined, err := converter.Quarantine(dir, bad, errors.New("no transcript body"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy, err := ParsePolicy("raw=forever,alt=2,asr=90d,media=30d,backups=1,quarantine=30d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := Plan(dir, policy, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range files {
+		rel, _ := filepath.Rel(dir, f.Path)
+		got = append(got, f.Class+" "+rel)
+		if f.Path == newest {
+			t.Error("The newest revision should be kept")
+		}
+	}
+	want := []string{
+		"alt alt/SN/SN_1.site-20250101T000000Z.html",
+		"asr asr/SN/SN_1.vtt",
+		"media SN_1.mp3",
+		"backups " + filepath.Join(backup.Dir, backup.Name(old)),
+	}
+	sort.Strings(got)
+	sort.Strings(want)
+	if len(got) != len(want) {
+		t.Fatalf("Plan = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Plan = %v, want %v", got, want)
+			break
+		}
+	}
+
+	// Quarantined today, so kept; a year on it goes with its reason file
+	later, err := Plan(dir, Policy{Quarantine: {MaxAge: 30 * 24 * time.Hour}}, now.AddDate(1, 0, 0))
+	if err != nil || len(later) != 1 || later[0].Path != quarantined {
+		t.Fatalf("Plan a year later = %+v, %v", later, err)
+	}
+	files = append(files, later...)
+	n, err := Remove(dir, files)
+	if err != nil || n != len(files) {
+		t.Fatalf("Remove = %d, %v", n, err)
+	}
+	if _, err := os.Stat(quarantined + ".reason.json"); !os.IsNotExist(err) {
+		t.Error("Remove should delete a quarantined file's reason file")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "SN_1.html")); err != nil {
+		t.Error("Raw HTML kept forever should stay")
+	}
+}
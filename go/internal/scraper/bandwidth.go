@@ -0,0 +1,164 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrTransferBudget is returned once a run has downloaded its byte budget
+var ErrTransferBudget = errors.New("transfer budget exhausted")
+
+// Bandwidth, when set, limits the rate and total bytes of every response
+// body the scraper reads
+var Bandwidth *Limiter
+
+// client is shared by all scraper requests so that Bandwidth applies to them
+var client = &http.Client{Transport: limitTransport{}}
+
+// Limiter paces reads to Rate bytes per second and fails them once Max
+// bytes have been read. A zero Rate or Max is unlimited.
+type Limiter struct {
+	Rate int64
+	Max  int64
+
+	mu   sync.Mutex
+	used int64
+	next time.Time // When the bytes read so far are paid for
+}
+
+// NewLimiter returns a Limiter, or nil if both limits are zero
+func NewLimiter(rate, max int64) *Limiter {
+	if rate <= 0 && max <= 0 {
+		return nil
+	}
+	return &Limiter{Rate: rate, Max: max}
+}
+
+// ParseRate parses a transfer rate such as "500KB/s" or "2M"
+func ParseRate(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	for _, suffix := range []string{"/s", "/S", "ps", "PS"} {
+		s = strings.TrimSuffix(s, suffix)
+	}
+	n, err := ParseSize(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate '%s'", s)
+	}
+	return n, nil
+}
+
+// Used returns the bytes read so far
+func (l *Limiter) Used() int64 {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.used
+}
+
+// Exhausted reports whether the byte budget has been spent
+func (l *Limiter) Exhausted() bool {
+	return l != nil && l.Max > 0 && l.Used() >= l.Max
+}
+
+// Reader wraps r so that reads from it count against the limits
+func (l *Limiter) Reader(r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &limitedReader{r: r, l: l}
+}
+
+// allow returns how many of want bytes may be read now
+func (l *Limiter) allow(want int) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.Max > 0 {
+		left := l.Max - l.used
+		if left <= 0 {
+			return 0, ErrTransferBudget
+		}
+		if int64(want) > left {
+			want = int(left)
+		}
+	}
+	// Read at most a quarter second's worth at a time so pacing stays smooth
+	if chunk := int(l.Rate / 4); l.Rate > 0 && chunk > 0 && want > chunk {
+		want = chunk
+	}
+	return want, nil
+}
+
+// spend records n bytes read and sleeps until they are within the rate
+func (l *Limiter) spend(n int) {
+	l.mu.Lock()
+	l.used += int64(n)
+	var wait time.Duration
+	if l.Rate > 0 && n > 0 {
+		now := time.Now()
+		if l.next.Before(now) {
+			l.next = now
+		}
+		l.next = l.next.Add(time.Duration(int64(n) * int64(time.Second) / l.Rate))
+		wait = time.Until(l.next)
+	}
+	l.mu.Unlock()
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+type limitedReader struct {
+	r io.Reader
+	l *Limiter
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	want, err := lr.l.allow(len(p))
+	if err != nil {
+		return 0, err
+	}
+	n, err := lr.r.Read(p[:want])
+	lr.l.spend(n)
+	return n, err
+}
+
+// limitTransport sends requests through Transport, applying Bandwidth to
+// response bodies, feeding Politeness, counting connections and logging
+// exchanges to HTTPLog
+type limitTransport struct{}
+
+func (limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if Bandwidth.Exhausted() {
+		return nil, ErrTransferBudget
+	}
+	send := Transport.RoundTrip
+	if HTTPLog != nil {
+		send = roundTripLogged
+	}
+	Politeness.Wait()
+	start := time.Now()
+	resp, err := send(traceConn(req))
+	if err == nil {
+		countProto(resp)
+		Politeness.Observe(resp.StatusCode, time.Since(start), resp.Header.Get("Retry-After"))
+	} else {
+		Politeness.Observe(0, time.Since(start), "")
+	}
+	if err != nil || Bandwidth == nil {
+		return resp, err
+	}
+	resp.Body = limitedBody{Reader: Bandwidth.Reader(resp.Body), Closer: resp.Body}
+	return resp, nil
+}
+
+type limitedBody struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,76 @@
+package scraper
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := map[string]int64{
+		"500KB/s": 500 << 10,
+		"2M":      2 << 20,
+		"1mbps":   1 << 20,
+		"":        0,
+	}
+	for in, want := range tests {
+		got, err := ParseRate(in)
+		if err != nil || got != want {
+			t.Errorf("ParseRate(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	if _, err := ParseRate("fast"); err == nil {
+		t.Error("Expected an error for an invalid rate")
+	}
+}
+
+func TestLimiterRate(t *testing.T) {
+	l := NewLimiter(4000, 0)
+	start := time.Now()
+	n, err := io.Copy(io.Discard, l.Reader(bytes.NewReader(make([]byte, 2000))))
+	if err != nil || n != 2000 {
+		t.Fatalf("Copy = %d, %v", n, err)
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("2000 bytes at 4000 B/s took %v, expected about 500ms", elapsed)
+	}
+	if l.Used() != 2000 {
+		t.Errorf("Used = %d, want 2000", l.Used())
+	}
+	if NewLimiter(0, 0) != nil {
+		t.Error("Expected no limiter without limits")
+	}
+}
+
+func TestDownloadPage_TransferBudget(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(strings.Repeat("x", 1000)))
+	}))
+	defer ts.Close()
+
+	Bandwidth = NewLimiter(0, 1500)
+	defer func() { Bandwidth = nil }()
+
+	if _, err := DownloadPage(ts.URL, 0); err != nil {
+		t.Fatalf("First page should fit the budget: %v", err)
+	}
+	if _, err := DownloadPage(ts.URL, 0); !errors.Is(err, ErrTransferBudget) {
+		t.Errorf("Expected ErrTransferBudget, got %v", err)
+	}
+	if _, err := DownloadPage(ts.URL, 0); !errors.Is(err, ErrTransferBudget) {
+		t.Errorf("Expected ErrTransferBudget, got %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected no requests (or retries) once the budget is spent, got %d requests", requests)
+	}
+	if !Bandwidth.Exhausted() {
+		t.Error("Expected the budget to be exhausted")
+	}
+}
@@ -0,0 +1,123 @@
+package scraper
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// CachedFile is a downloaded page kept in the cache or data directory
+type CachedFile struct {
+	Path    string
+	Page    int       // Listing page number, 0 for transcripts
+	Size    int64     // -1 when unknown (remote storage)
+	Fetched time.Time // Zero when unknown
+}
+
+var listPageFileRegex = regexp.MustCompile(`^transcripts_page_(\d+)\.html$`)
+
+// CachedListPages lists the cached listing pages, in page order, with the
+// fetch times recorded in ListCacheFile
+func CachedListPages(dataDir string) ([]CachedFile, error) {
+	matches, err := storage.Glob(storage.Join(config.ListPageDir(dataDir), "transcripts_page_*.html"))
+	if err != nil {
+		return nil, err
+	}
+	log := listFetchLog(dataDir)
+	var files []CachedFile
+	for _, path := range matches {
+		m := listPageFileRegex.FindStringSubmatch(storage.Base(path))
+		if m == nil {
+			continue
+		}
+		f := statCached(path)
+		f.Page, _ = strconv.Atoi(m[1])
+		f.Fetched, _ = log.fetched(f.Page, path)
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Page < files[j].Page })
+	return files, nil
+}
+
+// CachedTranscripts lists the transcript HTML archived for a show, dated by
+// file modification time
+func CachedTranscripts(dataDir, prefix string) ([]CachedFile, error) {
+	matches, err := config.ActiveLayout.RawFiles(dataDir, prefix)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]CachedFile, 0, len(matches))
+	for _, path := range matches {
+		files = append(files, statCached(path))
+	}
+	return files, nil
+}
+
+func statCached(path string) CachedFile {
+	f := CachedFile{Path: path, Size: -1}
+	if storage.IsRemote(path) {
+		return f
+	}
+	if info, err := os.Stat(path); err == nil {
+		f.Size, f.Fetched = info.Size(), info.ModTime()
+	}
+	return f
+}
+
+// CacheSize totals the known sizes of files
+func CacheSize(files []CachedFile) int64 {
+	var total int64
+	for _, f := range files {
+		if f.Size > 0 {
+			total += f.Size
+		}
+	}
+	return total
+}
+
+// FetchedBefore returns the files fetched before t. Files with no known
+// fetch time are never included.
+func FetchedBefore(files []CachedFile, t time.Time) []CachedFile {
+	var old []CachedFile
+	for _, f := range files {
+		if !f.Fetched.IsZero() && f.Fetched.Before(t) {
+			old = append(old, f)
+		}
+	}
+	return old
+}
+
+// RemoveCached deletes files, forgets the fetch times of listing pages among
+// them and drops the index entries of transcripts among them, so the index
+// never describes files that are gone. It returns how many files were
+// removed and how many index entries dropped.
+func RemoveCached(dataDir string, files []CachedFile) (int, int, error) {
+	removed := 0
+	var pages []int
+	var transcripts []string
+	for _, f := range files {
+		if err := storage.Remove(f.Path); err != nil {
+			dropped, _ := forgetTranscripts(dataDir, transcripts)
+			return removed, dropped, fmt.Errorf("removing %s: %w", f.Path, err)
+		}
+		removed++
+		if f.Page > 0 {
+			pages = append(pages, f.Page)
+		} else {
+			transcripts = append(transcripts, f.Path)
+		}
+	}
+	if len(pages) > 0 {
+		if err := listFetchLog(dataDir).forget(pages); err != nil {
+			return removed, 0, err
+		}
+	}
+	dropped, err := forgetTranscripts(dataDir, transcripts)
+	return removed, dropped, err
+}
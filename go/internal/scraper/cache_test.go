@@ -0,0 +1,92 @@
+package scraper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+)
+
+func TestCachedListPages(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "twittest")
+	defer os.RemoveAll(tmpDir)
+
+	for _, n := range []int{10, 2, 1} {
+		os.WriteFile(filepath.Join(tmpDir, fmt.Sprintf("transcripts_page_%d.html", n)), []byte("page"), 0644)
+	}
+	os.WriteFile(filepath.Join(tmpDir, "SN_1.html"), []byte("transcript"), 0644)
+	old := time.Now().Add(-100 * 24 * time.Hour)
+	os.Chtimes(filepath.Join(tmpDir, "transcripts_page_10.html"), old, old)
+	// A recorded fetch time wins over the file time
+	if err := listFetchLog(tmpDir).record(2, old); err != nil {
+		t.Fatal(err)
+	}
+
+	pages, err := CachedListPages(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 3 || pages[0].Page != 1 || pages[1].Page != 2 || pages[2].Page != 10 {
+		t.Fatalf("Pages = %+v, want 1, 2, 10", pages)
+	}
+	if CacheSize(pages) != 12 {
+		t.Errorf("CacheSize = %d, want 12", CacheSize(pages))
+	}
+
+	stale := FetchedBefore(pages, time.Now().Add(-90*24*time.Hour))
+	if len(stale) != 2 || stale[0].Page != 2 || stale[1].Page != 10 {
+		t.Fatalf("Stale pages = %+v, want 2 and 10", stale)
+	}
+	if n, _, err := RemoveCached(tmpDir, stale); n != 2 || err != nil {
+		t.Fatalf("RemoveCached = %d, %v", n, err)
+	}
+	pages, _ = CachedListPages(tmpDir)
+	if len(pages) != 1 || pages[0].Page != 1 {
+		t.Errorf("After pruning, pages = %+v", pages)
+	}
+	if _, ok := listFetchLog(tmpDir).times["2"]; ok {
+		t.Error("Fetch time of a removed page should be forgotten")
+	}
+
+	files, err := CachedTranscripts(tmpDir, "SN")
+	if err != nil || len(files) != 1 || files[0].Size != 10 || files[0].Fetched.IsZero() {
+		t.Errorf("CachedTranscripts = %+v, %v", files, err)
+	}
+}
+
+func TestRemoveCachedTranscripts(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "SN_1.html"), []byte("transcript"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "SN_2.html"), []byte("transcript"), 0644)
+	ix := index.New()
+	ix.Entries["SN_1"] = &index.Entry{File: "SN_1.html", Prefix: "SN", Number: 1, URL: "https://twit.tv/posts/transcripts/sn-1", Tags: []string{"spinrite"}}
+	ix.Entries["SN_2"] = &index.Entry{File: "SN_2.html", Prefix: "SN", Number: 2}
+	if err := ix.Save(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := archivedAt("https://twit.tv/posts/transcripts/sn-1", tmpDir); !ok {
+		t.Fatal("SN 1 should be archived at its URL")
+	}
+
+	files, _ := CachedTranscripts(tmpDir, "SN")
+	n, dropped, err := RemoveCached(tmpDir, files[:1])
+	if n != 1 || dropped != 1 || err != nil {
+		t.Fatalf("RemoveCached = %d, %d, %v", n, dropped, err)
+	}
+	ix, err = index.Load(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ix.Entries["SN_1"]; ok {
+		t.Error("The index entry of a removed transcript should be dropped")
+	}
+	if _, ok := ix.Entries["SN_2"]; !ok {
+		t.Error("Other index entries should be kept")
+	}
+	if _, ok := archivedAt("https://twit.tv/posts/transcripts/sn-1", tmpDir); ok {
+		t.Error("A removed transcript should no longer count as archived at its URL")
+	}
+}
@@ -0,0 +1,134 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// Archived transcripts are named by show and episode number, so a page that
+// is republished under a new slug, or listed under a title that numbers it
+// differently, would be archived twice. Fetch recognizes such pages by the
+// canonical URLs and aliases recorded in the index.
+
+// ErrDuplicate is returned for a downloaded page that is archived under
+// another name already
+var ErrDuplicate = errors.New("already archived")
+
+var (
+	canonicalMu sync.Mutex
+	// Archived files by the index.URLKey of their URLs, per data directory
+	canonicalFiles = make(map[string]map[string]string)
+)
+
+// urlFiles returns the data directory's archived files by URL, loading them
+// from the index the first time. canonicalMu must be held.
+func urlFiles(dataDir string) map[string]string {
+	files, ok := canonicalFiles[dataDir]
+	if !ok {
+		files = make(map[string]string)
+		if ix, err := index.Load(dataDir); err == nil {
+			for u, key := range ix.ByURL() {
+				files[u] = ix.Entries[key].File
+			}
+		}
+		canonicalFiles[dataDir] = files
+	}
+	return files
+}
+
+// archivedAt returns the transcript file archived from a URL, if it is
+// still there
+func archivedAt(u, dataDir string) (string, bool) {
+	canonicalMu.Lock()
+	file, ok := urlFiles(dataDir)[index.URLKey(u)]
+	canonicalMu.Unlock()
+	if !ok {
+		return "", false
+	}
+	path := storage.Join(dataDir, file)
+	return path, storage.Exists(path)
+}
+
+// recordFetched records a saved transcript in the data directory's index:
+// its canonical URL, the listed URL that led to it if that differs, and
+// whether it is pending
+func recordFetched(path, listed, dataDir string) (*index.Entry, error) {
+	ep, err := converter.LoadEpisode(path)
+	if err != nil {
+		return nil, err
+	}
+	ix, err := index.Load(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	e := ix.Upsert(ep)
+	e.AddAlias(listed)
+	if err := ix.Save(dataDir); err != nil {
+		return nil, err
+	}
+	canonicalMu.Lock()
+	files := urlFiles(dataDir)
+	for _, u := range append([]string{e.URL}, e.Aliases...) {
+		files[index.URLKey(u)] = e.File
+	}
+	canonicalMu.Unlock()
+	markPending(dataDir, index.Key(path), e.Pending)
+	return e, nil
+}
+
+// recordAlias adds a URL to the aliases of the transcript archived at path,
+// so later fetches skip it without downloading
+func recordAlias(path, u, dataDir string) error {
+	ix, err := index.Load(dataDir)
+	if err != nil {
+		return err
+	}
+	e, ok := ix.Entries[index.Key(path)]
+	if !ok {
+		return fmt.Errorf("%s is not in the index", path)
+	}
+	if !e.AddAlias(u) {
+		return nil
+	}
+	if err := ix.Save(dataDir); err != nil {
+		return err
+	}
+	canonicalMu.Lock()
+	urlFiles(dataDir)[index.URLKey(u)] = e.File
+	canonicalMu.Unlock()
+	return nil
+}
+
+// forgetTranscripts drops the index entries of removed transcript files,
+// with the URLs and pending marks fetch knew them by, and returns how many
+// were dropped
+func forgetTranscripts(dataDir string, paths []string) (int, error) {
+	if len(paths) == 0 {
+		return 0, nil
+	}
+	ix, err := index.Load(dataDir)
+	if err != nil {
+		return 0, err
+	}
+	dropped := 0
+	for _, path := range paths {
+		key := index.Key(path)
+		if _, ok := ix.Entries[key]; ok {
+			delete(ix.Entries, key)
+			markPending(dataDir, key, false)
+			dropped++
+		}
+	}
+	if dropped == 0 {
+		return 0, nil
+	}
+	canonicalMu.Lock()
+	delete(canonicalFiles, dataDir)
+	canonicalMu.Unlock()
+	return dropped, ix.Save(dataDir)
+}
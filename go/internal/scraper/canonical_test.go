@@ -0,0 +1,98 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+)
+
+func TestDownloadTranscriptCanonical(t *testing.T) {
+	var ts *httptest.Server
+	requested := make(map[string]int)
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested[r.URL.Path]++
+		switch r.URL.Path {
+		case "/posts/sn-999-old":
+			http.Redirect(w, r, "/posts/sn-999", http.StatusMovedPermanently)
+		case "/posts/sn-999":
+			fmt.Fprint(w, `<html><h1 class="post-title">Security Now 999</h1>`+transcriptBody+`</html>`)
+		case "/posts/sn-1000", "/posts/sn-1000-republished":
+			fmt.Fprintf(w, `<html><head><link rel="canonical" href="%s/posts/sn-1000"></head><h1 class="post-title">Security Now 1000</h1>%s</html>`, ts.URL, transcriptBody)
+		}
+	}))
+	defer ts.Close()
+	saved := config.BaseSiteURL
+	config.BaseSiteURL = ts.URL
+	defer func() { config.BaseSiteURL = saved }()
+	dir := t.TempDir()
+
+	// A redirected link is saved with the address it ended at
+	if skipped, err := DownloadTranscriptWithStatus("/posts/sn-999-old", "Security Now 999", "SN", dir, 0); err != nil || skipped {
+		t.Fatalf("got skipped=%v, %v", skipped, err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "SN_999.html"))
+	if u, _, _ := converter.PageSource(string(data)); u != ts.URL+"/posts/sn-999" {
+		t.Errorf("Stamped source = %q, want the redirect target", u)
+	}
+	ix, _ := index.Load(dir)
+	if e := ix.Entries["SN_999"]; e == nil || e.URL != ts.URL+"/posts/sn-999" || len(e.Aliases) != 1 || e.Aliases[0] != ts.URL+"/posts/sn-999-old" {
+		t.Fatalf("SN_999 = %+v", e)
+	}
+
+	if _, err := DownloadTranscriptWithStatus("/posts/sn-1000", "Security Now 1000", "SN", dir, 0); err != nil {
+		t.Fatal(err)
+	}
+	// Republished under a new slug and misnumbered in the listing: the
+	// canonical URL gives it away, and the slug is remembered
+	skipped, err := DownloadTranscriptWithStatus("/posts/sn-1000-republished", "Security Now 1001", "SN", dir, 0)
+	if err != nil || !skipped {
+		t.Fatalf("got skipped=%v, %v; want a skipped duplicate", skipped, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "SN_1001.html")); err == nil {
+		t.Error("The duplicate was saved as SN_1001.html")
+	}
+	ix, _ = index.Load(dir)
+	if e := ix.Entries["SN_1000"]; e == nil || len(e.Aliases) != 1 || !strings.HasSuffix(e.Aliases[0], "/posts/sn-1000-republished") {
+		t.Errorf("SN_1000 = %+v", e)
+	}
+	if skipped, err := DownloadTranscriptWithStatus("/posts/sn-1000-republished", "Security Now 1001", "SN", dir, 0); err != nil || !skipped {
+		t.Errorf("got skipped=%v, %v", skipped, err)
+	}
+	if requested["/posts/sn-1000-republished"] != 1 {
+		t.Errorf("The known alias was requested %d times, want 1", requested["/posts/sn-1000-republished"])
+	}
+}
+
+func TestListPageRedirect(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "9" {
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		}
+		fmt.Fprint(w, `<div class="item summary"><h2 class="title"><a href="/posts/sn1">Security Now 1 Transcript</a></h2></div>`)
+	}))
+	defer ts.Close()
+	saved := config.BaseListURL
+	config.BaseListURL = ts.URL
+	defer func() { config.BaseListURL = saved }()
+	dir := t.TempDir()
+
+	content, cached, err := GetListPageWithCacheStatus(9, dir, false, 0)
+	if err != nil || cached || content != "" {
+		t.Errorf("got %q, %v, %v; want an empty page past the end", content, cached, err)
+	}
+	if _, err := os.Stat(filepath.Join(config.ListPageDir(dir), "transcripts_page_9.html")); err == nil {
+		t.Error("The redirected page was cached")
+	}
+	if content, _, _ := GetListPageWithCacheStatus(2, dir, false, 0); len(ExtractItems(content)) != 1 {
+		t.Errorf("Page 2 = %q", content)
+	}
+}
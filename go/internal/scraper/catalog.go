@@ -0,0 +1,96 @@
+package scraper
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+// RemoteEpisode is a transcript listed on the site
+type RemoteEpisode struct {
+	Number int
+	Title  string
+	URL    string
+}
+
+// CatalogEntry is one episode's availability upstream and in the archive
+type CatalogEntry struct {
+	Number   int
+	Title    string
+	Upstream bool
+	Local    bool
+}
+
+// ListRemote scans up to pages listing pages and returns the transcripts
+// listed for a show, without downloading them. Paging stops at the first
+// empty page.
+func ListRemote(prefix, dataDir string, pages int, refresh bool, throttle time.Duration) ([]RemoteEpisode, error) {
+	var episodes []RemoteEpisode
+	seen := make(map[int]bool)
+	for pageNum := 1; pageNum <= pages; pageNum++ {
+		html, _, err := GetListPageWithCacheStatus(pageNum, dataDir, refresh, throttle)
+		if err != nil {
+			return episodes, fmt.Errorf("list page %d: %v", pageNum, err)
+		}
+		items := ExtractItems(html)
+		if len(items) == 0 {
+			break
+		}
+		for _, item := range items {
+			if config.PrefixForTitle(item.Title) != prefix {
+				continue
+			}
+			n, _ := strconv.Atoi(TitleEpisode(item.Title))
+			if seen[n] {
+				continue
+			}
+			seen[n] = true
+			episodes = append(episodes, RemoteEpisode{Number: n, Title: item.Title, URL: item.URL})
+		}
+	}
+	return episodes, nil
+}
+
+// LocalEpisodes returns the episode numbers archived for a show
+func LocalEpisodes(prefix, dataDir string) ([]int, error) {
+	files, err := config.ActiveLayout.RawFiles(dataDir, prefix)
+	if err != nil {
+		return nil, err
+	}
+	var nums []int
+	for _, f := range files {
+		if n := converter.GetEpNum(f); n > 0 {
+			nums = append(nums, n)
+		}
+	}
+	sort.Ints(nums)
+	return nums, nil
+}
+
+// Catalog merges the remote listing with the local archive, ordered by
+// episode number
+func Catalog(remote []RemoteEpisode, local []int) []CatalogEntry {
+	byNum := make(map[int]*CatalogEntry)
+	for _, r := range remote {
+		byNum[r.Number] = &CatalogEntry{Number: r.Number, Title: r.Title, Upstream: true}
+	}
+	for _, n := range local {
+		e, ok := byNum[n]
+		if !ok {
+			e = &CatalogEntry{Number: n}
+			byNum[n] = e
+		}
+		e.Local = true
+	}
+
+	entries := make([]CatalogEntry, 0, len(byNum))
+	for _, e := range byNum {
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Number < entries[j].Number })
+	return entries
+}
@@ -0,0 +1,55 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+)
+
+func TestCatalog(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "twittest")
+	defer os.RemoveAll(tmpDir)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") != "" {
+			return // Page 2 is empty
+		}
+		for _, title := range []string{"Security Now 3 Transcript", "Windows Weekly 900 Transcript", "Security Now 2 Transcript"} {
+			fmt.Fprintf(w, `<div class="item summary"><h2 class="title"><a href="/posts/x">%s</a></h2></div>`, title)
+		}
+	}))
+	defer ts.Close()
+	saved := config.BaseListURL
+	config.BaseListURL = ts.URL
+	defer func() { config.BaseListURL = saved }()
+
+	for _, name := range []string{"SN_1.html", "SN_2.html"} {
+		os.WriteFile(filepath.Join(tmpDir, name), []byte("x"), 0644)
+	}
+
+	remote, err := ListRemote("SN", tmpDir, 5, false, 0)
+	if err != nil || len(remote) != 2 {
+		t.Fatalf("ListRemote = %v, %v", remote, err)
+	}
+	local, _ := LocalEpisodes("SN", tmpDir)
+
+	entries := Catalog(remote, local)
+	want := []CatalogEntry{
+		{Number: 1, Local: true},
+		{Number: 2, Title: "Security Now 2 Transcript", Upstream: true, Local: true},
+		{Number: 3, Title: "Security Now 3 Transcript", Upstream: true},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("Expected %d entries, got %v", len(want), entries)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("Entry %d = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+}
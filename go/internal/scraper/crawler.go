@@ -0,0 +1,413 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/errs"
+)
+
+// Crawler finds the transcripts of a set of shows, through the site search
+// or the listing, and downloads those not archived yet. The zero value of
+// each option is a sensible default, except for Shows and DataDir.
+type Crawler struct {
+	// Shows are the prefixes to fetch
+	Shows   []string
+	DataDir string
+	// Discovery is "list" (page through the listing), "search" (search the
+	// site per show) or "auto" (search, and page through the listing if the
+	// search finds nothing). Other sources than the site are always listed.
+	Discovery string
+	// Pages is the number of search result or listing pages to scan
+	Pages int
+	// Workers is the number of listing pages fetched at once
+	Workers int
+	// Refresh downloads listing pages again even if they are cached
+	Refresh bool
+	// StopAfterEmpty ends the listing after this many pages in a row
+	// without the shows (see EarlyStop)
+	StopAfterEmpty int
+	// StopAfterKnown ends the listing after this many pages in a row on
+	// which every transcript of the shows is archived already, for
+	// incremental runs that only need the newest episodes
+	StopAfterKnown int
+	Throttle       time.Duration
+	// Filter limits the downloads to an episode or date range
+	Filter converter.Filter
+	// Schedule orders the downloads and caps them per show (nil for
+	// listing order without caps); its counts start over with each Run
+	Schedule *Schedule
+	// WithMedia also downloads the audio of each fetched episode, within
+	// MediaBudget (nil for no limit)
+	WithMedia   bool
+	MediaBudget *MediaBudget
+	// Report collects the failures for errors.json; its --strict classes
+	// stop the crawl. Downloads cut off by Bandwidth or Quota are not
+	// failures, and are listed as left instead.
+	Report *errs.Report
+	// Stop, if set, is asked before each download whether to stop, for
+	// interrupts and deadlines. The crawl also stops once Bandwidth or
+	// Quota are used up.
+	Stop func() bool
+	// Progress, if set, is told what the crawler is about to do
+	Progress func(detail string)
+
+	report  *CrawlReport
+	targets map[string]bool
+	media   bool // Media downloads still allowed
+}
+
+// CrawlReport is what a crawl did
+type CrawlReport struct {
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	// Discovery is the method that found the transcripts: "search" or
+	// "list"
+	Discovery       string `json:"discovery"`
+	PagesScanned    int    `json:"pages_scanned"`
+	PagesDownloaded int    `json:"pages_downloaded"`
+	PagesCached     int    `json:"pages_cached"`
+	// Found counts the transcripts seen, Ignored those of other shows
+	Found   int `json:"found"`
+	Ignored int `json:"ignored"`
+	// Shows has a report per show, in the order of Crawler.Shows
+	Shows []*ShowReport `json:"shows"`
+	// Failed lists failures that belong to no show, such as listing pages
+	Failed []errs.Failure `json:"failed,omitempty"`
+	// Unknown counts the listed transcripts of shows that are not in the
+	// catalog
+	Unknown UnknownShows `json:"unknown,omitempty"`
+	// Stopped is set when the crawl ended before it was done; NextPage is
+	// then the first listing page not scanned (0 if there was none to
+	// scan) and Unsearched the shows the search did not get to
+	Stopped    bool     `json:"stopped"`
+	NextPage   int      `json:"next_page,omitempty"`
+	Unsearched []string `json:"unsearched,omitempty"`
+	// MediaStopped is set when MediaBudget ran out
+	MediaStopped bool `json:"media_stopped,omitempty"`
+}
+
+// ShowReport lists what a crawl did with each transcript of a show
+type ShowReport struct {
+	Prefix     string  `json:"prefix"`
+	Downloaded []Entry `json:"downloaded,omitempty"`
+	// Skipped were archived already
+	Skipped []Entry `json:"skipped,omitempty"`
+	// Pending were saved without their transcript, which is not out yet
+	Pending []Entry `json:"pending,omitempty"`
+	// Filtered were outside the episode or date range
+	Filtered []Entry `json:"filtered,omitempty"`
+	// Deferred were over the show's quota
+	Deferred []Entry `json:"deferred,omitempty"`
+	// Left were listed but not fetched before the crawl stopped
+	Left   []Entry        `json:"left,omitempty"`
+	Failed []errs.Failure `json:"failed,omitempty"`
+	// Media lists the audio files downloaded
+	Media       []string       `json:"media,omitempty"`
+	MediaFailed []errs.Failure `json:"media_failed,omitempty"`
+}
+
+// Entry is one listed transcript
+type Entry struct {
+	Episode string `json:"episode"`
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+}
+
+// CrawlTotals adds up the show reports of a crawl
+type CrawlTotals struct {
+	Downloaded, Skipped, Pending, Filtered, Deferred, Left, Failed int
+	Media, MediaFailed                                             int
+}
+
+// Show returns the report of a show, nil if it was not crawled
+func (r *CrawlReport) Show(prefix string) *ShowReport {
+	for _, s := range r.Shows {
+		if s.Prefix == prefix {
+			return s
+		}
+	}
+	return nil
+}
+
+// Totals adds up the show reports
+func (r *CrawlReport) Totals() CrawlTotals {
+	var t CrawlTotals
+	for _, s := range r.Shows {
+		t.Downloaded += len(s.Downloaded)
+		t.Skipped += len(s.Skipped)
+		t.Pending += len(s.Pending)
+		t.Filtered += len(s.Filtered)
+		t.Deferred += len(s.Deferred)
+		t.Left += len(s.Left)
+		t.Failed += len(s.Failed)
+		t.Media += len(s.Media)
+		t.MediaFailed += len(s.MediaFailed)
+	}
+	return t
+}
+
+// Run crawls and returns what it did
+func (c *Crawler) Run() *CrawlReport {
+	c.report = &CrawlReport{Started: time.Now().UTC(), Unknown: UnknownShows{}}
+	c.targets = make(map[string]bool)
+	for _, prefix := range c.Shows {
+		c.targets[prefix] = true
+		c.report.Shows = append(c.report.Shows, &ShowReport{Prefix: prefix})
+	}
+	c.media = c.WithMedia
+	c.Schedule.Reset()
+	if c.Pages <= 0 {
+		c.Pages = 1
+	}
+
+	discovery := c.Discovery
+	if discovery == "" || (discovery == "auto" && config.ActiveSource != nil) {
+		discovery = "list"
+	}
+	// The site search is only worth it when a few shows are wanted; "auto"
+	// falls back to the listing if the search turns up nothing
+	if discovery == "search" || discovery == "auto" {
+		if found := c.search(); found == 0 && discovery == "auto" && !c.stopping() {
+			fmt.Println("Search found no transcripts. Falling back to the full listing.")
+			discovery = "list"
+		} else {
+			discovery = "search"
+		}
+	}
+	if discovery == "list" {
+		c.list()
+	}
+	c.report.Discovery = discovery
+	c.report.Finished = time.Now().UTC()
+	return c.report
+}
+
+// stopping reports whether the crawl should end
+func (c *Crawler) stopping() bool {
+	return c.Report.ShouldStop() || Bandwidth.Exhausted() || Quota.Full() || (c.Stop != nil && c.Stop())
+}
+
+// progress reports what the crawler is about to do
+func (c *Crawler) progress(detail string) {
+	if c.Progress != nil {
+		c.Progress(detail)
+	}
+}
+
+// fail records a failure, except for downloads cut off by the transfer
+// budget or archive size limit, which a later run picks up anyway. It
+// reports whether the failure was recorded.
+func (c *Crawler) fail(failed *[]errs.Failure, target string, err error) bool {
+	if errors.Is(err, ErrTransferBudget) || errors.Is(err, ErrQuota) {
+		return false
+	}
+	c.Report.Add(target, err)
+	*failed = append(*failed, errs.Failure{Target: target, Class: errs.Class(err), Error: err.Error(), Time: time.Now().UTC()})
+	return true
+}
+
+// search looks for the shows' transcripts with the site search, and
+// returns the number found
+func (c *Crawler) search() int {
+	found := 0
+	for i, prefix := range c.Shows {
+		if c.stopping() {
+			c.report.Stopped = true
+			c.report.Unsearched = c.Shows[i:]
+			break
+		}
+		c.progress("searching " + prefix)
+		items, pages, err := SearchShow(prefix, c.Pages, c.Throttle)
+		c.report.PagesScanned += pages
+		c.report.PagesDownloaded += pages
+		if err != nil {
+			fmt.Printf("Search failed for %s: %v\n", prefix, err)
+		}
+		found += len(items)
+		for _, item := range items {
+			c.handle(item)
+		}
+	}
+	return found
+}
+
+// list pages through the listing a batch of pages at a time, handling each
+// batch in page order. With priorities the whole listing is read first, so
+// that the shows that matter most are downloaded before any budget runs
+// out.
+func (c *Crawler) list() {
+	workers := c.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	early := EarlyStop{After: c.StopAfterEmpty}
+	known := 0 // Pages in a row with nothing new
+	var queued []Item
+	next := 1 // First listing page not scanned
+listing:
+	for first := 1; first <= c.Pages; first += workers {
+		if c.stopping() {
+			c.report.Stopped = true
+			break
+		}
+		last := first + workers - 1
+		if last > c.Pages {
+			last = c.Pages
+		}
+		c.progress(fmt.Sprintf("listing page %d", first))
+		for _, page := range FetchListPages(first, last, c.DataDir, c.Refresh, c.Throttle, workers) {
+			if c.stopping() {
+				c.report.Stopped = true
+				break listing
+			}
+			c.report.PagesScanned++
+			next = page.Num + 1
+			fmt.Printf("--- Processing Page %d ---\n", page.Num)
+
+			if page.Err != nil {
+				fmt.Printf("Failed to get content for page %d: %v. Stopping.\n", page.Num, page.Err)
+				c.fail(&c.report.Failed, ListPageURL(page.Num), page.Err)
+				next = 0
+				break listing
+			}
+			if page.Cached {
+				c.report.PagesCached++
+			} else {
+				c.report.PagesDownloaded++
+			}
+
+			items := ExtractItems(page.HTML)
+			if len(items) == 0 {
+				fmt.Printf("No items found on page %d. Stopping.\n", page.Num)
+				next = 0
+				break listing
+			}
+			fmt.Printf("Found %d items on page %d.\n", len(items), page.Num)
+
+			wanted, fresh := false, false
+			for _, item := range items {
+				prefix := config.PrefixForTitle(item.Title)
+				wanted = wanted || c.targets[prefix]
+				fresh = fresh || (c.targets[prefix] && !c.known(prefix, item))
+				if c.Schedule.Ordered() {
+					queued = append(queued, item)
+				} else {
+					c.handle(item)
+				}
+			}
+			if early.Page(wanted) {
+				fmt.Printf("No transcripts of the target shows on the last %d pages. Stopping.\n", early.After)
+				next = 0
+				break listing
+			}
+			if known++; fresh {
+				known = 0
+			}
+			if c.StopAfterKnown > 0 && known >= c.StopAfterKnown {
+				fmt.Printf("Nothing new for the target shows on the last %d pages. Stopping.\n", known)
+				next = 0
+				break listing
+			}
+			if c.Schedule.Full(c.Shows) {
+				fmt.Println("Every target show has reached its quota. Stopping.")
+				next = 0
+				break listing
+			}
+		}
+	}
+	if next > c.Pages {
+		next = 0
+	}
+	c.report.NextPage = next
+	if len(queued) > 0 {
+		fmt.Printf("Downloading %d listed transcripts in priority order: %s first\n", len(queued), strings.Join(c.Schedule.Order, ", "))
+		c.Schedule.SortItems(queued)
+		for _, item := range queued {
+			c.handle(item)
+		}
+	}
+}
+
+// known reports whether a listed transcript needs no download: it is
+// archived (and not pending), archived from its URL under another name, or
+// outside the episode range
+func (c *Crawler) known(prefix string, item Item) bool {
+	epNum := TitleEpisode(item.Title)
+	if n, err := strconv.Atoi(epNum); err == nil && !c.Filter.MatchEpisode(n) {
+		return true
+	}
+	if _, ok := archivedAt(config.SiteURL()+item.URL, c.DataDir); ok {
+		return true
+	}
+	return Archived(prefix, epNum, c.DataDir) && !pending(prefix, epNum, c.DataDir)
+}
+
+// handle downloads a listed transcript if it belongs to one of the shows
+func (c *Crawler) handle(item Item) {
+	prefix := config.PrefixForTitle(item.Title)
+	show := c.report.Show(prefix)
+	entry := Entry{Episode: TitleEpisode(item.Title), Title: item.Title, URL: config.SiteURL() + item.URL}
+	if c.stopping() {
+		c.report.Stopped = true
+		if show != nil {
+			show.Left = append(show.Left, entry)
+		}
+		return
+	}
+	c.report.Found++
+	if prefix == "" {
+		c.report.Unknown.Add(item.Title)
+	}
+	if show == nil {
+		c.report.Ignored++
+		return
+	}
+	if !c.Schedule.Admit(prefix, item.Title, c.DataDir) {
+		show.Deferred = append(show.Deferred, entry)
+		return
+	}
+	c.progress(item.Title)
+	skipped, err := DownloadTranscriptWithFilter(item.URL, item.Title, prefix, c.DataDir, c.Throttle, c.Filter)
+	switch {
+	case errors.Is(err, ErrFiltered):
+		show.Filtered = append(show.Filtered, entry)
+	case errors.Is(err, ErrPending):
+		fmt.Printf("No transcript yet for %s; will check again next run\n", item.Title)
+		show.Pending = append(show.Pending, entry)
+	case err != nil:
+		fmt.Printf("Error downloading %s: %v\n", item.Title, err)
+		if !c.fail(&show.Failed, entry.URL, err) {
+			show.Left = append(show.Left, entry)
+		}
+	case skipped:
+		show.Skipped = append(show.Skipped, entry)
+	default:
+		show.Downloaded = append(show.Downloaded, entry)
+		c.Schedule.Take(prefix)
+	}
+	if err == nil && c.media {
+		c.fetchMedia(show, entry.Episode)
+	}
+}
+
+// fetchMedia downloads an episode's audio, until the media budget runs out
+func (c *Crawler) fetchMedia(show *ShowReport, epNum string) {
+	path, err := DownloadEpisodeMedia(show.Prefix, epNum, c.DataDir, c.MediaBudget, c.Throttle)
+	switch {
+	case errors.Is(err, ErrOverBudget):
+		fmt.Println("Media budget reached. No more media will be downloaded.")
+		c.media = false
+		c.report.MediaStopped = true
+	case err != nil:
+		fmt.Printf("Error downloading media for %s %s: %v\n", show.Prefix, epNum, err)
+		c.fail(&show.MediaFailed, fmt.Sprintf("%s_%s media", show.Prefix, epNum), err)
+	case path != "":
+		show.Media = append(show.Media, path)
+	}
+}
@@ -0,0 +1,142 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/errs"
+	"github.com/aramova/twit-transcript-archiver/go/internal/utils"
+)
+
+// crawlServer serves a two-page listing and the transcripts on it, except
+// for Security Now 2, which is missing
+func crawlServer(t *testing.T) {
+	listing := map[string][][2]string{
+		"": {
+			{"/posts/sn3", "Security Now 3 Transcript"},
+			{"/posts/ww900", "Windows Weekly 900 Transcript"},
+			{"/posts/sn2", "Security Now 2 Transcript"},
+		},
+		"2": {
+			{"/posts/sn1", "Security Now 1 Transcript"},
+			{"/posts/new5", "Some New Show 5 Transcript"},
+		},
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			for _, item := range listing[r.URL.Query().Get("page")] {
+				fmt.Fprintf(w, `<div class="item summary"><h2 class="title"><a href="%s">%s</a></h2></div>`, item[0], item[1])
+			}
+		case "/posts/sn2":
+			http.NotFound(w, r)
+		default:
+			fmt.Fprintf(w, `<html><h1 class="post-title">%s</h1><div class="body textual"><p>%s</p></div></html>`, r.URL.Path, strings.Repeat("word ", 60))
+		}
+	}))
+	t.Cleanup(ts.Close)
+	savedSite, savedList := config.BaseSiteURL, config.BaseListURL
+	config.BaseSiteURL, config.BaseListURL = ts.URL, ts.URL
+	t.Cleanup(func() { config.BaseSiteURL, config.BaseListURL = savedSite, savedList })
+}
+
+func TestCrawler(t *testing.T) {
+	crawlServer(t)
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "SN_1.html"), []byte("<html></html>"), 0644)
+
+	report := errs.NewReport("test")
+	crawl := (&Crawler{Shows: []string{"SN"}, DataDir: tmpDir, Discovery: "list", Pages: 5, Report: report}).Run()
+
+	sn := crawl.Show("SN")
+	if sn == nil {
+		t.Fatal("No report for SN")
+	}
+	if len(sn.Downloaded) != 1 || sn.Downloaded[0].Episode != "3" || len(sn.Skipped) != 1 || sn.Skipped[0].Episode != "1" {
+		t.Errorf("SN = %+v", sn)
+	}
+	if len(sn.Failed) != 1 || sn.Failed[0].Class != "not_found" || report.Len() != 1 {
+		t.Errorf("Failed = %+v, report has %d", sn.Failed, report.Len())
+	}
+	if crawl.Discovery != "list" || crawl.PagesScanned != 3 || crawl.Found != 5 || crawl.Ignored != 2 || crawl.Stopped || crawl.NextPage != 0 {
+		t.Errorf("Crawl = %+v", crawl)
+	}
+	if len(crawl.Unknown) != 1 {
+		t.Errorf("Unknown = %v", crawl.Unknown)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "SN_3.html")); err != nil {
+		t.Errorf("SN 3 was not saved: %v", err)
+	}
+}
+
+func TestCrawlerStop(t *testing.T) {
+	crawlServer(t)
+	tmpDir := t.TempDir()
+
+	// Stop as soon as the first transcript is in
+	stop := func() bool {
+		_, err := os.Stat(filepath.Join(tmpDir, "SN_3.html"))
+		return err == nil
+	}
+	crawl := (&Crawler{Shows: []string{"SN"}, DataDir: tmpDir, Discovery: "list", Pages: 5, Stop: stop}).Run()
+
+	sn := crawl.Show("SN")
+	if len(sn.Downloaded) != 1 || len(sn.Left) != 1 || sn.Left[0].Episode != "2" {
+		t.Errorf("SN = %+v", sn)
+	}
+	if !crawl.Stopped || crawl.NextPage != 2 {
+		t.Errorf("Stopped = %v, NextPage = %d; want true, 2", crawl.Stopped, crawl.NextPage)
+	}
+	if tot := crawl.Totals(); tot.Downloaded != 1 || tot.Left != 1 || tot.Failed != 0 {
+		t.Errorf("Totals = %+v", tot)
+	}
+}
+
+func TestCrawlerStopAfterKnown(t *testing.T) {
+	crawlServer(t)
+	tmpDir := t.TempDir()
+	for _, name := range []string{"SN_3.html", "SN_2.html"} {
+		os.WriteFile(filepath.Join(tmpDir, name), []byte("<html></html>"), 0644)
+	}
+
+	// Nothing new on page 1, so page 2 and SN 1 on it are never read
+	crawl := (&Crawler{Shows: []string{"SN"}, DataDir: tmpDir, Discovery: "list", Pages: 5, StopAfterKnown: 1}).Run()
+	if crawl.PagesScanned != 1 || crawl.NextPage != 0 {
+		t.Errorf("PagesScanned = %d, NextPage = %d; want 1, 0", crawl.PagesScanned, crawl.NextPage)
+	}
+	if sn := crawl.Show("SN"); len(sn.Downloaded) != 0 || len(sn.Skipped) != 2 {
+		t.Errorf("SN = %+v", sn)
+	}
+
+	// A new transcript on page 1 keeps the listing going, until page 2
+	// with nothing new
+	os.Remove(filepath.Join(tmpDir, "SN_3.html"))
+	os.WriteFile(filepath.Join(tmpDir, "SN_1.html"), []byte("<html></html>"), 0644)
+	crawl = (&Crawler{Shows: []string{"SN"}, DataDir: tmpDir, Discovery: "list", Pages: 5, StopAfterKnown: 1}).Run()
+	if crawl.PagesScanned != 2 {
+		t.Errorf("PagesScanned = %d, want 2", crawl.PagesScanned)
+	}
+}
+
+func TestCrawlerDeadline(t *testing.T) {
+	crawlServer(t)
+	tmpDir := t.TempDir()
+
+	// A --max-duration that is used up stops the crawl before any request
+	deadline := utils.NewDeadline(time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	crawl := (&Crawler{Shows: []string{"SN"}, DataDir: tmpDir, Discovery: "list", Pages: 5, Stop: deadline.Passed}).Run()
+	if !crawl.Stopped || crawl.PagesScanned != 0 || crawl.NextPage != 1 {
+		t.Errorf("Stopped = %v, PagesScanned = %d, NextPage = %d; want true, 0, 1", crawl.Stopped, crawl.PagesScanned, crawl.NextPage)
+	}
+	if tot := crawl.Totals(); tot.Downloaded != 0 {
+		t.Errorf("Totals = %+v", tot)
+	}
+}
@@ -0,0 +1,79 @@
+package scraper
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/httplog"
+)
+
+// HTTPLog, when set, records every request the scraper makes with its
+// headers and timings, and the response bodies if it keeps them
+var HTTPLog *httplog.Writer
+
+// DebugBodyLimit caps the bytes of each response body HTTPLog keeps, so
+// media downloads do not end up in the log whole
+var DebugBodyLimit = 1 << 20
+
+// roundTripLogged sends a request through Transport, recording it in
+// HTTPLog once its body has been read or closed
+func roundTripLogged(req *http.Request) (*http.Response, error) {
+	x := httplog.Exchange{Started: time.Now(), Request: req}
+	resp, err := Transport.RoundTrip(req)
+	x.Wait = time.Since(x.Started)
+	if err != nil {
+		x.Err = err
+		recordExchange(x)
+		return nil, err
+	}
+	x.Response = resp
+	resp.Body = &loggedBody{ReadCloser: resp.Body, x: x, received: time.Now()}
+	return resp, nil
+}
+
+func recordExchange(x httplog.Exchange) {
+	if log := HTTPLog; log != nil {
+		log.Record(x)
+	}
+}
+
+// loggedBody records its exchange at the end of the body or when closed
+type loggedBody struct {
+	io.ReadCloser
+	x        httplog.Exchange
+	received time.Time
+	once     sync.Once
+}
+
+func (b *loggedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.x.BodySize += int64(n)
+	if log := HTTPLog; log != nil && log.Bodies && len(b.x.Body) < DebugBodyLimit {
+		keep := n
+		if room := DebugBodyLimit - len(b.x.Body); keep > room {
+			keep = room
+		}
+		b.x.Body = append(b.x.Body, p[:keep]...)
+	}
+	if err != nil {
+		if err != io.EOF {
+			b.x.Err = err
+		}
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *loggedBody) Close() error {
+	b.finish()
+	return b.ReadCloser.Close()
+}
+
+func (b *loggedBody) finish() {
+	b.once.Do(func() {
+		b.x.Receive = time.Since(b.received)
+		recordExchange(b.x)
+	})
+}
@@ -0,0 +1,46 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/httplog"
+)
+
+func TestHTTPLog(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "<html>Content</html>")
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	HTTPLog = httplog.NewWriter(&buf, false, "test")
+	HTTPLog.Bodies = true
+	defer func() { HTTPLog = nil }()
+	oldLimit := DebugBodyLimit
+	DebugBodyLimit = 10
+	defer func() { DebugBodyLimit = oldLimit }()
+
+	if _, err := DownloadPage(ts.URL+"/page", 0); err != nil {
+		t.Fatal(err)
+	}
+	DownloadPage(ts.URL+"/missing", 0)
+
+	out := buf.String()
+	if HTTPLog.Records != 2 {
+		t.Errorf("Records = %d, want 2:\n%s", HTTPLog.Records, out)
+	}
+	for _, want := range []string{"GET " + ts.URL + "/page\n", "< HTTP/1.1 200 OK\n", "20 bytes\n<html>Cont\n", "< HTTP/1.1 404 Not Found\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Log lacks %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package scraper
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// showTitleRegex captures the show name before the episode number of a
+// listing title ("Floss Weekly 800 Transcript" -> "Floss Weekly")
+var showTitleRegex = regexp.MustCompile(`^(.*?)[\s:#-]+\d+\b`)
+
+// UnknownShow is a show seen in the listing that matches no ShowMap entry
+type UnknownShow struct {
+	Name    string // Lowercase title segment
+	Count   int    // Transcripts seen
+	Example string // One full listing title
+}
+
+// UnknownShows collects the listing titles that match no known show
+type UnknownShows map[string]*UnknownShow
+
+// ShowTitle returns the lowercase show name of a listing title, or "" if
+// the title has no episode number to split on
+func ShowTitle(title string) string {
+	m := showTitleRegex.FindStringSubmatch(strings.TrimSpace(title))
+	if m == nil {
+		return ""
+	}
+	return strings.ToLower(strings.Trim(m[1], " :-#|"))
+}
+
+// Add records an unmatched listing title
+func (u UnknownShows) Add(title string) {
+	name := ShowTitle(title)
+	if name == "" {
+		return
+	}
+	s, ok := u[name]
+	if !ok {
+		s = &UnknownShow{Name: name, Example: title}
+		u[name] = s
+	}
+	s.Count++
+}
+
+// Sorted returns the unknown shows, most transcripts first
+func (u UnknownShows) Sorted() []*UnknownShow {
+	shows := make([]*UnknownShow, 0, len(u))
+	for _, s := range u {
+		shows = append(shows, s)
+	}
+	sort.Slice(shows, func(i, j int) bool {
+		if shows[i].Count != shows[j].Count {
+			return shows[i].Count > shows[j].Count
+		}
+		return shows[i].Name < shows[j].Name
+	})
+	return shows
+}
@@ -0,0 +1,25 @@
+package scraper
+
+import "testing"
+
+func TestUnknownShows(t *testing.T) {
+	u := UnknownShows{}
+	for _, title := range []string{
+		"This Week in Enterprise Tech 500 Transcript",
+		"This Week in Enterprise Tech 501 Transcript",
+		"Tech News Weekly: 300 Transcript",
+		"Year in Review Transcript",
+	} {
+		u.Add(title)
+	}
+	shows := u.Sorted()
+	if len(shows) != 2 {
+		t.Fatalf("Expected 2 unknown shows, got %+v", shows)
+	}
+	if shows[0].Name != "this week in enterprise tech" || shows[0].Count != 2 {
+		t.Errorf("Unexpected first show: %+v", shows[0])
+	}
+	if shows[1].Name != "tech news weekly" {
+		t.Errorf("Expected the colon to be trimmed, got %q", shows[1].Name)
+	}
+}
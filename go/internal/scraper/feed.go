@@ -0,0 +1,80 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/chapters"
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+// ErrArchived is returned by IngestFeedItem for episodes already archived
+var ErrArchived = errors.New("already archived")
+
+// ErrNoTranscript is returned by IngestFeedItem for items that link no
+// transcript
+var ErrNoTranscript = errors.New("no podcast:transcript link")
+
+// captionPreference ranks transcript formats: speaker-labelled and timed
+// ones first, plain text last
+var captionPreference = []string{converter.CaptionsJSON, converter.CaptionsVTT, converter.CaptionsSRT, converter.CaptionsHTML, converter.CaptionsText}
+
+// PreferredTranscript picks which of an item's transcripts to ingest, by
+// captionPreference and then feed order
+func PreferredTranscript(ts []chapters.Transcript) (chapters.Transcript, bool) {
+	for _, format := range captionPreference {
+		for _, t := range ts {
+			if converter.CaptionFormat(t.Type, t.URL) == format {
+				return t, true
+			}
+		}
+	}
+	return chapters.Transcript{}, false
+}
+
+// IngestFeedItem downloads the transcript a podcast feed item links with
+// podcast:transcript, renders it as a transcript page (see
+// converter.CaptionsPage) with its speaker labels named (see
+// ReconcileSpeakers) and ingests it. The show comes from opts.Prefix
+// or the item title, the episode number from opts.Episode or the item.
+// Archived episodes are skipped with ErrArchived unless opts.Force is set.
+func IngestFeedItem(item chapters.Item, dataDir string, throttle time.Duration, opts IngestOptions) (string, error) {
+	t, ok := PreferredTranscript(item.Transcripts)
+	if !ok {
+		return "", ErrNoTranscript
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = config.PrefixForTitle(item.Title)
+	}
+	if opts.Prefix == "" {
+		return "", fmt.Errorf("cannot tell the show from title %q (use --show)", item.Title)
+	}
+	if opts.Episode == 0 {
+		opts.Episode = item.Episode
+	}
+	if opts.Episode == 0 {
+		return "", fmt.Errorf("cannot tell the episode number of %q", item.Title)
+	}
+	if !opts.Force && Archived(opts.Prefix, strconv.Itoa(opts.Episode), dataDir) {
+		return "", ErrArchived
+	}
+	if !strings.HasPrefix(t.URL, "http://") && !strings.HasPrefix(t.URL, "https://") {
+		return "", fmt.Errorf("invalid transcript URL '%s'", t.URL)
+	}
+
+	data, err := DownloadPage(t.URL, throttle)
+	if err != nil {
+		return "", err
+	}
+	cues, err := converter.ParseCaptions([]byte(data), converter.CaptionFormat(t.Type, t.URL))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", t.URL, err)
+	}
+	ReconcileSpeakers(cues, opts.Prefix, dataDir)
+	opts.URL = t.URL
+	return IngestPage(converter.CaptionsPage(item.Title, item.Published, converter.OriginFeed, cues), dataDir, opts)
+}
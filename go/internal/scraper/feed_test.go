@@ -0,0 +1,76 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/chapters"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+func TestPreferredTranscript(t *testing.T) {
+	ts := []chapters.Transcript{
+		{URL: "https://example.com/1.txt", Type: "text/plain"},
+		{URL: "https://example.com/1.srt"},
+		{URL: "https://example.com/1.vtt", Type: "text/vtt"},
+	}
+	if got, ok := PreferredTranscript(ts); !ok || got.URL != "https://example.com/1.vtt" {
+		t.Errorf("PreferredTranscript = %+v, %v", got, ok)
+	}
+	if _, ok := PreferredTranscript(nil); ok {
+		t.Error("Expected no transcript for an item without links")
+	}
+}
+
+func TestIngestFeedItem(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "twittest")
+	defer os.RemoveAll(tmpDir)
+
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, "WEBVTT\n\n00:00:01.000 --> 00:00:03.000\n<v Leo Laporte>It's time for Security Now.\n")
+	}))
+	defer ts.Close()
+
+	item := chapters.Item{
+		Title:       "Security Now 1000: The Big One",
+		Episode:     1000,
+		Published:   time.Date(2024, 11, 19, 20, 0, 0, 0, time.UTC),
+		Transcripts: []chapters.Transcript{{URL: ts.URL + "/sn1000.vtt", Type: "text/vtt"}},
+	}
+	path, err := IngestFeedItem(item, tmpDir, 0, IngestOptions{})
+	if err != nil || filepath.Base(path) != "SN_1000.html" {
+		t.Fatalf("IngestFeedItem = %s, %v", path, err)
+	}
+	data, _ := os.ReadFile(path)
+	page := string(data)
+	if converter.PageTitle(page) != item.Title || !strings.Contains(page, "00:00:01 - Leo Laporte") {
+		t.Errorf("Saved page:\n%s", page)
+	}
+	if src, _, ok := converter.PageSource(page); !ok || src != item.Transcripts[0].URL {
+		t.Errorf("Source = %q, %v", src, ok)
+	}
+
+	if _, err := IngestFeedItem(item, tmpDir, 0, IngestOptions{}); !errors.Is(err, ErrArchived) || requests != 1 {
+		t.Errorf("Second ingest = %v after %d requests, want ErrArchived without downloading", err, requests)
+	}
+	if _, err := IngestFeedItem(item, tmpDir, 0, IngestOptions{Force: true}); err != nil || requests != 2 {
+		t.Errorf("Forced ingest = %v after %d requests", err, requests)
+	}
+	if _, err := IngestFeedItem(chapters.Item{Title: item.Title}, tmpDir, 0, IngestOptions{}); !errors.Is(err, ErrNoTranscript) {
+		t.Errorf("Item without transcripts = %v, want ErrNoTranscript", err)
+	}
+	item.Episode = 0
+	item.Title = "Bonus"
+	if _, err := IngestFeedItem(item, tmpDir, 0, IngestOptions{Prefix: "SN"}); err == nil {
+		t.Error("Expected an error without an episode number")
+	}
+}
@@ -0,0 +1,162 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/errs"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// Gaps returns the episode numbers missing between the lowest and highest
+// of nums, which must be sorted
+func Gaps(nums []int) []int {
+	var gaps []int
+	for i := 1; i < len(nums); i++ {
+		for n := nums[i-1] + 1; n < nums[i]; n++ {
+			gaps = append(gaps, n)
+		}
+	}
+	return gaps
+}
+
+// FormatGaps renders episode numbers compactly ("312, 519-521")
+func FormatGaps(nums []int) string {
+	var parts []string
+	for i := 0; i < len(nums); {
+		j := i
+		for j+1 < len(nums) && nums[j+1] == nums[j]+1 {
+			j++
+		}
+		if j > i {
+			parts = append(parts, fmt.Sprintf("%d-%d", nums[i], nums[j]))
+		} else {
+			parts = append(parts, strconv.Itoa(nums[i]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
+
+// TranscriptURL guesses the site URL of an episode's transcript, e.g.
+// https://twit.tv/posts/transcripts/security-now-950-transcript
+func TranscriptURL(prefix string, ep int) string {
+	slug := strings.ReplaceAll(config.ShowName(prefix), " ", "-")
+	return fmt.Sprintf("%s/posts/transcripts/%s-%d-transcript", config.BaseSiteURL, slug, ep)
+}
+
+// FindTranscript looks for a single episode without paging the listing. It
+// tries the guessed URL, then the site search, then the Wayback Machine's
+// copy of the guessed URL, and returns the page with the source that found it.
+func FindTranscript(prefix string, ep int, throttle time.Duration) (string, string, error) {
+	html, source, _, _, err := findTranscript(prefix, ep, throttle)
+	return html, source, err
+}
+
+// findTranscript is FindTranscript, also returning the URL the page was
+// requested from and the one it was served from after redirects
+func findTranscript(prefix string, ep int, throttle time.Duration) (string, string, string, string, error) {
+	if config.ActiveSource != nil {
+		return "", "", "", "", fmt.Errorf("looking up single episodes only works on %s, not source %s", config.BaseSiteURL, config.ActiveSource.Name)
+	}
+	guess := TranscriptURL(prefix, ep)
+	if html, final, err := probePage(guess, throttle); err == nil && isTranscriptFor(html, prefix, ep) {
+		return html, "direct", guess, final, nil
+	}
+
+	query := fmt.Sprintf("%s %d transcript", config.ShowName(prefix), ep)
+	if html, _, err := probePage(fmt.Sprintf(config.SearchURL, url.QueryEscape(query)), throttle); err == nil {
+		for _, item := range ExtractItems(html) {
+			if config.PrefixForTitle(item.Title) != prefix || TitleEpisode(item.Title) != strconv.Itoa(ep) {
+				continue
+			}
+			if page, final, err := probePage(config.BaseSiteURL+item.URL, throttle); err == nil && isTranscriptFor(page, prefix, ep) {
+				return page, "search", config.BaseSiteURL + item.URL, final, nil
+			}
+		}
+	}
+
+	if snapshot, err := waybackSnapshot(guess, throttle); err == nil && snapshot != "" {
+		if html, final, err := probePage(snapshot, throttle); err == nil && isTranscriptFor(html, prefix, ep) {
+			return html, "wayback", snapshot, final, nil
+		}
+	}
+
+	return "", "", "", "", fmt.Errorf("%w: %s %d", errs.ErrNotFound, prefix, ep)
+}
+
+// FillGap finds and saves a missing episode, returning where it was found.
+// The page is checked and recorded as downloads from the listing are: a
+// page archived under another name already returns ErrDuplicate.
+func FillGap(prefix string, ep int, dataDir string, throttle time.Duration) (string, error) {
+	html, source, pageURL, final, err := findTranscript(prefix, ep, throttle)
+	if err != nil {
+		return "", err
+	}
+	if err := converter.CheckPage(html); err != nil {
+		return "", err
+	}
+	filename := storage.Join(config.ActiveLayout.RawDir(dataDir, prefix), fmt.Sprintf("%s_%d.html", prefix, ep))
+	return source, storeTranscript(html, pageURL, final, filename, dataDir, false)
+}
+
+// isTranscriptFor checks that a page is the transcript of the given episode
+func isTranscriptFor(html, prefix string, ep int) bool {
+	title := converter.PageTitle(html)
+	return config.PrefixForTitle(title) == prefix && TitleEpisode(title) == strconv.Itoa(ep)
+}
+
+// probePage fetches a URL once, returning the page and the URL it was served
+// from after redirects. Unlike DownloadPage it does not retry, since most
+// guesses are expected to miss.
+func probePage(u string, throttle time.Duration) (string, string, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return "", "", err
+	}
+	identify(req)
+	resp, err := client.Do(req)
+	defer pace(throttle)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.Request != nil {
+		req = resp.Request
+	}
+	body, err := readBody(req, resp)
+	if resp.StatusCode != 200 {
+		return "", "", statusError(resp.StatusCode)
+	}
+	return string(body), req.URL.String(), err
+}
+
+// waybackSnapshot returns the URL of the closest archived copy of a page
+func waybackSnapshot(pageURL string, throttle time.Duration) (string, error) {
+	body, _, err := probePage(config.WaybackAPI+"?url="+url.QueryEscape(pageURL), throttle)
+	if err != nil {
+		return "", err
+	}
+	var res struct {
+		ArchivedSnapshots struct {
+			Closest struct {
+				Available bool   `json:"available"`
+				URL       string `json:"url"`
+			} `json:"closest"`
+		} `json:"archived_snapshots"`
+	}
+	if err := json.Unmarshal([]byte(body), &res); err != nil {
+		return "", err
+	}
+	if !res.ArchivedSnapshots.Closest.Available {
+		return "", nil
+	}
+	return res.ArchivedSnapshots.Closest.URL, nil
+}
@@ -0,0 +1,98 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/errs"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+)
+
+func TestGaps(t *testing.T) {
+	gaps := Gaps([]int{310, 311, 313, 314, 318})
+	if want := []int{312, 315, 316, 317}; !reflect.DeepEqual(gaps, want) {
+		t.Errorf("Gaps = %v, want %v", gaps, want)
+	}
+	if s := FormatGaps(gaps); s != "312, 315-317" {
+		t.Errorf("FormatGaps = %q", s)
+	}
+	if Gaps([]int{5}) != nil {
+		t.Error("Expected no gaps for a single episode")
+	}
+}
+
+func TestFindTranscript(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "twittest")
+	defer os.RemoveAll(tmpDir)
+
+	page := func(ep int) string {
+		return fmt.Sprintf(`<h1 class="post-title">Security Now %d Transcript</h1>`, ep) + transcriptBody
+	}
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/posts/transcripts/security-now-1-transcript":
+			fmt.Fprint(w, page(1))
+		case "/search":
+			fmt.Fprint(w, `<div class="item summary"><h2 class="title"><a href="/posts/sn2">Security Now 2 Transcript</a></h2></div>`)
+		case "/posts/sn2":
+			fmt.Fprint(w, page(2))
+		case "/posts/transcripts/security-now-5-transcript":
+			// Episode 1 again, republished and misnumbered
+			fmt.Fprintf(w, `<link rel="canonical" href="%s/posts/transcripts/security-now-1-transcript">`+page(5), ts.URL)
+		case "/posts/transcripts/security-now-6-transcript":
+			fmt.Fprint(w, `<html>`+page(6)) // Cut short
+		case "/wayback":
+			if r.URL.Query().Get("url") == config.BaseSiteURL+"/posts/transcripts/security-now-3-transcript" {
+				fmt.Fprintf(w, `{"archived_snapshots":{"closest":{"available":true,"url":"%s/web/sn3"}}}`, ts.URL)
+				return
+			}
+			fmt.Fprint(w, `{"archived_snapshots":{}}`)
+		case "/web/sn3":
+			fmt.Fprint(w, page(3))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	savedSite, savedSearch, savedWayback := config.BaseSiteURL, config.SearchURL, config.WaybackAPI
+	config.BaseSiteURL, config.SearchURL, config.WaybackAPI = ts.URL, ts.URL+"/search?q=%s", ts.URL+"/wayback"
+	defer func() { config.BaseSiteURL, config.SearchURL, config.WaybackAPI = savedSite, savedSearch, savedWayback }()
+
+	for ep, want := range map[int]string{1: "direct", 2: "search", 3: "wayback"} {
+		source, err := FillGap("SN", ep, tmpDir, 0)
+		if err != nil || source != want {
+			t.Errorf("FillGap(%d) = %q, %v, want %q", ep, source, err, want)
+		}
+		if _, err := os.Stat(filepath.Join(tmpDir, fmt.Sprintf("SN_%d.html", ep))); err != nil {
+			t.Errorf("SN_%d.html not saved", ep)
+		}
+	}
+
+	if _, err := FillGap("SN", 4, tmpDir, 0); err == nil {
+		t.Error("Expected an error for an episode that cannot be found")
+	}
+
+	// Gap-filled pages are recorded in the index like any download
+	ix, _ := index.Load(tmpDir)
+	if e := ix.Entries["SN_1"]; e == nil || e.URL != ts.URL+"/posts/transcripts/security-now-1-transcript" {
+		t.Errorf("SN_1 = %+v", e)
+	}
+	if _, err := FillGap("SN", 5, tmpDir, 0); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("FillGap(5) = %v, want ErrDuplicate", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "SN_5.html")); err == nil {
+		t.Error("The duplicate was saved as SN_5.html")
+	}
+	if _, err := FillGap("SN", 6, tmpDir, 0); !errors.Is(err, errs.ErrTruncated) {
+		t.Errorf("FillGap(6) = %v, want a truncated page", err)
+	}
+}
@@ -0,0 +1,51 @@
+package scraper
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+)
+
+// Run "go test ./internal/scraper -run TestGolden -update" after an intended
+// change to listing extraction, and review the diff of testdata
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestGoldenListPage extracts the items of a listing page that follows the
+// twit.tv markup and compares them, with the show and episode each maps to,
+// against testdata/transcripts_page.txt
+func TestGoldenListPage(t *testing.T) {
+	page := filepath.Join("testdata", "transcripts_page.html")
+	html, err := os.ReadFile(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	for _, item := range ExtractItems(string(html)) {
+		prefix := config.PrefixForTitle(item.Title)
+		if prefix == "" {
+			prefix = "?"
+		}
+		fmt.Fprintf(&b, "%-5s %-4s %s | %s\n", prefix, TitleEpisode(item.Title), item.Title, item.URL)
+	}
+	got := b.String()
+
+	golden := strings.TrimSuffix(page, ".html") + ".txt"
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("Items extracted from %s drifted from %s:\ngot:\n%s\nwant:\n%s", page, golden, got, want)
+	}
+}
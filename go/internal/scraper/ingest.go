@@ -0,0 +1,105 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// IngestOptions controls how a single transcript page is added to the archive
+type IngestOptions struct {
+	// Prefix and Episode override what is read from the page title
+	Prefix  string
+	Episode int
+	// Force replaces an archived copy of the episode, if the new copy
+	// ranks as high by config.Precedence; the other copy is kept as an
+	// alternative
+	Force bool
+	// URL is where the page was downloaded from, recorded in the saved copy
+	// (see converter.StampSource); empty for pages saved by hand
+	URL string
+}
+
+// IngestPage saves a transcript page under its archive name, working out
+// the show and episode number from the page title. Replacing an archived
+// copy, the better of the two by config.Precedence becomes the episode's
+// transcript and the other an alternative (see converter.AltDir). Returns
+// the saved path.
+func IngestPage(html, dataDir string, opts IngestOptions) (string, error) {
+	title := converter.PageTitle(html)
+	prefix := opts.Prefix
+	if prefix == "" {
+		prefix = config.PrefixForTitle(title)
+	}
+	if prefix == "" {
+		return "", fmt.Errorf("cannot tell the show from title %q (use --show)", title)
+	}
+	ep := opts.Episode
+	if ep == 0 {
+		ep, _ = strconv.Atoi(TitleEpisode(title))
+	}
+	if ep == 0 {
+		return "", fmt.Errorf("cannot tell the episode number from title %q (use --episode)", title)
+	}
+
+	filename := storage.Join(config.ActiveLayout.RawDir(dataDir, prefix), fmt.Sprintf("%s_%d.html", prefix, ep))
+	exists := storage.Exists(filename)
+	if !opts.Force && exists {
+		return filename, fmt.Errorf("%s already exists (use --force to replace it)", filename)
+	}
+	now := time.Now()
+	if opts.URL != "" {
+		html = converter.StampSource(html, opts.URL, now)
+	}
+	if exists {
+		old, err := storage.ReadFile(filename)
+		if err != nil {
+			return "", err
+		}
+		// A page saved by hand has no stamp, but is the newest copy
+		next := converter.Copy{Kind: converter.PageKind(html), Fetched: now.UTC()}
+		if cur := converter.PageCopy(filename, string(old)); !cur.Placeholder && cur.Outranks(next) {
+			alt := converter.AltPath(dataDir, prefix, ep, next)
+			if err := saveFile(alt, []byte(html)); err != nil {
+				return "", err
+			}
+			return alt, fmt.Errorf("%w (%s copy of %s %d kept as %s)", ErrOutranked, next.Kind, prefix, ep, alt)
+		}
+		if _, err := converter.SetAside(dataDir, prefix, ep, filename); err != nil {
+			return "", err
+		}
+	}
+	return filename, saveFile(filename, []byte(html))
+}
+
+// ErrOutranked is returned by IngestPage when the archived copy of the
+// episode comes first in config.Precedence, so the new one was only kept as
+// an alternative
+var ErrOutranked = errors.New("the archived copy takes precedence")
+
+// FetchURL downloads a single transcript page, given as a full URL or a
+// site path, and ingests it
+func FetchURL(u, dataDir string, throttle time.Duration, opts IngestOptions) (string, error) {
+	if strings.HasPrefix(u, "/") {
+		u = config.SiteURL() + u
+	}
+	if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+		return "", fmt.Errorf("invalid transcript URL '%s'", u)
+	}
+	fmt.Printf("Downloading %s\n", u)
+	html, err := DownloadPage(u, throttle)
+	if err != nil {
+		return "", err
+	}
+	if err := converter.CheckPage(html); err != nil {
+		return "", err
+	}
+	opts.URL = u
+	return IngestPage(html, dataDir, opts)
+}
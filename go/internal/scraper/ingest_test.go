@@ -0,0 +1,83 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+func TestIngestPage(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "twittest")
+	defer os.RemoveAll(tmpDir)
+
+	html := `<h1 class="post-title">Windows Weekly 900 Transcript</h1>`
+	path, err := IngestPage(html, tmpDir, IngestOptions{})
+	if err != nil || path != filepath.Join(tmpDir, "WW_900.html") {
+		t.Fatalf("IngestPage = %s, %v", path, err)
+	}
+	if _, err := IngestPage(html, tmpDir, IngestOptions{}); err == nil {
+		t.Error("Expected an error when the episode is already archived")
+	}
+	if _, err := IngestPage(html, tmpDir, IngestOptions{Force: true}); err != nil {
+		t.Errorf("Force ingest failed: %v", err)
+	}
+
+	// A placeholder is replaced; a transcript is kept as an alternative
+	// unless it outranks the new copy
+	page := `<h1 class="post-title">Windows Weekly 900 Transcript</h1><div class="body textual"><p>` + strings.Repeat("word ", 60) + `</p></div>`
+	if _, err := IngestPage(page, tmpDir, IngestOptions{Force: true, URL: "https://twit.tv/ww-900"}); err != nil {
+		t.Fatal(err)
+	}
+	if alts, _ := converter.Alternatives(tmpDir, "WW"); len(alts) != 0 {
+		t.Errorf("Placeholder kept as an alternative: %v", alts)
+	}
+	captions := converter.CaptionsPage("Windows Weekly 900", time.Now(), converter.OriginCaptions, []converter.Cue{{Text: "Hello"}})
+	alt, err := IngestPage(captions, tmpDir, IngestOptions{Force: true})
+	if !errors.Is(err, ErrOutranked) || !strings.Contains(alt, converter.AltDir) {
+		t.Errorf("Captions over a site page = %s, %v, want ErrOutranked", alt, err)
+	}
+	if _, err := IngestPage(page, tmpDir, IngestOptions{Force: true, URL: "https://twit.tv/ww-900"}); err != nil {
+		t.Errorf("Newer revision = %v", err)
+	}
+	if alts, _ := converter.Alternatives(tmpDir, "WW"); len(alts[900]) != 2 || alts[900][0].Kind != converter.KindSite {
+		t.Errorf("Alternatives = %+v", alts)
+	}
+
+	if _, err := IngestPage(`<h1 class="post-title">Special Episode</h1>`, tmpDir, IngestOptions{}); err == nil {
+		t.Error("Expected an error for an unknown show")
+	}
+	path, err = IngestPage(`<h1 class="post-title">Special Episode</h1>`, tmpDir, IngestOptions{Prefix: "TWIT", Episode: 5})
+	if err != nil || filepath.Base(path) != "TWIT_5.html" {
+		t.Errorf("IngestPage with overrides = %s, %v", path, err)
+	}
+}
+
+func TestFetchURL(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "twittest")
+	defer os.RemoveAll(tmpDir)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<h1 class="post-title">Security Now 1000 Transcript</h1>`)
+	}))
+	defer ts.Close()
+	saved := config.BaseSiteURL
+	config.BaseSiteURL = ts.URL
+	defer func() { config.BaseSiteURL = saved }()
+
+	path, err := FetchURL("/posts/transcripts/security-now-1000-transcript", tmpDir, 0, IngestOptions{})
+	if err != nil || filepath.Base(path) != "SN_1000.html" {
+		t.Errorf("FetchURL = %s, %v", path, err)
+	}
+	if _, err := FetchURL("ftp://example.com/x", tmpDir, 0, IngestOptions{}); err == nil {
+		t.Error("Expected an error for a non-HTTP URL")
+	}
+}
@@ -0,0 +1,203 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// ListCacheFile records when each listing page was downloaded
+const ListCacheFile = "list_cache.json"
+
+// Forever is a cache TTL that never expires
+const Forever time.Duration = -1
+
+// CacheRule sets how long cached listing pages From..To stay fresh. A zero
+// To has no upper bound; a zero TTL always re-downloads.
+type CacheRule struct {
+	From, To int
+	TTL      time.Duration
+}
+
+// CachePolicy is a list of rules; the first rule covering a page applies,
+// and pages no rule covers are cached forever
+type CachePolicy []CacheRule
+
+// DefaultCachePolicy re-downloads the first five pages, where new episodes
+// appear, and keeps older pages, which rarely change
+var DefaultCachePolicy = CachePolicy{{From: 1, To: 5, TTL: 0}, {From: 6, TTL: Forever}}
+
+// ListCache is the policy GetListPageWithCacheStatus follows
+var ListCache = DefaultCachePolicy
+
+// ParseCachePolicy parses --cache-ttl: comma-separated "RANGE=TTL" rules
+// ("1-5=1h,6-=720h") where RANGE is as for --episodes, or a bare TTL for all
+// pages ("24h"). A TTL is a Go duration (with "d" for days allowed) or
+// "never" for pages that never expire. Rules are checked in order.
+func ParseCachePolicy(s string) (CachePolicy, error) {
+	var p CachePolicy
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		rule := CacheRule{From: 1}
+		ttl := entry
+		if pages, d, ok := strings.Cut(entry, "="); ok {
+			from, to, err := converter.ParseEpisodeRange(pages)
+			if err != nil {
+				return nil, fmt.Errorf("invalid page range in --cache-ttl '%s'", entry)
+			}
+			if from > 0 {
+				rule.From = from
+			}
+			rule.To, ttl = to, d
+		}
+		d, err := parseTTL(ttl)
+		if err != nil {
+			return nil, err
+		}
+		rule.TTL = d
+		p = append(p, rule)
+	}
+	return p, nil
+}
+
+func parseTTL(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "never" || s == "forever" {
+		return Forever, nil
+	}
+	d, err := ParseAge(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cache TTL '%s' (want e.g. 0, 30m, 24h, 7d or never)", s)
+	}
+	return d, nil
+}
+
+// ParseAge parses a non-negative Go duration, also allowing days ("90d")
+func ParseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasSuffix(s, "d") {
+		if n, err := strconv.ParseFloat(strings.TrimSuffix(s, "d"), 64); err == nil && n >= 0 {
+			return time.Duration(n * float64(24*time.Hour)), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age '%s' (want e.g. 12h or 90d)", s)
+	}
+	return d, nil
+}
+
+// TTL returns how long a cached copy of a page stays fresh
+func (p CachePolicy) TTL(page int) time.Duration {
+	for _, r := range p {
+		if page >= r.From && (r.To == 0 || page <= r.To) {
+			return r.TTL
+		}
+	}
+	return Forever
+}
+
+// fetchLog is the in-memory copy of a data directory's ListCacheFile
+type fetchLog struct {
+	mu    sync.Mutex
+	path  string
+	times map[string]time.Time
+}
+
+var (
+	fetchLogsMu sync.Mutex
+	fetchLogs   = make(map[string]*fetchLog)
+)
+
+// listFetchLog loads (once) the fetch times of a listing directory
+func listFetchLog(dataDir string) *fetchLog {
+	path := storage.Join(config.ListPageDir(dataDir), ListCacheFile)
+	fetchLogsMu.Lock()
+	defer fetchLogsMu.Unlock()
+	if l, ok := fetchLogs[path]; ok {
+		return l
+	}
+	l := &fetchLog{path: path, times: make(map[string]time.Time)}
+	if data, err := storage.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &l.times); err != nil {
+			fmt.Printf("Warning: ignoring unreadable %s: %v\n", path, err)
+		}
+	}
+	fetchLogs[path] = l
+	return l
+}
+
+// fetched returns when a page was downloaded. Pages cached before fetch
+// times were recorded fall back to the file's modification time.
+func (l *fetchLog) fetched(page int, filename string) (time.Time, bool) {
+	l.mu.Lock()
+	t, ok := l.times[strconv.Itoa(page)]
+	l.mu.Unlock()
+	if ok {
+		return t, true
+	}
+	if !storage.IsRemote(filename) {
+		if info, err := os.Stat(filename); err == nil {
+			return info.ModTime(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// record saves the download time of a page
+func (l *fetchLog) record(page int, at time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.times[strconv.Itoa(page)] = at.UTC()
+	data, err := json.MarshalIndent(l.times, "", "  ")
+	if err != nil {
+		return err
+	}
+	return storage.WriteFile(l.path, data)
+}
+
+// forget drops the fetch times of removed pages
+func (l *fetchLog) forget(pages []int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, page := range pages {
+		delete(l.times, strconv.Itoa(page))
+	}
+	if len(l.times) == 0 {
+		if err := storage.Remove(l.path); err != nil && !errors.Is(err, storage.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(l.times, "", "  ")
+	if err != nil {
+		return err
+	}
+	return storage.WriteFile(l.path, data)
+}
+
+// listPageFresh reports whether the cached copy of a listing page can be
+// used under ListCache
+func listPageFresh(page int, filename, dataDir string) bool {
+	ttl := ListCache.TTL(page)
+	switch {
+	case ttl < 0:
+		return true
+	case ttl == 0:
+		return false
+	}
+	at, ok := listFetchLog(dataDir).fetched(page, filename)
+	return ok && time.Since(at) < ttl
+}
@@ -0,0 +1,85 @@
+package scraper
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseCachePolicy(t *testing.T) {
+	p, err := ParseCachePolicy("1-5=6h, 6-20=7d, 21-=never")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for page, want := range map[int]time.Duration{1: 6 * time.Hour, 5: 6 * time.Hour, 6: 7 * 24 * time.Hour, 20: 7 * 24 * time.Hour, 21: Forever, 500: Forever} {
+		if got := p.TTL(page); got != want {
+			t.Errorf("TTL(%d) = %v, want %v", page, got, want)
+		}
+	}
+
+	p, _ = ParseCachePolicy("24h")
+	if p.TTL(1) != 24*time.Hour || p.TTL(300) != 24*time.Hour {
+		t.Errorf("Bare TTL should cover every page, got %v", p)
+	}
+	p, _ = ParseCachePolicy("-3=0")
+	if p.TTL(2) != 0 || p.TTL(4) != Forever {
+		t.Errorf("Pages outside every rule should be cached forever, got %v", p)
+	}
+
+	for _, bad := range []string{"soon", "-1h", "a-b=1h", "1-5=", "1-5=xd"} {
+		if _, err := ParseCachePolicy(bad); err == nil {
+			t.Errorf("ParseCachePolicy(%q) should fail", bad)
+		}
+	}
+}
+
+func TestDefaultCachePolicy(t *testing.T) {
+	for page, want := range map[int]time.Duration{1: 0, 5: 0, 6: Forever, 200: Forever} {
+		if got := DefaultCachePolicy.TTL(page); got != want {
+			t.Errorf("TTL(%d) = %v, want %v", page, got, want)
+		}
+	}
+}
+
+func TestGetListPage_CacheTTL(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "twittest")
+	defer os.RemoveAll(tmpDir)
+
+	requests := 0
+	fakeTransport(t, func(req *http.Request) (int, string) {
+		requests++
+		return http.StatusOK, "Fresh"
+	})
+	saved := ListCache
+	defer func() { ListCache = saved }()
+	ListCache, _ = ParseCachePolicy("1h")
+
+	// A page cached before fetch times were recorded goes by its file time
+	old := filepath.Join(tmpDir, "transcripts_page_1.html")
+	os.WriteFile(old, []byte("Cached"), 0644)
+	if _, cached, _ := GetListPageWithCacheStatus(1, tmpDir, false, 0); !cached || requests != 0 {
+		t.Errorf("A page written just now should be fresh (cached %v, %d requests)", cached, requests)
+	}
+	stale := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(old, stale, stale)
+	if content, cached, _ := GetListPageWithCacheStatus(1, tmpDir, false, 0); cached || content != "Fresh" || requests != 1 {
+		t.Errorf("A page older than the TTL should be downloaded (got %q, cached %v, %d requests)", content, cached, requests)
+	}
+
+	// The download time is recorded, and wins over the file time
+	data, err := os.ReadFile(filepath.Join(tmpDir, ListCacheFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var times map[string]time.Time
+	if err := json.Unmarshal(data, &times); err != nil || time.Since(times["1"]) > time.Minute {
+		t.Fatalf("Fetch time not recorded: %s (%v)", data, err)
+	}
+	os.Chtimes(old, stale, stale)
+	if _, cached, _ := GetListPageWithCacheStatus(1, tmpDir, false, 0); !cached || requests != 1 {
+		t.Errorf("A page fetched just now should be fresh (cached %v, %d requests)", cached, requests)
+	}
+}
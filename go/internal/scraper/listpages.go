@@ -0,0 +1,70 @@
+package scraper
+
+import (
+	"sync"
+	"time"
+)
+
+// ListPage is a transcript listing page fetched by FetchListPages
+type ListPage struct {
+	Num    int
+	HTML   string
+	Cached bool
+	Err    error
+}
+
+// FetchListPages gets the listing pages from first to last (inclusive),
+// up to workers at a time, and returns them in page order. Discovery only
+// reads the listing, so its pages can be fetched side by side; each worker
+// still waits throttle after its own downloads.
+func FetchListPages(first, last int, dataDir string, forceRefresh bool, throttle time.Duration, workers int) []ListPage {
+	if last < first {
+		return nil
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	pages := make([]ListPage, last-first+1)
+	nums := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(pages); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range nums {
+				html, cached, err := GetListPageWithCacheStatus(n, dataDir, forceRefresh, throttle)
+				pages[n-first] = ListPage{Num: n, HTML: html, Cached: cached, Err: err}
+			}
+		}()
+	}
+	for n := first; n <= last; n++ {
+		nums <- n
+	}
+	close(nums)
+	wg.Wait()
+	return pages
+}
+
+// EarlyStop ends paging through the listing once the wanted shows stop
+// appearing: after After consecutive pages without any of their transcripts,
+// counted from the first page that had one. Zero disables it.
+type EarlyStop struct {
+	After int
+
+	seen  bool
+	quiet int
+}
+
+// Page records whether a page listed transcripts of the wanted shows and
+// reports whether paging should stop
+func (s *EarlyStop) Page(wanted bool) bool {
+	if wanted {
+		s.seen, s.quiet = true, 0
+		return false
+	}
+	if !s.seen || s.After <= 0 {
+		return false
+	}
+	s.quiet++
+	return s.quiet >= s.After
+}
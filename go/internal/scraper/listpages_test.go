@@ -0,0 +1,72 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+)
+
+func TestFetchListPages(t *testing.T) {
+	var inFlight, most int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&most)
+			if n <= m || atomic.CompareAndSwapInt32(&most, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		page := r.URL.Query().Get("page")
+		if page == "" {
+			page = "1"
+		}
+		fmt.Fprintf(w, "page %s", page)
+	}))
+	defer ts.Close()
+	saved := config.BaseListURL
+	config.BaseListURL = ts.URL
+	defer func() { config.BaseListURL = saved }()
+
+	pages := FetchListPages(1, 6, t.TempDir(), false, 0, 3)
+	if len(pages) != 6 {
+		t.Fatalf("Got %d pages, want 6", len(pages))
+	}
+	for i, p := range pages {
+		if p.Err != nil || p.Num != i+1 || p.HTML != fmt.Sprintf("page %d", i+1) || p.Cached {
+			t.Errorf("Page %d = %+v", i+1, p)
+		}
+	}
+	if most < 2 || most > 3 {
+		t.Errorf("Expected 2-3 requests at once, got %d", most)
+	}
+	if FetchListPages(3, 2, "", false, 0, 3) != nil {
+		t.Error("Expected no pages for an empty range")
+	}
+}
+
+func TestEarlyStop(t *testing.T) {
+	s := EarlyStop{After: 2}
+	// Pages before the first wanted one do not count
+	for i, c := range []struct {
+		wanted, stop bool
+	}{{false, false}, {false, false}, {true, false}, {false, false}, {true, false}, {false, false}, {false, true}} {
+		if got := s.Page(c.wanted); got != c.stop {
+			t.Errorf("Page %d: stop = %v, want %v", i+1, got, c.stop)
+		}
+	}
+
+	off := EarlyStop{}
+	off.Page(true)
+	for i := 0; i < 10; i++ {
+		if off.Page(false) {
+			t.Fatal("A zero EarlyStop should never stop")
+		}
+	}
+}
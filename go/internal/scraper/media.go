@@ -0,0 +1,162 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/errs"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// ErrOverBudget is returned when a media file would exceed the media budget
+var ErrOverBudget = errors.New("media budget exhausted")
+
+// MediaBudget caps the bytes downloaded for media in one run, separately
+// from transcripts. A zero Max is unlimited.
+type MediaBudget struct {
+	Max  int64
+	Used int64
+}
+
+// allows reports whether n more bytes fit in the budget
+func (b *MediaBudget) allows(n int64) bool {
+	return b == nil || b.Max <= 0 || b.Used+n <= b.Max
+}
+
+// ParseSize parses a byte count with an optional K, M, G or T suffix
+// ("500M", "10GB"). Units are powers of 1024.
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(s)), "B")
+	if s == "" {
+		return 0, nil
+	}
+	mult := int64(1)
+	if i := strings.IndexAny(s, "KMGT"); i == len(s)-1 {
+		mult = 1 << (10 * (strings.IndexByte("KMGT", s[i]) + 1))
+		s = s[:i]
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size '%s'", s)
+	}
+	return int64(n * float64(mult)), nil
+}
+
+// DownloadEpisodeMedia downloads the audio linked from an archived
+// transcript into the media directory, named like the transcript
+// (SN_950.mp3). Returns the saved path, or "" if the page links no audio.
+func DownloadEpisodeMedia(prefix, epNum, dataDir string, budget *MediaBudget, throttle time.Duration) (string, error) {
+	if storage.IsRemote(dataDir) {
+		return "", fmt.Errorf("media downloads need a local data directory")
+	}
+	page := storage.Join(config.ActiveLayout.RawDir(dataDir, prefix), fmt.Sprintf("%s_%s.html", prefix, epNum))
+	html, err := storage.ReadFile(page)
+	if err != nil {
+		return "", err
+	}
+	for _, u := range converter.MediaURLs(string(html)) {
+		if converter.IsAudioURL(u) {
+			return DownloadMedia(u, prefix, epNum, dataDir, budget, throttle)
+		}
+	}
+	return "", nil
+}
+
+// DownloadMedia downloads an episode's audio or video from u into the
+// media directory, named like the transcript with the file's extension
+// (SN_950.mp3), unless it is there already. Returns the saved path.
+func DownloadMedia(u, prefix, epNum, dataDir string, budget *MediaBudget, throttle time.Duration) (string, error) {
+	if storage.IsRemote(dataDir) {
+		return "", fmt.Errorf("media downloads need a local data directory")
+	}
+	ext := path.Ext(strings.SplitN(u, "?", 2)[0])
+	dest := filepath.Join(config.ActiveLayout.MediaDir(dataDir, prefix), fmt.Sprintf("%s_%s%s", prefix, epNum, ext))
+	if _, err := os.Stat(dest); err == nil {
+		return dest, nil
+	}
+	fmt.Printf("Downloading media for %s %s: %s\n", prefix, epNum, u)
+	_, err := DownloadResumable(u, dest, budget, throttle)
+	return dest, err
+}
+
+// DownloadResumable downloads url to dest. Data is written to dest+".part"
+// and an interrupted download resumes from it with a Range request. The
+// file is only renamed to dest once complete. Returns the bytes transferred.
+func DownloadResumable(url, dest string, budget *MediaBudget, throttle time.Duration) (int64, error) {
+	part := dest + ".part"
+	if err := storage.CheckWritable(dest); err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return 0, err
+	}
+	var offset int64
+	if info, err := os.Stat(part); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return 0, err
+	}
+	identify(req)
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	defer pace(throttle)
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		flags |= os.O_APPEND
+	case http.StatusOK:
+		// Server ignored the range: start over
+		offset = 0
+		flags |= os.O_TRUNC
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The part file is already complete
+		return 0, os.Rename(part, dest)
+	default:
+		return 0, statusError(resp.StatusCode)
+	}
+
+	if resp.ContentLength > 0 && !budget.allows(resp.ContentLength) {
+		return 0, ErrOverBudget
+	}
+	if resp.ContentLength > 0 {
+		if err := Quota.reserve(resp.ContentLength); err != nil {
+			return 0, err
+		}
+	}
+
+	f, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(f, resp.Body)
+	if budget != nil {
+		budget.Used += n
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return n, fmt.Errorf("%w: interrupted after %d bytes (rerun to resume): %v", errs.ErrTruncated, offset+n, err)
+	}
+	return n, os.Rename(part, dest)
+}
@@ -0,0 +1,84 @@
+package scraper
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{"": 0, "512": 512, "2K": 2048, "1.5M": 1572864, "10GB": 10 << 30}
+	for input, want := range tests {
+		if got, err := ParseSize(input); err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d", input, got, err, want)
+		}
+	}
+	if _, err := ParseSize("lots"); err == nil {
+		t.Error("Expected an error for an invalid size")
+	}
+}
+
+func TestDownloadResumable(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "twittest")
+	defer os.RemoveAll(tmpDir)
+
+	audio := bytes.Repeat([]byte("0123456789"), 100)
+	var ranges []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "ep.mp3", time.Time{}, bytes.NewReader(audio))
+	}))
+	defer ts.Close()
+
+	// Simulate an interrupted earlier download
+	dest := filepath.Join(tmpDir, "media", "SN_1.mp3")
+	os.MkdirAll(filepath.Dir(dest), 0755)
+	os.WriteFile(dest+".part", audio[:400], 0644)
+
+	n, err := DownloadResumable(ts.URL+"/ep.mp3", dest, nil, 0)
+	if err != nil || n != 600 {
+		t.Fatalf("DownloadResumable = %d, %v", n, err)
+	}
+	if ranges[0] != "bytes=400-" {
+		t.Errorf("Expected a range request, got %q", ranges[0])
+	}
+	got, _ := os.ReadFile(dest)
+	if !bytes.Equal(got, audio) {
+		t.Error("Resumed file does not match the original")
+	}
+	if _, err := os.Stat(dest + ".part"); err == nil {
+		t.Error("Part file left behind")
+	}
+
+	budget := &MediaBudget{Max: 500}
+	if _, err := DownloadResumable(ts.URL+"/ep.mp3", filepath.Join(tmpDir, "big.mp3"), budget, 0); !errors.Is(err, ErrOverBudget) {
+		t.Errorf("Expected ErrOverBudget, got %v", err)
+	}
+}
+
+func TestDownloadEpisodeMedia(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "twittest")
+	defer os.RemoveAll(tmpDir)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("audio"))
+	}))
+	defer ts.Close()
+
+	page := `<a href="` + ts.URL + `/video/sn0950.mp4">Video</a> <a href="` + ts.URL + `/audio/sn0950.mp3?src=rss">Audio</a>`
+	os.WriteFile(filepath.Join(tmpDir, "SN_950.html"), []byte(page), 0644)
+
+	path, err := DownloadEpisodeMedia("SN", "950", tmpDir, nil, 0)
+	if err != nil || !strings.HasSuffix(path, "SN_950.mp3") {
+		t.Fatalf("DownloadEpisodeMedia = %s, %v", path, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "audio" {
+		t.Errorf("Unexpected media content %q", data)
+	}
+}
@@ -0,0 +1,125 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// MirrorDir is the directory of the data directory that holds the site
+// mirror, one subdirectory per host
+const MirrorDir = "raw"
+
+// MirrorMapFile is the URL-to-file mapping table kept in the mirror root
+const MirrorMapFile = "mirror.json"
+
+// SiteMirror, when set, receives a copy of every page the scraper downloads
+// successfully
+var SiteMirror *Mirror
+
+// Mirror keeps an exact copy of downloaded pages under their URL structure
+// (twit.tv/posts/transcripts/index.html) with a table mapping each URL to
+// its file, so the tree can be served as a static mirror or replayed
+type Mirror struct {
+	Root  string
+	Files map[string]string // URL -> file path relative to Root
+	Saved int               // Pages written since the mirror was opened
+
+	mu sync.Mutex
+}
+
+// OpenMirror opens the mirror rooted at dir, loading its mapping table if
+// there is one
+func OpenMirror(dir string) (*Mirror, error) {
+	m := &Mirror{Root: dir, Files: make(map[string]string)}
+	data, err := storage.ReadFile(storage.Join(dir, MirrorMapFile))
+	if errors.Is(err, storage.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &m.Files); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", MirrorMapFile, err)
+	}
+	return m, nil
+}
+
+// unsafeFileChars are replaced in the query part of mirror file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._=-]+`)
+
+// MirrorPath returns the file a URL is mirrored to, relative to the mirror
+// root: the host, then the path, with index.html for directory-like paths
+// (those whose last segment has no extension). A query string is folded
+// into the file name ("/posts/transcripts?page=2" ->
+// "twit.tv/posts/transcripts/index_page=2.html").
+func MirrorPath(u *url.URL) (string, error) {
+	if u.Host == "" {
+		return "", fmt.Errorf("cannot mirror %s: no host", u)
+	}
+	p := path.Clean("/" + u.EscapedPath())
+	if strings.HasSuffix(u.Path, "/") || path.Ext(p) == "" {
+		p = path.Join(p, "index.html")
+	}
+	if u.RawQuery != "" {
+		ext := path.Ext(p)
+		query := strings.Trim(unsafeFileChars.ReplaceAllString(u.RawQuery, "_"), "_")
+		p = strings.TrimSuffix(p, ext) + "_" + query + ext
+	}
+	return strings.ToLower(u.Host) + p, nil
+}
+
+// Save writes a page to the mirror and records its URL
+func (m *Mirror) Save(u *url.URL, body []byte) error {
+	rel, err := MirrorPath(u)
+	if err != nil {
+		return err
+	}
+	if err := saveFile(storage.Join(m.Root, rel), body); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Files[u.String()] = rel
+	m.Saved++
+	return nil
+}
+
+// File returns the mirrored file of a URL, relative to the root
+func (m *Mirror) File(rawURL string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rel, ok := m.Files[rawURL]
+	return rel, ok
+}
+
+// URLs returns the mirrored URLs in order
+func (m *Mirror) URLs() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	urls := make([]string, 0, len(m.Files))
+	for u := range m.Files {
+		urls = append(urls, u)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// Close writes the mapping table
+func (m *Mirror) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := json.MarshalIndent(m.Files, "", "  ")
+	if err != nil {
+		return err
+	}
+	return storage.WriteFile(storage.Join(m.Root, MirrorMapFile), append(data, '\n'))
+}
@@ -0,0 +1,88 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMirrorPath(t *testing.T) {
+	tests := map[string]string{
+		"https://twit.tv/posts/transcripts":             "twit.tv/posts/transcripts/index.html",
+		"https://twit.tv/posts/transcripts?page=2":      "twit.tv/posts/transcripts/index_page=2.html",
+		"https://TWiT.tv/shows/security-now/":           "twit.tv/shows/security-now/index.html",
+		"https://twit.tv/":                              "twit.tv/index.html",
+		"https://twit.tv/feed.xml":                      "twit.tv/feed.xml",
+		"https://twit.tv/../../etc/passwd.txt":          "twit.tv/etc/passwd.txt",
+		"https://twit.tv/search?q=ai%20news&type=posts": "twit.tv/search/index_q=ai_20news_type=posts.html",
+	}
+	for raw, want := range tests {
+		u, _ := url.Parse(raw)
+		got, err := MirrorPath(u)
+		if err != nil || got != want {
+			t.Errorf("MirrorPath(%s) = %q, %v, want %q", raw, got, err, want)
+		}
+	}
+	if _, err := MirrorPath(&url.URL{Path: "/relative"}); err == nil {
+		t.Error("Expected an error for a URL without a host")
+	}
+}
+
+func TestMirrorDownloads(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, "<html>%s</html>", r.URL.RequestURI())
+	}))
+	defer ts.Close()
+	host := strings.TrimPrefix(ts.URL, "http://")
+
+	dir := t.TempDir()
+	m, err := OpenMirror(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SiteMirror = m
+	defer func() { SiteMirror = nil }()
+	oldDelay := RetryDelay
+	RetryDelay = 0
+	defer func() { RetryDelay = oldDelay }()
+
+	if _, err := DownloadPage(ts.URL+"/posts/transcripts?page=2", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DownloadPage(ts.URL+"/missing", 0); err == nil {
+		t.Fatal("Expected the missing page to fail")
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rel := filepath.Join(host, "posts", "transcripts", "index_page=2.html")
+	data, err := os.ReadFile(filepath.Join(dir, rel))
+	if err != nil || string(data) != "<html>/posts/transcripts?page=2</html>" {
+		t.Errorf("Mirrored page = %q, %v", data, err)
+	}
+	if m.Saved != 1 {
+		t.Errorf("Saved = %d, want 1 (failed pages are not mirrored)", m.Saved)
+	}
+
+	// The table survives reopening
+	m, err = OpenMirror(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := m.File(ts.URL + "/posts/transcripts?page=2"); !ok || got != filepath.ToSlash(rel) {
+		t.Errorf("File = %q, %v, want %q", got, ok, rel)
+	}
+	if urls := m.URLs(); len(urls) != 1 {
+		t.Errorf("URLs = %v", urls)
+	}
+}
@@ -0,0 +1,53 @@
+package scraper
+
+import (
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// PartSuffix marks a download still being written. Pages are saved under
+// their name plus PartSuffix and renamed once complete, so a run killed
+// part way leaves a .part file instead of a truncated page that later runs
+// would skip as already archived.
+const PartSuffix = ".part"
+
+// writeComplete writes a local file through a .part file. Object stores
+// only show an upload once it is complete, so remote files are written
+// directly.
+func writeComplete(path string, data []byte) error {
+	if storage.IsRemote(path) {
+		return storage.WriteFile(path, data)
+	}
+	part := path + PartSuffix
+	if err := storage.WriteFile(part, data); err != nil {
+		storage.Remove(part)
+		return err
+	}
+	return storage.Rename(part, path)
+}
+
+// CleanPartial removes the page downloads that runs killed part way left
+// as .part files in the raw and list page directories, returning their
+// paths. Partial media downloads are kept: the next download resumes them.
+func CleanPartial(dataDir string) ([]string, error) {
+	var removed []string
+	seen := make(map[string]bool)
+	for _, dir := range []string{config.ActiveLayout.RawDir(dataDir, "*"), config.ListPageDir(dataDir)} {
+		// In the flat layout both are the data directory
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		matches, err := storage.Glob(storage.Join(dir, "*.html"+PartSuffix))
+		if err != nil {
+			return removed, err
+		}
+		for _, m := range matches {
+			if err := storage.Remove(m); err != nil {
+				return removed, err
+			}
+			removed = append(removed, m)
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,78 @@
+package scraper
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+func TestSaveFileLeavesNoPart(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "SN_1.html")
+	if err := saveFile(path, []byte("<html></html>")); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "<html></html>" {
+		t.Errorf("Saved %q, %v", data, err)
+	}
+	if _, err := os.Stat(path + PartSuffix); !os.IsNotExist(err) {
+		t.Error("The .part file should be renamed into place")
+	}
+}
+
+func TestCleanPartial(t *testing.T) {
+	for _, layout := range []config.Layout{config.FlatLayout, config.StructuredLayout} {
+		t.Run(layout.Name, func(t *testing.T) {
+			saved := config.ActiveLayout
+			config.ActiveLayout = layout
+			defer func() { config.ActiveLayout = saved }()
+
+			dir := t.TempDir()
+			raw := layout.RawDir(dir, "SN")
+			media := layout.MediaDir(dir, "SN")
+			lists := config.ListPageDir(dir)
+			files := map[string]bool{
+				filepath.Join(raw, "SN_1.html"):                            false,
+				filepath.Join(raw, "SN_2.html"+PartSuffix):                 true,
+				filepath.Join(lists, "transcripts_page_3.html"+PartSuffix): true,
+				filepath.Join(media, "SN_1.mp3"+PartSuffix):                false,
+			}
+			for f := range files {
+				os.MkdirAll(filepath.Dir(f), 0755)
+				os.WriteFile(f, []byte("x"), 0644)
+			}
+
+			removed, err := CleanPartial(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(removed) != 2 {
+				t.Errorf("Removed %v, want the two page .part files", removed)
+			}
+			for f, gone := range files {
+				if _, err := os.Stat(f); os.IsNotExist(err) != gone {
+					t.Errorf("%s: removed = %v, want %v", f, os.IsNotExist(err), gone)
+				}
+			}
+		})
+	}
+}
+
+func TestSaveFileReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	saved := storage.ReadOnly
+	storage.ReadOnly = []string{dir}
+	defer func() { storage.ReadOnly = saved }()
+
+	path := filepath.Join(dir, "SN_1.html")
+	if err := saveFile(path, []byte("<html></html>")); !errors.Is(err, storage.ErrReadOnly) {
+		t.Errorf("saveFile = %v, want ErrReadOnly", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("A read-only directory was written to: %v", entries)
+	}
+}
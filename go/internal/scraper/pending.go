@@ -0,0 +1,56 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+)
+
+// ErrPending is returned for episode pages published before their
+// transcript. The page is saved and marked pending in the index, so later
+// fetches download it again instead of skipping it as archived.
+var ErrPending = errors.New("transcript not published yet")
+
+var (
+	pendingMu sync.Mutex
+	// Index keys of the pending episodes, per data directory
+	pendingKeys = make(map[string]map[string]bool)
+)
+
+// pending reports whether an archived episode is marked pending in the
+// data directory's index
+func pending(prefix, epNum, dataDir string) bool {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+	keys, ok := pendingKeys[dataDir]
+	if !ok {
+		keys = make(map[string]bool)
+		if ix, err := index.Load(dataDir); err == nil {
+			for key, e := range ix.Entries {
+				if e.Pending {
+					keys[key] = true
+				}
+			}
+		}
+		pendingKeys[dataDir] = keys
+	}
+	for _, p := range config.EraPrefixes(prefix) {
+		if keys[fmt.Sprintf("%s_%s", p, epNum)] {
+			return true
+		}
+	}
+	return false
+}
+
+// markPending notes that an index entry was marked pending or, once the
+// transcript is out, cleared (see recordFetched)
+func markPending(dataDir, key string, marked bool) {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+	if keys, ok := pendingKeys[dataDir]; ok {
+		keys[key] = marked
+	}
+}
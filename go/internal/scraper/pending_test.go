@@ -0,0 +1,63 @@
+package scraper
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+)
+
+func TestDownloadTranscriptPending(t *testing.T) {
+	dir := t.TempDir()
+	page := `<html><h1 class="post-title">Security Now 1001</h1><div class="body textual"><p>Transcript coming soon.</p></div></html>`
+	requests := 0
+	fakeTransport(t, func(req *http.Request) (int, string) {
+		requests++
+		return http.StatusOK, page
+	})
+
+	if _, err := DownloadTranscriptWithStatus("/sn-1001", "Security Now 1001", "SN", dir, 0); !errors.Is(err, ErrPending) {
+		t.Fatalf("got %v, want ErrPending", err)
+	}
+	ix, err := index.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := ix.Entries["SN_1001"]; e == nil || !e.Pending {
+		t.Fatalf("index entry = %+v, want pending", e)
+	}
+
+	// Still a placeholder: downloaded again, still pending
+	if _, err := DownloadTranscriptWithStatus("/sn-1001", "Security Now 1001", "SN", dir, 0); !errors.Is(err, ErrPending) {
+		t.Fatalf("got %v, want ErrPending", err)
+	}
+
+	page = `<html><h1 class="post-title">Security Now 1001</h1>` + transcriptBody + `</html>`
+	skipped, err := DownloadTranscriptWithStatus("/sn-1001", "Security Now 1001", "SN", dir, 0)
+	if err != nil || skipped {
+		t.Fatalf("got skipped=%v, %v", skipped, err)
+	}
+	if requests != 3 {
+		t.Errorf("%d requests, want 3", requests)
+	}
+	ix, _ = index.Load(dir)
+	if e := ix.Entries["SN_1001"]; e == nil || e.Pending {
+		t.Errorf("index entry = %+v, want not pending", e)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "SN_1001.html"))
+	if !strings.Contains(string(data), "It's time for the show") {
+		t.Errorf("transcript not saved: %q", data)
+	}
+
+	// Archived for good now
+	if skipped, err := DownloadTranscriptWithStatus("/sn-1001", "Security Now 1001", "SN", dir, 0); err != nil || !skipped {
+		t.Errorf("got skipped=%v, %v", skipped, err)
+	}
+	if requests != 3 {
+		t.Errorf("%d requests, want 3", requests)
+	}
+}
@@ -0,0 +1,212 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/utils"
+)
+
+// Politeness, when set, adapts the wait between requests to how the site
+// responds; nil keeps the fixed --throttle
+var Politeness *Pacer
+
+// Pacer is a closed-loop politeness controller. Every response feeds it:
+// 429 and 503 responses double the wait between requests, and so do
+// response times well above the fastest seen so far, while each ordinary
+// response brings the wait back down by a fifth towards --throttle. Three
+// pushbacks in a row, or a Retry-After header, pause all requests for a
+// cooldown that doubles each time it is needed again.
+type Pacer struct {
+	// MinDelay is the least wait once slowed down, for runs without a
+	// throttle
+	MinDelay time.Duration
+	// MaxFactor caps how many times the throttle the wait can grow
+	MaxFactor float64
+	// Cooldown is the first pause after repeated pushback; MaxCooldown
+	// caps it and any Retry-After
+	Cooldown, MaxCooldown time.Duration
+	// SlowFactor is how many times the fastest average response time an
+	// average must reach to count as the server struggling
+	SlowFactor float64
+	// Beat, if set, is called every BeatEvery while Wait pauses, so that a
+	// supervisor's watchdog sees a cooldown as progress rather than a hang
+	Beat      func()
+	BeatEvery time.Duration
+
+	mu       sync.Mutex
+	factor   float64
+	strikes  int // Consecutive pushbacks
+	pauses   int // Cooldowns since the wait was last back at the throttle
+	until    time.Time
+	samples  int
+	latency  time.Duration // Moving average of response times
+	baseline time.Duration // Lowest moving average seen
+	stats    PacerStats
+}
+
+// PacerStats counts what a Pacer saw and did
+type PacerStats struct {
+	Requests  int
+	Pushbacks int // 429 and 503 responses
+	Slow      int // Responses while the average response time was high
+	Pauses    int
+	Paused    time.Duration
+	// MaxFactor is the most the wait was multiplied by
+	MaxFactor float64
+}
+
+// NewPacer returns a Pacer with the default settings
+func NewPacer() *Pacer {
+	return &Pacer{
+		MinDelay:    500 * time.Millisecond,
+		MaxFactor:   32,
+		Cooldown:    time.Minute,
+		MaxCooldown: 15 * time.Minute,
+		SlowFactor:  3,
+		factor:      1,
+	}
+}
+
+// Delay returns the wait after a request for a run throttled to throttle
+func (p *Pacer) Delay(throttle time.Duration) time.Duration {
+	if p == nil {
+		return throttle
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.factor <= 1 {
+		return throttle
+	}
+	if throttle < p.MinDelay {
+		throttle = p.MinDelay
+	}
+	return time.Duration(float64(throttle) * p.factor)
+}
+
+// Wait blocks while a cooldown is in progress, calling Beat as it goes
+func (p *Pacer) Wait() {
+	if p == nil {
+		return
+	}
+	for {
+		p.mu.Lock()
+		wait, beat, every := time.Until(p.until), p.Beat, p.BeatEvery
+		p.mu.Unlock()
+		if wait <= 0 {
+			return
+		}
+		if beat == nil || every <= 0 || wait <= every {
+			time.Sleep(wait)
+			return
+		}
+		time.Sleep(every)
+		beat()
+	}
+}
+
+// Observe feeds the pacer a response: its status code (0 for a request
+// that failed without one), how long it took to arrive and its Retry-After
+// header
+func (p *Pacer) Observe(status int, elapsed time.Duration, retryAfter string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stats.Requests++
+	was := p.factor
+
+	if status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable {
+		p.stats.Pushbacks++
+		p.strikes++
+		p.slowDown(2)
+		if d, ok := utils.ParseRetryAfter(retryAfter, time.Now()); ok {
+			p.pause(d, fmt.Sprintf("the site asked to wait (status %d)", status))
+		} else if p.strikes >= 3 {
+			d := p.Cooldown
+			for i := 0; i < p.pauses && d < p.MaxCooldown; i++ {
+				d *= 2
+			}
+			p.pause(d, fmt.Sprintf("%d responses with status 429/503 in a row", p.strikes))
+		} else if was <= 1 {
+			fmt.Printf("Site is pushing back (status %d): slowing down\n", status)
+		}
+		return
+	}
+	p.strikes = 0
+	if status == 0 {
+		p.slowDown(1.5) // Timeouts and dropped connections
+		return
+	}
+
+	if p.samples == 0 {
+		p.latency = elapsed
+	} else {
+		p.latency = (4*p.latency + elapsed) / 5
+	}
+	p.samples++
+	if p.samples >= 3 && (p.baseline == 0 || p.latency < p.baseline) {
+		p.baseline = p.latency
+	}
+	if p.baseline > 0 && float64(p.latency) > p.SlowFactor*float64(p.baseline) {
+		p.stats.Slow++
+		p.slowDown(1.5)
+		if was <= 1 {
+			fmt.Printf("Site is responding slowly (%v on average, %v at best): slowing down\n", p.latency.Round(time.Millisecond), p.baseline.Round(time.Millisecond))
+		}
+		return
+	}
+	if p.factor > 1 {
+		p.factor *= 0.8
+		if p.factor <= 1.05 {
+			p.factor, p.pauses = 1, 0
+			fmt.Println("Site is responding normally again: back to the configured throttle")
+		}
+	}
+}
+
+// slowDown multiplies the wait by f, up to MaxFactor
+func (p *Pacer) slowDown(f float64) {
+	p.factor *= f
+	if p.factor > p.MaxFactor {
+		p.factor = p.MaxFactor
+	}
+	if p.factor > p.stats.MaxFactor {
+		p.stats.MaxFactor = p.factor
+	}
+}
+
+// pause starts a cooldown of d, capped at MaxCooldown
+func (p *Pacer) pause(d time.Duration, why string) {
+	if d > p.MaxCooldown {
+		d = p.MaxCooldown
+	}
+	if until := time.Now().Add(d); until.After(p.until) {
+		p.until = until
+		p.pauses++
+		p.strikes = 0
+		p.stats.Pauses++
+		p.stats.Paused += d
+		fmt.Printf("Pausing requests for %v: %s\n", d, why)
+	}
+}
+
+// Stats returns what the pacer saw so far
+func (p *Pacer) Stats() PacerStats {
+	if p == nil {
+		return PacerStats{}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats
+}
+
+// pace waits between requests: the throttle, or as long as Politeness says
+func pace(throttle time.Duration) {
+	if d := Politeness.Delay(throttle); d > 0 {
+		time.Sleep(d)
+	}
+}
@@ -0,0 +1,71 @@
+package scraper
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPacerPushback(t *testing.T) {
+	p := NewPacer()
+	p.Cooldown = 20 * time.Millisecond
+	throttle := time.Second
+	if d := p.Delay(throttle); d != throttle {
+		t.Fatalf("Delay before any pushback = %v, want the throttle", d)
+	}
+
+	p.Observe(http.StatusTooManyRequests, 0, "")
+	p.Observe(http.StatusServiceUnavailable, 0, "")
+	if d := p.Delay(throttle); d != 4*time.Second {
+		t.Errorf("Delay after two pushbacks = %v, want 4s", d)
+	}
+	p.Observe(http.StatusTooManyRequests, 0, "")
+	start := time.Now()
+	p.Wait()
+	if waited := time.Since(start); waited < 15*time.Millisecond {
+		t.Errorf("Three pushbacks in a row should pause requests, waited %v", waited)
+	}
+
+	for i := 0; i < 20; i++ {
+		p.Observe(http.StatusOK, 10*time.Millisecond, "")
+	}
+	if d := p.Delay(throttle); d != throttle {
+		t.Errorf("Delay after recovering = %v, want the throttle", d)
+	}
+	s := p.Stats()
+	if s.Pushbacks != 3 || s.Pauses != 1 || s.MaxFactor != 8 {
+		t.Errorf("Stats = %+v", s)
+	}
+}
+
+func TestPacerSlowResponses(t *testing.T) {
+	p := NewPacer()
+	for i := 0; i < 5; i++ {
+		p.Observe(http.StatusOK, 100*time.Millisecond, "")
+	}
+	for i := 0; i < 10; i++ {
+		p.Observe(http.StatusOK, 2*time.Second, "")
+	}
+	if d := p.Delay(0); d < p.MinDelay*2 {
+		t.Errorf("Delay with slow responses = %v, want well above MinDelay", d)
+	}
+	if p.Stats().Slow == 0 {
+		t.Error("Slow responses should be counted")
+	}
+}
+
+func TestPacerWaitBeats(t *testing.T) {
+	p := NewPacer()
+	beats := 0
+	p.Beat = func() { beats++ }
+	p.BeatEvery = 10 * time.Millisecond
+	p.pause(55*time.Millisecond, "test")
+	start := time.Now()
+	p.Wait()
+	if waited := time.Since(start); waited < 50*time.Millisecond {
+		t.Errorf("Wait returned after %v, before the cooldown ended", waited)
+	}
+	if beats < 4 {
+		t.Errorf("Beat was called %d times during a 55ms cooldown, want one per 10ms", beats)
+	}
+}
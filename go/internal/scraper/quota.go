@@ -0,0 +1,180 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// ErrQuota is returned when saving a file would take the data directory past
+// its size limit
+var ErrQuota = errors.New("archive size limit reached")
+
+// Quota, when set, caps the total size of the data directory
+var Quota *SizeQuota
+
+// DefaultTranscriptSize is assumed for estimates when nothing is archived yet
+const DefaultTranscriptSize = 80 << 10
+
+// SizeQuota tracks the size of a local data directory against a limit
+type SizeQuota struct {
+	Max int64
+
+	mu   sync.Mutex
+	used int64
+	full bool
+}
+
+// NewSizeQuota measures dataDir and returns a quota of max bytes for it
+func NewSizeQuota(dataDir string, max int64) (*SizeQuota, error) {
+	used, err := DirSize(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	return &SizeQuota{Max: max, used: used}, nil
+}
+
+// Used returns the current size of the data directory
+func (q *SizeQuota) Used() int64 {
+	if q == nil {
+		return 0
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.used
+}
+
+// Full reports whether a file has been refused for lack of space
+func (q *SizeQuota) Full() bool {
+	if q == nil {
+		return false
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.full
+}
+
+// reserve accounts for n more bytes, or returns ErrQuota if they do not fit
+func (q *SizeQuota) reserve(n int64) error {
+	if q == nil || q.Max <= 0 {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.used+n > q.Max {
+		q.full = true
+		return fmt.Errorf("%w: %d of %d bytes used", ErrQuota, q.used, q.Max)
+	}
+	q.used += n
+	return nil
+}
+
+// saveFile writes a downloaded page through a .part file, charging its
+// growth to Quota
+func saveFile(path string, data []byte) error {
+	n := int64(len(data))
+	if !storage.IsRemote(path) {
+		if info, err := os.Stat(path); err == nil {
+			n -= info.Size()
+		}
+	}
+	if err := Quota.reserve(n); err != nil {
+		return err
+	}
+	return writeComplete(path, data)
+}
+
+// DirSize returns the total size of the files under a local directory
+func DirSize(dir string) (int64, error) {
+	if storage.IsRemote(dir) {
+		return 0, fmt.Errorf("archive size limits need a local data directory")
+	}
+	var total int64
+	err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
+
+// Estimate is a preflight guess at the space a fetch run needs
+type Estimate struct {
+	Pages   int   // Cached listing pages read
+	Missing int   // Listed transcripts of the target shows not yet archived
+	AvgSize int64 // Average size of an archived transcript
+	Bytes   int64 // Missing * AvgSize
+}
+
+// EstimateFetch counts the transcripts of the given shows that the cached
+// listing pages list but the archive lacks, and sizes them by the average
+// archived transcript. Nothing is downloaded, so the estimate only covers
+// listing pages fetched by earlier runs.
+func EstimateFetch(prefixes map[string]bool, dataDir string) (Estimate, error) {
+	var est Estimate
+	seen := make(map[string]bool)
+	for pageNum := 1; ; pageNum++ {
+		filename := storage.Join(config.ListPageDir(dataDir), fmt.Sprintf("transcripts_page_%d.html", pageNum))
+		html, err := storage.ReadFile(filename)
+		if err != nil {
+			break
+		}
+		est.Pages++
+		for _, item := range ExtractItems(string(html)) {
+			prefix := config.PrefixForTitle(item.Title)
+			epNum := TitleEpisode(item.Title)
+			key := prefix + "_" + epNum
+			if !prefixes[prefix] || seen[key] {
+				continue
+			}
+			seen[key] = true
+			if !Archived(prefix, epNum, dataDir) {
+				est.Missing++
+			}
+		}
+	}
+
+	var total int64
+	var count int
+	for prefix := range prefixes {
+		files, err := config.ActiveLayout.RawFiles(dataDir, prefix)
+		if err != nil {
+			return est, err
+		}
+		for _, f := range files {
+			if info, err := os.Stat(f); err == nil {
+				total += info.Size()
+				count++
+			}
+		}
+	}
+	est.AvgSize = DefaultTranscriptSize
+	if count > 0 {
+		est.AvgSize = total / int64(count)
+	}
+	est.Bytes = int64(est.Missing) * est.AvgSize
+	return est, nil
+}
+
+// Archived reports whether an episode is saved under the prefix or one of
+// its earlier eras
+func Archived(prefix, epNum, dataDir string) bool {
+	for _, p := range config.EraPrefixes(prefix) {
+		if storage.Exists(storage.Join(config.ActiveLayout.RawDir(dataDir, p), fmt.Sprintf("%s_%s.html", p, epNum))) {
+			return true
+		}
+	}
+	return false
+}
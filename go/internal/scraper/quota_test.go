@@ -0,0 +1,73 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+)
+
+func TestSizeQuota(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "twittest")
+	defer os.RemoveAll(tmpDir)
+	os.WriteFile(filepath.Join(tmpDir, "SN_1.html"), make([]byte, 600), 0644)
+
+	q, err := NewSizeQuota(tmpDir, 1000)
+	if err != nil || q.Used() != 600 {
+		t.Fatalf("NewSizeQuota = %v, %v", q, err)
+	}
+	Quota = q
+	defer func() { Quota = nil }()
+
+	if err := saveFile(filepath.Join(tmpDir, "SN_2.html"), make([]byte, 300)); err != nil {
+		t.Fatalf("300 bytes should fit: %v", err)
+	}
+	// Replacing a file only charges the difference
+	if err := saveFile(filepath.Join(tmpDir, "SN_1.html"), make([]byte, 650)); err != nil {
+		t.Fatalf("Growing SN_1 by 50 bytes should fit: %v", err)
+	}
+	if q.Full() {
+		t.Error("Quota should not be full yet")
+	}
+	err = saveFile(filepath.Join(tmpDir, "SN_3.html"), make([]byte, 100))
+	if !errors.Is(err, ErrQuota) {
+		t.Errorf("Expected ErrQuota, got %v", err)
+	}
+	if _, serr := os.Stat(filepath.Join(tmpDir, "SN_3.html")); serr == nil {
+		t.Error("A refused file should not be written")
+	}
+	if !q.Full() || q.Used() != 950 {
+		t.Errorf("Full = %v, Used = %d; want true, 950", q.Full(), q.Used())
+	}
+}
+
+func TestEstimateFetch(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "twittest")
+	defer os.RemoveAll(tmpDir)
+
+	var page strings.Builder
+	for _, title := range []string{"Security Now 1 Transcript", "Security Now 2 Transcript", "Security Now 3 Transcript", "Windows Weekly 900 Transcript"} {
+		fmt.Fprintf(&page, `<div class="item summary"><h2 class="title"><a href="/posts/x">%s</a></h2></div>`, title)
+	}
+	listDir := config.ActiveLayout.ListPageDir(tmpDir)
+	os.MkdirAll(listDir, 0755)
+	os.WriteFile(filepath.Join(listDir, "transcripts_page_1.html"), []byte(page.String()), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "SN_1.html"), make([]byte, 1000), 0644)
+
+	est, err := EstimateFetch(map[string]bool{"SN": true}, tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est.Pages != 1 || est.Missing != 2 || est.AvgSize != 1000 || est.Bytes != 2000 {
+		t.Errorf("Unexpected estimate: %+v", est)
+	}
+
+	est, _ = EstimateFetch(map[string]bool{"WW": true}, tmpDir)
+	if est.Missing != 1 || est.AvgSize != DefaultTranscriptSize {
+		t.Errorf("Expected the default size without archived transcripts: %+v", est)
+	}
+}
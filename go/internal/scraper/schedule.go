@@ -0,0 +1,158 @@
+package scraper
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+)
+
+// Schedule decides which shows a run fetches first and how many new
+// transcripts of each it downloads at most, so a run that is cut short by
+// a budget has the most important shows in. A nil Schedule fetches in
+// listing order without limits.
+type Schedule struct {
+	// Order lists prefixes, most important first; shows not in it come
+	// after, in their usual order
+	Order []string
+	// Quotas caps new downloads per run by prefix; "*" applies to shows
+	// without their own quota. Missing or 0 means no cap.
+	Quotas map[string]int
+
+	taken map[string]int
+}
+
+// ParseSchedule reads a comma-separated priority list of shows ("SN,TWIT")
+// and quotas ("SN=20,*=5", or "5" for every show). It returns nil if both
+// are empty.
+func ParseSchedule(priority, quotas string) (*Schedule, error) {
+	s := &Schedule{Quotas: make(map[string]int)}
+	for _, name := range strings.Split(priority, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		prefix, ok := config.ResolveShow(name)
+		if !ok {
+			return nil, config.UnknownShowError(name)
+		}
+		s.Order = append(s.Order, prefix)
+	}
+	for _, rule := range strings.Split(quotas, ",") {
+		if rule = strings.TrimSpace(rule); rule == "" {
+			continue
+		}
+		name, value, found := strings.Cut(rule, "=")
+		if !found {
+			name, value = "*", rule
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid quota '%s' (want SHOW=N, *=N or N)", rule)
+		}
+		key := strings.TrimSpace(name)
+		if key != "*" {
+			prefix, ok := config.ResolveShow(key)
+			if !ok {
+				return nil, config.UnknownShowError(key)
+			}
+			key = prefix
+		}
+		s.Quotas[key] = n
+	}
+	if len(s.Order) == 0 && len(s.Quotas) == 0 {
+		return nil, nil
+	}
+	return s, nil
+}
+
+// Ordered reports whether the schedule puts some shows first
+func (s *Schedule) Ordered() bool {
+	return s != nil && len(s.Order) > 0
+}
+
+// Rank returns the position of a show in Order, len(Order) for shows not in
+// it
+func (s *Schedule) Rank(prefix string) int {
+	if s == nil {
+		return 0
+	}
+	for i, p := range s.Order {
+		if p == prefix {
+			return i
+		}
+	}
+	return len(s.Order)
+}
+
+// SortShows puts prefixes in priority order, keeping the order of equals
+func (s *Schedule) SortShows(prefixes []string) {
+	sort.SliceStable(prefixes, func(i, j int) bool { return s.Rank(prefixes[i]) < s.Rank(prefixes[j]) })
+}
+
+// SortItems puts listing items in the priority order of their shows,
+// keeping the listing order (newest first) within a show
+func (s *Schedule) SortItems(items []Item) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return s.Rank(config.PrefixForTitle(items[i].Title)) < s.Rank(config.PrefixForTitle(items[j].Title))
+	})
+}
+
+// Quota returns the most new transcripts of a show a run downloads, 0 for
+// no cap
+func (s *Schedule) Quota(prefix string) int {
+	if s == nil {
+		return 0
+	}
+	if n, ok := s.Quotas[prefix]; ok {
+		return n
+	}
+	return s.Quotas["*"]
+}
+
+// Admit reports whether a listed transcript may be fetched. Once a show's
+// quota is used up, transcripts not archived yet are refused, to be
+// fetched by a later run; archived ones are let through so they are
+// counted as skipped as usual.
+func (s *Schedule) Admit(prefix, title, dataDir string) bool {
+	max := s.Quota(prefix)
+	if max == 0 || s.taken[prefix] < max {
+		return true
+	}
+	epNum := TitleEpisode(title)
+	return Archived(prefix, epNum, dataDir) && !pending(prefix, epNum, dataDir)
+}
+
+// Take counts a new download of a show against its quota
+func (s *Schedule) Take(prefix string) {
+	if s == nil {
+		return
+	}
+	if s.taken == nil {
+		s.taken = make(map[string]int)
+	}
+	s.taken[prefix]++
+}
+
+// Full reports whether every one of the shows has used up its quota
+func (s *Schedule) Full(prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return false
+	}
+	for _, p := range prefixes {
+		max := s.Quota(p)
+		if max == 0 || s.taken[p] < max {
+			return false
+		}
+	}
+	return true
+}
+
+// Reset clears the counts, for the next run of a daemon
+func (s *Schedule) Reset() {
+	if s == nil {
+		return
+	}
+	s.taken = nil
+}
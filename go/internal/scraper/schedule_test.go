@@ -0,0 +1,91 @@
+package scraper
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSchedule(t *testing.T) {
+	s, err := ParseSchedule("Security Now, TWIT", "SN=2,*=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Order) != 2 || s.Order[0] != "SN" || s.Order[1] != "TWIT" {
+		t.Errorf("Order = %v", s.Order)
+	}
+	if s.Quota("SN") != 2 || s.Quota("WW") != 1 {
+		t.Errorf("Quotas = %v", s.Quotas)
+	}
+	if s, err := ParseSchedule("", "3"); err != nil || s.Quota("IM") != 3 || s.Ordered() {
+		t.Errorf("ParseSchedule(\"\", 3) = %+v, %v", s, err)
+	}
+	if s, err := ParseSchedule("", ""); s != nil || err != nil {
+		t.Errorf("An empty schedule should be nil, got %+v, %v", s, err)
+	}
+	for _, bad := range []string{"SN=x", "SN=-1", "NOPE=2"} {
+		if _, err := ParseSchedule("", bad); err == nil {
+			t.Errorf("ParseSchedule(%q) should fail", bad)
+		}
+	}
+	if _, err := ParseSchedule("Not A Show", ""); err == nil {
+		t.Error("Unknown shows in the priority list should fail")
+	}
+}
+
+func TestScheduleOrder(t *testing.T) {
+	s := &Schedule{Order: []string{"SN", "TWIT"}}
+	items := []Item{
+		{Title: "Windows Weekly 900 Transcript"},
+		{Title: "This Week in Tech 1000 Transcript"},
+		{Title: "Security Now 950 Transcript"},
+		{Title: "Windows Weekly 899 Transcript"},
+		{Title: "Security Now 949 Transcript"},
+	}
+	s.SortItems(items)
+	want := []string{"Security Now 950", "Security Now 949", "This Week in Tech 1000", "Windows Weekly 900", "Windows Weekly 899"}
+	for i, w := range want {
+		if items[i].Title != w+" Transcript" {
+			t.Errorf("item %d = %s, want %s", i, items[i].Title, w)
+		}
+	}
+	shows := []string{"IM", "TWIT", "SN"}
+	s.SortShows(shows)
+	if shows[0] != "SN" || shows[1] != "TWIT" || shows[2] != "IM" {
+		t.Errorf("SortShows = %v", shows)
+	}
+
+	var none *Schedule
+	none.SortShows(shows) // Must not panic
+	if !none.Admit("SN", "Security Now 1 Transcript", "") || none.Full(shows) {
+		t.Error("A nil schedule should admit everything")
+	}
+}
+
+func TestScheduleQuota(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "twittest")
+	defer os.RemoveAll(tmpDir)
+	os.WriteFile(filepath.Join(tmpDir, "SN_1.html"), []byte("<html></html>"), 0644)
+
+	s := &Schedule{Quotas: map[string]int{"SN": 1}}
+	if !s.Admit("SN", "Security Now 3 Transcript", tmpDir) {
+		t.Fatal("The first new transcript should be admitted")
+	}
+	s.Take("SN")
+	if s.Admit("SN", "Security Now 2 Transcript", tmpDir) {
+		t.Error("A new transcript over the quota should be deferred")
+	}
+	if !s.Admit("SN", "Security Now 1 Transcript", tmpDir) {
+		t.Error("Archived transcripts should still be admitted, to be skipped")
+	}
+	if !s.Admit("WW", "Windows Weekly 900 Transcript", tmpDir) {
+		t.Error("Shows without a quota should be admitted")
+	}
+	if !s.Full([]string{"SN"}) || s.Full([]string{"SN", "WW"}) {
+		t.Error("Full should only hold when every show reached its quota")
+	}
+	s.Reset()
+	if !s.Admit("SN", "Security Now 2 Transcript", tmpDir) {
+		t.Error("Reset should clear the counts")
+	}
+}
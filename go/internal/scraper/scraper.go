@@ -1,104 +1,208 @@
 package scraper
 
 import (
+	"errors"
 	"fmt"
+	"html"
 	"io"
 	"net/http"
-	"os"
-	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/aramova/twit-transcript-archiver/go/internal/config"
-	"github.com/aramova/twit-transcript-archiver/go/internal/utils"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/errs"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+	"github.com/aramova/twit-transcript-archiver/go/internal/warc"
 )
 
+// ErrFiltered is returned for transcripts outside the requested episode or
+// date range
+var ErrFiltered = errors.New("outside the requested range")
+
+// Archive, when set, receives every HTTP exchange the scraper makes
+var Archive *warc.Writer
+
+// Transport sends the scraper's HTTP requests, over the tuned transport of
+// NewTransport by default. Tests replace it to serve canned responses
+// without a server.
+var Transport http.RoundTripper = NewTransport()
+
+// RetryDelay is the wait before DownloadPage retries a failed request
+var RetryDelay = 2 * time.Second
+
+// readBody reads a response body, recording the exchange in Archive and a
+// successful page in SiteMirror. A body cut short of its Content-Length
+// returns errs.ErrTruncated.
+func readBody(req *http.Request, resp *http.Response) ([]byte, error) {
+	at := time.Now()
+	body, err := io.ReadAll(resp.Body)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return body, fmt.Errorf("%w: got %d of %d bytes", errs.ErrTruncated, len(body), resp.ContentLength)
+	}
+	if err == nil && Archive != nil {
+		if werr := Archive.WriteExchange(req, resp, body, at); werr != nil {
+			fmt.Printf("Warning: could not write WARC record for %s: %v\n", req.URL, werr)
+		}
+	}
+	if err == nil && SiteMirror != nil && resp.StatusCode == http.StatusOK {
+		if merr := SiteMirror.Save(req.URL, body); merr != nil {
+			fmt.Printf("Warning: could not mirror %s: %v\n", req.URL, merr)
+		}
+	}
+	return body, err
+}
+
 type Item struct {
 	URL   string
 	Title string
 }
 
+// identify sets the headers that tell the site who is fetching: the
+// archiver's User-Agent and, if configured, a From address
+func identify(req *http.Request) {
+	req.Header.Set("User-Agent", config.UserAgent)
+	if config.From != "" {
+		req.Header.Set("From", config.From)
+	}
+}
+
 // DownloadPage downloads content from a URL with retries and throttling
 func DownloadPage(url string, throttle time.Duration) (string, error) {
+	content, _, err := DownloadPageURL(url, throttle)
+	return content, err
+}
+
+// DownloadPageURL is DownloadPage that also returns the URL the content
+// came from, which differs from url when the site redirected the request
+func DownloadPageURL(url string, throttle time.Duration) (string, string, error) {
 	var lastErr error
 	for retries := 3; retries > 0; retries-- {
-		client := &http.Client{}
 		req, err := http.NewRequest("GET", url, nil)
 		if err != nil {
 			lastErr = err
-			time.Sleep(2 * time.Second)
+			time.Sleep(RetryDelay)
 			continue
 		}
-		req.Header.Set("User-Agent", config.UserAgent)
+		identify(req)
 
 		resp, err := client.Do(req)
+		if errors.Is(err, ErrTransferBudget) {
+			return "", "", err
+		}
 		if err != nil {
 			lastErr = err
-			time.Sleep(2 * time.Second)
+			time.Sleep(RetryDelay)
 			continue
 		}
 		defer resp.Body.Close()
 
+		// After redirects the response is that of the last request
+		if resp.Request != nil {
+			req = resp.Request
+		}
+		body, err := readBody(req, resp)
 		if resp.StatusCode != 200 {
-			lastErr = fmt.Errorf("status code %d", resp.StatusCode)
-			time.Sleep(2 * time.Second)
+			lastErr = statusError(resp.StatusCode)
+			if errors.Is(lastErr, errs.ErrNotFound) {
+				return "", "", lastErr // Retrying will not help
+			}
+			time.Sleep(RetryDelay)
 			continue
 		}
-
-		body, err := io.ReadAll(resp.Body)
+		if errors.Is(err, ErrTransferBudget) {
+			return "", "", err
+		}
 		if err != nil {
 			lastErr = err
-			time.Sleep(2 * time.Second)
+			time.Sleep(RetryDelay)
 			continue
 		}
 
-		if throttle > 0 {
-			time.Sleep(throttle)
+		pace(throttle)
+		return string(body), req.URL.String(), nil
+	}
+	return "", "", fmt.Errorf("failed after retries: %w", lastErr)
+}
+
+// statusError classifies a non-200 response
+func statusError(code int) error {
+	switch code {
+	case http.StatusNotFound, http.StatusGone:
+		return fmt.Errorf("%w: status code %d", errs.ErrNotFound, code)
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return fmt.Errorf("%w: status code %d", errs.ErrRateLimited, code)
+	}
+	return fmt.Errorf("status code %d", code)
+}
+
+// ListPageURL returns the URL of a page of the transcript listing
+func ListPageURL(pageNum int) string {
+	return config.ListURL(pageNum)
+}
+
+// ListPageNumber returns the page number of a listing URL of the active
+// source, or false if the URL is not one
+func ListPageNumber(u string) (int, bool) {
+	if s := config.ActiveSource; s != nil {
+		if s.FirstListURL != "" && u == s.FirstListURL {
+			return 1, true
 		}
-		return string(body), nil
+		re := regexp.MustCompile("^" + strings.ReplaceAll(regexp.QuoteMeta(s.ListURL), `\{page\}`, `(\d+)`) + "$")
+		if m := re.FindStringSubmatch(u); m != nil {
+			n, err := strconv.Atoi(m[1])
+			return n, err == nil
+		}
+		return 0, false
+	}
+	if u == config.BaseListURL {
+		return 1, true
+	}
+	if rest := strings.TrimPrefix(u, config.BaseListURL+"?page="); rest != u {
+		n, err := strconv.Atoi(rest)
+		return n, err == nil
 	}
-	return "", fmt.Errorf("failed after retries: %v", lastErr)
+	return 0, false
 }
 
 // GetListPageWithCacheStatus retrieves the list page content, using cache if appropriate
 // Returns content, isCached, error
 func GetListPageWithCacheStatus(pageNum int, dataDir string, forceRefresh bool, throttle time.Duration) (string, bool, error) {
-	filename := filepath.Join(dataDir, fmt.Sprintf("transcripts_page_%d.html", pageNum))
-
-	shouldDownload := true
-	if !forceRefresh {
-		if utils.FileExists(filename) {
-			// Cache logic: Pages > 5 are cached indefinitely
-			if pageNum > 5 {
-				shouldDownload = false
-			} else {
-				// Recent pages (1-5) are re-downloaded to check for updates
-				shouldDownload = true
-			}
-		}
-	}
+	filename := storage.Join(config.ListPageDir(dataDir), fmt.Sprintf("transcripts_page_%d.html", pageNum))
+
+	// How long a cached page is used for is set by ListCache
+	shouldDownload := forceRefresh || !storage.Exists(filename) || !listPageFresh(pageNum, filename, dataDir)
 
 	if !shouldDownload {
-		content, err := os.ReadFile(filename)
+		content, err := storage.ReadFile(filename)
 		if err == nil {
 			return string(content), true, nil
 		}
 	}
 
-	url := config.BaseListURL
-	if pageNum > 1 {
-		url = fmt.Sprintf("%s?page=%d", url, pageNum)
-	}
-
+	url := ListPageURL(pageNum)
 	fmt.Printf("Downloading list page %d: %s\n", pageNum, url)
-	content, err := DownloadPage(url, throttle)
+	content, final, err := DownloadPageURL(url, throttle)
 	if err != nil {
 		return "", false, err
 	}
+	// Past the last page the site may redirect to another page of the
+	// listing (usually the first), whose items were handled already
+	if n, ok := ListPageNumber(strings.TrimSuffix(final, "/")); final != url && ok && n != pageNum {
+		fmt.Printf("List page %d redirects to page %d: end of the listing\n", pageNum, n)
+		return "", false, nil
+	}
 
-	err = os.WriteFile(filename, []byte(content), 0644)
-	return content, false, err
+	if err := saveFile(filename, []byte(content)); err != nil {
+		return content, false, err
+	}
+	if err := listFetchLog(dataDir).record(pageNum, time.Now()); err != nil {
+		fmt.Printf("Warning: could not record fetch time of list page %d: %v\n", pageNum, err)
+	}
+	return content, false, nil
 }
 
 // Wrapper for backward compatibility if needed, though we updated main.go
@@ -107,56 +211,167 @@ func GetListPage(pageNum int, dataDir string, forceRefresh bool, throttle time.D
 	return content, err
 }
 
-// ExtractItems parses the HTML list page to find transcripts
-func ExtractItems(html string) []Item {
-	// <div class="item summary">.*?<h2 class="title"><a href="([^"]+)">([^<]+)</a></h2>
-	re := regexp.MustCompile(`(?s)<div class="item summary">.*?<h2 class="title"><a href="([^"]+)">([^<]+)</a></h2>`)
-	matches := re.FindAllStringSubmatch(html, -1)
+var (
+	// itemRegex finds the transcripts of a twit.tv listing page
+	itemRegex    = regexp.MustCompile(`(?s)<div class="item summary">.*?<h2 class="title"><a href="([^"]+)">([^<]+)</a></h2>`)
+	listTagRegex = regexp.MustCompile(`<[^>]+>`)
+)
+
+// ExtractItems parses the HTML list page to find transcripts, with the
+// item patterns of config.ActiveSource if one is set. The first pattern
+// that finds any items is used.
+func ExtractItems(page string) []Item {
+	patterns := []*regexp.Regexp{itemRegex}
+	if s := config.ActiveSource; s != nil {
+		patterns = s.Items
+	}
+	for _, re := range patterns {
+		if items := extractItems(re, page); len(items) > 0 {
+			return items
+		}
+	}
+	return nil
+}
 
+func extractItems(re *regexp.Regexp, page string) []Item {
 	var items []Item
-	for _, match := range matches {
-		if len(match) >= 3 {
-			url := match[1]
-			// Security: Ensure strict relative path
-			if !strings.HasPrefix(url, "/") {
-				continue
-			}
+	for _, match := range re.FindAllStringSubmatch(page, -1) {
+		url := match[1]
+		// Links on the site itself are made relative
+		if site := config.SiteURL(); strings.HasPrefix(url, site+"/") {
+			url = strings.TrimPrefix(url, site)
+		}
+		// Security: Ensure strict relative path
+		if !strings.HasPrefix(url, "/") || strings.HasPrefix(url, "//") {
+			continue
+		}
 
-			items = append(items, Item{
-				URL:   url,
-				Title: strings.TrimSpace(match[2]),
-			})
+		title := match[2]
+		if config.ActiveSource != nil {
+			// Patterns of other sites may capture markup around the title
+			title = html.UnescapeString(listTagRegex.ReplaceAllString(title, ""))
 		}
+		items = append(items, Item{
+			URL:   url,
+			Title: strings.TrimSpace(title),
+		})
 	}
 	return items
 }
 
+// titleNumberRegex matches the episode number in a listing title
+var titleNumberRegex = regexp.MustCompile(`(\d+)`)
+
+// TitleEpisode returns the episode number from a listing title, or
+// "unknown" if it has none
+func TitleEpisode(title string) string {
+	if matches := titleNumberRegex.FindStringSubmatch(title); len(matches) > 1 {
+		return matches[1]
+	}
+	return "unknown"
+}
+
 // DownloadTranscriptWithStatus downloads a specific transcript
 // Returns skipped (bool) and error
 func DownloadTranscriptWithStatus(urlPath, title, prefix, dataDir string, throttle time.Duration) (bool, error) {
-	// Extract episode number
-	re := regexp.MustCompile(`(\d+)`)
-	matches := re.FindStringSubmatch(title)
-	epNum := "unknown"
-	if len(matches) > 1 {
-		epNum = matches[1]
+	return DownloadTranscriptWithFilter(urlPath, title, prefix, dataDir, throttle, converter.Filter{})
+}
+
+// DownloadTranscriptWithFilter downloads a transcript if it passes the filter.
+// The episode range is checked against the title before downloading; the
+// date range needs the page's byline, so out-of-range pages are fetched but
+// not saved. Filtered transcripts return ErrFiltered, and placeholder pages
+// without a transcript yet ErrPending. Redirects are followed; a page whose
+// canonical URL is archived under another name already is skipped rather
+// than saved twice, and the listed URL is recorded in the index as an alias
+// so later runs skip it without downloading.
+func DownloadTranscriptWithFilter(urlPath, title, prefix, dataDir string, throttle time.Duration, filter converter.Filter) (bool, error) {
+	epNum := TitleEpisode(title)
+
+	if n, _ := strconv.Atoi(epNum); !filter.MatchEpisode(n) {
+		return false, ErrFiltered
 	}
 
-	filename := filepath.Join(dataDir, fmt.Sprintf("%s_%s.html", prefix, epNum))
+	filename := storage.Join(config.ActiveLayout.RawDir(dataDir, prefix), fmt.Sprintf("%s_%s.html", prefix, epNum))
 
-	if utils.FileExists(filename) {
-		return true, nil // Skipped
+	// Episodes of a renamed show may be archived under an earlier prefix.
+	// Placeholders saved before the transcript was out are checked again.
+	recheck := false
+	if Archived(prefix, epNum, dataDir) {
+		if !pending(prefix, epNum, dataDir) {
+			return true, nil // Skipped
+		}
+		recheck = true
 	}
 
-	fullURL := config.BaseSiteURL + urlPath
-	fmt.Printf("Downloading %s %s: %s\n", prefix, epNum, title)
+	fullURL := config.SiteURL() + urlPath
+	if !recheck {
+		if path, ok := archivedAt(fullURL, dataDir); ok {
+			fmt.Printf("Skipping %s %s: %s is archived as %s\n", prefix, epNum, fullURL, storage.Base(path))
+			return true, nil
+		}
+	}
+	if recheck {
+		fmt.Printf("Re-checking pending %s %s: %s\n", prefix, epNum, title)
+	} else {
+		fmt.Printf("Downloading %s %s: %s\n", prefix, epNum, title)
+	}
 
-	content, err := DownloadPage(fullURL, throttle)
+	content, final, err := DownloadPageURL(fullURL, throttle)
 	if err != nil {
 		return false, err
 	}
+	if err := converter.CheckPage(content); err != nil {
+		return false, err
+	}
+	if filter.HasDates() {
+		if t, _ := converter.PublishedDate(content); !filter.MatchDate(t) {
+			return false, ErrFiltered
+		}
+	}
 
-	return false, os.WriteFile(filename, []byte(content), 0644)
+	err = storeTranscript(content, fullURL, final, filename, dataDir, recheck)
+	if errors.Is(err, ErrDuplicate) {
+		fmt.Printf("Skipping %s %s: %v\n", prefix, epNum, err)
+		return true, nil
+	}
+	return false, err
+}
+
+// storeTranscript saves a downloaded transcript page, checked with
+// converter.CheckPage, to filename and records it in the index. listed is
+// the URL the page was requested from and final the one it was served from
+// after redirects. A page whose canonical URL is archived under another
+// name already is not saved; listed becomes an alias of the archived one
+// and ErrDuplicate is returned. Placeholder pages are saved and return
+// ErrPending.
+func storeTranscript(content, listed, final, filename, dataDir string, recheck bool) error {
+	canonical := converter.PageURL(content)
+	if canonical == "" {
+		canonical = final
+	}
+	if path, ok := archivedAt(canonical, dataDir); ok && index.Key(path) != index.Key(filename) {
+		if err := recordAlias(path, listed, dataDir); err != nil {
+			fmt.Printf("Warning: could not record %s in the index: %v\n", listed, err)
+		}
+		return fmt.Errorf("%w: same page as %s (%s)", ErrDuplicate, storage.Base(path), canonical)
+	}
+
+	placeholder := converter.IsPlaceholder(content)
+	if err := saveFile(filename, []byte(converter.StampSource(content, final, time.Now()))); err != nil {
+		return err
+	}
+	if _, err := recordFetched(filename, listed, dataDir); err != nil {
+		// The pending mark decides whether the next run downloads it again
+		if placeholder || recheck {
+			return err
+		}
+		fmt.Printf("Warning: could not record %s in the index: %v\n", storage.Base(filename), err)
+	}
+	if placeholder {
+		return ErrPending
+	}
+	return nil
 }
 
 // Wrapper
@@ -1,12 +1,21 @@
 package scraper
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/errs"
 )
 
 func TestExtractItems(t *testing.T) {
@@ -30,6 +39,45 @@ func TestExtractItems(t *testing.T) {
 	}
 }
 
+func TestExtractItemsSource(t *testing.T) {
+	config.ActiveSource = &config.Source{
+		Name:    "maxfun",
+		SiteURL: "https://example.org",
+		ListURL: "https://example.org/transcripts/page/{page}",
+		Items: []*regexp.Regexp{
+			regexp.MustCompile(`<a class="ep" href="([^"]+)">(.*?)</a>`),
+			regexp.MustCompile(`<li><a href="([^"]+)">(.*?)</a>`),
+		},
+	}
+	defer func() { config.ActiveSource = nil }()
+
+	page := `<li><a href="https://example.org/ep/12">Jordan <em>Jesse</em> Go! #12 &amp; more</a>
+	<li><a href="https://elsewhere.example/ep/13">Offsite</a>
+	<li><a href="//example.org/ep/14">Protocol-relative</a>`
+	items := ExtractItems(page)
+	if len(items) != 1 || items[0].URL != "/ep/12" || items[0].Title != "Jordan Jesse Go! #12 & more" {
+		t.Errorf("ExtractItems = %+v", items)
+	}
+
+	if n, ok := ListPageNumber("https://example.org/transcripts/page/7"); !ok || n != 7 {
+		t.Errorf("ListPageNumber = %d, %v", n, ok)
+	}
+	if _, ok := ListPageNumber("https://example.org/ep/12"); ok {
+		t.Error("A transcript URL is not a listing page")
+	}
+}
+
+func TestListPageNumber(t *testing.T) {
+	for u, want := range map[string]int{config.BaseListURL: 1, config.BaseListURL + "?page=4": 4} {
+		if n, ok := ListPageNumber(u); !ok || n != want {
+			t.Errorf("ListPageNumber(%s) = %d, %v; want %d", u, n, ok, want)
+		}
+	}
+	if _, ok := ListPageNumber(config.BaseListURL + "/sn-1000"); ok {
+		t.Error("A transcript URL is not a listing page")
+	}
+}
+
 func TestDownloadPage(t *testing.T) {
 	// Mock Server
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -47,6 +95,34 @@ func TestDownloadPage(t *testing.T) {
 	}
 }
 
+func TestDownloadPageIdentity(t *testing.T) {
+	var agent, from string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agent, from = r.Header.Get("User-Agent"), r.Header.Get("From")
+		fmt.Fprint(w, "<html>Content</html>")
+	}))
+	defer ts.Close()
+
+	oldAgent, oldFrom := config.UserAgent, config.From
+	defer func() { config.UserAgent, config.From = oldAgent, oldFrom }()
+	config.UserAgent, config.From = "twit-transcript-archiver/test", "archive@example.com"
+
+	if _, err := DownloadPage(ts.URL, 0); err != nil {
+		t.Fatal(err)
+	}
+	if agent != config.UserAgent || from != config.From {
+		t.Errorf("Sent User-Agent %q and From %q", agent, from)
+	}
+
+	config.From = ""
+	if _, err := DownloadPage(ts.URL, 0); err != nil {
+		t.Fatal(err)
+	}
+	if from != "" {
+		t.Errorf("Expected no From header, got %q", from)
+	}
+}
+
 func TestDownloadPage_RetryFail(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -59,6 +135,90 @@ func TestDownloadPage_RetryFail(t *testing.T) {
 	}
 }
 
+// roundTripFunc serves requests from a function instead of the network
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// fakeTransport routes the scraper's requests to serve for the rest of the
+// test, and turns off the delay between retries
+func fakeTransport(t *testing.T, serve func(req *http.Request) (int, string)) {
+	t.Helper()
+	savedTransport, savedDelay := Transport, RetryDelay
+	Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		code, body := serve(req)
+		return &http.Response{
+			StatusCode:    code,
+			Header:        make(http.Header),
+			Body:          io.NopCloser(strings.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	})
+	RetryDelay = 0
+	t.Cleanup(func() { Transport, RetryDelay = savedTransport, savedDelay })
+}
+
+func TestDownloadPage_Retries(t *testing.T) {
+	attempts := 0
+	fakeTransport(t, func(req *http.Request) (int, string) {
+		attempts++
+		if attempts < 3 {
+			return http.StatusServiceUnavailable, ""
+		}
+		return http.StatusOK, "finally"
+	})
+	body, err := DownloadPage("https://twit.tv/flaky", 0)
+	if err != nil || body != "finally" || attempts != 3 {
+		t.Errorf("DownloadPage = %q, %v after %d attempts; want success on the third", body, err, attempts)
+	}
+
+	attempts = 0
+	fakeTransport(t, func(req *http.Request) (int, string) {
+		attempts++
+		return http.StatusInternalServerError, ""
+	})
+	if _, err := DownloadPage("https://twit.tv/down", 0); err == nil || attempts != 3 {
+		t.Errorf("Expected failure after 3 attempts, got %v after %d", err, attempts)
+	}
+}
+
+func TestDownloadPage_ErrorClasses(t *testing.T) {
+	savedDelay := RetryDelay
+	RetryDelay = 0
+	defer func() { RetryDelay = savedDelay }()
+
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/busy":
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/short":
+			w.Header().Set("Content-Length", "100")
+			w.Write([]byte("<html>cut off"))
+		}
+	}))
+	defer ts.Close()
+
+	if _, err := DownloadPage(ts.URL+"/missing", 0); !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("A 404 should not be retried, got %d requests", requests)
+	}
+	if _, err := DownloadPage(ts.URL+"/busy", 0); !errors.Is(err, errs.ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, got %v", err)
+	}
+	if _, err := DownloadPage(ts.URL+"/short", 0); !errors.Is(err, errs.ErrTruncated) {
+		t.Errorf("Expected ErrTruncated, got %v", err)
+	}
+}
+
 func TestGetListPage_Cache(t *testing.T) {
 	tmpDir, _ := os.MkdirTemp("", "twittest")
 	defer os.RemoveAll(tmpDir)
@@ -76,13 +236,18 @@ func TestGetListPage_Cache(t *testing.T) {
 	}
 }
 
+// transcriptBody is a transcript body long enough not to be a placeholder
+var transcriptBody = `<div class="body textual"><p>` + strings.Repeat("Leo Laporte: It's time for the show. ", 20) + `</p></div>`
+
 func TestDownloadTranscript(t *testing.T) {
 	tmpDir, _ := os.MkdirTemp("", "twittest")
 	defer os.RemoveAll(tmpDir)
 
-	// Mock download by mocking DownloadPage?
-	// Since DownloadPage uses http.Get, we can't easily mock it without dependency injection or modifying global state (bad).
-	// But we can check if it SKIPS existing files without network.
+	var requested []string
+	fakeTransport(t, func(req *http.Request) (int, string) {
+		requested = append(requested, req.URL.Path)
+		return http.StatusOK, `<h1 class="post-title">Intelligent Machines 124 Transcript</h1>` + transcriptBody
+	})
 
 	filename := filepath.Join(tmpDir, "IM_123.html")
 	os.WriteFile(filename, []byte("Existing"), 0644)
@@ -96,4 +261,85 @@ func TestDownloadTranscript(t *testing.T) {
 	if string(content) != "Existing" {
 		t.Error("File was overwritten despite existing")
 	}
+	if len(requested) != 0 {
+		t.Errorf("Expected no requests for an archived episode, got %v", requested)
+	}
+
+	if err := DownloadTranscript("/posts/im-124", "Intelligent Machines 124 Transcript", "IM", tmpDir, 0); err != nil {
+		t.Fatalf("DownloadTranscript failed: %v", err)
+	}
+	if len(requested) != 1 || requested[0] != "/posts/im-124" {
+		t.Errorf("Unexpected requests %v", requested)
+	}
+	content, _ = os.ReadFile(filepath.Join(tmpDir, "IM_124.html"))
+	if !strings.Contains(string(content), "Intelligent Machines 124") {
+		t.Errorf("Downloaded transcript not saved, got %q", content)
+	}
+	if u, at, ok := converter.PageSource(string(content)); !ok || u != config.BaseSiteURL+"/posts/im-124" || time.Since(at) > time.Minute {
+		t.Errorf("Expected the saved page stamped with its source, got %q, %v", u, at)
+	}
+}
+
+func TestGetListPage_RefreshesRecentPages(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "twittest")
+	defer os.RemoveAll(tmpDir)
+
+	requests := 0
+	fakeTransport(t, func(req *http.Request) (int, string) {
+		requests++
+		return http.StatusOK, "Fresh " + req.URL.RawQuery
+	})
+	for _, n := range []int{1, 6} {
+		os.WriteFile(filepath.Join(tmpDir, fmt.Sprintf("transcripts_page_%d.html", n)), []byte("Cached"), 0644)
+	}
+
+	content, cached, err := GetListPageWithCacheStatus(1, tmpDir, false, 0)
+	if err != nil || cached || content != "Fresh " {
+		t.Errorf("Page 1 = %q, cached %v, %v; want a fresh download", content, cached, err)
+	}
+	content, cached, _ = GetListPageWithCacheStatus(6, tmpDir, false, 0)
+	if !cached || content != "Cached" || requests != 1 {
+		t.Errorf("Page 6 = %q, cached %v after %d requests; want the cached copy", content, cached, requests)
+	}
+	content, _, _ = GetListPageWithCacheStatus(6, tmpDir, true, 0)
+	if content != "Fresh page=6" || requests != 2 {
+		t.Errorf("Refreshing page 6 = %q after %d requests", content, requests)
+	}
+	if saved, _ := os.ReadFile(filepath.Join(tmpDir, "transcripts_page_6.html")); string(saved) != "Fresh page=6" {
+		t.Errorf("Refreshed page not cached, got %q", saved)
+	}
+}
+
+func TestDownloadTranscriptWithFilter(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "twittest")
+	defer os.RemoveAll(tmpDir)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<h1 class="post-title">Security Now 950</h1><p class="byline">May 10th 2022</p>`+transcriptBody)
+	}))
+	defer ts.Close()
+	saved := config.BaseSiteURL
+	config.BaseSiteURL = ts.URL
+	defer func() { config.BaseSiteURL = saved }()
+
+	filter, _ := converter.NewFilter("900-950", "", "")
+	if _, err := DownloadTranscriptWithFilter("/ep", "Security Now 951", "SN", tmpDir, 0, filter); !errors.Is(err, ErrFiltered) {
+		t.Errorf("Expected ErrFiltered for episode outside range, got %v", err)
+	}
+
+	filter, _ = converter.NewFilter("900-950", "2023-01-01", "")
+	if _, err := DownloadTranscriptWithFilter("/ep", "Security Now 950", "SN", tmpDir, 0, filter); !errors.Is(err, ErrFiltered) {
+		t.Errorf("Expected ErrFiltered for date outside range, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "SN_950.html")); err == nil {
+		t.Error("Out-of-range transcript was saved")
+	}
+
+	filter, _ = converter.NewFilter("900-950", "2022-01-01", "2022-12-31")
+	if _, err := DownloadTranscriptWithFilter("/ep", "Security Now 950", "SN", tmpDir, 0, filter); err != nil {
+		t.Errorf("Download failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "SN_950.html")); err != nil {
+		t.Error("In-range transcript was not saved")
+	}
 }
@@ -0,0 +1,46 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+)
+
+// SearchShow queries the site search for one show's transcripts instead of
+// paging through the full listing. Result pages are followed until one adds
+// no new transcripts for the show or pages is reached. Returns the matching
+// items and the number of result pages fetched.
+func SearchShow(prefix string, pages int, throttle time.Duration) ([]Item, int, error) {
+	query := url.QueryEscape(config.ShowName(prefix) + " transcript")
+	seen := make(map[string]bool)
+	var items []Item
+	fetched := 0
+	for pageNum := 1; pageNum <= pages; pageNum++ {
+		u := fmt.Sprintf(config.SearchURL, query)
+		if pageNum > 1 {
+			u = fmt.Sprintf("%s&page=%d", u, pageNum)
+		}
+		fmt.Printf("Searching %s, page %d: %s\n", prefix, pageNum, u)
+		html, err := DownloadPage(u, throttle)
+		if err != nil {
+			return items, fetched, err
+		}
+		fetched++
+
+		added := 0
+		for _, item := range ExtractItems(html) {
+			if config.PrefixForTitle(item.Title) != prefix || seen[item.URL] {
+				continue
+			}
+			seen[item.URL] = true
+			items = append(items, item)
+			added++
+		}
+		if added == 0 {
+			break
+		}
+	}
+	return items, fetched, nil
+}
@@ -0,0 +1,47 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+)
+
+func TestSearchShow(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Query().Get("q") != "security now transcript" {
+			t.Errorf("Unexpected query %q", r.URL.Query().Get("q"))
+		}
+		item := func(url, title string) {
+			fmt.Fprintf(w, `<div class="item summary"><h2 class="title"><a href="%s">%s</a></h2></div>`, url, title)
+		}
+		switch r.URL.Query().Get("page") {
+		case "":
+			item("/sn2", "Security Now 2 Transcript")
+			item("/ww1", "Windows Weekly 1 Transcript")
+		case "2":
+			item("/sn1", "Security Now 1 Transcript")
+		default:
+			item("/sn1", "Security Now 1 Transcript") // Nothing new: stop
+		}
+	}))
+	defer ts.Close()
+	saved := config.SearchURL
+	config.SearchURL = ts.URL + "/search?q=%s"
+	defer func() { config.SearchURL = saved }()
+
+	items, pages, err := SearchShow("SN", 10, 0)
+	if err != nil {
+		t.Fatalf("SearchShow failed: %v", err)
+	}
+	if len(items) != 2 || items[0].URL != "/sn2" || items[1].URL != "/sn1" {
+		t.Errorf("Unexpected items: %v", items)
+	}
+	if pages != 3 || requests != 3 {
+		t.Errorf("Expected 3 result pages, got %d (%d requests)", pages, requests)
+	}
+}
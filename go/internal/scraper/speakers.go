@@ -0,0 +1,40 @@
+package scraper
+
+import (
+	"sync"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+// speakerProfiles caches the learned speaker profiles of each show (keyed
+// by data directory and prefix) for the rest of the run
+var (
+	speakerProfilesMu sync.Mutex
+	speakerProfiles   = make(map[string][]converter.SpeakerProfile)
+)
+
+// ReconcileSpeakers names the diarization labels of cues ("SPEAKER_01")
+// after the show's people, learned from its archived transcripts (see
+// converter.ReconcileSpeakers), so captions and speech recognition output
+// share the speaker names of published transcripts. Returns the renamed
+// labels.
+func ReconcileSpeakers(cues []converter.Cue, prefix, dataDir string) map[string]string {
+	labelled := false
+	for _, c := range cues {
+		labelled = labelled || converter.IsDiarizationLabel(c.Speaker)
+	}
+	if !labelled {
+		return nil
+	}
+
+	key := dataDir + "\x00" + prefix
+	speakerProfilesMu.Lock()
+	profiles, ok := speakerProfiles[key]
+	if !ok {
+		// Without profiles the labels stay, which is no worse than before
+		profiles, _ = converter.ShowSpeakers(prefix, dataDir)
+		speakerProfiles[key] = profiles
+	}
+	speakerProfilesMu.Unlock()
+	return converter.ReconcileSpeakers(cues, profiles)
+}
@@ -0,0 +1,34 @@
+package scraper
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+func TestReconcileSpeakers(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "twittest")
+	defer os.RemoveAll(tmpDir)
+	os.WriteFile(filepath.Join(tmpDir, "SN_1.html"), []byte(`<h1 class="post-title">Security Now 1</h1>
+<div class="hosts"><a href="/people/leo-laporte">Leo Laporte</a><a href="/people/steve-gibson">Steve Gibson</a></div>
+<div class="body textual"><p>0:00:00 - Leo Laporte<br>
+Hello.</p></div>`), 0644)
+
+	cues := []converter.Cue{
+		{Speaker: "SPEAKER_00", Text: "Hi, I'm Steve."},
+		{Speaker: "SPEAKER_01", Text: "And this is Leo Laporte."},
+	}
+	renamed := ReconcileSpeakers(cues, "SN", tmpDir)
+	if !reflect.DeepEqual(renamed, map[string]string{"SPEAKER_00": "Steve Gibson", "SPEAKER_01": "Leo Laporte"}) {
+		t.Errorf("ReconcileSpeakers = %v", renamed)
+	}
+	if cues[0].Speaker != "Steve Gibson" {
+		t.Errorf("Cue not renamed: %+v", cues[0])
+	}
+	if renamed := ReconcileSpeakers([]converter.Cue{{Speaker: "Leo", Text: "Hi"}}, "SN", tmpDir); renamed != nil {
+		t.Errorf("Cues without labels = %v", renamed)
+	}
+}
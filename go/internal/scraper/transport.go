@@ -0,0 +1,80 @@
+package scraper
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
+)
+
+// MaxIdleConnsPerHost is how many idle connections to one host the scraper
+// keeps open for reuse. The default transport keeps two, so concurrent
+// fetches (listing pages, media) would keep opening new ones.
+var MaxIdleConnsPerHost = 16
+
+// NewTransport returns the transport shared by scraper requests. It keeps
+// connections open between requests and negotiates HTTP/2 where the server
+// supports it, so a crawl of thousands of pages reuses a handful of
+// connections instead of a TLS handshake per page.
+func NewTransport() *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   MaxIdleConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: time.Minute,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// ConnStats counts the scraper's requests by how their connection was
+// obtained and the protocol they were answered with
+type ConnStats struct {
+	Requests int64
+	Opened   int64 // Requests that had to open a new connection
+	Reused   int64 // Requests sent on a kept-alive connection
+	HTTP2    int64 // Responses received over HTTP/2
+}
+
+var connStats ConnStats
+
+// Connections returns the connection statistics of the run so far
+func Connections() ConnStats {
+	return ConnStats{
+		Requests: atomic.LoadInt64(&connStats.Requests),
+		Opened:   atomic.LoadInt64(&connStats.Opened),
+		Reused:   atomic.LoadInt64(&connStats.Reused),
+		HTTP2:    atomic.LoadInt64(&connStats.HTTP2),
+	}
+}
+
+// connTrace counts how each request got its connection
+var connTrace = &httptrace.ClientTrace{
+	GotConn: func(info httptrace.GotConnInfo) {
+		if info.Reused {
+			atomic.AddInt64(&connStats.Reused, 1)
+		} else {
+			atomic.AddInt64(&connStats.Opened, 1)
+		}
+	},
+}
+
+// traceConn records a request in the connection statistics
+func traceConn(req *http.Request) *http.Request {
+	atomic.AddInt64(&connStats.Requests, 1)
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), connTrace))
+}
+
+// countProto records the protocol a response came over
+func countProto(resp *http.Response) {
+	if resp.ProtoMajor == 2 {
+		atomic.AddInt64(&connStats.HTTP2, 1)
+	}
+}
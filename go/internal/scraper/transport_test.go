@@ -0,0 +1,40 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTransportReusesConnections(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html>Content</html>")
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	tr := NewTransport()
+	tr.TLSClientConfig = ts.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+	saved := Transport
+	Transport = tr
+	defer func() { Transport = saved }()
+
+	before := Connections()
+	for i := 0; i < 3; i++ {
+		if _, err := DownloadPage(ts.URL, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	after := Connections()
+	got := ConnStats{
+		Requests: after.Requests - before.Requests,
+		Opened:   after.Opened - before.Opened,
+		Reused:   after.Reused - before.Reused,
+		HTTP2:    after.HTTP2 - before.HTTP2,
+	}
+	if want := (ConnStats{Requests: 3, Opened: 1, Reused: 2, HTTP2: 3}); got != want {
+		t.Errorf("Connections = %+v, want %+v", got, want)
+	}
+}
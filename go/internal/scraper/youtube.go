@@ -0,0 +1,137 @@
+package scraper
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/errs"
+)
+
+// YouTubeURL is where video feeds and captions are requested; tests point
+// it at a local server
+var YouTubeURL = "https://www.youtube.com"
+
+// ErrNoCaptions is returned by IngestVideo for videos without captions in
+// the requested language
+var ErrNoCaptions = errors.New("no captions")
+
+// videoIDRegex matches a YouTube video id
+var videoIDRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// Video is an entry of a channel or playlist feed
+type Video struct {
+	ID        string
+	Title     string
+	Published time.Time
+}
+
+// WatchURL is the video's page, recorded as the source of its transcript
+func (v Video) WatchURL() string {
+	return YouTubeURL + "/watch?v=" + v.ID
+}
+
+// VideoFeedURL returns the Atom feed of a channel's (UC...) or a
+// playlist's (PL...) latest videos; exactly one must be given
+func VideoFeedURL(channel, playlist string) (string, error) {
+	switch {
+	case channel != "" && playlist != "":
+		return "", fmt.Errorf("give a channel or a playlist, not both")
+	case channel != "":
+		return YouTubeURL + "/feeds/videos.xml?channel_id=" + url.QueryEscape(channel), nil
+	case playlist != "":
+		return YouTubeURL + "/feeds/videos.xml?playlist_id=" + url.QueryEscape(playlist), nil
+	}
+	return "", fmt.Errorf("no YouTube channel or playlist configured")
+}
+
+type videoFeed struct {
+	Entries []struct {
+		ID        string `xml:"http://www.youtube.com/xml/schemas/2015 videoId"`
+		Title     string `xml:"title"`
+		Published string `xml:"published"`
+	} `xml:"entry"`
+}
+
+// ParseVideoFeed reads the videos of a channel or playlist feed, newest
+// first as YouTube lists them
+func ParseVideoFeed(data []byte) ([]Video, error) {
+	var feed videoFeed
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		return nil, fmt.Errorf("invalid video feed: %w", err)
+	}
+	var videos []Video
+	for _, e := range feed.Entries {
+		if !videoIDRegex.MatchString(e.ID) {
+			continue
+		}
+		v := Video{ID: e.ID, Title: e.Title}
+		v.Published, _ = time.Parse(time.RFC3339, e.Published)
+		videos = append(videos, v)
+	}
+	return videos, nil
+}
+
+// CaptionsURL returns the WebVTT captions of a video in a language: the
+// uploaded ones, or with auto the automatically generated ones
+func CaptionsURL(id, lang string, auto bool) string {
+	q := url.Values{"v": {id}, "lang": {lang}, "fmt": {"vtt"}}
+	if auto {
+		q.Set("kind", "asr")
+	}
+	return YouTubeURL + "/api/timedtext?" + q.Encode()
+}
+
+// IngestVideo downloads a video's captions in lang, preferring uploaded
+// captions to automatic ones (which are only tried with auto), and
+// ingests them as a transcript page marked converter.OriginCaptions. The
+// show comes from opts.Prefix or the video title, as does the episode
+// number unless opts.Episode is set. Archived episodes are skipped with
+// ErrArchived unless opts.Force is set, so only episodes without a
+// transcript are filled in.
+func IngestVideo(v Video, dataDir string, throttle time.Duration, lang string, auto bool, opts IngestOptions) (string, error) {
+	if opts.Prefix == "" {
+		opts.Prefix = config.PrefixForTitle(v.Title)
+	}
+	if opts.Prefix == "" {
+		return "", fmt.Errorf("cannot tell the show from title %q (use --show)", v.Title)
+	}
+	if opts.Episode == 0 {
+		opts.Episode, _ = strconv.Atoi(TitleEpisode(v.Title))
+	}
+	if opts.Episode == 0 {
+		return "", fmt.Errorf("cannot tell the episode number of %q", v.Title)
+	}
+	if !opts.Force && Archived(opts.Prefix, strconv.Itoa(opts.Episode), dataDir) {
+		return "", ErrArchived
+	}
+
+	kinds := []bool{false}
+	if auto {
+		kinds = append(kinds, true)
+	}
+	for _, asr := range kinds {
+		// YouTube answers with a 404 or an empty document when there are none
+		data, err := DownloadPage(CaptionsURL(v.ID, lang, asr), throttle)
+		if errors.Is(err, errs.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		cues, err := converter.ParseCaptions([]byte(data), converter.CaptionsVTT)
+		if err != nil {
+			continue
+		}
+		ReconcileSpeakers(cues, opts.Prefix, dataDir)
+		opts.URL = v.WatchURL()
+		return IngestPage(converter.CaptionsPage(v.Title, v.Published, converter.OriginCaptions, cues), dataDir, opts)
+	}
+	return "", ErrNoCaptions
+}
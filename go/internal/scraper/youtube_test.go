@@ -0,0 +1,87 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+const testVideoFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">
+  <entry>
+    <yt:videoId>abcdefghijk</yt:videoId>
+    <title>Security Now 1001: Captions Only</title>
+    <published>2024-11-26T20:00:00+00:00</published>
+  </entry>
+  <entry>
+    <yt:videoId>bad id</yt:videoId>
+    <title>Broken</title>
+  </entry>
+</feed>`
+
+func TestParseVideoFeed(t *testing.T) {
+	videos, err := ParseVideoFeed([]byte(testVideoFeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(videos) != 1 || videos[0].ID != "abcdefghijk" || videos[0].Published.Format("2006-01-02") != "2024-11-26" {
+		t.Errorf("ParseVideoFeed = %+v", videos)
+	}
+	if u, err := VideoFeedURL("UC123", ""); err != nil || !strings.HasSuffix(u, "/feeds/videos.xml?channel_id=UC123") {
+		t.Errorf("VideoFeedURL = %s, %v", u, err)
+	}
+	if _, err := VideoFeedURL("UC123", "PL456"); err == nil {
+		t.Error("Expected an error for both a channel and a playlist")
+	}
+	if _, err := VideoFeedURL("", ""); err == nil {
+		t.Error("Expected an error without a channel or playlist")
+	}
+}
+
+func TestIngestVideo(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "twittest")
+	defer os.RemoveAll(tmpDir)
+
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Query().Get("kind"))
+		if r.URL.Query().Get("kind") != "asr" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, "WEBVTT\n\n00:00:05.000 --> 00:00:07.000\nhello and welcome\n")
+	}))
+	defer ts.Close()
+	saved := YouTubeURL
+	YouTubeURL = ts.URL
+	defer func() { YouTubeURL = saved }()
+
+	videos, _ := ParseVideoFeed([]byte(testVideoFeed))
+	if _, err := IngestVideo(videos[0], tmpDir, 0, "en", false, IngestOptions{}); !errors.Is(err, ErrNoCaptions) {
+		t.Errorf("Without automatic captions = %v, want ErrNoCaptions", err)
+	}
+	path, err := IngestVideo(videos[0], tmpDir, 0, "en", true, IngestOptions{})
+	if err != nil || filepath.Base(path) != "SN_1001.html" {
+		t.Fatalf("IngestVideo = %s, %v", path, err)
+	}
+	if strings.Join(requests, ",") != ",,asr" {
+		t.Errorf("Requested kinds %q, want uploaded captions first", requests)
+	}
+	data, _ := os.ReadFile(path)
+	if converter.PageOrigin(string(data)) != converter.OriginCaptions || !strings.Contains(string(data), "(00:00:05): hello and welcome") {
+		t.Errorf("Saved page:\n%s", data)
+	}
+	if src, _, _ := converter.PageSource(string(data)); src != ts.URL+"/watch?v=abcdefghijk" {
+		t.Errorf("Source = %s", src)
+	}
+	if _, err := IngestVideo(videos[0], tmpDir, 0, "en", true, IngestOptions{}); !errors.Is(err, ErrArchived) {
+		t.Errorf("Second ingest = %v, want ErrArchived", err)
+	}
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// entryFilter selects index entries. Zero fields match everything.
+type entryFilter struct {
+	Show             string // Prefix or name, as accepted by config.ResolveShow
+	Number           int
+	Tag, Host, Guest string
+	Since, Until     string // YYYY-MM-DD, inclusive; undated entries never match
+	Pending          bool   // Only placeholders without a transcript yet
+}
+
+// matchEntries returns the index entries matching a filter, in index order
+func matchEntries(ix *index.Index, f entryFilter) ([]*index.Entry, error) {
+	prefixes := map[string]bool{}
+	if f.Show != "" {
+		prefix, ok := config.ResolveShow(f.Show)
+		if !ok {
+			return nil, config.UnknownShowError(f.Show)
+		}
+		for _, p := range config.EraPrefixes(prefix) {
+			prefixes[p] = true
+		}
+	}
+	var entries []*index.Entry
+	for _, e := range ix.Sorted() {
+		switch {
+		case len(prefixes) > 0 && !prefixes[e.Prefix],
+			f.Number != 0 && e.Number != f.Number,
+			f.Tag != "" && !e.HasTag(f.Tag),
+			f.Host != "" && !e.HasHost(f.Host),
+			f.Guest != "" && !e.HasGuest(f.Guest),
+			f.Since != "" && (e.Date == "" || e.Date < f.Since),
+			f.Until != "" && (e.Date == "" || e.Date > f.Until),
+			f.Pending && !e.Pending:
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// segment is one speaker turn of an indexed episode
+type segment struct {
+	Entry *index.Entry
+	Turn  int
+	Seg   converter.Segment
+	Who   string
+}
+
+// segmentFilter selects speaker turns. Zero fields match everything.
+type segmentFilter struct {
+	Speaker string   // A name or its start, in whole words ("Leo" matches "Leo Laporte")
+	Terms   []string // Text that must all appear in the turn, case-insensitively
+}
+
+// findSegments returns the turns of the entries' episodes that match a
+// filter, parsing the transcripts (or reusing cached parses)
+func (s *Server) findSegments(entries []*index.Entry, f segmentFilter) ([]segment, error) {
+	speaker := strings.ToLower(f.Speaker)
+	terms := make([]string, len(f.Terms))
+	for i, t := range f.Terms {
+		terms[i] = strings.ToLower(t)
+	}
+
+	var segs []segment
+	for _, e := range entries {
+		ep, err := s.loadEpisode(s.entryPath(e))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.File, err)
+		}
+	turns:
+		for i, seg := range converter.Turns(ep.Content) {
+			who := ""
+			if i < len(ep.Turns) {
+				who = ep.Turns[i].Speaker
+			}
+			if speaker != "" && !speakerMatches(strings.ToLower(who), speaker) {
+				continue
+			}
+			text := strings.ToLower(seg.Text)
+			for _, t := range terms {
+				if !strings.Contains(text, t) {
+					continue turns
+				}
+			}
+			segs = append(segs, segment{Entry: e, Turn: i, Seg: seg, Who: who})
+		}
+	}
+	return segs, nil
+}
+
+// speakerMatches reports whether a speaker name is want or starts with it
+// as whole words
+func speakerMatches(name, want string) bool {
+	return name == want || strings.HasPrefix(name, want+" ")
+}
+
+// entryPath is the transcript file of an index entry
+func (s *Server) entryPath(e *index.Entry) string {
+	return storage.Join(s.DataDir, e.File)
+}
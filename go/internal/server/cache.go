@@ -0,0 +1,111 @@
+package server
+
+import (
+	"container/list"
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// RemoteTTL is how long responses built from object storage are reused.
+// Remote files have no cheap modification time to check, so they are
+// re-read once per period instead.
+var RemoteTTL = time.Minute
+
+// Response is a rendered response body
+type Response struct {
+	ContentType string
+	Body        []byte
+	ETag        string
+}
+
+// Cache keeps the most recently used responses and parsed episodes, so
+// popular episodes are not parsed again on every request. Each value is
+// stored with the version of the file it was built from and is dropped once
+// the file changes.
+type Cache struct {
+	Max int // Values kept; the least recently used is evicted first
+
+	mu     sync.Mutex
+	ll     *list.List // Most recently used first
+	items  map[string]*list.Element
+	hits   int
+	misses int
+}
+
+type cacheEntry struct {
+	key, version string
+	value        interface{}
+}
+
+// NewCache returns a cache holding up to max values
+func NewCache(max int) *Cache {
+	return &Cache{Max: max, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+// Get returns the value cached for key, if it was built from this version
+// of its file
+func (c *Cache) Get(key, version string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok || el.Value.(*cacheEntry).version != version {
+		c.misses++
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	c.hits++
+	return el.Value.(*cacheEntry).value, true
+}
+
+// Put caches a value built from a version of its file
+func (c *Cache) Put(key, version string, value interface{}) {
+	if c.Max <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		el.Value = &cacheEntry{key, version, value}
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&cacheEntry{key, version, value})
+	for c.ll.Len() > c.Max {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// Stats returns the number of cached values, hits and misses
+func (c *Cache) Stats() (size, hits, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len(), c.hits, c.misses
+}
+
+// fileVersion identifies the current contents of a file: its modification
+// time and size for local files, the current RemoteTTL period for object
+// storage. A missing local file has version "".
+func fileVersion(path string) string {
+	if storage.IsRemote(path) {
+		return fmt.Sprintf("ttl-%d", time.Now().UnixNano()/int64(RemoteTTL))
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size())
+}
+
+// etag is the entity tag of the response for key built from a version of
+// its file, known before the response is built so unchanged clients can be
+// answered without parsing anything
+func etag(key, version string) string {
+	return fmt.Sprintf(`"%x"`, sha1.Sum([]byte(key+"\x00"+version)))
+}
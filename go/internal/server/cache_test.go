@@ -0,0 +1,51 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheLRU(t *testing.T) {
+	c := NewCache(2)
+	c.Put("a", "1", Response{Body: []byte("A")})
+	c.Put("b", "1", Response{Body: []byte("B")})
+	if _, ok := c.Get("a", "1"); !ok {
+		t.Fatal("a missing")
+	}
+	c.Put("c", "1", Response{Body: []byte("C")}) // evicts b, the least recently used
+	if _, ok := c.Get("b", "1"); ok {
+		t.Error("b was not evicted")
+	}
+	if v, ok := c.Get("a", "1"); !ok || string(v.(Response).Body) != "A" {
+		t.Errorf("a = %v, %v", v, ok)
+	}
+	if _, ok := c.Get("a", "2"); ok {
+		t.Error("stale version served")
+	}
+	if size, hits, misses := c.Stats(); size != 2 || hits != 2 || misses != 2 {
+		t.Errorf("Stats = %d, %d, %d", size, hits, misses)
+	}
+
+	off := NewCache(0)
+	off.Put("a", "1", Response{})
+	if _, ok := off.Get("a", "1"); ok {
+		t.Error("disabled cache stored a response")
+	}
+}
+
+func TestFileVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "SN_1.html")
+	if v := fileVersion(path); v != "" {
+		t.Errorf("missing file has version %q", v)
+	}
+	os.WriteFile(path, []byte("one"), 0644)
+	v1 := fileVersion(path)
+	os.WriteFile(path, []byte("two!"), 0644)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+	if v2 := fileVersion(path); v2 == v1 || v2 == "" {
+		t.Errorf("version did not change: %q -> %q", v1, v2)
+	}
+}
@@ -0,0 +1,594 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+)
+
+// Schema is the GraphQL schema served at /api/graphql. Dates are
+// YYYY-MM-DD strings; since and until are inclusive.
+const Schema = `type Query {
+  shows: [Show!]!
+  episodes(show: String, tag: String, host: String, guest: String, since: String, until: String, pending: Boolean, first: Int = 50, offset: Int = 0): EpisodePage!
+  episode(show: String!, number: Int!): Episode
+  # Speaker turns. speaker matches a name or its start ("Leo" matches "Leo Laporte"), contains the text.
+  segments(show: String, episode: Int, speaker: String, contains: String, since: String, until: String, first: Int = 50, offset: Int = 0): SegmentPage!
+}
+
+type Show {
+  prefix: String!
+  name: String!
+  description: String
+  episodes: Int!
+}
+
+type EpisodePage {
+  total: Int!
+  items: [Episode!]!
+}
+
+type Episode {
+  prefix: String!
+  show: String!
+  number: Int!
+  title: String!
+  date: String
+  words: Int!
+  language: String
+  hosts: [String!]!
+  guests: [String!]!
+  tags: [String!]!
+  topics: [String!]!
+  pending: Boolean!
+  file: String!
+  # Reading these parses the transcript
+  url: String
+  text: String!
+  segments(speaker: String, contains: String, first: Int = 0, offset: Int = 0): [Segment!]!
+}
+
+type SegmentPage {
+  total: Int!
+  items: [Segment!]!
+}
+
+type Segment {
+  prefix: String!
+  episode: Int!
+  title: String!
+  date: String
+  turn: Int!
+  speaker: String
+  timecode: String
+  startSeconds: Float
+  text: String!
+}
+`
+
+// fieldDef is a field of a schema type
+type fieldDef struct {
+	Type string
+	Args map[string]argDef
+}
+
+// argDef is an argument of a schema field
+type argDef struct {
+	Type, Default string
+}
+
+var (
+	sdlTypeRegex  = regexp.MustCompile(`^type (\w+) \{$`)
+	sdlFieldRegex = regexp.MustCompile(`^\s+(\w+)(?:\((.*)\))?: (\S+)$`)
+	sdlArgRegex   = regexp.MustCompile(`^(\w+): (\S+?)(?: = (\S+))?$`)
+)
+
+// schemaTypes is Schema parsed into its types' fields
+var schemaTypes = parseSchema(Schema)
+
+func parseSchema(sdl string) map[string]map[string]fieldDef {
+	types := make(map[string]map[string]fieldDef)
+	var cur map[string]fieldDef
+	for _, line := range strings.Split(sdl, "\n") {
+		if m := sdlTypeRegex.FindStringSubmatch(line); m != nil {
+			cur = make(map[string]fieldDef)
+			types[m[1]] = cur
+			continue
+		}
+		m := sdlFieldRegex.FindStringSubmatch(line)
+		if m == nil || cur == nil {
+			continue
+		}
+		def := fieldDef{Type: m[3], Args: make(map[string]argDef)}
+		if m[2] != "" {
+			for _, a := range strings.Split(m[2], ", ") {
+				am := sdlArgRegex.FindStringSubmatch(a)
+				if am == nil {
+					panic("server: bad schema argument " + a)
+				}
+				def.Args[am[1]] = argDef{Type: am[2], Default: am[3]}
+			}
+		}
+		cur[m[1]] = def
+	}
+	return types
+}
+
+// object is a JSON object that keeps the order of the query's fields
+type object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o object) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		b.Write(key)
+		b.WriteByte(':')
+		v, err := json.Marshal(o.values[k])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// graphQLRequest is the body of a POST to /api/graphql
+type graphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+func (s *Server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req graphQLRequest
+	switch r.Method {
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	case http.MethodGet:
+		req.Query = r.URL.Query().Get("query")
+		if v := r.URL.Query().Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				http.Error(w, "invalid variables: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	result := map[string]interface{}{}
+	data, err := s.Query(req.Query, req.Variables)
+	if err != nil {
+		result["errors"] = []map[string]string{{"message": err.Error()}}
+		result["data"] = nil
+	} else {
+		result["data"] = data
+	}
+	resp, err := jsonResponse(result)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", resp.ContentType)
+	w.Write(resp.Body)
+}
+
+func (s *Server) handleSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, Schema)
+}
+
+// Query executes a GraphQL query against the archive
+func (s *Server) Query(query string, vars map[string]interface{}) (interface{}, error) {
+	sel, err := parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	ix, _, err := s.index()
+	if err != nil {
+		return nil, err
+	}
+	x := &executor{s: s, ix: ix, vars: vars}
+	return x.selectObject("Query", sel, nil)
+}
+
+// executor runs one query
+type executor struct {
+	s    *Server
+	ix   *index.Index
+	vars map[string]interface{}
+}
+
+// selectObject resolves the selected fields of a value of a schema type
+func (x *executor) selectObject(typ string, sel []*field, parent interface{}) (object, error) {
+	out := object{values: make(map[string]interface{})}
+	for _, f := range sel {
+		if _, dup := out.values[f.Alias]; !dup {
+			out.keys = append(out.keys, f.Alias)
+		}
+		if f.Name == "__typename" {
+			out.values[f.Alias] = typ
+			continue
+		}
+		def, ok := schemaTypes[typ][f.Name]
+		if !ok {
+			return out, fmt.Errorf("cannot query field %q on type %q", f.Name, typ)
+		}
+		for name := range f.Args {
+			if _, ok := def.Args[name]; !ok {
+				return out, fmt.Errorf("unknown argument %q on field %s.%s", name, typ, f.Name)
+			}
+		}
+		for name, a := range def.Args {
+			if strings.HasSuffix(a.Type, "!") && x.arg(f, name) == nil {
+				return out, fmt.Errorf("field %s.%s needs argument %q", typ, f.Name, name)
+			}
+		}
+		f.def = def
+		v, err := x.resolve(typ, parent, f)
+		if err != nil {
+			return out, err
+		}
+		if out.values[f.Alias], err = x.complete(def.Type, v, f); err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+// complete shapes a resolved value as its schema type: lists element by
+// element, objects by their selection
+func (x *executor) complete(typ string, v interface{}, f *field) (interface{}, error) {
+	typ = strings.TrimSuffix(typ, "!")
+	if strings.HasPrefix(typ, "[") {
+		elem := strings.TrimSuffix(strings.TrimPrefix(typ, "["), "]")
+		items, _ := v.([]interface{})
+		list := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			c, err := x.complete(elem, item, f)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, c)
+		}
+		return list, nil
+	}
+	if _, isObject := schemaTypes[typ]; !isObject {
+		if f.Selection != nil {
+			return nil, fmt.Errorf("field %q of type %s has no subfields", f.Name, typ)
+		}
+		return v, nil
+	}
+	if f.Selection == nil {
+		return nil, fmt.Errorf("field %q of type %s needs a selection of subfields", f.Name, typ)
+	}
+	if v == nil {
+		return nil, nil
+	}
+	return x.selectObject(typ, f.Selection, v)
+}
+
+// arg returns an argument's value, with variables substituted
+func (x *executor) arg(f *field, name string) interface{} {
+	v, ok := f.Args[name]
+	if !ok {
+		return nil
+	}
+	if ref, ok := v.(variable); ok {
+		return x.vars[string(ref)]
+	}
+	return v
+}
+
+func (x *executor) stringArg(f *field, name string) (string, error) {
+	switch v := x.arg(f, name).(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	default:
+		return "", fmt.Errorf("argument %q of %s must be a String, not %v", name, f.Name, v)
+	}
+}
+
+// intArg returns an Int argument, or its schema default
+func (x *executor) intArg(f *field, name string) (int, error) {
+	switch v := x.arg(f, name).(type) {
+	case nil:
+		n, _ := strconv.Atoi(f.def.Args[name].Default)
+		return n, nil
+	case int:
+		return v, nil
+	case float64: // Numbers in JSON variables
+		if v == float64(int(v)) {
+			return int(v), nil
+		}
+	}
+	return 0, fmt.Errorf("argument %q of %s must be an Int", name, f.Name)
+}
+
+func (x *executor) boolArg(f *field, name string) (bool, error) {
+	switch v := x.arg(f, name).(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	}
+	return false, fmt.Errorf("argument %q of %s must be a Boolean", name, f.Name)
+}
+
+// page is the value of EpisodePage and SegmentPage
+type page struct {
+	Total int
+	Items []interface{}
+}
+
+// resolve returns the value of a field of a schema type
+func (x *executor) resolve(typ string, parent interface{}, f *field) (interface{}, error) {
+	switch typ {
+	case "Query":
+		return x.resolveQuery(f)
+	case "Show":
+		sh := parent.(showInfo)
+		switch f.Name {
+		case "prefix":
+			return sh.Prefix, nil
+		case "name":
+			return sh.Name, nil
+		case "description":
+			return nullable(sh.Description), nil
+		case "episodes":
+			return sh.Episodes, nil
+		}
+	case "EpisodePage", "SegmentPage":
+		p := parent.(page)
+		if f.Name == "total" {
+			return p.Total, nil
+		}
+		return p.Items, nil
+	case "Episode":
+		return x.resolveEpisode(parent.(*index.Entry), f)
+	case "Segment":
+		sg := parent.(segment)
+		switch f.Name {
+		case "prefix":
+			return sg.Entry.Prefix, nil
+		case "episode":
+			return sg.Entry.Number, nil
+		case "title":
+			return sg.Entry.Title, nil
+		case "date":
+			return nullable(sg.Entry.Date), nil
+		case "turn":
+			return sg.Turn, nil
+		case "speaker":
+			return nullable(sg.Who), nil
+		case "timecode":
+			return nullable(sg.Seg.Timecode), nil
+		case "startSeconds":
+			if sg.Seg.Timecode == "" {
+				return nil, nil
+			}
+			return sg.Seg.Start.Seconds(), nil
+		case "text":
+			return sg.Seg.Text, nil
+		}
+	}
+	return nil, fmt.Errorf("no resolver for %s.%s", typ, f.Name)
+}
+
+func (x *executor) resolveQuery(f *field) (interface{}, error) {
+	switch f.Name {
+	case "shows":
+		counts := make(map[string]int)
+		for _, e := range x.ix.Entries {
+			counts[config.CanonicalPrefix(e.Prefix)]++
+		}
+		var shows []interface{}
+		for _, sh := range config.Shows() {
+			shows = append(shows, showInfo{sh.Prefix, config.ShowName(sh.Prefix), sh.Description, counts[sh.Prefix]})
+		}
+		return shows, nil
+
+	case "episode":
+		show, _ := x.stringArg(f, "show")
+		n, err := x.intArg(f, "number")
+		if err != nil {
+			return nil, err
+		}
+		prefix, ok := config.ResolveShow(show)
+		if !ok {
+			return nil, config.UnknownShowError(show)
+		}
+		for _, p := range config.EraPrefixes(prefix) {
+			if e := x.ix.Entries[fmt.Sprintf("%s_%d", p, n)]; e != nil {
+				return e, nil
+			}
+		}
+		return nil, nil
+
+	case "episodes", "segments":
+		entries, err := x.filterEntries(f)
+		if err != nil {
+			return nil, err
+		}
+		first, err := x.intArg(f, "first")
+		if err != nil {
+			return nil, err
+		}
+		offset, err := x.intArg(f, "offset")
+		if err != nil {
+			return nil, err
+		}
+		var items []interface{}
+		if f.Name == "episodes" {
+			for _, e := range entries {
+				items = append(items, e)
+			}
+		} else if items, err = x.segments(entries, f); err != nil {
+			return nil, err
+		}
+		return page{len(items), paginate(items, first, offset)}, nil
+	}
+	return nil, fmt.Errorf("no resolver for Query.%s", f.Name)
+}
+
+// filterEntries returns the index entries matching the show, tag, people,
+// date and episode arguments of a field, in index order
+func (x *executor) filterEntries(f *field) ([]*index.Entry, error) {
+	var flt entryFilter
+	for name, dst := range map[string]*string{
+		"show": &flt.Show, "tag": &flt.Tag, "host": &flt.Host, "guest": &flt.Guest,
+		"since": &flt.Since, "until": &flt.Until,
+	} {
+		if _, ok := f.def.Args[name]; !ok {
+			continue
+		}
+		v, err := x.stringArg(f, name)
+		if err != nil {
+			return nil, err
+		}
+		*dst = v
+	}
+	var err error
+	if flt.Pending, err = x.boolArg(f, "pending"); err != nil {
+		return nil, err
+	}
+	if _, ok := f.def.Args["episode"]; ok {
+		if flt.Number, err = x.intArg(f, "episode"); err != nil {
+			return nil, err
+		}
+	}
+	return matchEntries(x.ix, flt)
+}
+
+// segments returns the speaker turns of the entries' episodes that match
+// the speaker and contains arguments of a field
+func (x *executor) segments(entries []*index.Entry, f *field) ([]interface{}, error) {
+	var flt segmentFilter
+	var err error
+	if flt.Speaker, err = x.stringArg(f, "speaker"); err != nil {
+		return nil, err
+	}
+	contains, err := x.stringArg(f, "contains")
+	if err != nil {
+		return nil, err
+	}
+	if contains != "" {
+		flt.Terms = []string{contains}
+	}
+	segs, err := x.s.findSegments(entries, flt)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]interface{}, len(segs))
+	for i, sg := range segs {
+		items[i] = sg
+	}
+	return items, nil
+}
+
+func (x *executor) resolveEpisode(e *index.Entry, f *field) (interface{}, error) {
+	switch f.Name {
+	case "prefix":
+		return e.Prefix, nil
+	case "show":
+		return config.ShowName(e.Prefix), nil
+	case "number":
+		return e.Number, nil
+	case "title":
+		return e.Title, nil
+	case "date":
+		return nullable(e.Date), nil
+	case "words":
+		return e.Words, nil
+	case "language":
+		return nullable(e.Language), nil
+	case "hosts":
+		return strings2list(e.Hosts), nil
+	case "guests":
+		return strings2list(e.Guests), nil
+	case "tags":
+		return strings2list(e.Tags), nil
+	case "topics":
+		return strings2list(e.Topics), nil
+	case "pending":
+		return e.Pending, nil
+	case "file":
+		return e.File, nil
+	}
+
+	ep, err := x.s.loadEpisode(x.s.entryPath(e))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", e.File, err)
+	}
+	switch f.Name {
+	case "url":
+		return nullable(ep.URL), nil
+	case "text":
+		return ep.Content, nil
+	case "segments":
+		items, err := x.segments([]*index.Entry{e}, f)
+		if err != nil {
+			return nil, err
+		}
+		first, err := x.intArg(f, "first")
+		if err != nil {
+			return nil, err
+		}
+		offset, err := x.intArg(f, "offset")
+		if err != nil {
+			return nil, err
+		}
+		return paginate(items, first, offset), nil
+	}
+	return nil, fmt.Errorf("no resolver for Episode.%s", f.Name)
+}
+
+// paginate returns up to first items (all if first is 0) after offset
+func paginate(items []interface{}, first, offset int) []interface{} {
+	if offset >= len(items) {
+		return []interface{}{}
+	}
+	if offset > 0 {
+		items = items[offset:]
+	}
+	if first > 0 && first < len(items) {
+		items = items[:first]
+	}
+	return items
+}
+
+// nullable returns nil for an empty string, which GraphQL renders as null
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func strings2list(ss []string) []interface{} {
+	list := make([]interface{}, len(ss))
+	for i, s := range ss {
+		list[i] = s
+	}
+	return list
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+)
+
+// graphQLArchive writes two TWiT episodes and indexes them
+func graphQLArchive(t *testing.T) *Server {
+	t.Helper()
+	dir := t.TempDir()
+	pages := map[string]string{
+		"TWIT_950.html": `<html><h1 class="post-title">This Week in Tech 950</h1><p class="byline">Oct 01 2023</p>` +
+			`<div class="body textual"><p>Leo Laporte: Welcome to TWiT.</p><p>Paris Martineau: Thanks, Leo.</p></div></html>`,
+		"TWIT_1000.html": `<html><h1 class="post-title">This Week in Tech 1000</h1><p class="byline">Sep 22 2024</p>` +
+			`<div class="body textual"><p>Leo Laporte: Episode one thousand!</p><p>Leo Laporte: Let's talk about AI.</p>` +
+			`<p>Jeff Jarvis: Not again.</p></div></html>`,
+	}
+	for name, html := range pages {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(html), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := index.AddFile(dir, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return New(dir, 16)
+}
+
+func TestGraphQLSegments(t *testing.T) {
+	s := graphQLArchive(t)
+	data, err := s.Query(`query($who: String) {
+		segments(speaker: $who, show: "TWIT", since: "2024-01-01") {
+			total
+			items { episode speaker text }
+		}
+	}`, map[string]interface{}{"who": "Leo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := json.Marshal(data)
+	want := `{"segments":{"total":2,"items":[` +
+		`{"episode":1000,"speaker":"Leo Laporte","text":"Leo Laporte Episode one thousand!"},` +
+		`{"episode":1000,"speaker":"Leo Laporte","text":"Leo Laporte Let's talk about AI."}]}}`
+	if string(got) != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestGraphQLEpisodes(t *testing.T) {
+	s := graphQLArchive(t)
+	data, err := s.Query(`{
+		all: episodes(show: "twit") { total items { number date } }
+		paged: episodes(first: 1, offset: 1) { total items { number __typename } }
+		one: episode(show: "TWIT", number: 950) { title segments(contains: "thanks") { speaker } }
+		none: episode(show: "TWIT", number: 1) { title }
+	}`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := json.Marshal(data)
+	want := `{"all":{"total":2,"items":[{"number":950,"date":"2023-10-01"},{"number":1000,"date":"2024-09-22"}]},` +
+		`"paged":{"total":2,"items":[{"number":1000,"__typename":"Episode"}]},` +
+		`"one":{"title":"This Week in Tech 950","segments":[{"speaker":"Paris Martineau"}]},` +
+		`"none":null}`
+	if string(got) != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	for query, msg := range map[string]string{
+		`{ episodes { items { salary } } }`:           `cannot query field "salary" on type "Episode"`,
+		`{ episodes(colour: "red") { total } }`:       `unknown argument "colour"`,
+		`{ episode(number: 1) { title } }`:            `needs argument "show"`,
+		`{ episodes(first: "ten") { total } }`:        `must be an Int`,
+		`{ episodes { total { x } } }`:                `has no subfields`,
+		`{ shows }`:                                   `needs a selection`,
+		`{ segments(show: "Nonexistent") { total } }`: `Nonexistent`,
+	} {
+		if _, err := s.Query(query, nil); err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("Query(%q) error = %v, want %q", query, err, msg)
+		}
+	}
+}
+
+func TestGraphQLHandler(t *testing.T) {
+	s := graphQLArchive(t)
+	h := s.Handler()
+
+	body := `{"query": "query($n: Int!) { episode(show: \"TWIT\", number: $n) { number } }", "variables": {"n": 1000}}`
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/api/graphql", strings.NewReader(body)))
+	if got := strings.Join(strings.Fields(rec.Body.String()), ""); got != `{"data":{"episode":{"number":1000}}}` {
+		t.Errorf("POST = %d %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/api/graphql?query=%7B+nope+%7D", nil))
+	if !strings.Contains(rec.Body.String(), `"errors"`) || !strings.Contains(rec.Body.String(), `"data": null`) {
+		t.Errorf("GET with a bad query = %s", rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/api/graphql/schema", nil))
+	if !strings.Contains(rec.Body.String(), "type Segment {") {
+		t.Errorf("schema = %s", rec.Body)
+	}
+}
@@ -0,0 +1,385 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+)
+
+// MCPProtocolVersion is the Model Context Protocol revision ServeMCP speaks
+const MCPProtocolVersion = "2024-11-05"
+
+// mcpMaxText caps the text of one search result, as turns can run for pages
+const mcpMaxText = 1000
+
+// rpcMessage is a JSON-RPC 2.0 request or notification
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// JSON-RPC error codes
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+// mcpTool is a tool offered to MCP clients
+type mcpTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+
+	call func(s *Server, args map[string]interface{}) (string, error)
+}
+
+// schema builds a JSON Schema object with the given properties
+func schema(required []string, props map[string]string) map[string]interface{} {
+	properties := make(map[string]interface{})
+	for name, spec := range props {
+		typ, desc, _ := strings.Cut(spec, ":")
+		properties[name] = map[string]string{"type": typ, "description": strings.TrimSpace(desc)}
+	}
+	s := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// mcpTools are the tools ServeMCP offers
+var mcpTools = []mcpTool{
+	{
+		Name:        "list_shows",
+		Description: "List the TWiT network shows with their prefixes and the number of archived episodes.",
+		InputSchema: schema(nil, nil),
+		call:        mcpListShows,
+	},
+	{
+		Name:        "list_episodes",
+		Description: "List archived episodes (show, number, date, title), optionally filtered by show, date range, tag, host or guest.",
+		InputSchema: schema(nil, map[string]string{
+			"show":   "string: Show prefix (SN) or name (Security Now)",
+			"since":  "string: Earliest date, YYYY-MM-DD",
+			"until":  "string: Latest date, YYYY-MM-DD",
+			"tag":    "string: Entity or topic from the tag index, e.g. OpenSSL or security",
+			"host":   "string: Host name",
+			"guest":  "string: Guest name",
+			"limit":  "integer: Episodes to return (default 50)",
+			"offset": "integer: Episodes to skip, for paging",
+		}),
+		call: mcpListEpisodes,
+	},
+	{
+		Name:        "search_transcripts",
+		Description: "Search the transcripts for speaker turns containing all the given words. Returns the show, episode, date, timecode, speaker and text of each match. Narrow by show or date for faster results.",
+		InputSchema: schema([]string{"query"}, map[string]string{
+			"query":   "string: Words that must all appear in the turn (case-insensitive)",
+			"show":    "string: Show prefix (SN) or name (Security Now)",
+			"speaker": "string: Speaker name or its start, e.g. Leo",
+			"since":   "string: Earliest date, YYYY-MM-DD",
+			"until":   "string: Latest date, YYYY-MM-DD",
+			"limit":   "integer: Matches to return (default 20)",
+			"offset":  "integer: Matches to skip, for paging",
+		}),
+		call: mcpSearch,
+	},
+	{
+		Name:        "get_episode",
+		Description: "Get the full transcript of an episode as Markdown, with its metadata as front matter.",
+		InputSchema: schema([]string{"show", "number"}, map[string]string{
+			"show":   "string: Show prefix (SN) or name (Security Now)",
+			"number": "integer: Episode number",
+		}),
+		call: mcpGetEpisode,
+	},
+}
+
+// ServeMCP serves the archive to an LLM assistant over the Model Context
+// Protocol's stdio transport: newline-delimited JSON-RPC messages read from
+// r, responses written to w. It returns when r is exhausted.
+func (s *Server) ServeMCP(r io.Reader, w io.Writer) error {
+	in := bufio.NewScanner(r)
+	in.Buffer(make([]byte, 64*1024), 16<<20)
+	out := json.NewEncoder(w)
+	for in.Scan() {
+		line := bytes.TrimSpace(in.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var msg rpcMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			if err := out.Encode(rpcResponse{JSONRPC: "2.0", Error: &rpcError{rpcParseError, err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+		if msg.ID == nil {
+			continue // Notifications (initialized, cancelled) need no answer
+		}
+		resp := rpcResponse{JSONRPC: "2.0", ID: msg.ID}
+		resp.Result, resp.Error = s.handleMCP(msg)
+		if err := out.Encode(resp); err != nil {
+			return err
+		}
+	}
+	return in.Err()
+}
+
+func (s *Server) handleMCP(msg rpcMessage) (interface{}, *rpcError) {
+	switch msg.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"protocolVersion": MCPProtocolVersion,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]string{"name": "twit-archiver", "version": "1.0"},
+			"instructions":    "Transcripts of the TWiT.tv network's podcasts. Use list_shows for show prefixes, search_transcripts to find what was said, and get_episode for a full transcript.",
+		}, nil
+	case "ping":
+		return map[string]interface{}{}, nil
+	case "tools/list":
+		return map[string]interface{}{"tools": mcpTools}, nil
+	case "tools/call":
+		var params struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &rpcError{rpcInvalidParams, err.Error()}
+		}
+		for _, t := range mcpTools {
+			if t.Name != params.Name {
+				continue
+			}
+			text, err := t.call(s, params.Arguments)
+			if err != nil {
+				return mcpText(err.Error(), true), nil
+			}
+			return mcpText(text, false), nil
+		}
+		return nil, &rpcError{rpcInvalidParams, fmt.Sprintf("unknown tool %q", params.Name)}
+	}
+	return nil, &rpcError{rpcMethodNotFound, fmt.Sprintf("method %q not found", msg.Method)}
+}
+
+// mcpText is a tool result holding text
+func mcpText(text string, isError bool) map[string]interface{} {
+	return map[string]interface{}{
+		"content": []map[string]string{{"type": "text", "text": text}},
+		"isError": isError,
+	}
+}
+
+func stringArg(args map[string]interface{}, name string) (string, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	}
+	return "", fmt.Errorf("argument %q must be a string", name)
+}
+
+func intArg(args map[string]interface{}, name string, def int) (int, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return def, nil
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), nil
+		}
+	case string: // Some clients send every argument as a string
+		var n int
+		if _, err := fmt.Sscan(v, &n); err == nil {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("argument %q must be an integer", name)
+}
+
+// mcpFilter reads the entry filter arguments of a tool call
+func mcpFilter(args map[string]interface{}) (entryFilter, error) {
+	var f entryFilter
+	for name, dst := range map[string]*string{
+		"show": &f.Show, "tag": &f.Tag, "host": &f.Host, "guest": &f.Guest,
+		"since": &f.Since, "until": &f.Until,
+	} {
+		v, err := stringArg(args, name)
+		if err != nil {
+			return f, err
+		}
+		*dst = v
+	}
+	return f, nil
+}
+
+// mcpPage reads the limit and offset arguments of a tool call
+func mcpPage(args map[string]interface{}, defLimit int) (limit, offset int, err error) {
+	if limit, err = intArg(args, "limit", defLimit); err != nil {
+		return 0, 0, err
+	}
+	offset, err = intArg(args, "offset", 0)
+	return limit, offset, err
+}
+
+func mcpListShows(s *Server, args map[string]interface{}) (string, error) {
+	ix, _, err := s.index()
+	if err != nil {
+		return "", err
+	}
+	counts := make(map[string]int)
+	for _, e := range ix.Entries {
+		counts[config.CanonicalPrefix(e.Prefix)]++
+	}
+	shows := config.Shows()
+	sort.Slice(shows, func(i, j int) bool { return shows[i].Prefix < shows[j].Prefix })
+	var b strings.Builder
+	for _, sh := range shows {
+		fmt.Fprintf(&b, "%s\t%s\t%d episodes", sh.Prefix, config.ShowName(sh.Prefix), counts[sh.Prefix])
+		if sh.Description != "" {
+			fmt.Fprintf(&b, "\t%s", sh.Description)
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+func mcpListEpisodes(s *Server, args map[string]interface{}) (string, error) {
+	f, err := mcpFilter(args)
+	if err != nil {
+		return "", err
+	}
+	limit, offset, err := mcpPage(args, 50)
+	if err != nil {
+		return "", err
+	}
+	ix, _, err := s.index()
+	if err != nil {
+		return "", err
+	}
+	entries, err := matchEntries(ix, f)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	shown := 0
+	for i, e := range entries {
+		if i < offset || shown >= limit {
+			continue
+		}
+		date := e.Date
+		if date == "" {
+			date = "----------"
+		}
+		fmt.Fprintf(&b, "%s %d\t%s\t%s\n", e.Prefix, e.Number, date, e.Title)
+		shown++
+	}
+	fmt.Fprintf(&b, "(%d of %d episodes)\n", shown, len(entries))
+	return b.String(), nil
+}
+
+func mcpSearch(s *Server, args map[string]interface{}) (string, error) {
+	query, err := stringArg(args, "query")
+	if err != nil {
+		return "", err
+	}
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return "", fmt.Errorf("query is empty")
+	}
+	speaker, err := stringArg(args, "speaker")
+	if err != nil {
+		return "", err
+	}
+	f, err := mcpFilter(args)
+	if err != nil {
+		return "", err
+	}
+	limit, offset, err := mcpPage(args, 20)
+	if err != nil {
+		return "", err
+	}
+	ix, _, err := s.index()
+	if err != nil {
+		return "", err
+	}
+	entries, err := matchEntries(ix, f)
+	if err != nil {
+		return "", err
+	}
+	segs, err := s.findSegments(entries, segmentFilter{Speaker: speaker, Terms: terms})
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	shown := 0
+	for i, sg := range segs {
+		if i < offset || shown >= limit {
+			continue
+		}
+		fmt.Fprintf(&b, "%s %d", sg.Entry.Prefix, sg.Entry.Number)
+		if sg.Entry.Date != "" {
+			fmt.Fprintf(&b, " (%s)", sg.Entry.Date)
+		}
+		if sg.Seg.Timecode != "" {
+			fmt.Fprintf(&b, " [%s]", sg.Seg.Timecode)
+		}
+		text := sg.Seg.Text
+		if len(text) > mcpMaxText {
+			text = strings.ToValidUTF8(text[:mcpMaxText], "") + "..."
+		}
+		fmt.Fprintf(&b, " %s\n", text)
+		shown++
+	}
+	fmt.Fprintf(&b, "(%d of %d matching turns)\n", shown, len(segs))
+	return b.String(), nil
+}
+
+func mcpGetEpisode(s *Server, args map[string]interface{}) (string, error) {
+	show, err := stringArg(args, "show")
+	if err != nil {
+		return "", err
+	}
+	n, err := intArg(args, "number", 0)
+	if err != nil {
+		return "", err
+	}
+	prefix, ok := config.ResolveShow(show)
+	if !ok {
+		return "", config.UnknownShowError(show)
+	}
+	path, err := s.episodeFile(prefix, n)
+	if err != nil {
+		return "", err
+	}
+	if path == "" {
+		return "", fmt.Errorf("%s %d is not archived", prefix, n)
+	}
+	ep, err := s.loadEpisode(path)
+	if err != nil {
+		return "", err
+	}
+	return s.episodeMarkdown(ep)
+}
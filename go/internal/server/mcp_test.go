@@ -0,0 +1,81 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestServeMCP(t *testing.T) {
+	s := graphQLArchive(t)
+	in := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"0"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"search_transcripts","arguments":{"query":"talk AI","speaker":"Leo"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"get_episode","arguments":{"show":"twit","number":950}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"list_episodes","arguments":{"show":"TWIT","since":"2024-01-01"}}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"tools/call","params":{"name":"get_episode","arguments":{"show":"TWIT","number":1}}}`,
+		`{"jsonrpc":"2.0","id":7,"method":"resources/list"}`,
+		`not json`,
+	}, "\n")
+	var out bytes.Buffer
+	if err := s.ServeMCP(strings.NewReader(in), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	type response struct {
+		ID     *int `json:"id"`
+		Result struct {
+			ProtocolVersion string                   `json:"protocolVersion"`
+			Tools           []map[string]interface{} `json:"tools"`
+			Content         []struct{ Text string }  `json:"content"`
+			IsError         bool                     `json:"isError"`
+		} `json:"result"`
+		Error *rpcError `json:"error"`
+	}
+	var resps []response
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var r response
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		resps = append(resps, r)
+	}
+	if len(resps) != 8 {
+		t.Fatalf("got %d responses, want 8 (no answer to the notification)", len(resps))
+	}
+	text := func(i int) string {
+		if len(resps[i].Result.Content) == 0 {
+			return ""
+		}
+		return resps[i].Result.Content[0].Text
+	}
+
+	if resps[0].Result.ProtocolVersion != MCPProtocolVersion {
+		t.Errorf("initialize = %+v", resps[0])
+	}
+	if len(resps[1].Result.Tools) != len(mcpTools) || resps[1].Result.Tools[0]["inputSchema"] == nil {
+		t.Errorf("tools/list = %+v", resps[1].Result.Tools)
+	}
+	if got := text(2); !strings.Contains(got, "TWIT 1000 (2024-09-22) Leo Laporte Let's talk about AI.") || !strings.Contains(got, "(1 of 1 matching turns)") {
+		t.Errorf("search = %q", got)
+	}
+	if got := text(3); !strings.Contains(got, "# Episode: This Week in Tech 950") || !strings.Contains(got, "episode: 950") {
+		t.Errorf("get_episode = %q", got)
+	}
+	if got := text(4); !strings.Contains(got, "TWIT 1000\t2024-09-22\tThis Week in Tech 1000") || strings.Contains(got, "TWIT 950") {
+		t.Errorf("list_episodes = %q", got)
+	}
+	if !resps[5].Result.IsError || !strings.Contains(text(5), "not archived") {
+		t.Errorf("missing episode = %+v", resps[5])
+	}
+	if resps[6].Error == nil || resps[6].Error.Code != rpcMethodNotFound {
+		t.Errorf("unknown method = %+v", resps[6])
+	}
+	if resps[7].Error == nil || resps[7].Error.Code != rpcParseError || resps[7].ID != nil {
+		t.Errorf("bad JSON = %+v", resps[7])
+	}
+}
@@ -0,0 +1,308 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// field is one selected field of a GraphQL query
+type field struct {
+	Alias     string // Key in the result; the name unless aliased
+	Name      string
+	Args      map[string]interface{}
+	Selection []*field
+
+	def fieldDef // Schema definition, set when the field is executed
+}
+
+// variable is a reference to a query variable, resolved at execution
+type variable string
+
+// parseQuery parses the subset of GraphQL the API supports: one query
+// operation (optionally named, with variable definitions), fields with
+// aliases, arguments and nested selections, and string, number, boolean,
+// null, enum, list and variable values. Fragments, directives and
+// mutations are not supported.
+func parseQuery(src string) ([]*field, error) {
+	p := &queryParser{src: src}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.kind == tokName && p.tok == "query" {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if p.kind == tokName {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		}
+		if p.tok == "(" {
+			if err := p.skipVariableDefinitions(); err != nil {
+				return nil, err
+			}
+		}
+	} else if p.kind == tokName {
+		return nil, p.errorf("only query operations are supported, not %q", p.tok)
+	}
+	sel, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	if p.kind != tokEOF {
+		return nil, p.errorf("unexpected %q after the query", p.tok)
+	}
+	return sel, nil
+}
+
+const (
+	tokEOF = iota
+	tokName
+	tokPunct
+	tokString
+	tokNumber
+)
+
+type queryParser struct {
+	src  string
+	pos  int
+	tok  string // Current token; strings are unquoted
+	kind int
+	at   int // Offset of the current token
+}
+
+func (p *queryParser) errorf(format string, args ...interface{}) error {
+	line := strings.Count(p.src[:p.at], "\n") + 1
+	return fmt.Errorf("query line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// next advances to the next token, skipping whitespace, commas and comments
+func (p *queryParser) next() error {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == '#' {
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+			continue
+		}
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			p.pos++
+			continue
+		}
+		break
+	}
+	p.at = p.pos
+	if p.pos >= len(p.src) {
+		p.tok, p.kind = "", tokEOF
+		return nil
+	}
+	c := p.src[p.pos]
+	switch {
+	case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		start := p.pos
+		for p.pos < len(p.src) && isNameByte(p.src[p.pos]) {
+			p.pos++
+		}
+		p.tok, p.kind = p.src[start:p.pos], tokName
+	case c == '-' || c >= '0' && c <= '9':
+		start := p.pos
+		p.pos++
+		for p.pos < len(p.src) && strings.IndexByte("0123456789.eE+-", p.src[p.pos]) >= 0 {
+			p.pos++
+		}
+		p.tok, p.kind = p.src[start:p.pos], tokNumber
+	case c == '"':
+		end := p.pos + 1
+		for end < len(p.src) && p.src[end] != '"' {
+			if p.src[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(p.src) {
+			return p.errorf("unterminated string")
+		}
+		s, err := strconv.Unquote(p.src[p.pos : end+1])
+		if err != nil {
+			return p.errorf("invalid string %s", p.src[p.pos:end+1])
+		}
+		p.pos = end + 1
+		p.tok, p.kind = s, tokString
+	case strings.IndexByte("{}():[]$!=@", c) >= 0:
+		p.pos++
+		p.tok, p.kind = string(c), tokPunct
+	case c == '.':
+		return p.errorf("fragments are not supported")
+	default:
+		return p.errorf("unexpected character %q", c)
+	}
+	return nil
+}
+
+func isNameByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// expect consumes the punctuator tok
+func (p *queryParser) expect(tok string) error {
+	if p.kind != tokPunct || p.tok != tok {
+		if p.kind == tokEOF {
+			return p.errorf("expected %q, got the end of the query", tok)
+		}
+		return p.errorf("expected %q, got %q", tok, p.tok)
+	}
+	return p.next()
+}
+
+// skipVariableDefinitions skips "($name: Type = default, ...)". Variable
+// types are not checked; the arguments they are used in are.
+func (p *queryParser) skipVariableDefinitions() error {
+	depth := 0
+	for {
+		if p.kind == tokEOF {
+			return p.errorf("unterminated variable definitions")
+		}
+		if p.kind == tokPunct && p.tok == "(" {
+			depth++
+		}
+		if p.kind == tokPunct && p.tok == ")" {
+			depth--
+			if depth == 0 {
+				return p.next()
+			}
+		}
+		if err := p.next(); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *queryParser) selectionSet() ([]*field, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var fields []*field
+	for !(p.kind == tokPunct && p.tok == "}") {
+		if p.kind != tokName {
+			if p.kind == tokEOF {
+				return nil, p.errorf("unterminated selection set")
+			}
+			return nil, p.errorf("expected a field name, got %q", p.tok)
+		}
+		f := &field{Alias: p.tok, Name: p.tok}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if p.kind == tokPunct && p.tok == ":" {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			if p.kind != tokName {
+				return nil, p.errorf("expected a field name after alias %q", f.Alias)
+			}
+			f.Name = p.tok
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		}
+		if p.kind == tokPunct && p.tok == "(" {
+			args, err := p.arguments()
+			if err != nil {
+				return nil, err
+			}
+			f.Args = args
+		}
+		if p.kind == tokPunct && p.tok == "@" {
+			return nil, p.errorf("directives are not supported")
+		}
+		if p.kind == tokPunct && p.tok == "{" {
+			sel, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			f.Selection = sel
+		}
+		fields = append(fields, f)
+	}
+	return fields, p.next()
+}
+
+func (p *queryParser) arguments() (map[string]interface{}, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	args := make(map[string]interface{})
+	for !(p.kind == tokPunct && p.tok == ")") {
+		if p.kind != tokName {
+			return nil, p.errorf("expected an argument name, got %q", p.tok)
+		}
+		name := p.tok
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		args[name] = v
+	}
+	return args, p.next()
+}
+
+func (p *queryParser) value() (interface{}, error) {
+	tok, kind := p.tok, p.kind
+	switch {
+	case kind == tokString:
+		return tok, p.next()
+	case kind == tokNumber:
+		if n, err := strconv.Atoi(tok); err == nil {
+			return n, p.next()
+		}
+		f, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %q", tok)
+		}
+		return f, p.next()
+	case kind == tokName:
+		switch tok {
+		case "true", "false":
+			return tok == "true", p.next()
+		case "null":
+			return nil, p.next()
+		}
+		return tok, p.next() // Enum values are passed as strings
+	case kind == tokPunct && tok == "$":
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if p.kind != tokName {
+			return nil, p.errorf("expected a variable name")
+		}
+		name := variable(p.tok)
+		return name, p.next()
+	case kind == tokPunct && tok == "[":
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		list := []interface{}{}
+		for !(p.kind == tokPunct && p.tok == "]") {
+			if p.kind == tokEOF {
+				return nil, p.errorf("unterminated list")
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, p.next()
+	case kind == tokEOF:
+		return nil, p.errorf("expected a value, got the end of the query")
+	}
+	return nil, p.errorf("unexpected %q", tok)
+}
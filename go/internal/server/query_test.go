@@ -0,0 +1,52 @@
+package server
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	sel, err := parseQuery(`query Recent($show: String!, $n: Int = 5) {
+		# newest first
+		recent: episodes(show: $show, first: $n, pending: false, tags: ["a", "b"]) {
+			total
+			items { number title }
+		}
+		shows { prefix }
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sel) != 2 || sel[0].Alias != "recent" || sel[0].Name != "episodes" || sel[1].Name != "shows" {
+		t.Fatalf("selection = %+v", sel)
+	}
+	want := map[string]interface{}{
+		"show":    variable("show"),
+		"first":   variable("n"),
+		"pending": false,
+		"tags":    []interface{}{"a", "b"},
+	}
+	if !reflect.DeepEqual(sel[0].Args, want) {
+		t.Errorf("args = %#v", sel[0].Args)
+	}
+	if items := sel[0].Selection[1]; items.Name != "items" || len(items.Selection) != 2 || items.Selection[1].Name != "title" {
+		t.Errorf("items = %+v", items)
+	}
+
+	if sel, err := parseQuery(`{ episode(show: "SN", number: 1000) { title } }`); err != nil || sel[0].Args["number"] != 1000 {
+		t.Errorf("shorthand query = %+v, %v", sel, err)
+	}
+
+	for src, msg := range map[string]string{
+		`mutation { x }`:               "only query operations",
+		`{ shows { ...ShowFields } }`:  "fragments are not supported",
+		`{ shows { prefix }`:           "unterminated selection set",
+		`{ episode(show: "SN) { x } }`: "unterminated string",
+		`{ episode(show "SN") }`:       `expected ":"`,
+	} {
+		if _, err := parseQuery(src); err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("parseQuery(%q) error = %v, want %q", src, err, msg)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+// Package server serves the archive over HTTP as a read-only REST API: the
+// shows, the episode index, and each episode as JSON or Markdown.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/export"
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// Server answers API requests for a data directory
+type Server struct {
+	DataDir string
+	Cache   *Cache
+
+	mu        sync.Mutex
+	ix        *index.Index
+	ixVersion string
+}
+
+// New returns a server for a data directory, caching up to cacheSize
+// rendered responses
+func New(dataDir string, cacheSize int) *Server {
+	return &Server{DataDir: dataDir, Cache: NewCache(cacheSize)}
+}
+
+// Handler returns the API's routes:
+//
+//	GET /api/shows                    known shows with archived episode counts
+//	GET /api/episodes                 index entries (?show=, ?tag=, ?host=, ?guest=, ?pending=1)
+//	GET /api/episodes/SN/1000         one episode as JSON
+//	GET /api/episodes/SN/1000.md      the same episode as Markdown
+//	POST /api/graphql                 GraphQL queries over episodes and segments
+//	GET /api/graphql/schema           the GraphQL schema
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/graphql", s.handleGraphQL)
+	mux.HandleFunc("/api/graphql/schema", s.handleSchema)
+	mux.HandleFunc("/api/shows", s.handleShows)
+	mux.HandleFunc("/api/episodes", s.handleEpisodes)
+	mux.HandleFunc("/api/episodes/", s.handleEpisode)
+	return mux
+}
+
+// serve writes the response for key, built from a version of its source
+// file. Clients holding the current ETag get 304 Not Modified; otherwise a
+// cached response is reused while the file is unchanged.
+func (s *Server) serve(w http.ResponseWriter, r *http.Request, key, version string, build func() (Response, error)) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	tag := etag(key, version)
+	w.Header().Set("ETag", tag)
+	if r.Header.Get("If-None-Match") == tag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	var resp Response
+	if v, ok := s.Cache.Get(key, version); ok {
+		resp = v.(Response)
+		w.Header().Set("X-Cache", "HIT")
+	} else {
+		var err error
+		if resp, err = build(); err != nil {
+			code := http.StatusInternalServerError
+			if errors.Is(err, storage.ErrNotExist) {
+				code = http.StatusNotFound
+			}
+			http.Error(w, err.Error(), code)
+			return
+		}
+		resp.ETag = tag
+		s.Cache.Put(key, version, resp)
+		w.Header().Set("X-Cache", "MISS")
+	}
+	w.Header().Set("Content-Type", resp.ContentType)
+	w.Write(resp.Body)
+}
+
+// index returns the archive index, reloaded when index.json changes
+func (s *Server) index() (*index.Index, string, error) {
+	version := fileVersion(storage.Join(s.DataDir, index.FileName))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ix == nil || version != s.ixVersion {
+		ix, err := index.Load(s.DataDir)
+		if err != nil {
+			return nil, "", err
+		}
+		s.ix, s.ixVersion = ix, version
+	}
+	return s.ix, s.ixVersion, nil
+}
+
+// showInfo is a show as listed by /api/shows
+type showInfo struct {
+	Prefix      string `json:"prefix"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Episodes    int    `json:"episodes"`
+}
+
+func (s *Server) handleShows(w http.ResponseWriter, r *http.Request) {
+	ix, version, err := s.index()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.serve(w, r, "shows", version, func() (Response, error) {
+		counts := make(map[string]int)
+		for _, e := range ix.Entries {
+			counts[config.CanonicalPrefix(e.Prefix)]++
+		}
+		var shows []showInfo
+		for _, sh := range config.Shows() {
+			shows = append(shows, showInfo{sh.Prefix, config.ShowName(sh.Prefix), sh.Description, counts[sh.Prefix]})
+		}
+		sort.Slice(shows, func(i, j int) bool { return shows[i].Prefix < shows[j].Prefix })
+		return jsonResponse(shows)
+	})
+}
+
+func (s *Server) handleEpisodes(w http.ResponseWriter, r *http.Request) {
+	ix, version, err := s.index()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	q := r.URL.Query()
+	s.serve(w, r, "episodes?"+q.Encode(), version, func() (Response, error) {
+		entries := []*index.Entry{}
+		for _, e := range ix.Sorted() {
+			if show := q.Get("show"); show != "" && !strings.EqualFold(e.Prefix, show) {
+				continue
+			}
+			if tag := q.Get("tag"); tag != "" && !e.HasTag(tag) {
+				continue
+			}
+			if host := q.Get("host"); host != "" && !e.HasHost(host) {
+				continue
+			}
+			if guest := q.Get("guest"); guest != "" && !e.HasGuest(guest) {
+				continue
+			}
+			if q.Get("pending") != "" && !e.Pending {
+				continue
+			}
+			entries = append(entries, e)
+		}
+		return jsonResponse(entries)
+	})
+}
+
+func (s *Server) handleEpisode(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/episodes/"), "/")
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	format := "json"
+	num := parts[1]
+	if strings.HasSuffix(num, ".md") {
+		format, num = "md", strings.TrimSuffix(num, ".md")
+	} else if strings.HasSuffix(num, ".json") {
+		num = strings.TrimSuffix(num, ".json")
+	} else if strings.Contains(r.Header.Get("Accept"), "text/markdown") {
+		format = "md"
+	}
+	prefix, ok := config.ResolveShow(parts[0])
+	n, err := strconv.Atoi(num)
+	if !ok || err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	path, err := s.episodeFile(prefix, n)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if path == "" {
+		http.Error(w, fmt.Sprintf("%s %d is not archived", prefix, n), http.StatusNotFound)
+		return
+	}
+
+	// Markdown carries the episode's tags from the index in its front matter
+	version := fileVersion(path)
+	if format == "md" {
+		version += "+" + fileVersion(storage.Join(s.DataDir, index.FileName))
+	}
+	s.serve(w, r, format+":"+path, version, func() (Response, error) {
+		ep, err := s.loadEpisode(path)
+		if err != nil {
+			return Response{}, err
+		}
+		if format == "json" {
+			return jsonResponse(export.NewEpisodeRecord(ep))
+		}
+		md, err := s.episodeMarkdown(ep)
+		if err != nil {
+			return Response{}, err
+		}
+		return Response{ContentType: "text/markdown; charset=utf-8", Body: []byte(md)}, nil
+	})
+}
+
+// episodeMarkdown renders an episode as Markdown with front matter, as
+// process-transcripts --front-matter writes it
+func (s *Server) episodeMarkdown(ep converter.Episode) (string, error) {
+	var tags []string
+	if ix, _, err := s.index(); err == nil {
+		if e := ix.Entries[index.Key(ep.Path)]; e != nil {
+			tags = e.Tags
+		}
+	}
+	md, err := converter.DefaultTemplates().RenderEpisode(ep, ep.Content, false)
+	if err != nil {
+		return "", err
+	}
+	return converter.FrontMatter(ep, len(strings.Fields(ep.Content)), tags) + md, nil
+}
+
+// loadEpisode parses a transcript, reusing the parse while the file is
+// unchanged
+func (s *Server) loadEpisode(path string) (converter.Episode, error) {
+	key, version := "parsed:"+path, fileVersion(path)
+	if v, ok := s.Cache.Get(key, version); ok {
+		return v.(converter.Episode), nil
+	}
+	ep, err := converter.LoadEpisode(path)
+	if err != nil {
+		return ep, err
+	}
+	s.Cache.Put(key, version, ep)
+	return ep, nil
+}
+
+// episodeFile returns the transcript file of an episode, or "" if it is not
+// archived
+func (s *Server) episodeFile(prefix string, n int) (string, error) {
+	files, err := config.ActiveLayout.RawFiles(s.DataDir, prefix)
+	if err != nil {
+		return "", err
+	}
+	for _, f := range files {
+		if converter.GetEpNum(storage.Base(f)) == n {
+			return f, nil
+		}
+	}
+	return "", nil
+}
+
+func jsonResponse(v interface{}) (Response, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return Response{}, err
+	}
+	return Response{ContentType: "application/json", Body: append(data, '\n')}, nil
+}
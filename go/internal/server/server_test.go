@@ -0,0 +1,95 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/index"
+)
+
+func testPage(title, text string) string {
+	return `<html><h1 class="post-title">` + title + `</h1><p class="byline">May 10 2022</p>` +
+		`<div class="body textual"><p>Leo Laporte: ` + text + `</p></div></html>`
+}
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "SN_950.html")
+	if err := os.WriteFile(path, []byte(testPage("Security Now 950", "First version.")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := index.AddFile(dir, path); err != nil {
+		t.Fatal(err)
+	}
+	srv := New(dir, 8)
+	h := srv.Handler()
+
+	get := func(url, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", url, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/api/episodes/sn/950", "")
+	if rec.Code != 200 || rec.Header().Get("X-Cache") != "MISS" {
+		t.Fatalf("got %d %s: %s", rec.Code, rec.Header().Get("X-Cache"), rec.Body)
+	}
+	var ep struct {
+		Episode int    `json:"episode"`
+		Text    string `json:"text"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &ep); err != nil || ep.Episode != 950 || !strings.Contains(ep.Text, "First version.") {
+		t.Errorf("episode = %+v, %v", ep, err)
+	}
+	if rec := get("/api/episodes/SN/950", ""); rec.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("second request was not a cache hit")
+	}
+	etag := rec.Header().Get("ETag")
+	if rec := get("/api/episodes/SN/950", etag); rec.Code != http.StatusNotModified {
+		t.Errorf("revalidation got %d, want 304", rec.Code)
+	}
+
+	md := get("/api/episodes/SN/950.md", "")
+	if md.Code != 200 || !strings.HasPrefix(md.Body.String(), "---\n") || !strings.Contains(md.Body.String(), "# Episode: Security Now 950") {
+		t.Errorf("markdown = %d %q", md.Code, md.Body)
+	}
+
+	// New tags in the index invalidate the Markdown, which shows them
+	mdTag := md.Header().Get("ETag")
+	ix, _ := index.Load(dir)
+	ix.Entries["SN_950"].Tags = []string{"spinrite"}
+	ix.Save(dir)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(dir, index.FileName), later, later)
+	if md := get("/api/episodes/SN/950.md", mdTag); md.Code != 200 || md.Header().Get("X-Cache") != "MISS" || !strings.Contains(md.Body.String(), "spinrite") {
+		t.Errorf("markdown after tagging = %d %s: %q", md.Code, md.Header().Get("X-Cache"), md.Body)
+	}
+
+	// A changed file invalidates the cached response and its ETag
+	os.WriteFile(path, []byte(testPage("Security Now 950", "Second version, corrected.")), 0644)
+	os.Chtimes(path, later, later)
+	rec = get("/api/episodes/SN/950", etag)
+	if rec.Code != 200 || rec.Header().Get("X-Cache") != "MISS" || !strings.Contains(rec.Body.String(), "Second version") {
+		t.Errorf("after change got %d %s: %s", rec.Code, rec.Header().Get("X-Cache"), rec.Body)
+	}
+
+	if rec := get("/api/episodes/SN/951", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("missing episode got %d", rec.Code)
+	}
+	if rec := get("/api/episodes?show=SN", ""); rec.Code != 200 || !strings.Contains(rec.Body.String(), `"episode": 950`) {
+		t.Errorf("episodes = %d %s", rec.Code, rec.Body)
+	}
+	if rec := get("/api/shows", ""); rec.Code != 200 || !strings.Contains(rec.Body.String(), `"prefix": "SN"`) {
+		t.Errorf("shows = %d %s", rec.Code, rec.Body)
+	}
+}
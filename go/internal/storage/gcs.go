@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GCS stores files as objects in a Google Cloud Storage bucket using the
+// JSON API. An OAuth2 access token is read from GOOGLE_OAUTH_ACCESS_TOKEN
+// (e.g. the output of `gcloud auth print-access-token`); STORAGE_EMULATOR_HOST
+// overrides the API endpoint.
+type GCS struct {
+	Bucket   string
+	Prefix   string
+	Endpoint string
+	Token    string
+	Client   *http.Client
+}
+
+// NewGCS creates a GCS backend configured from the environment
+func NewGCS(bucket, prefix string) *GCS {
+	endpoint := "https://storage.googleapis.com"
+	if host := os.Getenv("STORAGE_EMULATOR_HOST"); host != "" {
+		endpoint = host
+		if !strings.Contains(endpoint, "://") {
+			endpoint = "http://" + endpoint
+		}
+	}
+	return &GCS{
+		Bucket:   bucket,
+		Prefix:   prefix,
+		Endpoint: strings.TrimRight(endpoint, "/"),
+		Token:    os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"),
+		Client:   &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+func (g *GCS) objectURL(name string) string {
+	return fmt.Sprintf("%s/storage/v1/b/%s/o/%s", g.Endpoint, url.PathEscape(g.Bucket), url.PathEscape(objectKey(g.Prefix, name)))
+}
+
+func (g *GCS) ReadFile(name string) ([]byte, error) {
+	resp, err := g.do("GET", g.objectURL(name)+"?alt=media", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := gcsStatus(resp, name); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (g *GCS) WriteFile(name string, data []byte) error {
+	u := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?uploadType=media&name=%s",
+		g.Endpoint, url.PathEscape(g.Bucket), url.QueryEscape(objectKey(g.Prefix, name)))
+	resp, err := g.do("POST", u, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return gcsStatus(resp, name)
+}
+
+func (g *GCS) Exists(name string) bool {
+	resp, err := g.do("GET", g.objectURL(name), nil)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+func (g *GCS) Remove(name string) error {
+	resp, err := g.do("DELETE", g.objectURL(name), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return gcsStatus(resp, name)
+}
+
+type gcsListResult struct {
+	Items []struct {
+		Name string `json:"name"`
+	} `json:"items"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+func (g *GCS) Glob(pattern string) ([]string, error) {
+	listPrefix := objectKey(g.Prefix, "")
+	if listPrefix != "" {
+		listPrefix = strings.TrimRight(listPrefix, "/") + "/"
+	}
+	if i := strings.IndexAny(pattern, "*?[\\"); i >= 0 {
+		listPrefix += pattern[:i]
+	} else {
+		listPrefix += pattern
+	}
+
+	var keys []string
+	token := ""
+	for {
+		query := url.Values{"prefix": {listPrefix}}
+		if token != "" {
+			query.Set("pageToken", token)
+		}
+		u := fmt.Sprintf("%s/storage/v1/b/%s/o?%s", g.Endpoint, url.PathEscape(g.Bucket), query.Encode())
+		resp, err := g.do("GET", u, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result gcsListResult
+		err = gcsStatus(resp, pattern)
+		if err == nil {
+			err = json.NewDecoder(resp.Body).Decode(&result)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range result.Items {
+			keys = append(keys, item.Name)
+		}
+		if result.NextPageToken == "" {
+			break
+		}
+		token = result.NextPageToken
+	}
+	names := matchNames(keys, g.Prefix, pattern)
+	sort.Strings(names)
+	return names, nil
+}
+
+func (g *GCS) do(method, u string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if g.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.Token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	return g.Client.Do(req)
+}
+
+func gcsStatus(resp *http.Response, name string) error {
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("gcs: %s: %w", name, ErrNotExist)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("gcs: %s: status code %d: %s", name, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
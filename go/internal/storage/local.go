@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Local stores files in a directory on the local filesystem
+type Local struct {
+	Root string
+}
+
+func (l Local) path(name string) string {
+	return filepath.Join(l.Root, filepath.FromSlash(name))
+}
+
+func (l Local) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(l.path(name))
+}
+
+// WriteFile writes the file, creating parent directories as needed
+func (l Local) WriteFile(name string, data []byte) error {
+	p := l.path(name)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(p, data, 0644)
+}
+
+func (l Local) Exists(name string) bool {
+	info, err := os.Stat(l.path(name))
+	if err != nil {
+		return false
+	}
+	return !info.IsDir()
+}
+
+func (l Local) Glob(pattern string) ([]string, error) {
+	matches, err := filepath.Glob(l.path(pattern))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, m := range matches {
+		rel, err := filepath.Rel(l.Root, m)
+		if err != nil {
+			continue
+		}
+		names = append(names, filepath.ToSlash(rel))
+	}
+	return names, nil
+}
+
+func (l Local) Remove(name string) error {
+	return os.Remove(l.path(name))
+}
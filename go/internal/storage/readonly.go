@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ErrReadOnly is returned for writes to a read-only location
+var ErrReadOnly = errors.New("archive is read-only")
+
+// ReadOnly lists locations (local directories or object storage prefixes)
+// that must not be modified. Writes, removals and renames of files inside
+// them fail with ErrReadOnly; everything outside stays writable, so e.g. an
+// export can still go to another directory.
+var ReadOnly []string
+
+// CheckWritable returns an error wrapping ErrReadOnly if p is inside a
+// ReadOnly location. Code that writes with the os package instead of this
+// package must call it first.
+func CheckWritable(p string) error {
+	for _, root := range ReadOnly {
+		if within(root, p) {
+			return fmt.Errorf("storage: cannot modify %s: %w", p, ErrReadOnly)
+		}
+	}
+	return nil
+}
+
+// within reports whether p is root or inside it
+func within(root, p string) bool {
+	if IsRemote(root) != IsRemote(p) {
+		return false
+	}
+	if IsRemote(root) {
+		root = strings.TrimRight(root, "/")
+		return p == root || strings.HasPrefix(p, root+"/")
+	}
+	r, err1 := filepath.Abs(root)
+	a, err2 := filepath.Abs(p)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return a == r || strings.HasPrefix(a, r+string(filepath.Separator))
+}
+
+// guarded wraps a backend while ReadOnly is set, refusing writes and
+// removals of files inside a read-only location
+type guarded struct {
+	Storage
+	location string
+}
+
+func (g guarded) WriteFile(name string, data []byte) error {
+	if err := CheckWritable(Join(g.location, name)); err != nil {
+		return err
+	}
+	return g.Storage.WriteFile(name, data)
+}
+
+func (g guarded) Remove(name string) error {
+	if err := CheckWritable(Join(g.location, name)); err != nil {
+		return err
+	}
+	return g.Storage.Remove(name)
+}
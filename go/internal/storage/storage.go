@@ -0,0 +1,288 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotExist is returned by backends when an object or file is missing
+var ErrNotExist = os.ErrNotExist
+
+// Storage abstracts the archive's file operations so the data directory can
+// live on local disk or in object storage. Names are relative to the root of
+// the backend and always use forward slashes.
+type Storage interface {
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte) error
+	Exists(name string) bool
+	// Glob returns the names (relative to the root) matching a slash-separated
+	// filepath.Match pattern.
+	Glob(pattern string) ([]string, error)
+	Remove(name string) error
+}
+
+// Open returns the backend for a location. Supported forms are a local
+// directory path, "s3://bucket/prefix" and "gs://bucket/prefix".
+func Open(location string) (Storage, error) {
+	s, err := open(location)
+	if err != nil || len(ReadOnly) == 0 {
+		return s, err
+	}
+	return guarded{Storage: s, location: location}, nil
+}
+
+func open(location string) (Storage, error) {
+	scheme, rest := splitScheme(location)
+	switch scheme {
+	case "":
+		return Local{Root: location}, nil
+	case "s3":
+		bucket, prefix := splitBucket(rest)
+		if bucket == "" {
+			return nil, fmt.Errorf("storage: missing bucket in %q", location)
+		}
+		return NewS3(bucket, prefix), nil
+	case "gs":
+		bucket, prefix := splitBucket(rest)
+		if bucket == "" {
+			return nil, fmt.Errorf("storage: missing bucket in %q", location)
+		}
+		return NewGCS(bucket, prefix), nil
+	default:
+		return nil, fmt.Errorf("storage: unsupported scheme %q", scheme)
+	}
+}
+
+// IsRemote reports whether a location refers to object storage
+func IsRemote(location string) bool {
+	scheme, _ := splitScheme(location)
+	return scheme != ""
+}
+
+// Join joins a location and a name. Local paths use filepath.Join, object
+// storage locations are joined with "/".
+func Join(location string, elem ...string) string {
+	if !IsRemote(location) {
+		return filepath.Join(append([]string{location}, elem...)...)
+	}
+	parts := []string{strings.TrimRight(location, "/")}
+	for _, e := range elem {
+		if e = strings.Trim(e, "/"); e != "" {
+			parts = append(parts, e)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// Split separates a full path into its location and base name
+func Split(p string) (string, string) {
+	if !IsRemote(p) {
+		return filepath.Dir(p), filepath.Base(p)
+	}
+	i := strings.LastIndex(p, "/")
+	return p[:i], p[i+1:]
+}
+
+// Base returns the last element of a local path or object URL
+func Base(p string) string {
+	_, name := Split(p)
+	return name
+}
+
+// ReadFile reads a file given its full path or object URL
+func ReadFile(p string) ([]byte, error) {
+	s, name, err := resolve(p)
+	if err != nil {
+		return nil, err
+	}
+	return s.ReadFile(name)
+}
+
+// WriteFile writes a file given its full path or object URL
+func WriteFile(p string, data []byte) error {
+	s, name, err := resolve(p)
+	if err != nil {
+		return err
+	}
+	return s.WriteFile(name, data)
+}
+
+// Exists reports whether a file exists at the full path or object URL
+func Exists(p string) bool {
+	s, name, err := resolve(p)
+	if err != nil {
+		return false
+	}
+	return s.Exists(name)
+}
+
+// Remove deletes a file given its full path or object URL
+func Remove(p string) error {
+	s, name, err := resolve(p)
+	if err != nil {
+		return err
+	}
+	return s.Remove(name)
+}
+
+// Glob behaves like filepath.Glob but also accepts object storage locations.
+// Wildcards never match the path separator.
+func Glob(pattern string) ([]string, error) {
+	dir, rel := splitGlob(pattern)
+	s, err := Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	names, err := s.Glob(rel)
+	if err != nil {
+		return nil, err
+	}
+	matches := make([]string, 0, len(names))
+	for _, n := range names {
+		matches = append(matches, Join(dir, n))
+	}
+	return matches, nil
+}
+
+// splitGlob separates the literal leading directories of a pattern from the
+// part containing wildcards
+func splitGlob(pattern string) (string, string) {
+	dir, rel := Split(pattern)
+	for strings.ContainsAny(dir, "*?[") {
+		parent, name := Split(dir)
+		rel = name + "/" + rel
+		dir = parent
+	}
+	return dir, rel
+}
+
+// Rel returns p relative to the location root, using forward slashes
+func Rel(root, p string) string {
+	if IsRemote(root) {
+		return strings.TrimPrefix(p, strings.TrimRight(root, "/")+"/")
+	}
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return filepath.ToSlash(p)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// Rename moves a file, creating destination directories as needed. Moves
+// between different backends are done as copy and delete.
+func Rename(src, dst string) error {
+	if err := CheckWritable(src); err != nil {
+		return err
+	}
+	if err := CheckWritable(dst); err != nil {
+		return err
+	}
+	if !IsRemote(src) && !IsRemote(dst) {
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		return os.Rename(src, dst)
+	}
+	data, err := ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := WriteFile(dst, data); err != nil {
+		return err
+	}
+	return Remove(src)
+}
+
+func resolve(p string) (Storage, string, error) {
+	dir, name := Split(p)
+	if name == "" {
+		return nil, "", errors.New("storage: empty file name")
+	}
+	s, err := Open(dir)
+	return s, name, err
+}
+
+func splitScheme(location string) (string, string) {
+	if i := strings.Index(location, "://"); i > 0 {
+		return location[:i], location[i+3:]
+	}
+	return "", location
+}
+
+func splitBucket(rest string) (string, string) {
+	rest = strings.Trim(rest, "/")
+	if i := strings.Index(rest, "/"); i >= 0 {
+		return rest[:i], rest[i+1:]
+	}
+	return rest, ""
+}
+
+// objectKey joins a key prefix and a relative name
+func objectKey(prefix, name string) string {
+	name = strings.TrimLeft(name, "/")
+	if prefix == "" {
+		return name
+	}
+	return strings.TrimRight(prefix, "/") + "/" + name
+}
+
+// matchNames filters keys below prefix against a slash-separated pattern
+func matchNames(keys []string, prefix, pattern string) []string {
+	var names []string
+	base := ""
+	if prefix != "" {
+		base = strings.TrimRight(prefix, "/") + "/"
+	}
+	for _, k := range keys {
+		if !strings.HasPrefix(k, base) {
+			continue
+		}
+		name := strings.TrimPrefix(k, base)
+		if ok, _ := path.Match(pattern, name); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Create opens a file for streaming writes. Local files are written directly;
+// for object storage the data is buffered in a temporary file and uploaded on
+// Close.
+func Create(p string) (io.WriteCloser, error) {
+	if err := CheckWritable(p); err != nil {
+		return nil, err
+	}
+	if !IsRemote(p) {
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return nil, err
+		}
+		return os.Create(p)
+	}
+	tmp, err := os.CreateTemp("", "twit-upload-*")
+	if err != nil {
+		return nil, err
+	}
+	return &uploadWriter{File: tmp, dest: p}, nil
+}
+
+type uploadWriter struct {
+	*os.File
+	dest string
+}
+
+func (u *uploadWriter) Close() error {
+	defer os.Remove(u.Name())
+	if err := u.File.Close(); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(u.Name())
+	if err != nil {
+		return err
+	}
+	return WriteFile(u.dest, data)
+}
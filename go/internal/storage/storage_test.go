@@ -0,0 +1,268 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestJoinAndSplit(t *testing.T) {
+	if got := Join("s3://bucket/archive/", "IM_1.html"); got != "s3://bucket/archive/IM_1.html" {
+		t.Errorf("Join = %q", got)
+	}
+	dir, name := Split("gs://bucket/archive/IM_1.html")
+	if dir != "gs://bucket/archive" || name != "IM_1.html" {
+		t.Errorf("Split = %q, %q", dir, name)
+	}
+	dir, name = Split("/tmp/data/IM_1.html")
+	if dir != "/tmp/data" || name != "IM_1.html" {
+		t.Errorf("Split (local) = %q, %q", dir, name)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	if _, ok := mustOpen(t, "/tmp/data").(Local); !ok {
+		t.Error("Expected Local backend for a plain path")
+	}
+	if s, ok := mustOpen(t, "s3://bucket/a/b").(*S3); !ok || s.Bucket != "bucket" || s.Prefix != "a/b" {
+		t.Errorf("Unexpected S3 backend: %+v", s)
+	}
+	if g, ok := mustOpen(t, "gs://bucket").(*GCS); !ok || g.Bucket != "bucket" || g.Prefix != "" {
+		t.Errorf("Unexpected GCS backend: %+v", g)
+	}
+	if _, err := Open("ftp://host/dir"); err == nil {
+		t.Error("Expected error for unsupported scheme")
+	}
+}
+
+func mustOpen(t *testing.T, location string) Storage {
+	t.Helper()
+	s, err := Open(location)
+	if err != nil {
+		t.Fatalf("Open(%q) failed: %v", location, err)
+	}
+	return s
+}
+
+func TestLocalRoundTrip(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "storagetest")
+	defer os.RemoveAll(tmpDir)
+
+	if err := WriteFile(Join(tmpDir, "IM_1.html"), []byte("one")); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	WriteFile(Join(tmpDir, "IM_2.html"), []byte("two"))
+	WriteFile(Join(tmpDir, "TWIG_1.html"), []byte("three"))
+
+	content, err := ReadFile(Join(tmpDir, "IM_1.html"))
+	if err != nil || string(content) != "one" {
+		t.Errorf("ReadFile = %q, %v", content, err)
+	}
+	if !Exists(Join(tmpDir, "IM_2.html")) || Exists(Join(tmpDir, "IM_3.html")) {
+		t.Error("Exists returned the wrong result")
+	}
+
+	matches, _ := Glob(Join(tmpDir, "IM_*.html"))
+	if len(matches) != 2 {
+		t.Errorf("Expected 2 matches, got %v", matches)
+	}
+
+	if err := Remove(Join(tmpDir, "IM_1.html")); err != nil || Exists(Join(tmpDir, "IM_1.html")) {
+		t.Errorf("Remove failed: %v", err)
+	}
+}
+
+// fakeObjectServer is a minimal in-memory S3/GCS stand-in
+type fakeObjectServer struct {
+	mu      sync.Mutex
+	objects map[string]string
+	auth    []string
+}
+
+func newFakeObjectServer() *fakeObjectServer {
+	return &fakeObjectServer{objects: map[string]string{}}
+}
+
+func (f *fakeObjectServer) keys(prefix string) []string {
+	var keys []string
+	for k := range f.objects {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (f *fakeObjectServer) s3Handler(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.auth = append(f.auth, r.Header.Get("Authorization"))
+
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	if len(parts) == 1 && r.Method == "GET" {
+		fmt.Fprint(w, "<ListBucketResult>")
+		for _, k := range f.keys(r.URL.Query().Get("prefix")) {
+			fmt.Fprintf(w, "<Contents><Key>%s</Key></Contents>", k)
+		}
+		fmt.Fprint(w, "<IsTruncated>false</IsTruncated></ListBucketResult>")
+		return
+	}
+	key := parts[1]
+	switch r.Method {
+	case "PUT":
+		body, _ := io.ReadAll(r.Body)
+		f.objects[key] = string(body)
+	case "GET", "HEAD":
+		v, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, v)
+	case "DELETE":
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	tmpDir := t.TempDir()
+	archive := Join(tmpDir, "data")
+	WriteFile(Join(archive, "IM_1.html"), []byte("one"))
+	ReadOnly = []string{archive}
+	defer func() { ReadOnly = nil }()
+
+	if data, err := ReadFile(Join(archive, "IM_1.html")); err != nil || string(data) != "one" {
+		t.Errorf("Reads should still work: %q, %v", data, err)
+	}
+	if matches, _ := Glob(Join(archive, "*.html")); len(matches) != 1 {
+		t.Errorf("Glob should still work: %v", matches)
+	}
+	for name, err := range map[string]error{
+		"WriteFile": WriteFile(Join(archive, "index.json"), []byte("{}")),
+		"Remove":    Remove(Join(archive, "IM_1.html")),
+		"Rename":    Rename(Join(archive, "IM_1.html"), Join(tmpDir, "IM_1.html")),
+		"CreateSub": func() error { _, err := Create(Join(archive, "sub", "corpus.jsonl")); return err }(),
+	} {
+		if !errors.Is(err, ErrReadOnly) {
+			t.Errorf("%s inside the archive: got %v, want ErrReadOnly", name, err)
+		}
+	}
+	if !Exists(Join(archive, "IM_1.html")) || Exists(Join(archive, "index.json")) {
+		t.Error("The read-only archive was modified")
+	}
+
+	// Outside it, and in a sibling sharing its name as a prefix, writes work
+	for _, p := range []string{Join(tmpDir, "corpus.jsonl"), Join(tmpDir, "data2", "x.txt")} {
+		if err := WriteFile(p, []byte("x")); err != nil {
+			t.Errorf("WriteFile(%s) outside the archive: %v", p, err)
+		}
+	}
+
+	ReadOnly = []string{"s3://bucket/archive"}
+	if err := CheckWritable("s3://bucket/archive/IM_1.html"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Remote location not protected: %v", err)
+	}
+	if err := CheckWritable("s3://bucket/archive-copy/IM_1.html"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestS3Backend(t *testing.T) {
+	fake := newFakeObjectServer()
+	ts := httptest.NewServer(http.HandlerFunc(fake.s3Handler))
+	defer ts.Close()
+
+	s := NewS3("bucket", "archive")
+	s.Endpoint = ts.URL
+	s.AccessKey = "AKID"
+	s.SecretKey = "secret"
+
+	if err := s.WriteFile("IM_1.html", []byte("one")); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	s.WriteFile("IM_2.html", []byte("two"))
+	s.WriteFile("TWIG_1.html", []byte("three"))
+
+	if _, ok := fake.objects["archive/IM_1.html"]; !ok {
+		t.Errorf("Object not stored under prefix: %v", fake.objects)
+	}
+	content, err := s.ReadFile("IM_2.html")
+	if err != nil || string(content) != "two" {
+		t.Errorf("ReadFile = %q, %v", content, err)
+	}
+	if _, err := s.ReadFile("IM_9.html"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("Expected ErrNotExist, got %v", err)
+	}
+	if !s.Exists("IM_1.html") || s.Exists("IM_9.html") {
+		t.Error("Exists returned the wrong result")
+	}
+
+	names, err := s.Glob("IM_*.html")
+	if err != nil || len(names) != 2 || names[0] != "IM_1.html" {
+		t.Errorf("Glob = %v, %v", names, err)
+	}
+
+	for _, a := range fake.auth {
+		if !strings.HasPrefix(a, "AWS4-HMAC-SHA256 Credential=AKID/") {
+			t.Fatalf("Request not signed: %q", a)
+		}
+	}
+}
+
+func TestGCSBackend(t *testing.T) {
+	fake := newFakeObjectServer()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		fake.auth = append(fake.auth, r.Header.Get("Authorization"))
+
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/upload/"):
+			body, _ := io.ReadAll(r.Body)
+			fake.objects[r.URL.Query().Get("name")] = string(body)
+		case strings.HasSuffix(r.URL.Path, "/o"):
+			var items []string
+			for _, k := range fake.keys(r.URL.Query().Get("prefix")) {
+				items = append(items, fmt.Sprintf(`{"name":%q}`, k))
+			}
+			fmt.Fprintf(w, `{"items":[%s]}`, strings.Join(items, ","))
+		default:
+			key := r.URL.Path[strings.Index(r.URL.Path, "/o/")+3:]
+			v, ok := fake.objects[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			fmt.Fprint(w, v)
+		}
+	}))
+	defer ts.Close()
+
+	g := NewGCS("bucket", "")
+	g.Endpoint = ts.URL
+	g.Token = "token"
+
+	if err := g.WriteFile("SN_1.html", []byte("hello")); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	content, err := g.ReadFile("SN_1.html")
+	if err != nil || string(content) != "hello" {
+		t.Errorf("ReadFile = %q, %v", content, err)
+	}
+	names, _ := g.Glob("SN_*.html")
+	if len(names) != 1 || names[0] != "SN_1.html" {
+		t.Errorf("Glob = %v", names)
+	}
+	if fake.auth[0] != "Bearer token" {
+		t.Errorf("Expected bearer token, got %q", fake.auth[0])
+	}
+}
@@ -0,0 +1,165 @@
+// Package summarize generates per-episode abstracts and topic lists with a
+// large language model behind an OpenAI-compatible chat completions API
+package summarize
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/utils"
+)
+
+// Prompt instructs the model; the episode's show, title and transcript
+// follow it
+var Prompt = `You summarize podcast episodes from their transcripts.
+Reply with only a JSON object of the form
+{"abstract": "...", "topics": ["...", "..."]}
+where abstract is a neutral summary of the episode in 2 to 4 sentences and
+topics lists the 3 to 8 main subjects discussed, as short noun phrases.`
+
+// MaxChars caps the transcript text sent to the model. Longer transcripts
+// are cut in the middle, keeping their opening and closing parts, to stay
+// within the context windows of local models.
+var MaxChars = 48000
+
+// Client calls an OpenAI-compatible /chat/completions endpoint. This covers
+// the OpenAI API as well as local servers such as Ollama, llama.cpp and vLLM.
+type Client struct {
+	Endpoint string // Base URL, e.g. https://api.openai.com/v1
+	Model    string
+	APIKey   string
+	HTTP     *http.Client
+}
+
+// NewClient creates a client with a default HTTP timeout
+func NewClient(endpoint, model, apiKey string) *Client {
+	return &Client{
+		Endpoint: strings.TrimRight(endpoint, "/"),
+		Model:    model,
+		APIKey:   apiKey,
+		HTTP:     &http.Client{Timeout: 10 * time.Minute},
+	}
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Temperature float64       `json:"temperature"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// Source identifies the version of an episode's transcript, so summaries
+// are only regenerated when it changes
+func Source(ep converter.Episode) string {
+	sum := sha256.Sum256([]byte(ep.Title + "\n" + ep.Content))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Summarize generates the abstract and topics of an episode
+func (c *Client) Summarize(ep converter.Episode) (*converter.Summary, error) {
+	reply, err := c.complete(Prompt, Input(ep))
+	if err != nil {
+		return nil, err
+	}
+	s, err := Parse(reply)
+	if err != nil {
+		return nil, err
+	}
+	s.Model = c.Model
+	s.Source = Source(ep)
+	return s, nil
+}
+
+// Input is the text sent to the model for an episode: its show, title and
+// transcript turns without their line prefixes, cut to MaxChars
+func Input(ep converter.Episode) string {
+	turns := converter.Turns(ep.Content)
+	lines := make([]string, len(turns))
+	for i, t := range turns {
+		lines[i] = t.Text
+	}
+	text := strings.Join(lines, "\n")
+	if MaxChars > 0 && len(text) > MaxChars {
+		half := MaxChars / 2
+		head, tail := text[:half], text[len(text)-half:]
+		// Cut at line breaks so no turn is split mid-word
+		if i := strings.LastIndex(head, "\n"); i > 0 {
+			head = head[:i]
+		}
+		if i := strings.Index(tail, "\n"); i >= 0 {
+			tail = tail[i+1:]
+		}
+		text = head + "\n[...]\n" + tail
+	}
+	return fmt.Sprintf("Show: %s\nTitle: %s\n\nTranscript:\n%s", config.ShowName(ep.Prefix), ep.Title, text)
+}
+
+// jsonObjectRegex finds the JSON object in a reply that wraps it in prose
+// or a code fence
+var jsonObjectRegex = regexp.MustCompile(`(?s)\{.*\}`)
+
+// Parse reads the model's reply
+func Parse(reply string) (*converter.Summary, error) {
+	var s converter.Summary
+	obj := jsonObjectRegex.FindString(reply)
+	if obj == "" {
+		return nil, fmt.Errorf("no JSON object in the model's reply: %.200q", reply)
+	}
+	if err := json.Unmarshal([]byte(obj), &s); err != nil {
+		return nil, fmt.Errorf("invalid JSON in the model's reply: %v", err)
+	}
+	s.Abstract = strings.Join(strings.Fields(s.Abstract), " ")
+	if s.Abstract == "" {
+		return nil, fmt.Errorf("the model's reply has no abstract")
+	}
+	var topics []string
+	for _, t := range s.Topics {
+		if t = strings.TrimSpace(t); t != "" {
+			topics = append(topics, t)
+		}
+	}
+	s.Topics = topics
+	return &s, nil
+}
+
+// complete sends a system and user message and returns the reply
+func (c *Client) complete(system, user string) (string, error) {
+	body, err := json.Marshal(chatRequest{
+		Model:    c.Model,
+		Messages: []chatMessage{{"system", system}, {"user", user}},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	respBody, err := utils.PostJSON(c.HTTP, c.Endpoint+"/chat/completions", body, c.APIKey)
+	if err != nil {
+		return "", fmt.Errorf("completion request failed: %v", err)
+	}
+	var parsed chatResponse
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return "", fmt.Errorf("invalid completion response: %v", err)
+	}
+	if len(parsed.Choices) == 0 {
+		return "", fmt.Errorf("completion response has no choices")
+	}
+	return parsed.Choices[0].Message.Content, nil
+}
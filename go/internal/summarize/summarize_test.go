@@ -0,0 +1,57 @@
+package summarize
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+func TestSummarize(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if r.URL.Path != "/chat/completions" || req.Model != "m" || len(req.Messages) != 2 || !strings.Contains(req.Messages[1].Content, "Steve Gibson Hello") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		reply := "Here you go:\n```json\n{\"abstract\": \"Steve  says\\nhello.\", \"topics\": [\"greetings\", \" \"]}\n```"
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": reply}}},
+		})
+	}))
+	defer srv.Close()
+
+	ep := converter.Episode{Prefix: "SN", Number: 1000, Title: "Security Now 1000", Content: "EP:1000 Date:24-11-19 - Steve Gibson Hello"}
+	s, err := NewClient(srv.URL, "m", "").Summarize(ep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Abstract != "Steve says hello." || len(s.Topics) != 1 || s.Topics[0] != "greetings" || s.Model != "m" || s.Source != Source(ep) {
+		t.Errorf("Summarize = %+v", s)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, reply := range []string{"I cannot help", `{"topics": ["x"]}`, `{"abstract": `} {
+		if _, err := Parse(reply); err == nil {
+			t.Errorf("Parse(%q) succeeded", reply)
+		}
+	}
+}
+
+func TestInput(t *testing.T) {
+	defer func(n int) { MaxChars = n }(MaxChars)
+	MaxChars = 40
+	var lines []string
+	for _, w := range []string{"first", "second", "third", "fourth", "fifth", "sixth"} {
+		lines = append(lines, "EP:1 Date:24-11-19 - A "+w+" turn")
+	}
+	in := Input(converter.Episode{Prefix: "SN", Title: "T", Content: strings.Join(lines, "\n")})
+	if !strings.Contains(in, "A first turn\n[...]\nA sixth turn") || strings.Contains(in, "fourth") {
+		t.Errorf("Input =\n%s", in)
+	}
+}
@@ -0,0 +1,344 @@
+package transcribe
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+// maxCells bounds the edit-distance table of one stretch of the alignment;
+// longer stretches are first split at words that occur once in both
+var maxCells = 1 << 20
+
+// excerptWords is how much of each side a Hotspot quotes
+const excerptWords = 30
+
+// Comparison measures how a published transcript differs from speech
+// recognition output of the same episode. The published transcript is the
+// reference: Deletions are its words missing from the recognizer's output
+// and Insertions words only the recognizer has.
+type Comparison struct {
+	Prefix        string    `json:"prefix"`
+	Number        int       `json:"episode"`
+	RefWords      int       `json:"published_words"`
+	HypWords      int       `json:"asr_words"`
+	Substitutions int       `json:"substitutions"`
+	Deletions     int       `json:"deletions"`
+	Insertions    int       `json:"insertions"`
+	WER           float64   `json:"wer"` // (Substitutions+Deletions+Insertions) / RefWords
+	Hotspots      []Hotspot `json:"hotspots,omitempty"`
+}
+
+// Hotspot is a stretch where the two transcripts disagree most, with the
+// time it starts at in each (HH:MM:SS, empty if unknown)
+type Hotspot struct {
+	Errors  int    `json:"errors"`
+	RefTime string `json:"published_time,omitempty"`
+	HypTime string `json:"asr_time,omitempty"`
+	Ref     string `json:"published"`
+	Hyp     string `json:"asr"`
+}
+
+// Compare aligns an episode's published transcript with recognizer output
+// word by word, ignoring case, punctuation and speaker names, and reports
+// the error counts and the top hotspots. Since long stretches without
+// common words are not aligned exactly, the word error rate is an
+// estimate, though a close one for transcripts of the same audio.
+func Compare(ep converter.Episode, cues []converter.Cue, hotspots int) Comparison {
+	ref, refTimes := publishedWords(ep)
+	var hyp, hypTimes []string
+	for _, c := range cues {
+		tc := ""
+		if c.Timed {
+			tc = converter.FormatTimecode(c.Start)
+		}
+		for _, w := range normalizeWords(c.Text) {
+			hyp = append(hyp, w)
+			hypTimes = append(hypTimes, tc)
+		}
+	}
+
+	al := &aligner{a: ref, b: hyp}
+	al.align(0, len(ref), 0, len(hyp))
+	cmp := Comparison{
+		Prefix: ep.Prefix, Number: ep.Number,
+		RefWords: len(ref), HypWords: len(hyp),
+		Substitutions: al.sub, Deletions: al.del, Insertions: al.ins,
+	}
+	if len(ref) > 0 {
+		cmp.WER = float64(al.sub+al.del+al.ins) / float64(len(ref))
+	}
+
+	sort.SliceStable(al.hunks, func(i, j int) bool { return al.hunks[i].errors > al.hunks[j].errors })
+	for i := 0; i < len(al.hunks) && i < hotspots; i++ {
+		h := al.hunks[i]
+		hs := Hotspot{Errors: h.errors, Ref: excerpt(ref, h.a0, h.a1), Hyp: excerpt(hyp, h.b0, h.b1)}
+		if h.a0 < len(refTimes) {
+			hs.RefTime = refTimes[h.a0]
+		}
+		if h.b0 < len(hypTimes) {
+			hs.HypTime = hypTimes[h.b0]
+		}
+		cmp.Hotspots = append(cmp.Hotspots, hs)
+	}
+	return cmp
+}
+
+// ErrSameSource is returned by CompareEpisode when the archived page was
+// itself made from the recognizer output
+var ErrSameSource = errors.New("the archived transcript came from speech recognition")
+
+// CompareEpisode compares an episode's archived transcript page with its
+// kept recognizer output
+func CompareEpisode(dataDir, prefix string, ep, hotspots int) (Comparison, error) {
+	out, ok := FindOutput(dataDir, prefix, ep)
+	if !ok {
+		return Comparison{}, fmt.Errorf("no speech recognition output for %s %d", prefix, ep)
+	}
+	cues, err := LoadOutput(out)
+	if err != nil {
+		return Comparison{}, err
+	}
+	e, err := converter.LoadEpisode(pagePath(dataDir, prefix, ep))
+	if err != nil {
+		return Comparison{}, err
+	}
+	if e.Origin == converter.OriginASR {
+		return Comparison{}, ErrSameSource
+	}
+	return Compare(e, cues, hotspots), nil
+}
+
+// publishedWords returns the normalized words of an episode's turns,
+// without speaker names, and the timecode of the turn of each
+func publishedWords(ep converter.Episode) ([]string, []string) {
+	var words, times []string
+	for i, seg := range converter.Turns(ep.Content) {
+		text := seg.Text
+		if i < len(ep.Turns) && ep.Turns[i].Speaker != "" {
+			text = strings.TrimPrefix(text, ep.Turns[i].Speaker)
+		}
+		for _, w := range normalizeWords(text) {
+			words = append(words, w)
+			times = append(times, seg.Timecode)
+		}
+	}
+	return words, times
+}
+
+// normalizeWords lowercases text and splits it into words, dropping
+// punctuation; apostrophes are removed so "it's" and "its" match
+func normalizeWords(s string) []string {
+	s = strings.NewReplacer("'", "", "’", "").Replace(strings.ToLower(s))
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func excerpt(words []string, start, end int) string {
+	if end-start > excerptWords {
+		return strings.Join(words[start:start+excerptWords], " ") + " ..."
+	}
+	return strings.Join(words[start:end], " ")
+}
+
+// hunk is a stretch of differing words: a[a0:a1] against b[b0:b1]
+type hunk struct {
+	a0, a1, b0, b1 int
+	errors         int
+}
+
+// aligner aligns two word sequences like a patience diff: common ends are
+// trimmed, stretches small enough get an exact edit-distance alignment and
+// larger ones are split at words occurring once in each side
+type aligner struct {
+	a, b          []string
+	sub, del, ins int
+	hunks         []hunk
+}
+
+func (al *aligner) align(a0, a1, b0, b1 int) {
+	for a0 < a1 && b0 < b1 && al.a[a0] == al.b[b0] {
+		a0++
+		b0++
+	}
+	for a1 > a0 && b1 > b0 && al.a[a1-1] == al.b[b1-1] {
+		a1--
+		b1--
+	}
+	if a0 == a1 && b0 == b1 {
+		return
+	}
+	if a0 == a1 || b0 == b1 || (a1-a0)*(b1-b0) <= maxCells {
+		al.editDistance(a0, a1, b0, b1)
+		return
+	}
+	anchors := al.anchors(a0, a1, b0, b1)
+	if len(anchors) == 0 {
+		// No way to line this stretch up; count it as replaced
+		n, m := a1-a0, b1-b0
+		s := n
+		if m < s {
+			s = m
+		}
+		al.sub += s
+		al.del += n - s
+		al.ins += m - s
+		al.add(hunk{a0, a1, b0, b1, s + (n - s) + (m - s)})
+		return
+	}
+	pa, pb := a0, b0
+	for _, an := range anchors {
+		al.align(pa, an[0], pb, an[1])
+		pa, pb = an[0]+1, an[1]+1
+	}
+	al.align(pa, a1, pb, b1)
+}
+
+// anchors returns the words occurring exactly once in both stretches, as
+// index pairs, reduced to the longest run in order on both sides
+func (al *aligner) anchors(a0, a1, b0, b1 int) [][2]int {
+	type seen struct{ n, at int }
+	inA := make(map[string]*seen)
+	for i := a0; i < a1; i++ {
+		if s := inA[al.a[i]]; s != nil {
+			s.n++
+		} else {
+			inA[al.a[i]] = &seen{1, i}
+		}
+	}
+	inB := make(map[string]*seen)
+	for j := b0; j < b1; j++ {
+		if s := inB[al.b[j]]; s != nil {
+			s.n++
+		} else {
+			inB[al.b[j]] = &seen{1, j}
+		}
+	}
+	var pairs [][2]int
+	for i := a0; i < a1; i++ {
+		sa, sb := inA[al.a[i]], inB[al.a[i]]
+		if sa.n == 1 && sb != nil && sb.n == 1 {
+			pairs = append(pairs, [2]int{i, sb.at})
+		}
+	}
+	return longestIncreasing(pairs)
+}
+
+// longestIncreasing returns the longest subsequence of pairs (sorted by
+// their first index) whose second indexes increase, by patience sorting
+func longestIncreasing(pairs [][2]int) [][2]int {
+	var tails []int // Index in pairs of the smallest tail of each length
+	prev := make([]int, len(pairs))
+	for i, p := range pairs {
+		k := sort.Search(len(tails), func(k int) bool { return pairs[tails[k]][1] >= p[1] })
+		prev[i] = -1
+		if k > 0 {
+			prev[i] = tails[k-1]
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+	if len(tails) == 0 {
+		return nil
+	}
+	out := make([][2]int, len(tails))
+	for k, i := len(tails)-1, tails[len(tails)-1]; k >= 0; k-- {
+		out[k] = pairs[i]
+		i = prev[i]
+	}
+	return out
+}
+
+// editDistance aligns a[a0:a1] with b[b0:b1] exactly and records the
+// differing stretches
+func (al *aligner) editDistance(a0, a1, b0, b1 int) {
+	n, m := a1-a0, b1-b0
+	w := m + 1
+	d := make([]int32, (n+1)*w)
+	for i := 0; i <= n; i++ {
+		d[i*w] = int32(i)
+	}
+	for j := 0; j <= m; j++ {
+		d[j] = int32(j)
+	}
+	for i := 1; i <= n; i++ {
+		for j := 1; j <= m; j++ {
+			best := d[(i-1)*w+j-1]
+			if al.a[a0+i-1] != al.b[b0+j-1] {
+				best++
+			}
+			if v := d[(i-1)*w+j] + 1; v < best {
+				best = v
+			}
+			if v := d[i*w+j-1] + 1; v < best {
+				best = v
+			}
+			d[i*w+j] = best
+		}
+	}
+
+	// Walk back from the end, collecting the differing stretches
+	var hunks []hunk
+	cur := hunk{a0: -1}
+	flush := func(i, j int) {
+		if cur.a0 >= 0 {
+			cur.a0, cur.b0 = a0+i, b0+j
+			hunks = append(hunks, cur)
+			cur = hunk{a0: -1}
+		}
+	}
+	open := func(i, j int) {
+		if cur.a0 < 0 {
+			cur = hunk{a0: 0, a1: a0 + i, b1: b0 + j}
+		}
+		cur.errors++
+	}
+	i, j := n, m
+	for i > 0 || j > 0 {
+		switch {
+		case i > 0 && j > 0 && al.a[a0+i-1] == al.b[b0+j-1] && d[i*w+j] == d[(i-1)*w+j-1]:
+			flush(i, j)
+			i--
+			j--
+		case i > 0 && j > 0 && d[i*w+j] == d[(i-1)*w+j-1]+1:
+			open(i, j)
+			al.sub++
+			i--
+			j--
+		case i > 0 && d[i*w+j] == d[(i-1)*w+j]+1:
+			open(i, j)
+			al.del++
+			i--
+		default:
+			open(i, j)
+			al.ins++
+			j--
+		}
+	}
+	flush(0, 0)
+	for k := len(hunks) - 1; k >= 0; k-- {
+		al.add(hunks[k])
+	}
+}
+
+// add records a differing stretch, merging it with the previous one when
+// they touch
+func (al *aligner) add(h hunk) {
+	if n := len(al.hunks); n > 0 {
+		last := &al.hunks[n-1]
+		if last.a1 == h.a0 && last.b1 == h.b0 {
+			last.a1, last.b1 = h.a1, h.b1
+			last.errors += h.errors
+			return
+		}
+	}
+	al.hunks = append(al.hunks, h)
+}
@@ -0,0 +1,104 @@
+package transcribe
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+func TestAligner(t *testing.T) {
+	ref := normalizeWords("The quick brown fox jumps over the lazy dog.")
+	hyp := normalizeWords("the quick brown box jumps over lazy dog today")
+	al := &aligner{a: ref, b: hyp}
+	al.align(0, len(ref), 0, len(hyp))
+	if al.sub != 1 || al.del != 1 || al.ins != 1 || len(al.hunks) != 3 {
+		t.Errorf("Alignment = %d/%d/%d, hunks %+v", al.sub, al.del, al.ins, al.hunks)
+	}
+
+	// Split at unique words, a long stretch aligns as the exact table does
+	var a, b []string
+	for i := 0; i < 200; i++ {
+		word := "w" + strings.Repeat("x", i%7) + string(rune('a'+i%26)) + string(rune('a'+i/26))
+		a = append(a, word, "and")
+		if i%10 == 3 {
+			b = append(b, "uh", "and")
+		} else {
+			b = append(b, word, "and")
+		}
+	}
+	exact := &aligner{a: a, b: b}
+	exact.align(0, len(a), 0, len(b))
+	saved := maxCells
+	maxCells = 16
+	defer func() { maxCells = saved }()
+	split := &aligner{a: a, b: b}
+	split.align(0, len(a), 0, len(b))
+	if exact.sub != 20 || split.sub != exact.sub || split.del != exact.del || split.ins != exact.ins {
+		t.Errorf("Split alignment %d/%d/%d, exact %d/%d/%d", split.sub, split.del, split.ins, exact.sub, exact.del, exact.ins)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "transcribetest")
+	defer os.RemoveAll(tmpDir)
+	path := filepath.Join(tmpDir, "SN_1000.html")
+	os.WriteFile(path, []byte(`<h1 class="post-title">Security Now 1000</h1><div class="body textual">
+<p>0:00:00 - Leo Laporte<br>
+It's time for Security Now, with Steve Gibson.</p>
+<p>0:00:10 - Steve Gibson<br>
+Great to be here, Leo. Let's talk about passkeys.</p>
+</div>`), 0644)
+	ep, err := converter.LoadEpisode(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cues := []converter.Cue{
+		{Start: time.Second, Timed: true, Text: "Its time for Security Now with Steve Gibson."},
+		{Start: 11 * time.Second, Timed: true, Text: "Great to be here Leo. Lets talk about pass keys."},
+	}
+	cmp := Compare(ep, cues, 5)
+	if cmp.RefWords != 17 || cmp.HypWords != 18 || cmp.Substitutions != 1 || cmp.Insertions != 1 || cmp.Deletions != 0 {
+		t.Fatalf("Compare = %+v", cmp)
+	}
+	if want := 2.0 / 17; cmp.WER != want {
+		t.Errorf("WER = %v, want %v", cmp.WER, want)
+	}
+	if len(cmp.Hotspots) != 1 {
+		t.Fatalf("Hotspots = %+v", cmp.Hotspots)
+	}
+	if h := cmp.Hotspots[0]; h.Ref != "passkeys" || h.Hyp != "pass keys" || h.RefTime != "00:00:10" || h.HypTime != "00:00:11" {
+		t.Errorf("Hotspot = %+v", h)
+	}
+}
+
+func TestCompareEpisode(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "transcribetest")
+	defer os.RemoveAll(tmpDir)
+	cues := []converter.Cue{{Start: time.Second, Timed: true, Text: "hello and welcome"}}
+	os.WriteFile(filepath.Join(tmpDir, "SN_1.html"), []byte(`<h1 class="post-title">Security Now 1</h1><div class="body textual"><p>0:00:01 - Leo<br>
+Hello, welcome!</p></div>`), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "SN_2.html"), []byte(converter.CaptionsPage("Security Now 2", time.Time{}, converter.OriginASR, cues)), 0644)
+
+	if _, err := CompareEpisode(tmpDir, "SN", 1, 5); err == nil {
+		t.Error("Expected an error without recognizer output")
+	}
+	for _, ep := range []int{1, 2} {
+		out := OutputPath(tmpDir, "SN", ep, "vtt")
+		os.MkdirAll(filepath.Dir(out), 0755)
+		os.WriteFile(out, []byte("WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nhello and welcome\n"), 0644)
+	}
+	if outs, err := Outputs(tmpDir, "SN"); err != nil || len(outs) != 2 || filepath.Base(outs[2]) != "SN_2.vtt" {
+		t.Errorf("Outputs = %v, %v", outs, err)
+	}
+	cmp, err := CompareEpisode(tmpDir, "SN", 1, 5)
+	if err != nil || cmp.Number != 1 || cmp.Insertions != 1 || cmp.RefWords != 2 {
+		t.Errorf("CompareEpisode = %+v, %v", cmp, err)
+	}
+	if _, err := CompareEpisode(tmpDir, "SN", 2, 5); err != ErrSameSource {
+		t.Errorf("Comparing a transcript with itself = %v, want ErrSameSource", err)
+	}
+}
@@ -0,0 +1,307 @@
+// Package transcribe fills in episodes that were never published as
+// transcripts by running their audio through speech recognition: an
+// external command such as whisper.cpp, or an OpenAI-compatible
+// transcription API. The result is ingested like any other transcript page,
+// marked with the origin "asr".
+package transcribe
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/chapters"
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/scraper"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+)
+
+// Transcriber turns an audio file into a transcript in one of the caption
+// formats (see converter.ParseCaptions), returning the data and its format
+type Transcriber interface {
+	Transcribe(audioPath string) ([]byte, string, error)
+}
+
+// Command runs an external speech recognizer. Args is the command line,
+// where "{audio}" is replaced by the audio file and "{out}" by a file name
+// without extension to write the result to, as whisper.cpp's -of expects;
+// the result is read from {out} plus the format's extension, or from the
+// command's output if Args has no "{out}". Args are not passed through a
+// shell.
+type Command struct {
+	Args   []string
+	Format string // Caption format of the result, e.g. "vtt"
+}
+
+// NewCommand splits a command line on spaces into a Command
+func NewCommand(line, format string) (*Command, error) {
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no transcription command given")
+	}
+	if !strings.Contains(line, "{audio}") {
+		return nil, fmt.Errorf("transcription command must contain {audio}")
+	}
+	return &Command{Args: args, Format: format}, nil
+}
+
+// Transcribe implements Transcriber
+func (c *Command) Transcribe(audioPath string) ([]byte, string, error) {
+	tmpDir, err := os.MkdirTemp("", "twit-transcribe")
+	if err != nil {
+		return nil, "", err
+	}
+	defer os.RemoveAll(tmpDir)
+	out := filepath.Join(tmpDir, "transcript")
+
+	args := make([]string, len(c.Args))
+	usesOut := false
+	for i, a := range c.Args {
+		usesOut = usesOut || strings.Contains(a, "{out}")
+		args[i] = strings.NewReplacer("{audio}", audioPath, "{out}", out).Replace(a)
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if i := strings.LastIndex(msg, "\n"); i >= 0 {
+			msg = msg[i+1:] // The last line usually says what went wrong
+		}
+		return nil, "", fmt.Errorf("%s: %v: %s", args[0], err, msg)
+	}
+	if !usesOut {
+		return stdout.Bytes(), c.Format, nil
+	}
+	data, err := os.ReadFile(out + "." + c.Format)
+	return data, c.Format, err
+}
+
+// API calls an OpenAI-compatible /audio/transcriptions endpoint, which the
+// OpenAI API and local servers such as faster-whisper-server and
+// LocalAI provide, asking for WebVTT
+type API struct {
+	Endpoint string // Base URL, e.g. https://api.openai.com/v1
+	Model    string
+	APIKey   string
+	HTTP     *http.Client
+}
+
+// NewAPI creates a client with a generous HTTP timeout, since an episode's
+// audio takes a while to transcribe
+func NewAPI(endpoint, model, apiKey string) *API {
+	return &API{
+		Endpoint: strings.TrimRight(endpoint, "/"),
+		Model:    model,
+		APIKey:   apiKey,
+		HTTP:     &http.Client{Timeout: time.Hour},
+	}
+}
+
+// Transcribe implements Transcriber
+func (a *API) Transcribe(audioPath string) ([]byte, string, error) {
+	f, err := os.Open(audioPath)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	w.WriteField("model", a.Model)
+	w.WriteField("response_format", converter.CaptionsVTT)
+	part, err := w.CreateFormFile("file", filepath.Base(audioPath))
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := io.Copy(part, f); err != nil {
+		return nil, "", err
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+
+	req, err := http.NewRequest("POST", a.Endpoint+"/audio/transcriptions", &body)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	if a.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+a.APIKey)
+	}
+	resp, err := a.HTTP.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode != 200 {
+		return nil, "", fmt.Errorf("status code %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return data, converter.CaptionsVTT, nil
+}
+
+// Dir keeps the recognizer's output, relative to the data directory, one
+// directory per show (asr/SN/SN_1000.vtt), for comparing with published
+// transcripts (see Compare) and converting again without re-running it
+const Dir = "asr"
+
+// ErrNoAudio is returned by Episode for feed items without an audio
+// enclosure
+var ErrNoAudio = errors.New("no audio enclosure")
+
+// Options controls which episodes Episode transcribes and how
+type Options struct {
+	Prefix   string // Show of the feed
+	Force    bool   // Transcribe archived episodes again
+	Budget   *scraper.MediaBudget
+	Throttle time.Duration
+}
+
+// OutputPath is where the recognizer's output for an episode is kept;
+// format is its caption format and file extension
+func OutputPath(dataDir, prefix string, ep int, format string) string {
+	return storage.Join(dataDir, Dir, prefix, fmt.Sprintf("%s_%d.%s", prefix, ep, format))
+}
+
+// FindOutput returns the kept recognizer output of an episode, if any
+func FindOutput(dataDir, prefix string, ep int) (string, bool) {
+	matches, _ := storage.Glob(OutputPath(dataDir, prefix, ep, "*"))
+	if len(matches) == 0 {
+		return "", false
+	}
+	return matches[0], true
+}
+
+// Outputs lists the kept recognizer output of a show by episode number
+func Outputs(dataDir, prefix string) (map[int]string, error) {
+	matches, err := storage.Glob(storage.Join(dataDir, Dir, prefix, prefix+"_*.*"))
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[int]string)
+	for _, m := range matches {
+		name := strings.TrimPrefix(storage.Base(m), prefix+"_")
+		if ep, err := strconv.Atoi(strings.SplitN(name, ".", 2)[0]); err == nil {
+			out[ep] = m
+		}
+	}
+	return out, nil
+}
+
+// LoadOutput reads kept recognizer output, in the format its extension
+// names
+func LoadOutput(path string) ([]converter.Cue, error) {
+	data, err := storage.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cues, err := converter.ParseCaptions(data, converter.CaptionFormat("", path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cues, nil
+}
+
+// pagePath is an episode's transcript page under the show's own prefix
+func pagePath(dataDir, prefix string, ep int) string {
+	return storage.Join(config.ActiveLayout.RawDir(dataDir, prefix), fmt.Sprintf("%s_%d.html", prefix, ep))
+}
+
+// Candidates returns the items of a show's feed that Episode would
+// transcribe: numbered episodes with audio that are not archived yet
+// (or, with Force, all numbered episodes with audio)
+func Candidates(items []chapters.Item, dataDir string, opts Options) []chapters.Item {
+	var out []chapters.Item
+	for _, it := range items {
+		if it.Episode == 0 || it.Audio == "" {
+			continue
+		}
+		if !opts.Force && scraper.Archived(opts.Prefix, strconv.Itoa(it.Episode), dataDir) {
+			continue
+		}
+		out = append(out, it)
+	}
+	return out
+}
+
+// ComparisonCandidates returns the items of a show's feed whose published
+// transcript can be compared with recognizer output: numbered episodes
+// with audio and an archived page that did not come from speech
+// recognition itself, and no kept output yet (unless Force is set)
+func ComparisonCandidates(items []chapters.Item, dataDir string, opts Options) []chapters.Item {
+	var out []chapters.Item
+	for _, it := range items {
+		if it.Episode == 0 || it.Audio == "" {
+			continue
+		}
+		if _, ok := FindOutput(dataDir, opts.Prefix, it.Episode); ok && !opts.Force {
+			continue
+		}
+		html, err := storage.ReadFile(pagePath(dataDir, opts.Prefix, it.Episode))
+		if err != nil || converter.PageOrigin(string(html)) == converter.OriginASR {
+			continue
+		}
+		out = append(out, it)
+	}
+	return out
+}
+
+// Run downloads a feed item's audio into the media directory (keeping it
+// there, as --with-media would), transcribes it and keeps the output in
+// Dir. Returns the output's path.
+func Run(t Transcriber, item chapters.Item, dataDir string, opts Options) (string, error) {
+	if item.Audio == "" {
+		return "", ErrNoAudio
+	}
+	epNum := strconv.Itoa(item.Episode)
+	audio, err := scraper.DownloadMedia(item.Audio, opts.Prefix, epNum, dataDir, opts.Budget, opts.Throttle)
+	if err != nil {
+		return "", err
+	}
+	data, format, err := t.Transcribe(audio)
+	if err != nil {
+		return "", err
+	}
+	if _, err := converter.ParseCaptions(data, format); err != nil {
+		return "", fmt.Errorf("transcription of %s: %w", audio, err)
+	}
+	if old, ok := FindOutput(dataDir, opts.Prefix, item.Episode); ok {
+		storage.Remove(old) // The new output may be in another format
+	}
+	out := OutputPath(dataDir, opts.Prefix, item.Episode, format)
+	return out, storage.WriteFile(out, data)
+}
+
+// Episode transcribes a feed item with Run and ingests the result as the
+// episode's transcript page, with the audio URL as its source. Speaker
+// labels of diarizing recognizers are named as by scraper.ReconcileSpeakers.
+// Returns the saved page.
+func Episode(t Transcriber, item chapters.Item, dataDir string, opts Options) (string, error) {
+	out, err := Run(t, item, dataDir, opts)
+	if err != nil {
+		return "", err
+	}
+	cues, err := LoadOutput(out)
+	if err != nil {
+		return "", err
+	}
+	scraper.ReconcileSpeakers(cues, opts.Prefix, dataDir)
+	page := converter.CaptionsPage(item.Title, item.Published, converter.OriginASR, cues)
+	return scraper.IngestPage(page, dataDir, scraper.IngestOptions{
+		Prefix: opts.Prefix, Episode: item.Episode, Force: opts.Force, URL: item.Audio,
+	})
+}
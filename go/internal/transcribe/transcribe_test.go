@@ -0,0 +1,135 @@
+package transcribe
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/chapters"
+	"github.com/aramova/twit-transcript-archiver/go/internal/config"
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+const testVTT = "WEBVTT\n\n00:00:01.000 --> 00:00:03.000\nhello and welcome\n"
+
+func TestCommand(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "transcribetest")
+	defer os.RemoveAll(tmpDir)
+	audio := filepath.Join(tmpDir, "SN_1.mp3")
+	os.WriteFile(audio, []byte(testVTT), 0644)
+
+	// The "audio" is already a transcript, so cat stands in for the recognizer
+	c, err := NewCommand("cat {audio}", "vtt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, format, err := c.Transcribe(audio)
+	if err != nil || string(data) != testVTT || format != "vtt" {
+		t.Errorf("Transcribe from output = %q, %q, %v", data, format, err)
+	}
+
+	c = &Command{Args: []string{"sh", "-c", `cp "$1" "$2.srt"`, "sh", "{audio}", "{out}"}, Format: "srt"}
+	if data, _, err := c.Transcribe(audio); err != nil || string(data) != testVTT {
+		t.Errorf("Transcribe to {out} = %q, %v", data, err)
+	}
+
+	c = &Command{Args: []string{"sh", "-c", "echo loading >&2; echo model not found >&2; exit 3"}}
+	if _, _, err := c.Transcribe(audio); err == nil || !strings.Contains(err.Error(), "model not found") {
+		t.Errorf("Failing command = %v, want its last error line", err)
+	}
+	if _, err := NewCommand("whisper-cli -f input.mp3", "vtt"); err == nil {
+		t.Error("Expected an error for a command without {audio}")
+	}
+}
+
+func TestAPI(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, hdr, err := r.FormFile("file")
+		if err != nil || r.URL.Path != "/v1/audio/transcriptions" || r.FormValue("model") != "whisper-1" ||
+			r.FormValue("response_format") != "vtt" || r.Header.Get("Authorization") != "Bearer key" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		f.Close()
+		fmt.Fprintf(w, "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\n%s\n", hdr.Filename)
+	}))
+	defer ts.Close()
+
+	tmpDir, _ := os.MkdirTemp("", "transcribetest")
+	defer os.RemoveAll(tmpDir)
+	audio := filepath.Join(tmpDir, "SN_1.mp3")
+	os.WriteFile(audio, []byte("ID3"), 0644)
+
+	data, format, err := NewAPI(ts.URL+"/v1/", "whisper-1", "key").Transcribe(audio)
+	if err != nil || format != converter.CaptionsVTT || !strings.Contains(string(data), "SN_1.mp3") {
+		t.Errorf("Transcribe = %q, %q, %v", data, format, err)
+	}
+	if _, _, err := NewAPI(ts.URL, "whisper-1", "").Transcribe(audio); err == nil {
+		t.Error("Expected an error for a rejected request")
+	}
+}
+
+func TestEpisode(t *testing.T) {
+	tmpDir, _ := os.MkdirTemp("", "transcribetest")
+	defer os.RemoveAll(tmpDir)
+	os.WriteFile(filepath.Join(tmpDir, "SN_999.html"), []byte(`<h1 class="post-title">Security Now 999</h1>`), 0644)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testVTT)
+	}))
+	defer ts.Close()
+
+	items := []chapters.Item{
+		{Title: "SN 1000: The Big One", Episode: 1000, Audio: ts.URL + "/sn1000.mp3", Published: time.Date(2024, 11, 19, 20, 0, 0, 0, time.UTC)},
+		{Title: "SN 999: Archived", Episode: 999, Audio: ts.URL + "/sn999.mp3"},
+		{Title: "Bonus", Audio: ts.URL + "/bonus.mp3"},
+		{Title: "SN 998: No audio", Episode: 998},
+	}
+	opts := Options{Prefix: "SN"}
+	todo := Candidates(items, tmpDir, opts)
+	if len(todo) != 1 || todo[0].Episode != 1000 {
+		t.Fatalf("Candidates = %+v", todo)
+	}
+	if len(Candidates(items, tmpDir, Options{Prefix: "SN", Force: true})) != 2 {
+		t.Error("Force should include archived episodes")
+	}
+
+	c, _ := NewCommand("cat {audio}", "vtt")
+	path, err := Episode(c, todo[0], tmpDir, opts)
+	if err != nil || filepath.Base(path) != "SN_1000.html" {
+		t.Fatalf("Episode = %s, %v", path, err)
+	}
+	if _, err := os.Stat(filepath.Join(config.ActiveLayout.MediaDir(tmpDir, "SN"), "SN_1000.mp3")); err != nil {
+		t.Errorf("Audio not kept in the media directory: %v", err)
+	}
+	ep, err := converter.LoadEpisode(path)
+	if err != nil || ep.Origin != converter.OriginASR || ep.URL != items[0].Audio || !strings.Contains(ep.Content, "hello and welcome") {
+		t.Errorf("Transcribed episode = %+v, %v", ep, err)
+	}
+	if _, err := Episode(c, items[3], tmpDir, opts); err != ErrNoAudio {
+		t.Errorf("Item without audio = %v, want ErrNoAudio", err)
+	}
+	if out, ok := FindOutput(tmpDir, "SN", 1000); !ok || filepath.Base(out) != "SN_1000.vtt" {
+		t.Errorf("Recognizer output not kept: %s", out)
+	}
+
+	// Only the published SN 999 can be compared; SN 1000 came from the recognizer
+	todo = ComparisonCandidates(items, tmpDir, opts)
+	if len(todo) != 1 || todo[0].Episode != 999 {
+		t.Fatalf("ComparisonCandidates = %+v", todo)
+	}
+	if _, err := Run(c, todo[0], tmpDir, opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(ComparisonCandidates(items, tmpDir, opts)) != 0 {
+		t.Error("Episodes with recognizer output should not be candidates again")
+	}
+	if ep, _ := converter.LoadEpisode(filepath.Join(tmpDir, "SN_999.html")); ep.Origin != "" {
+		t.Error("Run should not replace the published transcript")
+	}
+}
@@ -0,0 +1,240 @@
+// Package translate writes translated copies of the per-episode Markdown
+// through a pluggable translation service, one tree per language
+// (md/es/SN/SN_1000.md, ...). Episodes are translated once and only again
+// when their transcript changes.
+package translate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+	"github.com/aramova/twit-transcript-archiver/go/internal/storage"
+	"github.com/aramova/twit-transcript-archiver/go/internal/utils"
+)
+
+// Translator translates texts from the source to the target language,
+// returning one translation per text in order
+type Translator interface {
+	Translate(texts []string, source, target string) ([]string, error)
+}
+
+// HTTPTranslator calls a LibreTranslate-compatible /translate endpoint,
+// which self-hosted LibreTranslate and several hosted services provide
+type HTTPTranslator struct {
+	Endpoint string // Base URL, e.g. http://localhost:5000
+	APIKey   string
+	HTTP     *http.Client
+}
+
+// NewHTTPTranslator creates a translator with a default HTTP timeout
+func NewHTTPTranslator(endpoint, apiKey string) *HTTPTranslator {
+	return &HTTPTranslator{
+		Endpoint: strings.TrimRight(endpoint, "/"),
+		APIKey:   apiKey,
+		HTTP:     &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+type translateRequest struct {
+	Q      []string `json:"q"`
+	Source string   `json:"source"`
+	Target string   `json:"target"`
+	Format string   `json:"format"`
+	APIKey string   `json:"api_key,omitempty"`
+}
+
+// Translate implements Translator
+func (h *HTTPTranslator) Translate(texts []string, source, target string) ([]string, error) {
+	body, err := json.Marshal(translateRequest{Q: texts, Source: source, Target: target, Format: "text", APIKey: h.APIKey})
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := utils.PostJSON(h.HTTP, h.Endpoint+"/translate", body, "")
+	if err != nil {
+		return nil, fmt.Errorf("translation request failed: %v", err)
+	}
+	var parsed struct {
+		TranslatedText []string `json:"translatedText"`
+	}
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return nil, fmt.Errorf("invalid translation response: %v", err)
+	}
+	if len(parsed.TranslatedText) != len(texts) {
+		return nil, fmt.Errorf("expected %d translations, got %d", len(texts), len(parsed.TranslatedText))
+	}
+	return parsed.TranslatedText, nil
+}
+
+// Dir is where a show's Markdown translated into lang is written
+func Dir(dataDir, lang, prefix string) string {
+	return storage.Join(dataDir, "md", lang, prefix)
+}
+
+// CacheFile records, per language, which version of each episode was
+// translated
+const CacheFile = "translations.json"
+
+// Cache maps episode keys ("SN_1000") to a hash of the transcript they
+// were translated from
+type Cache struct {
+	path   string
+	Hashes map[string]string
+}
+
+// LoadCache reads the translation cache of a language
+func LoadCache(dataDir, lang string) (*Cache, error) {
+	c := &Cache{path: storage.Join(dataDir, "md", lang, CacheFile), Hashes: make(map[string]string)}
+	data, err := storage.ReadFile(c.path)
+	if errors.Is(err, storage.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &c.Hashes); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", c.path, err)
+	}
+	return c, nil
+}
+
+// Save writes the cache
+func (c *Cache) Save() error {
+	data, err := json.MarshalIndent(c.Hashes, "", "  ")
+	if err != nil {
+		return err
+	}
+	return storage.WriteFile(c.path, append(data, '\n'))
+}
+
+// sourceHash identifies the version of an episode a translation was made
+// from
+func sourceHash(ep converter.Episode) string {
+	sum := sha256.Sum256([]byte(ep.Title + "\n" + ep.Content))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Options configures a translation run
+type Options struct {
+	Source string // Language of episodes that do not record one
+	Target string
+	Batch  int  // Texts per translation request
+	Force  bool // Translate episodes again even if cached
+}
+
+// Result counts the episodes a run translated and found cached
+type Result struct {
+	Translated int
+	Cached     int
+}
+
+// Episodes translates the episodes into opts.Target, skipping those whose
+// translation is cached and still on disk. The cache is saved after each
+// episode, so an interrupted run resumes where it stopped.
+func Episodes(t Translator, episodes []converter.Episode, dataDir string, opts Options) (Result, error) {
+	var res Result
+	cache, err := LoadCache(dataDir, opts.Target)
+	if err != nil {
+		return res, err
+	}
+	for _, ep := range episodes {
+		key := fmt.Sprintf("%s_%d", ep.Prefix, ep.Number)
+		path := storage.Join(Dir(dataDir, opts.Target, ep.Prefix), key+".md")
+		hash := sourceHash(ep)
+		if !opts.Force && cache.Hashes[key] == hash && storage.Exists(path) {
+			res.Cached++
+			continue
+		}
+		md, err := Episode(t, ep, opts)
+		if err != nil {
+			return res, fmt.Errorf("%s %d: %v", ep.Prefix, ep.Number, err)
+		}
+		if err := storage.WriteFile(path, []byte(md)); err != nil {
+			return res, err
+		}
+		cache.Hashes[key] = hash
+		if err := cache.Save(); err != nil {
+			return res, err
+		}
+		res.Translated++
+	}
+	return res, nil
+}
+
+// Episode translates an episode's title and turns, returning its Markdown
+// with front matter. Turn prefixes, timecodes and speaker names are kept.
+func Episode(t Translator, ep converter.Episode, opts Options) (string, error) {
+	source := ep.Language
+	if source == "" {
+		source = opts.Source
+	}
+	batch := opts.Batch
+	if batch <= 0 {
+		batch = 50
+	}
+
+	// Collect the title and each turn's text, without prefix and speaker
+	lines := strings.Split(ep.Content, "\n")
+	texts := []string{ep.Title}
+	type turnLine struct {
+		line int
+		head string // Prefix and speaker, kept as is
+	}
+	var turns []turnLine
+	turn := 0
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "EP:") {
+			continue
+		}
+		k := strings.Index(line, " - ")
+		if k < 0 {
+			turn++
+			continue
+		}
+		head, text := line[:k+3], line[k+3:]
+		if turn < len(ep.Turns) && ep.Turns[turn].Speaker != "" && strings.HasPrefix(text, ep.Turns[turn].Speaker) {
+			who := ep.Turns[turn].Speaker
+			head, text = head+who+" ", strings.TrimSpace(text[len(who):])
+		}
+		turn++
+		if text == "" {
+			continue
+		}
+		turns = append(turns, turnLine{i, head})
+		texts = append(texts, text)
+	}
+
+	translated := make([]string, 0, len(texts))
+	for start := 0; start < len(texts); start += batch {
+		end := start + batch
+		if end > len(texts) {
+			end = len(texts)
+		}
+		out, err := t.Translate(texts[start:end], source, opts.Target)
+		if err != nil {
+			return "", err
+		}
+		translated = append(translated, out...)
+	}
+
+	for j, tl := range turns {
+		// Translations never span lines in the Markdown
+		lines[tl.line] = tl.head + strings.Join(strings.Fields(translated[j+1]), " ")
+	}
+	tr := ep
+	tr.Title = strings.TrimSpace(translated[0])
+	tr.Language = opts.Target
+	tr.Content = strings.Join(lines, "\n")
+	md, err := converter.DefaultTemplates().RenderEpisode(tr, tr.Content, false)
+	if err != nil {
+		return "", err
+	}
+	return converter.FrontMatter(tr, len(strings.Fields(tr.Content)), nil) + md, nil
+}
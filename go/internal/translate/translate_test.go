@@ -0,0 +1,103 @@
+package translate
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aramova/twit-transcript-archiver/go/internal/converter"
+)
+
+// upper "translates" by upper-casing, counting the texts it was asked for
+type upper struct{ calls, texts int }
+
+func (u *upper) Translate(texts []string, source, target string) ([]string, error) {
+	u.calls++
+	u.texts += len(texts)
+	out := make([]string, len(texts))
+	for i, t := range texts {
+		out[i] = source + "/" + target + ": " + strings.ToUpper(t)
+	}
+	return out, nil
+}
+
+func testEpisode() converter.Episode {
+	return converter.Episode{
+		Prefix: "SN", Number: 1000, Title: "Security Now 1000",
+		Content: "EP:1000 Date:24-11-19 TS:0:10 - Steve Gibson Hello there\nEP:1000 Date:24-11-19 - Leo Laporte Hi",
+		Turns:   []converter.Turn{{Speaker: "Steve Gibson"}, {Speaker: "Leo Laporte"}},
+	}
+}
+
+func TestEpisodes(t *testing.T) {
+	dir := t.TempDir()
+	u := &upper{}
+	opts := Options{Source: "en", Target: "es", Batch: 2}
+	res, err := Episodes(u, []converter.Episode{testEpisode()}, dir, opts)
+	if err != nil || res != (Result{Translated: 1}) {
+		t.Fatalf("Episodes = %+v, %v", res, err)
+	}
+	// Title and two turns in batches of two
+	if u.calls != 2 || u.texts != 3 {
+		t.Errorf("Translator called %d times for %d texts", u.calls, u.texts)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "md", "es", "SN", "SN_1000.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := string(data)
+	for _, want := range []string{
+		`title: "en/es: SECURITY NOW 1000"`,
+		`language: "es"`,
+		"EP:1000 Date:24-11-19 TS:0:10 - Steve Gibson en/es: HELLO THERE",
+		"EP:1000 Date:24-11-19 - Leo Laporte en/es: HI",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Translation lacks %q:\n%s", want, md)
+		}
+	}
+
+	// Cached: no requests until the transcript changes or --force
+	u.calls = 0
+	if res, _ := Episodes(u, []converter.Episode{testEpisode()}, dir, opts); res != (Result{Cached: 1}) || u.calls != 0 {
+		t.Errorf("Second run = %+v with %d calls", res, u.calls)
+	}
+	changed := testEpisode()
+	changed.Content += "\nEP:1000 Date:24-11-19 - Steve Gibson More"
+	if res, _ := Episodes(u, []converter.Episode{changed}, dir, opts); res.Translated != 1 {
+		t.Errorf("Changed episode = %+v", res)
+	}
+	opts.Force = true
+	if res, _ := Episodes(u, []converter.Episode{changed}, dir, opts); res.Translated != 1 {
+		t.Errorf("Forced run = %+v", res)
+	}
+}
+
+func TestHTTPTranslator(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req translateRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if r.URL.Path != "/translate" || req.Source != "en" || req.Target != "de" || req.APIKey != "k" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		out := make([]string, len(req.Q))
+		for i, q := range req.Q {
+			out[i] = "de:" + q
+		}
+		json.NewEncoder(w).Encode(map[string][]string{"translatedText": out})
+	}))
+	defer srv.Close()
+
+	got, err := NewHTTPTranslator(srv.URL+"/", "k").Translate([]string{"a", "b"}, "en", "de")
+	if err != nil || strings.Join(got, ",") != "de:a,de:b" {
+		t.Errorf("Translate = %v, %v", got, err)
+	}
+	if _, err := NewHTTPTranslator(srv.URL, "wrong").Translate([]string{"a"}, "en", "de"); err == nil {
+		t.Error("Translate succeeded with a rejected key")
+	}
+}
@@ -0,0 +1,23 @@
+package utils
+
+import "time"
+
+// Deadline is the end of a run's time budget (--max-duration). The zero
+// Deadline never passes.
+type Deadline struct {
+	Budget time.Duration
+	At     time.Time
+}
+
+// NewDeadline starts a budget of d from now; d <= 0 means no budget
+func NewDeadline(d time.Duration) Deadline {
+	if d <= 0 {
+		return Deadline{}
+	}
+	return Deadline{Budget: d, At: time.Now().Add(d)}
+}
+
+// Passed reports whether the budget is used up
+func (d Deadline) Passed() bool {
+	return !d.At.IsZero() && time.Now().After(d.At)
+}
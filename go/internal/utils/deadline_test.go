@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeadline(t *testing.T) {
+	var unset Deadline
+	if unset.Passed() {
+		t.Error("The zero Deadline should never pass")
+	}
+	for _, d := range []time.Duration{0, -time.Minute} {
+		if dl := NewDeadline(d); dl.Passed() || !dl.At.IsZero() {
+			t.Errorf("NewDeadline(%v) = %+v, want no limit", d, dl)
+		}
+	}
+	if dl := NewDeadline(time.Hour); dl.Passed() || dl.Budget != time.Hour {
+		t.Errorf("NewDeadline(1h) = %+v, passed %v", dl, dl.Passed())
+	}
+	dl := NewDeadline(time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if !dl.Passed() {
+		t.Error("The deadline should have passed")
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package utils
+
+import "errors"
+
+// FreeSpace is not supported on this platform
+func FreeSpace(path string) (int64, error) {
+	return 0, errors.New("free space check not supported on this platform")
+}
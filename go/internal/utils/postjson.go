@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PostJSON tries a request this many times at most
+const postAttempts = 3
+
+var (
+	// RetryDelay is the wait between attempts of PostJSON when the server
+	// does not send a Retry-After
+	RetryDelay = 2 * time.Second
+	// MaxRetryAfter caps the Retry-After PostJSON honours
+	MaxRetryAfter = 2 * time.Minute
+)
+
+// PostJSON posts a JSON body and returns the body of a 200 reply. Network
+// errors, 429 and server errors are tried again, up to three attempts,
+// after the reply's Retry-After or RetryDelay; other client errors will not
+// succeed on retry and are returned at once. apiKey, if set, is sent as a
+// bearer token.
+func PostJSON(client *http.Client, url string, body []byte, apiKey string) ([]byte, error) {
+	var lastErr error
+	wait := time.Duration(0)
+	for attempt := 0; attempt < postAttempts; attempt++ {
+		time.Sleep(wait)
+		wait = RetryDelay
+		req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode == 200 {
+			return respBody, nil
+		}
+		lastErr = fmt.Errorf("status code %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != 429 {
+			break
+		}
+		if d, ok := ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			wait = d
+			if wait > MaxRetryAfter {
+				wait = MaxRetryAfter
+			}
+		}
+	}
+	return nil, lastErr
+}
+
+// ParseRetryAfter reads a Retry-After header: seconds or an HTTP date
+func ParseRetryAfter(h string, now time.Time) (time.Duration, bool) {
+	if h == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(h); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(h); err == nil && t.After(now) {
+		return t.Sub(now), true
+	}
+	return 0, false
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	if d, ok := ParseRetryAfter("120", now); !ok || d != 2*time.Minute {
+		t.Errorf("ParseRetryAfter(120) = %v, %v", d, ok)
+	}
+	if d, ok := ParseRetryAfter(now.Add(30*time.Second).Format(http.TimeFormat), now); !ok || d != 30*time.Second {
+		t.Errorf("ParseRetryAfter(date) = %v, %v", d, ok)
+	}
+	if _, ok := ParseRetryAfter("soon", now); ok {
+		t.Error("ParseRetryAfter should reject garbage")
+	}
+}
+
+func TestPostJSON(t *testing.T) {
+	defer func(d time.Duration) { RetryDelay = d }(RetryDelay)
+	RetryDelay = time.Millisecond
+
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("Authorization") != "Bearer key" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Headers = %v", r.Header)
+		}
+		switch r.URL.Path {
+		case "/busy":
+			if calls == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+		case "/down":
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		case "/bad":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, "no model")
+			return
+		}
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+	defer srv.Close()
+
+	if body, err := PostJSON(srv.Client(), srv.URL+"/busy", []byte("{}"), "key"); err != nil || string(body) != `{"ok":true}` || calls != 2 {
+		t.Errorf("After a 429: %q, %v in %d calls", body, err, calls)
+	}
+	calls = 0
+	if _, err := PostJSON(srv.Client(), srv.URL+"/down", []byte("{}"), "key"); err == nil || calls != postAttempts {
+		t.Errorf("Server errors: %v in %d calls, want an error after %d", err, calls, postAttempts)
+	}
+	calls = 0
+	if _, err := PostJSON(srv.Client(), srv.URL+"/bad", []byte("{}"), "key"); err == nil || !strings.Contains(err.Error(), "no model") || calls != 1 {
+		t.Errorf("Client errors should not be retried: %v in %d calls", err, calls)
+	}
+}
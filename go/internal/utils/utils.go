@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"flag"
 	"os"
 )
 
@@ -20,3 +21,25 @@ func FileExists(path string) bool {
 	}
 	return !info.IsDir()
 }
+
+// ParseFlags parses args with fs, allowing flags to follow positional
+// arguments (e.g. "SN --episodes 900-950"). It returns the positional
+// arguments; "--" ends flag parsing.
+func ParseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		// fs.Parse consumes a "--" terminator, so check what preceded rest
+		if n := len(args) - len(rest); n > 0 && args[n-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
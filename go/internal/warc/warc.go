@@ -0,0 +1,141 @@
+// Package warc writes HTTP exchanges as WARC 1.1 records, so a crawl can be
+// replayed and handed to digital-preservation tools.
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Writer appends records to a WARC file. It is safe for concurrent use.
+type Writer struct {
+	mu       sync.Mutex
+	w        io.Writer
+	compress bool // Gzip each record separately, as .warc.gz readers expect
+	Records  int  // Records written so far
+}
+
+// NewWriter returns a writer that starts with a warcinfo record describing
+// the software. With compress, every record is its own gzip member.
+func NewWriter(w io.Writer, compress bool, software string) (*Writer, error) {
+	ww := &Writer{w: w, compress: compress}
+	info := fmt.Sprintf("software: %s\r\nformat: WARC File Format 1.1\r\n", software)
+	err := ww.write(header{
+		"WARC-Type":    "warcinfo",
+		"Content-Type": "application/warc-fields",
+	}, []byte(info))
+	return ww, err
+}
+
+// WriteExchange records a request and its response. body is the response
+// payload as read by the client; resp.Body is not touched.
+func (ww *Writer) WriteExchange(req *http.Request, resp *http.Response, body []byte, at time.Time) error {
+	reqBlock, err := httputil.DumpRequestOut(req, false)
+	if err != nil {
+		return err
+	}
+	var respBlock bytes.Buffer
+	fmt.Fprintf(&respBlock, "%s %s\r\n", resp.Proto, resp.Status)
+	if err := resp.Header.Write(&respBlock); err != nil {
+		return err
+	}
+	respBlock.WriteString("\r\n")
+	respBlock.Write(body)
+
+	respID := recordID()
+	date := at.UTC().Format(time.RFC3339)
+	target := req.URL.String()
+	if err := ww.write(header{
+		"WARC-Type":           "response",
+		"WARC-Record-ID":      respID,
+		"WARC-Date":           date,
+		"WARC-Target-URI":     target,
+		"WARC-Payload-Digest": digest(body),
+		"Content-Type":        "application/http;msgtype=response",
+	}, respBlock.Bytes()); err != nil {
+		return err
+	}
+	return ww.write(header{
+		"WARC-Type":          "request",
+		"WARC-Date":          date,
+		"WARC-Target-URI":    target,
+		"WARC-Concurrent-To": respID,
+		"Content-Type":       "application/http;msgtype=request",
+	}, reqBlock)
+}
+
+// header holds a record's named fields, written in fieldOrder
+type header map[string]string
+
+var fieldOrder = []string{
+	"WARC-Type", "WARC-Record-ID", "WARC-Date", "WARC-Target-URI",
+	"WARC-Concurrent-To", "WARC-Payload-Digest", "WARC-Block-Digest",
+	"Content-Type", "Content-Length",
+}
+
+func (ww *Writer) write(h header, block []byte) error {
+	if h["WARC-Record-ID"] == "" {
+		h["WARC-Record-ID"] = recordID()
+	}
+	if h["WARC-Date"] == "" {
+		h["WARC-Date"] = time.Now().UTC().Format(time.RFC3339)
+	}
+	h["WARC-Block-Digest"] = digest(block)
+	h["Content-Length"] = fmt.Sprint(len(block))
+
+	var rec bytes.Buffer
+	rec.WriteString("WARC/1.1\r\n")
+	for _, name := range fieldOrder {
+		if v, ok := h[name]; ok {
+			fmt.Fprintf(&rec, "%s: %s\r\n", name, v)
+		}
+	}
+	rec.WriteString("\r\n")
+	rec.Write(block)
+	rec.WriteString("\r\n\r\n")
+
+	ww.mu.Lock()
+	defer ww.mu.Unlock()
+	if ww.compress {
+		gz := gzip.NewWriter(ww.w)
+		if _, err := gz.Write(rec.Bytes()); err != nil {
+			return err
+		}
+		if err := gz.Close(); err != nil {
+			return err
+		}
+	} else if _, err := ww.w.Write(rec.Bytes()); err != nil {
+		return err
+	}
+	ww.Records++
+	return nil
+}
+
+// IsCompressed reports whether a WARC path should be gzipped
+func IsCompressed(path string) bool {
+	return strings.HasSuffix(path, ".gz")
+}
+
+func digest(b []byte) string {
+	sum := sha1.Sum(b)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+// recordID returns a random (version 4) UUID URN
+func recordID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
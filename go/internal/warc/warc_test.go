@@ -0,0 +1,91 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteExchange(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html>transcript</html>"))
+	}))
+	defer ts.Close()
+
+	req, _ := http.NewRequest("GET", ts.URL+"/posts/transcripts/sn-1000", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, false, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteExchange(req, resp, body, time.Date(2024, 11, 19, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	if w.Records != 3 {
+		t.Errorf("Expected warcinfo, response and request records, got %d", w.Records)
+	}
+
+	out := buf.String()
+	if n := strings.Count(out, "WARC/1.1\r\n"); n != 3 {
+		t.Errorf("Expected 3 records, got %d", n)
+	}
+	for _, want := range []string{
+		"WARC-Type: warcinfo",
+		"WARC-Type: response",
+		"WARC-Type: request",
+		"WARC-Date: 2024-11-19T12:00:00Z",
+		"WARC-Target-URI: " + ts.URL + "/posts/transcripts/sn-1000",
+		"HTTP/1.1 200 OK\r\n",
+		"Content-Type: text/html\r\n",
+		"\r\n\r\n<html>transcript</html>\r\n\r\n",
+		"GET /posts/transcripts/sn-1000 HTTP/1.1\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Output missing %q", want)
+		}
+	}
+}
+
+func TestCompressedRecords(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, true, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewWriter(&buf, true, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if w.Records != 1 {
+		t.Fatalf("Expected 1 record, got %d", w.Records)
+	}
+
+	// Each record is its own gzip member; a multistream reader sees them all
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(bufio.NewReader(gz))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "WARC-Type: warcinfo"); n != 2 {
+		t.Errorf("Expected 2 warcinfo records, got %d", n)
+	}
+	if !IsCompressed("crawl.warc.gz") || IsCompressed("crawl.warc") {
+		t.Error("IsCompressed mismatch")
+	}
+}
@@ -3,6 +3,7 @@ from unittest.mock import patch, mock_open, MagicMock
 import sys
 import os
 import io
+import tempfile
 
 # Add parent directory to path
 # Insert at 0 to prioritize the 'git/' folder over the current root folder
@@ -96,10 +97,14 @@ class TestProcessTranscripts(unittest.TestCase):
         ]
         
         # Execute - this should now catch the error and continue
-        try:
-            process_transcripts.process_prefix("IM")
-        except OSError:
-            self.fail("process_prefix raised OSError instead of handling it gracefully")
+        # Write the chunk to a temp dir, not the real data folder
+        with tempfile.TemporaryDirectory() as tmp, \
+                patch("process_transcripts.OUTPUT_BASE", tmp):
+            try:
+                process_transcripts.process_prefix("IM")
+            except OSError:
+                self.fail("process_prefix raised OSError instead of handling it gracefully")
+            self.assertTrue(os.path.exists(os.path.join(tmp, "IM_Transcripts_0-0.md")))
 
     @patch("process_transcripts.glob.glob")
     @patch("builtins.open", new_callable=mock_open)